@@ -22,6 +22,17 @@ terraform {
 
 provider "aws" {
   region = var.aws_region
+
+  # Constants, so plans show the tags even where tags_all is only known at
+  # apply.
+  default_tags {
+    tags = {
+      Project     = "cs450-package-registry"
+      Environment = "dev"
+      Owner       = "cs450-group106"
+      CostCenter  = "cs450-group106"
+    }
+  }
 }
 
 locals {
@@ -1,5 +1,21 @@
-variable "aws_region" { type = string }
-variable "artifacts_bucket" { type = string }
+variable "aws_region" {
+  type        = string
+  description = "AWS region the environment is deployed to"
+
+  validation {
+    condition     = can(regex("^[a-z]{2}(-gov)?-[a-z]+-\\d$", var.aws_region))
+    error_message = "aws_region must be an AWS region such as us-east-1."
+  }
+}
+variable "artifacts_bucket" {
+  type        = string
+  description = "S3 bucket holding uploaded package artifacts"
+
+  validation {
+    condition     = can(regex("^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$", var.artifacts_bucket))
+    error_message = "artifacts_bucket must be a valid S3 bucket name."
+  }
+}
 variable "image_tag" {
   type        = string
   default     = "latest"
@@ -9,11 +25,11 @@ variable "aws_account_id" {
   type        = string
   description = "AWS account ID"
   default     = "838693051036"
-}
-variable "kms_key_arn" {
-  type        = string
-  description = "KMS key ARN for encryption (optional, will look up via alias if not provided)"
-  default     = ""
+
+  validation {
+    condition     = can(regex("^\\d{12}$", var.aws_account_id))
+    error_message = "aws_account_id must be a 12-digit AWS account ID."
+  }
 }
 variable "github_token" {
   type        = string
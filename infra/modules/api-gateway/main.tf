@@ -8,6 +8,11 @@ variable "aws_region" {
 variable "kms_key_arn" {
   type        = string
   description = "KMS key ARN for S3 encryption"
+
+  validation {
+    condition     = can(regex("^arn:aws[a-z-]*:kms:", var.kms_key_arn))
+    error_message = "kms_key_arn must be a KMS key ARN."
+  }
 }
 
 # API Gateway
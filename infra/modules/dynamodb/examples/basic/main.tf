@@ -0,0 +1,11 @@
+# The registry tables on their own.
+
+provider "aws" {
+  region = "us-east-1"
+}
+
+module "dynamodb" {
+  source = "../.."
+}
+
+output "arn_map" { value = module.dynamodb.arn_map }
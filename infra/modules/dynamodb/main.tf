@@ -90,6 +90,12 @@ resource "aws_dynamodb_table" "this" {
     enabled        = try(each.value.ttl_attr != null, false)
     attribute_name = try(each.value.ttl_attr, null)
   }
+
+  # Encrypt with the AWS managed key rather than the default AWS owned one,
+  # so key use shows in CloudTrail.
+  server_side_encryption {
+    enabled = true
+  }
 }
 
 output "arn_map" { value = { for k, t in aws_dynamodb_table.this : k => t.arn } }
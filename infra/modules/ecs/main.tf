@@ -134,7 +134,7 @@ resource "aws_ecs_service" "validator_service" {
 
   network_configuration {
     subnets          = [aws_subnet.validator_subnet_1.id, aws_subnet.validator_subnet_2.id]
-    security_groups  = [aws_security_group.validator_sg.id]
+    security_groups  = [aws_security_group.validator_task_sg.id]
     assign_public_ip = true
   }
 
@@ -253,7 +253,7 @@ resource "aws_route_table_association" "validator_rta_2" {
   route_table_id = aws_route_table.validator_rt.id
 }
 
-# Security Group
+# Security Group of the load balancer, the only way in from the internet
 resource "aws_security_group" "validator_sg" {
   name_prefix = "validator-sg"
   vpc_id      = aws_vpc.validator_vpc.id
@@ -265,13 +265,30 @@ resource "aws_security_group" "validator_sg" {
     cidr_blocks = ["0.0.0.0/0"]
   }
 
-  ingress {
-    from_port   = 3000
-    to_port     = 3000
-    protocol    = "tcp"
+  egress {
+    from_port   = 0
+    to_port     = 0
+    protocol    = "-1"
     cidr_blocks = ["0.0.0.0/0"]
   }
 
+  tags = {
+    Name = "validator-sg"
+  }
+}
+
+# Security Group of the tasks, reachable only through the load balancer
+resource "aws_security_group" "validator_task_sg" {
+  name_prefix = "validator-task-sg"
+  vpc_id      = aws_vpc.validator_vpc.id
+
+  ingress {
+    from_port       = 3000
+    to_port         = 3000
+    protocol        = "tcp"
+    security_groups = [aws_security_group.validator_sg.id]
+  }
+
   egress {
     from_port   = 0
     to_port     = 0
@@ -280,7 +297,7 @@ resource "aws_security_group" "validator_sg" {
   }
 
   tags = {
-    Name = "validator-sg"
+    Name = "validator-task-sg"
   }
 }
 
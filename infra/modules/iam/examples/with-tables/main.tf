@@ -0,0 +1,18 @@
+# The project policy granting access to an artifacts bucket and the registry
+# tables, wired the way infra/envs does it.
+
+provider "aws" {
+  region = "us-east-1"
+}
+
+module "dynamodb" {
+  source = "../../../dynamodb"
+}
+
+module "iam" {
+  source            = "../.."
+  artifacts_bucket  = "cs450-example-artifacts"
+  ddb_tables_arnmap = module.dynamodb.arn_map
+}
+
+output "group106_policy_arn" { value = module.iam.group106_policy_arn }
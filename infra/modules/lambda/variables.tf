@@ -9,6 +9,11 @@ variable "aws_region" {
   type        = string
   description = "AWS region"
   default     = "us-east-1"
+
+  validation {
+    condition     = can(regex("^[a-z]{2}(-gov)?-[a-z]+-\\d$", var.aws_region))
+    error_message = "aws_region must be an AWS region such as us-east-1."
+  }
 }
 
 variable "lambda_function_name" {
@@ -0,0 +1,13 @@
+# Artifacts bucket with S3-managed encryption.
+
+provider "aws" {
+  region = "us-east-1"
+}
+
+module "s3" {
+  source         = "../.."
+  artifacts_name = "cs450-example-artifacts"
+}
+
+output "artifacts_bucket" { value = module.s3.artifacts_bucket }
+output "access_point_arn" { value = module.s3.access_point_arn }
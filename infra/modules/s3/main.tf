@@ -14,6 +14,23 @@ resource "aws_s3_bucket_server_side_encryption_configuration" "this" {
   }
 }
 
+resource "aws_s3_bucket_versioning" "this" {
+  bucket = aws_s3_bucket.artifacts.id
+  versioning_configuration {
+    status = "Enabled"
+  }
+}
+
+# Access point policies granting named roles are not public, so blocking
+# public policies on the bucket does not stop them attaching.
+resource "aws_s3_bucket_public_access_block" "this" {
+  bucket                  = aws_s3_bucket.artifacts.id
+  block_public_acls       = true
+  block_public_policy     = true
+  ignore_public_acls      = true
+  restrict_public_buckets = true
+}
+
 # Access point DEFINITION ONLY
 resource "aws_s3_access_point" "main" {
   name   = "cs450-s3"
@@ -1,7 +1,19 @@
-variable "artifacts_name" { type = string }
-variable "kms_key_arn" {
-  type        = string
-  description = "KMS key ARN for S3 bucket encryption"
-  default     = ""
-}
-
+variable "artifacts_name" {
+  type        = string
+  description = "Name of the artifacts bucket"
+
+  validation {
+    condition     = can(regex("^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$", var.artifacts_name))
+    error_message = "artifacts_name must be a valid S3 bucket name."
+  }
+}
+variable "kms_key_arn" {
+  type        = string
+  description = "KMS key ARN for S3 bucket encryption"
+  default     = ""
+
+  validation {
+    condition     = var.kms_key_arn == "" || can(regex("^arn:aws[a-z-]*:kms:", var.kms_key_arn))
+    error_message = "kms_key_arn must be empty or a KMS key ARN."
+  }
+}
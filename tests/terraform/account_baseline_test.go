@@ -0,0 +1,46 @@
+package terraformtests
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/awsauth"
+	"cs450/terraformtests/livestate"
+	"cs450/terraformtests/plancheck"
+)
+
+// Account-wide settings such as the S3 public access block are not planned
+// with the environment, so they are read from the live account and judged
+// like a plan that managed them.
+func TestAccountBaselineIsEnforced(t *testing.T) {
+	if os.Getenv(postApplyEnv) == "" {
+		t.Skipf("set %s=1 to check the live account settings", postApplyEnv)
+	}
+
+	config, err := plancheck.LoadConfig(complianceFile)
+	require.NoError(t, err)
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		preflight, err := awsauth.NewPreflight(env.Region, creds.AWS())
+		require.NoError(t, err)
+		identity, err := preflight.Check(context.Background())
+		require.NoError(t, err)
+		runFindings.Identify(env.Name, identity.String())
+
+		clients, err := livestate.NewClients(env.Region, creds.AWS())
+		require.NoError(t, err)
+		live, err := clients.AccountBaseline(identity.Account)
+		require.NoError(t, err)
+
+		findings := plancheck.Evaluate(
+			&plancheck.Input{Plan: live, DefaultRegion: env.Region, Environment: env.Name, Config: config},
+			requireRules(t, "account.baseline")...,
+		)
+		requireNoFindings(t, live, findings)
+	})
+}
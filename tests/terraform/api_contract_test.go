@@ -0,0 +1,53 @@
+package terraformtests
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/apicontract"
+)
+
+const (
+	// postApplyEnv enables the tests that inspect deployed infrastructure
+	// rather than the plan; they need AWS credentials and applied state.
+	postApplyEnv = "COMPLIANCE_POST_APPLY"
+
+	apiSpecFile = "../../docs/ece461_fall_2025_openapi_spec (2).yaml"
+)
+
+func TestDeployedAPIMatchesSpec(t *testing.T) {
+	if os.Getenv(postApplyEnv) == "" {
+		t.Skipf("set %s=1 to compare the deployed API with %s", postApplyEnv, apiSpecFile)
+	}
+
+	document, err := os.ReadFile(apiSpecFile)
+	require.NoError(t, err)
+	spec, err := apicontract.Operations(document)
+	require.NoError(t, err, "API spec must be an OpenAPI document")
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		options := environmentOptions(t, env)
+		options.EnvVars = creds.Env()
+		terraform.Init(t, options)
+		stage, err := apicontract.ParseInvokeURL(terraform.OutputRequired(t, options, "api_gateway_url"))
+		require.NoError(t, err)
+
+		export, err := apicontract.Export(stage, creds.AWS())
+		require.NoError(t, err)
+		deployed, err := apicontract.Operations(export)
+		require.NoError(t, err, "API Gateway export must be an OpenAPI document")
+
+		drift := apicontract.Compare(spec, deployed)
+		for _, operation := range drift.Missing {
+			t.Errorf("%s is in %s but not deployed to stage %s", operation, apiSpecFile, stage.Name)
+		}
+		for _, operation := range drift.Undocumented {
+			t.Errorf("%s is deployed to stage %s but missing from %s", operation, stage.Name, apiSpecFile)
+		}
+	})
+}
@@ -0,0 +1,13 @@
+package terraformtests
+
+import (
+	"testing"
+)
+
+// The registry API is internet-facing. Every method needs an authorizer
+// unless compliance.yaml lists its route under api.public_routes, and every
+// stage needs throttling limits and access logs. The dev API authorizes in its
+// handlers and sets no throttling limits yet, so this fails until it does.
+func TestAPIGatewayRequiresAuthorizationAndThrottling(t *testing.T) {
+	requireCompliance(t, "apigateway.authorization", "apigateway.throttling", "apigateway.access-logs")
+}
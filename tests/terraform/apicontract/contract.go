@@ -0,0 +1,175 @@
+// Package apicontract compares the operations of a deployed API Gateway REST
+// API, as exported in OpenAPI form, with the OpenAPI spec kept in the repo.
+package apicontract
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigateway"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"gopkg.in/yaml.v3"
+
+	"cs450/terraformtests/awsapi"
+)
+
+// Operation is an HTTP method on a path template. Path parameters are
+// normalised to "{}" so /artifacts/{id} and /artifacts/{artifact_id} match.
+type Operation struct {
+	Method string
+	Path   string
+}
+
+func (o Operation) String() string {
+	return o.Method + " " + o.Path
+}
+
+// anyMethod is how API Gateway exports an ANY method.
+const anyMethod = "X-AMAZON-APIGATEWAY-ANY-METHOD"
+
+var (
+	methods = map[string]bool{
+		"GET": true, "PUT": true, "POST": true, "DELETE": true,
+		"PATCH": true, "HEAD": true, "OPTIONS": true, anyMethod: true,
+	}
+	pathParameter = regexp.MustCompile(`\{[^}]*\}`)
+)
+
+// Operations parses an OpenAPI 2 or 3 document, in YAML or JSON, and returns
+// its operations sorted by path and method.
+func Operations(document []byte) ([]Operation, error) {
+	var spec struct {
+		Paths map[string]map[string]interface{} `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(document, &spec); err != nil {
+		return nil, fmt.Errorf("apicontract: %w", err)
+	}
+	if len(spec.Paths) == 0 {
+		return nil, fmt.Errorf("apicontract: document has no paths")
+	}
+
+	var operations []Operation
+	for path, item := range spec.Paths {
+		for method := range item {
+			method = strings.ToUpper(method)
+			if !methods[method] {
+				continue // parameters, summary, servers...
+			}
+			if method == anyMethod {
+				method = "ANY"
+			}
+			operations = append(operations, Operation{method, pathParameter.ReplaceAllString(path, "{}")})
+		}
+	}
+	sortOperations(operations)
+	return operations, nil
+}
+
+// Drift is the difference between the spec and the deployed API.
+type Drift struct {
+	// Missing operations are in the spec but not deployed.
+	Missing []Operation
+	// Undocumented operations are deployed but not in the spec.
+	Undocumented []Operation
+}
+
+// Empty reports whether the deployed API matches the spec.
+func (d Drift) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Undocumented) == 0
+}
+
+// Compare returns the drift between the spec and the deployed operations. A
+// deployed ANY method covers every spec operation on its path, and deployed
+// OPTIONS methods, which API Gateway adds for CORS, need not be documented.
+func Compare(spec, deployed []Operation) Drift {
+	documented := map[Operation]bool{}
+	for _, operation := range spec {
+		documented[operation] = true
+	}
+	served := map[Operation]bool{}
+	for _, operation := range deployed {
+		served[operation] = true
+	}
+
+	var drift Drift
+	for _, operation := range spec {
+		if !served[operation] && !served[Operation{"ANY", operation.Path}] {
+			drift.Missing = append(drift.Missing, operation)
+		}
+	}
+	for _, operation := range deployed {
+		if documented[operation] || operation.Method == "OPTIONS" {
+			continue
+		}
+		if operation.Method == "ANY" && documentsPath(spec, operation.Path) {
+			continue
+		}
+		drift.Undocumented = append(drift.Undocumented, operation)
+	}
+	return drift
+}
+
+func documentsPath(spec []Operation, path string) bool {
+	for _, operation := range spec {
+		if operation.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Stage identifies a deployed REST API stage.
+type Stage struct {
+	RestAPIID string
+	Region    string
+	Name      string
+}
+
+// ParseInvokeURL splits a stage invoke URL such as
+// https://abc123.execute-api.us-east-1.amazonaws.com/prod.
+func ParseInvokeURL(invokeURL string) (Stage, error) {
+	u, err := url.Parse(invokeURL)
+	if err != nil {
+		return Stage{}, fmt.Errorf("apicontract: %w", err)
+	}
+	host := strings.Split(u.Hostname(), ".")
+	stage := strings.Trim(u.Path, "/")
+	if len(host) < 4 || host[1] != "execute-api" || stage == "" || strings.Contains(stage, "/") {
+		return Stage{}, fmt.Errorf("apicontract: %q is not an API Gateway stage invoke URL", invokeURL)
+	}
+	return Stage{RestAPIID: host[0], Region: host[2], Name: stage}, nil
+}
+
+// Export downloads the OpenAPI 3 definition of a deployed stage using creds,
+// or the default AWS credential chain when creds is nil.
+func Export(stage Stage, creds *credentials.Credentials) ([]byte, error) {
+	ctx := context.Background()
+	cfg, err := awsapi.Default().WithCredentials(creds).Config(ctx, stage.Region)
+	if err != nil {
+		return nil, fmt.Errorf("apicontract: %w", err)
+	}
+	out, err := apigateway.NewFromConfig(cfg).GetExport(ctx, &apigateway.GetExportInput{
+		RestApiId:  aws.String(stage.RestAPIID),
+		StageName:  aws.String(stage.Name),
+		ExportType: aws.String("oas30"),
+		Accepts:    aws.String("application/json"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("apicontract: exporting %s stage %s: %w", stage.RestAPIID, stage.Name, err)
+	}
+	return out.Body, nil
+}
+
+func sortOperations(operations []Operation) {
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].Path != operations[j].Path {
+			return operations[i].Path < operations[j].Path
+		}
+		return operations[i].Method < operations[j].Method
+	})
+}
@@ -0,0 +1,74 @@
+package apicontract
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const specYAML = `
+openapi: 3.0.2
+paths:
+  /health:
+    get: {}
+  /artifacts/{artifact_type}/{id}:
+    parameters: []
+    get: {}
+    delete: {}
+  /reset:
+    delete: {}
+`
+
+// exportJSON is shaped like an API Gateway oas30 export.
+const exportJSON = `{
+  "openapi": "3.0.1",
+  "paths": {
+    "/health": {"get": {}, "options": {}},
+    "/artifacts/{type}/{artifact_id}": {"get": {}},
+    "/reset": {"x-amazon-apigateway-any-method": {}},
+    "/debug": {"get": {}}
+  }
+}`
+
+func TestOperationsNormalisesPathParameters(t *testing.T) {
+	operations, err := Operations([]byte(specYAML))
+	require.NoError(t, err)
+	require.Equal(t, []Operation{
+		{"DELETE", "/artifacts/{}/{}"},
+		{"GET", "/artifacts/{}/{}"},
+		{"GET", "/health"},
+		{"DELETE", "/reset"},
+	}, operations)
+
+	_, err = Operations([]byte("openapi: 3.0.2\n"))
+	require.Error(t, err)
+}
+
+func TestCompareReportsDriftBothWays(t *testing.T) {
+	spec, err := Operations([]byte(specYAML))
+	require.NoError(t, err)
+	deployed, err := Operations([]byte(exportJSON))
+	require.NoError(t, err)
+
+	drift := Compare(spec, deployed)
+	require.False(t, drift.Empty())
+	require.Equal(t, []Operation{{"DELETE", "/artifacts/{}/{}"}}, drift.Missing)
+	require.Equal(t, []Operation{{"GET", "/debug"}}, drift.Undocumented)
+
+	require.True(t, Compare(spec, spec).Empty())
+}
+
+func TestParseInvokeURL(t *testing.T) {
+	stage, err := ParseInvokeURL("https://abc123.execute-api.us-east-1.amazonaws.com/prod")
+	require.NoError(t, err)
+	require.Equal(t, Stage{RestAPIID: "abc123", Region: "us-east-1", Name: "prod"}, stage)
+
+	for _, invalid := range []string{
+		"https://d111111abcdef8.cloudfront.net/prod",
+		"https://abc123.execute-api.us-east-1.amazonaws.com/",
+		"https://abc123.execute-api.us-east-1.amazonaws.com/prod/health",
+	} {
+		_, err := ParseInvokeURL(invalid)
+		require.Errorf(t, err, "%s", invalid)
+	}
+}
@@ -0,0 +1,149 @@
+// Package awsapi is the shared layer the live checks call AWS through. It
+// loads one AWS SDK v2 configuration per region with config.LoadDefaultConfig,
+// so the region, shared-config profile, role and endpoint are resolved in one
+// place. Requests retry throttled and failed attempts with exponential backoff
+// and draw every attempt from one client-side rate limit, and its Cache runs
+// identical read requests once, so a large suite does not get throttled.
+//
+// The awsauth package, which issues the credentials the checks sign with,
+// still builds its clients from Session, an aws-sdk-go v1 session that signs
+// with the v2 configuration's credentials and follows the same retry and
+// rate limits.
+package awsapi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// RateEnv overrides the requests per second all sessions of the process may
+// send together, e.g. "5". The default is DefaultRate.
+const RateEnv = "COMPLIANCE_AWS_RATE"
+
+const (
+	// DefaultRate and DefaultBurst keep a suite well under the per-account
+	// limits of the control-plane APIs the checks call.
+	DefaultRate  = 10
+	DefaultBurst = 5
+
+	// DefaultMaxRetries is how often a throttled or failed request is retried.
+	DefaultMaxRetries = 8
+)
+
+// DefaultLimiter is shared by every session that does not bring its own.
+var DefaultLimiter = NewLimiter(rateFromEnv(), DefaultBurst)
+
+// DefaultCache is shared by the live checks of a test binary.
+var DefaultCache = &Cache{}
+
+// Options configure a session.
+type Options struct {
+	Region string
+	// Credentials default to the AWS credential chain.
+	Credentials *credentials.Credentials
+	// Profile selects a profile of the shared AWS config files instead of
+	// AWS_PROFILE.
+	Profile string
+	// RoleARN, when set, is assumed with the credentials above and the
+	// session uses the temporary credentials.
+	RoleARN string
+	// Endpoint sends every request to one URL, e.g. LocalStack's
+	// http://localhost:4566, with path-style S3 addressing.
+	Endpoint string
+	// MaxRetries defaults to DefaultMaxRetries.
+	MaxRetries int
+	// Limiter defaults to DefaultLimiter.
+	Limiter *Limiter
+}
+
+// LoadConfig returns the AWS SDK v2 configuration opts describe, with
+// backoff retries and rate limiting. S3 clients built from it take
+// S3PathStyle, for opts.Endpoint.
+func LoadConfig(ctx context.Context, opts Options) (aws.Config, error) {
+	retries := opts.MaxRetries
+	if retries == 0 {
+		retries = DefaultMaxRetries
+	}
+	loads := []func(*config.LoadOptions) error{
+		config.WithRegion(opts.Region),
+		config.WithRetryer(func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.MaxAttempts = retries + 1
+				o.MaxBackoff = 20 * time.Second
+				// The Limiter paces every attempt instead.
+				o.RateLimiter = ratelimit.None
+			})
+		}),
+		config.WithAPIOptions([]func(*middleware.Stack) error{limitAttempts(opts.Limiter)}),
+	}
+	if opts.Profile != "" {
+		loads = append(loads, config.WithSharedConfigProfile(opts.Profile))
+	}
+	if opts.Credentials != nil {
+		loads = append(loads, config.WithCredentialsProvider(fromV1{opts.Credentials}))
+	}
+	if opts.Endpoint != "" {
+		loads = append(loads, config.WithBaseEndpoint(opts.Endpoint))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, loads...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("awsapi: %w", err)
+	}
+	if opts.RoleARN != "" {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), opts.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = "tfcompliance"
+		}))
+	}
+	return cfg, nil
+}
+
+// S3PathStyle addresses buckets by path rather than by host name when cfg
+// sends every request to one endpoint, which LocalStack needs:
+//
+//	s3.NewFromConfig(cfg, awsapi.S3PathStyle(cfg))
+func S3PathStyle(cfg aws.Config) func(*s3.Options) {
+	return func(o *s3.Options) {
+		o.UsePathStyle = cfg.BaseEndpoint != nil
+	}
+}
+
+// limitAttempts makes every attempt of a request, retries included, wait
+// for limiter, or DefaultLimiter when it is nil.
+func limitAttempts(limiter *Limiter) func(*middleware.Stack) error {
+	if limiter == nil {
+		limiter = DefaultLimiter
+	}
+	limit := middleware.FinalizeMiddlewareFunc("awsapi.Limit", func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+		if err := limiter.Wait(ctx); err != nil {
+			return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+		}
+		return next.HandleFinalize(ctx, in)
+	})
+	return func(stack *middleware.Stack) error {
+		// The retry middleware runs the rest of the step once per attempt.
+		if err := stack.Finalize.Insert(limit, "Retry", middleware.After); err != nil {
+			return stack.Finalize.Add(limit, middleware.After)
+		}
+		return nil
+	}
+}
+
+func rateFromEnv() float64 {
+	if rate, err := strconv.ParseFloat(os.Getenv(RateEnv), 64); err == nil && rate > 0 {
+		return rate
+	}
+	return DefaultRate
+}
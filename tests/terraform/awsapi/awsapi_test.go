@@ -0,0 +1,167 @@
+package awsapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	stsv2 "github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterAllowsBurstThenPaces(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := NewLimiter(2, 2)
+	limiter.now = func() time.Time { return now }
+
+	require.Zero(t, limiter.reserve())
+	require.Zero(t, limiter.reserve())
+	require.Equal(t, 500*time.Millisecond, limiter.reserve())
+	require.Equal(t, time.Second, limiter.reserve(), "queued callers wait their own turn")
+
+	now = now.Add(10 * time.Second)
+	require.Zero(t, limiter.reserve(), "tokens refill up to the burst")
+	require.Zero(t, limiter.reserve())
+	require.Equal(t, 500*time.Millisecond, limiter.reserve())
+}
+
+func TestLimiterWaitStopsWithContext(t *testing.T) {
+	limiter := NewLimiter(0.001, 1)
+	require.NoError(t, limiter.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, limiter.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestCacheRunsIdenticalCallsOnce(t *testing.T) {
+	var cache Cache
+	var runs int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&runs, 1)
+		<-release
+		return "policy", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cache.Do("s3.GetBucketPolicy {\"Bucket\":\"state\"}", fn)
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.EqualValues(t, 1, runs)
+	for _, result := range results {
+		require.Equal(t, "policy", result)
+	}
+	value, err := cache.Do("s3.GetBucketPolicy {\"Bucket\":\"state\"}", fn)
+	require.NoError(t, err)
+	require.Equal(t, "policy", value)
+	require.EqualValues(t, 1, runs, "later callers get the cached result")
+}
+
+func TestCacheRetriesFailedCalls(t *testing.T) {
+	var cache Cache
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		if calls == 1 {
+			return nil, context.DeadlineExceeded
+		}
+		return "ok", nil
+	}
+
+	_, err := cache.Do("key", fn)
+	require.Error(t, err)
+	value, err := cache.Do("key", fn)
+	require.NoError(t, err)
+	require.Equal(t, "ok", value)
+
+	var none *Cache
+	value, err = none.Do("key", fn)
+	require.NoError(t, err)
+	require.Equal(t, "ok", value)
+	require.Equal(t, 3, calls)
+}
+
+func TestKeyDistinguishesInputs(t *testing.T) {
+	a := Key("sts.AssumeRole", &sts.AssumeRoleInput{RoleArn: aws.String("arn:aws:iam::1:role/a")})
+	b := Key("sts.AssumeRole", &sts.AssumeRoleInput{RoleArn: aws.String("arn:aws:iam::1:role/b")})
+	require.NotEqual(t, a, b)
+	require.Equal(t, a, Key("sts.AssumeRole", &sts.AssumeRoleInput{RoleArn: aws.String("arn:aws:iam::1:role/a")}))
+}
+
+func TestSessionRetriesThrottledRequestsThroughTheLimiter(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Code>Throttling</Code><Message>Rate exceeded</Message></Error></ErrorResponse>`))
+			return
+		}
+		w.Write([]byte(`<GetCallerIdentityResponse><GetCallerIdentityResult><Account>123456789012</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`))
+	}))
+	defer server.Close()
+
+	limiter := NewLimiter(1000, 1)
+	var waits int32
+	now := limiter.now
+	limiter.now = func() time.Time { atomic.AddInt32(&waits, 1); return now() }
+
+	sess, err := NewSession(Options{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Limiter:     limiter,
+	})
+	require.NoError(t, err)
+	out, err := sts.New(sess, &aws.Config{Endpoint: aws.String(server.URL)}).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	require.NoError(t, err)
+	require.Equal(t, "123456789012", aws.StringValue(out.Account))
+	require.EqualValues(t, 2, requests, "the throttled request is retried")
+	require.EqualValues(t, 2, waits, "every attempt waits for the limiter")
+}
+
+func TestConfigRetriesThrottledRequestsThroughTheLimiter(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Code>Throttling</Code><Message>Rate exceeded</Message></Error></ErrorResponse>`))
+			return
+		}
+		w.Write([]byte(`<GetCallerIdentityResponse><GetCallerIdentityResult><Account>123456789012</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`))
+	}))
+	defer server.Close()
+
+	limiter := NewLimiter(1000, 1)
+	var waits int32
+	now := limiter.now
+	limiter.now = func() time.Time { atomic.AddInt32(&waits, 1); return now() }
+
+	cfg, err := LoadConfig(context.Background(), Options{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Endpoint:    server.URL,
+		Limiter:     limiter,
+	})
+	require.NoError(t, err)
+	out, err := stsv2.NewFromConfig(cfg).GetCallerIdentity(context.Background(), &stsv2.GetCallerIdentityInput{})
+	require.NoError(t, err)
+	require.Equal(t, "123456789012", aws.StringValue(out.Account))
+	require.EqualValues(t, 2, requests, "the throttled request is retried")
+	require.EqualValues(t, 2, waits, "every attempt waits for the limiter")
+}
@@ -0,0 +1,61 @@
+package awsapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Cache runs each read once: concurrent callers with the same key wait for
+// the first one, and later callers get its result. Failed calls are not
+// kept, so they are tried again. A nil Cache runs every call.
+type Cache struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// Do returns the result of fn for key, running it only if no result is
+// cached or in flight.
+func (c *Cache) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	if c == nil {
+		return fn()
+	}
+
+	c.mu.Lock()
+	if c.calls == nil {
+		c.calls = map[string]*call{}
+	}
+	if existing, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		<-existing.done
+		return existing.value, existing.err
+	}
+	current := &call{done: make(chan struct{})}
+	c.calls[key] = current
+	c.mu.Unlock()
+
+	current.value, current.err = fn()
+	if current.err != nil {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+	}
+	close(current.done)
+	return current.value, current.err
+}
+
+// Key identifies a request by its operation and input, e.g.
+// Key("s3.GetBucketPolicy", input).
+func Key(operation string, input interface{}) string {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return operation + " " + fmt.Sprint(input)
+	}
+	return operation + " " + string(data)
+}
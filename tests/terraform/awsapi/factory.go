@@ -0,0 +1,115 @@
+package awsapi
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// EndpointEnv points every client of the default factory at one endpoint,
+// such as LocalStack. It is the variable the newer AWS SDKs and CLI read.
+const EndpointEnv = "AWS_ENDPOINT_URL"
+
+// Factory hands out the configurations and sessions live checks build their
+// clients from, one per region, so profile, role and endpoint overrides are
+// set in one place.
+type Factory struct {
+	opts Options
+
+	mu       sync.Mutex
+	configs  map[string]aws.Config
+	sessions map[string]*session.Session
+}
+
+// NewFactory returns a factory whose configurations use opts. opts.Region is
+// the region of Config and Session for "".
+func NewFactory(opts Options) *Factory {
+	return &Factory{opts: opts}
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultFactory *Factory
+)
+
+// Default returns the process-wide factory, configured from EndpointEnv and
+// the AWS_PROFILE and credential variables the SDK reads itself.
+func Default() *Factory {
+	defaultOnce.Do(func() {
+		defaultFactory = NewFactory(Options{Endpoint: os.Getenv(EndpointEnv)})
+	})
+	return defaultFactory
+}
+
+// Config returns the factory's AWS SDK v2 configuration for region.
+func (f *Factory) Config(ctx context.Context, region string) (aws.Config, error) {
+	if region == "" {
+		region = f.opts.Region
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.config(ctx, region)
+}
+
+func (f *Factory) config(ctx context.Context, region string) (aws.Config, error) {
+	if cfg, ok := f.configs[region]; ok {
+		return cfg, nil
+	}
+	opts := f.opts
+	opts.Region = region
+	cfg, err := LoadConfig(ctx, opts)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	if f.configs == nil {
+		f.configs = map[string]aws.Config{}
+	}
+	f.configs[region] = cfg
+	return cfg, nil
+}
+
+// Session returns an aws-sdk-go v1 session for region built from the
+// factory's configuration, for the awsauth clients not yet built from
+// Config.
+func (f *Factory) Session(region string) (*session.Session, error) {
+	if region == "" {
+		region = f.opts.Region
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if sess, ok := f.sessions[region]; ok {
+		return sess, nil
+	}
+	cfg, err := f.config(context.Background(), region)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := sessionFor(cfg, f.opts)
+	if err != nil {
+		return nil, err
+	}
+	if f.sessions == nil {
+		f.sessions = map[string]*session.Session{}
+	}
+	f.sessions[region] = sess
+	return sess, nil
+}
+
+// WithCredentials returns a factory like f that signs with creds, e.g. the
+// temporary credentials of an environment's role. Nil creds return f.
+func (f *Factory) WithCredentials(creds *credentials.Credentials) *Factory {
+	if creds == nil {
+		return f
+	}
+	opts := f.opts
+	opts.Credentials = creds
+	opts.Profile = ""
+	opts.RoleARN = ""
+	return NewFactory(opts)
+}
@@ -0,0 +1,116 @@
+package awsapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	stsv2 "github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSTS answers AssumeRole and GetCallerIdentity and records the access key
+// each request was signed with.
+func fakeSTS(t *testing.T) (*httptest.Server, func() []string) {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		form, _ := url.ParseQuery(string(body))
+		credential := r.Header.Get("Authorization")
+		credential = credential[strings.Index(credential, "Credential=")+len("Credential="):]
+		mu.Lock()
+		keys = append(keys, credential[:strings.Index(credential, "/")])
+		mu.Unlock()
+
+		switch form.Get("Action") {
+		case "AssumeRole":
+			w.Write([]byte(`<AssumeRoleResponse><AssumeRoleResult><Credentials>
+<AccessKeyId>ASIATEMP</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken>
+<Expiration>2099-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`))
+		default:
+			w.Write([]byte(`<GetCallerIdentityResponse><GetCallerIdentityResult><Account>000000000000</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), keys...)
+	}
+}
+
+func TestFactorySendsRequestsToEndpoint(t *testing.T) {
+	server, keys := fakeSTS(t)
+	factory := NewFactory(Options{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentials("AKIDBASE", "secret", ""),
+		Endpoint:    server.URL,
+	})
+
+	sess, err := factory.Session("")
+	require.NoError(t, err)
+	same, err := factory.Session("us-east-1")
+	require.NoError(t, err)
+	require.Same(t, sess, same, "sessions are reused per region")
+	require.Equal(t, "us-east-1", aws.StringValue(sess.Config.Region))
+	require.True(t, aws.BoolValue(sess.Config.S3ForcePathStyle))
+
+	out, err := sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	require.NoError(t, err)
+	require.Equal(t, "000000000000", aws.StringValue(out.Account))
+	require.Equal(t, []string{"AKIDBASE"}, keys())
+
+	require.Same(t, factory, factory.WithCredentials(nil))
+	other, err := factory.WithCredentials(credentials.NewStaticCredentials("AKIDENV", "secret", "")).Session("")
+	require.NoError(t, err)
+	_, err = sts.New(other).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"AKIDBASE", "AKIDENV"}, keys())
+}
+
+func TestFactoryAssumesRole(t *testing.T) {
+	server, keys := fakeSTS(t)
+	sess, err := NewFactory(Options{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentials("AKIDBASE", "secret", ""),
+		RoleARN:     "arn:aws:iam::000000000000:role/tfcompliance-plan",
+		Endpoint:    server.URL,
+	}).Session("")
+	require.NoError(t, err)
+
+	_, err = sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"AKIDBASE", "ASIATEMP"}, keys(), "the role is assumed with the base credentials")
+}
+
+func TestFactoryConfigSharesTheSessionsCredentials(t *testing.T) {
+	server, keys := fakeSTS(t)
+	factory := NewFactory(Options{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentials("AKIDBASE", "secret", ""),
+		RoleARN:     "arn:aws:iam::000000000000:role/tfcompliance-plan",
+		Endpoint:    server.URL,
+	})
+	cfg, err := factory.Config(context.Background(), "")
+	require.NoError(t, err)
+	require.Equal(t, "us-east-1", cfg.Region)
+	_, err = stsv2.NewFromConfig(cfg).GetCallerIdentity(context.Background(), &stsv2.GetCallerIdentityInput{})
+	require.NoError(t, err)
+
+	sess, err := factory.Session("")
+	require.NoError(t, err)
+	_, err = sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"AKIDBASE", "ASIATEMP", "ASIATEMP"}, keys(), "the session reuses the role credentials the configuration assumed")
+}
@@ -0,0 +1,27 @@
+package awsapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Poll calls done every interval until it reports true or fails, for
+// resources that settle asynchronously, such as a restoring table. It gives
+// up when ctx is done, naming what it waited for.
+func Poll(ctx context.Context, interval time.Duration, what string, done func() (bool, error)) error {
+	for {
+		ok, err := done()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up waiting for %s: %w", what, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package awsapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollWaitsUntilDoneOrGivesUp(t *testing.T) {
+	calls := 0
+	err := Poll(context.Background(), time.Millisecond, "the table", func() (bool, error) {
+		calls++
+		return calls == 3, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	failed := errors.New("table deleted")
+	require.Equal(t, failed, Poll(context.Background(), time.Millisecond, "the table", func() (bool, error) {
+		return false, failed
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = Poll(ctx, time.Millisecond, "the table", func() (bool, error) { return false, nil })
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, "gave up waiting for the table")
+}
@@ -0,0 +1,132 @@
+package awsapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// NewSession returns an aws-sdk-go v1 session for the configuration opts
+// describe, with backoff retries and rate limiting.
+func NewSession(opts Options) (*session.Session, error) {
+	cfg, err := LoadConfig(context.Background(), opts)
+	if err != nil {
+		return nil, err
+	}
+	return sessionFor(cfg, opts)
+}
+
+// sessionFor returns a v1 session signing with cfg's credentials, in its
+// region and against its endpoint, so both SDKs resolve the profile, role
+// and endpoint the same way.
+func sessionFor(cfg awsv2.Config, opts Options) (*session.Session, error) {
+	retries := opts.MaxRetries
+	if retries == 0 {
+		retries = DefaultMaxRetries
+	}
+	creds := opts.Credentials
+	if creds == nil || opts.RoleARN != "" {
+		creds = credentials.NewCredentials(&fromV2{provider: cfg.Credentials})
+	}
+	config := request.WithRetryer(&aws.Config{
+		Region:      aws.String(cfg.Region),
+		Credentials: creds,
+	}, client.DefaultRetryer{
+		NumMaxRetries:    retries,
+		MinRetryDelay:    100 * time.Millisecond,
+		MaxRetryDelay:    5 * time.Second,
+		MinThrottleDelay: 500 * time.Millisecond,
+		MaxThrottleDelay: 20 * time.Second,
+	})
+	if cfg.BaseEndpoint != nil {
+		config.Endpoint = aws.String(*cfg.BaseEndpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigDisable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("awsapi: %w", err)
+	}
+	Limit(sess, opts.Limiter)
+	return sess, nil
+}
+
+// Limit makes every request attempt of clients built from sess wait for
+// limiter, or DefaultLimiter when it is nil.
+func Limit(sess *session.Session, limiter *Limiter) {
+	if limiter == nil {
+		limiter = DefaultLimiter
+	}
+	// Sign runs before every attempt, retries included.
+	sess.Handlers.Sign.PushFrontNamed(request.NamedHandler{
+		Name: "awsapi.Limit",
+		Fn: func(r *request.Request) {
+			if err := limiter.Wait(r.Context()); err != nil {
+				r.Error = err
+			}
+		},
+	})
+}
+
+// fromV1 hands v1 credentials, such as an environment's role credentials,
+// to v2 clients.
+type fromV1 struct {
+	creds *credentials.Credentials
+}
+
+func (p fromV1) Retrieve(ctx context.Context) (awsv2.Credentials, error) {
+	value, err := p.creds.GetWithContext(ctx)
+	if err != nil {
+		return awsv2.Credentials{}, err
+	}
+	expires, err := p.creds.ExpiresAt()
+	return awsv2.Credentials{
+		AccessKeyID:     value.AccessKeyID,
+		SecretAccessKey: value.SecretAccessKey,
+		SessionToken:    value.SessionToken,
+		Source:          value.ProviderName,
+		CanExpire:       err == nil && !expires.IsZero(),
+		Expires:         expires,
+	}, nil
+}
+
+// fromV2 hands the credentials a v2 configuration resolved to v1 clients.
+// The v2 provider caches and renews them itself.
+type fromV2 struct {
+	provider awsv2.CredentialsProvider
+	current  awsv2.Credentials
+}
+
+func (p *fromV2) Retrieve() (credentials.Value, error) {
+	return p.RetrieveWithContext(context.Background())
+}
+
+func (p *fromV2) RetrieveWithContext(ctx credentials.Context) (credentials.Value, error) {
+	if p.provider == nil {
+		return credentials.Value{}, fmt.Errorf("awsapi: no AWS credentials found")
+	}
+	creds, err := p.provider.Retrieve(ctx)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	p.current = creds
+	return credentials.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		ProviderName:    creds.Source,
+	}, nil
+}
+
+func (p *fromV2) IsExpired() bool {
+	return p.current.AccessKeyID == "" || p.current.CanExpire && time.Now().Add(time.Minute).After(p.current.Expires)
+}
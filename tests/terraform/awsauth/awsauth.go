@@ -0,0 +1,220 @@
+// Package awsauth assumes the per-environment roles named in compliance.yaml,
+// so one CI identity can plan and inspect every account.
+package awsauth
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/plancheck"
+)
+
+// DefaultDuration is how long assumed credentials last; a plan of the
+// largest environment finishes well within it.
+const DefaultDuration = time.Hour
+
+// ExpiryWindow is how long before they expire assumed credentials are
+// renewed, so a request signed with them cannot outlive them.
+const ExpiryWindow = 5 * time.Minute
+
+// Credentials are temporary credentials for one environment's role. The
+// fields hold the credentials first assumed; Env and AWS renew them by
+// assuming the role again once they near expiry.
+type Credentials struct {
+	RoleARN         string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+
+	// refreshing renews the credentials; nil for credentials built by hand.
+	refreshing *credentials.Credentials
+}
+
+// Env returns the credentials as the environment variables terraform and
+// its AWS provider read, renewed if they are about to expire. Static
+// credentials in the environment take precedence over AWS_PROFILE and the
+// shared config files. Nil credentials leave the environment alone.
+func (c *Credentials) Env() map[string]string {
+	if c == nil {
+		return nil
+	}
+	value := credentials.Value{AccessKeyID: c.AccessKeyID, SecretAccessKey: c.SecretAccessKey, SessionToken: c.SessionToken}
+	if c.refreshing != nil {
+		// A failed renewal leaves the old credentials, which terraform
+		// then reports as expired.
+		if renewed, err := c.refreshing.Get(); err == nil {
+			value = renewed
+		}
+	}
+	return map[string]string{
+		"AWS_ACCESS_KEY_ID":     value.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY": value.SecretAccessKey,
+		"AWS_SESSION_TOKEN":     value.SessionToken,
+	}
+}
+
+// AWS returns the credentials for an aws-sdk-go client configuration. Clients
+// holding them, such as a run lock's heartbeat, keep working past the first
+// credentials' expiry. Nil credentials return nil, which selects the default
+// credential chain.
+func (c *Credentials) AWS() *credentials.Credentials {
+	if c == nil {
+		return nil
+	}
+	if c.refreshing != nil {
+		return c.refreshing
+	}
+	return credentials.NewStaticCredentials(c.AccessKeyID, c.SecretAccessKey, c.SessionToken)
+}
+
+// assumeFunc assumes one role.
+type assumeFunc func(ctx context.Context) (*Credentials, error)
+
+// withRefresh assumes a role and attaches a provider that assumes it again
+// ExpiryWindow before the credentials expire.
+func withRefresh(ctx context.Context, assume assumeFunc) (*Credentials, error) {
+	creds, err := assume(ctx)
+	if err != nil {
+		return nil, err
+	}
+	creds.refreshing = credentials.NewCredentials(&refresher{assume: assume, next: creds})
+	return creds, nil
+}
+
+// refresher is a credentials.Provider assuming a role whenever the last
+// credentials are about to expire. The credentials.Credentials wrapping it
+// serializes its calls.
+type refresher struct {
+	credentials.Expiry
+	assume assumeFunc
+	// next are credentials already assumed, handed out before assuming
+	// again.
+	next *Credentials
+}
+
+func (r *refresher) Retrieve() (credentials.Value, error) {
+	return r.RetrieveWithContext(context.Background())
+}
+
+func (r *refresher) RetrieveWithContext(ctx credentials.Context) (credentials.Value, error) {
+	creds := r.next
+	r.next = nil
+	if creds == nil {
+		var err error
+		if creds, err = r.assume(ctx); err != nil {
+			return credentials.Value{}, err
+		}
+	}
+	r.SetExpiration(creds.Expiration, ExpiryWindow)
+	return credentials.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		ProviderName:    "tfcompliance",
+	}, nil
+}
+
+// Assumer assumes roles with the caller's own credentials.
+type Assumer struct {
+	STS stsiface.STSAPI
+	// Duration defaults to DefaultDuration.
+	Duration time.Duration
+}
+
+// NewAssumer returns an Assumer calling STS in region with the default
+// credential chain.
+func NewAssumer(region string) (*Assumer, error) {
+	sess, err := awsapi.Default().Session(region)
+	if err != nil {
+		return nil, fmt.Errorf("awsauth: %w", err)
+	}
+	return &Assumer{STS: sts.New(sess)}, nil
+}
+
+var sessionNameUnsafe = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// Assume assumes role for environment. The session is named after the
+// environment so CloudTrail shows which suite acted in the account.
+func (a *Assumer) Assume(ctx context.Context, environment, role string) (*Credentials, error) {
+	return withRefresh(ctx, func(ctx context.Context) (*Credentials, error) {
+		return a.assume(ctx, environment, role)
+	})
+}
+
+func (a *Assumer) assume(ctx context.Context, environment, role string) (*Credentials, error) {
+	duration := a.Duration
+	if duration == 0 {
+		duration = DefaultDuration
+	}
+	out, err := a.STS.AssumeRoleWithContext(ctx, &sts.AssumeRoleInput{
+		RoleArn:         aws.String(role),
+		RoleSessionName: aws.String(sessionName(environment)),
+		DurationSeconds: aws.Int64(int64(duration / time.Second)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("awsauth: assuming %s for %s: %w", role, environment, err)
+	}
+	return fromSTS(environment, role, out.Credentials)
+}
+
+func sessionName(environment string) string {
+	return sessionNameUnsafe.ReplaceAllString("tfcompliance-"+environment, "-")
+}
+
+func fromSTS(environment, role string, creds *sts.Credentials) (*Credentials, error) {
+	if creds == nil {
+		return nil, fmt.Errorf("awsauth: assuming %s for %s returned no credentials", role, environment)
+	}
+	return &Credentials{
+		RoleARN:         role,
+		AccessKeyID:     aws.StringValue(creds.AccessKeyId),
+		SecretAccessKey: aws.StringValue(creds.SecretAccessKey),
+		SessionToken:    aws.StringValue(creds.SessionToken),
+		Expiration:      aws.TimeValue(creds.Expiration),
+	}, nil
+}
+
+// ForEnvironment assumes the role compliance.yaml names for environment with
+// the default credential chain, calling STS in region. It returns nil and no
+// error when the environment names no role.
+//
+// In a GitHub Actions job that may request OIDC tokens, it assumes the role,
+// or backend.ci_role for an environment without one, with a web identity
+// token instead, so CI needs no long-lived credentials.
+func ForEnvironment(ctx context.Context, config *plancheck.Config, environment, region string) (*Credentials, error) {
+	if GitHubOIDC() && webIdentityRole(config, environment) != "" {
+		web, err := NewWebIdentity(region)
+		if err != nil {
+			return nil, err
+		}
+		return web.ForEnvironment(ctx, config, environment)
+	}
+	if config.Environment(environment).Role == "" {
+		return nil, nil
+	}
+	assumer, err := NewAssumer(region)
+	if err != nil {
+		return nil, err
+	}
+	return assumer.ForEnvironment(ctx, config, environment)
+}
+
+// ForEnvironment assumes the role compliance.yaml names for environment. It
+// returns nil and no error when the environment names no role, in which
+// case the caller's own credentials are used.
+func (a *Assumer) ForEnvironment(ctx context.Context, config *plancheck.Config, environment string) (*Credentials, error) {
+	role := config.Environment(environment).Role
+	if role == "" {
+		return nil, nil
+	}
+	return a.Assume(ctx, environment, role)
+}
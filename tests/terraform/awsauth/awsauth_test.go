@@ -0,0 +1,87 @@
+package awsauth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+type fakeSTS struct {
+	stsiface.STSAPI
+	inputs []*sts.AssumeRoleInput
+	// expiration defaults to 2030.
+	expiration time.Time
+}
+
+func (f *fakeSTS) AssumeRoleWithContext(_ aws.Context, in *sts.AssumeRoleInput, _ ...request.Option) (*sts.AssumeRoleOutput, error) {
+	f.inputs = append(f.inputs, in)
+	expiration := f.expiration
+	if expiration.IsZero() {
+		expiration = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return &sts.AssumeRoleOutput{Credentials: &sts.Credentials{
+		AccessKeyId:     aws.String("ASIAEXAMPLE"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String(fmt.Sprintf("token-%d", len(f.inputs))),
+		Expiration:      aws.Time(expiration),
+	}}, nil
+}
+
+func TestForEnvironmentAssumesTheConfiguredRole(t *testing.T) {
+	fake := &fakeSTS{}
+	assumer := &Assumer{STS: fake}
+	config := &plancheck.Config{Environments: map[string]plancheck.Environment{
+		"prod": {Role: "arn:aws:iam::111111111111:role/tfcompliance"},
+		"dev":  {},
+	}}
+
+	creds, err := assumer.ForEnvironment(context.Background(), config, "prod")
+	require.NoError(t, err)
+	require.Equal(t, "arn:aws:iam::111111111111:role/tfcompliance", creds.RoleARN)
+	require.Equal(t, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "ASIAEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_SESSION_TOKEN":     "token-1",
+	}, creds.Env())
+
+	require.Len(t, fake.inputs, 1)
+	require.Equal(t, "tfcompliance-prod", aws.StringValue(fake.inputs[0].RoleSessionName))
+	require.Equal(t, int64(3600), aws.Int64Value(fake.inputs[0].DurationSeconds))
+
+	creds, err = assumer.ForEnvironment(context.Background(), config, "dev")
+	require.NoError(t, err)
+	require.Nil(t, creds, "environments without a role use the caller's credentials")
+	require.Nil(t, creds.Env())
+	require.Nil(t, creds.AWS())
+	require.Len(t, fake.inputs, 1)
+}
+
+func TestAssumedCredentialsRenewBeforeTheyExpire(t *testing.T) {
+	fake := &fakeSTS{}
+	creds, err := (&Assumer{STS: fake}).Assume(context.Background(), "prod", "arn:aws:iam::111111111111:role/tfcompliance")
+	require.NoError(t, err)
+	value, err := creds.AWS().Get()
+	require.NoError(t, err)
+	require.Equal(t, "token-1", value.SessionToken)
+	_, err = creds.AWS().Get()
+	require.NoError(t, err)
+	require.Len(t, fake.inputs, 1, "credentials far from expiry are reused")
+
+	fake.expiration = time.Now().Add(ExpiryWindow / 2)
+	creds, err = (&Assumer{STS: fake}).Assume(context.Background(), "prod", "arn:aws:iam::111111111111:role/tfcompliance")
+	require.NoError(t, err)
+	value, err = creds.AWS().Get()
+	require.NoError(t, err)
+	require.Equal(t, "token-2", value.SessionToken, "the first credentials are handed out without assuming again")
+	require.Equal(t, "token-3", creds.Env()["AWS_SESSION_TOKEN"], "credentials within ExpiryWindow of expiry are renewed")
+	require.Len(t, fake.inputs, 3)
+}
@@ -0,0 +1,152 @@
+package awsauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/plancheck"
+)
+
+// GitHub Actions sets these in jobs granted the id-token: write permission.
+const (
+	GitHubTokenURLEnv     = "ACTIONS_ID_TOKEN_REQUEST_URL"
+	GitHubTokenRequestEnv = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
+)
+
+// STSAudience is the audience STS accepts in web identity tokens.
+const STSAudience = "sts.amazonaws.com"
+
+// GitHubOIDC reports whether the job can request an OIDC token from GitHub
+// Actions.
+func GitHubOIDC() bool {
+	return os.Getenv(GitHubTokenURLEnv) != "" && os.Getenv(GitHubTokenRequestEnv) != ""
+}
+
+// TokenSource returns a web identity token.
+type TokenSource func(ctx context.Context) (string, error)
+
+// GitHubToken returns a TokenSource requesting a token for STSAudience from
+// GitHub Actions. Each token lasts minutes, so one is requested per role.
+func GitHubToken(client *http.Client) TokenSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context) (string, error) {
+		endpoint, err := url.Parse(os.Getenv(GitHubTokenURLEnv))
+		if err != nil || endpoint.Host == "" {
+			return "", fmt.Errorf("%s is not a URL", GitHubTokenURLEnv)
+		}
+		query := endpoint.Query()
+		query.Set("audience", STSAudience)
+		endpoint.RawQuery = query.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Authorization", "Bearer "+os.Getenv(GitHubTokenRequestEnv))
+		req.Header.Set("Accept", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("requesting a GitHub OIDC token: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return "", fmt.Errorf("requesting a GitHub OIDC token: %s: %s", resp.Status, body)
+		}
+		var token struct {
+			Value string `json:"value"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return "", fmt.Errorf("reading the GitHub OIDC token: %w", err)
+		}
+		if token.Value == "" {
+			return "", fmt.Errorf("GitHub returned an empty OIDC token")
+		}
+		return token.Value, nil
+	}
+}
+
+// WebIdentity assumes roles with a web identity token, so no long-lived
+// credentials are needed.
+type WebIdentity struct {
+	STS   stsiface.STSAPI
+	Token TokenSource
+	// Duration defaults to DefaultDuration.
+	Duration time.Duration
+}
+
+// NewWebIdentity returns a WebIdentity calling STS in region with tokens
+// from GitHub Actions. AssumeRoleWithWebIdentity is not signed, so it needs
+// no credentials.
+func NewWebIdentity(region string) (*WebIdentity, error) {
+	sess, err := awsapi.Default().WithCredentials(credentials.AnonymousCredentials).Session(region)
+	if err != nil {
+		return nil, fmt.Errorf("awsauth: %w", err)
+	}
+	return &WebIdentity{STS: sts.New(sess), Token: GitHubToken(nil)}, nil
+}
+
+// Assume assumes role for environment with a fresh token, naming the
+// session as Assumer.Assume does. Renewing the credentials requests another
+// token.
+func (w *WebIdentity) Assume(ctx context.Context, environment, role string) (*Credentials, error) {
+	return withRefresh(ctx, func(ctx context.Context) (*Credentials, error) {
+		return w.assume(ctx, environment, role)
+	})
+}
+
+func (w *WebIdentity) assume(ctx context.Context, environment, role string) (*Credentials, error) {
+	duration := w.Duration
+	if duration == 0 {
+		duration = DefaultDuration
+	}
+	token, err := w.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("awsauth: assuming %s for %s: %w", role, environment, err)
+	}
+	out, err := w.STS.AssumeRoleWithWebIdentityWithContext(ctx, &sts.AssumeRoleWithWebIdentityInput{
+		RoleArn:          aws.String(role),
+		RoleSessionName:  aws.String(sessionName(environment)),
+		WebIdentityToken: aws.String(token),
+		DurationSeconds:  aws.Int64(int64(duration / time.Second)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("awsauth: assuming %s for %s with a web identity: %w", role, environment, err)
+	}
+	return fromSTS(environment, role, out.Credentials)
+}
+
+// ForEnvironment assumes the role compliance.yaml names for environment, or
+// backend.ci_role for an environment without one. It returns nil and no
+// error when neither is set.
+func (w *WebIdentity) ForEnvironment(ctx context.Context, config *plancheck.Config, environment string) (*Credentials, error) {
+	role := webIdentityRole(config, environment)
+	if role == "" {
+		return nil, nil
+	}
+	return w.Assume(ctx, environment, role)
+}
+
+func webIdentityRole(config *plancheck.Config, environment string) string {
+	if role := config.Environment(environment).Role; role != "" {
+		return role
+	}
+	if config == nil {
+		return ""
+	}
+	return config.Backend.CIRole
+}
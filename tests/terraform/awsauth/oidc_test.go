@@ -0,0 +1,93 @@
+package awsauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+type webIdentitySTS struct {
+	stsiface.STSAPI
+	inputs []*sts.AssumeRoleWithWebIdentityInput
+}
+
+func (f *webIdentitySTS) AssumeRoleWithWebIdentityWithContext(_ aws.Context, in *sts.AssumeRoleWithWebIdentityInput, _ ...request.Option) (*sts.AssumeRoleWithWebIdentityOutput, error) {
+	f.inputs = append(f.inputs, in)
+	return &sts.AssumeRoleWithWebIdentityOutput{Credentials: &sts.Credentials{
+		AccessKeyId:     aws.String("ASIAEXAMPLE"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}}, nil
+}
+
+func TestGitHubTokenRequestsTheSTSAudience(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer request-token" {
+			http.Error(w, "bad request token", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("api-version") != "2.0" || r.URL.Query().Get("audience") != STSAudience {
+			http.Error(w, "bad query "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"count": 1, "value": "oidc-token"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv(GitHubTokenURLEnv, server.URL+"/token?api-version=2.0")
+	t.Setenv(GitHubTokenRequestEnv, "request-token")
+	require.True(t, GitHubOIDC())
+	token, err := GitHubToken(server.Client())(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "oidc-token", token)
+
+	t.Setenv(GitHubTokenRequestEnv, "expired")
+	_, err = GitHubToken(server.Client())(context.Background())
+	require.ErrorContains(t, err, "401 Unauthorized")
+
+	t.Setenv(GitHubTokenRequestEnv, "")
+	require.False(t, GitHubOIDC(), "jobs without id-token: write get no request token")
+}
+
+func TestWebIdentityFallsBackToTheCIRole(t *testing.T) {
+	fake := &webIdentitySTS{}
+	web := &WebIdentity{STS: fake, Token: func(context.Context) (string, error) { return "oidc-token", nil }}
+	config := &plancheck.Config{
+		Backend: plancheck.BackendPolicy{CIRole: "arn:aws:iam::111111111111:role/github-actions"},
+		Environments: map[string]plancheck.Environment{
+			"prod": {Role: "arn:aws:iam::222222222222:role/tfcompliance"},
+			"dev":  {},
+		},
+	}
+
+	creds, err := web.ForEnvironment(context.Background(), config, "prod")
+	require.NoError(t, err)
+	require.Equal(t, "arn:aws:iam::222222222222:role/tfcompliance", creds.RoleARN)
+	require.Equal(t, "ASIAEXAMPLE", creds.Env()["AWS_ACCESS_KEY_ID"])
+
+	creds, err = web.ForEnvironment(context.Background(), config, "dev")
+	require.NoError(t, err)
+	require.Equal(t, "arn:aws:iam::111111111111:role/github-actions", creds.RoleARN)
+
+	require.Len(t, fake.inputs, 2)
+	require.Equal(t, "oidc-token", aws.StringValue(fake.inputs[1].WebIdentityToken))
+	require.Equal(t, "tfcompliance-dev", aws.StringValue(fake.inputs[1].RoleSessionName))
+	require.Equal(t, int64(3600), aws.Int64Value(fake.inputs[1].DurationSeconds))
+
+	config.Backend.CIRole = ""
+	creds, err = web.ForEnvironment(context.Background(), config, "dev")
+	require.NoError(t, err)
+	require.Nil(t, creds, "without a role the caller's own credentials are used")
+	require.Len(t, fake.inputs, 2)
+}
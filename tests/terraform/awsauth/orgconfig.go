@@ -0,0 +1,54 @@
+package awsauth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/plancheck"
+)
+
+// The organization's configuration layer is usually kept in a bucket of the
+// security account, readable by every CI identity.
+func init() {
+	plancheck.RegisterConfigFetcher("s3", FetchS3Config)
+}
+
+// FetchS3Config reads the object at an s3://bucket/key URL with the default
+// credentials, from whichever region holds the bucket.
+func FetchS3Config(url string) ([]byte, error) {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(url, "s3://"), "/")
+	if !ok || bucket == "" || key == "" {
+		return nil, fmt.Errorf("%s is not an s3://bucket/key URL", url)
+	}
+
+	hint := os.Getenv("AWS_REGION")
+	if hint == "" {
+		hint = "us-east-1"
+	}
+	sess, err := awsapi.Default().Session(hint)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	region, err := s3manager.GetBucketRegion(ctx, sess, bucket, hint)
+	if err != nil {
+		return nil, fmt.Errorf("locating bucket %s: %w", bucket, err)
+	}
+	out, err := s3.New(sess, aws.NewConfig().WithRegion(region)).GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", url, err)
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
@@ -0,0 +1,311 @@
+package awsauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/plancheck"
+)
+
+// UnknownScopeEnv, set to 1, lets Preflight pass a principal that may not
+// read its own policies, whose scope is then unknown.
+const UnknownScopeEnv = "COMPLIANCE_ALLOW_UNKNOWN_SCOPE"
+
+// BroadPolicies are managed policies granting far more than planning and
+// reading an environment needs.
+var BroadPolicies = []string{
+	"arn:aws:iam::aws:policy/AdministratorAccess",
+	"arn:aws:iam::aws:policy/IAMFullAccess",
+	"arn:aws:iam::aws:policy/PowerUserAccess",
+}
+
+// awsManagedPolicies prefixes the ARNs of the policies AWS maintains. Those
+// outside BroadPolicies are not read.
+const awsManagedPolicies = "arn:aws:iam::aws:policy/"
+
+// Identity is the principal the suite runs as.
+type Identity struct {
+	Account string
+	ARN     string
+	// ScopeUnknown is set when the principal may not read its own
+	// policies and UnknownScopeEnv waived the scope check.
+	ScopeUnknown bool
+}
+
+func (id *Identity) String() string {
+	if id.ScopeUnknown {
+		return fmt.Sprintf("%s (account %s, scope unknown)", id.ARN, id.Account)
+	}
+	return fmt.Sprintf("%s (account %s)", id.ARN, id.Account)
+}
+
+// Preflight checks the credentials before anything is planned.
+type Preflight struct {
+	STS stsiface.STSAPI
+	IAM iamiface.IAMAPI
+	// AllowUnknownScope passes a principal that may not read its own
+	// policies instead of refusing it.
+	AllowUnknownScope bool
+}
+
+// NewPreflight returns a Preflight for creds, or the default credential
+// chain when creds is nil. UnknownScopeEnv sets AllowUnknownScope.
+func NewPreflight(region string, creds *credentials.Credentials) (*Preflight, error) {
+	sess, err := awsapi.Default().WithCredentials(creds).Session(region)
+	if err != nil {
+		return nil, fmt.Errorf("awsauth: %w", err)
+	}
+	return &Preflight{STS: sts.New(sess), IAM: iam.New(sess), AllowUnknownScope: os.Getenv(UnknownScopeEnv) == "1"}, nil
+}
+
+// Check returns the caller's identity. It refuses the root user, and a role
+// or user granted every action: one with BroadPolicies attached, or whose
+// managed, inline or group policies allow "*" or "*:*", or every action but
+// some through NotAction, on every resource. A principal that may not read
+// its policies is refused too, unless AllowUnknownScope is set.
+func (p *Preflight) Check(ctx context.Context) (*Identity, error) {
+	out, err := p.STS.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("awsauth: verifying credentials: %w", err)
+	}
+	identity := &Identity{Account: aws.StringValue(out.Account), ARN: aws.StringValue(out.Arn)}
+
+	// arn:aws:iam::<account>:root, arn:aws:iam::<account>:user/<path>/<name>
+	// or arn:aws:sts::<account>:assumed-role/<name>/<session>.
+	parts := strings.SplitN(identity.ARN, ":", 6)
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("awsauth: unexpected caller ARN %q", identity.ARN)
+	}
+	resource := strings.Split(parts[5], "/")
+	if resource[0] == "root" {
+		return nil, fmt.Errorf("awsauth: refusing to run as the root user of account %s", identity.Account)
+	}
+
+	var grant string
+	switch {
+	case resource[0] == "assumed-role" && len(resource) >= 2:
+		grant, err = p.broadGrant(ctx, principal{kind: "role", name: resource[1]})
+	case resource[0] == "user":
+		grant, err = p.broadGrant(ctx, principal{kind: "user", name: resource[len(resource)-1]})
+	}
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "AccessDenied" {
+		if !p.AllowUnknownScope {
+			return nil, fmt.Errorf("awsauth: %s may not read its own policies, so its scope is unknown; "+
+				"allow it the iam:List* and iam:Get* calls on itself, or set %s=1: %w", identity.ARN, UnknownScopeEnv, err)
+		}
+		identity.ScopeUnknown = true
+		return identity, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("awsauth: reading the policies of %s: %w", identity.ARN, err)
+	}
+	if grant != "" {
+		return nil, fmt.Errorf("awsauth: %s is granted every action by %s; run with a narrower role", identity.ARN, grant)
+	}
+	return identity, nil
+}
+
+// principal is a role, user or group whose policies Preflight reads.
+type principal struct {
+	kind, name string
+}
+
+func (p principal) String() string {
+	return p.kind + " " + p.name
+}
+
+// broadGrant describes the policy granting who every action, or returns ""
+// when none does. A user's groups are read too.
+func (p *Preflight) broadGrant(ctx context.Context, who principal) (string, error) {
+	attached, err := p.attachedPolicies(ctx, who)
+	if err != nil {
+		return "", err
+	}
+	for _, arn := range attached {
+		if containsString(BroadPolicies, arn) {
+			return fmt.Sprintf("%s, attached to %s", arn, who), nil
+		}
+		if strings.HasPrefix(arn, awsManagedPolicies) {
+			continue
+		}
+		document, err := p.managedPolicy(ctx, arn)
+		if err != nil {
+			return "", err
+		}
+		if grantsEverything(document) {
+			return fmt.Sprintf("%s, attached to %s", arn, who), nil
+		}
+	}
+
+	inline, err := p.inlinePolicies(ctx, who)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range inline {
+		document, err := p.inlinePolicy(ctx, who, name)
+		if err != nil {
+			return "", err
+		}
+		if grantsEverything(document) {
+			return fmt.Sprintf("the inline policy %s of %s", name, who), nil
+		}
+	}
+
+	if who.kind != "user" {
+		return "", nil
+	}
+	var groups []string
+	err = p.IAM.ListGroupsForUserPagesWithContext(ctx, &iam.ListGroupsForUserInput{UserName: aws.String(who.name)},
+		func(page *iam.ListGroupsForUserOutput, _ bool) bool {
+			for _, group := range page.Groups {
+				groups = append(groups, aws.StringValue(group.GroupName))
+			}
+			return true
+		})
+	if err != nil {
+		return "", err
+	}
+	for _, group := range groups {
+		if grant, err := p.broadGrant(ctx, principal{kind: "group", name: group}); grant != "" || err != nil {
+			return grant, err
+		}
+	}
+	return "", nil
+}
+
+// attachedPolicies returns the ARNs of the managed policies attached to who.
+func (p *Preflight) attachedPolicies(ctx context.Context, who principal) ([]string, error) {
+	var arns []string
+	collect := func(policies []*iam.AttachedPolicy) bool {
+		for _, policy := range policies {
+			arns = append(arns, aws.StringValue(policy.PolicyArn))
+		}
+		return true
+	}
+	var err error
+	switch who.kind {
+	case "role":
+		err = p.IAM.ListAttachedRolePoliciesPagesWithContext(ctx,
+			&iam.ListAttachedRolePoliciesInput{RoleName: aws.String(who.name)},
+			func(page *iam.ListAttachedRolePoliciesOutput, _ bool) bool { return collect(page.AttachedPolicies) })
+	case "user":
+		err = p.IAM.ListAttachedUserPoliciesPagesWithContext(ctx,
+			&iam.ListAttachedUserPoliciesInput{UserName: aws.String(who.name)},
+			func(page *iam.ListAttachedUserPoliciesOutput, _ bool) bool { return collect(page.AttachedPolicies) })
+	case "group":
+		err = p.IAM.ListAttachedGroupPoliciesPagesWithContext(ctx,
+			&iam.ListAttachedGroupPoliciesInput{GroupName: aws.String(who.name)},
+			func(page *iam.ListAttachedGroupPoliciesOutput, _ bool) bool { return collect(page.AttachedPolicies) })
+	}
+	return arns, err
+}
+
+// inlinePolicies returns the names of the policies embedded in who.
+func (p *Preflight) inlinePolicies(ctx context.Context, who principal) ([]string, error) {
+	var names []string
+	collect := func(policies []*string) bool {
+		names = append(names, aws.StringValueSlice(policies)...)
+		return true
+	}
+	var err error
+	switch who.kind {
+	case "role":
+		err = p.IAM.ListRolePoliciesPagesWithContext(ctx,
+			&iam.ListRolePoliciesInput{RoleName: aws.String(who.name)},
+			func(page *iam.ListRolePoliciesOutput, _ bool) bool { return collect(page.PolicyNames) })
+	case "user":
+		err = p.IAM.ListUserPoliciesPagesWithContext(ctx,
+			&iam.ListUserPoliciesInput{UserName: aws.String(who.name)},
+			func(page *iam.ListUserPoliciesOutput, _ bool) bool { return collect(page.PolicyNames) })
+	case "group":
+		err = p.IAM.ListGroupPoliciesPagesWithContext(ctx,
+			&iam.ListGroupPoliciesInput{GroupName: aws.String(who.name)},
+			func(page *iam.ListGroupPoliciesOutput, _ bool) bool { return collect(page.PolicyNames) })
+	}
+	return names, err
+}
+
+// inlinePolicy returns the document of the policy name embedded in who.
+func (p *Preflight) inlinePolicy(ctx context.Context, who principal, name string) (string, error) {
+	var document *string
+	switch who.kind {
+	case "role":
+		out, err := p.IAM.GetRolePolicyWithContext(ctx, &iam.GetRolePolicyInput{RoleName: aws.String(who.name), PolicyName: aws.String(name)})
+		if err != nil {
+			return "", err
+		}
+		document = out.PolicyDocument
+	case "user":
+		out, err := p.IAM.GetUserPolicyWithContext(ctx, &iam.GetUserPolicyInput{UserName: aws.String(who.name), PolicyName: aws.String(name)})
+		if err != nil {
+			return "", err
+		}
+		document = out.PolicyDocument
+	case "group":
+		out, err := p.IAM.GetGroupPolicyWithContext(ctx, &iam.GetGroupPolicyInput{GroupName: aws.String(who.name), PolicyName: aws.String(name)})
+		if err != nil {
+			return "", err
+		}
+		document = out.PolicyDocument
+	}
+	// IAM returns policy documents URL-encoded.
+	return url.QueryUnescape(aws.StringValue(document))
+}
+
+// managedPolicy returns the document of the default version of the managed
+// policy arn.
+func (p *Preflight) managedPolicy(ctx context.Context, arn string) (string, error) {
+	policy, err := p.IAM.GetPolicyWithContext(ctx, &iam.GetPolicyInput{PolicyArn: aws.String(arn)})
+	if err != nil {
+		return "", err
+	}
+	version, err := p.IAM.GetPolicyVersionWithContext(ctx, &iam.GetPolicyVersionInput{
+		PolicyArn: aws.String(arn),
+		VersionId: policy.Policy.DefaultVersionId,
+	})
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(aws.StringValue(version.PolicyVersion.Document))
+}
+
+// grantsEverything reports whether document allows "*" or "*:*", or every
+// action but some through NotAction, on every resource. A document that does
+// not parse grants nothing here; IAM only stores valid ones.
+func grantsEverything(document string) bool {
+	var policy map[string]interface{}
+	if err := json.Unmarshal([]byte(document), &policy); err != nil {
+		return false
+	}
+	for _, statement := range plancheck.Statements(policy) {
+		if !statement.Allows() || !containsString(statement.Resource, "*") {
+			continue
+		}
+		if containsString(statement.Action, "*") || containsString(statement.Action, "*:*") || len(statement.NotAction) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
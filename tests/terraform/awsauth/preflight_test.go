@@ -0,0 +1,199 @@
+package awsauth
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/require"
+)
+
+type callerSTS struct {
+	fakeSTS
+	arn string
+}
+
+func (f *callerSTS) GetCallerIdentityWithContext(aws.Context, *sts.GetCallerIdentityInput, ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{Account: aws.String("111111111111"), Arn: aws.String(f.arn)}, nil
+}
+
+// fakeIAM holds the policies of roles, users and groups by name.
+type fakeIAM struct {
+	iamiface.IAMAPI
+	attached  map[string][]string
+	inline    map[string]map[string]string
+	groups    map[string][]string
+	documents map[string]string
+	err       error
+}
+
+func (f *fakeIAM) attachedTo(name string) []*iam.AttachedPolicy {
+	var policies []*iam.AttachedPolicy
+	for _, arn := range f.attached[name] {
+		policies = append(policies, &iam.AttachedPolicy{PolicyArn: aws.String(arn)})
+	}
+	return policies
+}
+
+func (f *fakeIAM) inlineOf(name string) []*string {
+	var names []string
+	for policy := range f.inline[name] {
+		names = append(names, policy)
+	}
+	sort.Strings(names)
+	return aws.StringSlice(names)
+}
+
+func (f *fakeIAM) inlineDocument(name, policy string) *string {
+	return aws.String(url.QueryEscape(f.inline[name][policy]))
+}
+
+func (f *fakeIAM) ListAttachedRolePoliciesPagesWithContext(_ aws.Context, in *iam.ListAttachedRolePoliciesInput, fn func(*iam.ListAttachedRolePoliciesOutput, bool) bool, _ ...request.Option) error {
+	if f.err != nil {
+		return f.err
+	}
+	fn(&iam.ListAttachedRolePoliciesOutput{AttachedPolicies: f.attachedTo(aws.StringValue(in.RoleName))}, true)
+	return nil
+}
+
+func (f *fakeIAM) ListAttachedUserPoliciesPagesWithContext(_ aws.Context, in *iam.ListAttachedUserPoliciesInput, fn func(*iam.ListAttachedUserPoliciesOutput, bool) bool, _ ...request.Option) error {
+	if f.err != nil {
+		return f.err
+	}
+	fn(&iam.ListAttachedUserPoliciesOutput{AttachedPolicies: f.attachedTo(aws.StringValue(in.UserName))}, true)
+	return nil
+}
+
+func (f *fakeIAM) ListAttachedGroupPoliciesPagesWithContext(_ aws.Context, in *iam.ListAttachedGroupPoliciesInput, fn func(*iam.ListAttachedGroupPoliciesOutput, bool) bool, _ ...request.Option) error {
+	fn(&iam.ListAttachedGroupPoliciesOutput{AttachedPolicies: f.attachedTo(aws.StringValue(in.GroupName))}, true)
+	return nil
+}
+
+func (f *fakeIAM) ListRolePoliciesPagesWithContext(_ aws.Context, in *iam.ListRolePoliciesInput, fn func(*iam.ListRolePoliciesOutput, bool) bool, _ ...request.Option) error {
+	fn(&iam.ListRolePoliciesOutput{PolicyNames: f.inlineOf(aws.StringValue(in.RoleName))}, true)
+	return nil
+}
+
+func (f *fakeIAM) ListUserPoliciesPagesWithContext(_ aws.Context, in *iam.ListUserPoliciesInput, fn func(*iam.ListUserPoliciesOutput, bool) bool, _ ...request.Option) error {
+	fn(&iam.ListUserPoliciesOutput{PolicyNames: f.inlineOf(aws.StringValue(in.UserName))}, true)
+	return nil
+}
+
+func (f *fakeIAM) ListGroupPoliciesPagesWithContext(_ aws.Context, in *iam.ListGroupPoliciesInput, fn func(*iam.ListGroupPoliciesOutput, bool) bool, _ ...request.Option) error {
+	fn(&iam.ListGroupPoliciesOutput{PolicyNames: f.inlineOf(aws.StringValue(in.GroupName))}, true)
+	return nil
+}
+
+func (f *fakeIAM) GetRolePolicyWithContext(_ aws.Context, in *iam.GetRolePolicyInput, _ ...request.Option) (*iam.GetRolePolicyOutput, error) {
+	return &iam.GetRolePolicyOutput{PolicyDocument: f.inlineDocument(aws.StringValue(in.RoleName), aws.StringValue(in.PolicyName))}, nil
+}
+
+func (f *fakeIAM) GetUserPolicyWithContext(_ aws.Context, in *iam.GetUserPolicyInput, _ ...request.Option) (*iam.GetUserPolicyOutput, error) {
+	return &iam.GetUserPolicyOutput{PolicyDocument: f.inlineDocument(aws.StringValue(in.UserName), aws.StringValue(in.PolicyName))}, nil
+}
+
+func (f *fakeIAM) GetGroupPolicyWithContext(_ aws.Context, in *iam.GetGroupPolicyInput, _ ...request.Option) (*iam.GetGroupPolicyOutput, error) {
+	return &iam.GetGroupPolicyOutput{PolicyDocument: f.inlineDocument(aws.StringValue(in.GroupName), aws.StringValue(in.PolicyName))}, nil
+}
+
+func (f *fakeIAM) ListGroupsForUserPagesWithContext(_ aws.Context, in *iam.ListGroupsForUserInput, fn func(*iam.ListGroupsForUserOutput, bool) bool, _ ...request.Option) error {
+	var groups []*iam.Group
+	for _, name := range f.groups[aws.StringValue(in.UserName)] {
+		groups = append(groups, &iam.Group{GroupName: aws.String(name)})
+	}
+	fn(&iam.ListGroupsForUserOutput{Groups: groups}, true)
+	return nil
+}
+
+func (f *fakeIAM) GetPolicyWithContext(_ aws.Context, in *iam.GetPolicyInput, _ ...request.Option) (*iam.GetPolicyOutput, error) {
+	return &iam.GetPolicyOutput{Policy: &iam.Policy{Arn: in.PolicyArn, DefaultVersionId: aws.String("v3")}}, nil
+}
+
+func (f *fakeIAM) GetPolicyVersionWithContext(_ aws.Context, in *iam.GetPolicyVersionInput, _ ...request.Option) (*iam.GetPolicyVersionOutput, error) {
+	if aws.StringValue(in.VersionId) != "v3" {
+		return nil, awserr.New("NoSuchEntity", "not the default version", nil)
+	}
+	return &iam.GetPolicyVersionOutput{PolicyVersion: &iam.PolicyVersion{
+		Document: aws.String(url.QueryEscape(f.documents[aws.StringValue(in.PolicyArn)])),
+	}}, nil
+}
+
+func TestPreflight(t *testing.T) {
+	const (
+		readOnly = "arn:aws:iam::aws:policy/ReadOnlyAccess"
+		planner  = "arn:aws:iam::111111111111:policy/tfcompliance-plan"
+		anything = "arn:aws:iam::111111111111:policy/anything"
+	)
+	iamAPI := &fakeIAM{
+		attached: map[string][]string{
+			"tfcompliance-plan": {readOnly, planner},
+			"break-glass":       {"arn:aws:iam::aws:policy/AdministratorAccess"},
+			"power":             {"arn:aws:iam::aws:policy/PowerUserAccess"},
+			"custom-admin":      {anything},
+			"alice":             {"arn:aws:iam::aws:policy/AdministratorAccess"},
+			"admins":            {"arn:aws:iam::aws:policy/IAMFullAccess"},
+		},
+		inline: map[string]map[string]string{
+			"tfcompliance-plan": {"state": `{"Statement":[{"Effect":"Allow","Action":"s3:*","Resource":"*"},{"Effect":"Deny","Action":"*","Resource":"*"}]}`},
+			"inline-admin":      {"everything": `{"Statement":{"Effect":"Allow","Action":["*:*"],"Resource":"*"}}`},
+			"operators":         {"all-but-iam": `{"Statement":[{"Effect":"Allow","NotAction":"iam:*","Resource":"*"}]}`},
+		},
+		groups: map[string][]string{
+			"bob":   {"readers", "admins"},
+			"carol": {"operators"},
+			"dave":  {"readers"},
+		},
+		documents: map[string]string{
+			planner:  `{"Statement":[{"Effect":"Allow","Action":["dynamodb:GetItem","dynamodb:PutItem"],"Resource":"*"}]}`,
+			anything: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"*","Resource":"*"}]}`,
+		},
+	}
+	denied := &fakeIAM{err: awserr.New("AccessDenied", "not authorized", nil)}
+
+	for _, tc := range []struct {
+		name         string
+		arn          string
+		iam          iamiface.IAMAPI
+		allowUnknown bool
+		want         string
+		err          string
+	}{
+		{name: "narrow role", arn: "arn:aws:sts::111111111111:assumed-role/tfcompliance-plan/tfcompliance-dev", iam: iamAPI},
+		{name: "narrow user", arn: "arn:aws:iam::111111111111:user/people/dave", iam: iamAPI},
+		{name: "root", arn: "arn:aws:iam::111111111111:root", err: "root user"},
+		{name: "admin role", arn: "arn:aws:sts::111111111111:assumed-role/break-glass/me", iam: iamAPI, err: "AdministratorAccess, attached to role break-glass"},
+		{name: "admin user", arn: "arn:aws:iam::111111111111:user/people/alice", iam: iamAPI, err: "AdministratorAccess, attached to user alice"},
+		{name: "power user", arn: "arn:aws:sts::111111111111:assumed-role/power/me", iam: iamAPI, err: "PowerUserAccess"},
+		{name: "customer managed admin", arn: "arn:aws:sts::111111111111:assumed-role/custom-admin/me", iam: iamAPI, err: anything + ", attached to role custom-admin"},
+		{name: "inline admin", arn: "arn:aws:sts::111111111111:assumed-role/inline-admin/me", iam: iamAPI, err: "the inline policy everything of role inline-admin"},
+		{name: "admin group", arn: "arn:aws:iam::111111111111:user/bob", iam: iamAPI, err: "IAMFullAccess, attached to group admins"},
+		{name: "group inline NotAction", arn: "arn:aws:iam::111111111111:user/carol", iam: iamAPI, err: "the inline policy all-but-iam of group operators"},
+		{name: "policies not readable", arn: "arn:aws:sts::111111111111:assumed-role/tfcompliance-plan/ci", iam: denied, err: "scope is unknown"},
+		{
+			name: "policies not readable, waived", arn: "arn:aws:sts::111111111111:assumed-role/tfcompliance-plan/ci", iam: denied, allowUnknown: true,
+			want: "arn:aws:sts::111111111111:assumed-role/tfcompliance-plan/ci (account 111111111111, scope unknown)",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			preflight := &Preflight{STS: &callerSTS{arn: tc.arn}, IAM: tc.iam, AllowUnknownScope: tc.allowUnknown}
+			identity, err := preflight.Check(context.Background())
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			want := tc.want
+			if want == "" {
+				want = tc.arn + " (account 111111111111)"
+			}
+			require.Equal(t, want, identity.String())
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package terraformtests
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/restore"
+)
+
+// restoreEnv enables the restore check. It writes to the deployed tables and
+// creates backups and tables of its own, so it is never implied by
+// COMPLIANCE_POST_APPLY.
+const restoreEnv = "COMPLIANCE_RESTORE"
+
+// Each planned DynamoDB table of a deployed sandbox gets a sentinel item and
+// an on-demand backup, which is restored to a temporary table that must hold
+// the sentinel. The sentinel, backup and temporary table are removed
+// afterwards. Environments compliance.yaml does not mark sandbox: true are
+// skipped.
+func TestSandboxBackupsRestore(t *testing.T) {
+	if os.Getenv(restoreEnv) == "" {
+		t.Skipf("set %s=1 to back up and restore the deployed sandbox tables", restoreEnv)
+	}
+
+	config, err := plancheck.LoadConfig(complianceFile)
+	require.NoError(t, err)
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		if !config.Environment(env.Name).Sandbox {
+			t.Skipf("refusing to write to the tables of %s, which %s does not mark sandbox: true", env.Name, complianceFile)
+		}
+
+		_, plan := environmentPlan(t, env.Name)
+		tables := restore.Tables(plan, config.Restore.Tables)
+		if len(tables) == 0 {
+			t.Skipf("the %s plan has no DynamoDB tables matching %v", env.Name, config.Restore.Tables)
+		}
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		clients, err := restore.NewClients(env.Region, creds.AWS())
+		require.NoError(t, err)
+
+		for _, table := range tables {
+			table := table
+			t.Run(table.Name, func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), config.Restore.Timeout())
+				defer cancel()
+				result, err := clients.Verify(ctx, table.Name)
+				require.NoError(t, err, "%s must be restorable from backup", table.Address)
+				t.Log(result)
+			})
+		}
+	})
+}
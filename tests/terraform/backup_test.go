@@ -0,0 +1,28 @@
+package terraformtests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// dev is not required to be recoverable, but stage and prod deploy the same
+// modules, so the dev plan is judged against their backup and replication
+// settings. This stays a dev-only test, with subtests named after the
+// settings' environments, while stage and prod have no root of their own.
+func TestStatefulResourcesAreBackedUp(t *testing.T) {
+	options, plan := environmentPlan(t, devEnvironment)
+	config, err := plancheck.LoadConfig(complianceFile)
+	require.NoError(t, err, "compliance configuration must load")
+
+	for _, environment := range []string{"stage", "prod"} {
+		t.Run(environment, func(t *testing.T) {
+			require.NotNil(t, config.Environment(environment).Backup, "%s must configure backup in %s", environment, complianceFile)
+
+			findings := evaluateRules(t, plan, options, environment, "backup.coverage", "backup.rpo", "s3.replication")
+			requireNoFindings(t, plan, findings)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package terraformtests
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/livestate"
+	"cs450/terraformtests/plancheck"
+)
+
+// Certificates close to expiry fail the suite weeks before they would take an
+// endpoint down. Certificates are read in the region of their ARN, so
+// CloudFront's us-east-1 certificates are found from any region.
+func TestCertificatesAreFarFromExpiry(t *testing.T) {
+	if os.Getenv(postApplyEnv) == "" {
+		t.Skipf("set %s=1 to check the expiry of the certificates the stack uses", postApplyEnv)
+	}
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		options, plan := environmentPlan(t, env.Name)
+		config, err := plancheck.LoadConfig(complianceFile)
+		require.NoError(t, err)
+
+		byRegion := map[string][]string{}
+		for _, ref := range plancheck.CertificateReferences(plan) {
+			region := env.Region
+			if parts := strings.Split(ref.ARN, ":"); len(parts) > 3 && parts[3] != "" {
+				region = parts[3]
+			}
+			byRegion[region] = append(byRegion[region], ref.ARN)
+		}
+		if len(byRegion) == 0 {
+			t.Skipf("the %s plan uses no certificates", env.Name)
+		}
+
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		readAt := time.Now()
+		certificates := map[string]plancheck.Certificate{}
+		for region, arns := range byRegion {
+			clients, err := livestate.NewClients(region, creds.AWS())
+			require.NoError(t, err)
+			certs, err := clients.Certificates(arns)
+			require.NoError(t, err)
+			for arn, cert := range certs {
+				certificates[arn] = cert
+			}
+		}
+
+		findings := plancheck.Evaluate(&plancheck.Input{
+			Plan:          plan,
+			DefaultRegion: env.Region,
+			Environment:   env.Name,
+			Config:        config,
+			Runtime:       &plancheck.Runtime{Certificates: certificates, ReadAt: readAt},
+		}, requireRules(t, "acm.expiry")...)
+		plancheck.NewSourceIndex(plan, options.TerraformDir).Annotate(findings)
+		requireNoFindings(t, plan, findings)
+	})
+}
@@ -0,0 +1,133 @@
+// Package chaos injects faults into a deployed sandbox stack and undoes
+// them, so a test can check that terraform notices what it should and that
+// the stack recovers. Faults change real infrastructure: run them only
+// against environments nobody depends on.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/awsapi"
+)
+
+// ECSAPI is the part of the ECS API faults stop tasks and watch services
+// through.
+type ECSAPI interface {
+	ListTasks(context.Context, *ecs.ListTasksInput, ...func(*ecs.Options)) (*ecs.ListTasksOutput, error)
+	StopTask(context.Context, *ecs.StopTaskInput, ...func(*ecs.Options)) (*ecs.StopTaskOutput, error)
+	DescribeServices(context.Context, *ecs.DescribeServicesInput, ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error)
+}
+
+// EC2API is the part of the EC2 API faults edit routes through.
+type EC2API interface {
+	DeleteRoute(context.Context, *ec2.DeleteRouteInput, ...func(*ec2.Options)) (*ec2.DeleteRouteOutput, error)
+	CreateRoute(context.Context, *ec2.CreateRouteInput, ...func(*ec2.Options)) (*ec2.CreateRouteOutput, error)
+}
+
+// Clients are the AWS APIs faults are injected through.
+type Clients struct {
+	ECS ECSAPI
+	EC2 EC2API
+
+	// PollInterval is how long to wait between checks on a recovering
+	// resource. Zero means five seconds.
+	PollInterval time.Duration
+}
+
+// NewClients returns the ECS and EC2 clients faults are injected through in
+// region. Nil creds sign with the default awsapi factory's credentials.
+func NewClients(region string, creds *credentials.Credentials) (*Clients, error) {
+	cfg, err := awsapi.Default().WithCredentials(creds).Config(context.Background(), region)
+	if err != nil {
+		return nil, fmt.Errorf("chaos: %w", err)
+	}
+	return &Clients{ECS: ecs.NewFromConfig(cfg), EC2: ec2.NewFromConfig(cfg)}, nil
+}
+
+func (c *Clients) pollInterval() time.Duration {
+	if c.PollInterval == 0 {
+		return 5 * time.Second
+	}
+	return c.PollInterval
+}
+
+// Fault is one injected failure of one planned resource.
+type Fault struct {
+	// Experiment names the kind of fault, e.g. "stop-task".
+	Experiment string
+	// Address is the planned resource the fault hits.
+	Address string
+	// Description says what Inject does, for the test log.
+	Description string
+	// ExpectDrift is whether terraform plan must propose a change to
+	// Address while the fault is in place. Faults the stack heals by itself,
+	// such as a stopped task, must not show up as drift.
+	ExpectDrift bool
+
+	inject  func(ctx context.Context) error
+	restore func(ctx context.Context) error
+}
+
+// Inject applies the fault.
+func (f *Fault) Inject(ctx context.Context) error {
+	if err := f.inject(ctx); err != nil {
+		return fmt.Errorf("chaos: %s on %s: %w", f.Experiment, f.Address, err)
+	}
+	return nil
+}
+
+// Restore undoes the fault, or waits for the stack to undo it, until ctx is
+// done. It is safe to call after a failed Inject.
+func (f *Fault) Restore(ctx context.Context) error {
+	if err := f.restore(ctx); err != nil {
+		return fmt.Errorf("chaos: restoring %s on %s: %w", f.Experiment, f.Address, err)
+	}
+	return nil
+}
+
+// Experiments are the names of the faults Faults knows how to inject.
+var Experiments = []string{"stop-task", "remove-default-route"}
+
+// Faults returns one fault per named experiment, each hitting the first
+// resource of the plan it applies to. Experiments with nothing to hit are
+// left out.
+func (c *Clients) Faults(plan *tfjson.Plan, experiments []string) ([]*Fault, error) {
+	var faults []*Fault
+	for _, experiment := range experiments {
+		var fault *Fault
+		switch experiment {
+		case "stop-task":
+			fault = c.stopTask(plan)
+		case "remove-default-route":
+			fault = c.removeDefaultRoute(plan)
+		default:
+			return nil, fmt.Errorf("chaos: unknown experiment %q (known: %v)", experiment, Experiments)
+		}
+		if fault != nil {
+			faults = append(faults, fault)
+		}
+	}
+	return faults, nil
+}
+
+// Drift returns the actions terraform plans for address other than no-op
+// and read, which are empty when the plan leaves the resource alone.
+func Drift(plan *tfjson.Plan, address string) tfjson.Actions {
+	for _, change := range plan.ResourceChanges {
+		if change == nil || change.Address != address || change.Change == nil {
+			continue
+		}
+		if change.Change.Actions.NoOp() || change.Change.Actions.Read() {
+			return nil
+		}
+		return change.Change.Actions
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+const sandboxPlan = `{
+  "format_version": "1.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "module.ecs.aws_ecs_service.validator_service", "type": "aws_ecs_service", "name": "validator_service", "values": {"name": "validator-service", "cluster": "arn:aws:ecs:us-east-1:123456789012:cluster/validator"}},
+        {"address": "module.ecs.aws_route_table.private", "type": "aws_route_table", "name": "private", "values": {"id": "rtb-1", "route": [{"cidr_block": "10.1.0.0/16", "gateway_id": "pcx-1"}]}},
+        {"address": "module.ecs.aws_route_table.validator_rt", "type": "aws_route_table", "name": "validator_rt", "values": {"id": "rtb-2", "route": [{"cidr_block": "0.0.0.0/0", "gateway_id": "igw-1"}]}}
+      ]
+    }
+  },
+  "resource_changes": [
+    {"address": "module.ecs.aws_ecs_service.validator_service", "type": "aws_ecs_service", "name": "validator_service", "change": {"actions": ["no-op"]}},
+    {"address": "module.ecs.aws_route_table.validator_rt", "type": "aws_route_table", "name": "validator_rt", "change": {"actions": ["update"]}}
+  ]
+}`
+
+func loadPlan(t *testing.T) *tfjson.Plan {
+	t.Helper()
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(sandboxPlan), &plan))
+	return &plan
+}
+
+// fakeECS runs one task that is replaced two polls after it is stopped.
+type fakeECS struct {
+	ECSAPI
+	task    string
+	stopped []string
+	polls   int
+}
+
+func (f *fakeECS) ListTasks(_ context.Context, in *ecs.ListTasksInput, _ ...func(*ecs.Options)) (*ecs.ListTasksOutput, error) {
+	if len(f.stopped) > 0 && f.polls < 2 {
+		return &ecs.ListTasksOutput{}, nil
+	}
+	return &ecs.ListTasksOutput{TaskArns: []string{f.task}}, nil
+}
+
+func (f *fakeECS) StopTask(_ context.Context, in *ecs.StopTaskInput, _ ...func(*ecs.Options)) (*ecs.StopTaskOutput, error) {
+	f.stopped = append(f.stopped, aws.ToString(in.Task))
+	f.task = "arn:aws:ecs:us-east-1:123456789012:task/validator/replacement"
+	return &ecs.StopTaskOutput{}, nil
+}
+
+func (f *fakeECS) DescribeServices(_ context.Context, in *ecs.DescribeServicesInput, _ ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error) {
+	f.polls++
+	running := int32(0)
+	if f.polls >= 2 {
+		running = 1
+	}
+	return &ecs.DescribeServicesOutput{Services: []types.Service{
+		{ServiceName: aws.String(in.Services[0]), DesiredCount: 1, RunningCount: running},
+	}}, nil
+}
+
+type fakeEC2 struct {
+	EC2API
+	deleted []*ec2.DeleteRouteInput
+	created []*ec2.CreateRouteInput
+}
+
+func (f *fakeEC2) DeleteRoute(_ context.Context, in *ec2.DeleteRouteInput, _ ...func(*ec2.Options)) (*ec2.DeleteRouteOutput, error) {
+	f.deleted = append(f.deleted, in)
+	return &ec2.DeleteRouteOutput{}, nil
+}
+
+func (f *fakeEC2) CreateRoute(_ context.Context, in *ec2.CreateRouteInput, _ ...func(*ec2.Options)) (*ec2.CreateRouteOutput, error) {
+	f.created = append(f.created, in)
+	return &ec2.CreateRouteOutput{Return: aws.Bool(true)}, nil
+}
+
+func TestStopTaskWaitsForReplacement(t *testing.T) {
+	fake := &fakeECS{task: "arn:aws:ecs:us-east-1:123456789012:task/validator/original"}
+	clients := &Clients{ECS: fake, PollInterval: time.Millisecond}
+
+	faults, err := clients.Faults(loadPlan(t), []string{"stop-task"})
+	require.NoError(t, err)
+	require.Len(t, faults, 1)
+	fault := faults[0]
+	require.Equal(t, "module.ecs.aws_ecs_service.validator_service", fault.Address)
+	require.False(t, fault.ExpectDrift)
+
+	ctx := context.Background()
+	require.NoError(t, fault.Inject(ctx))
+	require.Equal(t, []string{"arn:aws:ecs:us-east-1:123456789012:task/validator/original"}, fake.stopped)
+	require.NoError(t, fault.Restore(ctx))
+	require.GreaterOrEqual(t, fake.polls, 2, "restore waits until the replacement runs")
+}
+
+func TestStopTaskGivesUpWhenServiceDoesNotRecover(t *testing.T) {
+	fake := &fakeECS{task: "arn:aws:ecs:us-east-1:123456789012:task/validator/original"}
+	clients := &Clients{ECS: fake, PollInterval: 50 * time.Millisecond}
+	faults, err := clients.Faults(loadPlan(t), []string{"stop-task"})
+	require.NoError(t, err)
+
+	require.NoError(t, faults[0].Inject(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = faults[0].Restore(ctx)
+	require.ErrorContains(t, err, "chaos: restoring stop-task on module.ecs.aws_ecs_service.validator_service: gave up waiting for service validator-service to replace the stopped task")
+}
+
+func TestRemoveDefaultRouteRecreatesRoute(t *testing.T) {
+	fake := &fakeEC2{}
+	clients := &Clients{EC2: fake}
+
+	faults, err := clients.Faults(loadPlan(t), []string{"remove-default-route"})
+	require.NoError(t, err)
+	require.Len(t, faults, 1)
+	fault := faults[0]
+	require.Equal(t, "module.ecs.aws_route_table.validator_rt", fault.Address)
+	require.True(t, fault.ExpectDrift)
+
+	ctx := context.Background()
+	require.NoError(t, fault.Inject(ctx))
+	require.Equal(t, []*ec2.DeleteRouteInput{{RouteTableId: aws.String("rtb-2"), DestinationCidrBlock: aws.String("0.0.0.0/0")}}, fake.deleted)
+	require.NoError(t, fault.Restore(ctx))
+	require.NoError(t, fault.Restore(ctx), "a second restore is a no-op")
+	require.Equal(t, []*ec2.CreateRouteInput{{RouteTableId: aws.String("rtb-2"), DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-1")}}, fake.created)
+}
+
+func TestFaultsRejectsUnknownExperiment(t *testing.T) {
+	_, err := (&Clients{}).Faults(loadPlan(t), []string{"kill-az"})
+	require.EqualError(t, err, `chaos: unknown experiment "kill-az" (known: [stop-task remove-default-route])`)
+}
+
+func TestDriftIgnoresNoOps(t *testing.T) {
+	plan := loadPlan(t)
+	require.Empty(t, Drift(plan, "module.ecs.aws_ecs_service.validator_service"))
+	require.Equal(t, tfjson.Actions{tfjson.ActionUpdate}, Drift(plan, "module.ecs.aws_route_table.validator_rt"))
+	require.Empty(t, Drift(plan, "aws_s3_bucket.missing"))
+}
@@ -0,0 +1,190 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/plancheck"
+)
+
+// stopTask stops one running task of the first ECS service in the plan.
+// ECS must start a replacement on its own: the service's desired count is
+// unchanged, so terraform must see no drift.
+func (c *Clients) stopTask(plan *tfjson.Plan) *Fault {
+	services := plancheck.Resources(plan, "aws_ecs_service")
+	if len(services) == 0 {
+		return nil
+	}
+	service := services[0]
+	cluster := plancheck.LookupString(service.AttributeValues, "cluster")
+	name := plancheck.LookupString(service.AttributeValues, "name")
+	if cluster == "" || name == "" {
+		return nil
+	}
+
+	var stopped string
+	return &Fault{
+		Experiment:  "stop-task",
+		Address:     service.Address,
+		Description: fmt.Sprintf("stop one running task of ECS service %s", name),
+		inject: func(ctx context.Context) error {
+			tasks, err := c.ECS.ListTasks(ctx, &ecs.ListTasksInput{
+				Cluster:       aws.String(cluster),
+				ServiceName:   aws.String(name),
+				DesiredStatus: types.DesiredStatusRunning,
+			})
+			if err != nil {
+				return err
+			}
+			if len(tasks.TaskArns) == 0 {
+				return fmt.Errorf("service %s has no running task to stop", name)
+			}
+			stopped = tasks.TaskArns[0]
+			_, err = c.ECS.StopTask(ctx, &ecs.StopTaskInput{
+				Cluster: aws.String(cluster),
+				Task:    aws.String(stopped),
+				Reason:  aws.String("chaos: stop-task experiment"),
+			})
+			return err
+		},
+		restore: func(ctx context.Context) error {
+			return awsapi.Poll(ctx, c.pollInterval(), "service "+name+" to replace the stopped task", func() (bool, error) {
+				return c.serviceRecovered(ctx, cluster, name, stopped)
+			})
+		},
+	}
+}
+
+// serviceRecovered reports whether a service runs its desired count of
+// tasks, none of them the stopped one.
+func (c *Clients) serviceRecovered(ctx context.Context, cluster, name, stopped string) (bool, error) {
+	out, err := c.ECS.DescribeServices(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(cluster),
+		Services: []string{name},
+	})
+	if err != nil {
+		return false, err
+	}
+	if len(out.Services) == 0 {
+		return false, fmt.Errorf("service %s not found", name)
+	}
+	service := out.Services[0]
+	if service.RunningCount < service.DesiredCount {
+		return false, nil
+	}
+	tasks, err := c.ECS.ListTasks(ctx, &ecs.ListTasksInput{
+		Cluster:       aws.String(cluster),
+		ServiceName:   aws.String(name),
+		DesiredStatus: types.DesiredStatusRunning,
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, task := range tasks.TaskArns {
+		if task == stopped {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// defaultRoute is a 0.0.0.0/0 route of a planned route table.
+type defaultRoute struct {
+	address      string
+	routeTableID string
+	gatewayID    string
+	natGatewayID string
+}
+
+// removeDefaultRoute deletes the 0.0.0.0/0 route of the first route table
+// with one, cutting the subnets it serves off from the internet as a failed
+// NAT gateway would. Terraform must plan to put the route back.
+func (c *Clients) removeDefaultRoute(plan *tfjson.Plan) *Fault {
+	route, ok := findDefaultRoute(plan)
+	if !ok {
+		return nil
+	}
+	target := route.gatewayID
+	if route.natGatewayID != "" {
+		target = route.natGatewayID
+	}
+
+	var removed bool
+	return &Fault{
+		Experiment:  "remove-default-route",
+		Address:     route.address,
+		Description: fmt.Sprintf("delete the 0.0.0.0/0 route to %s from route table %s", target, route.routeTableID),
+		ExpectDrift: true,
+		inject: func(ctx context.Context) error {
+			_, err := c.EC2.DeleteRoute(ctx, &ec2.DeleteRouteInput{
+				RouteTableId:         aws.String(route.routeTableID),
+				DestinationCidrBlock: aws.String("0.0.0.0/0"),
+			})
+			removed = err == nil
+			return err
+		},
+		restore: func(ctx context.Context) error {
+			if !removed {
+				return nil
+			}
+			input := &ec2.CreateRouteInput{
+				RouteTableId:         aws.String(route.routeTableID),
+				DestinationCidrBlock: aws.String("0.0.0.0/0"),
+			}
+			if route.natGatewayID != "" {
+				input.NatGatewayId = aws.String(route.natGatewayID)
+			} else {
+				input.GatewayId = aws.String(route.gatewayID)
+			}
+			if _, err := c.EC2.CreateRoute(ctx, input); err != nil {
+				return err
+			}
+			removed = false
+			return nil
+		},
+	}
+}
+
+// findDefaultRoute returns the first 0.0.0.0/0 route through an internet or
+// NAT gateway, declared inline on an aws_route_table or as an aws_route.
+func findDefaultRoute(plan *tfjson.Plan) (defaultRoute, bool) {
+	for _, table := range plancheck.Resources(plan, "aws_route_table") {
+		id := plancheck.LookupString(table.AttributeValues, "id")
+		for _, block := range plancheck.Blocks(table.AttributeValues, "route") {
+			if plancheck.LookupString(block, "cidr_block") != "0.0.0.0/0" {
+				continue
+			}
+			route := defaultRoute{
+				address:      table.Address,
+				routeTableID: id,
+				gatewayID:    plancheck.LookupString(block, "gateway_id"),
+				natGatewayID: plancheck.LookupString(block, "nat_gateway_id"),
+			}
+			if id != "" && (route.gatewayID != "" || route.natGatewayID != "") {
+				return route, true
+			}
+		}
+	}
+	for _, resource := range plancheck.Resources(plan, "aws_route") {
+		if plancheck.LookupString(resource.AttributeValues, "destination_cidr_block") != "0.0.0.0/0" {
+			continue
+		}
+		route := defaultRoute{
+			address:      resource.Address,
+			routeTableID: plancheck.LookupString(resource.AttributeValues, "route_table_id"),
+			gatewayID:    plancheck.LookupString(resource.AttributeValues, "gateway_id"),
+			natGatewayID: plancheck.LookupString(resource.AttributeValues, "nat_gateway_id"),
+		}
+		if route.routeTableID != "" && (route.gatewayID != "" || route.natGatewayID != "") {
+			return route, true
+		}
+	}
+	return defaultRoute{}, false
+}
@@ -0,0 +1,114 @@
+package terraformtests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/chaos"
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/smoke"
+)
+
+// chaosEnv enables the chaos stage. It breaks the deployed stack on purpose,
+// so it is never implied by COMPLIANCE_POST_APPLY.
+const chaosEnv = "COMPLIANCE_CHAOS"
+
+// Each fault is injected into a deployed sandbox, the sandbox is planned
+// again to check terraform sees the drift the fault should cause (and none
+// from the faults the stack heals itself), then the fault is undone and the
+// validator service's health check must pass again within
+// chaos.recovery_seconds. Environments compliance.yaml does not mark
+// sandbox: true are skipped.
+func TestSandboxRecoversFromFaults(t *testing.T) {
+	if os.Getenv(chaosEnv) == "" {
+		t.Skipf("set %s=1 to inject faults into the deployed sandboxes", chaosEnv)
+	}
+	skipWithoutPlanning(t)
+
+	config, err := plancheck.LoadConfig(complianceFile)
+	require.NoError(t, err)
+	experiments := config.Chaos.Experiments
+	if len(experiments) == 0 {
+		experiments = chaos.Experiments
+	}
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		if !config.Environment(env.Name).Sandbox {
+			t.Skipf("refusing to inject faults into %s, which %s does not mark sandbox: true", env.Name, complianceFile)
+		}
+
+		options, plan := environmentPlan(t, env.Name)
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		clients, err := chaos.NewClients(env.Region, creds.AWS())
+		require.NoError(t, err)
+		faults, err := clients.Faults(plan, experiments)
+		require.NoError(t, err)
+		if len(faults) == 0 {
+			t.Skipf("the %s plan has nothing for %v to hit", env.Name, experiments)
+		}
+
+		health := smoke.HealthCheck{
+			Address: "validator_service_url",
+			URL:     strings.TrimSuffix(terraform.OutputRequired(t, options, "validator_service_url"), "/") + "/health",
+		}
+		checker := &smoke.Checker{}
+		require.NoError(t, checker.Healthy(context.Background(), health), "the sandbox must be healthy before faults are injected")
+
+		for _, fault := range faults {
+			fault := fault
+			t.Run(fault.Experiment, func(t *testing.T) {
+				t.Logf("%s: %s", fault.Address, fault.Description)
+				t.Cleanup(func() {
+					ctx, cancel := context.WithTimeout(context.Background(), config.Chaos.Recovery())
+					defer cancel()
+					if err := fault.Restore(ctx); err != nil {
+						t.Errorf("%v; the sandbox may need terraform apply", err)
+					}
+				})
+				require.NoError(t, fault.Inject(context.Background()))
+
+				planFile, err := planFilePath("chaos-" + fault.Experiment)
+				require.NoError(t, err)
+				cleanupArtifacts(t, filepath.Dir(planFile))
+				drifted := *options
+				drifted.PlanFilePath = planFile
+				driftPlan, err := showPlanE(t, &drifted)
+				require.NoError(t, err, "dev must plan with the fault in place")
+				actions := chaos.Drift(driftPlan, fault.Address)
+				if fault.ExpectDrift {
+					require.NotEmpty(t, actions, "terraform plan must propose to repair %s", fault.Address)
+				} else {
+					require.Empty(t, actions, "terraform plan must leave %s alone; the stack should heal %s by itself", fault.Address, fault.Experiment)
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), config.Chaos.Recovery())
+				defer cancel()
+				require.NoError(t, fault.Restore(ctx))
+				require.NoError(t, waitHealthy(ctx, checker, health), "%s must recover from %s", health.URL, fault.Experiment)
+			})
+		}
+	})
+}
+
+// waitHealthy polls a health check until it passes or ctx is done, returning
+// the last failure.
+func waitHealthy(ctx context.Context, checker *smoke.Checker, check smoke.HealthCheck) error {
+	var last error
+	err := awsapi.Poll(ctx, 5*time.Second, check.URL+" to be healthy", func() (bool, error) {
+		last = checker.Healthy(ctx, check)
+		return last == nil, nil
+	})
+	if err != nil && last != nil {
+		return last
+	}
+	return err
+}
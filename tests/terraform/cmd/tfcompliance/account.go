@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"cs450/terraformtests/awsauth"
+	"cs450/terraformtests/livestate"
+	"cs450/terraformtests/plancheck"
+)
+
+func runAccount(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("account", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	environment := flags.String("env", "dev", "environment whose account is checked, for its role and compliance.yaml settings")
+	configFile := flags.String("config", "compliance.yaml", "compliance configuration file")
+	region := flags.String("region", "us-east-1", "region whose EBS default encryption and default security groups are checked")
+	baselineFile := flags.String("baseline", "baseline.yaml", "baseline of accepted findings")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := plancheck.LoadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	baseline, err := plancheck.LoadBaseline(*baselineFile)
+	if err != nil {
+		return err
+	}
+	rules, err := lookupRules([]string{"account.baseline"})
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	creds, err := awsauth.ForEnvironment(ctx, config, *environment, *region)
+	if err != nil {
+		return err
+	}
+	preflight, err := awsauth.NewPreflight(*region, creds.AWS())
+	if err != nil {
+		return err
+	}
+	identity, err := preflight.Check(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "checking account %s of %s as %s\n", identity.Account, *environment, identity)
+
+	clients, err := livestate.NewClients(*region, creds.AWS())
+	if err != nil {
+		return err
+	}
+	live, err := clients.AccountBaseline(identity.Account)
+	if err != nil {
+		return err
+	}
+	findings := plancheck.Evaluate(&plancheck.Input{Plan: live, DefaultRegion: *region, Environment: *environment, Config: config}, rules...)
+	findings, _ = baseline.Filter(findings)
+	for _, finding := range findings {
+		fmt.Fprintf(stdout, "%s %s\n    at %s\n", finding.Label(), finding.Message, finding.Location())
+	}
+	if len(findings) > 0 {
+		plancheck.WriteSummary(stdout, findings)
+		return fmt.Errorf("%d finding(s)", len(findings))
+	}
+	fmt.Fprintln(stdout, "no findings")
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/quota"
+	"cs450/terraformtests/runlock"
+	"cs450/terraformtests/runner"
+)
+
+// runApply applies a plan file that tfcompliance check approved, and only
+// after checking it again: the approval must be signed by the expected key,
+// unexpired and for these very bytes, and the plan must still pass the
+// rules, which may have changed since it was approved. The environment is
+// locked while it is applied.
+func runApply(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("apply", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var p planFlags
+	p.register(flags)
+	planFile := flags.String("plan-file", "", "binary plan file tfcompliance check -out wrote (required)")
+	approvalFile := flags.String("approval", "", "approval tfcompliance check -approval wrote for the plan file (required)")
+	keyFile := flags.String("key", "", "PEM-encoded Ed25519 public key the approval must be signed with (required)")
+	ruleList := flags.String("rule", "", "comma-separated rules the plan must still pass (default: all)")
+	baselineFile := flags.String("baseline", "baseline.yaml", "baseline of accepted findings")
+	quotas := flags.Bool("quotas", false, "refuse to apply a plan that would exceed the account's service quotas")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *planFile == "" || *approvalFile == "" || *keyFile == "" {
+		return errors.New("-plan-file, -approval and -key are required")
+	}
+
+	key, err := plancheck.LoadVerifyingKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	approval, err := plancheck.ReadApproval(*approvalFile)
+	if err != nil {
+		return err
+	}
+	if approval.Environment != p.environment {
+		return fmt.Errorf("the approval is for %s, not %s", approval.Environment, p.environment)
+	}
+	if err := approval.Verify(*planFile, key, time.Now()); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "approval of %s for %s is valid until %s\n", *planFile, p.environment, approval.Expires.Format(time.RFC3339))
+	if version := plancheck.PackVersion(); approval.PackVersion != version {
+		fmt.Fprintf(stdout, "the plan was approved under policy pack %s and is checked again under %s\n", approval.PackVersion, version)
+	}
+
+	if err := loadPlugins(p.plugins); err != nil {
+		return err
+	}
+	var ids []string
+	if *ruleList != "" {
+		ids = strings.Split(*ruleList, ",")
+	}
+	rules, err := lookupRules(ids)
+	if err != nil {
+		return err
+	}
+	baseline, err := plancheck.LoadBaseline(*baselineFile)
+	if err != nil {
+		return err
+	}
+	p.baseline = baseline
+
+	ctx := context.Background()
+	if err := p.prepare(ctx, stdout); err != nil {
+		return err
+	}
+	opts := runner.Options{Dir: p.dir, Env: p.env}
+	plan, err := runner.Show(ctx, opts, *planFile)
+	if err != nil {
+		return err
+	}
+	findings, _, err := p.evaluate(plan, rules)
+	if err != nil {
+		return err
+	}
+	findings, _ = baseline.Filter(findings)
+	findings, _ = plancheck.TagSuppressions(plan).Filter(findings)
+	if blocking, _ := plancheck.SplitAdvisories(findings); len(blocking) > 0 {
+		for _, finding := range blocking {
+			fmt.Fprintf(stdout, "%s %s\n    at %s\n", finding.Label(), finding.Message, finding.Location())
+		}
+		return fmt.Errorf("the approved plan no longer passes: %d finding(s); nothing applied", len(blocking))
+	}
+
+	config, err := plancheck.LoadConfig(p.config)
+	if err != nil {
+		return err
+	}
+	if *quotas {
+		clients, err := quota.NewClients(p.region, p.creds.AWS())
+		if err != nil {
+			return err
+		}
+		results, err := clients.Check(ctx, plan)
+		if err != nil {
+			return err
+		}
+		if exceeded := quota.Exceeded(results); len(exceeded) > 0 {
+			for _, result := range exceeded {
+				fmt.Fprintf(stdout, "would exceed a quota: %s\n", result)
+			}
+			return errors.New("the plan does not fit the account's service quotas; nothing applied")
+		}
+	}
+
+	if config.Lock.Table != "" {
+		client, err := runlock.NewClient(config.Lock.TableRegion(), config.Lock.Table, p.creds.AWS())
+		if err != nil {
+			return err
+		}
+		client.StaleAfter = config.Lock.StaleAfter()
+		lock, err := client.Acquire(ctx, p.environment, runlock.DefaultOwner())
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := lock.Release(context.Background()); err != nil {
+				fmt.Fprintf(stderr, "releasing the run lock on %s: %v\n", p.environment, err)
+			}
+		}()
+	}
+
+	// The file may have been replaced while the rules ran.
+	if err := approval.Verify(*planFile, key, time.Now()); err != nil {
+		return err
+	}
+	if err := runner.Apply(ctx, opts, *planFile, stdout); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "applied %s to %s\n", *planFile, p.environment)
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/runner"
+)
+
+// fakeApplyingTerraform logs apply calls and shows a log group whose
+// retention is unset while a file named "unretained" exists.
+const fakeApplyingTerraform = `#!/bin/sh
+if [ "$1" = "apply" ]; then echo "$@" >> applied.log; exit 0; fi
+if [ "$1" != "show" ]; then exit 0; fi
+retention=30
+if [ -f unretained ]; then retention=0; fi
+cat <<JSON
+{"format_version":"1.0","planned_values":{"root_module":{"resources":[
+  {"address":"aws_cloudwatch_log_group.api","mode":"managed","type":"aws_cloudwatch_log_group","name":"api",
+   "values":{"name":"/ecs/api","retention_in_days":$retention}}]}}}
+JSON
+`
+
+func TestApplyReverifiesTheApprovedPlan(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "terraform")
+	require.NoError(t, os.WriteFile(bin, []byte(fakeApplyingTerraform), 0o755))
+	t.Setenv(runner.BinaryEnv, bin)
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, "compliance.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("environments: {}\n"), 0o644))
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "approval.pub")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644))
+
+	planFile := filepath.Join(dir, "dev.tfplan")
+	require.NoError(t, os.WriteFile(planFile, []byte("binary plan\n"), 0o644))
+	approval, err := plancheck.NewApproval("dev", planFile, nil, time.Now(), time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, approval.Sign(private))
+	approvalFile := filepath.Join(dir, "approval.json")
+	require.NoError(t, plancheck.WriteApproval(approvalFile, approval))
+
+	apply := func() (string, error) {
+		var out bytes.Buffer
+		err := runApply([]string{
+			"-preflight=false", "-dir", dir, "-config", config, "-rule", "logs.retention",
+			"-baseline", filepath.Join(dir, "baseline.yaml"),
+			"-plan-file", planFile, "-approval", approvalFile, "-key", keyFile,
+		}, &out, &out)
+		return out.String(), err
+	}
+	applied := func() string {
+		data, _ := os.ReadFile(filepath.Join(dir, "applied.log"))
+		return strings.TrimSpace(string(data))
+	}
+
+	// A rule the plan fails now, though it passed when approved.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unretained"), nil, 0o644))
+	out, err := apply()
+	require.ErrorContains(t, err, "no longer passes")
+	require.Contains(t, out, "[logs.retention]")
+	require.Empty(t, applied())
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "unretained")))
+	out, err = apply()
+	require.NoError(t, err, out)
+	require.Equal(t, "apply -input=false -no-color "+planFile, applied())
+
+	require.NoError(t, os.WriteFile(planFile, []byte("another plan\n"), 0o644))
+	_, err = apply()
+	require.ErrorContains(t, err, "is not the approved dev plan")
+	require.Equal(t, "apply -input=false -no-color "+planFile, applied(), "a changed plan file must not be applied")
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func runCheck(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var p planFlags
+	p.register(flags)
+	ruleList := flags.String("rule", "", "comma-separated rules to run (default: all)")
+	planFile := flags.String("plan", "", "plan JSON (terraform show -json) to check instead of planning -dir; needs neither terraform nor AWS credentials")
+	baselineFile := flags.String("baseline", "baseline.yaml", "baseline of accepted findings")
+	watch := flags.Bool("watch", false, "re-plan and re-check whenever a .tf file under -watch-dir changes")
+	watchDir := flags.String("watch-dir", "../../infra", "directory watched for .tf changes")
+	interval := flags.Duration("interval", time.Second, "how often -watch polls for changes")
+	locales := flags.String("locales", "locales", "directory of message catalogs selected by "+plancheck.LocaleEnv)
+	flags.StringVar(&p.out, "out", "", "keep the binary plan made from -dir in this file, for terraform apply")
+	approvalFile := flags.String("approval", "", "when the check passes, write an approval of the -out plan file to this file for the deploy job")
+	approvalTTL := flags.Duration("approval-ttl", plancheck.DefaultApprovalTTL, "how long the approval stays valid")
+	keyFile := flags.String("key", "", "PEM-encoded Ed25519 private key to sign the approval with (default $"+plancheck.ManifestKeyEnv+")")
+	failuresFile := flags.String("failures", "", "where each check records its blocking findings for -failed-only (default .tfcompliance/<env>-failures.json)")
+	var focus listFlag
+	flags.Var(&focus, "focus", "address or pattern, e.g. 'module.artifacts.*', of the resources to report findings on (repeatable; default: all)")
+	failedOnly := flags.Bool("failed-only", false, "re-run only the rules that failed last time, reporting only the resources they failed on")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *planFile != "" && *watch {
+		return errors.New("-plan and -watch cannot be combined: a plan file does not change with the .tf files")
+	}
+	if p.out != "" && (*planFile != "" || *watch) {
+		return errors.New("-out keeps a plan made from -dir; it cannot be combined with -plan or -watch")
+	}
+	if *approvalFile != "" && p.out == "" {
+		return errors.New("-approval needs -out: only a plan file made by the check can be approved")
+	}
+	if *approvalFile != "" && (*ruleList != "" || len(focus) > 0 || *failedOnly) {
+		return errors.New("-approval approves the whole plan; it cannot be combined with -rule, -focus or -failed-only, which check part of it")
+	}
+	if *failedOnly && (*ruleList != "" || *watch) {
+		return errors.New("-failed-only picks the rules from the last run; it cannot be combined with -rule or -watch")
+	}
+	if *keyFile == "" {
+		*keyFile = os.Getenv(plancheck.ManifestKeyEnv)
+	}
+	var err error
+	if p.focus, err = plancheck.ParseFocus(focus); err != nil {
+		return err
+	}
+	if *failuresFile == "" {
+		*failuresFile = filepath.Join(".tfcompliance", p.environment+"-failures.json")
+	}
+	if err := loadPlugins(p.plugins); err != nil {
+		return err
+	}
+	var ids []string
+	if *ruleList != "" {
+		ids = strings.Split(*ruleList, ",")
+	}
+	var failures plancheck.Failures
+	if *failedOnly {
+		if failures, err = plancheck.LoadFailures(*failuresFile); err != nil {
+			return err
+		}
+		if failures.Empty() {
+			fmt.Fprintf(stdout, "no failures recorded in %s; run a full check\n", *failuresFile)
+			return nil
+		}
+		ids = failures.RuleIDs()
+		fmt.Fprintf(stdout, "re-checking %d rule(s) on %d resource(s) that failed last time\n", len(ids), failures.Resources())
+	}
+	rules, err := lookupRules(ids)
+	if err != nil {
+		return err
+	}
+	baseline, err := plancheck.LoadBaseline(*baselineFile)
+	if err != nil {
+		return err
+	}
+	p.baseline = baseline
+	catalog, err := plancheck.LoadCatalogFromEnv(*locales)
+	if err != nil {
+		return err
+	}
+
+	check := func(ctx context.Context, skipInit bool) ([]plancheck.Finding, error) {
+		var (
+			plan *tfjson.Plan
+			err  error
+		)
+		if *planFile != "" {
+			plan, err = readPlan(*planFile)
+		} else {
+			plan, err = p.plan(ctx, skipInit)
+		}
+		if err != nil {
+			return nil, err
+		}
+		findings, _, err := p.evaluate(plan, rules)
+		if err != nil {
+			return nil, err
+		}
+		open, _ := baseline.Filter(findings)
+		open, _ = plancheck.TagSuppressions(plan).Filter(open)
+		if *failedOnly {
+			open = failures.Filter(open)
+		}
+		catalog.Localize(open)
+		return open, nil
+	}
+
+	if *planFile == "" {
+		if err := p.prepare(context.Background(), stdout); err != nil {
+			return err
+		}
+	}
+
+	if len(p.focus) > 0 {
+		fmt.Fprintf(stdout, "focusing on %s\n", strings.Join(p.focus, ", "))
+	}
+	if !*watch {
+		findings, err := check(context.Background(), false)
+		if err != nil {
+			return err
+		}
+		// -rule, -focus and -failed-only re-check part of the plan, so only
+		// the failures they re-checked are replaced.
+		if err := plancheck.UpdateFailures(*failuresFile, ids, p.focus, findings); err != nil {
+			return err
+		}
+		for _, finding := range findings {
+			fmt.Fprintf(stdout, "%s %s\n    at %s\n", finding.Label(), finding.Message, finding.Location())
+		}
+		// Advisories are printed but do not fail the check.
+		blocking, advisories := plancheck.SplitAdvisories(findings)
+		if len(blocking) > 0 {
+			plancheck.WriteSummary(stdout, findings)
+			return fmt.Errorf("%d finding(s)", len(blocking))
+		}
+		if len(advisories) > 0 {
+			fmt.Fprintf(stdout, "no findings, %d advisory finding(s)\n", len(advisories))
+			return writeApproval(stdout, *approvalFile, p.environment, p.out, findings, *approvalTTL, *keyFile)
+		}
+		fmt.Fprintln(stdout, "no findings")
+		return writeApproval(stdout, *approvalFile, p.environment, p.out, findings, *approvalTTL, *keyFile)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return watchLoop(ctx, stdout, *watchDir, *interval, check)
+}
+
+// writeApproval approves planFile, which passed the check with findings
+// left open, when approvalFile is set, signing the approval when keyFile is.
+func writeApproval(stdout io.Writer, approvalFile, environment, planFile string, findings []plancheck.Finding, ttl time.Duration, keyFile string) error {
+	if approvalFile == "" {
+		return nil
+	}
+	approval, err := plancheck.NewApproval(environment, planFile, findings, time.Now(), ttl)
+	if err != nil {
+		return err
+	}
+	if keyFile != "" {
+		key, err := plancheck.LoadSigningKey(keyFile)
+		if err != nil {
+			return err
+		}
+		if err := approval.Sign(key); err != nil {
+			return err
+		}
+	}
+	if err := plancheck.WriteApproval(approvalFile, approval); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "approved %s for %s until %s: %s\n", planFile, environment, approval.Expires.Format(time.RFC3339), approvalFile)
+	return nil
+}
+
+// watchLoop checks once with a full init, then re-checks with the cached init
+// whenever the .tf files under dir change, printing only what changed. Plan
+// errors are reported and the loop keeps watching.
+func watchLoop(ctx context.Context, w io.Writer, dir string, interval time.Duration, check func(ctx context.Context, skipInit bool) ([]plancheck.Finding, error)) error {
+	// Snapshot before planning so edits made during the first plan are seen.
+	snapshot, err := tfSnapshot(dir)
+	if err != nil {
+		return err
+	}
+	previous, err := check(ctx, false)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%d finding(s); watching %s for changes\n", len(previous), dir)
+	for _, finding := range previous {
+		fmt.Fprintf(w, "  %s %s\n    at %s\n", finding.Label(), finding.Message, finding.Location())
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := tfSnapshot(dir)
+		if err != nil {
+			return err
+		}
+		if snapshotsEqual(snapshot, current) {
+			continue
+		}
+		snapshot = current
+
+		fmt.Fprintf(w, "\n%s change detected, re-planning\n", time.Now().Format("15:04:05"))
+		findings, err := check(ctx, true)
+		if err != nil {
+			fmt.Fprintf(w, "%v\n", err)
+			continue
+		}
+		printComparison(w, plancheck.Compare(previous, findings))
+		previous = findings
+	}
+}
+
+// tfSnapshot records the modification time and size of every .tf file under dir.
+func tfSnapshot(dir string) (map[string]string, error) {
+	snapshot := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == ".terraform" {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".tf" {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		snapshot[path] = fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+		return nil
+	})
+	return snapshot, err
+}
+
+func snapshotsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, stamp := range a {
+		if b[path] != stamp {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/runner"
+)
+
+func TestWatchLoopReportsChangesAfterEdits(t *testing.T) {
+	dir := t.TempDir()
+	mainTF := filepath.Join(dir, "main.tf")
+	require.NoError(t, os.WriteFile(mainTF, []byte("# v1\n"), 0o644))
+
+	logs := plancheck.NewFinding("logs.retention", "aws_cloudwatch_log_group.api", "never expires")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var calls []bool
+	check := func(_ context.Context, skipInit bool) ([]plancheck.Finding, error) {
+		calls = append(calls, skipInit)
+		if len(calls) == 1 {
+			require.NoError(t, os.WriteFile(mainTF, []byte("# v2, fixed\n"), 0o644))
+			return []plancheck.Finding{logs}, nil
+		}
+		cancel()
+		return nil, nil
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, watchLoop(ctx, &out, dir, 10*time.Millisecond, check))
+	require.Equal(t, []bool{false, true}, calls, "first check runs init, re-checks reuse it")
+	require.Contains(t, out.String(), "1 finding(s); watching")
+	require.Contains(t, out.String(), "- [logs.retention] never expires")
+	require.Contains(t, out.String(), "0 new, 1 fixed, 0 changed, 0 moved")
+}
+
+func TestCheckEvaluatesPlanFileWithoutTerraform(t *testing.T) {
+	// An empty PATH proves terraform is never run.
+	t.Setenv("PATH", "")
+	dir := t.TempDir()
+	planFile := filepath.Join(dir, "plan.json")
+	require.NoError(t, os.WriteFile(planFile, []byte(`{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"resources": [
+    {"address": "aws_cloudwatch_log_group.api", "mode": "managed", "type": "aws_cloudwatch_log_group", "name": "api", "values": {"name": "/ecs/api"}}
+  ]}}
+}`), 0o644))
+
+	var out bytes.Buffer
+	err := runCheck([]string{
+		"-plan", planFile,
+		"-rule", "logs.retention",
+		"-dir", dir,
+		"-config", "../../compliance.yaml",
+		"-baseline", filepath.Join(dir, "baseline.yaml"),
+		"-locales", filepath.Join(dir, "locales"),
+		"-failures", filepath.Join(dir, "failures.json"),
+	}, &out, &out)
+	require.EqualError(t, err, "1 finding(s)")
+	require.NotContains(t, out.String(), "planning dev as")
+	require.Contains(t, out.String(), "[logs.retention] log group aws_cloudwatch_log_group.api")
+
+	err = runCheck([]string{"-plan", "plan.json", "-watch"}, &out, &out)
+	require.ErrorContains(t, err, "-plan and -watch cannot be combined")
+}
+
+func TestCheckFailedOnlyRechecksLastFailures(t *testing.T) {
+	t.Setenv("PATH", "")
+	dir := t.TempDir()
+	planFile := filepath.Join(dir, "plan.json")
+	writePlan := func(resources string) {
+		require.NoError(t, os.WriteFile(planFile, []byte(`{"format_version": "1.2", "planned_values": {"root_module": {"resources": [`+resources+`]}}}`), 0o644))
+	}
+	const (
+		api     = `{"address": "aws_cloudwatch_log_group.api", "mode": "managed", "type": "aws_cloudwatch_log_group", "name": "api", "values": {"name": "/ecs/api"}}`
+		apiKept = `{"address": "aws_cloudwatch_log_group.api", "mode": "managed", "type": "aws_cloudwatch_log_group", "name": "api", "values": {"name": "/ecs/api", "retention_in_days": 30}}`
+		worker  = `{"address": "aws_cloudwatch_log_group.worker", "mode": "managed", "type": "aws_cloudwatch_log_group", "name": "worker", "values": {"name": "/ecs/worker"}}`
+	)
+	failuresFile := filepath.Join(dir, ".tfcompliance", "dev-failures.json")
+	run := func(extra ...string) (string, error) {
+		var out bytes.Buffer
+		err := runCheck(append([]string{
+			"-plan", planFile,
+			"-config", "../../compliance.yaml",
+			"-baseline", filepath.Join(dir, "baseline.yaml"),
+			"-locales", filepath.Join(dir, "locales"),
+			"-failures", failuresFile,
+		}, extra...), &out, &out)
+		return out.String(), err
+	}
+
+	out, err := run("-failed-only")
+	require.NoError(t, err)
+	require.Contains(t, out, "no failures recorded in "+failuresFile+"; run a full check")
+
+	writePlan(api)
+	_, err = run("-rule", "logs.retention")
+	require.EqualError(t, err, "1 finding(s)")
+
+	// The new worker log group fails the same rule, but only the resource
+	// that failed last time is reported.
+	writePlan(api + "," + worker)
+	out, err = run("-failed-only")
+	require.EqualError(t, err, "1 finding(s)")
+	require.Contains(t, out, "re-checking 1 rule(s) on 1 resource(s) that failed last time")
+	require.Contains(t, out, "aws_cloudwatch_log_group.api")
+	require.NotContains(t, out, "aws_cloudwatch_log_group.worker")
+
+	writePlan(apiKept + "," + worker)
+	out, err = run("-failed-only")
+	require.NoError(t, err)
+	require.Contains(t, out, "no findings")
+	failures, err := plancheck.LoadFailures(failuresFile)
+	require.NoError(t, err)
+	require.True(t, failures.Empty(), "a passing re-run clears the failures")
+
+	_, err = run("-failed-only", "-rule", "logs.retention")
+	require.ErrorContains(t, err, "cannot be combined with -rule or -watch")
+
+	// A passing run of another rule keeps the failures of the full run.
+	writePlan(api + "," + worker)
+	_, err = run()
+	require.Error(t, err)
+	out, err = run("-rule", "ec2.imdsv2")
+	require.NoError(t, err)
+	require.Contains(t, out, "no findings")
+	out, err = run("-failed-only")
+	require.EqualError(t, err, "2 finding(s)")
+	require.Contains(t, out, "re-checking 1 rule(s) on 2 resource(s) that failed last time")
+}
+
+func TestCheckFocusReportsOnlyMatchingResources(t *testing.T) {
+	t.Setenv("PATH", "")
+	dir := t.TempDir()
+	planFile := filepath.Join(dir, "plan.json")
+	require.NoError(t, os.WriteFile(planFile, []byte(`{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"child_modules": [
+    {"address": "module.artifacts", "resources": [
+      {"address": "module.artifacts.aws_cloudwatch_log_group.api", "mode": "managed", "type": "aws_cloudwatch_log_group", "name": "api", "values": {"name": "/ecs/api"}}]},
+    {"address": "module.registry", "resources": [
+      {"address": "module.registry.aws_cloudwatch_log_group.api", "mode": "managed", "type": "aws_cloudwatch_log_group", "name": "api", "values": {"name": "/ecs/registry"}}]}
+  ]}}
+}`), 0o644))
+
+	var out bytes.Buffer
+	err := runCheck([]string{
+		"-plan", planFile,
+		"-rule", "logs.retention",
+		"-focus", "module.artifacts.*",
+		"-config", "../../compliance.yaml",
+		"-baseline", filepath.Join(dir, "baseline.yaml"),
+		"-locales", filepath.Join(dir, "locales"),
+		"-failures", filepath.Join(dir, "failures.json"),
+	}, &out, &out)
+	require.EqualError(t, err, "1 finding(s)")
+	require.Contains(t, out.String(), "focusing on module.artifacts.*")
+	require.Contains(t, out.String(), "module.artifacts.aws_cloudwatch_log_group.api")
+	require.NotContains(t, out.String(), "module.registry")
+
+	err = runCheck([]string{"-plan", planFile, "-focus", "module.[artifacts"}, &out, &out)
+	require.ErrorContains(t, err, "invalid focus pattern")
+}
+
+// fakePlanningTerraform writes a binary plan file for -out and shows a plan
+// without findings for logs.retention.
+const fakePlanningTerraform = `#!/bin/sh
+for arg in "$@"; do
+  case "$arg" in -out=*) echo "binary plan" > "${arg#-out=}" ;; esac
+done
+if [ "$1" != "show" ]; then exit 0; fi
+cat <<JSON
+{"format_version":"1.0","planned_values":{"root_module":{"resources":[
+  {"address":"aws_cloudwatch_log_group.api","mode":"managed","type":"aws_cloudwatch_log_group","name":"api",
+   "values":{"name":"/ecs/api","retention_in_days":30}}]}}}
+JSON
+`
+
+func TestCheckApprovesThePlanFileItChecked(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "terraform")
+	require.NoError(t, os.WriteFile(bin, []byte(fakePlanningTerraform), 0o755))
+	t.Setenv(runner.BinaryEnv, bin)
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, "compliance.yaml")
+	// A sandbox may keep its state locally, so the whole plan passes.
+	require.NoError(t, os.WriteFile(config, []byte("environments:\n  dev:\n    sandbox: true\n"), 0o644))
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "approval.key")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	planFile := filepath.Join(dir, "dev.tfplan")
+	approvalFile := filepath.Join(dir, "approval.json")
+	var out bytes.Buffer
+	err = runCheck([]string{
+		"-preflight=false", "-dir", dir, "-config", config,
+		"-baseline", filepath.Join(dir, "baseline.yaml"), "-locales", filepath.Join(dir, "locales"),
+		"-failures", filepath.Join(dir, "failures.json"),
+		"-out", planFile, "-approval", approvalFile, "-approval-ttl", "1h", "-key", keyFile,
+	}, &out, &out)
+	require.NoError(t, err, out.String())
+	require.Contains(t, out.String(), "approved "+planFile+" for dev")
+
+	approval, err := plancheck.ReadApproval(approvalFile)
+	require.NoError(t, err)
+	require.Equal(t, 0, approval.Findings.Count)
+	require.NoError(t, approval.Verify(planFile, public, time.Now()))
+	require.ErrorIs(t, approval.Verify(planFile, public, time.Now().Add(2*time.Hour)), plancheck.ErrApprovalExpired)
+
+	require.NoError(t, os.WriteFile(planFile, []byte("another plan\n"), 0o644))
+	require.ErrorContains(t, approval.Verify(planFile, public, time.Now()), "is not the approved dev plan")
+
+	err = runCheck([]string{"-dir", dir, "-approval", approvalFile}, &out, &out)
+	require.ErrorContains(t, err, "-approval needs -out")
+
+	for _, partial := range [][]string{{"-rule", "logs.retention"}, {"-focus", "module.artifacts.*"}, {"-failed-only"}} {
+		args := append([]string{"-dir", dir, "-out", planFile, "-approval", approvalFile}, partial...)
+		err = runCheck(args, &out, &out)
+		require.ErrorContains(t, err, "-approval approves the whole plan", "%v", partial)
+	}
+}
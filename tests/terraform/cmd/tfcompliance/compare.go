@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// errNewFindings makes compare exit non-zero under -fail-on-new.
+var errNewFindings = errors.New("new findings introduced")
+
+func runCompare(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	base := flags.String("base", "", "findings.json from the base run, e.g. main (required)")
+	head := flags.String("head", "", "findings.json from the head run, e.g. the PR branch (required)")
+	asJSON := flags.Bool("json", false, "print the comparison as JSON")
+	failOnNew := flags.Bool("fail-on-new", false, "exit with status 1 when head introduces findings")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *base == "" || *head == "" {
+		flags.Usage()
+		return fmt.Errorf("-base and -head are required")
+	}
+
+	before, err := plancheck.ReadFindings(*base)
+	if err != nil {
+		return err
+	}
+	after, err := plancheck.ReadFindings(*head)
+	if err != nil {
+		return err
+	}
+	comparison := plancheck.Compare(before, after)
+
+	if *asJSON {
+		data, err := json.MarshalIndent(comparison, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(stdout, "%s\n", data); err != nil {
+			return err
+		}
+	} else {
+		printComparison(stdout, comparison)
+	}
+
+	if *failOnNew && len(comparison.New) > 0 {
+		return errNewFindings
+	}
+	return nil
+}
+
+func printComparison(w io.Writer, comparison plancheck.Comparison) {
+	if comparison.Empty() {
+		fmt.Fprintln(w, "no changes in findings")
+		return
+	}
+	for _, finding := range comparison.New {
+		fmt.Fprintf(w, "+ %s %s\n    at %s\n", finding.Label(), finding.Message, finding.Location())
+	}
+	for _, finding := range comparison.Fixed {
+		fmt.Fprintf(w, "- %s %s\n    at %s\n", finding.Label(), finding.Message, finding.Location())
+	}
+	for _, change := range comparison.Changed {
+		fmt.Fprintf(w, "~ %s %s\n    at %s\n", change.After.Label(), change.After.Message, change.After.Location())
+		if change.Before.Message != change.After.Message {
+			fmt.Fprintf(w, "    was: %s\n", change.Before.Message)
+		}
+		if change.Before.Owner != change.After.Owner {
+			fmt.Fprintf(w, "    owner: %s (was %s)\n", change.After.Owner, change.Before.Owner)
+		}
+		if change.Before.Severity != change.After.Severity {
+			fmt.Fprintf(w, "    severity: %s (was %s)\n", severityName(change.After), severityName(change.Before))
+		}
+	}
+	if len(comparison.Moved) > 0 {
+		fmt.Fprintln(w)
+	}
+	for _, change := range comparison.Moved {
+		fmt.Fprintf(w, "  moved [%s] %s: %s -> %s\n", change.After.RuleID, change.After.Address, sourceOf(change.Before), sourceOf(change.After))
+	}
+	fmt.Fprintf(w, "\n%d new, %d fixed, %d changed, %d moved\n", len(comparison.New), len(comparison.Fixed), len(comparison.Changed), len(comparison.Moved))
+}
+
+func sourceOf(finding plancheck.Finding) string {
+	if finding.Source == nil {
+		return "unknown"
+	}
+	return finding.Source.String()
+}
+
+func severityName(finding plancheck.Finding) plancheck.Severity {
+	if finding.Severity == "" {
+		return plancheck.SeverityError
+	}
+	return finding.Severity
+}
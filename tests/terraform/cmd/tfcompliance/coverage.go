@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func runCoverage(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("coverage", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var p planFlags
+	p.register(flags)
+	planFile := flags.String("plan", "", "plan JSON to report on instead of planning -dir")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := loadPlugins(p.plugins); err != nil {
+		return err
+	}
+
+	var (
+		plan *tfjson.Plan
+		err  error
+	)
+	if *planFile != "" {
+		plan, err = readPlan(*planFile)
+	} else {
+		plan, err = p.plan(context.Background(), false)
+	}
+	if err != nil {
+		return err
+	}
+
+	coverage := plancheck.Coverage(plan)
+	if *asJSON {
+		data, err := json.MarshalIndent(coverage, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(stdout, "%s\n", data)
+		return err
+	}
+	plancheck.PrintCoverage(stdout, coverage)
+	return nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"gopkg.in/yaml.v3"
+
+	"cs450/terraformtests/awsauth"
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/runner"
+)
+
+// doctorEnvironment is the part of an environments.yaml entry doctor checks.
+type doctorEnvironment struct {
+	Name   string            `yaml:"name"`
+	Dir    string            `yaml:"dir"`
+	Region string            `yaml:"region"`
+	Skip   map[string]string `yaml:"skip,omitempty"`
+}
+
+// doctorReport prints one line per check and counts the failures and
+// warnings.
+type doctorReport struct {
+	w              io.Writer
+	failed, warned int
+}
+
+func (r *doctorReport) line(status, check, format string, args ...interface{}) {
+	fmt.Fprintf(r.w, "%-5s %-12s %s\n", status, check, fmt.Sprintf(format, args...))
+}
+
+func (r *doctorReport) ok(check, format string, args ...interface{}) {
+	r.line("ok", check, format, args...)
+}
+
+func (r *doctorReport) warn(check, format string, args ...interface{}) {
+	r.warned++
+	r.line("warn", check, format, args...)
+}
+
+func (r *doctorReport) fail(check, format string, args ...interface{}) {
+	r.failed++
+	r.line("FAIL", check, format, args...)
+}
+
+func runDoctor(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFile := flags.String("config", "compliance.yaml", "compliance configuration file")
+	baselineFile := flags.String("baseline", "baseline.yaml", "baseline of accepted findings")
+	environmentsFile := flags.String("environments", "environments.yaml", "environments of the test matrix")
+	region := flags.String("region", "us-east-1", "region for environments that name none")
+	checkAWS := flags.Bool("aws", true, "assume each environment's role and check the identity; -aws=false skips the AWS calls")
+	var plugins listFlag
+	flags.Var(&plugins, "plugin", "rule plugin to load (repeatable; also read from "+plancheck.PluginEnv+")")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report := &doctorReport{w: stdout}
+	ctx := context.Background()
+
+	if err := loadPlugins(plugins); err != nil {
+		report.fail("rules", "%v", err)
+	}
+	config := doctorConfig(report, *configFile)
+	doctorBaseline(report, *baselineFile)
+	envs := doctorEnvironments(report, *environmentsFile, config)
+	doctorTerraform(ctx, report, envs)
+	if *checkAWS && config != nil {
+		doctorCredentials(ctx, report, config, envs, *region)
+	}
+
+	if report.failed > 0 {
+		return fmt.Errorf("%d check(s) failed, %d warning(s)", report.failed, report.warned)
+	}
+	fmt.Fprintf(stdout, "ready, %d warning(s)\n", report.warned)
+	return nil
+}
+
+// doctorConfig loads the compliance configuration and checks that the rules
+// it overrides are registered. It returns nil when the file does not load.
+func doctorConfig(report *doctorReport, filename string) *plancheck.Config {
+	config, err := plancheck.LoadConfig(filename)
+	if err != nil {
+		report.fail("config", "%v", err)
+		return nil
+	}
+	var unknown []string
+	for ruleID := range config.Severities {
+		if _, ok := plancheck.LookupRule(ruleID); !ok {
+			unknown = append(unknown, "severities."+ruleID)
+		}
+	}
+	for ruleID := range config.Canaries {
+		if _, ok := plancheck.LookupRule(ruleID); !ok {
+			unknown = append(unknown, "canaries."+ruleID)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		report.fail("config", "%s: no registered rule for %s (see tfcompliance rules)", filename, strings.Join(unknown, ", "))
+		return config
+	}
+	report.ok("config", "%s: %d rules registered, %d severity override(s)", filename, len(plancheck.Rules()), len(config.Severities))
+	return config
+}
+
+// doctorBaseline loads the baseline. Expired entries fail, as
+// waivers.expired fails the run on them; entries for rules no longer
+// registered only warn, as they accept nothing.
+func doctorBaseline(report *doctorReport, filename string) {
+	if _, err := os.Stat(filename); errors.Is(err, fs.ErrNotExist) {
+		report.ok("baseline", "%s: none, no findings accepted", filename)
+		return
+	}
+	baseline, err := plancheck.LoadBaseline(filename)
+	if err != nil {
+		report.fail("baseline", "%v", err)
+		return
+	}
+
+	expired := plancheck.ExpiredWaivers(baseline, nil, time.Now())
+	for _, waiver := range expired {
+		report.fail("baseline", "%s: %s on %s expired on %s", filename, strings.Join(waiver.RuleIDs, " "), waiver.Key, waiver.Expires)
+	}
+	for _, entry := range baseline.Accepted {
+		if _, ok := plancheck.LookupRule(entry.RuleID); !ok {
+			report.warn("baseline", "%s: %s on %s accepts a rule that is no longer registered", filename, entry.RuleID, entry.Resource)
+		}
+	}
+	if len(expired) == 0 {
+		report.ok("baseline", "%s: %d accepted finding(s)", filename, len(baseline.Accepted))
+	}
+}
+
+// doctorEnvironments reads the environments file and checks that each
+// environment's root module parses. Environments skipped by every test are
+// left out of the result.
+func doctorEnvironments(report *doctorReport, filename string, config *plancheck.Config) []doctorEnvironment {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		report.fail("environments", "%v", err)
+		return nil
+	}
+	var file struct {
+		Environments []doctorEnvironment `yaml:"environments"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		report.fail("environments", "%s: %v", filename, err)
+		return nil
+	}
+
+	var envs []doctorEnvironment
+	for _, env := range file.Environments {
+		if reason := env.Skip["*"]; reason != "" {
+			report.ok("environments", "%s: skipped, %s", env.Name, reason)
+			continue
+		}
+		// Directories are relative to the environments file.
+		if !filepath.IsAbs(env.Dir) {
+			env.Dir = filepath.Join(filepath.Dir(filename), env.Dir)
+		}
+		module, err := plancheck.LoadModule(env.Dir)
+		switch {
+		case err != nil:
+			report.fail("environments", "%s: %v", env.Name, err)
+			continue
+		case module == nil:
+			report.fail("environments", "%s: %s has no .tf files", env.Name, env.Dir)
+			continue
+		}
+		if _, err := plancheck.LoadBackend(env.Dir); err != nil {
+			report.fail("environments", "%s: %v", env.Name, err)
+			continue
+		}
+		if config != nil {
+			if _, ok := config.Environments[env.Name]; !ok {
+				report.warn("environments", "%s: compliance.yaml has no settings for it; the defaults apply", env.Name)
+			}
+		}
+		report.ok("environments", "%s: %s", env.Name, env.Dir)
+		envs = append(envs, env)
+	}
+	return envs
+}
+
+// doctorTerraform checks that terraform runs, that its version meets each
+// environment's required_version, and that init has installed the providers
+// each environment requires.
+func doctorTerraform(ctx context.Context, report *doctorReport, envs []doctorEnvironment) {
+	installed, err := runner.Version(ctx, runner.Options{Dir: "."})
+	if err != nil {
+		report.fail("terraform", "%v", err)
+		return
+	}
+	current, err := version.NewVersion(installed.Version)
+	if err != nil {
+		report.fail("terraform", "version %q: %v", installed.Version, err)
+		return
+	}
+	if installed.Outdated {
+		report.warn("terraform", "%s is not the latest release", installed.Version)
+	} else {
+		report.ok("terraform", "%s on %s", installed.Version, installed.Platform)
+	}
+
+	for _, env := range envs {
+		module, err := plancheck.LoadModule(env.Dir)
+		if err != nil {
+			// doctorEnvironments reported it.
+			continue
+		}
+		requirements := module.Requirements()
+		if requirements.Terraform != "" {
+			constraints, err := version.NewConstraint(requirements.Terraform)
+			switch {
+			case err != nil:
+				report.fail("terraform", "%s: required_version %q: %v", env.Name, requirements.Terraform, err)
+			case !constraints.Check(current):
+				report.fail("terraform", "%s requires terraform %s, not %s", env.Name, requirements.Terraform, installed.Version)
+			}
+		}
+
+		selected, err := runner.Version(ctx, runner.Options{Dir: env.Dir})
+		if err != nil {
+			report.fail("providers", "%s: %v", env.Name, err)
+			continue
+		}
+		if len(selected.ProviderSelections) == 0 && len(requirements.Providers) > 0 {
+			report.warn("providers", "%s: not initialized; run terraform init in %s", env.Name, env.Dir)
+			continue
+		}
+		var missing []string
+		for name, source := range requirements.Providers {
+			if _, ok := selected.ProviderSelections[providerAddress(source)]; !ok {
+				missing = append(missing, name+" ("+source+")")
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			report.fail("providers", "%s: %s not installed; run terraform init -upgrade in %s", env.Name, strings.Join(missing, ", "), env.Dir)
+			continue
+		}
+		report.ok("providers", "%s: %d provider(s) installed", env.Name, len(selected.ProviderSelections))
+	}
+}
+
+// providerAddress returns the fully qualified address terraform reports a
+// provider source under, e.g. registry.terraform.io/hashicorp/aws.
+func providerAddress(source string) string {
+	if strings.Count(source, "/") >= 2 {
+		return source
+	}
+	return "registry.terraform.io/" + source
+}
+
+// doctorCredentials assumes each environment's role and checks the identity
+// the suite would plan as.
+func doctorCredentials(ctx context.Context, report *doctorReport, config *plancheck.Config, envs []doctorEnvironment, defaultRegion string) {
+	for _, env := range envs {
+		region := env.Region
+		if region == "" {
+			region = defaultRegion
+		}
+		creds, err := awsauth.ForEnvironment(ctx, config, env.Name, region)
+		if err != nil {
+			report.fail("credentials", "%s: %v", env.Name, err)
+			continue
+		}
+		preflight, err := awsauth.NewPreflight(region, creds.AWS())
+		if err != nil {
+			report.fail("credentials", "%s: %v", env.Name, err)
+			continue
+		}
+		identity, err := preflight.Check(ctx)
+		if err != nil {
+			report.fail("credentials", "%s: %v", env.Name, err)
+			continue
+		}
+		report.ok("credentials", "%s: %s", env.Name, identity)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/runner"
+)
+
+// fakeVersionTerraform answers terraform version -json, listing the aws
+// provider only in directories init has run in.
+const fakeVersionTerraform = `#!/bin/sh
+if [ -d .terraform ]; then
+  echo '{"terraform_version":"1.6.2","platform":"linux_amd64","provider_selections":{"registry.terraform.io/hashicorp/aws":"5.31.0"},"terraform_outdated":false}'
+else
+  echo '{"terraform_version":"1.6.2","platform":"linux_amd64","provider_selections":{},"terraform_outdated":false}'
+fi
+`
+
+func writeDoctorFiles(t *testing.T, requiredVersion string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "terraform")
+	require.NoError(t, os.WriteFile(binary, []byte(fakeVersionTerraform), 0o755))
+	t.Setenv(runner.BinaryEnv, binary)
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "dev"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dev", "main.tf"), []byte(`terraform {
+  required_version = "`+requiredVersion+`"
+  required_providers {
+    aws = {
+      source = "hashicorp/aws"
+    }
+  }
+}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "environments.yaml"), []byte(`environments:
+  - name: dev
+    dir: dev
+    region: us-east-1
+  - name: prod
+    dir: prod
+    skip:
+      "*": not built yet
+`), 0o644))
+	return dir
+}
+
+func TestDoctorReportsReadiness(t *testing.T) {
+	dir := writeDoctorFiles(t, ">= 1.6.0")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "dev", ".terraform"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "compliance.yaml"), []byte("environments:\n  dev:\n    sandbox: true\n"), 0o644))
+
+	var out bytes.Buffer
+	err := runDoctor([]string{
+		"-config", filepath.Join(dir, "compliance.yaml"),
+		"-baseline", filepath.Join(dir, "baseline.yaml"),
+		"-environments", filepath.Join(dir, "environments.yaml"),
+		"-aws=false",
+	}, &out, &out)
+	require.NoError(t, err, out.String())
+	require.Contains(t, out.String(), "ok    environments prod: skipped, not built yet")
+	require.Contains(t, out.String(), "ok    terraform    1.6.2 on linux_amd64")
+	require.Contains(t, out.String(), "ok    providers    dev: 1 provider(s) installed")
+	require.NotContains(t, out.String(), "credentials")
+	require.Contains(t, out.String(), "ready, 0 warning(s)")
+}
+
+func TestDoctorFailsOnProblems(t *testing.T) {
+	dir := writeDoctorFiles(t, ">= 1.9.0")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "compliance.yaml"), []byte("severities:\n  no.such-rule: advisory\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "baseline.yaml"), []byte(`accepted:
+  - rule: logs.retention
+    resource: aws_cloudwatch_log_group.api
+    justification: short-lived log group
+    expires: "2000-01-01"
+`), 0o644))
+
+	var out bytes.Buffer
+	err := runDoctor([]string{
+		"-config", filepath.Join(dir, "compliance.yaml"),
+		"-baseline", filepath.Join(dir, "baseline.yaml"),
+		"-environments", filepath.Join(dir, "environments.yaml"),
+		"-aws=false",
+	}, &out, &out)
+	require.EqualError(t, err, "3 check(s) failed, 2 warning(s)", out.String())
+	require.Contains(t, out.String(), "no registered rule for severities.no.such-rule")
+	require.Contains(t, out.String(), "logs.retention on aws_cloudwatch_log_group.api expired on 2000-01-01")
+	require.Contains(t, out.String(), "warn  environments dev: compliance.yaml has no settings for it")
+	require.Contains(t, out.String(), "dev requires terraform >= 1.9.0, not 1.6.2")
+	require.Contains(t, out.String(), "warn  providers    dev: not initialized")
+}
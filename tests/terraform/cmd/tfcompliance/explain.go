@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func runExplain(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var plugins listFlag
+	flags.Var(&plugins, "plugin", "rule plugin to load (repeatable; also read from "+plancheck.PluginEnv+")")
+	locales := flags.String("locales", "locales", "directory of message catalogs selected by "+plancheck.LocaleEnv)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("usage: tfcompliance explain <rule>...")
+	}
+	if err := loadPlugins(plugins); err != nil {
+		return err
+	}
+	catalog, err := plancheck.LoadCatalogFromEnv(*locales)
+	if err != nil {
+		return err
+	}
+	rules, err := lookupRules(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	for i, rule := range rules {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		explain(stdout, catalog.Rule(rule))
+	}
+	return nil
+}
+
+// explain prints what a rule checks, why, and how to fix its findings.
+func explain(w io.Writer, rule plancheck.Rule) {
+	severity := rule.Severity
+	if severity == "" {
+		severity = plancheck.SeverityError
+	}
+	fmt.Fprintf(w, "%s (%s)\n", rule.ID, severity)
+	fmt.Fprintf(w, "%s\n", indent(rule.Description))
+
+	if rule.Rationale != "" {
+		fmt.Fprintf(w, "\nwhy:\n%s\n", indent(rule.Rationale))
+	}
+	fmt.Fprintf(w, "\nfix:\n%s\n", indent(rule.Remediation))
+	fmt.Fprintln(w, indent("Findings with a suggested fix can be applied with tfcompliance fix -rule "+rule.ID+"."))
+	if rule.Example != "" {
+		fmt.Fprintf(w, "\nexample:\n%s\n", indent(strings.TrimSpace(rule.Example)))
+	}
+
+	var where []string
+	if rule.Check != nil {
+		where = append(where, "plan")
+	}
+	if rule.CheckSource != nil {
+		where = append(where, "pre-commit")
+	}
+	fmt.Fprintf(w, "\nchecked in: %s\n", strings.Join(where, ", "))
+	if len(rule.ResourceTypes) > 0 {
+		fmt.Fprintf(w, "inspects: %s\n", strings.Join(rule.ResourceTypes, ", "))
+	}
+}
+
+func indent(text string) string {
+	return "    " + strings.ReplaceAll(text, "\n", "\n    ")
+}
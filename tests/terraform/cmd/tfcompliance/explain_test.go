@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainPrintsRationaleAndExample(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"explain", "ec2.imdsv2", "cost.gp2"}, &stdout, &stderr)
+	require.Equalf(t, 0, code, "stderr: %s", stderr.String())
+
+	out := stdout.String()
+	require.Contains(t, out, "ec2.imdsv2 (error)\n")
+	require.Contains(t, out, "why:\n    IMDSv1 answers")
+	require.Contains(t, out, "example:\n    resource \"aws_launch_template\" \"api\" {")
+	require.Contains(t, out, "tfcompliance fix -rule ec2.imdsv2")
+	require.Contains(t, out, "checked in: plan, pre-commit\n")
+	require.Contains(t, out, "cost.gp2 (advisory)\n")
+
+	stdout.Reset()
+	require.Equal(t, 1, run([]string{"explain", "no.such-rule"}, &stdout, &stderr))
+	require.Contains(t, stderr.String(), `unknown rule "no.such-rule"`)
+}
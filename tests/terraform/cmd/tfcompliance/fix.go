@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"cs450/terraformtests/fix"
+	"cs450/terraformtests/plancheck"
+)
+
+func runFix(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("fix", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var p planFlags
+	p.register(flags)
+	ruleList := flags.String("rule", "", "comma-separated rules to fix, e.g. tags.required (required)")
+	dryRun := flags.Bool("dry-run", false, "print the patches instead of editing the files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *ruleList == "" {
+		flags.Usage()
+		return fmt.Errorf("-rule is required")
+	}
+	if err := loadPlugins(p.plugins); err != nil {
+		return err
+	}
+	rules, err := lookupRules(strings.Split(*ruleList, ","))
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := p.prepare(ctx, stdout); err != nil {
+		return err
+	}
+	plan, err := p.plan(ctx, false)
+	if err != nil {
+		return err
+	}
+	findings, index, err := p.evaluate(plan, rules)
+	if err != nil {
+		return err
+	}
+
+	// Instances of a count or for_each resource share one block, so each
+	// distinct fix is applied to it once.
+	applied := map[string]string{}
+	var fixed []plancheck.Finding
+	for _, finding := range findings {
+		if finding.Fix == nil {
+			fmt.Fprintf(stdout, "skipped [%s] %s: no mechanical fix\n", finding.RuleID, finding.Location())
+			continue
+		}
+		key := fixKey(finding)
+		if filename, ok := applied[key]; ok {
+			if !*dryRun {
+				fmt.Fprintf(stdout, "fixed [%s] %s in %s\n", finding.RuleID, finding.Address, filename)
+				fixed = append(fixed, finding)
+			}
+			continue
+		}
+		if *dryRun {
+			patch, err := fix.Patch(index, finding)
+			if err != nil {
+				fmt.Fprintf(stdout, "skipped [%s] %s: %v\n", finding.RuleID, finding.Location(), err)
+				continue
+			}
+			fmt.Fprint(stdout, patch)
+			applied[key] = ""
+			continue
+		}
+		filename, err := fix.Write(index, finding)
+		if err != nil {
+			fmt.Fprintf(stdout, "skipped [%s] %s: %v\n", finding.RuleID, finding.Location(), err)
+			continue
+		}
+		fmt.Fprintf(stdout, "fixed [%s] %s in %s\n", finding.RuleID, finding.Address, filename)
+		applied[key] = filename
+		fixed = append(fixed, finding)
+	}
+	if len(fixed) == 0 {
+		return nil
+	}
+
+	// Re-plan to confirm the edits resolve the findings without breaking the
+	// configuration.
+	plan, err = p.plan(ctx, true)
+	if err != nil {
+		return fmt.Errorf("re-plan after fixing: %w", err)
+	}
+	after, _, err := p.evaluate(plan, rules)
+	if err != nil {
+		return err
+	}
+	resolved := plancheck.Compare(fixed, after).Fixed
+	if unresolved := len(fixed) - len(resolved); unresolved > 0 {
+		for _, finding := range fixed {
+			if !containsFinding(resolved, finding) {
+				fmt.Fprintf(stdout, "still failing [%s] %s\n", finding.RuleID, finding.Location())
+			}
+		}
+		return fmt.Errorf("%d of %d fixed finding(s) are still reported after re-planning", unresolved, len(fixed))
+	}
+	fmt.Fprintf(stdout, "verified: %d finding(s) resolved\n", len(fixed))
+	return nil
+}
+
+// fixKey identifies a fix by the configuration block it edits and the edit,
+// so instances of one resource share a key.
+func fixKey(finding plancheck.Finding) string {
+	value, _ := json.Marshal(finding.Fix)
+	return plancheck.ConfigAddress(finding.Address) + "\x00" + string(value)
+}
+
+func containsFinding(findings []plancheck.Finding, finding plancheck.Finding) bool {
+	for _, f := range findings {
+		if reflect.DeepEqual(f, finding) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/runner"
+)
+
+// fakeTerraform plans a single log group whose retention reflects main.tf.
+const fakeTerraform = `#!/bin/sh
+if [ "$1" != "show" ]; then exit 0; fi
+retention=0
+if grep -q retention_in_days main.tf; then retention=30; fi
+cat <<JSON
+{"format_version":"1.0","planned_values":{"root_module":{"resources":[
+  {"address":"aws_cloudwatch_log_group.api","mode":"managed","type":"aws_cloudwatch_log_group","name":"api",
+   "values":{"name":"/ecs/api","retention_in_days":$retention}}]}}}
+JSON
+`
+
+func TestFixEditsSourceAndVerifiesWithAPlan(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "terraform")
+	require.NoError(t, os.WriteFile(bin, []byte(fakeTerraform), 0o755))
+	t.Setenv(runner.BinaryEnv, bin)
+
+	dir := t.TempDir()
+	mainTF := filepath.Join(dir, "main.tf")
+	require.NoError(t, os.WriteFile(mainTF, []byte("resource \"aws_cloudwatch_log_group\" \"api\" {\n  name = \"/ecs/api\"\n}\n"), 0o644))
+	config := filepath.Join(dir, "compliance.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("environments: {}\n"), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"fix", "-preflight=false", "-dir", dir, "-config", config, "-rule", "logs.retention"}, &stdout, &stderr)
+	require.Equalf(t, 0, code, "stdout: %s\nstderr: %s", stdout.String(), stderr.String())
+	require.Contains(t, stdout.String(), "verified: 1 finding(s) resolved")
+
+	data, err := os.ReadFile(mainTF)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "retention_in_days = 30")
+}
+
+// fakeTaggedTerraform plans two instances of a counted bucket whose tags
+// reflect main.tf.
+const fakeTaggedTerraform = `#!/bin/sh
+if [ "$1" != "show" ]; then exit 0; fi
+tags='{"Name":"logs"}'
+if grep -q Project main.tf; then tags='{"Name":"logs","Project":"cs450"}'; fi
+cat <<JSON
+{"format_version":"1.0","planned_values":{"root_module":{"resources":[
+  {"address":"aws_s3_bucket.logs[0]","mode":"managed","type":"aws_s3_bucket","name":"logs","index":0,"values":{"tags":$tags}},
+  {"address":"aws_s3_bucket.logs[1]","mode":"managed","type":"aws_s3_bucket","name":"logs","index":1,"values":{"tags":$tags}}]}}}
+JSON
+`
+
+func TestFixMergesTagsOncePerBlock(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "terraform")
+	require.NoError(t, os.WriteFile(bin, []byte(fakeTaggedTerraform), 0o755))
+	t.Setenv(runner.BinaryEnv, bin)
+
+	dir := t.TempDir()
+	mainTF := filepath.Join(dir, "main.tf")
+	require.NoError(t, os.WriteFile(mainTF, []byte("resource \"aws_s3_bucket\" \"logs\" {\n  count = 2\n  tags = {\n    Name = \"logs\"\n  }\n}\n"), 0o644))
+	config := filepath.Join(dir, "compliance.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("tags:\n  required:\n    Project: cs450\nenvironments: {}\n"), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"fix", "-preflight=false", "-dir", dir, "-config", config, "-rule", "tags.required"}, &stdout, &stderr)
+	require.Equalf(t, 0, code, "stdout: %s\nstderr: %s", stdout.String(), stderr.String())
+	require.Contains(t, stdout.String(), "verified: 2 finding(s) resolved")
+
+	data, err := os.ReadFile(mainTF)
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(data), "Project"), string(data))
+}
@@ -0,0 +1,73 @@
+// Command tfcompliance runs the terraform compliance tooling outside of go test.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type command struct {
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = map[string]command{
+	"account":   {summary: "check the live account settings terraform does not manage", run: runAccount},
+	"apply":     {summary: "re-verify an approved plan file and apply exactly it", run: runApply},
+	"check":     {summary: "plan and report findings, optionally re-checking on every change", run: runCheck},
+	"compare":   {summary: "report new, fixed and changed findings between two runs", run: runCompare},
+	"coverage":  {summary: "list planned resource types by the number of rules that inspect them", run: runCoverage},
+	"doctor":    {summary: "check the configuration, terraform, providers and credentials before a run", run: runDoctor},
+	"explain":   {summary: "print the rationale, an example and fix instructions for rules", run: runExplain},
+	"fix":       {summary: "apply mechanical fixes to the terraform source and verify them", run: runFix},
+	"manifest":  {summary: "verify a run manifest's signature and the plans it names", run: runManifest},
+	"pack":      {summary: "print the policy pack version, or the rule changes between two versions", run: runPack},
+	"precommit": {summary: "statically check staged .tf files without planning", run: runPrecommit},
+	"redact":    {summary: "write a sanitized copy of a plan JSON file", run: runRedact},
+	"shadow":    {summary: "plan a base ref as well and fail unless a refactor leaves the plan identical", run: runShadow},
+	"rules":     {summary: "list the registered rules, including plugins", run: runRules},
+	"triage":    {summary: "review findings and accept them into the baseline", run: runTriage},
+	"unlock":    {summary: "show or remove the run lock on an environment", run: runUnlock},
+	"upgrade":   {summary: "re-plan with a candidate provider version and list the planned values that change", run: runUpgrade},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stderr)
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "tfcompliance: unknown command %q\n\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	if err := cmd.run(args[1:], stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "tfcompliance %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: tfcompliance <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// runManifest checks a run manifest for an auditor: that its signature is by
+// the given key, and that plan files are the plans the run checked.
+func runManifest(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("manifest", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	manifestFile := flags.String("manifest", "", "manifest a run wrote to COMPLIANCE_MANIFEST (required)")
+	keyFile := flags.String("key", "", "PEM-encoded Ed25519 public key the manifest must be signed with; without it the signature is not checked")
+	plans := varFlags{}
+	flags.Var(plans, "plan", "an environment's plan JSON (terraform show -json) as env=file, which must match the manifest's digest (repeatable)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *manifestFile == "" {
+		return fmt.Errorf("-manifest is required")
+	}
+
+	m, err := plancheck.ReadManifest(*manifestFile)
+	if err != nil {
+		return err
+	}
+	if *keyFile != "" {
+		key, err := plancheck.LoadVerifyingKey(*keyFile)
+		if err != nil {
+			return err
+		}
+		if err := m.Verify(key); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "signature by key %s (%s) is valid\n", m.Signature.KeyID, m.Signer)
+	}
+
+	for environment, filename := range plans {
+		var want string
+		for _, plan := range m.Plans {
+			if plan.Environment == environment {
+				want = plan.SHA256
+			}
+		}
+		if want == "" {
+			return fmt.Errorf("the manifest has no plan for %s", environment)
+		}
+		plan, err := plancheck.LoadPlanOrState(filename)
+		if err != nil {
+			return err
+		}
+		got, err := plancheck.PlanDigest(plan)
+		if err != nil {
+			return err
+		}
+		if got != want {
+			return fmt.Errorf("%s is not the %s plan the run checked: digest %s, manifest has %s", filename, environment, got, want)
+		}
+		fmt.Fprintf(stdout, "%s is the %s plan the run checked\n", filename, environment)
+	}
+
+	dirty := ""
+	if m.Dirty {
+		dirty = " with uncommitted changes"
+	}
+	pack := ""
+	if m.PackVersion != "" {
+		pack = " of policy pack " + m.PackVersion
+	}
+	fmt.Fprintf(stdout, "commit %s%s, terraform %s: %d rules%s evaluated, %d findings\n",
+		m.Commit, dirty, m.TerraformVersion, len(m.Rules), pack, m.Findings.Count)
+	return nil
+}
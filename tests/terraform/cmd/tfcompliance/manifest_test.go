@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func TestManifestVerifiesSignatureAndPlans(t *testing.T) {
+	dir := t.TempDir()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "key.pub")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644))
+
+	planFile := filepath.Join(dir, "dev.json")
+	require.NoError(t, os.WriteFile(planFile, []byte(`{"format_version": "1.1", "terraform_version": "1.6.0", "planned_values": {"root_module": {}}}`), 0o644))
+	plan, err := plancheck.LoadPlanOrState(planFile)
+	require.NoError(t, err)
+	sum, err := plancheck.PlanDigest(plan)
+	require.NoError(t, err)
+
+	m := plancheck.Manifest{Commit: "16efa07", TerraformVersion: "1.6.0", Plans: []plancheck.ManifestPlan{{Environment: "dev", SHA256: sum}}, Signer: "ci"}
+	require.NoError(t, m.Sign(private))
+	manifestFile := filepath.Join(dir, "manifest.json")
+	require.NoError(t, plancheck.WriteManifest(manifestFile, m))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"manifest", "-manifest", manifestFile, "-key", keyFile, "-plan", "dev=" + planFile}, &stdout, &stderr)
+	require.Equalf(t, 0, code, "stderr: %s", stderr.String())
+	require.Contains(t, stdout.String(), "is valid")
+	require.Contains(t, stdout.String(), planFile+" is the dev plan the run checked")
+
+	require.NoError(t, os.WriteFile(planFile, []byte(`{"format_version": "1.1", "terraform_version": "1.6.1", "planned_values": {"root_module": {}}}`), 0o644))
+	stderr.Reset()
+	code = run([]string{"manifest", "-manifest", manifestFile, "-plan", "dev=" + planFile}, &stdout, &stderr)
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr.String(), "is not the dev plan the run checked")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// runPack describes the policy pack this build carries, and with -base lists
+// what changed since another version, so a plan that passed under the old
+// pack and fails under the new one can be traced to the rule responsible.
+func runPack(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("pack", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var plugins listFlag
+	flags.Var(&plugins, "plugin", "rule plugin to load (repeatable; also read from "+plancheck.PluginEnv+")")
+	base := flags.String("base", "", "pack.json of the version to compare with, e.g. from the last release")
+	head := flags.String("head", "", "pack.json of the newer version (default: this build's pack)")
+	out := flags.String("o", "", "write this build's pack.json to this file")
+	asJSON := flags.Bool("json", false, "print the changes as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := loadPlugins(plugins); err != nil {
+		return err
+	}
+
+	current := plancheck.CurrentPack(plancheck.Rules())
+	if *out != "" {
+		if err := plancheck.WritePack(*out, current); err != nil {
+			return err
+		}
+	}
+	if *base == "" {
+		fmt.Fprintf(stdout, "policy pack %s: %d rules\n", current.Version, len(current.Rules))
+		return nil
+	}
+
+	before, err := plancheck.ReadPack(*base)
+	if err != nil {
+		return err
+	}
+	after := current
+	if *head != "" {
+		if after, err = plancheck.ReadPack(*head); err != nil {
+			return err
+		}
+	}
+	changes := plancheck.DiffPacks(before, after)
+
+	if *asJSON {
+		if changes == nil {
+			changes = []plancheck.PackChange{}
+		}
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(stdout, "%s\n", data)
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintf(stdout, "no rule changes from policy pack %s to %s\n", before.Version, after.Version)
+		return nil
+	}
+	fmt.Fprintf(stdout, "%d rule change(s) from policy pack %s to %s:\n", len(changes), before.Version, after.Version)
+	for _, change := range changes {
+		fmt.Fprintln(stdout, change)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/rules"
+)
+
+func TestPackListsRuleChangesSinceBase(t *testing.T) {
+	current := plancheck.CurrentPack(plancheck.Rules())
+	require.Equal(t, rules.Version, current.Version)
+
+	// The base release lacked the last rule, had the first one at advisory
+	// and knew a rule that has since been removed.
+	base := plancheck.Pack{Version: "0.9.0", Rules: append([]plancheck.ManifestRule(nil), current.Rules[:len(current.Rules)-1]...)}
+	base.Rules[0].Severity = plancheck.SeverityAdvisory
+	base.Rules = append(base.Rules, plancheck.ManifestRule{ID: "zz.retired", Severity: plancheck.SeverityError})
+	baseFile := filepath.Join(t.TempDir(), "pack.json")
+	require.NoError(t, plancheck.WritePack(baseFile, base))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"pack", "-base", baseFile}, &stdout, &stderr)
+	require.Equalf(t, 0, code, "stderr: %s", stderr.String())
+	first, last := current.Rules[0], current.Rules[len(current.Rules)-1]
+	require.Equal(t, "3 rule change(s) from policy pack 0.9.0 to "+rules.Version+":\n"+
+		"~ "+first.ID+" severity: advisory -> "+string(first.Severity)+"\n"+
+		"+ "+last.ID+" ("+string(last.Severity)+")\n"+
+		"- zz.retired (error)\n", stdout.String())
+
+	stdout.Reset()
+	headFile := filepath.Join(t.TempDir(), "pack.json")
+	require.Equal(t, 0, run([]string{"pack", "-o", headFile}, &stdout, &stderr))
+	require.Equal(t, fmt.Sprintf("policy pack %s: %d rules\n", rules.Version, len(current.Rules)), stdout.String())
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"pack", "-base", headFile, "-head", headFile}, &stdout, &stderr))
+	require.Equal(t, "no rule changes from policy pack "+rules.Version+" to "+rules.Version+"\n", stdout.String())
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/awsauth"
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/runner"
+)
+
+// varFlags collects repeated -var name=value flags.
+type varFlags map[string]string
+
+func (v varFlags) String() string { return fmt.Sprint(map[string]string(v)) }
+
+func (v varFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("want name=value, got %q", s)
+	}
+	v[name] = value
+	return nil
+}
+
+// planFlags are shared by the commands that plan a configuration and
+// evaluate rules against it.
+type planFlags struct {
+	dir         string
+	environment string
+	config      string
+	region      string
+	vars        varFlags
+	plugins     listFlag
+	peers       listFlag
+	destroy     listFlag
+	preflight   bool
+
+	// out, when set, keeps the binary plan in that file.
+	out string
+
+	// focus, when set, keeps only the findings on the resources it matches.
+	focus plancheck.Focus
+
+	// baseline, when the command loaded one, is given to the rules that
+	// review its waivers.
+	baseline *plancheck.Baseline
+
+	// prepared is set once the credentials are assumed and checked; env
+	// holds them for terraform, and creds for the AWS clients.
+	prepared bool
+	env      map[string]string
+	creds    *awsauth.Credentials
+}
+
+func (p *planFlags) register(flags *flag.FlagSet) {
+	p.vars = varFlags{}
+	flags.StringVar(&p.dir, "dir", "../../infra/envs/dev", "terraform root module to plan")
+	flags.StringVar(&p.environment, "env", "dev", "environment the plan belongs to, for compliance.yaml settings")
+	flags.StringVar(&p.config, "config", "compliance.yaml", "compliance configuration file")
+	flags.StringVar(&p.region, "region", "us-east-1", "region of resources whose provider region cannot be resolved")
+	flags.Var(p.vars, "var", "terraform variable as name=value (repeatable)")
+	flags.Var(&p.plugins, "plugin", "rule plugin to load (repeatable; also read from "+plancheck.PluginEnv+")")
+	flags.Var(&p.peers, "peer", "another environment's plan or state JSON as env=file, for cross-environment rules (repeatable)")
+	flags.Var(&p.destroy, "allow-destroy", "address or pattern of a protected resource the plan may destroy (repeatable; also read from "+plancheck.AllowDestroyEnv+")")
+	flags.BoolVar(&p.preflight, "preflight", true, "verify the AWS identity before planning and refuse root or administrator credentials")
+}
+
+func (p *planFlags) plan(ctx context.Context, skipInit bool) (*tfjson.Plan, error) {
+	if err := p.prepare(ctx, io.Discard); err != nil {
+		return nil, err
+	}
+	return runner.Plan(ctx, runner.Options{Dir: p.dir, Vars: p.vars, SkipInit: skipInit, Env: p.env, PlanFile: p.out})
+}
+
+// prepare assumes the role compliance.yaml names for the environment and,
+// unless -preflight=false, checks the resulting identity and prints it as
+// the report header. It does nothing after the first call.
+func (p *planFlags) prepare(ctx context.Context, stdout io.Writer) error {
+	if p.prepared {
+		return nil
+	}
+	config, err := plancheck.LoadConfig(p.config)
+	if err != nil {
+		return err
+	}
+	creds, err := awsauth.ForEnvironment(ctx, config, p.environment, p.region)
+	if err != nil {
+		return err
+	}
+	if p.preflight {
+		preflight, err := awsauth.NewPreflight(p.region, creds.AWS())
+		if err != nil {
+			return err
+		}
+		identity, err := preflight.Check(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "planning %s as %s\n", p.environment, identity)
+	}
+	p.env, p.creds, p.prepared = creds.Env(), creds, true
+	return nil
+}
+
+// lookupRules resolves rule IDs, or every registered rule when ids is empty.
+func lookupRules(ids []string) ([]plancheck.Rule, error) {
+	if len(ids) == 0 {
+		return plancheck.Rules(), nil
+	}
+	rules := make([]plancheck.Rule, 0, len(ids))
+	for _, id := range ids {
+		rule, ok := plancheck.LookupRule(id)
+		if !ok {
+			return nil, fmt.Errorf("unknown rule %q (see tfcompliance rules)", id)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// evaluate runs rules against plan and locates the findings in p.dir.
+func (p *planFlags) evaluate(plan *tfjson.Plan, rules []plancheck.Rule) ([]plancheck.Finding, *plancheck.SourceIndex, error) {
+	config, err := plancheck.LoadConfig(p.config)
+	if err != nil {
+		return nil, nil, err
+	}
+	peers, err := plancheck.LoadPeers(p.peers)
+	if err != nil {
+		return nil, nil, err
+	}
+	backend, err := plancheck.LoadBackend(p.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	module, err := plancheck.LoadModule(p.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	findings := plancheck.Evaluate(&plancheck.Input{
+		Plan:          plan,
+		DefaultRegion: p.region,
+		Environment:   p.environment,
+		Config:        config,
+		Peers:         peers,
+		Backend:       backend,
+		Module:        module,
+		Baseline:      p.baseline,
+		AllowDestroy:  append(plancheck.AllowDestroyFromEnv(), p.destroy...),
+		Focus:         p.focus,
+	}, rules...)
+
+	index := plancheck.NewSourceIndex(plan, p.dir)
+	index.Annotate(findings)
+	plancheck.SortFindings(findings)
+	return findings, index, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func runPrecommit(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("precommit", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tfcompliance precommit [file.tf ...]")
+		fmt.Fprintln(stderr, "Checks the given files, or the staged .tf files, with the rules that need no plan.")
+		flags.PrintDefaults()
+	}
+	baselineFile := flags.String("baseline", "baseline.yaml", "baseline of accepted findings")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	baseline, err := plancheck.LoadBaseline(*baselineFile)
+	if err != nil {
+		return err
+	}
+
+	sources, err := precommitSources(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	var findings []plancheck.Finding
+	for _, name := range sortedNames(sources) {
+		file, err := plancheck.ParseSource(name, sources[name])
+		if err != nil {
+			return err
+		}
+		findings = append(findings, plancheck.EvaluateSource(file)...)
+	}
+	findings, _ = baseline.Filter(findings)
+
+	for _, finding := range findings {
+		fmt.Fprintf(stdout, "%s: %s %s\n", finding.Source, finding.Label(), finding.Message)
+	}
+	if blocking, _ := plancheck.SplitAdvisories(findings); len(blocking) > 0 {
+		return fmt.Errorf("%d finding(s) in staged terraform; plan-based rules still run in CI", len(blocking))
+	}
+	return nil
+}
+
+// precommitSources reads the named files, or the staged contents of every
+// added or modified .tf file when none are named.
+func precommitSources(files []string) (map[string][]byte, error) {
+	sources := map[string][]byte{}
+	if len(files) > 0 {
+		for _, name := range files {
+			if filepath.Ext(name) != ".tf" {
+				continue
+			}
+			data, err := os.ReadFile(name)
+			if err != nil {
+				return nil, err
+			}
+			sources[name] = data
+		}
+		return sources, nil
+	}
+
+	root, err := git("", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = strings.TrimSpace(root)
+	// -z separates names with NUL and leaves them unquoted, so names with
+	// spaces or unusual characters come through intact.
+	staged, err := git(root, "diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR", "--", "*.tf")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(staged, "\x00") {
+		if name == "" {
+			continue
+		}
+		data, err := git(root, "show", ":"+name)
+		if err != nil {
+			return nil, err
+		}
+		sources[filepath.Join(root, name)] = []byte(data)
+	}
+	return sources, nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func sortedNames(sources map[string][]byte) []string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
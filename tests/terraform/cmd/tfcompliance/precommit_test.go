@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func TestPrecommitChecksStagedContent(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	for _, args := range [][]string{{"init", "-q"}, {"config", "user.email", "dev@example.com"}, {"config", "user.name", "dev"}} {
+		_, err := git(repo, args...)
+		require.NoError(t, err)
+	}
+
+	mainTF := filepath.Join(repo, "main.tf")
+	require.NoError(t, os.WriteFile(mainTF, []byte("resource \"aws_cloudwatch_log_group\" \"api\" {\n  name = \"/ecs/api\"\n}\n"), 0o644))
+	_, err := git(repo, "add", "main.tf")
+	require.NoError(t, err)
+	// Unstaged edits must not hide the staged problem.
+	require.NoError(t, os.WriteFile(mainTF, []byte("resource \"aws_cloudwatch_log_group\" \"api\" {\n  retention_in_days = 7\n}\n"), 0o644))
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(repo))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"precommit"}, &stdout, &stderr)
+	require.Equal(t, 1, code)
+	require.Contains(t, stdout.String(), "main.tf:1: [logs.retention] log group aws_cloudwatch_log_group.api never expires its logs")
+
+	stdout.Reset()
+	code = run([]string{"precommit", mainTF}, &stdout, &stderr)
+	require.Equalf(t, 0, code, "stdout: %s", stdout.String())
+}
+
+func TestPrecommitHonorsBaselineAndOddFileNames(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	for _, args := range [][]string{{"init", "-q"}, {"config", "user.email", "dev@example.com"}, {"config", "user.name", "dev"}} {
+		_, err := git(repo, args...)
+		require.NoError(t, err)
+	}
+
+	// git quotes names like this one unless -z is used.
+	name := "log groups \"api\".tf"
+	require.NoError(t, os.WriteFile(filepath.Join(repo, name), []byte("resource \"aws_cloudwatch_log_group\" \"api\" {\n  name = \"/ecs/api\"\n}\n"), 0o644))
+	_, err := git(repo, "add", name)
+	require.NoError(t, err)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(repo))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	baselineFile := filepath.Join(t.TempDir(), "baseline.yaml")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"precommit", "-baseline", baselineFile}, &stdout, &stderr)
+	require.Equalf(t, 1, code, "stderr: %s", stderr.String())
+	require.Contains(t, stdout.String(), name+":1: [logs.retention]")
+
+	baseline := &plancheck.Baseline{}
+	baseline.Accept(plancheck.NewFinding("logs.retention", "aws_cloudwatch_log_group.api", "").WithPath("retention_in_days"), "logs are exported nightly", time.Time{})
+	require.NoError(t, baseline.Save(baselineFile))
+
+	stdout.Reset()
+	code = run([]string{"precommit", "-baseline", baselineFile}, &stdout, &stderr)
+	require.Equalf(t, 0, code, "stdout: %s", stdout.String())
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"cs450/terraformtests/redact"
+)
+
+func runRedact(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("redact", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	in := flags.String("in", "", "plan JSON produced by terraform show -json (required)")
+	out := flags.String("out", "", "where to write the redacted plan (default: stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		flags.Usage()
+		return fmt.Errorf("-in is required")
+	}
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		return err
+	}
+	redacted, err := redact.Plan(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *in, err)
+	}
+
+	if *out == "" {
+		_, err = stdout.Write(redacted)
+		return err
+	}
+	return os.WriteFile(*out, redacted, 0o644)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"cs450/terraformtests/plancheck"
+	_ "cs450/terraformtests/rules" // registers the built-in rules
+)
+
+// listFlag collects a repeatable flag such as -plugin.
+type listFlag []string
+
+func (p *listFlag) String() string     { return strings.Join(*p, ",") }
+func (p *listFlag) Set(v string) error { *p = append(*p, v); return nil }
+
+// loadPlugins registers the rules of COMPLIANCE_PLUGINS and any -plugin flags.
+func loadPlugins(plugins listFlag) error {
+	if _, err := plancheck.LoadPluginsFromEnv(); err != nil {
+		return err
+	}
+	for _, path := range plugins {
+		if _, err := plancheck.LoadPlugin(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runRules(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("rules", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var plugins listFlag
+	flags.Var(&plugins, "plugin", "rule plugin to load (repeatable; also read from "+plancheck.PluginEnv+")")
+	locales := flags.String("locales", "locales", "directory of message catalogs selected by "+plancheck.LocaleEnv)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := loadPlugins(plugins); err != nil {
+		return err
+	}
+	catalog, err := plancheck.LoadCatalogFromEnv(*locales)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range plancheck.Rules() {
+		rule = catalog.Rule(rule)
+		description := rule.Description
+		if !rule.Severity.Blocking() {
+			description = "(" + string(rule.Severity) + ") " + description
+		}
+		if rule.Canary != "" {
+			description = "(canary until " + rule.Canary + ") " + description
+		}
+		fmt.Fprintf(stdout, "%-28s %s\n", rule.ID, description)
+	}
+	return nil
+}
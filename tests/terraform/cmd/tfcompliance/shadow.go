@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/runner"
+)
+
+func runShadow(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("shadow", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var p planFlags
+	p.register(flags)
+	ref := flags.String("ref", "", "git ref of the configuration before the refactor, e.g. origin/main (required unless -base-plan is set)")
+	basePlan := flags.String("base-plan", "", "plan JSON to compare against instead of planning -dir at -ref")
+	root := flags.String("root", "../../infra", "directory whose moved blocks may cover resources that changed address")
+	asJSON := flags.Bool("json", false, "print the changes and moves as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *ref == "" && *basePlan == "" {
+		flags.Usage()
+		return fmt.Errorf("-ref or -base-plan is required")
+	}
+
+	config, err := plancheck.LoadConfig(p.config)
+	if err != nil {
+		return err
+	}
+	moved, err := plancheck.LoadMovedBlocks(*root)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	current, err := p.plan(ctx, false)
+	if err != nil {
+		return err
+	}
+	var base *tfjson.Plan
+	if *basePlan != "" {
+		base, err = readPlan(*basePlan)
+	} else {
+		base, err = runner.PlanRef(ctx, runner.Options{Dir: p.dir, Vars: p.vars, Env: p.env}, *ref)
+		if err != nil {
+			err = fmt.Errorf("planning %s: %w", *ref, err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	diff := plancheck.DiffShadow(
+		plancheck.NewSnapshot(p.environment, base, config.Snapshot),
+		plancheck.NewSnapshot(p.environment, current, config.Snapshot),
+		moved,
+	)
+
+	if *asJSON {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s\n", data)
+	} else {
+		for _, change := range diff.Changes {
+			fmt.Fprintln(stdout, change)
+		}
+		for _, move := range diff.Moves {
+			fmt.Fprintln(stdout, move)
+		}
+	}
+	if !diff.Identical() {
+		undeclared := 0
+		for _, move := range diff.Moves {
+			if !move.Declared {
+				undeclared++
+			}
+		}
+		return fmt.Errorf("%d planned value(s) change and %d move(s) lack a moved block", len(diff.Changes), undeclared)
+	}
+	if !*asJSON {
+		fmt.Fprintf(stdout, "plans are identical (%d move(s) covered by moved blocks)\n", len(diff.Moves))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/runner"
+)
+
+// fakeShadowTerraform plans whatever plan.json in the planned directory
+// holds.
+const fakeShadowTerraform = `#!/bin/sh
+if [ "$1" = "show" ]; then cat plan.json; fi
+`
+
+func shadowPlan(address string) string {
+	return `{"format_version":"1.0","planned_values":{"root_module":{"resources":[
+  {"address":"` + address + `","mode":"managed","type":"aws_s3_bucket","name":"logs","values":{"bucket":"logs"}}]}}}`
+}
+
+func TestShadowRequiresMovedBlocksForAnIdenticalPlan(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	bin := filepath.Join(t.TempDir(), "terraform")
+	require.NoError(t, os.WriteFile(bin, []byte(fakeShadowTerraform), 0o755))
+	t.Setenv(runner.BinaryEnv, bin)
+
+	repo := t.TempDir()
+	dir := filepath.Join(repo, "dev")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "plan.json"), []byte(shadowPlan("aws_s3_bucket.logs")), 0o644))
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repo
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "base")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "plan.json"), []byte(shadowPlan("aws_s3_bucket.access_logs")), 0o644))
+	config := filepath.Join(t.TempDir(), "compliance.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("environments: {}\n"), 0o644))
+	args := []string{"shadow", "-preflight=false", "-dir", dir, "-root", repo, "-config", config, "-ref", "HEAD"}
+
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	require.Equal(t, 1, code, stdout.String())
+	require.Equal(t, "aws_s3_bucket.logs -> aws_s3_bucket.access_logs (no moved block: terraform would replace it)\n", stdout.String())
+	require.Contains(t, stderr.String(), "0 planned value(s) change and 1 move(s) lack a moved block")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "moved.tf"), []byte(`moved {
+  from = aws_s3_bucket.logs
+  to   = aws_s3_bucket.access_logs
+}
+`), 0o644))
+	stdout.Reset()
+	stderr.Reset()
+	code = run(args, &stdout, &stderr)
+	require.Equalf(t, 0, code, "stdout: %s\nstderr: %s", stdout.String(), stderr.String())
+	require.Equal(t, "aws_s3_bucket.logs -> aws_s3_bucket.access_logs (moved block)\nplans are identical (1 move(s) covered by moved blocks)\n", stdout.String())
+}
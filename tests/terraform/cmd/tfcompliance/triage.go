@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// stdin is where triage reads answers from; tests replace it.
+var stdin io.Reader = os.Stdin
+
+func runTriage(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("triage", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	findingsFile := flags.String("findings", "", "findings.json written by the compliance tests (required)")
+	planFile := flags.String("plan", "", "plan JSON to show offending values from (optional)")
+	baselineFile := flags.String("baseline", "baseline.yaml", "baseline file accepted findings are written to")
+	expiryDays := flags.Int("expires", 90, "days an accepted finding stays accepted before it must be reviewed again (0: never expires)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *findingsFile == "" {
+		flags.Usage()
+		return fmt.Errorf("-findings is required")
+	}
+	if *expiryDays < 0 {
+		return fmt.Errorf("-expires must not be negative")
+	}
+	var expires time.Time
+	if *expiryDays > 0 {
+		expires = time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, *expiryDays)
+	}
+
+	findings, err := plancheck.ReadFindings(*findingsFile)
+	if err != nil {
+		return err
+	}
+	baseline, err := plancheck.LoadBaseline(*baselineFile)
+	if err != nil {
+		return err
+	}
+	var plan *tfjson.Plan
+	if *planFile != "" {
+		if plan, err = readPlan(*planFile); err != nil {
+			return err
+		}
+	}
+
+	open, _ := baseline.Filter(findings)
+	if len(open) == 0 {
+		fmt.Fprintln(stdout, "no findings to triage")
+		return nil
+	}
+
+	answers := bufio.NewScanner(stdin)
+	var accepted, skipped int
+triage:
+	for i, finding := range open {
+		fmt.Fprintf(stdout, "\n[%d/%d] %s\n", i+1, len(open), finding.RuleID)
+		printFinding(stdout, finding, plan)
+
+		answer, ok := prompt(stdout, answers, "[a]ccept, [s]kip, [q]uit? ")
+		switch {
+		case !ok || answer == "q" || answer == "quit":
+			break triage
+		case answer == "a" || answer == "accept":
+			justification, ok := "", true
+			for ok && justification == "" {
+				justification, ok = prompt(stdout, answers, "justification: ")
+			}
+			if !ok {
+				break triage
+			}
+			baseline.Accept(finding, justification, expires)
+			accepted++
+		default:
+			skipped++
+		}
+	}
+
+	if accepted > 0 {
+		if err := baseline.Save(*baselineFile); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(stdout, "\naccepted %d, skipped %d, %d left untriaged; baseline: %s\n",
+		accepted, skipped, len(open)-accepted-skipped, *baselineFile)
+	return nil
+}
+
+// prompt asks a question and returns the trimmed answer, or false once input
+// is exhausted.
+func prompt(w io.Writer, answers *bufio.Scanner, question string) (string, bool) {
+	fmt.Fprint(w, question)
+	if !answers.Scan() {
+		fmt.Fprintln(w)
+		return "", false
+	}
+	return strings.TrimSpace(answers.Text()), true
+}
+
+func printFinding(w io.Writer, finding plancheck.Finding, plan *tfjson.Plan) {
+	fmt.Fprintf(w, "  %s\n", finding.Message)
+	fmt.Fprintf(w, "  at:    %s\n", finding.Location())
+	if finding.Owner != "" {
+		fmt.Fprintf(w, "  owner: %s\n", finding.Owner)
+	}
+	if value, ok := offendingValue(plan, finding); ok {
+		fmt.Fprintf(w, "  value: %s\n", value)
+	}
+	if finding.Evidence != nil {
+		fmt.Fprintf(w, "  evidence: %s\n", finding.Evidence)
+	}
+	if rule, ok := plancheck.LookupRule(finding.RuleID); ok && rule.Remediation != "" {
+		fmt.Fprintf(w, "  fix:   %s\n", rule.Remediation)
+	}
+	if finding.Patch != "" {
+		fmt.Fprintf(w, "\n%s\n", finding.Patch)
+	}
+}
+
+// offendingValue renders the planned value at the finding's path.
+func offendingValue(plan *tfjson.Plan, finding plancheck.Finding) (string, bool) {
+	if plan == nil || finding.Path == "" {
+		return "", false
+	}
+	for _, resource := range plancheck.PlannedResources(plan) {
+		if resource == nil || resource.Address != finding.Address {
+			continue
+		}
+		value, ok := plancheck.ValueAt(resource.AttributeValues, finding.Path)
+		if !ok {
+			return "", false
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", false
+		}
+		return string(data), true
+	}
+	return "", false
+}
+
+func readPlan(filename string) (*tfjson.Plan, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var plan tfjson.Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &plan, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func TestTriageAcceptsFindingsIntoBaseline(t *testing.T) {
+	dir := t.TempDir()
+	findingsFile := filepath.Join(dir, "findings.json")
+	baselineFile := filepath.Join(dir, "baseline.yaml")
+
+	logs := plancheck.NewFinding("iam.wildcard-resource", "aws_iam_policy.logs", "IAM policy aws_iam_policy.logs contains wildcard Resource").
+		WithPath("policy.Statement[0].Resource")
+	ecr := plancheck.NewFinding("iam.wildcard-resource", "aws_iam_policy.ecr", "IAM policy aws_iam_policy.ecr contains wildcard Resource").
+		WithPath("policy.Statement[0].Resource")
+	require.NoError(t, plancheck.WriteFindings(findingsFile, []plancheck.Finding{logs, ecr}))
+
+	previous := stdin
+	stdin = strings.NewReader("a\n\nlogs:CreateLogGroup has no resource-level permissions\ns\n")
+	t.Cleanup(func() { stdin = previous })
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"triage", "-findings", findingsFile, "-baseline", baselineFile}, &stdout, &stderr)
+	require.Equalf(t, 0, code, "stderr: %s", stderr.String())
+	require.Contains(t, stdout.String(), "fix:   Scope the statement")
+	require.Contains(t, stdout.String(), "accepted 1, skipped 1, 0 left untriaged")
+
+	baseline, err := plancheck.LoadBaseline(baselineFile)
+	require.NoError(t, err)
+	require.True(t, baseline.Accepts(logs))
+	require.False(t, baseline.Accepts(ecr))
+	require.Equal(t, "logs:CreateLogGroup has no resource-level permissions", baseline.Accepted[0].Justification)
+	expires, err := plancheck.ParseExpiry(baseline.Accepted[0].Expires)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().AddDate(0, 0, 90), expires, 24*time.Hour, "accepted findings are reviewed again after 90 days by default")
+}
+
+func TestTriageReadsScriptedAnswersPerLine(t *testing.T) {
+	dir := t.TempDir()
+	findingsFile := filepath.Join(dir, "findings.json")
+	baselineFile := filepath.Join(dir, "baseline.yaml")
+	planFile := filepath.Join(dir, "plan.json")
+
+	var findings []plancheck.Finding
+	for _, name := range []string{"logs", "ecr", "sqs"} {
+		findings = append(findings, plancheck.NewFinding("iam.wildcard-action", "aws_iam_policy."+name, "IAM policy aws_iam_policy."+name+" contains wildcard Action").
+			WithPath("policy.Statement[0].Action"))
+	}
+	require.NoError(t, plancheck.WriteFindings(findingsFile, findings))
+	require.NoError(t, os.WriteFile(planFile, []byte(`{"format_version": "1.0", "planned_values": {"root_module": {"resources": [
+		{"address": "aws_iam_policy.logs", "type": "aws_iam_policy", "name": "logs",
+		 "values": {"policy": "{\"Statement\":[{\"Action\":\"logs:*\",\"Resource\":\"*\"}]}"}}
+	]}}}`), 0o644))
+
+	// The long answers work like the short ones; input ends before the last
+	// finding, which stays untriaged.
+	previous := stdin
+	stdin = strings.NewReader("skip\naccept\nECR pulls are scoped by repository policy\n")
+	t.Cleanup(func() { stdin = previous })
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"triage", "-findings", findingsFile, "-baseline", baselineFile, "-plan", planFile}, &stdout, &stderr)
+	require.Equalf(t, 0, code, "stderr: %s", stderr.String())
+	require.Contains(t, stdout.String(), `value: "logs:*"`)
+	require.Contains(t, stdout.String(), "accepted 1, skipped 1, 1 left untriaged")
+
+	baseline, err := plancheck.LoadBaseline(baselineFile)
+	require.NoError(t, err)
+	require.False(t, baseline.Accepts(findings[0]))
+	require.True(t, baseline.Accepts(findings[1]))
+	require.False(t, baseline.Accepts(findings[2]))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"cs450/terraformtests/awsauth"
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/runlock"
+)
+
+// runUnlock shows who holds an environment's run lock, and removes it when
+// given the holder's ID: the escape hatch for a run that died holding the
+// lock, when waiting for the lock to go stale is not an option.
+func runUnlock(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("unlock", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	environment := flags.String("env", "dev", "environment whose run lock is shown or removed")
+	configFile := flags.String("config", "compliance.yaml", "compliance configuration file naming the lock table")
+	region := flags.String("region", "us-east-1", "region the environment's role is assumed in")
+	id := flags.String("id", "", "ID of the lock to remove, as shown without -id; the lock is only removed while still held under it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := plancheck.LoadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	if config.Lock.Table == "" {
+		return fmt.Errorf("%s names no lock.table; runs are not locked", *configFile)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	creds, err := awsauth.ForEnvironment(ctx, config, *environment, *region)
+	if err != nil {
+		return err
+	}
+	client, err := runlock.NewClient(config.Lock.TableRegion(), config.Lock.Table, creds.AWS())
+	if err != nil {
+		return err
+	}
+
+	holder, found, err := client.Holder(ctx, *environment)
+	if err != nil {
+		return err
+	}
+	if !found {
+		fmt.Fprintf(stdout, "%s is not locked\n", *environment)
+		return nil
+	}
+	if *id == "" {
+		fmt.Fprintf(stdout, "%s is locked by %s\n", *environment, holder)
+		if stale := time.Since(holder.Heartbeat); stale > config.Lock.StaleAfter() {
+			fmt.Fprintf(stdout, "the lock is stale (no heartbeat for %s); the next run takes it over\n", stale.Round(time.Second))
+		}
+		fmt.Fprintf(stdout, "to remove it: tfcompliance unlock -env %s -id %s\n", *environment, holder.ID)
+		return nil
+	}
+	if holder.ID != *id {
+		return fmt.Errorf("%s is locked by %s, not under %s; nothing removed", *environment, holder, *id)
+	}
+	if err := client.ForceUnlock(ctx, *environment, *id); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "removed the lock %s on %s held by %s\n", *id, *environment, holder.Owner)
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/runner"
+)
+
+func runUpgrade(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("upgrade", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var p planFlags
+	p.register(flags)
+	var upgrade runner.Upgrade
+	flags.StringVar(&upgrade.Provider, "provider", "aws", "local name of the provider to upgrade")
+	flags.StringVar(&upgrade.Source, "source", "", "provider source address (default hashicorp/<provider>)")
+	flags.StringVar(&upgrade.Version, "version", "", "candidate provider version, e.g. 5.31.0 (required)")
+	flags.StringVar(&upgrade.Root, "root", "../../infra", "directory copied for the candidate plan; must contain -dir and every local module it uses")
+	basePlan := flags.String("base", "", "plan JSON to compare against instead of planning -dir with the locked provider")
+	asJSON := flags.Bool("json", false, "print the changes as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if upgrade.Version == "" {
+		flags.Usage()
+		return fmt.Errorf("-version is required")
+	}
+
+	ctx := context.Background()
+	if err := p.prepare(ctx, io.Discard); err != nil {
+		return err
+	}
+	var (
+		base *tfjson.Plan
+		err  error
+	)
+	if *basePlan != "" {
+		base, err = readPlan(*basePlan)
+	} else {
+		base, err = p.plan(ctx, false)
+	}
+	if err != nil {
+		return err
+	}
+	candidate, err := runner.PlanUpgrade(ctx, runner.Options{Dir: p.dir, Vars: p.vars, Env: p.env}, upgrade)
+	if err != nil {
+		return fmt.Errorf("planning with %s %s: %w", upgrade.Provider, upgrade.Version, err)
+	}
+	changes := plancheck.DiffPlannedValues(base, candidate)
+
+	if *asJSON {
+		if changes == nil {
+			changes = []plancheck.ValueChange{}
+		}
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(stdout, "%s\n", data)
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintf(stdout, "no planned values change with %s %s\n", upgrade.Provider, upgrade.Version)
+		return nil
+	}
+	fmt.Fprintf(stdout, "%d planned value(s) change with %s %s:\n", len(changes), upgrade.Provider, upgrade.Version)
+	for _, change := range changes {
+		fmt.Fprintln(stdout, change)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/runner"
+)
+
+// fakeUpgradeTerraform plans a bucket whose versioning default changes when
+// the provider is pinned by the override file.
+const fakeUpgradeTerraform = `#!/bin/sh
+if [ "$1" != "show" ]; then exit 0; fi
+enabled=false
+if [ -f tfcompliance_override.tf ]; then enabled=true; fi
+cat <<JSON
+{"format_version":"1.0","planned_values":{"root_module":{"resources":[
+  {"address":"aws_s3_bucket.logs","mode":"managed","type":"aws_s3_bucket","name":"logs",
+   "values":{"bucket":"logs","versioning":[{"enabled":$enabled}]}}]}}}
+JSON
+`
+
+func TestUpgradeListsChangedPlannedValues(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "terraform")
+	require.NoError(t, os.WriteFile(bin, []byte(fakeUpgradeTerraform), 0o755))
+	t.Setenv(runner.BinaryEnv, bin)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte("resource \"aws_s3_bucket\" \"logs\" {}\n"), 0o644))
+	config := filepath.Join(dir, "compliance.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("environments: {}\n"), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"upgrade", "-preflight=false", "-dir", dir, "-root", dir, "-config", config, "-version", "5.31.0"}, &stdout, &stderr)
+	require.Equalf(t, 0, code, "stdout: %s\nstderr: %s", stdout.String(), stderr.String())
+	require.Equal(t, "1 planned value(s) change with aws 5.31.0:\n~ aws_s3_bucket.logs versioning.0.enabled: false -> true\n", stdout.String())
+	require.NoFileExists(t, filepath.Join(dir, "tfcompliance_override.tf"))
+}
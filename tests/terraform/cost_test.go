@@ -0,0 +1,23 @@
+package terraformtests
+
+import (
+	"testing"
+)
+
+// The cost rules are advisories: requireNoFindings logs what they find for
+// the owners to weigh, but this test only fails if the plan cannot be checked.
+func TestCostAdvisories(t *testing.T) {
+	requireCompliance(t, "cost.nat-gateway", "cost.cross-az", "cost.gp2", "cost.provisioned-iops", "cost.unattached-eip", "cost.graviton", "cost.s3-storage-class", "cost.log-archive")
+}
+
+func TestInstanceTypesAreApproved(t *testing.T) {
+	requireCompliance(t, "instances.approved-types")
+}
+
+func TestAutoscalingGroupsFollowSpotPolicy(t *testing.T) {
+	requireCompliance(t, "autoscaling.spot-mix")
+}
+
+func TestNonProductionScalesDown(t *testing.T) {
+	requireCompliance(t, "schedule.scale-down")
+}
@@ -0,0 +1,31 @@
+package terraformtests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// TestRuleCoverage reports which planned resource types no rule inspects. It
+// never fails; the report is for prioritising new rules.
+func TestRuleCoverage(t *testing.T) {
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		_, plan := environmentPlan(t, env.Name)
+		coverage := plancheck.Coverage(plan)
+
+		var report strings.Builder
+		plancheck.PrintCoverage(&report, coverage)
+		t.Logf("rule coverage by resource type:\n%s", report.String())
+
+		if dir := os.Getenv(plancheck.ReportDirEnv); dir != "" {
+			dir = filepath.Join(dir, filepath.FromSlash(t.Name()))
+			require.NoError(t, os.MkdirAll(dir, 0o755))
+			require.NoError(t, plancheck.WriteCoverage(filepath.Join(dir, "coverage.json"), coverage))
+		}
+	})
+}
@@ -0,0 +1,12 @@
+package terraformtests
+
+import (
+	"testing"
+)
+
+// Plans must not destroy or replace S3 buckets, DynamoDB tables, RDS
+// instances or KMS keys (changes.protected_types in compliance.yaml), unless
+// COMPLIANCE_ALLOW_DESTROY lists the address for this run.
+func TestPlanDestroysNoStatefulResources(t *testing.T) {
+	requireCompliance(t, "changes.destroy-protected")
+}
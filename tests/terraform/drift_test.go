@@ -0,0 +1,70 @@
+package terraformtests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// backendConfigEnv names a -backend-config file for the drift check, with
+// {environment} standing for the environment's name, e.g.
+// backends/{environment}.tfbackend. Unset uses the backend block as written.
+const backendConfigEnv = "COMPLIANCE_BACKEND_CONFIG"
+
+// Changes made in the console never show up in a plan of a fresh
+// configuration. Against the applied state, terraform plan -detailed-exitcode
+// exits 2 when a resource was changed outside terraform or the configuration
+// has changes not yet applied; either way the environment has drifted.
+func TestNoDriftInDeployedEnvironment(t *testing.T) {
+	if os.Getenv(postApplyEnv) == "" {
+		t.Skipf("set %s=1 to plan the deployed environments against their state", postApplyEnv)
+	}
+	skipWithoutPlanning(t)
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		require.NoError(t, checkIdentity(t, env.Name, creds))
+
+		options := environmentOptions(t, env)
+		options.EnvVars = creds.Env()
+		// Hold the state lock, so the plan never reads state an apply is
+		// halfway through writing.
+		options.Lock = true
+		if pattern := os.Getenv(backendConfigEnv); pattern != "" {
+			file, err := filepath.Abs(strings.ReplaceAll(pattern, "{environment}", env.Name))
+			require.NoError(t, err)
+			require.FileExists(t, file, "%s must name a backend config file for %s", backendConfigEnv, env.Name)
+			// terratest passes BackendConfig entries as key=value only.
+			if options.EnvVars == nil {
+				options.EnvVars = map[string]string{}
+			}
+			options.EnvVars["TF_CLI_ARGS_init"] = "-backend-config=" + file
+		}
+		options.PlanFilePath, err = planFilePath(env.Name + "-drift")
+		require.NoError(t, err)
+		cleanupArtifacts(t, filepath.Dir(options.PlanFilePath))
+
+		_, err = terraform.InitE(t, options)
+		require.NoError(t, err)
+		exitCode, err := terraform.PlanExitCodeE(t, options)
+		require.NoError(t, err, "terraform plan of %s must succeed", env.Name)
+		if exitCode == terraform.DefaultSuccessExitCode {
+			return
+		}
+
+		planJSON, err := terraform.RunTerraformCommandAndGetStdoutE(t, options, "show", "-json", options.PlanFilePath)
+		require.NoError(t, err)
+		drifts, err := plancheck.Drifts([]byte(planJSON))
+		require.NoError(t, err)
+		var summary strings.Builder
+		plancheck.WriteDriftSummary(&summary, drifts)
+		t.Errorf("%s has drifted from its configuration: %d resource change(s)\n%s", env.Name, len(drifts), summary.String())
+	})
+}
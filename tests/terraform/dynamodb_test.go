@@ -0,0 +1,9 @@
+package terraformtests
+
+import (
+	"testing"
+)
+
+func TestDynamoDBTablesAreManaged(t *testing.T) {
+	requireCompliance(t, "dynamodb.autoscaling", "dynamodb.ttl", "dynamodb.gsi-schema")
+}
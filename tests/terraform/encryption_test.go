@@ -0,0 +1,12 @@
+package terraformtests
+
+import (
+	"testing"
+)
+
+// DynamoDB tables, RDS instances, EBS volumes, SQS queues and SNS topics must
+// be encrypted at rest, with a customer managed key for the resource types
+// listed under encryption.customer_managed_keys in compliance.yaml.
+func TestDataStoresAreEncrypted(t *testing.T) {
+	requireCompliance(t, "encryption.at-rest", "encryption.customer-managed-key")
+}
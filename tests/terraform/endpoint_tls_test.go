@@ -0,0 +1,55 @@
+package terraformtests
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/tlsprobe"
+)
+
+// tlsEndpointOutputs are the outputs holding the URLs clients connect to.
+var tlsEndpointOutputs = []string{"api_gateway_url", "cloudfront_url", "validator_service_url"}
+
+// Deployed endpoints must negotiate modern TLS and must not let a client be
+// downgraded: no old protocol versions or weak cipher suites, HSTS with a long
+// max-age, and no content served over plain HTTP.
+func TestEndpointsEnforceTLSPolicy(t *testing.T) {
+	if os.Getenv(postApplyEnv) == "" {
+		t.Skipf("set %s=1 to probe the TLS configuration of the deployed endpoints", postApplyEnv)
+	}
+
+	config, err := plancheck.LoadConfig(complianceFile)
+	require.NoError(t, err)
+	policy := tlsprobe.Policy{HSTSMaxAge: time.Duration(config.TLS.HSTSMaxAge) * time.Second}
+	if config.TLS.MinVersion != "" {
+		policy.MinVersion, err = tlsprobe.ParseVersion(config.TLS.MinVersion)
+		require.NoError(t, err, "tls.min_version in %s", complianceFile)
+	}
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		options := environmentOptions(t, env)
+		options.EnvVars = creds.Env()
+		terraform.Init(t, options)
+
+		prober := &tlsprobe.Prober{Policy: policy}
+		for _, output := range tlsEndpointOutputs {
+			endpoint := terraform.OutputRequired(t, options, output)
+			result, err := prober.Probe(endpoint)
+			if err != nil {
+				t.Errorf("%s: %v", output, err)
+				continue
+			}
+			t.Logf("%s (%s) negotiates %s with %s", output, endpoint, result.Version, result.Cipher)
+			for _, problem := range result.Problems {
+				t.Errorf("%s (%s) %s", output, endpoint, problem)
+			}
+		}
+	})
+}
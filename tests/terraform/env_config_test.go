@@ -0,0 +1,250 @@
+package terraformtests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// environmentsFile lists the environments of the test matrix.
+const environmentsFile = "environments.yaml"
+
+// EnvConfig holds the terraform variables an environment is planned with.
+// It is validated before terraform runs, so a mistyped region or bucket name
+// fails with a clear error instead of a provider error deep in the plan.
+type EnvConfig struct {
+	// Name is the environment's name in compliance.yaml and in test names.
+	Name string `yaml:"name"`
+	// Dir is the environment's root module, relative to this directory.
+	Dir string `yaml:"dir"`
+	// Region is aws_region, e.g. us-east-1.
+	Region string `yaml:"region"`
+	// ArtifactsBucket is artifacts_bucket, an S3 bucket name.
+	ArtifactsBucket string `yaml:"artifacts_bucket"`
+	// ImageTag is image_tag; empty keeps the module's default.
+	ImageTag string `yaml:"image_tag,omitempty"`
+
+	// Skip maps test names, or "*" for every test, to why the test does not
+	// run against the environment.
+	Skip map[string]string `yaml:"skip,omitempty"`
+}
+
+// skipReason returns why test does not run against c, or "".
+func (c EnvConfig) skipReason(test string) string {
+	if reason, ok := c.Skip[test]; ok {
+		return reason
+	}
+	return c.Skip["*"]
+}
+
+var (
+	environmentsOnce sync.Once
+	environments     []EnvConfig
+	environmentsErr  error
+)
+
+// loadEnvironments reads environments.yaml once per test binary.
+func loadEnvironments() ([]EnvConfig, error) {
+	environmentsOnce.Do(func() {
+		environments, environmentsErr = readEnvironments(environmentsFile)
+	})
+	return environments, environmentsErr
+}
+
+func readEnvironments(filename string) ([]EnvConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Environments []EnvConfig `yaml:"environments"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	seen := map[string]bool{}
+	for _, env := range file.Environments {
+		if env.Name == "" {
+			return nil, fmt.Errorf("%s: an environment has no name", filename)
+		}
+		if seen[env.Name] {
+			return nil, fmt.Errorf("%s: environment %s is listed twice", filename, env.Name)
+		}
+		seen[env.Name] = true
+	}
+	return file.Environments, nil
+}
+
+// testEnvironment returns the named environment of the matrix, failing the
+// test if there is none.
+func testEnvironment(t *testing.T, name string) EnvConfig {
+	t.Helper()
+
+	envs, err := loadEnvironments()
+	require.NoError(t, err, "%s must load", environmentsFile)
+	for _, env := range envs {
+		if env.Name == name {
+			return env
+		}
+	}
+	require.FailNow(t, "unknown environment", "%s lists no environment %q", environmentsFile, name)
+	return EnvConfig{}
+}
+
+// forEachEnvironment runs fn as a subtest named after each environment of
+// the matrix, skipping the environments whose skip list names the test.
+func forEachEnvironment(t *testing.T, fn func(t *testing.T, env EnvConfig)) {
+	t.Helper()
+
+	envs, err := loadEnvironments()
+	require.NoError(t, err, "%s must load", environmentsFile)
+	test := t.Name()
+	for _, env := range envs {
+		env := env
+		t.Run(env.Name, func(t *testing.T) {
+			if reason := env.skipReason(test); reason != "" {
+				t.Skip(reason)
+			}
+			fn(t, env)
+		})
+	}
+}
+
+// requireCompliance evaluates rules against the plan of every environment
+// and fails each environment's subtest on its findings.
+func requireCompliance(t *testing.T, ruleIDs ...string) {
+	t.Helper()
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		options, plan := environmentPlan(t, env.Name)
+		findings := evaluateRules(t, plan, options, env.Name, ruleIDs...)
+		requireNoFindings(t, plan, findings)
+	})
+}
+
+var (
+	regionPattern   = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]*)?-[a-z]+-\d$`)
+	bucketPattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+	imageTagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+)
+
+// Validate reports every invalid field at once.
+func (c EnvConfig) Validate() error {
+	var errs []error
+	if c.Dir == "" {
+		errs = append(errs, errors.New("Dir is empty"))
+	}
+	if !regionPattern.MatchString(c.Region) {
+		errs = append(errs, fmt.Errorf("Region %q is not an AWS region such as us-east-1", c.Region))
+	}
+	if err := validateBucketName(c.ArtifactsBucket); err != nil {
+		errs = append(errs, fmt.Errorf("ArtifactsBucket: %w", err))
+	}
+	if c.ImageTag != "" && !imageTagPattern.MatchString(c.ImageTag) {
+		errs = append(errs, fmt.Errorf("ImageTag %q is not a valid image tag", c.ImageTag))
+	}
+	return errors.Join(errs...)
+}
+
+// validateBucketName applies the S3 general purpose bucket naming rules.
+func validateBucketName(name string) error {
+	switch {
+	case !bucketPattern.MatchString(name):
+		return fmt.Errorf("%q must be 3-63 lowercase letters, digits, dots and hyphens, starting and ending with a letter or digit", name)
+	case strings.Contains(name, ".."):
+		return fmt.Errorf("%q must not contain adjacent dots", name)
+	case strings.HasPrefix(name, "xn--") || strings.HasPrefix(name, "sthree-"):
+		return fmt.Errorf("%q uses a reserved prefix", name)
+	case strings.HasSuffix(name, "-s3alias") || strings.HasSuffix(name, "--ol-s3"):
+		return fmt.Errorf("%q uses a reserved suffix", name)
+	}
+	if _, err := netip.ParseAddr(name); err == nil {
+		return fmt.Errorf("%q must not be formatted as an IP address", name)
+	}
+	return nil
+}
+
+// Options returns terratest options planning c.Dir with c's variables.
+func (c EnvConfig) Options() *terraform.Options {
+	vars := map[string]interface{}{
+		"aws_region":       c.Region,
+		"artifacts_bucket": c.ArtifactsBucket,
+	}
+	if c.ImageTag != "" {
+		vars["image_tag"] = c.ImageTag
+	}
+	return &terraform.Options{
+		TerraformDir: filepath.Clean(c.Dir),
+		NoColor:      true,
+		Vars:         vars,
+	}
+}
+
+func TestEnvConfigValidation(t *testing.T) {
+	envs, err := loadEnvironments()
+	require.NoError(t, err, "%s must load", environmentsFile)
+	for _, env := range envs {
+		require.NoError(t, env.Validate(), "the %s variables must be valid", env.Name)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		config EnvConfig
+		err    string
+	}{
+		{name: "region", config: EnvConfig{Dir: ".", Region: "us-east", ArtifactsBucket: "artifacts"}, err: "Region"},
+		{name: "gov region", config: EnvConfig{Dir: ".", Region: "us-gov-west-1", ArtifactsBucket: "artifacts"}},
+		{name: "uppercase bucket", config: EnvConfig{Dir: ".", Region: "us-east-1", ArtifactsBucket: "Artifacts"}, err: "ArtifactsBucket"},
+		{name: "short bucket", config: EnvConfig{Dir: ".", Region: "us-east-1", ArtifactsBucket: "ab"}, err: "ArtifactsBucket"},
+		{name: "dotted bucket", config: EnvConfig{Dir: ".", Region: "us-east-1", ArtifactsBucket: "a..b"}, err: "adjacent dots"},
+		{name: "IP bucket", config: EnvConfig{Dir: ".", Region: "us-east-1", ArtifactsBucket: "192.168.5.4"}, err: "IP address"},
+		{name: "alias bucket", config: EnvConfig{Dir: ".", Region: "us-east-1", ArtifactsBucket: "pkg-s3alias"}, err: "reserved suffix"},
+		{name: "image tag", config: EnvConfig{Dir: ".", Region: "us-east-1", ArtifactsBucket: "artifacts", ImageTag: "v1:2"}, err: "ImageTag"},
+		{name: "empty", config: EnvConfig{}, err: "Dir is empty"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestEnvironmentsFile(t *testing.T) {
+	envs, err := loadEnvironments()
+	require.NoError(t, err, "%s must load", environmentsFile)
+	config, err := plancheck.LoadConfig(complianceFile)
+	require.NoError(t, err)
+	for _, env := range envs {
+		require.Contains(t, config.Environments, env.Name, "%s must configure every environment in %s", complianceFile, environmentsFile)
+	}
+
+	env := EnvConfig{Skip: map[string]string{"*": "not deployed", "TestA": "no API"}}
+	require.Equal(t, "no API", env.skipReason("TestA"))
+	require.Equal(t, "not deployed", env.skipReason("TestB"))
+	require.Empty(t, EnvConfig{}.skipReason("TestB"))
+
+	dir := t.TempDir()
+	duplicate := filepath.Join(dir, "environments.yaml")
+	require.NoError(t, os.WriteFile(duplicate, []byte("environments:\n  - name: dev\n  - name: dev\n"), 0o644))
+	_, err = readEnvironments(duplicate)
+	require.EqualError(t, err, duplicate+": environment dev is listed twice")
+}
@@ -0,0 +1,54 @@
+package terraformtests
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/pipeline"
+	"cs450/terraformtests/plancheck"
+)
+
+// After an apply, an object dropped in the artifacts bucket must reach every
+// queue and Lambda function the bucket notifies of it: the queue's
+// NumberOfMessagesSent metric must rise and each handler must log the
+// object's key within pipeline.timeout_seconds. The handlers process the
+// object like any other, so environments compliance.yaml does not mark
+// sandbox: true are skipped.
+func TestArtifactsEventPipelineFires(t *testing.T) {
+	if os.Getenv(postApplyEnv) == "" {
+		t.Skipf("set %s=1 to drop an object in the deployed artifacts buckets", postApplyEnv)
+	}
+
+	config, err := plancheck.LoadConfig(complianceFile)
+	require.NoError(t, err)
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		if !config.Environment(env.Name).Sandbox {
+			t.Skipf("refusing to feed a test object to the handlers of %s, which %s does not mark sandbox: true", env.Name, complianceFile)
+		}
+
+		_, plan := environmentPlan(t, env.Name)
+		triggers := pipeline.Triggers(plan, env.ArtifactsBucket)
+		if len(triggers) == 0 {
+			t.Skipf("the %s plan notifies no queue or Lambda function of objects put in %s", env.Name, env.ArtifactsBucket)
+		}
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		clients, err := pipeline.NewClients(env.Region, creds.AWS())
+		require.NoError(t, err)
+
+		for _, trigger := range triggers {
+			trigger := trigger
+			t.Run(trigger.Target(), func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), config.Pipeline.Timeout())
+				defer cancel()
+				key, err := clients.Verify(ctx, trigger)
+				require.NoError(t, err)
+				t.Logf("s3://%s/%s reached %s", trigger.Bucket, key, trigger.Target())
+			})
+		}
+	})
+}
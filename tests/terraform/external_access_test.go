@@ -0,0 +1,42 @@
+package terraformtests
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/livestate"
+	"cs450/terraformtests/plancheck"
+)
+
+// Access Analyzer sees what the deployed resource policies actually grant,
+// including grants made outside terraform, so its active findings on the
+// resources this stack manages fail the suite.
+func TestNoExternalAccessToManagedResources(t *testing.T) {
+	if os.Getenv(postApplyEnv) == "" {
+		t.Skipf("set %s=1 to check Access Analyzer findings on the deployed stack", postApplyEnv)
+	}
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		options, plan := environmentPlan(t, env.Name)
+		config, err := plancheck.LoadConfig(complianceFile)
+		require.NoError(t, err)
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		clients, err := livestate.NewClients(env.Region, creds.AWS())
+		require.NoError(t, err)
+		access, err := clients.AccessFindings()
+		require.NoError(t, err)
+
+		findings := plancheck.Evaluate(&plancheck.Input{
+			Plan:          plan,
+			DefaultRegion: env.Region,
+			Environment:   env.Name,
+			Config:        config,
+			Runtime:       &plancheck.Runtime{AccessFindings: access},
+		}, requireRules(t, "access.external")...)
+		plancheck.NewSourceIndex(plan, options.TerraformDir).Annotate(findings)
+		requireNoFindings(t, plan, findings)
+	})
+}
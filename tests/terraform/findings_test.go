@@ -0,0 +1,110 @@
+package terraformtests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+	_ "cs450/terraformtests/rules" // registers the built-in rules
+)
+
+const (
+	ownersFile   = "OWNERS"
+	baselineFile = "baseline.yaml"
+	localesDir   = "locales"
+
+	// repoRoot is the checkout SARIF source paths are relative to.
+	repoRoot = "../.."
+)
+
+// runFindings collects the findings of every test for the SARIF and JUnit
+// reports TestMain writes.
+var runFindings plancheck.Collector
+
+// requireNoFindings routes findings to their owners, writes per-owner reports
+// when COMPLIANCE_REPORT_DIR is set, records them for the run's SARIF and
+// JUnit reports, and fails the test if any were found that neither the
+// baseline accepts nor a compliance:suppress tag of the resource in plan
+// suppresses. Advisory findings are logged and reported but do not fail the
+// test. A failing test ends with a per-rule summary. Messages are logged in
+// the language LOCALE selects.
+func requireNoFindings(t *testing.T, plan *tfjson.Plan, findings []plancheck.Finding) {
+	t.Helper()
+
+	baseline, err := plancheck.LoadBaseline(baselineFile)
+	require.NoError(t, err, "baseline file must be valid")
+	findings, accepted := baseline.Filter(findings)
+	for _, finding := range accepted {
+		t.Logf("accepted by %s: [%s] %s", baselineFile, finding.RuleID, finding.Location())
+	}
+	suppressions := plancheck.TagSuppressions(plan)
+	for _, address := range suppressions.Unjustified() {
+		t.Logf("ignoring the %s tag of %s, which has no %s tag", plancheck.SuppressTag, address, plancheck.JustificationTag)
+	}
+	findings, suppressed := suppressions.Filter(findings)
+	for _, finding := range suppressed {
+		t.Logf("suppressed by tag: [%s] %s: %s", finding.RuleID, finding.Location(), suppressions[finding.Address].Justification)
+	}
+
+	owners, err := plancheck.LoadOwners(ownersFile)
+	require.NoError(t, err, "ownership file must be readable")
+	owners.Assign(findings)
+	plancheck.SortFindings(findings)
+
+	if dir := os.Getenv(plancheck.ReportDirEnv); dir != "" {
+		written, err := plancheck.WriteReports(filepath.Join(dir, filepath.FromSlash(t.Name())), findings)
+		require.NoError(t, err, "compliance reports must be writable")
+		t.Logf("compliance reports written: %v", written)
+	}
+
+	catalog, err := plancheck.LoadCatalogFromEnv(localesDir)
+	require.NoError(t, err, "message catalog must be valid")
+	catalog.Localize(findings)
+	runFindings.Add(t.Name(), findings)
+	blocking, advisories := plancheck.SplitAdvisories(findings)
+	for _, finding := range advisories {
+		t.Logf("%s %s\n\tat %s (owner: %s)", finding.Label(), finding.Message, finding.Location(), finding.Owner)
+	}
+	for _, finding := range blocking {
+		t.Errorf("%s %s\n\tat %s (owner: %s)", finding.Label(), finding.Message, finding.Location(), finding.Owner)
+		if finding.Patch != "" {
+			t.Logf("suggested fix for %s:\n%s", finding.Address, finding.Patch)
+		}
+	}
+	if len(blocking) > 0 {
+		var summary strings.Builder
+		plancheck.WriteSummary(&summary, findings)
+		t.Log(summary.String())
+	}
+	require.Emptyf(t, blocking, "%d compliance finding(s)", len(blocking))
+}
+
+var (
+	pluginsOnce sync.Once
+	pluginsErr  error
+)
+
+// requireRules looks up registered rules by ID, failing the test on unknown IDs.
+// Rules from plugins listed in COMPLIANCE_PLUGINS are registered first.
+func requireRules(t *testing.T, ids ...string) []plancheck.Rule {
+	t.Helper()
+
+	pluginsOnce.Do(func() {
+		_, pluginsErr = plancheck.LoadPluginsFromEnv()
+	})
+	require.NoError(t, pluginsErr, "rule plugins must load")
+
+	rules := make([]plancheck.Rule, 0, len(ids))
+	for _, id := range ids {
+		rule, ok := plancheck.LookupRule(id)
+		require.Truef(t, ok, "rule %s must be registered", id)
+		rules = append(rules, rule)
+	}
+	return rules
+}
@@ -0,0 +1,285 @@
+// Package fix turns the fixes suggested by rules into unified-diff patches
+// against the terraform source, so mechanical findings can be applied with
+// patch(1) instead of edited by hand.
+package fix
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/zclconf/go-cty/cty"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// Suggest fills in the Patch of every finding that carries a Fix and whose
+// resource can be found through index. Findings whose fix cannot be applied
+// (e.g. tags built by a function call that is not an object) keep an empty
+// Patch.
+func Suggest(index *plancheck.SourceIndex, findings []plancheck.Finding) {
+	for i := range findings {
+		if findings[i].Fix == nil || findings[i].Patch != "" {
+			continue
+		}
+		if patch, err := Patch(index, findings[i]); err == nil {
+			findings[i].Patch = patch
+		}
+	}
+}
+
+// Patch returns a unified diff that applies finding.Fix to the file declaring
+// the finding's resource.
+func Patch(index *plancheck.SourceIndex, finding plancheck.Finding) (string, error) {
+	filename, before, after, err := edit(index, finding)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(before)),
+		B:        difflib.SplitLines(string(after)),
+		FromFile: filename,
+		ToFile:   filename,
+		Context:  3,
+	})
+}
+
+// Write applies finding.Fix to the file declaring the finding's resource and
+// returns the file's name.
+func Write(index *plancheck.SourceIndex, finding plancheck.Finding) (string, error) {
+	filename, _, after, err := edit(index, finding)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return "", err
+	}
+	return filename, os.WriteFile(filename, after, info.Mode().Perm())
+}
+
+func edit(index *plancheck.SourceIndex, finding plancheck.Finding) (filename string, before, after []byte, err error) {
+	if finding.Fix == nil {
+		return "", nil, nil, fmt.Errorf("%s: no fix for %s", finding.Address, finding.RuleID)
+	}
+	filename, ok := index.ResourceFile(finding.Address)
+	if !ok {
+		return "", nil, nil, fmt.Errorf("%s: configuration not found", finding.Address)
+	}
+
+	before, err = os.ReadFile(filename)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	after, err = Apply(before, filename, finding.Address, *finding.Fix)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return filename, before, after, nil
+}
+
+// Apply edits src, the contents of filename, so the resource at address
+// carries the fix, and returns the result with the edited resource block
+// formatted. The rest of the file is left as written.
+func Apply(src []byte, filename, address string, fix plancheck.Fix) ([]byte, error) {
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	resourceType, name := resourceLabels(address)
+	block := file.Body().FirstMatchingBlock("resource", []string{resourceType, name})
+	if block == nil {
+		return nil, fmt.Errorf("%s: resource block not found in %s", address, filename)
+	}
+
+	original := block.BuildTokens(nil).Bytes()
+	body := block.Body()
+	if fix.Block != "" {
+		nested := body.FirstMatchingBlock(fix.Block, nil)
+		if nested == nil {
+			nested = body.AppendNewBlock(fix.Block, nil)
+		}
+		body = nested.Body()
+	}
+
+	if err := setAttribute(body, fix); err != nil {
+		return nil, fmt.Errorf("%s: %w", address, err)
+	}
+
+	// Splice the formatted block into the original source rather than
+	// formatting the whole file, so the patch touches only this resource.
+	start := bytes.Index(src, original)
+	if start < 0 {
+		return hclwrite.Format(file.Bytes()), nil
+	}
+	out := append([]byte{}, src[:start]...)
+	out = append(out, hclwrite.Format(block.BuildTokens(nil).Bytes())...)
+	return append(out, src[start+len(original):]...), nil
+}
+
+func setAttribute(body *hclwrite.Body, fix plancheck.Fix) error {
+	value, err := ctyValue(fix.Value)
+	if err != nil {
+		return err
+	}
+
+	existing := body.GetAttribute(fix.Attribute)
+	if !fix.Merge || existing == nil || !value.Type().IsObjectType() {
+		body.SetAttributeValue(fix.Attribute, value)
+		return nil
+	}
+
+	tokens := existing.Expr().BuildTokens(nil)
+	if isObjectLiteral(tokens) {
+		// Keys the literal already sets are kept as written, so applying the
+		// same fix twice, e.g. once per count instance, changes nothing.
+		present := objectKeys(tokens)
+		var missing []string
+		for _, key := range sortedKeys(value) {
+			if !present[key] {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+
+		// Insert the new entries before the closing brace; Format realigns them.
+		closing := len(tokens) - 1
+		merged := append(hclwrite.Tokens{}, tokens[:closing]...)
+		if merged[len(merged)-1].Type != hclsyntax.TokenNewline {
+			merged = append(merged, &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")})
+		}
+		for _, key := range missing {
+			merged = append(merged, objectKey(key)...)
+			merged = append(merged, &hclwrite.Token{Type: hclsyntax.TokenEqual, Bytes: []byte("=")})
+			merged = append(merged, hclwrite.TokensForValue(value.GetAttr(key))...)
+			merged = append(merged, &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")})
+		}
+		merged = append(merged, tokens[closing:]...)
+		body.SetAttributeRaw(fix.Attribute, merged)
+		return nil
+	}
+
+	// Anything else (a variable, merge(...), a local) is wrapped in merge().
+	merged := hclwrite.Tokens{
+		{Type: hclsyntax.TokenIdent, Bytes: []byte("merge")},
+		{Type: hclsyntax.TokenOParen, Bytes: []byte("(")},
+	}
+	merged = append(merged, tokens...)
+	merged = append(merged, &hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")})
+	merged = append(merged, hclwrite.TokensForValue(value)...)
+	merged = append(merged, &hclwrite.Token{Type: hclsyntax.TokenCParen, Bytes: []byte(")")})
+	body.SetAttributeRaw(fix.Attribute, merged)
+	return nil
+}
+
+// objectKey renders an object key, quoting keys that are not identifiers
+// such as "cost center".
+func objectKey(key string) hclwrite.Tokens {
+	if hclsyntax.ValidIdentifier(key) {
+		return hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(key)}}
+	}
+	return hclwrite.TokensForValue(cty.StringVal(key))
+}
+
+// objectKeys returns the literal keys of an object constructor such as
+// { Name = "a", "cost center" = "42" }.
+func objectKeys(tokens hclwrite.Tokens) map[string]bool {
+	keys := map[string]bool{}
+	expr, diags := hclsyntax.ParseExpression(tokens.Bytes(), "", hcl.InitialPos)
+	object, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if diags.HasErrors() || !ok {
+		return keys
+	}
+	for _, item := range object.Items {
+		key, ok := item.KeyExpr.(*hclsyntax.ObjectConsKeyExpr)
+		if !ok {
+			continue
+		}
+		if name := hcl.ExprAsKeyword(key.Wrapped); name != "" {
+			keys[name] = true
+			continue
+		}
+		if value, diags := key.Wrapped.Value(nil); !diags.HasErrors() && value.Type() == cty.String && value.IsKnown() && !value.IsNull() {
+			keys[value.AsString()] = true
+		}
+	}
+	return keys
+}
+
+// isObjectLiteral reports whether tokens are a single { ... } expression.
+func isObjectLiteral(tokens hclwrite.Tokens) bool {
+	if len(tokens) < 2 || tokens[0].Type != hclsyntax.TokenOBrace || tokens[len(tokens)-1].Type != hclsyntax.TokenCBrace {
+		return false
+	}
+	depth := 0
+	for i, token := range tokens {
+		switch token.Type {
+		case hclsyntax.TokenOBrace:
+			depth++
+		case hclsyntax.TokenCBrace:
+			depth--
+			if depth == 0 && i != len(tokens)-1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ctyValue converts a JSON-decoded fix value.
+func ctyValue(value interface{}) (cty.Value, error) {
+	switch v := value.(type) {
+	case string:
+		return cty.StringVal(v), nil
+	case bool:
+		return cty.BoolVal(v), nil
+	case int:
+		return cty.NumberIntVal(int64(v)), nil
+	case float64:
+		if v == math.Trunc(v) {
+			return cty.NumberIntVal(int64(v)), nil
+		}
+		return cty.NumberFloatVal(v), nil
+	case map[string]interface{}:
+		attrs := make(map[string]cty.Value, len(v))
+		for key, item := range v {
+			converted, err := ctyValue(item)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			attrs[key] = converted
+		}
+		return cty.ObjectVal(attrs), nil
+	}
+	return cty.NilVal, fmt.Errorf("unsupported fix value %T", value)
+}
+
+func sortedKeys(object cty.Value) []string {
+	var keys []string
+	for key := range object.Type().AttributeTypes() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// resourceLabels returns the type and name of a resource address such as
+// module.s3.aws_s3_bucket.artifacts[0].
+func resourceLabels(address string) (string, string) {
+	config := plancheck.ConfigAddress(address)
+	if module := plancheck.ConfigAddress(plancheck.ModulePath(address)); module != "" {
+		config = config[len(module)+1:]
+	}
+	resourceType, name, _ := strings.Cut(config, ".")
+	return resourceType, name
+}
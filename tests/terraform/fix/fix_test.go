@@ -0,0 +1,136 @@
+package fix
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+const mainTF = `resource "aws_cloudwatch_log_group" "api" {
+  name = "/ecs/api"
+}
+
+resource "aws_s3_bucket" "artifacts" {
+  bucket = "pkg-artifacts"
+  tags = {
+    Name = "artifacts"
+  }
+}
+
+resource "aws_s3_bucket" "shared" {
+  bucket = "pkg-shared"
+  tags   = var.tags
+}
+
+resource "aws_instance" "bastion" {
+  ami = "ami-123"
+}
+`
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		fix     plancheck.Fix
+		want    string
+	}{
+		{
+			name:    "sets a missing attribute",
+			address: "aws_cloudwatch_log_group.api",
+			fix:     plancheck.Fix{Attribute: "retention_in_days", Value: float64(30)},
+			want:    "  name              = \"/ecs/api\"\n  retention_in_days = 30\n",
+		},
+		{
+			name:    "merges into an object literal",
+			address: "aws_s3_bucket.artifacts[0]",
+			fix:     plancheck.Fix{Attribute: "tags", Value: map[string]interface{}{"Project": "cs450", "cost center": "42"}, Merge: true},
+			want:    "    Name          = \"artifacts\"\n    Project       = \"cs450\"\n    \"cost center\" = \"42\"\n",
+		},
+		{
+			name:    "wraps other expressions in merge",
+			address: "aws_s3_bucket.shared",
+			fix:     plancheck.Fix{Attribute: "tags", Value: map[string]interface{}{"Project": "cs450"}, Merge: true},
+			want:    "tags = merge(var.tags, {\n    Project = \"cs450\"\n  })",
+		},
+		{
+			name:    "creates a nested block",
+			address: "aws_instance.bastion",
+			fix:     plancheck.Fix{Block: "metadata_options", Attribute: "http_tokens", Value: "required"},
+			want:    "  metadata_options {\n    http_tokens = \"required\"\n  }\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Apply([]byte(mainTF), "main.tf", tt.address, tt.fix)
+			require.NoError(t, err)
+			require.Contains(t, string(out), tt.want)
+		})
+	}
+}
+
+func TestApplyMergesEachKeyOnce(t *testing.T) {
+	fix := plancheck.Fix{Attribute: "tags", Value: map[string]interface{}{"Name": "other", "Project": "cs450"}, Merge: true}
+
+	once, err := Apply([]byte(mainTF), "main.tf", "aws_s3_bucket.artifacts[0]", fix)
+	require.NoError(t, err)
+	twice, err := Apply(once, "main.tf", "aws_s3_bucket.artifacts[1]", fix)
+	require.NoError(t, err)
+
+	require.Equal(t, string(once), string(twice), "a second instance of the resource adds nothing")
+	require.Equal(t, 1, strings.Count(string(twice), "Project"))
+	require.Contains(t, string(twice), "Name    = \"artifacts\"", "existing keys keep their value")
+}
+
+func TestApplyFormatsOnlyTheEditedBlock(t *testing.T) {
+	src := "locals {\n  a = 1\n  bb = 2\n}\n\n" + mainTF
+	out, err := Apply([]byte(src), "main.tf", "aws_cloudwatch_log_group.api", plancheck.Fix{Attribute: "retention_in_days", Value: float64(30)})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(out), "locals {\n  a = 1\n  bb = 2\n}\n"), "unrelated blocks are not reformatted:\n%s", out)
+	require.Contains(t, string(out), "  retention_in_days = 30\n")
+}
+
+func TestSuggestAttachesPatches(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "main.tf")
+	require.NoError(t, os.WriteFile(filename, []byte(mainTF), 0o644))
+
+	findings := []plancheck.Finding{
+		plancheck.NewFinding("logs.retention", "aws_cloudwatch_log_group.api", "never expires").
+			WithFix(plancheck.Fix{Attribute: "retention_in_days", Value: float64(30)}),
+		plancheck.NewFinding("logs.retention", "aws_cloudwatch_log_group.missing", "never expires").
+			WithFix(plancheck.Fix{Attribute: "retention_in_days", Value: float64(30)}),
+	}
+	Suggest(plancheck.NewSourceIndex(nil, dir), findings)
+
+	require.True(t, strings.HasPrefix(findings[0].Patch, "--- "+filename+"\n+++ "+filename+"\n@@ "), findings[0].Patch)
+	require.Contains(t, findings[0].Patch, "\n-  name = \"/ecs/api\"\n+  name              = \"/ecs/api\"\n+  retention_in_days = 30\n")
+	require.Empty(t, findings[1].Patch, "resources without configuration get no patch")
+}
+
+func TestWriteAppliesFixesInPlace(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "main.tf")
+	require.NoError(t, os.WriteFile(filename, []byte(mainTF), 0o644))
+	index := plancheck.NewSourceIndex(nil, dir)
+
+	for _, finding := range []plancheck.Finding{
+		plancheck.NewFinding("logs.retention", "aws_cloudwatch_log_group.api", "never expires").
+			WithFix(plancheck.Fix{Attribute: "retention_in_days", Value: float64(30)}),
+		plancheck.NewFinding("ec2.imdsv2", "aws_instance.bastion", "allows IMDSv1").
+			WithFix(plancheck.Fix{Block: "metadata_options", Attribute: "http_tokens", Value: "required"}),
+	} {
+		written, err := Write(index, finding)
+		require.NoError(t, err)
+		require.Equal(t, filename, written)
+	}
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "retention_in_days = 30")
+	require.Contains(t, string(data), "http_tokens = \"required\"")
+}
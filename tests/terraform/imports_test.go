@@ -0,0 +1,63 @@
+package terraformtests
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/livestate"
+	"cs450/terraformtests/plancheck"
+)
+
+// An import block puts a resource under management on the next apply, with
+// whatever configuration it was given, often one generated by terraform plan
+// -generate-config-out. Every rule judges the imported resources before
+// they land in state. With COMPLIANCE_POST_APPLY set, the objects the blocks
+// name are also looked up, so a wrong ID fails here rather than halfway
+// through an apply.
+func TestImportedResourcesPassEveryRule(t *testing.T) {
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		options, plan := environmentPlan(t, env.Name)
+		module, err := plancheck.LoadModule(options.TerraformDir)
+		require.NoError(t, err, "module source must parse")
+		imports := module.Imports()
+		if len(imports) == 0 {
+			t.Skipf("%s has no import blocks", env.Name)
+		}
+
+		imported := map[string]bool{}
+		for _, block := range imports {
+			imported[block.To] = true
+		}
+		requireRules(t)
+		var ids []string
+		for _, rule := range plancheck.Rules() {
+			ids = append(ids, rule.ID)
+		}
+		var findings []plancheck.Finding
+		for _, finding := range evaluateRules(t, plan, options, env.Name, ids...) {
+			if imported[finding.Address] {
+				findings = append(findings, finding)
+			}
+		}
+
+		if os.Getenv(postApplyEnv) != "" {
+			creds, err := roleCredentials(env.Name)
+			require.NoError(t, err)
+			clients, err := livestate.NewClients(env.Region, creds.AWS())
+			require.NoError(t, err)
+			readAt := time.Now()
+			targets, err := clients.ImportTargets(imports)
+			require.NoError(t, err)
+			findings = append(findings, plancheck.Evaluate(&plancheck.Input{
+				Plan:        plan,
+				Environment: env.Name,
+				Module:      module,
+				Runtime:     &plancheck.Runtime{Imports: targets, ReadAt: readAt},
+			}, requireRules(t, "import.target-exists")...)...)
+		}
+		requireNoFindings(t, plan, findings)
+	})
+}
@@ -0,0 +1,64 @@
+package livestate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/accessanalyzer"
+	"github.com/aws/aws-sdk-go-v2/service/accessanalyzer/types"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// AccessFindings returns the active findings of every active IAM Access
+// Analyzer in the clients' region. It fails when there is no active
+// analyzer, since no findings would then prove nothing.
+func (c *Clients) AccessFindings() ([]plancheck.AccessFinding, error) {
+	ctx := context.Background()
+	var analyzers []string
+	pages := accessanalyzer.NewListAnalyzersPaginator(c.AccessAnalyzer, &accessanalyzer.ListAnalyzersInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("livestate: listing access analyzers: %w", err)
+		}
+		for _, analyzer := range page.Analyzers {
+			if analyzer.Status == types.AnalyzerStatusActive {
+				analyzers = append(analyzers, aws.ToString(analyzer.Arn))
+			}
+		}
+	}
+	if len(analyzers) == 0 {
+		return nil, fmt.Errorf("livestate: no active IAM Access Analyzer in the region")
+	}
+
+	var findings []plancheck.AccessFinding
+	for _, analyzer := range analyzers {
+		pages := accessanalyzer.NewListFindingsPaginator(c.AccessAnalyzer, &accessanalyzer.ListFindingsInput{
+			AnalyzerArn: aws.String(analyzer),
+			Filter: map[string]types.Criterion{
+				"status": {Eq: []string{string(types.FindingStatusActive)}},
+			},
+		})
+		for pages.HasMorePages() {
+			page, err := pages.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("livestate: listing findings of %s: %w", analyzer, err)
+			}
+			for _, finding := range page.Findings {
+				findings = append(findings, plancheck.AccessFinding{
+					ID:           aws.ToString(finding.Id),
+					Resource:     aws.ToString(finding.Resource),
+					ResourceType: string(finding.ResourceType),
+					Status:       string(finding.Status),
+					IsPublic:     aws.ToBool(finding.IsPublic),
+					Principal:    finding.Principal,
+					Action:       finding.Action,
+					Condition:    finding.Condition,
+				})
+			}
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,58 @@
+package livestate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/accessanalyzer"
+	"github.com/aws/aws-sdk-go-v2/service/accessanalyzer/types"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+type fakeAccessAnalyzer struct {
+	AccessAnalyzerAPI
+	analyzers []types.AnalyzerSummary
+	queried   []string
+}
+
+func (f *fakeAccessAnalyzer) ListAnalyzers(context.Context, *accessanalyzer.ListAnalyzersInput, ...func(*accessanalyzer.Options)) (*accessanalyzer.ListAnalyzersOutput, error) {
+	return &accessanalyzer.ListAnalyzersOutput{Analyzers: f.analyzers}, nil
+}
+
+func (f *fakeAccessAnalyzer) ListFindings(_ context.Context, in *accessanalyzer.ListFindingsInput, _ ...func(*accessanalyzer.Options)) (*accessanalyzer.ListFindingsOutput, error) {
+	f.queried = append(f.queried, aws.ToString(in.AnalyzerArn))
+	return &accessanalyzer.ListFindingsOutput{Findings: []types.FindingSummary{{
+		Id:           aws.String("5f1c"),
+		Resource:     aws.String("arn:aws:s3:::pkg-artifacts"),
+		ResourceType: types.ResourceTypeAwsS3Bucket,
+		Status:       types.FindingStatusActive,
+		IsPublic:     aws.Bool(true),
+		Principal:    map[string]string{"AWS": "*"},
+		Action:       []string{"s3:GetObject"},
+	}}}, nil
+}
+
+func TestAccessFindingsReadsActiveAnalyzers(t *testing.T) {
+	analyzer := &fakeAccessAnalyzer{analyzers: []types.AnalyzerSummary{
+		{Arn: aws.String("arn:aws:access-analyzer:us-east-1:1:analyzer/account"), Status: types.AnalyzerStatusActive},
+		{Arn: aws.String("arn:aws:access-analyzer:us-east-1:1:analyzer/old"), Status: types.AnalyzerStatusDisabled},
+	}}
+	findings, err := (&Clients{AccessAnalyzer: analyzer}).AccessFindings()
+	require.NoError(t, err)
+	require.Equal(t, []string{"arn:aws:access-analyzer:us-east-1:1:analyzer/account"}, analyzer.queried)
+	require.Equal(t, []plancheck.AccessFinding{{
+		ID:           "5f1c",
+		Resource:     "arn:aws:s3:::pkg-artifacts",
+		ResourceType: "AWS::S3::Bucket",
+		Status:       "ACTIVE",
+		IsPublic:     true,
+		Principal:    map[string]string{"AWS": "*"},
+		Action:       []string{"s3:GetObject"},
+	}}, findings)
+
+	_, err = (&Clients{AccessAnalyzer: &fakeAccessAnalyzer{}}).AccessFindings()
+	require.ErrorContains(t, err, "no active IAM Access Analyzer")
+}
@@ -0,0 +1,126 @@
+package livestate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// AccountBaseline reads the account-wide settings of accountID in the
+// clients' region, which terraform may not manage, as planned values:
+// aws_s3_account_public_access_block.account,
+// aws_ebs_encryption_by_default.account,
+// aws_iam_account_password_policy.account (with no attributes when the
+// account has no password policy) and one aws_default_security_group per VPC,
+// keyed by VPC ID.
+func (c *Clients) AccountBaseline(accountID string) (*tfjson.Plan, error) {
+	ctx := context.Background()
+	var resources []*tfjson.StateResource
+
+	block := map[string]interface{}{
+		"account_id":              accountID,
+		"block_public_acls":       false,
+		"block_public_policy":     false,
+		"ignore_public_acls":      false,
+		"restrict_public_buckets": false,
+	}
+	access, err := c.S3Control.GetPublicAccessBlock(ctx, &s3control.GetPublicAccessBlockInput{AccountId: aws.String(accountID)})
+	if err != nil && !isCode(err, "NoSuchPublicAccessBlockConfiguration") {
+		return nil, fmt.Errorf("livestate: reading the S3 public access block of %s: %w", accountID, err)
+	}
+	if err == nil && access.PublicAccessBlockConfiguration != nil {
+		config := access.PublicAccessBlockConfiguration
+		block["block_public_acls"] = aws.ToBool(config.BlockPublicAcls)
+		block["block_public_policy"] = aws.ToBool(config.BlockPublicPolicy)
+		block["ignore_public_acls"] = aws.ToBool(config.IgnorePublicAcls)
+		block["restrict_public_buckets"] = aws.ToBool(config.RestrictPublicBuckets)
+	}
+	resources = append(resources, resource("aws_s3_account_public_access_block", "account", block))
+
+	ebs, err := c.EC2.GetEbsEncryptionByDefault(ctx, &ec2.GetEbsEncryptionByDefaultInput{})
+	if err != nil {
+		return nil, fmt.Errorf("livestate: reading EBS encryption by default: %w", err)
+	}
+	resources = append(resources, resource("aws_ebs_encryption_by_default", "account", map[string]interface{}{
+		"enabled": aws.ToBool(ebs.EbsEncryptionByDefault),
+	}))
+
+	passwords := map[string]interface{}{}
+	policy, err := c.IAM.GetAccountPasswordPolicy(ctx, &iam.GetAccountPasswordPolicyInput{})
+	if err != nil && !isCode(err, "NoSuchEntity") {
+		return nil, fmt.Errorf("livestate: reading the IAM password policy: %w", err)
+	}
+	if err == nil && policy.PasswordPolicy != nil {
+		p := policy.PasswordPolicy
+		passwords = map[string]interface{}{
+			"minimum_password_length":        float64(aws.ToInt32(p.MinimumPasswordLength)),
+			"password_reuse_prevention":      float64(aws.ToInt32(p.PasswordReusePrevention)),
+			"require_lowercase_characters":   p.RequireLowercaseCharacters,
+			"require_uppercase_characters":   p.RequireUppercaseCharacters,
+			"require_numbers":                p.RequireNumbers,
+			"require_symbols":                p.RequireSymbols,
+			"allow_users_to_change_password": p.AllowUsersToChangePassword,
+		}
+	}
+	resources = append(resources, resource("aws_iam_account_password_policy", "account", passwords))
+
+	groups := ec2.NewDescribeSecurityGroupsPaginator(c.EC2, &ec2.DescribeSecurityGroupsInput{
+		Filters: []ec2types.Filter{{Name: aws.String("group-name"), Values: []string{"default"}}},
+	})
+	for groups.HasMorePages() {
+		page, err := groups.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("livestate: listing default security groups: %w", err)
+		}
+		for _, group := range page.SecurityGroups {
+			vpc := aws.ToString(group.VpcId)
+			sg := resource("aws_default_security_group", "default", map[string]interface{}{
+				"id":      aws.ToString(group.GroupId),
+				"vpc_id":  vpc,
+				"ingress": permissions(group.IpPermissions),
+				"egress":  permissions(group.IpPermissionsEgress),
+			})
+			sg.Address = fmt.Sprintf("aws_default_security_group.default[%q]", vpc)
+			sg.Index = vpc
+			resources = append(resources, sg)
+		}
+	}
+
+	return &tfjson.Plan{
+		FormatVersion: "1.0",
+		PlannedValues: &tfjson.StateValues{RootModule: &tfjson.StateModule{Resources: resources}},
+	}, nil
+}
+
+// permissions converts security group rules to the provider's ingress and
+// egress blocks.
+func permissions(rules []ec2types.IpPermission) []interface{} {
+	blocks := []interface{}{}
+	for _, rule := range rules {
+		var cidrs, ipv6, groups []interface{}
+		for _, r := range rule.IpRanges {
+			cidrs = append(cidrs, aws.ToString(r.CidrIp))
+		}
+		for _, r := range rule.Ipv6Ranges {
+			ipv6 = append(ipv6, aws.ToString(r.CidrIpv6))
+		}
+		for _, pair := range rule.UserIdGroupPairs {
+			groups = append(groups, aws.ToString(pair.GroupId))
+		}
+		blocks = append(blocks, map[string]interface{}{
+			"protocol":         aws.ToString(rule.IpProtocol),
+			"from_port":        float64(aws.ToInt32(rule.FromPort)),
+			"to_port":          float64(aws.ToInt32(rule.ToPort)),
+			"cidr_blocks":      cidrs,
+			"ipv6_cidr_blocks": ipv6,
+			"security_groups":  groups,
+		})
+	}
+	return blocks
+}
@@ -0,0 +1,75 @@
+package livestate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+type fakeS3Control struct {
+	S3ControlAPI
+}
+
+func (f *fakeS3Control) GetPublicAccessBlock(context.Context, *s3control.GetPublicAccessBlockInput, ...func(*s3control.Options)) (*s3control.GetPublicAccessBlockOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "NoSuchPublicAccessBlockConfiguration", Message: "not configured"}
+}
+
+type fakeEC2 struct {
+	EC2API
+}
+
+func (f *fakeEC2) GetEbsEncryptionByDefault(context.Context, *ec2.GetEbsEncryptionByDefaultInput, ...func(*ec2.Options)) (*ec2.GetEbsEncryptionByDefaultOutput, error) {
+	return &ec2.GetEbsEncryptionByDefaultOutput{EbsEncryptionByDefault: aws.Bool(true)}, nil
+}
+
+func (f *fakeEC2) DescribeSecurityGroups(context.Context, *ec2.DescribeSecurityGroupsInput, ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: []ec2types.SecurityGroup{{
+		GroupId: aws.String("sg-1"),
+		VpcId:   aws.String("vpc-1"),
+		IpPermissionsEgress: []ec2types.IpPermission{{
+			IpProtocol: aws.String("-1"),
+			IpRanges:   []ec2types.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+		}},
+	}}}, nil
+}
+
+type fakeIAM struct {
+	IAMAPI
+}
+
+func (f *fakeIAM) GetAccountPasswordPolicy(context.Context, *iam.GetAccountPasswordPolicyInput, ...func(*iam.Options)) (*iam.GetAccountPasswordPolicyOutput, error) {
+	return &iam.GetAccountPasswordPolicyOutput{PasswordPolicy: &iamtypes.PasswordPolicy{
+		MinimumPasswordLength: aws.Int32(8),
+		RequireSymbols:        true,
+	}}, nil
+}
+
+func TestAccountBaselineReadsAccountSettings(t *testing.T) {
+	clients := &Clients{S3Control: &fakeS3Control{}, EC2: &fakeEC2{}, IAM: &fakeIAM{}}
+	plan, err := clients.AccountBaseline("123456789012")
+	require.NoError(t, err)
+
+	values := map[string]map[string]interface{}{}
+	for _, resource := range plancheck.PlannedResources(plan) {
+		values[resource.Address] = resource.AttributeValues
+	}
+	require.Len(t, values, 4)
+	require.False(t, plancheck.LookupBool(values["aws_s3_account_public_access_block.account"], "block_public_acls"),
+		"an account without a public access block blocks nothing")
+	require.True(t, plancheck.LookupBool(values["aws_ebs_encryption_by_default.account"], "enabled"))
+	length, _ := plancheck.LookupNumber(values["aws_iam_account_password_policy.account"], "minimum_password_length")
+	require.Equal(t, 8.0, length)
+	group := values[`aws_default_security_group.default["vpc-1"]`]
+	require.Empty(t, plancheck.Blocks(group, "ingress"))
+	require.Equal(t, []interface{}{"0.0.0.0/0"}, plancheck.Blocks(group, "egress")[0]["cidr_blocks"])
+}
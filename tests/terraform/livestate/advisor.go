@@ -0,0 +1,81 @@
+package livestate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// maxReportPolls bounds how many times ServiceAccess checks on a report
+// before giving up on it.
+const maxReportPolls = 120
+
+// ServiceAccess returns the IAM Access Advisor report of each role ARN: when
+// the role last used each service its policies grant. IAM builds the reports
+// asynchronously, so this waits for each in turn.
+func (c *Clients) ServiceAccess(roleARNs []string) (map[string][]plancheck.ServiceAccess, error) {
+	ctx := context.Background()
+	reports := map[string][]plancheck.ServiceAccess{}
+	for _, arn := range roleARNs {
+		job, err := c.IAM.GenerateServiceLastAccessedDetails(ctx, &iam.GenerateServiceLastAccessedDetailsInput{
+			Arn: aws.String(arn),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("livestate: requesting access advisor report for %s: %w", arn, err)
+		}
+		access, err := c.serviceAccessReport(ctx, arn, aws.ToString(job.JobId))
+		if err != nil {
+			return nil, err
+		}
+		reports[arn] = access
+	}
+	return reports, nil
+}
+
+func (c *Clients) serviceAccessReport(ctx context.Context, arn, jobID string) ([]plancheck.ServiceAccess, error) {
+	interval := c.PollInterval
+	if interval == 0 {
+		interval = time.Second
+	}
+
+	var access []plancheck.ServiceAccess
+	input := &iam.GetServiceLastAccessedDetailsInput{JobId: aws.String(jobID)}
+	for polls := 0; ; {
+		out, err := c.IAM.GetServiceLastAccessedDetails(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("livestate: reading access advisor report for %s: %w", arn, err)
+		}
+		switch out.JobStatus {
+		case types.JobStatusTypeInProgress:
+			if polls++; polls == maxReportPolls {
+				return nil, fmt.Errorf("livestate: access advisor report for %s not ready after %d checks", arn, polls)
+			}
+			time.Sleep(interval)
+			continue
+		case types.JobStatusTypeFailed:
+			reason := "no reason given"
+			if out.Error != nil {
+				reason = aws.ToString(out.Error.Message)
+			}
+			return nil, fmt.Errorf("livestate: access advisor report for %s failed: %s", arn, reason)
+		}
+
+		for _, service := range out.ServicesLastAccessed {
+			access = append(access, plancheck.ServiceAccess{
+				Namespace:    aws.ToString(service.ServiceNamespace),
+				Name:         aws.ToString(service.ServiceName),
+				LastAccessed: service.LastAuthenticated,
+			})
+		}
+		if !out.IsTruncated {
+			return access, nil
+		}
+		input.Marker = out.Marker
+	}
+}
@@ -0,0 +1,59 @@
+package livestate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+type fakeAccessAdvisor struct {
+	IAMAPI
+	pending int
+	pages   [][]types.ServiceLastAccessed
+}
+
+func (f *fakeAccessAdvisor) GenerateServiceLastAccessedDetails(_ context.Context, in *iam.GenerateServiceLastAccessedDetailsInput, _ ...func(*iam.Options)) (*iam.GenerateServiceLastAccessedDetailsOutput, error) {
+	return &iam.GenerateServiceLastAccessedDetailsOutput{JobId: aws.String("job-" + aws.ToString(in.Arn))}, nil
+}
+
+func (f *fakeAccessAdvisor) GetServiceLastAccessedDetails(_ context.Context, in *iam.GetServiceLastAccessedDetailsInput, _ ...func(*iam.Options)) (*iam.GetServiceLastAccessedDetailsOutput, error) {
+	if f.pending > 0 {
+		f.pending--
+		return &iam.GetServiceLastAccessedDetailsOutput{JobStatus: types.JobStatusTypeInProgress}, nil
+	}
+	page := 0
+	if in.Marker != nil {
+		page = 1
+	}
+	return &iam.GetServiceLastAccessedDetailsOutput{
+		JobStatus:            types.JobStatusTypeCompleted,
+		ServicesLastAccessed: f.pages[page],
+		IsTruncated:          page+1 < len(f.pages),
+		Marker:               aws.String("next"),
+	}, nil
+}
+
+func TestServiceAccessWaitsForReports(t *testing.T) {
+	used := time.Date(2026, 5, 20, 14, 0, 0, 0, time.UTC)
+	advisor := &fakeAccessAdvisor{pending: 2, pages: [][]types.ServiceLastAccessed{
+		{{ServiceNamespace: aws.String("s3"), ServiceName: aws.String("Amazon S3"), LastAuthenticated: &used}},
+		{{ServiceNamespace: aws.String("sqs"), ServiceName: aws.String("Amazon SQS")}},
+	}}
+	clients := &Clients{IAM: advisor, PollInterval: time.Millisecond}
+
+	reports, err := clients.ServiceAccess([]string{"arn:aws:iam::123456789012:role/pkg-pipeline"})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]plancheck.ServiceAccess{
+		"arn:aws:iam::123456789012:role/pkg-pipeline": {
+			{Namespace: "s3", Name: "Amazon S3", LastAccessed: &used},
+			{Namespace: "sqs", Name: "Amazon SQS"},
+		},
+	}, reports)
+}
@@ -0,0 +1,71 @@
+package livestate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// Certificates returns the validity of each certificate ARN: ACM
+// certificates, which must be in the clients' region, and IAM server
+// certificates. An ARN of neither kind is an error.
+func (c *Clients) Certificates(arns []string) (map[string]plancheck.Certificate, error) {
+	ctx := context.Background()
+	certs := map[string]plancheck.Certificate{}
+	var server []string
+	for _, arn := range arns {
+		switch {
+		case strings.Contains(arn, ":server-certificate/"):
+			server = append(server, arn)
+		case strings.HasPrefix(arn, "arn:") && strings.Contains(arn, ":acm:"):
+			out, err := c.ACM.DescribeCertificate(ctx, &acm.DescribeCertificateInput{CertificateArn: aws.String(arn)})
+			if err != nil {
+				return nil, fmt.Errorf("livestate: describing certificate %s: %w", arn, err)
+			}
+			cert := out.Certificate
+			certs[arn] = plancheck.Certificate{
+				Domain:   aws.ToString(cert.DomainName),
+				Type:     string(cert.Type),
+				Status:   string(cert.Status),
+				NotAfter: aws.ToTime(cert.NotAfter),
+			}
+		default:
+			return nil, fmt.Errorf("livestate: %s is not an ACM or IAM server certificate ARN", arn)
+		}
+	}
+	if len(server) == 0 {
+		return certs, nil
+	}
+
+	pages := iam.NewListServerCertificatesPaginator(c.IAM, &iam.ListServerCertificatesInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("livestate: listing server certificates: %w", err)
+		}
+		for _, meta := range page.ServerCertificateMetadataList {
+			arn := aws.ToString(meta.Arn)
+			for _, wanted := range server {
+				if arn == wanted {
+					certs[arn] = plancheck.Certificate{
+						Domain:   aws.ToString(meta.ServerCertificateName),
+						Type:     "IAM",
+						NotAfter: aws.ToTime(meta.Expiration),
+					}
+				}
+			}
+		}
+	}
+	for _, arn := range server {
+		if _, ok := certs[arn]; !ok {
+			return nil, fmt.Errorf("livestate: server certificate %s does not exist", arn)
+		}
+	}
+	return certs, nil
+}
@@ -0,0 +1,64 @@
+package livestate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+type fakeACM struct {
+	ACMAPI
+	notAfter time.Time
+}
+
+func (f *fakeACM) DescribeCertificate(_ context.Context, in *acm.DescribeCertificateInput, _ ...func(*acm.Options)) (*acm.DescribeCertificateOutput, error) {
+	return &acm.DescribeCertificateOutput{Certificate: &acmtypes.CertificateDetail{
+		CertificateArn: in.CertificateArn,
+		DomainName:     aws.String("api.pkg.example.com"),
+		Type:           acmtypes.CertificateTypeImported,
+		Status:         acmtypes.CertificateStatusIssued,
+		NotAfter:       aws.Time(f.notAfter),
+	}}, nil
+}
+
+type fakeServerCertificates struct {
+	IAMAPI
+	certificates []iamtypes.ServerCertificateMetadata
+}
+
+func (f *fakeServerCertificates) ListServerCertificates(context.Context, *iam.ListServerCertificatesInput, ...func(*iam.Options)) (*iam.ListServerCertificatesOutput, error) {
+	return &iam.ListServerCertificatesOutput{ServerCertificateMetadataList: f.certificates}, nil
+}
+
+func TestCertificatesReadsACMAndIAM(t *testing.T) {
+	acmExpiry := time.Date(2026, 6, 25, 12, 0, 0, 0, time.UTC)
+	iamExpiry := time.Date(2026, 5, 15, 0, 0, 0, 0, time.UTC)
+	acmARN := "arn:aws:acm:us-east-1:123456789012:certificate/22222222-partner"
+	iamARN := "arn:aws:iam::123456789012:server-certificate/legacy-2025"
+	clients := &Clients{
+		ACM: &fakeACM{notAfter: acmExpiry},
+		IAM: &fakeServerCertificates{certificates: []iamtypes.ServerCertificateMetadata{
+			{Arn: aws.String(iamARN), ServerCertificateName: aws.String("legacy-2025"), Expiration: aws.Time(iamExpiry)},
+			{Arn: aws.String("arn:aws:iam::123456789012:server-certificate/other"), Expiration: aws.Time(iamExpiry)},
+		}},
+	}
+
+	certs, err := clients.Certificates([]string{acmARN, iamARN})
+	require.NoError(t, err)
+	require.Equal(t, map[string]plancheck.Certificate{
+		acmARN: {Domain: "api.pkg.example.com", Type: "IMPORTED", Status: "ISSUED", NotAfter: acmExpiry},
+		iamARN: {Domain: "legacy-2025", Type: "IAM", NotAfter: iamExpiry},
+	}, certs)
+
+	_, err = clients.Certificates([]string{"arn:aws:iam::123456789012:server-certificate/deleted"})
+	require.ErrorContains(t, err, "does not exist")
+}
@@ -0,0 +1,69 @@
+package livestate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// importLookups look up the object an import ID names, by resource type,
+// and return the error code AWS answers with when there is none.
+var importLookups = map[string]func(ctx context.Context, c *Clients, id string) (notFound string, err error){
+	"aws_s3_bucket": func(ctx context.Context, c *Clients, id string) (string, error) {
+		_, err := c.S3.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(id)})
+		return "NotFound", err
+	},
+	"aws_dynamodb_table": func(ctx context.Context, c *Clients, id string) (string, error) {
+		_, err := c.DynamoDB.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(id)})
+		return "ResourceNotFoundException", err
+	},
+	"aws_iam_role": func(ctx context.Context, c *Clients, id string) (string, error) {
+		_, err := c.IAM.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(id)})
+		return "NoSuchEntity", err
+	},
+	"aws_iam_policy": func(ctx context.Context, c *Clients, id string) (string, error) {
+		_, err := c.IAM.GetPolicy(ctx, &iam.GetPolicyInput{PolicyArn: aws.String(id)})
+		return "NoSuchEntity", err
+	},
+	"aws_security_group": func(ctx context.Context, c *Clients, id string) (string, error) {
+		_, err := c.EC2.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{GroupIds: []string{id}})
+		return "InvalidGroup.NotFound", err
+	},
+	"aws_secretsmanager_secret": func(ctx context.Context, c *Clients, id string) (string, error) {
+		_, err := c.SecretsManager.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{SecretId: aws.String(id)})
+		return "ResourceNotFoundException", err
+	},
+	"aws_acm_certificate": func(ctx context.Context, c *Clients, id string) (string, error) {
+		_, err := c.ACM.DescribeCertificate(ctx, &acm.DescribeCertificateInput{CertificateArn: aws.String(id)})
+		return "ResourceNotFoundException", err
+	},
+}
+
+// ImportTargets looks up the object each import block names, by address.
+// Blocks without a literal ID, or importing a resource type not listed in
+// importLookups, are left out.
+func (c *Clients) ImportTargets(imports []plancheck.ImportBlock) (map[string]plancheck.ImportTarget, error) {
+	ctx := context.Background()
+	targets := map[string]plancheck.ImportTarget{}
+	for _, imported := range imports {
+		lookup, ok := importLookups[imported.ResourceType()]
+		if !ok || imported.ID == "" {
+			continue
+		}
+		notFound, err := lookup(ctx, c, imported.ID)
+		if err != nil && !isCode(err, notFound) {
+			return nil, fmt.Errorf("livestate: looking up %s %s: %w", imported.ResourceType(), imported.ID, err)
+		}
+		targets[imported.To] = plancheck.ImportTarget{ID: imported.ID, Exists: err == nil}
+	}
+	return targets, nil
+}
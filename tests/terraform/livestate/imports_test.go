@@ -0,0 +1,59 @@
+package livestate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+type fakeImportS3 struct {
+	S3API
+	buckets map[string]bool
+}
+
+func (f *fakeImportS3) HeadBucket(_ context.Context, in *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if !f.buckets[*in.Bucket] {
+		return nil, &smithy.GenericAPIError{Code: "NotFound", Message: "Not Found"}
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+type fakeImportIAM struct {
+	IAMAPI
+	err error
+}
+
+func (f *fakeImportIAM) GetRole(context.Context, *iam.GetRoleInput, ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	return &iam.GetRoleOutput{}, f.err
+}
+
+func TestImportTargetsLooksUpEachImportedObject(t *testing.T) {
+	clients := &Clients{
+		S3:  &fakeImportS3{buckets: map[string]bool{"pkg-artifacts": true}},
+		IAM: &fakeImportIAM{},
+	}
+
+	targets, err := clients.ImportTargets([]plancheck.ImportBlock{
+		{To: "module.s3.aws_s3_bucket.artifacts", ID: "pkg-artifacts"},
+		{To: `aws_s3_bucket.logs["access"]`, ID: "pkg-access-logs"},
+		{To: "aws_iam_role.api", ID: "pkg-api"},
+		{To: "aws_iam_role.worker"},
+		{To: "aws_cloudfront_distribution.cdn", ID: "E2QWRUHAPOMQZL"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]plancheck.ImportTarget{
+		"module.s3.aws_s3_bucket.artifacts": {ID: "pkg-artifacts", Exists: true},
+		`aws_s3_bucket.logs["access"]`:      {ID: "pkg-access-logs", Exists: false},
+		"aws_iam_role.api":                  {ID: "pkg-api", Exists: true},
+	}, targets)
+
+	clients.IAM = &fakeImportIAM{err: &smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized to perform iam:GetRole"}}
+	_, err = clients.ImportTargets([]plancheck.ImportBlock{{To: "aws_iam_role.api", ID: "pkg-api"}})
+	require.ErrorContains(t, err, "looking up aws_iam_role pkg-api")
+}
@@ -0,0 +1,324 @@
+// Package livestate reads deployed AWS resources that terraform does not
+// manage in the planned configuration, such as the state backend and the
+// account-wide settings, into plan form so the plan-based rules can judge
+// them.
+package livestate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/accessanalyzer"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/smithy-go"
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/plancheck"
+)
+
+// The interfaces below are the operations of each AWS API the package calls,
+// so tests can stand in for them.
+
+// S3API reads state buckets and looks up imported ones.
+type S3API interface {
+	GetBucketVersioning(context.Context, *s3.GetBucketVersioningInput, ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error)
+	GetBucketEncryption(context.Context, *s3.GetBucketEncryptionInput, ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error)
+	GetBucketPolicy(context.Context, *s3.GetBucketPolicyInput, ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error)
+	HeadBucket(context.Context, *s3.HeadBucketInput, ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+}
+
+// S3ControlAPI reads the account's S3 public access block.
+type S3ControlAPI interface {
+	GetPublicAccessBlock(context.Context, *s3control.GetPublicAccessBlockInput, ...func(*s3control.Options)) (*s3control.GetPublicAccessBlockOutput, error)
+}
+
+// DynamoDBAPI reads lock tables and looks up imported ones.
+type DynamoDBAPI interface {
+	DescribeTable(context.Context, *dynamodb.DescribeTableInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+	DescribeContinuousBackups(context.Context, *dynamodb.DescribeContinuousBackupsInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeContinuousBackupsOutput, error)
+}
+
+// EC2API reads the account's EBS default and security groups.
+type EC2API interface {
+	GetEbsEncryptionByDefault(context.Context, *ec2.GetEbsEncryptionByDefaultInput, ...func(*ec2.Options)) (*ec2.GetEbsEncryptionByDefaultOutput, error)
+	DescribeSecurityGroups(context.Context, *ec2.DescribeSecurityGroupsInput, ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+}
+
+// IAMAPI reads the password policy, Access Advisor reports, server
+// certificates and imported roles and policies.
+type IAMAPI interface {
+	GetAccountPasswordPolicy(context.Context, *iam.GetAccountPasswordPolicyInput, ...func(*iam.Options)) (*iam.GetAccountPasswordPolicyOutput, error)
+	GenerateServiceLastAccessedDetails(context.Context, *iam.GenerateServiceLastAccessedDetailsInput, ...func(*iam.Options)) (*iam.GenerateServiceLastAccessedDetailsOutput, error)
+	GetServiceLastAccessedDetails(context.Context, *iam.GetServiceLastAccessedDetailsInput, ...func(*iam.Options)) (*iam.GetServiceLastAccessedDetailsOutput, error)
+	ListServerCertificates(context.Context, *iam.ListServerCertificatesInput, ...func(*iam.Options)) (*iam.ListServerCertificatesOutput, error)
+	GetRole(context.Context, *iam.GetRoleInput, ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+	GetPolicy(context.Context, *iam.GetPolicyInput, ...func(*iam.Options)) (*iam.GetPolicyOutput, error)
+}
+
+// AccessAnalyzerAPI lists analyzer findings and validates policies.
+type AccessAnalyzerAPI interface {
+	ListAnalyzers(context.Context, *accessanalyzer.ListAnalyzersInput, ...func(*accessanalyzer.Options)) (*accessanalyzer.ListAnalyzersOutput, error)
+	ListFindings(context.Context, *accessanalyzer.ListFindingsInput, ...func(*accessanalyzer.Options)) (*accessanalyzer.ListFindingsOutput, error)
+	ValidatePolicy(context.Context, *accessanalyzer.ValidatePolicyInput, ...func(*accessanalyzer.Options)) (*accessanalyzer.ValidatePolicyOutput, error)
+}
+
+// SecretsManagerAPI describes secrets.
+type SecretsManagerAPI interface {
+	DescribeSecret(context.Context, *secretsmanager.DescribeSecretInput, ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error)
+}
+
+// CloudTrailAPI looks up who read a secret.
+type CloudTrailAPI interface {
+	LookupEvents(context.Context, *cloudtrail.LookupEventsInput, ...func(*cloudtrail.Options)) (*cloudtrail.LookupEventsOutput, error)
+}
+
+// ACMAPI describes certificates.
+type ACMAPI interface {
+	DescribeCertificate(context.Context, *acm.DescribeCertificateInput, ...func(*acm.Options)) (*acm.DescribeCertificateOutput, error)
+}
+
+// STSAPI identifies the caller's account.
+type STSAPI interface {
+	GetCallerIdentity(context.Context, *sts.GetCallerIdentityInput, ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// Clients are the AWS APIs the package reads from.
+type Clients struct {
+	S3             S3API
+	S3Control      S3ControlAPI
+	DynamoDB       DynamoDBAPI
+	EC2            EC2API
+	IAM            IAMAPI
+	AccessAnalyzer AccessAnalyzerAPI
+	SecretsManager SecretsManagerAPI
+	CloudTrail     CloudTrailAPI
+	ACM            ACMAPI
+	STS            STSAPI
+
+	// Region is the region the clients call.
+	Region string
+
+	// Cache, when set, reads each bucket and table once, for environments
+	// sharing a state backend. Reads are keyed by the account the STS
+	// client's caller belongs to and by Region, since every account may
+	// have a bucket or table of the same name.
+	Cache *awsapi.Cache
+
+	// PollInterval is how long to wait between checks on an IAM Access
+	// Advisor report being generated. Zero means one second.
+	PollInterval time.Duration
+
+	accountOnce sync.Once
+	account     string
+	accountErr  error
+}
+
+// scopedKey is the cache key of a read of name in the caller's account and
+// Region.
+type scopedKey struct {
+	Account string `json:"account"`
+	Region  string `json:"region"`
+	Name    string `json:"name"`
+}
+
+// cacheKey returns the key operation on name is cached under, asking STS for
+// the caller's account on first use. Without a Cache nothing is cached, so
+// STS is not asked.
+func (c *Clients) cacheKey(ctx context.Context, operation, name string) (string, error) {
+	if c.Cache == nil {
+		return "", nil
+	}
+	c.accountOnce.Do(func() {
+		out, err := c.STS.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err != nil {
+			c.accountErr = fmt.Errorf("livestate: identifying the account: %w", err)
+			return
+		}
+		c.account = aws.ToString(out.Account)
+	})
+	if c.accountErr != nil {
+		return "", c.accountErr
+	}
+	return awsapi.Key(operation, scopedKey{Account: c.account, Region: c.Region, Name: name}), nil
+}
+
+// NewClients returns the clients deployed resources are read through in
+// region. Nil creds use the default awsapi factory's credentials.
+func NewClients(region string, creds *credentials.Credentials) (*Clients, error) {
+	cfg, err := awsapi.Default().WithCredentials(creds).Config(context.Background(), region)
+	if err != nil {
+		return nil, fmt.Errorf("livestate: %w", err)
+	}
+	return &Clients{
+		S3:             s3.NewFromConfig(cfg, awsapi.S3PathStyle(cfg)),
+		S3Control:      s3control.NewFromConfig(cfg),
+		DynamoDB:       dynamodb.NewFromConfig(cfg),
+		EC2:            ec2.NewFromConfig(cfg),
+		IAM:            iam.NewFromConfig(cfg),
+		AccessAnalyzer: accessanalyzer.NewFromConfig(cfg),
+		SecretsManager: secretsmanager.NewFromConfig(cfg),
+		CloudTrail:     cloudtrail.NewFromConfig(cfg),
+		ACM:            acm.NewFromConfig(cfg),
+		STS:            sts.NewFromConfig(cfg),
+		Region:         region,
+		Cache:          awsapi.DefaultCache,
+	}, nil
+}
+
+// StateBackend reads an S3 backend's state bucket and lock table and returns
+// them as the planned values of a plan, in the provider's schema:
+// aws_s3_bucket.state with its versioning, encryption and policy resources,
+// and aws_dynamodb_table.lock.
+func (c *Clients) StateBackend(backend *plancheck.Backend) (*tfjson.Plan, error) {
+	if backend == nil || backend.Type != "s3" {
+		return nil, fmt.Errorf("livestate: not an s3 backend")
+	}
+
+	ctx := context.Background()
+	var resources []*tfjson.StateResource
+	if bucket := backend.Config["bucket"]; bucket != "" {
+		key, err := c.cacheKey(ctx, "livestate.bucket", bucket)
+		if err != nil {
+			return nil, err
+		}
+		bucketResources, err := c.Cache.Do(key, func() (interface{}, error) {
+			return c.bucket(ctx, bucket)
+		})
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, bucketResources.([]*tfjson.StateResource)...)
+	}
+	if table := backend.Config["dynamodb_table"]; table != "" {
+		key, err := c.cacheKey(ctx, "livestate.table", table)
+		if err != nil {
+			return nil, err
+		}
+		lock, err := c.Cache.Do(key, func() (interface{}, error) {
+			return c.table(ctx, table)
+		})
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, lock.(*tfjson.StateResource))
+	}
+
+	return &tfjson.Plan{
+		FormatVersion: "1.0",
+		PlannedValues: &tfjson.StateValues{RootModule: &tfjson.StateModule{Resources: resources}},
+	}, nil
+}
+
+func (c *Clients) bucket(ctx context.Context, name string) ([]*tfjson.StateResource, error) {
+	resources := []*tfjson.StateResource{
+		resource("aws_s3_bucket", "state", map[string]interface{}{"bucket": name}),
+	}
+
+	versioning, err := c.S3.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{Bucket: aws.String(name)})
+	if err != nil {
+		return nil, fmt.Errorf("livestate: reading versioning of %s: %w", name, err)
+	}
+	if status := string(versioning.Status); status != "" {
+		resources = append(resources, resource("aws_s3_bucket_versioning", "state", map[string]interface{}{
+			"bucket":                   name,
+			"versioning_configuration": []interface{}{map[string]interface{}{"status": status}},
+		}))
+	}
+
+	encryption, err := c.S3.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{Bucket: aws.String(name)})
+	if err != nil && !isCode(err, "ServerSideEncryptionConfigurationNotFoundError") {
+		return nil, fmt.Errorf("livestate: reading encryption of %s: %w", name, err)
+	}
+	if err == nil && encryption.ServerSideEncryptionConfiguration != nil {
+		var rules []interface{}
+		for _, rule := range encryption.ServerSideEncryptionConfiguration.Rules {
+			if rule.ApplyServerSideEncryptionByDefault == nil {
+				continue
+			}
+			rules = append(rules, map[string]interface{}{
+				"apply_server_side_encryption_by_default": []interface{}{map[string]interface{}{
+					"sse_algorithm":     string(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm),
+					"kms_master_key_id": aws.ToString(rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID),
+				}},
+			})
+		}
+		resources = append(resources, resource("aws_s3_bucket_server_side_encryption_configuration", "state", map[string]interface{}{
+			"bucket": name,
+			"rule":   rules,
+		}))
+	}
+
+	policy, err := c.S3.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{Bucket: aws.String(name)})
+	if err != nil && !isCode(err, "NoSuchBucketPolicy") {
+		return nil, fmt.Errorf("livestate: reading policy of %s: %w", name, err)
+	}
+	if err == nil {
+		resources = append(resources, resource("aws_s3_bucket_policy", "state", map[string]interface{}{
+			"bucket": name,
+			"policy": aws.ToString(policy.Policy),
+		}))
+	}
+	return resources, nil
+}
+
+func (c *Clients) table(ctx context.Context, name string) (*tfjson.StateResource, error) {
+	described, err := c.DynamoDB.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+	if err != nil {
+		return nil, fmt.Errorf("livestate: describing %s: %w", name, err)
+	}
+	backups, err := c.DynamoDB.DescribeContinuousBackups(ctx, &dynamodb.DescribeContinuousBackupsInput{TableName: aws.String(name)})
+	if err != nil {
+		return nil, fmt.Errorf("livestate: reading continuous backups of %s: %w", name, err)
+	}
+
+	sse := described.Table.SSEDescription
+	var pitr bool
+	if description := backups.ContinuousBackupsDescription; description != nil && description.PointInTimeRecoveryDescription != nil {
+		pitr = description.PointInTimeRecoveryDescription.PointInTimeRecoveryStatus == dynamodbtypes.PointInTimeRecoveryStatusEnabled
+	}
+	return resource("aws_dynamodb_table", "lock", map[string]interface{}{
+		"name": name,
+		"server_side_encryption": []interface{}{map[string]interface{}{
+			"enabled":     sse != nil && sse.Status == dynamodbtypes.SSEStatusEnabled,
+			"kms_key_arn": kmsKey(sse),
+		}},
+		"point_in_time_recovery": []interface{}{map[string]interface{}{"enabled": pitr}},
+	}), nil
+}
+
+func kmsKey(sse *dynamodbtypes.SSEDescription) string {
+	if sse == nil {
+		return ""
+	}
+	return aws.ToString(sse.KMSMasterKeyArn)
+}
+
+func resource(resourceType, name string, values map[string]interface{}) *tfjson.StateResource {
+	return &tfjson.StateResource{
+		Address:         resourceType + "." + name,
+		Mode:            tfjson.ManagedResourceMode,
+		Type:            resourceType,
+		Name:            name,
+		AttributeValues: values,
+	}
+}
+
+func isCode(err error, code string) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == code
+}
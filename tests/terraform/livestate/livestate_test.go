@@ -0,0 +1,125 @@
+package livestate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/plancheck"
+)
+
+type fakeS3 struct {
+	S3API
+	versioning s3types.BucketVersioningStatus
+	reads      int
+}
+
+func (f *fakeS3) GetBucketVersioning(context.Context, *s3.GetBucketVersioningInput, ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
+	f.reads++
+	return &s3.GetBucketVersioningOutput{Status: f.versioning}, nil
+}
+
+func (f *fakeS3) GetBucketEncryption(context.Context, *s3.GetBucketEncryptionInput, ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
+	return &s3.GetBucketEncryptionOutput{ServerSideEncryptionConfiguration: &s3types.ServerSideEncryptionConfiguration{
+		Rules: []s3types.ServerSideEncryptionRule{{
+			ApplyServerSideEncryptionByDefault: &s3types.ServerSideEncryptionByDefault{SSEAlgorithm: s3types.ServerSideEncryptionAes256},
+		}},
+	}}, nil
+}
+
+func (f *fakeS3) GetBucketPolicy(context.Context, *s3.GetBucketPolicyInput, ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "NoSuchBucketPolicy", Message: "The bucket policy does not exist"}
+}
+
+type fakeDynamoDB struct {
+	DynamoDBAPI
+}
+
+func (f *fakeDynamoDB) DescribeTable(_ context.Context, in *dynamodb.DescribeTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{Table: &dynamodbtypes.TableDescription{TableName: in.TableName}}, nil
+}
+
+func (f *fakeDynamoDB) DescribeContinuousBackups(context.Context, *dynamodb.DescribeContinuousBackupsInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+	return &dynamodb.DescribeContinuousBackupsOutput{ContinuousBackupsDescription: &dynamodbtypes.ContinuousBackupsDescription{
+		ContinuousBackupsStatus: dynamodbtypes.ContinuousBackupsStatusEnabled,
+		PointInTimeRecoveryDescription: &dynamodbtypes.PointInTimeRecoveryDescription{
+			PointInTimeRecoveryStatus: dynamodbtypes.PointInTimeRecoveryStatusEnabled,
+		},
+	}}, nil
+}
+
+func TestStateBackendReadsBucketAndLockTable(t *testing.T) {
+	clients := &Clients{S3: &fakeS3{versioning: "Enabled"}, DynamoDB: &fakeDynamoDB{}}
+	plan, err := clients.StateBackend(&plancheck.Backend{
+		Type:   "s3",
+		Config: map[string]string{"bucket": "state", "dynamodb_table": "lock"},
+	})
+	require.NoError(t, err)
+
+	values := map[string]map[string]interface{}{}
+	for _, resource := range plancheck.PlannedResources(plan) {
+		values[resource.Address] = resource.AttributeValues
+	}
+	require.Len(t, values, 4, "bucket, versioning, encryption and table; no policy")
+	require.Equal(t, "Enabled", plancheck.LookupString(values["aws_s3_bucket_versioning.state"], "versioning_configuration.0.status"))
+	require.Equal(t, "AES256", plancheck.LookupString(values["aws_s3_bucket_server_side_encryption_configuration.state"],
+		"rule.0.apply_server_side_encryption_by_default.0.sse_algorithm"))
+	require.True(t, plancheck.LookupBool(values["aws_dynamodb_table.lock"], "point_in_time_recovery.0.enabled"))
+	require.False(t, plancheck.LookupBool(values["aws_dynamodb_table.lock"], "server_side_encryption.0.enabled"))
+
+	_, err = clients.StateBackend(&plancheck.Backend{Type: "local"})
+	require.Error(t, err)
+}
+
+type callerSTS struct {
+	STSAPI
+	account string
+	calls   int
+}
+
+func (f *callerSTS) GetCallerIdentity(context.Context, *sts.GetCallerIdentityInput, ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	f.calls++
+	return &sts.GetCallerIdentityOutput{Account: aws.String(f.account)}, nil
+}
+
+func TestStateBackendReadsSharedBackendOnce(t *testing.T) {
+	cache := &awsapi.Cache{}
+	bucket := &fakeS3{versioning: "Enabled"}
+	dev := &callerSTS{account: "111111111111"}
+	clients := &Clients{S3: bucket, DynamoDB: &fakeDynamoDB{}, STS: dev, Region: "us-east-1", Cache: cache}
+	backend := &plancheck.Backend{Type: "s3", Config: map[string]string{"bucket": "state", "dynamodb_table": "lock"}}
+
+	first, err := clients.StateBackend(backend)
+	require.NoError(t, err)
+	second, err := clients.StateBackend(backend)
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	require.Equal(t, 1, bucket.reads)
+	require.Equal(t, 1, dev.calls, "the account is looked up once")
+
+	// Another environment in the same account shares the reads.
+	staging := &Clients{S3: bucket, DynamoDB: &fakeDynamoDB{}, STS: &callerSTS{account: "111111111111"}, Region: "us-east-1", Cache: cache}
+	_, err = staging.StateBackend(backend)
+	require.NoError(t, err)
+	require.Equal(t, 1, bucket.reads)
+
+	// Every account names its lock table alike, so another account reads its
+	// own, and so does another region.
+	prod := &Clients{S3: bucket, DynamoDB: &fakeDynamoDB{}, STS: &callerSTS{account: "222222222222"}, Region: "us-east-1", Cache: cache}
+	_, err = prod.StateBackend(backend)
+	require.NoError(t, err)
+	require.Equal(t, 2, bucket.reads)
+	west := &Clients{S3: bucket, DynamoDB: &fakeDynamoDB{}, STS: &callerSTS{account: "222222222222"}, Region: "us-west-2", Cache: cache}
+	_, err = west.StateBackend(backend)
+	require.NoError(t, err)
+	require.Equal(t, 3, bucket.reads)
+}
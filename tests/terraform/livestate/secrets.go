@@ -0,0 +1,100 @@
+package livestate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// trailHistory is how far back CloudTrail's event history goes.
+const trailHistory = 90 * 24 * time.Hour
+
+// Secrets returns, for each secret ARN, when the secret was created and last
+// rotated, and the principals CloudTrail recorded reading its value.
+func (c *Clients) Secrets(arns []string) (map[string]plancheck.SecretActivity, error) {
+	ctx := context.Background()
+	secrets := map[string]plancheck.SecretActivity{}
+	for _, arn := range arns {
+		out, err := c.SecretsManager.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{SecretId: aws.String(arn)})
+		if err != nil {
+			return nil, fmt.Errorf("livestate: describing secret %s: %w", arn, err)
+		}
+		readers, err := c.secretReaders(ctx, arn)
+		if err != nil {
+			return nil, err
+		}
+		secrets[arn] = plancheck.SecretActivity{
+			Name:        aws.ToString(out.Name),
+			CreatedAt:   out.CreatedDate,
+			LastRotated: out.LastRotatedDate,
+			Readers:     readers,
+		}
+	}
+	return secrets, nil
+}
+
+// trailEvent is the part of a CloudTrail event record naming the caller.
+type trailEvent struct {
+	UserIdentity struct {
+		ARN       string `json:"arn"`
+		InvokedBy string `json:"invokedBy"`
+	} `json:"userIdentity"`
+}
+
+func (c *Clients) secretReaders(ctx context.Context, arn string) ([]plancheck.SecretReader, error) {
+	readers := map[string]*plancheck.SecretReader{}
+	pages := cloudtrail.NewLookupEventsPaginator(c.CloudTrail, &cloudtrail.LookupEventsInput{
+		LookupAttributes: []types.LookupAttribute{{
+			AttributeKey:   types.LookupAttributeKeyResourceName,
+			AttributeValue: aws.String(arn),
+		}},
+		StartTime: aws.Time(time.Now().Add(-trailHistory)),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("livestate: looking up reads of secret %s: %w", arn, err)
+		}
+		for _, event := range page.Events {
+			if aws.ToString(event.EventName) != "GetSecretValue" {
+				continue
+			}
+			var record trailEvent
+			if err := json.Unmarshal([]byte(aws.ToString(event.CloudTrailEvent)), &record); err != nil {
+				return nil, fmt.Errorf("livestate: reading CloudTrail event %s: %w", aws.ToString(event.EventId), err)
+			}
+			principal := record.UserIdentity.ARN
+			if principal == "" {
+				principal = record.UserIdentity.InvokedBy
+			}
+			if principal == "" {
+				continue
+			}
+			reader, ok := readers[principal]
+			if !ok {
+				reader = &plancheck.SecretReader{Principal: principal}
+				readers[principal] = reader
+			}
+			reader.Reads++
+			if at := aws.ToTime(event.EventTime); at.After(reader.LastRead) {
+				reader.LastRead = at
+			}
+		}
+	}
+
+	list := make([]plancheck.SecretReader, 0, len(readers))
+	for _, reader := range readers {
+		list = append(list, *reader)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Principal < list[j].Principal })
+	return list, nil
+}
@@ -0,0 +1,77 @@
+package livestate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+type fakeSecretsManager struct {
+	SecretsManagerAPI
+	created, rotated time.Time
+}
+
+func (f *fakeSecretsManager) DescribeSecret(_ context.Context, in *secretsmanager.DescribeSecretInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error) {
+	return &secretsmanager.DescribeSecretOutput{
+		ARN:             in.SecretId,
+		Name:            aws.String("pkg-db"),
+		CreatedDate:     &f.created,
+		LastRotatedDate: &f.rotated,
+	}, nil
+}
+
+type fakeCloudTrail struct {
+	CloudTrailAPI
+	events []types.Event
+}
+
+func (f *fakeCloudTrail) LookupEvents(context.Context, *cloudtrail.LookupEventsInput, ...func(*cloudtrail.Options)) (*cloudtrail.LookupEventsOutput, error) {
+	return &cloudtrail.LookupEventsOutput{Events: f.events}, nil
+}
+
+func trailRead(event string, at time.Time, identity string) types.Event {
+	return types.Event{
+		EventName:       aws.String(event),
+		EventTime:       aws.Time(at),
+		CloudTrailEvent: aws.String(`{"userIdentity":` + identity + `}`),
+	}
+}
+
+func TestSecretsCountsReadsByPrincipal(t *testing.T) {
+	created := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	rotated := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	first := time.Date(2026, 5, 30, 8, 0, 0, 0, time.UTC)
+	last := time.Date(2026, 5, 31, 22, 10, 0, 0, time.UTC)
+	api := `{"type":"AssumedRole","arn":"arn:aws:sts::123456789012:assumed-role/pkg-api/1717000000"}`
+
+	clients := &Clients{
+		SecretsManager: &fakeSecretsManager{created: created, rotated: rotated},
+		CloudTrail: &fakeCloudTrail{events: []types.Event{
+			trailRead("GetSecretValue", last, api),
+			trailRead("GetSecretValue", first, api),
+			trailRead("DescribeSecret", last, `{"arn":"arn:aws:iam::123456789012:user/ops"}`),
+			trailRead("GetSecretValue", first, `{"type":"AWSService","invokedBy":"lambda.amazonaws.com"}`),
+		}},
+	}
+
+	arn := "arn:aws:secretsmanager:us-east-1:123456789012:secret:pkg-db-AbCdEf"
+	secrets, err := clients.Secrets([]string{arn})
+	require.NoError(t, err)
+	require.Equal(t, map[string]plancheck.SecretActivity{arn: {
+		Name:        "pkg-db",
+		CreatedAt:   &created,
+		LastRotated: &rotated,
+		Readers: []plancheck.SecretReader{
+			{Principal: "arn:aws:sts::123456789012:assumed-role/pkg-api/1717000000", Reads: 2, LastRead: last},
+			{Principal: "lambda.amazonaws.com", Reads: 1, LastRead: first},
+		},
+	}}, secrets)
+}
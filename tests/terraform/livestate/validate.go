@@ -0,0 +1,83 @@
+package livestate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/accessanalyzer"
+	"github.com/aws/aws-sdk-go-v2/service/accessanalyzer/types"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/plancheck"
+)
+
+// ValidatePolicies runs IAM Access Analyzer's ValidatePolicy on each
+// permission policy document, as an identity policy, and returns its
+// findings of every type. Documents are sent as planned, so values known
+// only after apply cannot be checked.
+func (c *Clients) ValidatePolicies(documents []plancheck.PolicyDocument) ([]plancheck.PolicyValidation, error) {
+	ctx := context.Background()
+	var validations []plancheck.PolicyValidation
+	for _, document := range documents {
+		findings, err := c.Cache.Do(awsapi.Key("livestate.validate-policy", document.Policy), func() (interface{}, error) {
+			return c.validatePolicy(ctx, document.Policy)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("livestate: validating the policy %s of %s: %w", document.Path, document.Address, err)
+		}
+		for _, finding := range findings.([]types.ValidatePolicyFinding) {
+			validation := plancheck.PolicyValidation{
+				Address:       document.Address,
+				Path:          document.Path,
+				Type:          string(finding.FindingType),
+				IssueCode:     aws.ToString(finding.IssueCode),
+				Details:       aws.ToString(finding.FindingDetails),
+				LearnMoreLink: aws.ToString(finding.LearnMoreLink),
+			}
+			if len(finding.Locations) > 0 {
+				validation.Location = locationPath(finding.Locations[0].Path)
+			}
+			validations = append(validations, validation)
+		}
+	}
+	return validations, nil
+}
+
+func (c *Clients) validatePolicy(ctx context.Context, policy string) ([]types.ValidatePolicyFinding, error) {
+	var findings []types.ValidatePolicyFinding
+	pages := accessanalyzer.NewValidatePolicyPaginator(c.AccessAnalyzer, &accessanalyzer.ValidatePolicyInput{
+		PolicyDocument: aws.String(policy),
+		PolicyType:     types.PolicyTypeIdentityPolicy,
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, page.Findings...)
+	}
+	return findings, nil
+}
+
+// locationPath renders the path of a ValidatePolicy location the way finding
+// paths are written, e.g. "Statement[0].Action[1]". Paths into a value or a
+// part of it stop at the element holding it.
+func locationPath(elements []types.PathElement) string {
+	var path strings.Builder
+	for _, element := range elements {
+		switch element := element.(type) {
+		case *types.PathElementMemberKey:
+			if path.Len() > 0 {
+				path.WriteByte('.')
+			}
+			path.WriteString(element.Value)
+		case *types.PathElementMemberIndex:
+			fmt.Fprintf(&path, "[%d]", element.Value)
+		default:
+			return path.String()
+		}
+	}
+	return path.String()
+}
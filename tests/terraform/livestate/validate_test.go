@@ -0,0 +1,76 @@
+package livestate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/accessanalyzer"
+	"github.com/aws/aws-sdk-go-v2/service/accessanalyzer/types"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+type fakeValidator struct {
+	fakeAccessAnalyzer
+	validated []string
+}
+
+func (f *fakeValidator) ValidatePolicy(_ context.Context, in *accessanalyzer.ValidatePolicyInput, _ ...func(*accessanalyzer.Options)) (*accessanalyzer.ValidatePolicyOutput, error) {
+	if in.NextToken != nil {
+		return &accessanalyzer.ValidatePolicyOutput{Findings: []types.ValidatePolicyFinding{{
+			FindingType:    types.ValidatePolicyFindingTypeSuggestion,
+			IssueCode:      aws.String("REDUNDANT_ACTION"),
+			FindingDetails: aws.String("The action is already covered."),
+		}}}, nil
+	}
+	f.validated = append(f.validated, string(in.PolicyType)+" "+aws.ToString(in.PolicyDocument))
+	if aws.ToString(in.PolicyDocument) == "clean" {
+		return &accessanalyzer.ValidatePolicyOutput{}, nil
+	}
+	return &accessanalyzer.ValidatePolicyOutput{
+		Findings: []types.ValidatePolicyFinding{{
+			FindingType:    types.ValidatePolicyFindingTypeError,
+			IssueCode:      aws.String("INVALID_ACTION"),
+			FindingDetails: aws.String("The action s3:GetObjects does not exist."),
+			LearnMoreLink:  aws.String("https://docs.aws.amazon.com/IAM/latest/UserGuide/access-analyzer-reference-policy-checks.html"),
+			Locations: []types.Location{{Path: []types.PathElement{
+				&types.PathElementMemberKey{Value: "Statement"},
+				&types.PathElementMemberIndex{Value: 0},
+				&types.PathElementMemberKey{Value: "Action"},
+				&types.PathElementMemberIndex{Value: 1},
+				&types.PathElementMemberValue{Value: "s3:GetObjects"},
+			}}},
+		}},
+		NextToken: aws.String("2"),
+	}, nil
+}
+
+func TestValidatePoliciesLocatesFindings(t *testing.T) {
+	analyzer := &fakeValidator{}
+	validations, err := (&Clients{AccessAnalyzer: analyzer}).ValidatePolicies([]plancheck.PolicyDocument{
+		{Address: "aws_iam_policy.reader", Path: "policy", Policy: "typo"},
+		{Address: "aws_iam_role.api", Path: "inline_policy[0].policy", Policy: "clean"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"IDENTITY_POLICY typo", "IDENTITY_POLICY clean"}, analyzer.validated)
+	require.Equal(t, []plancheck.PolicyValidation{
+		{
+			Address:       "aws_iam_policy.reader",
+			Path:          "policy",
+			Type:          "ERROR",
+			IssueCode:     "INVALID_ACTION",
+			Details:       "The action s3:GetObjects does not exist.",
+			Location:      "Statement[0].Action[1]",
+			LearnMoreLink: "https://docs.aws.amazon.com/IAM/latest/UserGuide/access-analyzer-reference-policy-checks.html",
+		},
+		{
+			Address:   "aws_iam_policy.reader",
+			Path:      "policy",
+			Type:      "SUGGESTION",
+			IssueCode: "REDUNDANT_ACTION",
+			Details:   "The action is already covered.",
+		},
+	}, validations)
+}
@@ -0,0 +1,47 @@
+package terraformtests
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/loadtest"
+	"cs450/terraformtests/plancheck"
+)
+
+// loadSmokeEnv enables the load smoke test. It is separate from
+// COMPLIANCE_POST_APPLY because it sends real traffic, which costs money and
+// shows up on the dashboards.
+const loadSmokeEnv = "COMPLIANCE_LOAD_SMOKE"
+
+// A short burst against the deployed API must stay within the latency and
+// error thresholds, which catches an undersized function or a throttling
+// limit set far too low before dev is promoted.
+func TestDeployedAPIHandlesLoadBurst(t *testing.T) {
+	if os.Getenv(loadSmokeEnv) == "" {
+		t.Skipf("set %s=1 to send a burst of requests to the deployed API", loadSmokeEnv)
+	}
+
+	config, err := plancheck.LoadConfig(complianceFile)
+	require.NoError(t, err)
+	policy := config.Load
+
+	creds, err := roleCredentials(devEnvironment)
+	require.NoError(t, err)
+	options := environmentOptions(t, testEnvironment(t, devEnvironment))
+	options.EnvVars = creds.Env()
+	terraform.Init(t, options)
+	url := strings.TrimSuffix(terraform.OutputRequired(t, options, "api_gateway_url"), "/") + policy.RequestPath()
+
+	attack := &loadtest.Attack{Rate: policy.RequestRate(), Duration: policy.Duration()}
+	report, err := attack.Run(context.Background(), url)
+	require.NoError(t, err)
+	t.Logf("GET %s at %d/s for %s: %s", url, attack.Rate, attack.Duration, report)
+	for _, problem := range report.Check(loadtest.Thresholds{P95: policy.P95Latency(), MaxErrorRate: policy.MaxErrorRate()}) {
+		t.Errorf("GET %s: %s", url, problem)
+	}
+}
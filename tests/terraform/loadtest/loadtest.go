@@ -0,0 +1,177 @@
+// Package loadtest sends a short burst of requests at a constant rate and
+// reports their latency and error rate, like vegeta's attack and report. It
+// is a smoke test, not a benchmark: it catches an undersized or misconfigured
+// deployment, not a few milliseconds of regression.
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Attack is a burst of GET requests at a constant rate.
+type Attack struct {
+	// Rate is the number of requests started per second.
+	Rate int
+
+	// Duration is how long requests are started for.
+	Duration time.Duration
+
+	// Timeout bounds each request; a request that times out is an error.
+	// Zero means ten seconds.
+	Timeout time.Duration
+
+	// Client sends the requests; nil uses a client with Timeout.
+	Client *http.Client
+}
+
+// Report summarises a burst.
+type Report struct {
+	Requests int
+	// Errors are the requests that failed or answered outside 2xx and 3xx.
+	Errors int
+	// Codes counts the responses by status code; 0 counts the requests that
+	// got no response.
+	Codes map[int]int
+	P50   time.Duration
+	P95   time.Duration
+	Max   time.Duration
+}
+
+// ErrorRate returns the fraction of requests that were errors.
+func (r Report) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Requests)
+}
+
+// String returns a one-line summary of the report.
+func (r Report) String() string {
+	return fmt.Sprintf("%d requests, %.2f%% errors, p50 %s, p95 %s, max %s",
+		r.Requests, 100*r.ErrorRate(), r.P50.Round(time.Millisecond), r.P95.Round(time.Millisecond), r.Max.Round(time.Millisecond))
+}
+
+// Thresholds are what a burst must stay within.
+type Thresholds struct {
+	P95          time.Duration
+	MaxErrorRate float64
+}
+
+// Check returns the thresholds the report exceeds, empty when it is within
+// them all.
+func (r Report) Check(thresholds Thresholds) []string {
+	var problems []string
+	if r.Requests == 0 {
+		return []string{"sent no requests"}
+	}
+	if thresholds.P95 > 0 && r.P95 > thresholds.P95 {
+		problems = append(problems, fmt.Sprintf("p95 latency %s exceeds %s", r.P95.Round(time.Millisecond), thresholds.P95))
+	}
+	if rate := r.ErrorRate(); rate > thresholds.MaxErrorRate {
+		problems = append(problems, fmt.Sprintf("error rate %.2f%% (%d of %d, by status %v) exceeds %.2f%%", 100*rate, r.Errors, r.Requests, r.Codes, 100*thresholds.MaxErrorRate))
+	}
+	return problems
+}
+
+// Run sends the burst to url and waits for every request to finish. A
+// cancelled ctx stops starting new requests.
+func (a *Attack) Run(ctx context.Context, url string) (Report, error) {
+	if a.Rate <= 0 || a.Duration <= 0 {
+		return Report{}, fmt.Errorf("loadtest: rate and duration must be positive, got %d/s for %s", a.Rate, a.Duration)
+	}
+	if _, err := http.NewRequest(http.MethodGet, url, nil); err != nil {
+		return Report{}, fmt.Errorf("loadtest: %w", err)
+	}
+	client := a.client()
+	total := int(a.Duration.Seconds() * float64(a.Rate))
+	interval := time.Second / time.Duration(a.Rate)
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies []time.Duration
+		report    = Report{Codes: map[int]int{}}
+	)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 0; i < total; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				i = total
+				continue
+			case <-ticker.C:
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, latency := hit(ctx, client, url)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Requests++
+			report.Codes[code]++
+			if code < 200 || code >= 400 {
+				report.Errors++
+			}
+			latencies = append(latencies, latency)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.P50 = percentile(latencies, 50)
+	report.P95 = percentile(latencies, 95)
+	if len(latencies) > 0 {
+		report.Max = latencies[len(latencies)-1]
+	}
+	return report, nil
+}
+
+func (a *Attack) client() *http.Client {
+	if a.Client != nil {
+		return a.Client
+	}
+	timeout := a.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// hit sends one request and returns its status code, 0 when it got no
+// response, and how long the response took to arrive in full.
+func hit(ctx context.Context, client *http.Client, url string) (int, time.Duration) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, 0
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, time.Since(start)
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return 0, time.Since(start)
+	}
+	return resp.StatusCode, time.Since(start)
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,75 @@
+package loadtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunReportsLatencyAndErrors(t *testing.T) {
+	var count atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// Every fifth request fails, and every tenth is slow.
+		n := count.Add(1)
+		if n%10 == 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if n%5 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	report, err := (&Attack{Rate: 100, Duration: 400 * time.Millisecond}).Run(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	require.Equal(t, 40, report.Requests)
+	require.Equal(t, 8, report.Errors)
+	require.Equal(t, map[int]int{200: 32, 503: 8}, report.Codes)
+	require.InDelta(t, 0.2, report.ErrorRate(), 1e-9)
+	require.Less(t, report.P50, 100*time.Millisecond)
+	require.GreaterOrEqual(t, report.P95, 100*time.Millisecond)
+	require.GreaterOrEqual(t, report.Max, report.P95)
+
+	problems := report.Check(Thresholds{P95: 50 * time.Millisecond, MaxErrorRate: 0.01})
+	require.Len(t, problems, 2)
+	require.Contains(t, problems[0], "exceeds 50ms")
+	require.Equal(t, "error rate 20.00% (8 of 40, by status map[200:32 503:8]) exceeds 1.00%", problems[1])
+
+	require.Empty(t, report.Check(Thresholds{P95: time.Second, MaxErrorRate: 0.25}))
+}
+
+func TestRunCountsUnansweredRequestsAsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	report, err := (&Attack{Rate: 20, Duration: 100 * time.Millisecond, Timeout: 20 * time.Millisecond}).Run(context.Background(), server.URL)
+	require.NoError(t, err)
+	require.Equal(t, Report{Requests: 2, Errors: 2, Codes: map[int]int{0: 2}, P50: report.P50, P95: report.P95, Max: report.Max}, report)
+}
+
+func TestRunRejectsEmptyAttack(t *testing.T) {
+	_, err := (&Attack{Duration: time.Second}).Run(context.Background(), "http://example.com")
+	require.EqualError(t, err, "loadtest: rate and duration must be positive, got 0/s for 1s")
+}
+
+func TestPercentileUsesNearestRank(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 20; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	require.Equal(t, 10*time.Millisecond, percentile(latencies, 50))
+	require.Equal(t, 19*time.Millisecond, percentile(latencies, 95))
+	require.Equal(t, time.Millisecond, percentile(latencies[:1], 95))
+	require.Zero(t, percentile(nil, 95))
+}
@@ -0,0 +1,94 @@
+// Package localstack runs an environment's root module against LocalStack:
+// it starts the container, writes the override that points the AWS provider
+// at it, and after the apply reads the planned buckets, roles and tables
+// back, catching what only fails at apply, such as policy JSON the IAM API
+// rejects.
+package localstack
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Port is the edge port LocalStack serves every API on.
+const Port = 4566
+
+// Services are the provider endpoints the override points at LocalStack:
+// those of the resource types the environments declare.
+var Services = []string{
+	"apigateway", "cloudfront", "cloudwatch", "dynamodb", "ec2", "ecr", "ecs",
+	"elbv2", "iam", "kms", "lambda", "logs", "s3", "s3control",
+	"secretsmanager", "sts",
+}
+
+// Container is a running LocalStack container.
+type Container struct {
+	ID string
+	// Endpoint is the URL of its edge port, e.g. http://127.0.0.1:49153.
+	Endpoint string
+}
+
+// Start runs image with its edge port published on an ephemeral local port
+// and waits up to timeout for LocalStack's health endpoint to answer. The
+// container is removed when t and its subtests complete.
+func Start(t *testing.T, image string, timeout time.Duration) (*Container, error) {
+	ctx := context.Background()
+	port := fmt.Sprintf("%d/tcp", Port)
+	container, err := testcontainers.Run(ctx, image,
+		testcontainers.WithExposedPorts(port),
+		testcontainers.WithWaitStrategy(wait.ForHTTP("/_localstack/health").WithPort(port).WithStartupTimeout(timeout)),
+	)
+	testcontainers.CleanupContainer(t, container)
+	if err != nil {
+		return nil, fmt.Errorf("localstack: starting %s: %w", image, err)
+	}
+	endpoint, err := container.PortEndpoint(ctx, port, "http")
+	if err != nil {
+		return nil, fmt.Errorf("localstack: %w", err)
+	}
+	return &Container{ID: container.GetContainerID(), Endpoint: endpoint}, nil
+}
+
+// OverrideFile is the name WriteOverride gives the override, which terraform
+// merges over the root module's provider and backend.
+const OverrideFile = "localstack_override.tf"
+
+// Override returns the configuration that points the AWS provider at
+// endpoint, with LocalStack's test credentials, and keeps state in a local
+// file rather than in the root module's backend. The provider keeps the root
+// module's region, which LocalStack emulates like any other.
+func Override(endpoint string) string {
+	services := append([]string(nil), Services...)
+	sort.Strings(services)
+
+	var b strings.Builder
+	b.WriteString("# Written by the LocalStack apply test; do not commit.\n")
+	b.WriteString("terraform {\n  backend \"local\" {}\n}\n\n")
+	b.WriteString("provider \"aws\" {\n")
+	b.WriteString("  access_key                  = \"test\"\n")
+	b.WriteString("  secret_key                  = \"test\"\n")
+	b.WriteString("  skip_credentials_validation = true\n")
+	b.WriteString("  skip_metadata_api_check     = true\n")
+	b.WriteString("  skip_requesting_account_id  = true\n")
+	b.WriteString("  s3_use_path_style           = true\n\n")
+	b.WriteString("  endpoints {\n")
+	for _, service := range services {
+		fmt.Fprintf(&b, "    %s = %q\n", service, endpoint)
+	}
+	b.WriteString("  }\n}\n")
+	return b.String()
+}
+
+// WriteOverride writes Override(endpoint) into the root module at dir.
+func WriteOverride(dir, endpoint string) error {
+	return os.WriteFile(filepath.Join(dir, OverrideFile), []byte(Override(endpoint)), 0o644)
+}
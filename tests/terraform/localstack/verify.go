@@ -0,0 +1,219 @@
+package localstack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/smithy-go"
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/plancheck"
+)
+
+// S3API reads back buckets and their tags.
+type S3API interface {
+	HeadBucket(context.Context, *s3.HeadBucketInput, ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	GetBucketTagging(context.Context, *s3.GetBucketTaggingInput, ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error)
+}
+
+// IAMAPI reads back roles.
+type IAMAPI interface {
+	GetRole(context.Context, *iam.GetRoleInput, ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+}
+
+// DynamoDBAPI reads back tables.
+type DynamoDBAPI interface {
+	DescribeTable(context.Context, *dynamodb.DescribeTableInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+}
+
+// Clients are the APIs the applied resources are read back through.
+type Clients struct {
+	S3       S3API
+	IAM      IAMAPI
+	DynamoDB DynamoDBAPI
+}
+
+// NewClients returns clients sending every request to endpoint, signed with
+// LocalStack's test credentials.
+func NewClients(endpoint, region string) (*Clients, error) {
+	cfg, err := awsapi.NewFactory(awsapi.Options{
+		Endpoint:    endpoint,
+		Credentials: credentials.NewStaticCredentials("test", "test", ""),
+	}).Config(context.Background(), region)
+	if err != nil {
+		return nil, fmt.Errorf("localstack: %w", err)
+	}
+	return &Clients{
+		S3:       s3.NewFromConfig(cfg, awsapi.S3PathStyle(cfg)),
+		IAM:      iam.NewFromConfig(cfg),
+		DynamoDB: dynamodb.NewFromConfig(cfg),
+	}, nil
+}
+
+// Verify reads back each bucket, role and table of plan and returns an error
+// for each that is missing or differs from its planned values: bucket tags,
+// a role's path and trust policy, a table's keys and billing mode. Resources
+// whose name is only known after apply are skipped.
+func (c *Clients) Verify(ctx context.Context, plan *tfjson.Plan) []error {
+	var errs []error
+	for _, resource := range plancheck.Resources(plan, "aws_s3_bucket", "aws_iam_role", "aws_dynamodb_table") {
+		var err error
+		switch resource.Type {
+		case "aws_s3_bucket":
+			err = c.bucket(ctx, resource.AttributeValues)
+		case "aws_iam_role":
+			err = c.role(ctx, resource.AttributeValues)
+		case "aws_dynamodb_table":
+			err = c.table(ctx, resource.AttributeValues)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", resource.Address, err))
+		}
+	}
+	return errs
+}
+
+func (c *Clients) bucket(ctx context.Context, values map[string]interface{}) error {
+	name := plancheck.LookupString(values, "bucket")
+	if name == "" {
+		return nil
+	}
+	if _, err := c.S3.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(name)}); err != nil {
+		return fmt.Errorf("bucket %s was not created: %w", name, err)
+	}
+
+	// tags_all adds the provider's default tags, where the plan knows them.
+	planned := stringMap(values["tags_all"])
+	if len(planned) == 0 {
+		planned = stringMap(values["tags"])
+	}
+	if len(planned) == 0 {
+		return nil
+	}
+	live := map[string]string{}
+	tagging, err := c.S3.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: aws.String(name)})
+	if err != nil && !isCode(err, "NoSuchTagSet") {
+		return fmt.Errorf("reading the tags of %s: %w", name, err)
+	}
+	if err == nil {
+		for _, tag := range tagging.TagSet {
+			live[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+	for key, value := range planned {
+		if got, ok := live[key]; !ok || got != value {
+			return fmt.Errorf("bucket %s has tag %s = %q, planned %q", name, key, got, value)
+		}
+	}
+	return nil
+}
+
+func (c *Clients) role(ctx context.Context, values map[string]interface{}) error {
+	name := plancheck.LookupString(values, "name")
+	if name == "" {
+		return nil
+	}
+	out, err := c.IAM.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(name)})
+	if err != nil {
+		return fmt.Errorf("role %s was not created: %w", name, err)
+	}
+
+	if path := plancheck.LookupString(values, "path"); path != "" && path != aws.ToString(out.Role.Path) {
+		return fmt.Errorf("role %s has path %s, planned %s", name, aws.ToString(out.Role.Path), path)
+	}
+	planned := plancheck.LookupString(values, "assume_role_policy")
+	if planned == "" {
+		return nil
+	}
+	// GetRole returns the trust policy URL-encoded.
+	live, err := url.QueryUnescape(aws.ToString(out.Role.AssumeRolePolicyDocument))
+	if err != nil {
+		return fmt.Errorf("decoding the trust policy of %s: %w", name, err)
+	}
+	same, err := sameJSON(planned, live)
+	if err != nil {
+		return fmt.Errorf("comparing the trust policy of %s: %w", name, err)
+	}
+	if !same {
+		return fmt.Errorf("role %s has trust policy %s, planned %s", name, live, planned)
+	}
+	return nil
+}
+
+func (c *Clients) table(ctx context.Context, values map[string]interface{}) error {
+	name := plancheck.LookupString(values, "name")
+	if name == "" {
+		return nil
+	}
+	out, err := c.DynamoDB.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+	if err != nil {
+		return fmt.Errorf("table %s was not created: %w", name, err)
+	}
+	table := out.Table
+
+	if status := table.TableStatus; status != types.TableStatusActive {
+		return fmt.Errorf("table %s is %s, not ACTIVE", name, status)
+	}
+	keys := map[types.KeyType]string{}
+	for _, element := range table.KeySchema {
+		keys[element.KeyType] = aws.ToString(element.AttributeName)
+	}
+	for keyType, attribute := range map[types.KeyType]string{
+		types.KeyTypeHash:  "hash_key",
+		types.KeyTypeRange: "range_key",
+	} {
+		if planned := plancheck.LookupString(values, attribute); planned != keys[keyType] {
+			return fmt.Errorf("table %s has %s %q, planned %q", name, attribute, keys[keyType], planned)
+		}
+	}
+
+	// Tables created with provisioned capacity have no billing mode summary.
+	billing := types.BillingModeProvisioned
+	if table.BillingModeSummary != nil {
+		billing = table.BillingModeSummary.BillingMode
+	}
+	if planned := plancheck.LookupString(values, "billing_mode"); planned != "" && planned != string(billing) {
+		return fmt.Errorf("table %s has billing mode %s, planned %s", name, billing, planned)
+	}
+	return nil
+}
+
+// sameJSON reports whether two JSON documents hold the same values.
+func sameJSON(a, b string) (bool, error) {
+	var left, right interface{}
+	if err := json.Unmarshal([]byte(a), &left); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(b), &right); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(left, right), nil
+}
+
+// stringMap returns a planned map attribute, such as tags, as strings.
+func stringMap(value interface{}) map[string]string {
+	values, _ := value.(map[string]interface{})
+	m := make(map[string]string, len(values))
+	for key, value := range values {
+		if s, ok := value.(string); ok {
+			m[key] = s
+		}
+	}
+	return m
+}
+
+func isCode(err error, code string) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == code
+}
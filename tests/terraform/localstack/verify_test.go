@@ -0,0 +1,133 @@
+package localstack
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+const trustPolicy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ecs-tasks.amazonaws.com"},"Action":"sts:AssumeRole"}]}`
+
+type fakeS3 struct {
+	S3API
+	tags map[string][]s3types.Tag
+}
+
+func (f *fakeS3) HeadBucket(_ context.Context, in *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if _, ok := f.tags[aws.ToString(in.Bucket)]; !ok {
+		return nil, &smithy.GenericAPIError{Code: "NotFound", Message: "Not Found"}
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (f *fakeS3) GetBucketTagging(_ context.Context, in *s3.GetBucketTaggingInput, _ ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error) {
+	tags := f.tags[aws.ToString(in.Bucket)]
+	if len(tags) == 0 {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchTagSet", Message: "The TagSet does not exist"}
+	}
+	return &s3.GetBucketTaggingOutput{TagSet: tags}, nil
+}
+
+type fakeIAM struct {
+	IAMAPI
+	roles map[string]string
+}
+
+func (f *fakeIAM) GetRole(_ context.Context, in *iam.GetRoleInput, _ ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	policy, ok := f.roles[aws.ToString(in.RoleName)]
+	if !ok {
+		return nil, &iamtypes.NoSuchEntityException{Message: aws.String("role not found")}
+	}
+	return &iam.GetRoleOutput{Role: &iamtypes.Role{
+		RoleName:                 in.RoleName,
+		Path:                     aws.String("/"),
+		AssumeRolePolicyDocument: aws.String(url.QueryEscape(policy)),
+	}}, nil
+}
+
+type fakeDynamoDB struct {
+	DynamoDBAPI
+	tables map[string]*dynamodbtypes.TableDescription
+}
+
+func (f *fakeDynamoDB) DescribeTable(_ context.Context, in *dynamodb.DescribeTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	table, ok := f.tables[aws.ToString(in.TableName)]
+	if !ok {
+		return nil, &dynamodbtypes.ResourceNotFoundException{Message: aws.String("table not found")}
+	}
+	return &dynamodb.DescribeTableOutput{Table: table}, nil
+}
+
+func planOf(resources ...*tfjson.StateResource) *tfjson.Plan {
+	return &tfjson.Plan{PlannedValues: &tfjson.StateValues{RootModule: &tfjson.StateModule{Resources: resources}}}
+}
+
+func planned(resourceType, name string, values map[string]interface{}) *tfjson.StateResource {
+	return &tfjson.StateResource{
+		Address:         resourceType + "." + name,
+		Mode:            tfjson.ManagedResourceMode,
+		Type:            resourceType,
+		Name:            name,
+		AttributeValues: values,
+	}
+}
+
+func TestVerifyComparesAppliedResourcesWithPlan(t *testing.T) {
+	clients := &Clients{
+		S3: &fakeS3{tags: map[string][]s3types.Tag{
+			"artifacts": {{Key: aws.String("Environment"), Value: aws.String("dev")}},
+		}},
+		IAM: &fakeIAM{roles: map[string]string{
+			"api-task": trustPolicy,
+			"drifted":  strings.Replace(trustPolicy, "ecs-tasks", "lambda", 1),
+		}},
+		DynamoDB: &fakeDynamoDB{tables: map[string]*dynamodbtypes.TableDescription{
+			"packages": {
+				TableStatus:        dynamodbtypes.TableStatusActive,
+				KeySchema:          []dynamodbtypes.KeySchemaElement{{AttributeName: aws.String("pkg_key"), KeyType: dynamodbtypes.KeyTypeHash}},
+				BillingModeSummary: &dynamodbtypes.BillingModeSummary{BillingMode: dynamodbtypes.BillingModePayPerRequest},
+			},
+		}},
+	}
+
+	errs := clients.Verify(context.Background(), planOf(
+		planned("aws_s3_bucket", "artifacts", map[string]interface{}{"bucket": "artifacts", "tags_all": map[string]interface{}{"Environment": "dev"}}),
+		planned("aws_iam_role", "api_task", map[string]interface{}{"name": "api-task", "path": "/", "assume_role_policy": trustPolicy}),
+		planned("aws_dynamodb_table", "packages", map[string]interface{}{"name": "packages", "hash_key": "pkg_key", "billing_mode": "PAY_PER_REQUEST"}),
+		planned("aws_iam_role", "computed", map[string]interface{}{}),
+	))
+	require.Empty(t, errs)
+
+	errs = clients.Verify(context.Background(), planOf(
+		planned("aws_s3_bucket", "logs", map[string]interface{}{"bucket": "logs"}),
+		planned("aws_s3_bucket", "artifacts", map[string]interface{}{"bucket": "artifacts", "tags": map[string]interface{}{"Environment": "prod"}}),
+		planned("aws_iam_role", "drifted", map[string]interface{}{"name": "drifted", "assume_role_policy": trustPolicy}),
+		planned("aws_dynamodb_table", "packages", map[string]interface{}{"name": "packages", "hash_key": "pkg_key", "range_key": "version"}),
+	))
+	require.Len(t, errs, 4)
+	require.ErrorContains(t, errs[0], "aws_s3_bucket.logs: bucket logs was not created")
+	require.ErrorContains(t, errs[1], `has tag Environment = "dev", planned "prod"`)
+	require.ErrorContains(t, errs[2], "aws_iam_role.drifted: role drifted has trust policy")
+	require.ErrorContains(t, errs[3], `has range_key "", planned "version"`)
+}
+
+func TestOverridePointsProviderAtEndpoint(t *testing.T) {
+	override := Override("http://127.0.0.1:49153")
+	require.Contains(t, override, `backend "local" {}`)
+	require.Contains(t, override, "skip_credentials_validation = true")
+	for _, service := range Services {
+		require.Contains(t, override, service+` = "http://127.0.0.1:49153"`)
+	}
+}
@@ -0,0 +1,79 @@
+package terraformtests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/localstack"
+	"cs450/terraformtests/plancheck"
+)
+
+// localStackEnv enables the LocalStack apply, which needs docker and
+// terraform and takes several minutes.
+const localStackEnv = "COMPLIANCE_LOCALSTACK"
+
+// A plan only shows what terraform will send; the provider can still reject
+// it at apply, e.g. a policy document the IAM API refuses. dev is applied to
+// a LocalStack container, its planned buckets, IAM roles and DynamoDB tables
+// are read back and compared with the plan, and everything is destroyed.
+func TestDevAppliesToLocalStack(t *testing.T) {
+	if os.Getenv(localStackEnv) == "" {
+		t.Skipf("set %s=1 to apply dev to a LocalStack container", localStackEnv)
+	}
+	skipWithoutPlanning(t)
+
+	config, err := plancheck.LoadConfig(complianceFile)
+	require.NoError(t, err)
+	env := testEnvironment(t, devEnvironment)
+
+	container, err := localstack.Start(t, config.LocalStack.ImageName(), 2*time.Minute)
+	require.NoError(t, err)
+	t.Logf("LocalStack %s listens on %s", config.LocalStack.ImageName(), container.Endpoint)
+
+	// The root module reaches its modules through ../../modules, so the
+	// whole infra tree is copied; the override goes into the copy only.
+	infra := filepath.Dir(filepath.Dir(filepath.Clean(env.Dir)))
+	rel, err := filepath.Rel(infra, filepath.Clean(env.Dir))
+	require.NoError(t, err)
+	copied, err := files.CopyTerraformFolderToTemp(infra, "tfcompliance-localstack-")
+	require.NoError(t, err)
+	cleanupArtifacts(t, copied)
+	dir := filepath.Join(copied, rel)
+	require.NoError(t, localstack.WriteOverride(dir, container.Endpoint))
+
+	options := environmentOptions(t, env)
+	options.TerraformDir = dir
+	options.Targets = config.LocalStack.Targets
+	options.EnvVars = map[string]string{"AWS_ACCESS_KEY_ID": "test", "AWS_SECRET_ACCESS_KEY": "test"}
+	options.PlanFilePath, err = planFilePath(devEnvironment + "-localstack")
+	require.NoError(t, err)
+	cleanupArtifacts(t, filepath.Dir(options.PlanFilePath))
+
+	plan, err := showPlanE(t, options)
+	require.NoError(t, err, "dev must plan against LocalStack")
+	defer func() {
+		// Destroy plans afresh, so it must not be handed the saved plan.
+		destroy := *options
+		destroy.PlanFilePath = ""
+		if _, err := terraform.DestroyE(t, &destroy); err != nil {
+			t.Errorf("destroying dev in LocalStack: %v", err)
+		}
+	}()
+	_, err = terraform.ApplyE(t, options)
+	require.NoError(t, err, "dev must apply to LocalStack")
+
+	clients, err := localstack.NewClients(container.Endpoint, env.Region)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	for _, err := range clients.Verify(ctx, plan) {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,48 @@
+package terraformtests
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/logdelivery"
+	"cs450/terraformtests/plancheck"
+)
+
+// After an apply, a request to each API stage with access logging must show
+// up in the stage's log group within log_delivery.timeout_seconds. The
+// request is found by the id API Gateway returns with the response, so the
+// check proves the whole pipeline delivers, not only that it is configured.
+func TestAccessLogsAreDelivered(t *testing.T) {
+	if os.Getenv(postApplyEnv) == "" {
+		t.Skipf("set %s=1 to check the deployed stages' access logs", postApplyEnv)
+	}
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		_, plan := environmentPlan(t, env.Name)
+		config, err := plancheck.LoadConfig(complianceFile)
+		require.NoError(t, err)
+
+		logs := logdelivery.AccessLogs(plan)
+		if len(logs) == 0 {
+			t.Skipf("the %s plan has no deployed API stage logging request ids to CloudWatch", env.Name)
+		}
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		clients, err := logdelivery.NewClients(env.Region, creds.AWS())
+		require.NoError(t, err)
+
+		for _, log := range logs {
+			log := log
+			t.Run(log.Address, func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), config.Logs.Timeout())
+				defer cancel()
+				delivery, err := clients.Verify(ctx, log, config.Logs.RequestPath())
+				require.NoError(t, err)
+				t.Logf("request %s reached %s/%s after %s", delivery.RequestID, log.LogGroup, delivery.LogStream, delivery.Latency)
+			})
+		}
+	})
+}
@@ -0,0 +1,175 @@
+// Package logdelivery checks that a deployed logging pipeline delivers: it
+// sends a request whose id it knows and waits for that id to show up where
+// the plan says the request is logged, rather than trusting the logging
+// configuration alone.
+package logdelivery
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/plancheck"
+)
+
+// RequestIDHeader is the response header API Gateway returns the request id
+// in, which $context.requestId writes to the access log.
+const RequestIDHeader = "x-amzn-RequestId"
+
+// LogsAPI searches access log groups.
+type LogsAPI interface {
+	FilterLogEvents(context.Context, *cloudwatchlogs.FilterLogEventsInput, ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error)
+}
+
+// Clients are the APIs sentinel requests are sent and looked up through.
+type Clients struct {
+	Logs LogsAPI
+	// HTTP sends sentinel requests. Nil means a client with a 10s timeout.
+	HTTP *http.Client
+
+	// PollInterval is how long to wait between log searches. Zero means
+	// five seconds.
+	PollInterval time.Duration
+}
+
+// NewClients returns a CloudWatch Logs client for the log groups in region,
+// signed with creds when set.
+func NewClients(region string, creds *credentials.Credentials) (*Clients, error) {
+	cfg, err := awsapi.Default().WithCredentials(creds).Config(context.Background(), region)
+	if err != nil {
+		return nil, fmt.Errorf("logdelivery: %w", err)
+	}
+	return &Clients{Logs: cloudwatchlogs.NewFromConfig(cfg)}, nil
+}
+
+func (c *Clients) httpClient() *http.Client {
+	if c.HTTP == nil {
+		return &http.Client{Timeout: 10 * time.Second}
+	}
+	return c.HTTP
+}
+
+func (c *Clients) pollInterval() time.Duration {
+	if c.PollInterval == 0 {
+		return 5 * time.Second
+	}
+	return c.PollInterval
+}
+
+// AccessLog is an API Gateway stage that writes access logs to a CloudWatch
+// log group.
+type AccessLog struct {
+	// Address is the aws_api_gateway_stage.
+	Address   string
+	InvokeURL string
+	LogGroup  string
+}
+
+// AccessLogs returns the API Gateway stages of a plan with access logging to
+// CloudWatch whose format records the request id. Stages not deployed yet,
+// which have no invoke URL, are left out.
+func AccessLogs(plan *tfjson.Plan) []AccessLog {
+	var logs []AccessLog
+	for _, stage := range plancheck.Resources(plan, "aws_api_gateway_stage") {
+		invokeURL := plancheck.LookupString(stage.AttributeValues, "invoke_url")
+		for _, settings := range plancheck.Blocks(stage.AttributeValues, "access_log_settings") {
+			group := logGroupName(plancheck.LookupString(settings, "destination_arn"))
+			format := plancheck.LookupString(settings, "format")
+			if invokeURL == "" || group == "" || !strings.Contains(format, "$context.requestId") {
+				continue
+			}
+			logs = append(logs, AccessLog{Address: stage.Address, InvokeURL: invokeURL, LogGroup: group})
+		}
+	}
+	return logs
+}
+
+// logGroupName returns the name in a CloudWatch log group ARN, or "" for
+// other destinations such as Kinesis Firehose.
+func logGroupName(arn string) string {
+	const marker = ":log-group:"
+	i := strings.Index(arn, marker)
+	if !strings.HasPrefix(arn, "arn:") || i < 0 {
+		return ""
+	}
+	return strings.TrimSuffix(arn[i+len(marker):], ":*")
+}
+
+// Delivery describes a sentinel request found in its log.
+type Delivery struct {
+	RequestID string
+	LogStream string
+	// Latency is how long after the request was sent it was found.
+	Latency time.Duration
+}
+
+// Verify requests path on the stage and waits until its request id appears
+// in the access log group, or ctx is done. Any response status will do:
+// API Gateway logs rejected requests too.
+func (c *Clients) Verify(ctx context.Context, log AccessLog, path string) (Delivery, error) {
+	url := strings.TrimSuffix(log.InvokeURL, "/") + "/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("logdelivery: %w", err)
+	}
+	sent := time.Now()
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("logdelivery: %s: %w", log.Address, err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	delivery := Delivery{RequestID: resp.Header.Get(RequestIDHeader)}
+	if delivery.RequestID == "" {
+		return delivery, fmt.Errorf("logdelivery: %s: GET %s answered %d without a %s header", log.Address, url, resp.StatusCode, RequestIDHeader)
+	}
+
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName:  aws.String(log.LogGroup),
+		FilterPattern: aws.String(`"` + delivery.RequestID + `"`),
+		// Allow for clock skew between this host and CloudWatch.
+		StartTime: aws.Int64(sent.Add(-time.Minute).UnixMilli()),
+	}
+	var searchErr error
+	err = awsapi.Poll(ctx, c.pollInterval(), "request "+delivery.RequestID+" in "+log.LogGroup, func() (bool, error) {
+		stream, err := c.find(ctx, input)
+		if err != nil {
+			searchErr = fmt.Errorf("logdelivery: searching %s for request %s: %w", log.LogGroup, delivery.RequestID, err)
+			return false, searchErr
+		}
+		delivery.LogStream = stream
+		return stream != "", nil
+	})
+	switch {
+	case searchErr != nil:
+		return delivery, searchErr
+	case err != nil:
+		return delivery, fmt.Errorf("logdelivery: request %s to %s was not logged to %s after %s: %w", delivery.RequestID, log.Address, log.LogGroup, time.Since(sent).Round(time.Second), err)
+	}
+	delivery.Latency = time.Since(sent)
+	return delivery, nil
+}
+
+// find returns the log stream of the first event matching input, or "".
+func (c *Clients) find(ctx context.Context, input *cloudwatchlogs.FilterLogEventsInput) (string, error) {
+	pages := cloudwatchlogs.NewFilterLogEventsPaginator(c.Logs, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return "", err
+		}
+		if len(page.Events) > 0 {
+			return aws.ToString(page.Events[0].LogStreamName), nil
+		}
+	}
+	return "", nil
+}
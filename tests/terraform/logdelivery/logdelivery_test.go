@@ -0,0 +1,105 @@
+package logdelivery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLogs finds the sentinel on the given search, counting from one.
+type fakeLogs struct {
+	LogsAPI
+	foundOn  int
+	searches []*cloudwatchlogs.FilterLogEventsInput
+}
+
+func (f *fakeLogs) FilterLogEvents(_ context.Context, in *cloudwatchlogs.FilterLogEventsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	f.searches = append(f.searches, in)
+	page := &cloudwatchlogs.FilterLogEventsOutput{}
+	if f.foundOn > 0 && len(f.searches) >= f.foundOn {
+		page.Events = []types.FilteredLogEvent{{LogStreamName: aws.String("stream-1"), Message: aws.String(`{"requestId":"req-1"}`)}}
+	}
+	return page, nil
+}
+
+func stageServer(t *testing.T, requestID string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/prod/health", r.URL.Path)
+		if requestID != "" {
+			w.Header().Set(RequestIDHeader, requestID)
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVerifyWaitsForRequestID(t *testing.T) {
+	server := stageServer(t, "req-1")
+	logs := &fakeLogs{foundOn: 3}
+	clients := &Clients{Logs: logs, PollInterval: time.Millisecond}
+
+	delivery, err := clients.Verify(context.Background(), AccessLog{Address: "aws_api_gateway_stage.main", InvokeURL: server.URL + "/prod/", LogGroup: "/aws/apigateway/abc/prod"}, "/health")
+	require.NoError(t, err)
+	require.Equal(t, "req-1", delivery.RequestID)
+	require.Equal(t, "stream-1", delivery.LogStream)
+	require.Len(t, logs.searches, 3)
+	require.Equal(t, `"req-1"`, aws.ToString(logs.searches[0].FilterPattern))
+	require.Equal(t, "/aws/apigateway/abc/prod", aws.ToString(logs.searches[0].LogGroupName))
+}
+
+func TestVerifyGivesUpWhenNothingIsLogged(t *testing.T) {
+	server := stageServer(t, "req-1")
+	clients := &Clients{Logs: &fakeLogs{}, PollInterval: 50 * time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := clients.Verify(ctx, AccessLog{Address: "aws_api_gateway_stage.main", InvokeURL: server.URL + "/prod", LogGroup: "/aws/apigateway/abc/prod"}, "health")
+	require.ErrorContains(t, err, "logdelivery: request req-1 to aws_api_gateway_stage.main was not logged to /aws/apigateway/abc/prod after")
+}
+
+func TestVerifyNeedsRequestID(t *testing.T) {
+	server := stageServer(t, "")
+	clients := &Clients{Logs: &fakeLogs{}}
+
+	_, err := clients.Verify(context.Background(), AccessLog{Address: "aws_api_gateway_stage.main", InvokeURL: server.URL + "/prod"}, "/health")
+	require.EqualError(t, err, "logdelivery: aws_api_gateway_stage.main: GET "+server.URL+"/prod/health answered 403 without a x-amzn-RequestId header")
+}
+
+func TestAccessLogsNeedRequestIDInFormat(t *testing.T) {
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(`{
+	  "format_version": "1.2",
+	  "planned_values": {"root_module": {"resources": [
+	    {"address": "aws_api_gateway_stage.main", "type": "aws_api_gateway_stage", "name": "main", "values": {
+	      "invoke_url": "https://abc.execute-api.us-east-1.amazonaws.com/prod",
+	      "access_log_settings": [{"destination_arn": "arn:aws:logs:us-east-1:123456789012:log-group:/aws/apigateway/abc/prod", "format": "{\"requestId\":\"$context.requestId\"}"}]
+	    }},
+	    {"address": "aws_api_gateway_stage.no_id", "type": "aws_api_gateway_stage", "name": "no_id", "values": {
+	      "invoke_url": "https://def.execute-api.us-east-1.amazonaws.com/prod",
+	      "access_log_settings": [{"destination_arn": "arn:aws:logs:us-east-1:123456789012:log-group:/aws/apigateway/def/prod:*", "format": "$context.status"}]
+	    }},
+	    {"address": "aws_api_gateway_stage.firehose", "type": "aws_api_gateway_stage", "name": "firehose", "values": {
+	      "invoke_url": "https://ghi.execute-api.us-east-1.amazonaws.com/prod",
+	      "access_log_settings": [{"destination_arn": "arn:aws:firehose:us-east-1:123456789012:deliverystream/amazon-apigateway-logs", "format": "$context.requestId"}]
+	    }},
+	    {"address": "aws_api_gateway_stage.unlogged", "type": "aws_api_gateway_stage", "name": "unlogged", "values": {"invoke_url": "https://jkl.execute-api.us-east-1.amazonaws.com/prod"}}
+	  ]}}
+	}`), &plan))
+
+	require.Equal(t, []AccessLog{{
+		Address:   "aws_api_gateway_stage.main",
+		InvokeURL: "https://abc.execute-api.us-east-1.amazonaws.com/prod",
+		LogGroup:  "/aws/apigateway/abc/prod",
+	}}, AccessLogs(&plan))
+	require.Equal(t, "/aws/apigateway/def/prod", logGroupName("arn:aws:logs:us-east-1:123456789012:log-group:/aws/apigateway/def/prod:*"))
+}
@@ -0,0 +1,41 @@
+package terraformtests
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// TestMain removes the shared plan files after every test has run. They
+// outlive the test that planned them because the options every later test
+// gets from environmentPlan point at the same plan file. It releases the
+// run locks taken while planning, and then writes the findings of the whole
+// run to the files COMPLIANCE_SARIF and COMPLIANCE_JUNIT name, and the run's
+// manifest to the file COMPLIANCE_MANIFEST names.
+func TestMain(m *testing.M) {
+	code := m.Run()
+	removeSharedArtifacts()
+	if !releaseLocks() {
+		code = 1
+	}
+
+	written, err := runFindings.WriteFiles(os.Getenv(plancheck.SARIFEnv), os.Getenv(plancheck.JUnitEnv), repoRoot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "writing compliance reports: %v\n", err)
+		code = 1
+	}
+	for _, filename := range written {
+		fmt.Printf("compliance report written: %s\n", filename)
+	}
+	if filename := os.Getenv(plancheck.ManifestEnv); filename != "" {
+		if err := writeManifest(filename); err != nil {
+			fmt.Fprintf(os.Stderr, "writing the run manifest: %v\n", err)
+			code = 1
+		} else {
+			fmt.Printf("run manifest written: %s\n", filename)
+		}
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,135 @@
+package terraformtests
+
+import (
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// runManifest records the plans and rules of the run for the manifest
+// TestMain writes to COMPLIANCE_MANIFEST.
+var runManifest manifestRecorder
+
+type manifestRecorder struct {
+	mu        sync.Mutex
+	plans     map[string]*tfjson.Plan
+	dirs      map[string]string
+	rules     map[string]plancheck.Rule
+	terraform string
+}
+
+// addPlan records the shared plan of environment, made from dir.
+func (r *manifestRecorder) addPlan(environment, dir string, plan *tfjson.Plan) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.plans == nil {
+		r.plans, r.dirs = map[string]*tfjson.Plan{}, map[string]string{}
+	}
+	r.plans[environment], r.dirs[environment] = plan, dir
+	if r.terraform == "" {
+		r.terraform = plan.TerraformVersion
+	}
+}
+
+// addRules records rules as evaluated.
+func (r *manifestRecorder) addRules(rules []plancheck.Rule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.rules == nil {
+		r.rules = map[string]plancheck.Rule{}
+	}
+	for _, rule := range rules {
+		r.rules[rule.ID] = rule
+	}
+}
+
+// manifest describes the run so far: the commit checked out, the recorded
+// plans with the provider versions their lock files select, the rules
+// evaluated and a digest of the findings collected.
+func (r *manifestRecorder) manifest(findings []plancheck.Finding) (plancheck.Manifest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	commit, dirty := gitCommit()
+	m := plancheck.Manifest{
+		Created:          time.Now().UTC().Truncate(time.Second),
+		Commit:           commit,
+		Dirty:            dirty,
+		TerraformVersion: r.terraform,
+		PackVersion:      plancheck.PackVersion(),
+		Plans:            []plancheck.ManifestPlan{},
+		Signer:           os.Getenv(plancheck.ManifestSignerEnv),
+	}
+	if m.Signer == "" {
+		m.Signer = os.Getenv("GITHUB_WORKFLOW_REF")
+	}
+
+	environments := make([]string, 0, len(r.plans))
+	for environment := range r.plans {
+		environments = append(environments, environment)
+	}
+	sort.Strings(environments)
+	for _, environment := range environments {
+		sum, err := plancheck.PlanDigest(r.plans[environment])
+		if err != nil {
+			return m, err
+		}
+		providers, err := plancheck.LockedProviders(r.dirs[environment])
+		if err != nil {
+			return m, err
+		}
+		m.Plans = append(m.Plans, plancheck.ManifestPlan{Environment: environment, SHA256: sum, Providers: providers})
+	}
+
+	rules := make([]plancheck.Rule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	m.Rules = plancheck.RuleDigests(rules)
+
+	var err error
+	m.Findings, err = plancheck.FindingsDigest(findings)
+	return m, err
+}
+
+// gitCommit returns the commit the run checked, preferring GITHUB_SHA, and
+// whether the working tree had changes on top of it.
+func gitCommit() (string, bool) {
+	commit := os.Getenv("GITHUB_SHA")
+	if commit == "" {
+		out, err := exec.Command("git", "-C", repoRoot, "rev-parse", "HEAD").Output()
+		if err != nil {
+			return "", false
+		}
+		commit = strings.TrimSpace(string(out))
+	}
+	status, err := exec.Command("git", "-C", repoRoot, "status", "--porcelain").Output()
+	return commit, err == nil && len(strings.TrimSpace(string(status))) > 0
+}
+
+// writeManifest writes the run's manifest to filename, signed with the key
+// COMPLIANCE_MANIFEST_KEY names when it is set.
+func writeManifest(filename string) error {
+	m, err := runManifest.manifest(runFindings.Findings())
+	if err != nil {
+		return err
+	}
+	if keyFile := os.Getenv(plancheck.ManifestKeyEnv); keyFile != "" {
+		key, err := plancheck.LoadSigningKey(keyFile)
+		if err != nil {
+			return err
+		}
+		if err := m.Sign(key); err != nil {
+			return err
+		}
+	}
+	return plancheck.WriteManifest(filename, m)
+}
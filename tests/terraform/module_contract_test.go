@@ -0,0 +1,141 @@
+package terraformtests
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/modulecontract"
+	"cs450/terraformtests/plancheck"
+)
+
+const (
+	moduleContractsFile = "module_contracts.yaml"
+	modulesDir          = "../../infra/modules"
+)
+
+// TestModulesHonorContracts checks every local module against its contract in
+// module_contracts.yaml, then plans it in isolation with the contract's
+// fixture to check the outputs callers read exist with the declared types.
+func TestModulesHonorContracts(t *testing.T) {
+	contracts, err := modulecontract.Load(moduleContractsFile)
+	require.NoError(t, err, "module contracts must load")
+
+	entries, err := os.ReadDir(modulesDir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			contract, ok := contracts[name]
+			require.True(t, ok, "%s has no contract in %s", name, moduleContractsFile)
+			dir := filepath.Join(modulesDir, name)
+
+			module, err := plancheck.LoadModule(dir)
+			require.NoError(t, err)
+			for _, problem := range contract.Check(module) {
+				t.Errorf("%s: %s", dir, problem)
+			}
+			if t.Failed() || testing.Short() {
+				return
+			}
+
+			plan := planModule(t, name, dir, contract)
+			for _, problem := range contract.CheckPlan(plan) {
+				t.Errorf("%s planned in isolation: %s", dir, problem)
+			}
+		})
+	}
+}
+
+// planModule plans the module at dir from a generated root configuration
+// that sets only the contract's fixture.
+func planModule(t *testing.T, name, dir string, contract modulecontract.Contract) *tfjson.Plan {
+	t.Helper()
+
+	wrapper, err := contract.Wrapper(dir, devDefaultRegion)
+	require.NoError(t, err)
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.tf"), []byte(wrapper), 0o644))
+
+	_, plan := planRoot(t, "module-"+name, root)
+	return plan
+}
+
+// planRoot plans the root configuration in dir with the dev role's
+// credentials, failing the test if it does not plan. name keeps the plan
+// file apart from other plans.
+func planRoot(t *testing.T, name, dir string) (*terraform.Options, *tfjson.Plan) {
+	t.Helper()
+	skipWithoutPlanning(t)
+
+	creds, err := roleCredentials(devEnvironment)
+	require.NoError(t, err)
+	planFile, err := planFilePath(name)
+	require.NoError(t, err)
+	cleanupArtifacts(t, filepath.Dir(planFile))
+
+	options := &terraform.Options{
+		TerraformDir: dir,
+		EnvVars:      creds.Env(),
+		PlanFilePath: planFile,
+		NoColor:      true,
+	}
+	plan, err := showPlanE(t, options)
+	require.NoError(t, err, "%s must plan", dir)
+	return options, plan
+}
+
+// TestModulesRejectInvalidInputs plans each module with the invalid inputs
+// listed under rejects in module_contracts.yaml and checks plan fails with the
+// expected validation error, so validation blocks guard what they claim to.
+func TestModulesRejectInvalidInputs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("planning modules needs AWS credentials")
+	}
+	skipWithoutPlanning(t)
+	contracts, err := modulecontract.Load(moduleContractsFile)
+	require.NoError(t, err, "module contracts must load")
+
+	names := make([]string, 0, len(contracts))
+	for name := range contracts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	creds, err := roleCredentials(devEnvironment)
+	require.NoError(t, err)
+	for _, name := range names {
+		contract := contracts[name]
+		dir := filepath.Join(modulesDir, name)
+		for _, reject := range contract.Rejects {
+			reject := reject
+			t.Run(name+"/"+reject.Name, func(t *testing.T) {
+				wrapper, err := contract.WithInputs(reject.Inputs).Wrapper(dir, devDefaultRegion)
+				require.NoError(t, err)
+				root := t.TempDir()
+				require.NoError(t, os.WriteFile(filepath.Join(root, "main.tf"), []byte(wrapper), 0o644))
+
+				_, err = terraform.InitAndPlanE(t, &terraform.Options{
+					TerraformDir: root,
+					EnvVars:      creds.Env(),
+					NoColor:      true,
+				})
+				require.Error(t, err, "%s must refuse %v", dir, reject.Inputs)
+				require.Truef(t, reject.Matches(err.Error()), "%s must refuse %v with %q, got: %v", dir, reject.Inputs, reject.Error, err)
+			})
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package terraformtests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/modulecontract"
+)
+
+// exampleRules apply to any configuration, whatever environment it is
+// deployed to, so module examples must pass them too.
+var exampleRules = []string{
+	"iam.wildcard-action",
+	"iam.wildcard-resource",
+	"iam.passrole-scope",
+	"logs.retention",
+	"ec2.imdsv2",
+	"lambda.tracing",
+	"lambda.runtime",
+	"lambda.environment-secrets",
+	"provider.deprecations",
+	"variables.contract",
+}
+
+// TestModuleExamplesComply plans every infra/modules/<module>/examples/<name>
+// configuration and runs exampleRules against it, so modules stay usable on
+// their own and examples keep up with the modules they show.
+func TestModuleExamplesComply(t *testing.T) {
+	if testing.Short() {
+		t.Skip("planning module examples needs AWS credentials")
+	}
+	examples, err := modulecontract.Examples(modulesDir)
+	require.NoError(t, err)
+	if len(examples) == 0 {
+		t.Skipf("no examples under %s", modulesDir)
+	}
+
+	for _, example := range examples {
+		example := example
+		t.Run(example.String(), func(t *testing.T) {
+			options, plan := planRoot(t, "example-"+strings.ReplaceAll(example.String(), "/", "-"), example.Dir)
+			findings := evaluateRules(t, plan, options, devEnvironment, exampleRules...)
+			requireNoFindings(t, plan, findings)
+		})
+	}
+}
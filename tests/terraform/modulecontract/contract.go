@@ -0,0 +1,336 @@
+// Package modulecontract checks the local modules under infra/modules against
+// the interface their callers rely on: the inputs they accept, which of them
+// are required, and the outputs they provide, with types.
+package modulecontract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"gopkg.in/yaml.v3"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// Contract is the interface of one module.
+type Contract struct {
+	// Inputs are the variables callers may set.
+	Inputs map[string]Input `yaml:"inputs"`
+	// Outputs maps the outputs callers read to their types, written as in
+	// a variable's type argument, e.g. "map(string)".
+	Outputs map[string]string `yaml:"outputs"`
+	// Fixture holds the values the module is planned with in isolation. It
+	// must set every required input.
+	Fixture map[string]interface{} `yaml:"fixture"`
+	// Rejects are inputs the module's validation blocks must refuse.
+	Rejects []Reject `yaml:"rejects"`
+}
+
+// Reject is an invalid input the module must refuse at plan time.
+type Reject struct {
+	Name string `yaml:"name"`
+	// Inputs override the fixture.
+	Inputs map[string]interface{} `yaml:"inputs"`
+	// Error is the part of the validation error message plan must print.
+	Error string `yaml:"error"`
+}
+
+// Matches reports whether terraform output contains the expected error.
+// Terraform wraps diagnostics in a box and across lines, so both are
+// normalised to single spaces first.
+func (r Reject) Matches(output string) bool {
+	return strings.Contains(normalise(output), normalise(r.Error))
+}
+
+func normalise(text string) string {
+	return strings.Join(strings.Fields(strings.NewReplacer("│", " ", "╷", " ", "╵", " ").Replace(text)), " ")
+}
+
+// Input is a variable of the module's interface.
+type Input struct {
+	Type     string `yaml:"type"`
+	Required bool   `yaml:"required,omitempty"`
+}
+
+// Load reads contracts keyed by module directory name, relative to the
+// modules directory.
+func Load(filename string) (map[string]Contract, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Modules map[string]Contract `yaml:"modules"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	for name, contract := range file.Modules {
+		if err := contract.validate(); err != nil {
+			return nil, fmt.Errorf("%s: module %s: %w", filename, name, err)
+		}
+	}
+	return file.Modules, nil
+}
+
+func (c Contract) validate() error {
+	for name, input := range c.Inputs {
+		if _, err := parseType(input.Type); err != nil {
+			return fmt.Errorf("input %s: %w", name, err)
+		}
+		if _, ok := c.Fixture[name]; input.Required && !ok {
+			return fmt.Errorf("fixture does not set required input %s", name)
+		}
+	}
+	for name, typ := range c.Outputs {
+		if _, err := parseType(typ); err != nil {
+			return fmt.Errorf("output %s: %w", name, err)
+		}
+	}
+	for name := range c.Fixture {
+		if _, ok := c.Inputs[name]; !ok {
+			return fmt.Errorf("fixture sets %s, which is not an input", name)
+		}
+	}
+	for _, reject := range c.Rejects {
+		if reject.Name == "" || reject.Error == "" || len(reject.Inputs) == 0 {
+			return fmt.Errorf("reject %q needs a name, inputs and an error", reject.Name)
+		}
+		for name := range reject.Inputs {
+			if _, ok := c.Inputs[name]; !ok {
+				return fmt.Errorf("reject %s sets %s, which is not an input", reject.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// WithInputs returns a copy of the contract whose fixture has inputs
+// overriding its own values, such as a Reject's.
+func (c Contract) WithInputs(inputs map[string]interface{}) Contract {
+	fixture := make(map[string]interface{}, len(c.Fixture)+len(inputs))
+	for name, value := range c.Fixture {
+		fixture[name] = value
+	}
+	for name, value := range inputs {
+		fixture[name] = value
+	}
+	c.Fixture = fixture
+	return c
+}
+
+func parseType(source string) (cty.Type, error) {
+	expr, diags := hclsyntax.ParseExpression([]byte(source), "type", hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.NilType, diags
+	}
+	typ, diags := typeexpr.TypeConstraint(expr)
+	if diags.HasErrors() {
+		return cty.NilType, diags
+	}
+	return typ, nil
+}
+
+// Check compares the module's declarations with the contract and returns one
+// problem per difference. Required variables missing from the contract are
+// problems too, since callers following the contract would not set them, as
+// are rejected inputs without a validation block to reject them.
+func (c Contract) Check(module *plancheck.Module) []string {
+	if module == nil {
+		return []string{"module has no .tf files"}
+	}
+	variables := map[string]*hclsyntax.Block{}
+	outputs := map[string]bool{}
+	for _, file := range module.Files {
+		for _, block := range file.Variables() {
+			variables[block.Labels[0]] = block
+		}
+		for _, block := range file.Body.Blocks {
+			if block.Type == "output" && len(block.Labels) == 1 {
+				outputs[block.Labels[0]] = true
+			}
+		}
+	}
+
+	var problems []string
+	for _, name := range sortedKeys(c.Inputs) {
+		input := c.Inputs[name]
+		block, ok := variables[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("input %s is not declared", name))
+			continue
+		}
+		want, _ := parseType(input.Type)
+		if got := declaredType(block); !got.Equals(want) {
+			problems = append(problems, fmt.Sprintf("input %s has type %s, want %s", name, typeexpr.TypeString(got), input.Type))
+		}
+		if _, hasDefault := block.Body.Attributes["default"]; hasDefault == input.Required {
+			if input.Required {
+				problems = append(problems, fmt.Sprintf("input %s has a default but the contract requires callers to set it", name))
+			} else {
+				problems = append(problems, fmt.Sprintf("input %s has no default, so callers that omit it break", name))
+			}
+		}
+	}
+	validated := map[string]bool{}
+	for _, reject := range c.Rejects {
+		for _, name := range sortedKeys(reject.Inputs) {
+			block, ok := variables[name]
+			if !ok || validated[name] {
+				continue
+			}
+			validated[name] = true
+			if !hasValidation(block) {
+				problems = append(problems, fmt.Sprintf("input %s has rejects but no validation block", name))
+			}
+		}
+	}
+	for _, name := range sortedKeys(variables) {
+		if _, ok := c.Inputs[name]; ok {
+			continue
+		}
+		if _, hasDefault := variables[name].Body.Attributes["default"]; !hasDefault {
+			problems = append(problems, fmt.Sprintf("variable %s is required but not in the contract", name))
+		}
+	}
+	for _, name := range sortedKeys(c.Outputs) {
+		if !outputs[name] {
+			problems = append(problems, fmt.Sprintf("output %s is not declared", name))
+		}
+	}
+	return problems
+}
+
+func hasValidation(block *hclsyntax.Block) bool {
+	for _, nested := range block.Body.Blocks {
+		if nested.Type == "validation" {
+			return true
+		}
+	}
+	return false
+}
+
+// declaredType returns a variable's type constraint; variables without one
+// accept any type.
+func declaredType(block *hclsyntax.Block) cty.Type {
+	attr, ok := block.Body.Attributes["type"]
+	if !ok {
+		return cty.DynamicPseudoType
+	}
+	typ, diags := typeexpr.TypeConstraint(attr.Expr)
+	if diags.HasErrors() {
+		return cty.DynamicPseudoType
+	}
+	return typ
+}
+
+// Wrapper returns a root module that calls the module at dir with the
+// fixture and re-exports the contract's outputs, so the module can be
+// planned in isolation. region configures the aws provider.
+func (c Contract) Wrapper(dir, region string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "provider \"aws\" {\n  region = %q\n}\n\n", region)
+	fmt.Fprintf(&b, "module \"under_test\" {\n  source = %q\n", filepath.ToSlash(abs))
+	for _, name := range sortedKeys(c.Fixture) {
+		value, err := json.Marshal(c.Fixture[name])
+		if err != nil {
+			return "", fmt.Errorf("fixture %s: %w", name, err)
+		}
+		// JSON values are valid HCL expressions.
+		fmt.Fprintf(&b, "  %s = %s\n", name, value)
+	}
+	b.WriteString("}\n")
+	for _, name := range sortedKeys(c.Outputs) {
+		fmt.Fprintf(&b, "\noutput %q {\n  value = module.under_test.%s\n}\n", name, name)
+	}
+	return b.String(), nil
+}
+
+// CheckPlan verifies that a plan of the wrapper has every contract output
+// and that the outputs known at plan time have the contract's types.
+func (c Contract) CheckPlan(plan *tfjson.Plan) []string {
+	return checkPlannedOutputs(plan, c.Outputs, false)
+}
+
+// checkPlannedOutputs checks the plan has each output of types, and that
+// those known at plan time have its type and, with nonEmpty, a value other
+// than null, "" or an empty collection.
+func checkPlannedOutputs(plan *tfjson.Plan, types map[string]string, nonEmpty bool) []string {
+	var planned map[string]*tfjson.StateOutput
+	if plan.PlannedValues != nil {
+		planned = plan.PlannedValues.Outputs
+	}
+
+	var problems []string
+	for _, name := range sortedKeys(types) {
+		_, changed := plan.OutputChanges[name]
+		output, known := planned[name]
+		if !changed && !known {
+			problems = append(problems, fmt.Sprintf("output %s is missing from the plan", name))
+			continue
+		}
+		if !known {
+			continue
+		}
+		if output.Value == nil {
+			if nonEmpty {
+				problems = append(problems, fmt.Sprintf("output %s is null", name))
+			}
+			continue
+		}
+		data, err := json.Marshal(output.Value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("output %s: %v", name, err))
+			continue
+		}
+		want, _ := parseType(types[name])
+		value, err := ctyjson.Unmarshal(data, want)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("output %s is not a %s: %v", name, types[name], err))
+			continue
+		}
+		if nonEmpty && isEmpty(value) {
+			problems = append(problems, fmt.Sprintf("output %s is empty", name))
+		}
+	}
+	return problems
+}
+
+// isEmpty reports whether value is an empty string or collection.
+func isEmpty(value cty.Value) bool {
+	switch {
+	case value.IsNull():
+		return true
+	case value.Type() == cty.String:
+		return value.AsString() == ""
+	case value.CanIterateElements():
+		return value.LengthInt() == 0
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,163 @@
+package modulecontract
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+const contractsYAML = `
+modules:
+  bucket:
+    inputs:
+      name: {type: string, required: true}
+      tags: {type: map(string)}
+    outputs:
+      arn: string
+      endpoints: map(string)
+    fixture:
+      name: contract
+    rejects:
+      - name: uppercase
+        inputs: {name: Contract}
+        error: name must be a valid S3 bucket name.
+`
+
+const moduleTF = `variable "name" {
+  type = string
+}
+
+variable "tags" {
+  type    = list(string)
+  default = []
+}
+
+variable "owner" {
+  type = string
+}
+
+output "arn" {
+  value = "arn:aws:s3:::${var.name}"
+}
+`
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	require.NoError(t, os.WriteFile(name, []byte(content), 0o644))
+	return name
+}
+
+func loadBucket(t *testing.T) Contract {
+	t.Helper()
+	contracts, err := Load(writeFile(t, filepath.Join(t.TempDir(), "contracts.yaml"), contractsYAML))
+	require.NoError(t, err)
+	return contracts["bucket"]
+}
+
+func TestLoadRejectsInvalidContracts(t *testing.T) {
+	for name, content := range map[string]string{
+		"bad type":          "modules:\n  m:\n    inputs:\n      a: {type: strng}\n",
+		"fixture missing":   "modules:\n  m:\n    inputs:\n      a: {type: string, required: true}\n",
+		"fixture unknown":   "modules:\n  m:\n    fixture:\n      a: x\n",
+		"bad output type":   "modules:\n  m:\n    outputs:\n      a: list(\n",
+		"unknown attribute": "modules:\n  m:\n    input: {}\n",
+		"reject no error":   "modules:\n  m:\n    inputs:\n      a: {type: string}\n    rejects:\n      - {name: r, inputs: {a: x}}\n",
+		"reject unknown":    "modules:\n  m:\n    rejects:\n      - {name: r, inputs: {a: x}, error: e}\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load(writeFile(t, filepath.Join(t.TempDir(), "contracts.yaml"), content))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCheckReportsInterfaceDifferences(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.tf"), moduleTF)
+	module, err := plancheck.LoadModule(dir)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"input tags has type list(string), want map(string)",
+		"input name has rejects but no validation block",
+		"variable owner is required but not in the contract",
+		"output endpoints is not declared",
+	}, loadBucket(t).Check(module))
+}
+
+func TestWrapperCallsModuleWithFixture(t *testing.T) {
+	wrapper, err := loadBucket(t).Wrapper("/modules/bucket", "us-east-1")
+	require.NoError(t, err)
+	require.Equal(t, `provider "aws" {
+  region = "us-east-1"
+}
+
+module "under_test" {
+  source = "/modules/bucket"
+  name = "contract"
+}
+
+output "arn" {
+  value = module.under_test.arn
+}
+
+output "endpoints" {
+  value = module.under_test.endpoints
+}
+`, wrapper)
+}
+
+func TestCheckPlanTypesKnownOutputs(t *testing.T) {
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "format_version": "1.0",
+  "planned_values": {
+    "outputs": {
+      "arn": {"sensitive": false},
+      "endpoints": {"sensitive": false, "value": ["https://example.com"]}
+    },
+    "root_module": {}
+  },
+  "output_changes": {
+    "arn": {"actions": ["create"], "after_unknown": true}
+  }
+}`), &plan))
+
+	problems := loadBucket(t).CheckPlan(&plan)
+	require.Len(t, problems, 1)
+	require.Contains(t, problems[0], "output endpoints is not a map(string)")
+
+	plan.PlannedValues.Outputs["endpoints"].Value = map[string]interface{}{"health": "https://example.com/health"}
+	require.Empty(t, loadBucket(t).CheckPlan(&plan))
+
+	delete(plan.PlannedValues.Outputs, "arn")
+	delete(plan.OutputChanges, "arn")
+	require.Equal(t, []string{"output arn is missing from the plan"}, loadBucket(t).CheckPlan(&plan))
+}
+
+func TestWithInputsOverridesFixture(t *testing.T) {
+	contract := loadBucket(t)
+	reject := contract.WithInputs(contract.Rejects[0].Inputs)
+	require.Equal(t, map[string]interface{}{"name": "Contract"}, reject.Fixture)
+	require.Equal(t, map[string]interface{}{"name": "contract"}, contract.Fixture, "the contract's own fixture must not change")
+}
+
+func TestRejectMatchesWrappedDiagnostics(t *testing.T) {
+	reject := loadBucket(t).Rejects[0]
+	require.True(t, reject.Matches(`
+│ Error: Invalid value for variable
+│
+│   on main.tf line 7, in module "under_test":
+│    7:   name = "Contract"
+│
+│ name must be a valid S3
+│ bucket name.
+╵`))
+	require.False(t, reject.Matches("Error: No valid credential sources found"))
+}
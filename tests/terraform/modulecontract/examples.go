@@ -0,0 +1,58 @@
+package modulecontract
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Example is a root configuration under a module's examples directory that
+// shows the module used on its own.
+type Example struct {
+	// Module is the module's directory name.
+	Module string
+	// Name is the example's directory name.
+	Name string
+	// Dir is the example's path.
+	Dir string
+}
+
+// String returns "module/name".
+func (e Example) String() string {
+	return e.Module + "/" + e.Name
+}
+
+// Examples returns every directory with .tf files directly under
+// <module>/examples in modulesDir, ordered by module and name.
+func Examples(modulesDir string) ([]Example, error) {
+	dirs, err := filepath.Glob(filepath.Join(modulesDir, "*", "examples", "*"))
+	if err != nil {
+		return nil, err
+	}
+	var examples []Example
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			continue
+		}
+		sources, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+		if err != nil {
+			return nil, err
+		}
+		if len(sources) == 0 {
+			continue
+		}
+		examples = append(examples, Example{
+			Module: filepath.Base(filepath.Dir(filepath.Dir(dir))),
+			Name:   filepath.Base(dir),
+			Dir:    dir,
+		})
+	}
+	sort.Slice(examples, func(i, j int) bool {
+		return examples[i].String() < examples[j].String()
+	})
+	return examples, nil
+}
@@ -0,0 +1,28 @@
+package modulecontract
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExamplesFindsRootConfigurations(t *testing.T) {
+	modules := t.TempDir()
+	for _, dir := range []string{"s3/examples/basic", "s3/examples/empty", "iam/examples/with-tables", "ecs"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(modules, dir), 0o755))
+	}
+	writeFile(t, filepath.Join(modules, "s3/examples/basic/main.tf"), "")
+	writeFile(t, filepath.Join(modules, "s3/examples/README.md"), "")
+	writeFile(t, filepath.Join(modules, "s3/examples/empty/notes.txt"), "")
+	writeFile(t, filepath.Join(modules, "iam/examples/with-tables/main.tf"), "")
+
+	examples, err := Examples(modules)
+	require.NoError(t, err)
+	require.Equal(t, []Example{
+		{Module: "iam", Name: "with-tables", Dir: filepath.Join(modules, "iam/examples/with-tables")},
+		{Module: "s3", Name: "basic", Dir: filepath.Join(modules, "s3/examples/basic")},
+	}, examples)
+	require.Equal(t, "iam/with-tables", examples[0].String())
+}
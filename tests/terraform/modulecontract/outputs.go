@@ -0,0 +1,92 @@
+package modulecontract
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"gopkg.in/yaml.v3"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// Outputs is the output contract of the environments' root modules: the
+// outputs downstream consumers, such as the application's config loader,
+// read after an apply.
+type Outputs struct {
+	// Outputs maps the outputs every environment must have to their types,
+	// written as in a variable's type argument.
+	Outputs map[string]string `yaml:"outputs"`
+	// Environments adds outputs required of one environment only.
+	Environments map[string]map[string]string `yaml:"environments"`
+}
+
+// LoadOutputs reads an output contract.
+func LoadOutputs(filename string) (*Outputs, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var outputs Outputs
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&outputs); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	for name, typ := range outputs.Outputs {
+		if _, err := parseType(typ); err != nil {
+			return nil, fmt.Errorf("%s: output %s: %w", filename, name, err)
+		}
+	}
+	for environment, types := range outputs.Environments {
+		for name, typ := range types {
+			if _, err := parseType(typ); err != nil {
+				return nil, fmt.Errorf("%s: environment %s: output %s: %w", filename, environment, name, err)
+			}
+		}
+	}
+	return &outputs, nil
+}
+
+// For returns the outputs required of environment, with their types.
+func (o *Outputs) For(environment string) map[string]string {
+	types := make(map[string]string, len(o.Outputs)+len(o.Environments[environment]))
+	for name, typ := range o.Outputs {
+		types[name] = typ
+	}
+	for name, typ := range o.Environments[environment] {
+		types[name] = typ
+	}
+	return types
+}
+
+// Check returns one problem per output required of environment that the
+// root module does not declare.
+func (o *Outputs) Check(environment string, module *plancheck.Module) []string {
+	if module == nil {
+		return []string{"root module has no .tf files"}
+	}
+	declared := map[string]bool{}
+	for _, file := range module.Files {
+		for _, block := range file.Body.Blocks {
+			if block.Type == "output" && len(block.Labels) == 1 {
+				declared[block.Labels[0]] = true
+			}
+		}
+	}
+	var problems []string
+	for _, name := range sortedKeys(o.For(environment)) {
+		if !declared[name] {
+			problems = append(problems, fmt.Sprintf("output %s is not declared", name))
+		}
+	}
+	return problems
+}
+
+// CheckPlan verifies that a plan of environment has every output required of
+// it, and that those known at plan time have the declared type and are not
+// null or empty. Outputs known only after apply pass.
+func (o *Outputs) CheckPlan(environment string, plan *tfjson.Plan) []string {
+	return checkPlannedOutputs(plan, o.For(environment), true)
+}
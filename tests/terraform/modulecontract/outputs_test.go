@@ -0,0 +1,93 @@
+package modulecontract
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+const outputsYAML = `
+outputs:
+  artifacts_bucket: string
+  ddb_tables: map(string)
+  api_url: string
+environments:
+  prod:
+    cdn_url: string
+`
+
+func loadOutputs(t *testing.T) *Outputs {
+	t.Helper()
+	outputs, err := LoadOutputs(writeFile(t, filepath.Join(t.TempDir(), "outputs.yaml"), outputsYAML))
+	require.NoError(t, err)
+	return outputs
+}
+
+func TestLoadOutputsRejectsInvalidTypes(t *testing.T) {
+	for name, content := range map[string]string{
+		"bad type":             "outputs:\n  a: strng\n",
+		"bad environment type": "environments:\n  dev:\n    a: list(\n",
+		"unknown attribute":    "output: {}\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadOutputs(writeFile(t, filepath.Join(t.TempDir(), "outputs.yaml"), content))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestOutputsForAddsEnvironmentOutputs(t *testing.T) {
+	outputs := loadOutputs(t)
+	require.Len(t, outputs.For("dev"), 3)
+	require.Equal(t, "string", outputs.For("prod")["cdn_url"])
+}
+
+func TestOutputsCheckFindsUndeclaredOutputs(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.tf"), `output "artifacts_bucket" { value = "pkg-artifacts" }
+output "ddb_tables" { value = {} }
+output "api_url" { value = "https://example.com" }
+`)
+	module, err := plancheck.LoadModule(dir)
+	require.NoError(t, err)
+
+	require.Empty(t, loadOutputs(t).Check("dev", module))
+	require.Equal(t, []string{"output cdn_url is not declared"}, loadOutputs(t).Check("prod", module))
+}
+
+func TestOutputsCheckPlanRequiresTypedNonEmptyValues(t *testing.T) {
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "format_version": "1.0",
+  "planned_values": {
+    "outputs": {
+      "artifacts_bucket": {"sensitive": false, "value": ""},
+      "ddb_tables": {"sensitive": false, "value": {}}
+    },
+    "root_module": {}
+  },
+  "output_changes": {
+    "api_url": {"actions": ["create"], "after_unknown": true}
+  }
+}`), &plan))
+
+	require.Equal(t, []string{
+		"output artifacts_bucket is empty",
+		"output ddb_tables is empty",
+	}, loadOutputs(t).CheckPlan("dev", &plan))
+
+	plan.PlannedValues.Outputs["artifacts_bucket"].Value = "pkg-artifacts"
+	plan.PlannedValues.Outputs["ddb_tables"].Value = []interface{}{"arn:aws:dynamodb:us-east-1:123456789012:table/packages"}
+	problems := loadOutputs(t).CheckPlan("prod", &plan)
+	require.Len(t, problems, 2)
+	require.Equal(t, "output cdn_url is missing from the plan", problems[0])
+	require.Contains(t, problems[1], "output ddb_tables is not a map(string)")
+
+	plan.PlannedValues.Outputs["ddb_tables"].Value = map[string]interface{}{"packages": "arn:aws:dynamodb:us-east-1:123456789012:table/packages"}
+	require.Empty(t, loadOutputs(t).CheckPlan("dev", &plan), "outputs known only after apply pass")
+}
@@ -0,0 +1,16 @@
+package terraformtests
+
+import (
+	"os"
+	"testing"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func TestNamesDoNotCollideAcrossEnvironments(t *testing.T) {
+	if os.Getenv(plancheck.PeersEnv) == "" {
+		t.Skipf("set %s to the plan or state JSON of the other environments", plancheck.PeersEnv)
+	}
+
+	requireCompliance(t, "names.collision")
+}
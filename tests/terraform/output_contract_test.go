@@ -0,0 +1,46 @@
+package terraformtests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/modulecontract"
+	"cs450/terraformtests/plancheck"
+)
+
+const outputContractsFile = "output_contracts.yaml"
+
+// TestRootModulesHonorOutputContract checks each environment's root module
+// declares the outputs output_contracts.yaml requires of it, then plans it to
+// check those known at plan time are non-empty and of the declared type, so
+// the application's config loader can rely on them.
+func TestRootModulesHonorOutputContract(t *testing.T) {
+	contract, err := modulecontract.LoadOutputs(outputContractsFile)
+	require.NoError(t, err, "output contract must load")
+	envs, err := loadEnvironments()
+	require.NoError(t, err, "%s must load", environmentsFile)
+	known := map[string]bool{}
+	for _, env := range envs {
+		known[env.Name] = true
+	}
+	for name := range contract.Environments {
+		require.True(t, known[name], "%s requires outputs of %s, which %s does not list", outputContractsFile, name, environmentsFile)
+	}
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		module, err := plancheck.LoadModule(env.Dir)
+		require.NoError(t, err)
+		for _, problem := range contract.Check(env.Name, module) {
+			t.Errorf("%s: %s", env.Dir, problem)
+		}
+		if t.Failed() || testing.Short() {
+			return
+		}
+
+		_, plan := environmentPlan(t, env.Name)
+		for _, problem := range contract.CheckPlan(env.Name, plan) {
+			t.Errorf("%s planned: %s", env.Name, problem)
+		}
+	})
+}
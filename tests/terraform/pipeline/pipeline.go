@@ -0,0 +1,276 @@
+// Package pipeline checks that a deployed S3 event pipeline fires: it drops
+// an object where a bucket notification will see it and waits for the
+// notified queue to receive a message, and for the Lambda consumers to log
+// the object's key.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatchtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/plancheck"
+)
+
+// SentinelPrefix starts the base name of every object Verify drops.
+const SentinelPrefix = "compliance-pipeline-sentinel-"
+
+// S3API drops and removes sentinel objects.
+type S3API interface {
+	PutObject(context.Context, *s3.PutObjectInput, ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(context.Context, *s3.DeleteObjectInput, ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// CloudWatchAPI reads the queue's metrics.
+type CloudWatchAPI interface {
+	GetMetricStatistics(context.Context, *cloudwatch.GetMetricStatisticsInput, ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricStatisticsOutput, error)
+}
+
+// LogsAPI searches the consumers' logs.
+type LogsAPI interface {
+	FilterLogEvents(context.Context, *cloudwatchlogs.FilterLogEventsInput, ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error)
+}
+
+// Clients are the AWS APIs the pipeline is driven and observed through.
+type Clients struct {
+	S3         S3API
+	CloudWatch CloudWatchAPI
+	Logs       LogsAPI
+
+	// PollInterval is how long to wait between checks. Zero means fifteen
+	// seconds: SQS metrics are published once a minute.
+	PollInterval time.Duration
+
+	// Now returns the time sentinel keys and metric windows are taken from.
+	// Nil means time.Now.
+	Now func() time.Time
+}
+
+// NewClients returns the S3, CloudWatch and CloudWatch Logs clients a
+// pipeline is followed through in region, signed with creds when set.
+func NewClients(region string, creds *credentials.Credentials) (*Clients, error) {
+	cfg, err := awsapi.Default().WithCredentials(creds).Config(context.Background(), region)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline: %w", err)
+	}
+	return &Clients{
+		S3:         s3.NewFromConfig(cfg, awsapi.S3PathStyle(cfg)),
+		CloudWatch: cloudwatch.NewFromConfig(cfg),
+		Logs:       cloudwatchlogs.NewFromConfig(cfg),
+	}, nil
+}
+
+func (c *Clients) pollInterval() time.Duration {
+	if c.PollInterval == 0 {
+		return 15 * time.Second
+	}
+	return c.PollInterval
+}
+
+func (c *Clients) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+// Trigger is one queue or Lambda function notified of new objects in a
+// bucket.
+type Trigger struct {
+	// Address is the aws_s3_bucket_notification.
+	Address string
+	Bucket  string
+	Prefix  string
+	Suffix  string
+	// Queue is the name of the notified queue, or empty.
+	Queue string
+	// Functions are the Lambda functions that handle the event: the notified
+	// function, or those consuming the notified queue.
+	Functions []string
+}
+
+// Target names what the trigger notifies, for test names and messages.
+func (t Trigger) Target() string {
+	if t.Queue != "" {
+		return "sqs:" + t.Queue
+	}
+	return "lambda:" + strings.Join(t.Functions, ",")
+}
+
+// Triggers returns the queue and Lambda notifications of bucket that fire
+// on objects created by PutObject. Notifications whose targets are not
+// known until apply are left out.
+func Triggers(plan *tfjson.Plan, bucket string) []Trigger {
+	consumers := map[string][]string{}
+	for _, mapping := range plancheck.Resources(plan, "aws_lambda_event_source_mapping") {
+		source := plancheck.LookupString(mapping.AttributeValues, "event_source_arn")
+		if function := functionName(plancheck.LookupString(mapping.AttributeValues, "function_name")); source != "" && function != "" {
+			consumers[source] = append(consumers[source], function)
+		}
+	}
+
+	var triggers []Trigger
+	for _, notification := range plancheck.Resources(plan, "aws_s3_bucket_notification") {
+		if plancheck.LookupString(notification.AttributeValues, "bucket") != bucket {
+			continue
+		}
+		for _, queue := range plancheck.Blocks(notification.AttributeValues, "queue") {
+			arn := plancheck.LookupString(queue, "queue_arn")
+			if arn == "" || !firesOnPut(queue) {
+				continue
+			}
+			triggers = append(triggers, Trigger{
+				Address:   notification.Address,
+				Bucket:    bucket,
+				Prefix:    plancheck.LookupString(queue, "filter_prefix"),
+				Suffix:    plancheck.LookupString(queue, "filter_suffix"),
+				Queue:     arn[strings.LastIndex(arn, ":")+1:],
+				Functions: consumers[arn],
+			})
+		}
+		for _, lambda := range plancheck.Blocks(notification.AttributeValues, "lambda_function") {
+			function := functionName(plancheck.LookupString(lambda, "lambda_function_arn"))
+			if function == "" || !firesOnPut(lambda) {
+				continue
+			}
+			triggers = append(triggers, Trigger{
+				Address:   notification.Address,
+				Bucket:    bucket,
+				Prefix:    plancheck.LookupString(lambda, "filter_prefix"),
+				Suffix:    plancheck.LookupString(lambda, "filter_suffix"),
+				Functions: []string{function},
+			})
+		}
+	}
+	return triggers
+}
+
+// firesOnPut reports whether a notification's events include objects
+// created by PutObject.
+func firesOnPut(block map[string]interface{}) bool {
+	events, _ := block["events"].([]interface{})
+	for _, event := range events {
+		name, _ := event.(string)
+		if matched, _ := path.Match(name, "s3:ObjectCreated:Put"); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// functionName returns the name of a Lambda function given by name or ARN,
+// without a qualifier.
+func functionName(nameOrARN string) string {
+	if !strings.HasPrefix(nameOrARN, "arn:") {
+		return nameOrARN
+	}
+	parts := strings.Split(nameOrARN, ":")
+	if len(parts) < 7 || parts[5] != "function" {
+		return ""
+	}
+	return parts[6]
+}
+
+// Verify drops a sentinel object matching the trigger's filter, waits until
+// the queue has received a message and every handler has logged the
+// object's key, or ctx is done, and deletes the object. It returns the key.
+func (c *Clients) Verify(ctx context.Context, trigger Trigger) (key string, err error) {
+	sent := c.now()
+	key = trigger.Prefix + SentinelPrefix + strconv.FormatInt(sent.UnixNano(), 10) + trigger.Suffix
+	if _, err := c.S3.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(trigger.Bucket),
+		Key:    aws.String(key),
+		Body:   strings.NewReader("compliance pipeline check\n"),
+	}); err != nil {
+		return key, fmt.Errorf("pipeline: dropping %s in %s: %w", key, trigger.Bucket, err)
+	}
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		_, deleteErr := c.S3.DeleteObject(cleanupCtx, &s3.DeleteObjectInput{Bucket: aws.String(trigger.Bucket), Key: aws.String(key)})
+		if deleteErr != nil && err == nil {
+			err = fmt.Errorf("pipeline: deleting %s from %s: %w", key, trigger.Bucket, deleteErr)
+		}
+	}()
+
+	if trigger.Queue != "" {
+		if err := awsapi.Poll(ctx, c.pollInterval(), fmt.Sprintf("queue %s to receive the notification for %s", trigger.Queue, key), func() (bool, error) {
+			return c.queueReceived(ctx, trigger.Queue, sent)
+		}); err != nil {
+			return key, fmt.Errorf("pipeline: %s: %w", trigger.Address, err)
+		}
+	}
+	for _, function := range trigger.Functions {
+		group := "/aws/lambda/" + function
+		if err := awsapi.Poll(ctx, c.pollInterval(), fmt.Sprintf("%s to log %s", group, key), func() (bool, error) {
+			return c.logged(ctx, group, key, sent)
+		}); err != nil {
+			return key, fmt.Errorf("pipeline: %s: %w", trigger.Address, err)
+		}
+	}
+	return key, nil
+}
+
+// queueReceived reports whether the queue was sent any message since the
+// sentinel was dropped, by its NumberOfMessagesSent metric. The metric
+// cannot tell the sentinel's notification from other traffic, which a
+// sandbox seldom has.
+func (c *Clients) queueReceived(ctx context.Context, queue string, since time.Time) (bool, error) {
+	out, err := c.CloudWatch.GetMetricStatistics(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String("AWS/SQS"),
+		MetricName: aws.String("NumberOfMessagesSent"),
+		Dimensions: []cloudwatchtypes.Dimension{{Name: aws.String("QueueName"), Value: aws.String(queue)}},
+		StartTime:  aws.Time(since.Truncate(time.Minute)),
+		EndTime:    aws.Time(c.now().Add(time.Minute)),
+		Period:     aws.Int32(60),
+		Statistics: []cloudwatchtypes.Statistic{cloudwatchtypes.StatisticSum},
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, point := range out.Datapoints {
+		if aws.ToFloat64(point.Sum) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// logged reports whether a log group has an event containing key since the
+// sentinel was dropped.
+func (c *Clients) logged(ctx context.Context, group, key string, since time.Time) (bool, error) {
+	pages := cloudwatchlogs.NewFilterLogEventsPaginator(c.Logs, &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName:  aws.String(group),
+		FilterPattern: aws.String(`"` + path.Base(key) + `"`),
+		StartTime:     aws.Int64(since.Add(-time.Minute).UnixMilli()),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		// The group is created by the handler's first invocation.
+		var notFound *logstypes.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if len(page.Events) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
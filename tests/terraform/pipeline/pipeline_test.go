@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatchtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+const ingestPlan = `{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"resources": [
+    {"address": "aws_s3_bucket_notification.ingest", "type": "aws_s3_bucket_notification", "name": "ingest", "values": {
+      "bucket": "pkg-artifacts",
+      "queue": [
+        {"queue_arn": "arn:aws:sqs:us-east-1:123456789012:package-ingest", "events": ["s3:ObjectCreated:*"], "filter_prefix": "packages/", "filter_suffix": ".zip"},
+        {"queue_arn": "arn:aws:sqs:us-east-1:123456789012:package-deletes", "events": ["s3:ObjectRemoved:*"]}
+      ],
+      "lambda_function": [
+        {"lambda_function_arn": "arn:aws:lambda:us-east-1:123456789012:function:thumbnailer:live", "events": ["s3:ObjectCreated:Put"], "filter_prefix": "images/"}
+      ]
+    }},
+    {"address": "aws_s3_bucket_notification.other", "type": "aws_s3_bucket_notification", "name": "other", "values": {
+      "bucket": "other-bucket",
+      "queue": [{"queue_arn": "arn:aws:sqs:us-east-1:123456789012:other", "events": ["s3:ObjectCreated:*"]}]
+    }},
+    {"address": "aws_lambda_event_source_mapping.ingest", "type": "aws_lambda_event_source_mapping", "name": "ingest", "values": {
+      "event_source_arn": "arn:aws:sqs:us-east-1:123456789012:package-ingest",
+      "function_name": "arn:aws:lambda:us-east-1:123456789012:function:package-ingest"
+    }}
+  ]}}
+}`
+
+func TestTriggersFollowQueuesToConsumers(t *testing.T) {
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(ingestPlan), &plan))
+
+	require.Equal(t, []Trigger{
+		{Address: "aws_s3_bucket_notification.ingest", Bucket: "pkg-artifacts", Prefix: "packages/", Suffix: ".zip", Queue: "package-ingest", Functions: []string{"package-ingest"}},
+		{Address: "aws_s3_bucket_notification.ingest", Bucket: "pkg-artifacts", Prefix: "images/", Functions: []string{"thumbnailer"}},
+	}, Triggers(&plan, "pkg-artifacts"))
+	require.Empty(t, Triggers(&plan, "missing"))
+}
+
+type fakeS3 struct {
+	S3API
+	put, deleted []string
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	f.put = append(f.put, aws.ToString(in.Key))
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) DeleteObject(_ context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	f.deleted = append(f.deleted, aws.ToString(in.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// fakeCloudWatch reports a message sent on the second query.
+type fakeCloudWatch struct {
+	CloudWatchAPI
+	queries int
+}
+
+func (f *fakeCloudWatch) GetMetricStatistics(_ context.Context, in *cloudwatch.GetMetricStatisticsInput, _ ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricStatisticsOutput, error) {
+	f.queries++
+	out := &cloudwatch.GetMetricStatisticsOutput{Datapoints: []cloudwatchtypes.Datapoint{{Sum: aws.Float64(0)}}}
+	if f.queries >= 2 {
+		out.Datapoints = append(out.Datapoints, cloudwatchtypes.Datapoint{Sum: aws.Float64(1)})
+	}
+	return out, nil
+}
+
+// fakeLogs finds what the handler logged, when it logs at all.
+type fakeLogs struct {
+	LogsAPI
+	logs     bool
+	searches []string
+}
+
+func (f *fakeLogs) FilterLogEvents(_ context.Context, in *cloudwatchlogs.FilterLogEventsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	f.searches = append(f.searches, aws.ToString(in.LogGroupName)+" "+aws.ToString(in.FilterPattern))
+	page := &cloudwatchlogs.FilterLogEventsOutput{}
+	if f.logs {
+		page.Events = []logstypes.FilteredLogEvent{{Message: in.FilterPattern}}
+	}
+	return page, nil
+}
+
+func clientsFor(s3 *fakeS3, logs *fakeLogs) (*Clients, *fakeCloudWatch) {
+	metrics := &fakeCloudWatch{}
+	return &Clients{
+		S3:           s3,
+		CloudWatch:   metrics,
+		Logs:         logs,
+		PollInterval: time.Millisecond,
+		Now:          func() time.Time { return time.Unix(1700000000, 0) },
+	}, metrics
+}
+
+func TestVerifyWaitsForQueueAndHandler(t *testing.T) {
+	bucket := &fakeS3{}
+	logs := &fakeLogs{logs: true}
+	clients, metrics := clientsFor(bucket, logs)
+
+	key, err := clients.Verify(context.Background(), Trigger{Address: "aws_s3_bucket_notification.ingest", Bucket: "pkg-artifacts", Prefix: "packages/", Suffix: ".zip", Queue: "package-ingest", Functions: []string{"package-ingest"}})
+	require.NoError(t, err)
+	require.Equal(t, "packages/compliance-pipeline-sentinel-1700000000000000000.zip", key)
+	require.Equal(t, []string{key}, bucket.put)
+	require.Equal(t, []string{key}, bucket.deleted)
+	require.Equal(t, 2, metrics.queries)
+	require.Equal(t, []string{`/aws/lambda/package-ingest "compliance-pipeline-sentinel-1700000000000000000.zip"`}, logs.searches)
+}
+
+func TestVerifyGivesUpOnSilentHandlerAndCleansUp(t *testing.T) {
+	bucket := &fakeS3{}
+	clients, _ := clientsFor(bucket, &fakeLogs{})
+	clients.PollInterval = 50 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := clients.Verify(ctx, Trigger{Address: "aws_s3_bucket_notification.ingest", Bucket: "pkg-artifacts", Prefix: "images/", Functions: []string{"thumbnailer"}})
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "pipeline: aws_s3_bucket_notification.ingest: gave up waiting for /aws/lambda/thumbnailer to log images/compliance-pipeline-sentinel-1700000000000000000"), err.Error())
+	require.Equal(t, bucket.put, bucket.deleted)
+}
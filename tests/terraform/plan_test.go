@@ -0,0 +1,363 @@
+package terraformtests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/awsauth"
+	"cs450/terraformtests/fix"
+	"cs450/terraformtests/plancheck"
+)
+
+const (
+	// keepArtifactsEnv keeps plan files and show output after the run for
+	// debugging; the test log says where they are.
+	keepArtifactsEnv = "KEEP_ARTIFACTS"
+
+	// planJSONEnv names "terraform show -json" output to evaluate instead of
+	// running terraform init and plan: one file for dev, or env=file pairs
+	// separated like PATH. Tests that must plan a configuration of their own
+	// skip while it is set.
+	planJSONEnv = "TF_PLAN_JSON"
+
+	devEnvironment   = "dev"
+	complianceFile   = "compliance.yaml"
+	devDefaultRegion = "us-east-1"
+)
+
+// environmentOptions returns the options planning env, failing the test if
+// its variables are invalid.
+func environmentOptions(t *testing.T, env EnvConfig) *terraform.Options {
+	t.Helper()
+
+	require.NoError(t, env.Validate(), "%s variables must be valid", env.Name)
+	return env.Options()
+}
+
+// sharedPlan is the plan of one environment, made by the first test that
+// asks for it.
+type sharedPlan struct {
+	once    sync.Once
+	options *terraform.Options
+	creds   *awsauth.Credentials
+	plan    *tfjson.Plan
+	err     error
+}
+
+var (
+	sharedPlansMu sync.Mutex
+	sharedPlans   = map[string]*sharedPlan{}
+
+	// sharedArtifacts are the directories of the shared plan files, which
+	// TestMain removes once every test has run.
+	sharedArtifactsMu sync.Mutex
+	sharedArtifacts   []string
+)
+
+// environmentPlan returns the options and parsed plan of a named
+// environment. terraform init and plan run once per environment per test
+// binary, so tests share one plan file and may run in parallel; a failed plan
+// fails every test that asks for it without planning again. Each test gets
+// the environment's credentials as they are when it asks, renewed if the
+// first ones are about to expire.
+func environmentPlan(t *testing.T, environment string) (*terraform.Options, *tfjson.Plan) {
+	t.Helper()
+
+	vars := testEnvironment(t, environment)
+	if _, err := planJSONFile(environment); errors.Is(err, errNoPlanJSON) {
+		t.Skipf("%v", err)
+	}
+
+	sharedPlansMu.Lock()
+	shared, ok := sharedPlans[environment]
+	if !ok {
+		shared = &sharedPlan{}
+		sharedPlans[environment] = shared
+	}
+	sharedPlansMu.Unlock()
+
+	shared.once.Do(func() {
+		shared.options, shared.creds, shared.plan, shared.err = planEnvironment(t, environment, vars)
+	})
+	require.NoError(t, shared.err, "%s plan must succeed", environment)
+	runManifest.addPlan(environment, shared.options.TerraformDir, shared.plan)
+	options := *shared.options
+	if shared.creds != nil {
+		options.EnvVars = shared.creds.Env()
+	}
+	return &options, shared.plan
+}
+
+func planEnvironment(t *testing.T, environment string, vars EnvConfig) (*terraform.Options, *awsauth.Credentials, *tfjson.Plan, error) {
+	if err := vars.Validate(); err != nil {
+		return nil, nil, nil, err
+	}
+	if filename, err := planJSONFile(environment); err != nil || filename != "" {
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		t.Logf("%s is set; evaluating %s from %s instead of planning it", planJSONEnv, environment, filename)
+		plan, err := plancheck.LoadPlanOrState(filename)
+		if err == nil && (plan.PlannedValues == nil || plan.PlannedValues.RootModule == nil) {
+			err = fmt.Errorf("%s has no planned root module", filename)
+		}
+		return vars.Options(), nil, plan, err
+	}
+	creds, err := roleCredentials(environment)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := checkIdentity(t, environment, creds); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := lockEnvironment(environment, creds); err != nil {
+		return nil, nil, nil, err
+	}
+
+	options := vars.Options()
+	options.EnvVars = creds.Env()
+	if options.PlanFilePath, err = planFilePath(environment); err != nil {
+		return nil, nil, nil, err
+	}
+	sharedArtifactsMu.Lock()
+	sharedArtifacts = append(sharedArtifacts, filepath.Dir(options.PlanFilePath))
+	sharedArtifactsMu.Unlock()
+
+	plan, err := showPlanE(t, options)
+	return options, creds, plan, err
+}
+
+// errNoPlanJSON is returned for environments TF_PLAN_JSON does not name;
+// their tests skip, as the run is meant not to need terraform.
+var errNoPlanJSON = errors.New("names no plan")
+
+// planJSONFile returns the file TF_PLAN_JSON names for environment, or "" to
+// plan it.
+func planJSONFile(environment string) (string, error) {
+	value := os.Getenv(planJSONEnv)
+	if value == "" {
+		return "", nil
+	}
+	entries := filepath.SplitList(value)
+	if len(entries) == 1 && !strings.Contains(entries[0], "=") {
+		if environment == devEnvironment {
+			return entries[0], nil
+		}
+		return "", fmt.Errorf("%s %w for %s: a single file is the dev plan; name others as env=file", planJSONEnv, errNoPlanJSON, environment)
+	}
+	for _, entry := range entries {
+		name, filename, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || filename == "" {
+			return "", fmt.Errorf("%s entry %q: want env=file", planJSONEnv, entry)
+		}
+		if name == environment {
+			return filename, nil
+		}
+	}
+	return "", fmt.Errorf("%s %w for %s", planJSONEnv, errNoPlanJSON, environment)
+}
+
+// skipWithoutPlanning skips tests that run terraform plan themselves when
+// TF_PLAN_JSON says terraform is not available.
+func skipWithoutPlanning(t *testing.T) {
+	t.Helper()
+	if os.Getenv(planJSONEnv) != "" {
+		t.Skipf("%s is set; this test plans its own configuration", planJSONEnv)
+	}
+}
+
+// planFilePath returns a plan file path in a directory of its own, so
+// concurrent test binaries and environments never share a plan file.
+func planFilePath(environment string) (string, error) {
+	dir, err := os.MkdirTemp("", "tfcompliance-"+environment+"-")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, environment+".tfplan"), nil
+}
+
+// sharedCredential is the credentials of one environment, assumed by the
+// first test that asks for them.
+type sharedCredential struct {
+	once  sync.Once
+	creds *awsauth.Credentials
+	err   error
+}
+
+var (
+	sharedCredentialsMu sync.Mutex
+	sharedCredentials   = map[string]*sharedCredential{}
+)
+
+// roleCredentials assumes the role compliance.yaml names for environment, or
+// returns nil to use the caller's own credentials. The role is assumed once
+// per environment per test binary, so tests do not call STS, or request a
+// GitHub OIDC token, again; the credentials renew themselves before they
+// expire. A failed assumption fails every test that asks for it.
+func roleCredentials(environment string) (*awsauth.Credentials, error) {
+	sharedCredentialsMu.Lock()
+	shared, ok := sharedCredentials[environment]
+	if !ok {
+		shared = &sharedCredential{}
+		sharedCredentials[environment] = shared
+	}
+	sharedCredentialsMu.Unlock()
+
+	shared.once.Do(func() {
+		config, err := plancheck.LoadConfig(complianceFile)
+		if err != nil {
+			shared.err = err
+			return
+		}
+		shared.creds, shared.err = awsauth.ForEnvironment(context.Background(), config, environment, devDefaultRegion)
+	})
+	return shared.creds, shared.err
+}
+
+// checkIdentity refuses root and administrator credentials before anything
+// is planned, logs who the suite runs as and records it for the headers of
+// the run's reports.
+func checkIdentity(t *testing.T, environment string, creds *awsauth.Credentials) error {
+	preflight, err := awsauth.NewPreflight(devDefaultRegion, creds.AWS())
+	if err != nil {
+		return err
+	}
+	identity, err := preflight.Check(context.Background())
+	if err != nil {
+		return err
+	}
+	t.Logf("planning %s as %s", environment, identity)
+	runFindings.Identify(environment, identity.String())
+	return nil
+}
+
+// cleanupArtifacts removes dir once t and its subtests finish, unless
+// KEEP_ARTIFACTS is set.
+func cleanupArtifacts(t *testing.T, dir string) {
+	t.Cleanup(func() {
+		if os.Getenv(keepArtifactsEnv) != "" {
+			t.Logf("%s is set; keeping %s", keepArtifactsEnv, dir)
+			return
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			t.Logf("removing %s: %v", dir, err)
+		}
+	})
+}
+
+// removeSharedArtifacts removes the directories of the shared plan files,
+// unless KEEP_ARTIFACTS is set.
+func removeSharedArtifacts() {
+	sharedArtifactsMu.Lock()
+	defer sharedArtifactsMu.Unlock()
+	for _, dir := range sharedArtifacts {
+		if os.Getenv(keepArtifactsEnv) != "" {
+			fmt.Fprintf(os.Stderr, "%s is set; keeping %s\n", keepArtifactsEnv, dir)
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "removing %s: %v\n", dir, err)
+		}
+	}
+	sharedArtifacts = nil
+}
+
+// showPlanE runs terraform init and plan and returns the parsed plan. With
+// KEEP_ARTIFACTS set, the show output is also written next to the plan file.
+func showPlanE(t *testing.T, options *terraform.Options) (*tfjson.Plan, error) {
+	if _, err := terraform.InitAndPlanE(t, options); err != nil {
+		return nil, err
+	}
+	planOutput, err := terraform.RunTerraformCommandAndGetStdoutE(t, options, "show", "-json", options.PlanFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv(keepArtifactsEnv) != "" {
+		dump := strings.TrimSuffix(options.PlanFilePath, ".tfplan") + ".json"
+		if err := os.WriteFile(dump, []byte(planOutput), 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	var plan tfjson.Plan
+	if err := json.Unmarshal([]byte(planOutput), &plan); err != nil {
+		return nil, err
+	}
+	if plan.PlannedValues == nil || plan.PlannedValues.RootModule == nil {
+		return nil, errors.New("plan has no planned root module")
+	}
+	return &plan, nil
+}
+
+// evaluateRules runs the named rules against plan as the given environment,
+// points the findings at their source in options.TerraformDir and attaches
+// patches for the ones with mechanical fixes.
+func evaluateRules(t *testing.T, plan *tfjson.Plan, options *terraform.Options, environment string, ruleIDs ...string) []plancheck.Finding {
+	t.Helper()
+
+	config, err := plancheck.LoadConfig(complianceFile)
+	require.NoError(t, err, "compliance configuration must load")
+
+	peers, err := plancheck.LoadPeersFromEnv()
+	require.NoError(t, err, "peer environment plans must load")
+
+	backend, err := plancheck.LoadBackend(options.TerraformDir)
+	require.NoError(t, err, "backend configuration must parse")
+
+	module, err := plancheck.LoadModule(options.TerraformDir)
+	require.NoError(t, err, "module source must parse")
+
+	baseline, err := plancheck.LoadBaseline(baselineFile)
+	require.NoError(t, err, "baseline file must be valid")
+
+	// The region the plan was made for, whatever environment it is judged
+	// as; plans of generated roots have none and use the dev default.
+	region, _ := options.Vars["aws_region"].(string)
+	if region == "" {
+		region = devDefaultRegion
+	}
+	rules := requireRules(t, ruleIDs...)
+	runManifest.addRules(rules)
+	findings := plancheck.Evaluate(
+		&plancheck.Input{Plan: plan, DefaultRegion: region, Environment: environment, Config: config, Peers: peers, Backend: backend, Module: module, Baseline: baseline, AllowDestroy: plancheck.AllowDestroyFromEnv()},
+		rules...,
+	)
+	index := plancheck.NewSourceIndex(plan, options.TerraformDir)
+	index.Annotate(findings)
+	fix.Suggest(index, findings)
+	return findings
+}
+
+func TestPlanJSONFileSelectsEnvironment(t *testing.T) {
+	t.Setenv(planJSONEnv, "")
+	filename, err := planJSONFile(devEnvironment)
+	require.NoError(t, err)
+	require.Empty(t, filename, "without TF_PLAN_JSON, dev is planned")
+
+	t.Setenv(planJSONEnv, "plan.json")
+	filename, err = planJSONFile(devEnvironment)
+	require.NoError(t, err)
+	require.Equal(t, "plan.json", filename)
+	_, err = planJSONFile("prod")
+	require.ErrorIs(t, err, errNoPlanJSON)
+	require.EqualError(t, err, "TF_PLAN_JSON names no plan for prod: a single file is the dev plan; name others as env=file")
+
+	t.Setenv(planJSONEnv, strings.Join([]string{"dev=dev.json", "prod=prod.json"}, string(os.PathListSeparator)))
+	filename, err = planJSONFile("prod")
+	require.NoError(t, err)
+	require.Equal(t, "prod.json", filename)
+	_, err = planJSONFile("stage")
+	require.ErrorIs(t, err, errNoPlanJSON)
+	require.EqualError(t, err, "TF_PLAN_JSON names no plan for stage")
+}
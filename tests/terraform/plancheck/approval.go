@@ -0,0 +1,134 @@
+package plancheck
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultApprovalTTL is how long an approval stays valid when the caller does
+// not choose: long enough for a deploy job queued behind the check, short
+// enough that an approved plan does not outlive the state it was made from.
+const DefaultApprovalTTL = 2 * time.Hour
+
+// ErrApprovalExpired is returned for an approval presented after it expired.
+var ErrApprovalExpired = errors.New("approval has expired")
+
+// Approval is the token a successful check writes for the deploy job: it ties
+// the binary plan file that was checked to the findings it had and a time
+// after which it may no longer be applied.
+type Approval struct {
+	Environment string `json:"environment"`
+	// PlanSHA256 is the digest of the plan file's bytes, as terraform plan
+	// -out wrote them.
+	PlanSHA256 string           `json:"plan_sha256"`
+	Approved   time.Time        `json:"approved"`
+	Expires    time.Time        `json:"expires"`
+	Findings   ManifestFindings `json:"findings"`
+	// PackVersion is the policy pack the plan was checked with.
+	PackVersion string     `json:"pack_version,omitempty"`
+	Signature   *Signature `json:"signature,omitempty"`
+}
+
+// NewApproval approves planFile for environment at now, with the findings
+// the check left open, for ttl.
+func NewApproval(environment, planFile string, findings []Finding, now time.Time, ttl time.Duration) (Approval, error) {
+	sum, err := FileDigest(planFile)
+	if err != nil {
+		return Approval{}, err
+	}
+	digest, err := FindingsDigest(findings)
+	if err != nil {
+		return Approval{}, err
+	}
+	now = now.UTC().Truncate(time.Second)
+	return Approval{
+		Environment: environment,
+		PlanSHA256:  sum,
+		Approved:    now,
+		Expires:     now.Add(ttl),
+		Findings:    digest,
+		PackVersion: PackVersion(),
+	}, nil
+}
+
+// FileDigest returns the hex SHA-256 digest of a file's bytes.
+func FileDigest(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return digest(data), nil
+}
+
+// Sign replaces the approval's signature with one by key.
+func (a *Approval) Sign(key ed25519.PrivateKey) error {
+	a.Signature = nil
+	data, err := a.encode()
+	if err != nil {
+		return err
+	}
+	a.Signature = sign(key, data)
+	return nil
+}
+
+// Verify checks that planFile is byte for byte the approved plan and that
+// the approval has not expired at now. With a key, the approval must also be
+// signed by it; without one, the signature is not checked.
+func (a Approval) Verify(planFile string, key ed25519.PublicKey, now time.Time) error {
+	if key != nil {
+		unsigned := a
+		unsigned.Signature = nil
+		data, err := unsigned.encode()
+		if err != nil {
+			return err
+		}
+		if err := verify(a.Signature, key, data, "approval"); err != nil {
+			return err
+		}
+	}
+	if !now.Before(a.Expires) {
+		return fmt.Errorf("%w: the %s plan was approved until %s", ErrApprovalExpired, a.Environment, a.Expires.Format(time.RFC3339))
+	}
+	sum, err := FileDigest(planFile)
+	if err != nil {
+		return err
+	}
+	if sum != a.PlanSHA256 {
+		return fmt.Errorf("%s is not the approved %s plan: digest %s, approved %s", planFile, a.Environment, sum, a.PlanSHA256)
+	}
+	return nil
+}
+
+func (a Approval) encode() ([]byte, error) {
+	return json.MarshalIndent(a, "", "  ")
+}
+
+// WriteApproval writes a to filename as indented JSON.
+func WriteApproval(filename string, a Approval) error {
+	data, err := a.encode()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// ReadApproval loads an approval written by WriteApproval.
+func ReadApproval(filename string) (Approval, error) {
+	var a Approval
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return a, err
+	}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return a, fmt.Errorf("%s: %w", filename, err)
+	}
+	return a, nil
+}
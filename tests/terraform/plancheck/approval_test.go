@@ -0,0 +1,41 @@
+package plancheck
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApprovalBindsThePlanFile(t *testing.T) {
+	dir := t.TempDir()
+	planFile := filepath.Join(dir, "dev.tfplan")
+	require.NoError(t, os.WriteFile(planFile, []byte("binary plan"), 0o644))
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	approval, err := NewApproval("dev", planFile, nil, now, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(time.Hour), approval.Expires)
+	require.NoError(t, approval.Sign(private))
+
+	approvalFile := filepath.Join(dir, "approval.json")
+	require.NoError(t, WriteApproval(approvalFile, approval))
+	read, err := ReadApproval(approvalFile)
+	require.NoError(t, err)
+	require.NoError(t, read.Verify(planFile, public, now.Add(59*time.Minute)))
+	require.ErrorIs(t, read.Verify(planFile, public, now.Add(time.Hour)), ErrApprovalExpired)
+
+	read.Expires = read.Expires.Add(24 * time.Hour)
+	require.ErrorContains(t, read.Verify(planFile, public, now), "does not match", "extending an approval breaks its signature")
+	require.NoError(t, read.Verify(planFile, nil, now), "without a key the signature is not checked")
+
+	unsigned := approval
+	unsigned.Signature = nil
+	require.ErrorContains(t, unsigned.Verify(planFile, public, now), "approval is not signed")
+}
@@ -0,0 +1,81 @@
+package plancheck
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Backend is the state backend a root module declares in its terraform block.
+type Backend struct {
+	Type string
+	// Config holds the backend arguments with literal values, rendered as
+	// strings ("true" for booleans). Arguments passed with -backend-config at
+	// init time are not visible here.
+	Config map[string]string
+	// Source is where the backend block is declared.
+	Source *SourceLocation
+}
+
+// LoadBackend reads the backend block from the .tf files in dir. It returns
+// nil and no error when the module declares no backend and so keeps its
+// state locally.
+func LoadBackend(dir string) (*Backend, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	var backend *Backend
+	for _, filename := range files {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		file, err := ParseSource(filename, data)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, block := range file.Body.Blocks {
+			if block.Type != "terraform" {
+				continue
+			}
+			for _, nested := range block.Body.Blocks {
+				if nested.Type != "backend" || len(nested.Labels) != 1 {
+					continue
+				}
+				if backend != nil {
+					return nil, fmt.Errorf("%s: backend declared twice, also at %s", nested.DefRange(), backend.Source)
+				}
+				backend = &Backend{
+					Type:   nested.Labels[0],
+					Config: map[string]string{},
+					Source: &SourceLocation{File: filename, Line: nested.DefRange().Start.Line},
+				}
+				for name, attr := range nested.Body.Attributes {
+					if value, ok := Literal(attr.Expr); ok && !value.IsNull() {
+						if rendered, ok := renderLiteral(value); ok {
+							backend.Config[name] = rendered
+						}
+					}
+				}
+			}
+		}
+	}
+	return backend, nil
+}
+
+func renderLiteral(value cty.Value) (string, bool) {
+	switch value.Type() {
+	case cty.String:
+		return value.AsString(), true
+	case cty.Bool:
+		return fmt.Sprint(value.True()), true
+	case cty.Number:
+		return value.AsBigFloat().Text('f', -1), true
+	}
+	return "", false
+}
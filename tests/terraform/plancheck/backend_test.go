@@ -0,0 +1,54 @@
+package plancheck
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBackendReadsLiteralArguments(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "versions.tf"), `terraform {
+  required_version = ">= 1.6.0"
+
+  backend "s3" {
+    bucket         = "acme-terraform-state"
+    key            = "terraform/state"
+    dynamodb_table = "terraform-state-lock"
+    encrypt        = true
+    max_retries    = 5
+    profile        = var.profile
+  }
+}
+`)
+
+	backend, err := LoadBackend(dir)
+	require.NoError(t, err)
+	require.Equal(t, "s3", backend.Type)
+	require.Equal(t, map[string]string{
+		"bucket":         "acme-terraform-state",
+		"key":            "terraform/state",
+		"dynamodb_table": "terraform-state-lock",
+		"encrypt":        "true",
+		"max_retries":    "5",
+	}, backend.Config)
+	require.Equal(t, &SourceLocation{File: filepath.Join(dir, "versions.tf"), Line: 4}, backend.Source)
+}
+
+func TestLoadBackendWithoutBackendIsLocal(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.tf"), `terraform {
+  required_version = ">= 1.6.0"
+}
+`)
+
+	backend, err := LoadBackend(dir)
+	require.NoError(t, err)
+	require.Nil(t, backend)
+
+	writeFile(t, filepath.Join(dir, "a.tf"), "terraform {\n  backend \"local\" {}\n}\n")
+	writeFile(t, filepath.Join(dir, "b.tf"), "terraform {\n  backend \"s3\" {}\n}\n")
+	_, err = LoadBackend(dir)
+	require.Error(t, err)
+}
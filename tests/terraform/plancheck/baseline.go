@@ -0,0 +1,139 @@
+package plancheck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Baseline records findings that have been reviewed and accepted, each with a
+// justification. Accepted findings are reported but do not fail the tests,
+// until the entry's expiry date.
+type Baseline struct {
+	Accepted []BaselineEntry `yaml:"accepted"`
+}
+
+// BaselineEntry accepts the finding of one rule at one attribute path of a
+// resource.
+type BaselineEntry struct {
+	RuleID        string `yaml:"rule"`
+	Resource      string `yaml:"resource"` // Finding.Key(): [region/]address
+	Path          string `yaml:"path,omitempty"`
+	Justification string `yaml:"justification"`
+
+	// Expires is the first day, e.g. 2026-11-30, the entry no longer
+	// accepts the finding, so that it is reviewed again; empty never
+	// expires.
+	Expires string `yaml:"expires,omitempty"`
+}
+
+// LoadBaseline reads a baseline file. A missing file is an empty baseline.
+func LoadBaseline(filename string) (*Baseline, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Baseline{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var baseline Baseline
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&baseline); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	for i, entry := range baseline.Accepted {
+		if entry.Justification == "" {
+			return nil, fmt.Errorf("%s: accepted[%d] (%s %s) has no justification", filename, i, entry.RuleID, entry.Resource)
+		}
+		if _, err := ParseExpiry(entry.Expires); err != nil {
+			return nil, fmt.Errorf("%s: accepted[%d] (%s %s): %w", filename, i, entry.RuleID, entry.Resource, err)
+		}
+	}
+	return &baseline, nil
+}
+
+// Save writes the baseline to filename, ordered by resource and rule.
+func (b *Baseline) Save(filename string) error {
+	sort.SliceStable(b.Accepted, func(i, j int) bool {
+		x, y := b.Accepted[i], b.Accepted[j]
+		if x.Resource != y.Resource {
+			return x.Resource < y.Resource
+		}
+		if x.RuleID != y.RuleID {
+			return x.RuleID < y.RuleID
+		}
+		return x.Path < y.Path
+	})
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(b); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, buf.Bytes(), 0o644)
+}
+
+// Accepts reports whether finding has been accepted by an entry that has not
+// expired.
+func (b *Baseline) Accepts(finding Finding) bool {
+	now := time.Now()
+	for _, entry := range b.Accepted {
+		if entry.matches(finding) && !entry.Expired(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// Accept adds finding to the baseline with the given justification, until
+// expires when it is not zero. An entry that already matches the finding is
+// renewed instead.
+func (b *Baseline) Accept(finding Finding, justification string, expires time.Time) {
+	entry := BaselineEntry{
+		RuleID:        finding.RuleID,
+		Resource:      finding.Key(),
+		Path:          finding.Path,
+		Justification: justification,
+	}
+	if !expires.IsZero() {
+		entry.Expires = expires.UTC().Format(CanaryLayout)
+	}
+	for i := range b.Accepted {
+		if b.Accepted[i].matches(finding) {
+			b.Accepted[i] = entry
+			return
+		}
+	}
+	b.Accepted = append(b.Accepted, entry)
+}
+
+// Filter splits findings into those still open and those the baseline accepts.
+func (b *Baseline) Filter(findings []Finding) (open, accepted []Finding) {
+	for _, finding := range findings {
+		if b.Accepts(finding) {
+			accepted = append(accepted, finding)
+		} else {
+			open = append(open, finding)
+		}
+	}
+	return open, accepted
+}
+
+// Expired reports whether the entry's expiry date has been reached by now.
+func (e BaselineEntry) Expired(now time.Time) bool {
+	return expired(e.Expires, now)
+}
+
+func (e BaselineEntry) matches(finding Finding) bool {
+	return e.RuleID == finding.RuleID && e.Resource == finding.Key() && e.Path == finding.Path
+}
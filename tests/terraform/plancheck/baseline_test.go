@@ -0,0 +1,84 @@
+package plancheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBaselineRoundTrip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "baseline.yaml")
+
+	baseline, err := LoadBaseline(filename)
+	require.NoError(t, err, "a missing baseline is empty")
+	require.Empty(t, baseline.Accepted)
+
+	accepted := Finding{RuleID: "iam.wildcard-resource", Address: "aws_iam_policy.logs", Region: "us-east-1", Path: "policy.Statement[0].Resource"}
+	baseline.Accept(accepted, "duplicate", time.Time{})
+	baseline.Accept(accepted, "CloudWatch Logs does not support resource-level permissions", time.Time{})
+	require.NoError(t, baseline.Save(filename))
+
+	loaded, err := LoadBaseline(filename)
+	require.NoError(t, err)
+	require.Len(t, loaded.Accepted, 1)
+	require.Equal(t, "us-east-1/aws_iam_policy.logs", loaded.Accepted[0].Resource)
+
+	otherPath := accepted.WithPath("policy.Statement[1].Resource")
+	open, filtered := loaded.Filter([]Finding{accepted, otherPath})
+	require.Equal(t, []Finding{otherPath}, open)
+	require.Equal(t, []Finding{accepted}, filtered)
+}
+
+func TestLoadBaselineRequiresJustification(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "baseline.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte("accepted:\n  - rule: iam.wildcard-action\n    resource: aws_iam_policy.x\n"), 0o644))
+
+	_, err := LoadBaseline(filename)
+	require.ErrorContains(t, err, "no justification")
+}
+
+func TestBaselineEntriesExpire(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "baseline.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte(`accepted:
+  - rule: iam.wildcard-action
+    resource: aws_iam_policy.ci
+    justification: CI deploys every stack
+    expires: 2000-01-01
+  - rule: iam.wildcard-resource
+    resource: us-east-1/aws_iam_policy.ci
+    justification: CI deploys every stack
+    expires: 2999-01-01
+`), 0o644))
+	baseline, err := LoadBaseline(filename)
+	require.NoError(t, err)
+
+	expired := Finding{RuleID: "iam.wildcard-action", Address: "aws_iam_policy.ci"}
+	current := Finding{RuleID: "iam.wildcard-resource", Address: "aws_iam_policy.ci", Region: "us-east-1"}
+	open, accepted := baseline.Filter([]Finding{expired, current})
+	require.Equal(t, []Finding{expired}, open, "an expired entry accepts nothing")
+	require.Equal(t, []Finding{current}, accepted)
+
+	require.Equal(t, []ExpiredWaiver{
+		{Key: "aws_iam_policy.ci", RuleIDs: []string{"iam.wildcard-action"}, Expires: "2000-01-01"},
+	}, ExpiredWaivers(baseline, nil, time.Now()))
+
+	// Accepting the finding again renews the entry.
+	baseline.Accept(expired, "still needed", time.Date(2999, 6, 1, 0, 0, 0, 0, time.UTC))
+	require.Len(t, baseline.Accepted, 2)
+	require.Equal(t, "2999-06-01", baseline.Accepted[0].Expires)
+	require.True(t, baseline.Accepts(expired))
+
+	require.NoError(t, os.WriteFile(filename, []byte("accepted:\n  - rule: iam.wildcard-action\n    resource: aws_iam_policy.x\n    justification: x\n    expires: next year\n"), 0o644))
+	_, err = LoadBaseline(filename)
+	require.ErrorContains(t, err, `expiry "next year" is not a date`)
+}
+
+func TestSplitKey(t *testing.T) {
+	region, address := SplitKey("eu-west-1/module.api.aws_iam_role.this")
+	require.Equal(t, []string{"eu-west-1", "module.api.aws_iam_role.this"}, []string{region, address})
+	region, address = SplitKey(`aws_s3_object.this["a/b"]`)
+	require.Equal(t, []string{"", `aws_s3_object.this["a/b"]`}, []string{region, address})
+}
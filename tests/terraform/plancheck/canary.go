@@ -0,0 +1,45 @@
+package plancheck
+
+import (
+	"fmt"
+	"time"
+)
+
+// CanaryLayout is the layout of canary dates.
+const CanaryLayout = "2006-01-02"
+
+// ParseCanary parses a canary date, the first day a rule fails runs, as
+// midnight UTC. An empty date is the zero time: no canary period.
+func ParseCanary(until string) (time.Time, error) {
+	if until == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(CanaryLayout, until)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("canary %q is not a date such as 2026-11-30", until)
+	}
+	return t, nil
+}
+
+// Canary returns the canary date compliance.yaml sets for a rule, if any.
+func (c *Config) Canary(ruleID string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	until, ok := c.Canaries[ruleID]
+	return until, ok
+}
+
+// canary returns the day rule starts failing runs when the input is judged
+// before it, so its findings are only reported.
+func (in *Input) canary(rule Rule) (string, bool) {
+	until := rule.Canary
+	if configured, ok := in.Config.Canary(rule.ID); ok {
+		until = configured
+	}
+	day, err := ParseCanary(until)
+	if err != nil || day.IsZero() {
+		return "", false
+	}
+	return until, in.Time().Before(day)
+}
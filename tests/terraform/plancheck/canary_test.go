@@ -0,0 +1,49 @@
+package plancheck
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateReportsCanaryRulesAsAdvisories(t *testing.T) {
+	rule := Rule{
+		ID:     "test.canary",
+		Canary: "2026-11-30",
+		Check: func(in *Input) []Finding {
+			return []Finding{
+				NewFinding("", "aws_s3_bucket.logs", "blocking"),
+				{Address: "aws_s3_bucket.logs", Message: "info", Severity: SeverityInfo},
+			}
+		},
+	}
+	before := time.Date(2026, 11, 29, 23, 0, 0, 0, time.UTC)
+	after := time.Date(2026, 11, 30, 0, 0, 0, 0, time.UTC)
+
+	findings := Evaluate(&Input{Now: before}, rule)
+	require.Len(t, findings, 2)
+	require.Equal(t, SeverityAdvisory, findings[0].Severity)
+	require.Equal(t, "2026-11-30", findings[0].Canary)
+	require.Equal(t, "[test.canary, canary until 2026-11-30]", findings[0].Label())
+	require.Equal(t, SeverityInfo, findings[1].Severity, "non-blocking findings keep their severity")
+	require.Empty(t, findings[1].Canary)
+
+	findings = Evaluate(&Input{Now: after}, rule)
+	require.True(t, findings[0].Severity.Blocking())
+	require.Empty(t, findings[0].Canary)
+
+	// compliance.yaml may extend the period, or end it early.
+	extended := &Config{Canaries: map[string]string{"test.canary": "2026-12-15"}}
+	findings = Evaluate(&Input{Now: after, Config: extended}, rule)
+	require.Equal(t, "2026-12-15", findings[0].Canary)
+
+	ended := &Config{Canaries: map[string]string{"test.canary": "2026-11-01"}}
+	findings = Evaluate(&Input{Now: before, Config: ended}, rule)
+	require.True(t, findings[0].Severity.Blocking())
+}
+
+func TestRegisterRejectsInvalidCanary(t *testing.T) {
+	err := register(Rule{ID: "test.bad-canary", Canary: "next month", Check: func(*Input) []Finding { return nil }})
+	require.ErrorContains(t, err, `canary "next month" is not a date`)
+}
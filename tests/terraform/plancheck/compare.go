@@ -0,0 +1,73 @@
+package plancheck
+
+// Comparison is the difference between the findings of two runs, e.g. the
+// main branch and a pull request.
+type Comparison struct {
+	New     []Finding        `json:"new"`
+	Fixed   []Finding        `json:"fixed"`
+	Changed []ChangedFinding `json:"changed"`
+
+	// Moved holds findings whose only difference is their source location,
+	// usually because lines were added above them. They are listed apart so
+	// they do not bury real changes.
+	Moved []ChangedFinding `json:"moved"`
+}
+
+// ChangedFinding is a finding present in both runs whose message, owner or
+// severity differs, or, in Comparison.Moved, whose source location differs.
+type ChangedFinding struct {
+	Before Finding `json:"before"`
+	After  Finding `json:"after"`
+}
+
+// Empty reports whether the two runs produced the same findings.
+func (c Comparison) Empty() bool {
+	return len(c.New) == 0 && len(c.Fixed) == 0 && len(c.Changed) == 0 && len(c.Moved) == 0
+}
+
+// Compare matches findings by rule, resource and attribute path and reports
+// the ones introduced, fixed, changed and moved between base and head. Only
+// the message, owner and severity count as a change; patches and suggested fixes are
+// derived from the finding and ignored.
+func Compare(base, head []Finding) Comparison {
+	before := make(map[string]Finding, len(base))
+	for _, finding := range base {
+		before[identity(finding)] = finding
+	}
+
+	comparison := Comparison{New: []Finding{}, Fixed: []Finding{}, Changed: []ChangedFinding{}, Moved: []ChangedFinding{}}
+	seen := make(map[string]bool, len(head))
+	for _, finding := range head {
+		id := identity(finding)
+		seen[id] = true
+		previous, ok := before[id]
+		switch {
+		case !ok:
+			comparison.New = append(comparison.New, finding)
+		case previous.Message != finding.Message || previous.Owner != finding.Owner || previous.Severity != finding.Severity:
+			comparison.Changed = append(comparison.Changed, ChangedFinding{Before: previous, After: finding})
+		case !sameSource(previous.Source, finding.Source):
+			comparison.Moved = append(comparison.Moved, ChangedFinding{Before: previous, After: finding})
+		}
+	}
+	for _, finding := range base {
+		if !seen[identity(finding)] {
+			comparison.Fixed = append(comparison.Fixed, finding)
+		}
+	}
+
+	SortFindings(comparison.New)
+	SortFindings(comparison.Fixed)
+	return comparison
+}
+
+func identity(f Finding) string {
+	return f.RuleID + "\x00" + f.Key() + "\x00" + f.Path
+}
+
+func sameSource(a, b *SourceLocation) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,39 @@
+package plancheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareReportsOnlyTheDelta(t *testing.T) {
+	unchanged := NewFinding("iam.wildcard-action", "aws_iam_policy.a", "wildcard Action").WithPath("policy.Statement[0].Action")
+	fixed := NewFinding("iam.wildcard-resource", "aws_iam_policy.a", "wildcard Resource")
+	moved := NewFinding("backup.coverage", "aws_dynamodb_table.t", "not backed up")
+	moved.Source = &SourceLocation{File: "main.tf", Line: 10}
+	reowned := NewFinding("logs.retention", "aws_cloudwatch_log_group.api", "no retention")
+	reowned.Owner = "platform"
+	introduced := NewFinding("iam.wildcard-resource", "aws_iam_policy.b", "wildcard Resource")
+	demoted := NewFinding("cost.gp2", "aws_ebs_volume.data", "gp2 volume")
+
+	movedAfter := moved
+	movedAfter.Source = &SourceLocation{File: "main.tf", Line: 14}
+	reownedAfter := reowned
+	reownedAfter.Owner = "api"
+	demotedAfter := demoted
+	demotedAfter.Severity = SeverityAdvisory
+	repatched := unchanged
+	repatched.Patch = "--- a/main.tf\n+++ b/main.tf\n"
+
+	comparison := Compare(
+		[]Finding{unchanged, fixed, moved, reowned, demoted},
+		[]Finding{repatched, movedAfter, reownedAfter, introduced, demotedAfter},
+	)
+
+	require.Equal(t, []Finding{introduced}, comparison.New)
+	require.Equal(t, []Finding{fixed}, comparison.Fixed)
+	require.Equal(t, []ChangedFinding{{Before: reowned, After: reownedAfter}, {Before: demoted, After: demotedAfter}}, comparison.Changed)
+	require.Equal(t, []ChangedFinding{{Before: moved, After: movedAfter}}, comparison.Moved)
+	require.False(t, comparison.Empty())
+	require.True(t, Compare([]Finding{unchanged}, []Finding{unchanged}).Empty())
+}
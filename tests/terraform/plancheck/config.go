@@ -0,0 +1,1052 @@
+package plancheck
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the compliance configuration shared by every rule, loaded from
+// compliance.yaml over the organization's defaults it extends. Settings that
+// vary between environments live under environments.<name>.
+type Config struct {
+	// Extends names the organization's configuration layer: an https:// or
+	// s3:// URL, or a file path relative to compliance.yaml. Its settings
+	// apply unless compliance.yaml sets them too.
+	Extends string `yaml:"extends,omitempty"`
+
+	// Locked lists, in the organization's layer, the dotted paths of
+	// settings repositories may not override, e.g. "severities" or
+	// "iam.allowed_action_patterns".
+	Locked []string `yaml:"locked,omitempty"`
+
+	Tags         TagPolicy              `yaml:"tags"`
+	DynamoDB     DynamoDBPolicy         `yaml:"dynamodb"`
+	Backend      BackendPolicy          `yaml:"backend"`
+	Storage      StoragePolicy          `yaml:"storage"`
+	Account      AccountPolicy          `yaml:"account"`
+	IAM          IAMPolicy              `yaml:"iam"`
+	Network      NetworkPolicy          `yaml:"network"`
+	Encryption   EncryptionPolicy       `yaml:"encryption"`
+	Changes      ChangePolicy           `yaml:"changes"`
+	Snapshot     SnapshotPolicy         `yaml:"snapshot"`
+	Secrets      SecretsPolicy          `yaml:"secrets"`
+	Lambda       FunctionPolicy         `yaml:"lambda"`
+	API          APIPolicy              `yaml:"api"`
+	Alarms       AlarmPolicy            `yaml:"alarms"`
+	Certificates CertificatePolicy      `yaml:"certificates"`
+	TLS          TLSPolicy              `yaml:"tls"`
+	Smoke        SmokePolicy            `yaml:"smoke"`
+	Load         LoadPolicy             `yaml:"load"`
+	Chaos        ChaosPolicy            `yaml:"chaos"`
+	Restore      RestorePolicy          `yaml:"restore"`
+	Logs         LogDeliveryPolicy      `yaml:"log_delivery"`
+	Pipeline     PipelinePolicy         `yaml:"pipeline"`
+	Lock         LockPolicy             `yaml:"lock"`
+	LocalStack   LocalStackPolicy       `yaml:"localstack"`
+	Environments map[string]Environment `yaml:"environments"`
+
+	// Severities overrides the severity of rules by ID, e.g. to demote a
+	// rule to advisory while its findings are fixed, without disabling it.
+	Severities map[string]Severity `yaml:"severities,omitempty"`
+
+	// Canaries sets, by rule ID, the day a rule leaves its canary period and
+	// starts failing runs, overriding the rule's own Canary date.
+	Canaries map[string]string `yaml:"canaries,omitempty"`
+}
+
+// Severity returns the severity configured for a rule, if any.
+func (c *Config) Severity(ruleID string) (Severity, bool) {
+	if c == nil {
+		return "", false
+	}
+	severity, ok := c.Severities[ruleID]
+	return severity, ok
+}
+
+// DynamoDBPolicy configures the DynamoDB table rules.
+type DynamoDBPolicy struct {
+	// TTLAttributes are attribute names that hold an expiry time; tables
+	// defining one must enable TTL on it.
+	TTLAttributes []string `yaml:"ttl_attributes"`
+
+	// GSISchema is a YAML file, relative to the configuration file, listing
+	// the approved global secondary indexes of each table.
+	GSISchema string `yaml:"gsi_schema"`
+
+	// Indexes is the content of GSISchema: table name -> index name -> index.
+	Indexes map[string]map[string]IndexSchema `yaml:"-"`
+}
+
+// StoragePolicy configures the storage cost advisories.
+type StoragePolicy struct {
+	// InfrequentPrefixes maps bucket name glob patterns to key prefixes that
+	// are rarely read, whose objects should move to a cheaper storage class
+	// through a lifecycle transition or intelligent tiering.
+	InfrequentPrefixes map[string][]string `yaml:"infrequent_prefixes,omitempty"`
+
+	// LogArchiveDays is the longest retention worth paying CloudWatch Logs
+	// prices for; log groups keeping logs longer should archive them to S3.
+	// Zero means DefaultLogArchiveDays.
+	LogArchiveDays int `yaml:"log_archive_days,omitempty"`
+}
+
+// DefaultLogArchiveDays is the LogArchiveDays used when none is configured.
+const DefaultLogArchiveDays = 90
+
+// ArchiveDays returns LogArchiveDays or its default.
+func (p StoragePolicy) ArchiveDays() int {
+	if p.LogArchiveDays == 0 {
+		return DefaultLogArchiveDays
+	}
+	return p.LogArchiveDays
+}
+
+// AccountPolicy configures the account baseline rule.
+type AccountPolicy struct {
+	// PasswordMinLength is the shortest IAM user password the account
+	// password policy may allow. Zero means DefaultPasswordMinLength.
+	PasswordMinLength int `yaml:"password_min_length,omitempty"`
+
+	// PasswordReusePrevention is how many previous passwords the policy must
+	// refuse. Zero means DefaultPasswordReusePrevention.
+	PasswordReusePrevention int `yaml:"password_reuse_prevention,omitempty"`
+}
+
+// Defaults for AccountPolicy, from the CIS AWS Foundations Benchmark.
+const (
+	DefaultPasswordMinLength       = 14
+	DefaultPasswordReusePrevention = 24
+)
+
+// MinLength returns PasswordMinLength or its default.
+func (p AccountPolicy) MinLength() int {
+	if p.PasswordMinLength == 0 {
+		return DefaultPasswordMinLength
+	}
+	return p.PasswordMinLength
+}
+
+// ReusePrevention returns PasswordReusePrevention or its default.
+func (p AccountPolicy) ReusePrevention() int {
+	if p.PasswordReusePrevention == 0 {
+		return DefaultPasswordReusePrevention
+	}
+	return p.PasswordReusePrevention
+}
+
+// IAMPolicy configures the IAM rules and advisories.
+type IAMPolicy struct {
+	// AllowedActionPatterns are wildcard actions, such as "logs:Create*",
+	// that policies may grant although they cover more than one action. A
+	// pattern also allows narrower wildcards it matches, e.g.
+	// "logs:CreateLog*".
+	AllowedActionPatterns []string `yaml:"allowed_action_patterns,omitempty"`
+
+	// TrustedAccounts are the account IDs, besides the environment's own,
+	// whose principals role trust policies may name.
+	TrustedAccounts []string `yaml:"trusted_accounts,omitempty"`
+
+	// ScopedServicePrincipals are the service principals that act on behalf
+	// of resources in any account, so trust policies naming them must
+	// require aws:SourceArn or aws:SourceAccount. Empty means
+	// DefaultScopedServicePrincipals.
+	ScopedServicePrincipals []string `yaml:"scoped_service_principals,omitempty"`
+
+	// EscalationCombos extend the built-in privilege escalation
+	// combinations iam.privilege-escalation looks for.
+	EscalationCombos []EscalationCombo `yaml:"escalation_combos,omitempty"`
+
+	// UnusedServiceDays is how long a role may go without using a service
+	// its policies grant before the grant is reported as unused. Zero means
+	// DefaultUnusedServiceDays.
+	UnusedServiceDays int `yaml:"unused_service_days,omitempty"`
+}
+
+// EscalationCombo is a set of actions that, granted together by one policy,
+// let its holder gain privileges it was not given.
+type EscalationCombo struct {
+	Name string `yaml:"name"`
+
+	// Actions must all be granted for the combination to apply.
+	Actions []string `yaml:"actions"`
+
+	// AnyResource limits the combination to grants on the "*" resource,
+	// e.g. sts:AssumeRole on every role.
+	AnyResource bool `yaml:"any_resource,omitempty"`
+}
+
+// DefaultScopedServicePrincipals are the ScopedServicePrincipals used when
+// none are configured: services AWS documents as open to the cross-service
+// confused deputy problem.
+var DefaultScopedServicePrincipals = []string{
+	"apigateway.amazonaws.com",
+	"cloudtrail.amazonaws.com",
+	"config.amazonaws.com",
+	"delivery.logs.amazonaws.com",
+	"events.amazonaws.com",
+	"logs.amazonaws.com",
+	"s3.amazonaws.com",
+	"scheduler.amazonaws.com",
+	"sns.amazonaws.com",
+	"states.amazonaws.com",
+}
+
+// ServicePrincipals returns ScopedServicePrincipals or its default.
+func (p IAMPolicy) ServicePrincipals() []string {
+	if len(p.ScopedServicePrincipals) == 0 {
+		return DefaultScopedServicePrincipals
+	}
+	return p.ScopedServicePrincipals
+}
+
+// DefaultUnusedServiceDays is the UnusedServiceDays used when none is
+// configured.
+const DefaultUnusedServiceDays = 90
+
+// UnusedDays returns UnusedServiceDays or its default.
+func (p IAMPolicy) UnusedDays() int {
+	if p.UnusedServiceDays == 0 {
+		return DefaultUnusedServiceDays
+	}
+	return p.UnusedServiceDays
+}
+
+// SecretsPolicy configures the secrets advisories.
+type SecretsPolicy struct {
+	// RotationDays is the longest a secret may go unrotated when the plan
+	// gives it no rotation schedule. Zero means DefaultSecretRotationDays.
+	RotationDays int `yaml:"rotation_days,omitempty"`
+
+	// Readers are glob patterns of principal ARNs, such as
+	// "arn:aws:iam::*:role/backup-*", expected to read any secret besides
+	// the roles the stack's own policies allow to.
+	Readers []string `yaml:"readers,omitempty"`
+}
+
+// DefaultSecretRotationDays is the RotationDays used when none is
+// configured.
+const DefaultSecretRotationDays = 90
+
+// MaxRotationDays returns RotationDays or its default.
+func (p SecretsPolicy) MaxRotationDays() int {
+	if p.RotationDays == 0 {
+		return DefaultSecretRotationDays
+	}
+	return p.RotationDays
+}
+
+// FunctionPolicy configures the Lambda function configuration rules.
+type FunctionPolicy struct {
+	// Runtimes are the supported runtimes, as glob patterns such as
+	// "python3.1*". Empty means DefaultLambdaRuntimes.
+	Runtimes []string `yaml:"runtimes,omitempty"`
+
+	// MaxTimeout is the longest timeout, in seconds, a function may set.
+	// Zero means DefaultLambdaMaxTimeout.
+	MaxTimeout int `yaml:"max_timeout,omitempty"`
+
+	// MaxMemory is the most memory, in MB, a function may set. Zero means
+	// DefaultLambdaMaxMemory.
+	MaxMemory int `yaml:"max_memory,omitempty"`
+
+	// Concurrency lists, by function_name glob pattern, the functions that
+	// must set reserved or provisioned concurrency, such as the handlers
+	// behind the registry API.
+	Concurrency []string `yaml:"concurrency,omitempty"`
+}
+
+// DefaultLambdaRuntimes are the Lambda runtimes AWS supports and has not
+// scheduled for deprecation.
+var DefaultLambdaRuntimes = []string{
+	"python3.10", "python3.11", "python3.12", "python3.13",
+	"nodejs20.x", "nodejs22.x",
+	"java17", "java21",
+	"dotnet8",
+	"ruby3.3", "ruby3.4",
+	"provided.al2023",
+}
+
+// DefaultLambdaMaxTimeout and DefaultLambdaMaxMemory are the MaxTimeout and
+// MaxMemory used when none is configured.
+const (
+	DefaultLambdaMaxTimeout = 300
+	DefaultLambdaMaxMemory  = 3008
+)
+
+// SupportedRuntimes returns Runtimes or its default.
+func (p FunctionPolicy) SupportedRuntimes() []string {
+	if len(p.Runtimes) == 0 {
+		return DefaultLambdaRuntimes
+	}
+	return p.Runtimes
+}
+
+// TimeoutLimit returns MaxTimeout or its default.
+func (p FunctionPolicy) TimeoutLimit() int {
+	if p.MaxTimeout == 0 {
+		return DefaultLambdaMaxTimeout
+	}
+	return p.MaxTimeout
+}
+
+// MemoryLimit returns MaxMemory or its default.
+func (p FunctionPolicy) MemoryLimit() int {
+	if p.MaxMemory == 0 {
+		return DefaultLambdaMaxMemory
+	}
+	return p.MaxMemory
+}
+
+// APIPolicy configures the API Gateway rules.
+type APIPolicy struct {
+	// PublicRoutes lists the routes that may be called without an
+	// authorizer, as "METHOD /path" glob patterns such as "GET /health" or
+	// "* /public/*". A pattern without a space matches a WebSocket route key
+	// such as "$connect".
+	PublicRoutes []string `yaml:"public_routes,omitempty"`
+}
+
+// Public reports whether route, e.g. "GET /health", is on PublicRoutes.
+func (p APIPolicy) Public(route string) bool {
+	method, routePath, _ := strings.Cut(route, " ")
+	for _, pattern := range p.PublicRoutes {
+		patternMethod, patternPath, ok := strings.Cut(pattern, " ")
+		if !ok {
+			if matched, _ := path.Match(pattern, route); matched {
+				return true
+			}
+			continue
+		}
+		methodMatched, _ := path.Match(patternMethod, method)
+		pathMatched, _ := path.Match(patternPath, routePath)
+		if methodMatched && pathMatched {
+			return true
+		}
+	}
+	return false
+}
+
+// AlarmPolicy configures the alarm rules for environments that set no
+// alarms of their own.
+type AlarmPolicy struct {
+	// MetricsFile is a YAML file, relative to the configuration file,
+	// mapping each critical resource type to the metrics every resource of
+	// that type needs an alarm on, as Environment.Alarms does.
+	MetricsFile string `yaml:"metrics_file,omitempty"`
+
+	// Metrics is the content of MetricsFile.
+	Metrics map[string][]string `yaml:"-"`
+}
+
+// CertificatePolicy configures the certificate expiry rule.
+type CertificatePolicy struct {
+	// MinDaysLeft is how many days a certificate must remain valid for.
+	// Zero means DefaultCertificateDaysLeft.
+	MinDaysLeft int `yaml:"min_days_left,omitempty"`
+}
+
+// DefaultCertificateDaysLeft is the MinDaysLeft used when none is
+// configured. ACM renews the certificates it issues 60 days before expiry,
+// so an issued certificate this close to expiry has failed to renew.
+const DefaultCertificateDaysLeft = 30
+
+// DaysLeft returns MinDaysLeft or its default.
+func (p CertificatePolicy) DaysLeft() int {
+	if p.MinDaysLeft == 0 {
+		return DefaultCertificateDaysLeft
+	}
+	return p.MinDaysLeft
+}
+
+// TLSPolicy configures the post-apply probe of the deployed endpoints.
+type TLSPolicy struct {
+	// MinVersion is the oldest TLS version endpoints may accept, e.g. "1.2".
+	// Empty means 1.2.
+	MinVersion string `yaml:"min_version,omitempty"`
+
+	// HSTSMaxAge is the shortest Strict-Transport-Security max-age, in
+	// seconds, endpoints may send. Zero means one year.
+	HSTSMaxAge int `yaml:"hsts_max_age,omitempty"`
+}
+
+// SmokePolicy configures the post-apply DNS and reachability checks.
+type SmokePolicy struct {
+	// HealthSLA is the longest, in milliseconds, a health check endpoint
+	// may take to answer. Zero means DefaultHealthSLA.
+	HealthSLA int `yaml:"health_sla_ms,omitempty"`
+}
+
+// DefaultHealthSLA is the HealthSLA used when none is set, in milliseconds.
+const DefaultHealthSLA = 2000
+
+// SLA returns HealthSLA, or its default, as a duration.
+func (p SmokePolicy) SLA() time.Duration {
+	if p.HealthSLA == 0 {
+		return DefaultHealthSLA * time.Millisecond
+	}
+	return time.Duration(p.HealthSLA) * time.Millisecond
+}
+
+// LoadPolicy configures the optional load smoke test of the deployed API.
+type LoadPolicy struct {
+	// Path is requested on the API's invoke URL. Empty means "/health".
+	Path string `yaml:"path,omitempty"`
+
+	// Rate is the number of requests per second. Zero means 20.
+	Rate int `yaml:"rate,omitempty"`
+
+	// Seconds is how long the burst lasts. Zero means 10.
+	Seconds int `yaml:"seconds,omitempty"`
+
+	// P95 is the highest p95 latency allowed, in milliseconds. Zero means
+	// 1000.
+	P95 int `yaml:"p95_ms,omitempty"`
+
+	// MaxErrorPercent is the highest share of failed or non-2xx/3xx requests
+	// allowed, in percent. Zero means 1.
+	MaxErrorPercent float64 `yaml:"max_error_percent,omitempty"`
+}
+
+// Defaults of the load smoke test.
+const (
+	DefaultLoadPath            = "/health"
+	DefaultLoadRate            = 20
+	DefaultLoadSeconds         = 10
+	DefaultLoadP95             = 1000
+	DefaultLoadMaxErrorPercent = 1
+)
+
+// RequestPath returns Path or its default.
+func (p LoadPolicy) RequestPath() string {
+	if p.Path == "" {
+		return DefaultLoadPath
+	}
+	return p.Path
+}
+
+// RequestRate returns Rate or its default.
+func (p LoadPolicy) RequestRate() int {
+	if p.Rate == 0 {
+		return DefaultLoadRate
+	}
+	return p.Rate
+}
+
+// Duration returns Seconds, or its default, as a duration.
+func (p LoadPolicy) Duration() time.Duration {
+	if p.Seconds == 0 {
+		return DefaultLoadSeconds * time.Second
+	}
+	return time.Duration(p.Seconds) * time.Second
+}
+
+// P95Latency returns P95, or its default, as a duration.
+func (p LoadPolicy) P95Latency() time.Duration {
+	if p.P95 == 0 {
+		return DefaultLoadP95 * time.Millisecond
+	}
+	return time.Duration(p.P95) * time.Millisecond
+}
+
+// MaxErrorRate returns MaxErrorPercent, or its default, as a fraction.
+func (p LoadPolicy) MaxErrorRate() float64 {
+	if p.MaxErrorPercent == 0 {
+		return DefaultLoadMaxErrorPercent / 100.0
+	}
+	return p.MaxErrorPercent / 100
+}
+
+// LogDeliveryPolicy configures the post-apply check that access logs reach
+// their log group.
+type LogDeliveryPolicy struct {
+	// Path is requested on each logged API stage. Empty means
+	// DefaultLogDeliveryPath.
+	Path string `yaml:"path,omitempty"`
+
+	// TimeoutSeconds is how long a request has to show up in its log group.
+	// Zero means DefaultLogDeliveryTimeoutSeconds.
+	TimeoutSeconds int `yaml:"timeout_seconds,omitempty"`
+}
+
+const (
+	// DefaultLogDeliveryPath is the Path used when none is set.
+	DefaultLogDeliveryPath = "/health"
+	// DefaultLogDeliveryTimeoutSeconds is the TimeoutSeconds used when none
+	// is set. API Gateway usually delivers access logs within a minute.
+	DefaultLogDeliveryTimeoutSeconds = 180
+)
+
+// RequestPath returns Path, or its default.
+func (p LogDeliveryPolicy) RequestPath() string {
+	if p.Path == "" {
+		return DefaultLogDeliveryPath
+	}
+	return p.Path
+}
+
+// Timeout returns TimeoutSeconds, or its default, as a duration.
+func (p LogDeliveryPolicy) Timeout() time.Duration {
+	if p.TimeoutSeconds == 0 {
+		return DefaultLogDeliveryTimeoutSeconds * time.Second
+	}
+	return time.Duration(p.TimeoutSeconds) * time.Second
+}
+
+// PipelinePolicy configures the post-apply check of the artifacts bucket's
+// event pipeline.
+type PipelinePolicy struct {
+	// TimeoutSeconds is how long the queue and handlers of a notification
+	// have to see a dropped object. Zero means DefaultPipelineTimeoutSeconds.
+	TimeoutSeconds int `yaml:"timeout_seconds,omitempty"`
+}
+
+// DefaultPipelineTimeoutSeconds is the TimeoutSeconds used when none is set:
+// SQS metrics can take several minutes to appear.
+const DefaultPipelineTimeoutSeconds = 600
+
+// Timeout returns TimeoutSeconds, or its default, as a duration.
+func (p PipelinePolicy) Timeout() time.Duration {
+	if p.TimeoutSeconds == 0 {
+		return DefaultPipelineTimeoutSeconds * time.Second
+	}
+	return time.Duration(p.TimeoutSeconds) * time.Second
+}
+
+// NetworkPolicy configures the security group rules.
+type NetworkPolicy struct {
+	// PublicIngress lists the ports security groups may open to the whole
+	// internet, such as 443 on a public load balancer's group.
+	PublicIngress []PublicIngress `yaml:"public_ingress,omitempty"`
+}
+
+// PublicIngress allows ingress from anywhere to some ports of some security
+// groups.
+type PublicIngress struct {
+	// SecurityGroups are glob patterns of aws_security_group addresses
+	// without instance keys, e.g. "module.ecs.aws_security_group.lb".
+	SecurityGroups []string `yaml:"security_groups"`
+	// Ports are the TCP and UDP ports that may be open.
+	Ports []int `yaml:"ports"`
+}
+
+// EncryptionPolicy configures the encryption at rest rules.
+type EncryptionPolicy struct {
+	// CustomerManagedKeys lists the resource types, e.g. aws_dynamodb_table,
+	// whose encryption must use a customer managed KMS key rather than an
+	// AWS managed or AWS owned one.
+	CustomerManagedKeys []string `yaml:"customer_managed_keys,omitempty"`
+}
+
+// AllowDestroyEnv names the environment variable listing, comma-separated,
+// the addresses a run may destroy despite changes.protected_types.
+const AllowDestroyEnv = "COMPLIANCE_ALLOW_DESTROY"
+
+// DefaultProtectedTypes are the stateful resource types a plan may not
+// destroy unless the run allows it.
+var DefaultProtectedTypes = []string{"aws_s3_bucket", "aws_dynamodb_table", "aws_db_instance", "aws_kms_key"}
+
+// ChangePolicy configures the checks of planned changes.
+type ChangePolicy struct {
+	// ProtectedTypes replaces DefaultProtectedTypes when set.
+	ProtectedTypes []string `yaml:"protected_types,omitempty"`
+}
+
+// Protected returns the resource types a plan may not destroy.
+func (p ChangePolicy) Protected() []string {
+	if len(p.ProtectedTypes) == 0 {
+		return DefaultProtectedTypes
+	}
+	return p.ProtectedTypes
+}
+
+// AllowDestroyFromEnv returns the addresses COMPLIANCE_ALLOW_DESTROY lists.
+func AllowDestroyFromEnv() []string {
+	var addresses []string
+	for _, address := range strings.Split(os.Getenv(AllowDestroyEnv), ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// SnapshotPolicy configures the plan snapshots.
+type SnapshotPolicy struct {
+	// Ignore lists attributes left out of snapshots as type.path, e.g.
+	// aws_lambda_function.source_code_hash or *.tags_all; both parts may be
+	// glob patterns, and the path is dotted as Lookup takes it.
+	Ignore []string `yaml:"ignore,omitempty"`
+}
+
+// LockPolicy configures the lock that keeps two runs from planning or
+// applying one environment at once.
+type LockPolicy struct {
+	// Table is the DynamoDB table, with a string partition key LockID,
+	// holding the locks; terraform's state lock table will do. Empty turns
+	// locking off.
+	Table string `yaml:"table,omitempty"`
+
+	// Region is the table's region. Empty means DefaultLockRegion.
+	Region string `yaml:"region,omitempty"`
+
+	// StaleMinutes is how long a run may go without refreshing its lock
+	// before another run takes it over. Zero means DefaultLockStaleMinutes.
+	StaleMinutes int `yaml:"stale_minutes,omitempty"`
+}
+
+// DefaultLockRegion is the Region used when none is set.
+const DefaultLockRegion = "us-east-1"
+
+// DefaultLockStaleMinutes is the StaleMinutes used when none is set. Held
+// locks are refreshed every third of it.
+const DefaultLockStaleMinutes = 15
+
+// TableRegion returns Region, or its default.
+func (p LockPolicy) TableRegion() string {
+	if p.Region == "" {
+		return DefaultLockRegion
+	}
+	return p.Region
+}
+
+// StaleAfter returns StaleMinutes, or its default, as a duration.
+func (p LockPolicy) StaleAfter() time.Duration {
+	if p.StaleMinutes == 0 {
+		return DefaultLockStaleMinutes * time.Minute
+	}
+	return time.Duration(p.StaleMinutes) * time.Minute
+}
+
+// LocalStackPolicy configures the opt-in apply of dev against LocalStack.
+type LocalStackPolicy struct {
+	// Image is the LocalStack image to run. Empty means DefaultLocalStackImage.
+	Image string `yaml:"image,omitempty"`
+
+	// Targets limits the apply to these resource and module addresses, for
+	// services the image does not emulate. Empty applies everything.
+	Targets []string `yaml:"targets,omitempty"`
+}
+
+// DefaultLocalStackImage is the Image used when none is set.
+const DefaultLocalStackImage = "localstack/localstack:3"
+
+// ImageName returns Image, or its default.
+func (p LocalStackPolicy) ImageName() string {
+	if p.Image == "" {
+		return DefaultLocalStackImage
+	}
+	return p.Image
+}
+
+// ChaosPolicy configures the opt-in chaos stage, which only runs against
+// sandbox environments.
+type ChaosPolicy struct {
+	// Experiments names the faults to inject, e.g. "stop-task". Empty runs
+	// every experiment the chaos package knows.
+	Experiments []string `yaml:"experiments,omitempty"`
+
+	// RecoverySeconds is how long the stack has to recover from each fault.
+	// Zero means DefaultChaosRecoverySeconds.
+	RecoverySeconds int `yaml:"recovery_seconds,omitempty"`
+}
+
+// DefaultChaosRecoverySeconds is the RecoverySeconds used when none is set:
+// long enough for Fargate to start a task and pass load balancer health
+// checks.
+const DefaultChaosRecoverySeconds = 300
+
+// Recovery returns RecoverySeconds, or its default, as a duration.
+func (p ChaosPolicy) Recovery() time.Duration {
+	if p.RecoverySeconds == 0 {
+		return DefaultChaosRecoverySeconds * time.Second
+	}
+	return time.Duration(p.RecoverySeconds) * time.Second
+}
+
+// RestorePolicy configures the opt-in restore check, which only runs
+// against sandbox environments.
+type RestorePolicy struct {
+	// Tables are glob patterns of the DynamoDB table names to back up and
+	// restore. Empty checks every planned table.
+	Tables []string `yaml:"tables,omitempty"`
+
+	// TimeoutMinutes is how long one table has to be backed up and restored.
+	// Zero means DefaultRestoreTimeoutMinutes.
+	TimeoutMinutes int `yaml:"timeout_minutes,omitempty"`
+}
+
+// DefaultRestoreTimeoutMinutes is the TimeoutMinutes used when none is set.
+// Restoring even a small table usually takes several minutes.
+const DefaultRestoreTimeoutMinutes = 30
+
+// Timeout returns TimeoutMinutes, or its default, as a duration.
+func (p RestorePolicy) Timeout() time.Duration {
+	if p.TimeoutMinutes == 0 {
+		return DefaultRestoreTimeoutMinutes * time.Minute
+	}
+	return time.Duration(p.TimeoutMinutes) * time.Minute
+}
+
+// BackendPolicy configures the state backend rules.
+type BackendPolicy struct {
+	// CIRole is the ARN of the role CI plans and applies with; the state
+	// bucket policy must deny every other principal.
+	CIRole string `yaml:"ci_role"`
+
+	// Buckets are glob patterns, such as "acme-terraform-state-*", naming
+	// the approved state buckets. Empty accepts any bucket.
+	Buckets []string `yaml:"buckets,omitempty"`
+
+	// KeyPattern is a regular expression every state key must match, with
+	// "{environment}" standing for the environment's name.
+	KeyPattern string `yaml:"key_pattern,omitempty"`
+}
+
+// KeyRegexp compiles KeyPattern for environment, or returns nil when no
+// pattern is set.
+func (p BackendPolicy) KeyRegexp(environment string) (*regexp.Regexp, error) {
+	if p.KeyPattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(strings.ReplaceAll(p.KeyPattern, "{environment}", regexp.QuoteMeta(environment)))
+}
+
+// IndexSchema is an approved global secondary index.
+type IndexSchema struct {
+	HashKey        string `yaml:"hash_key"`
+	RangeKey       string `yaml:"range_key,omitempty"`
+	ProjectionType string `yaml:"projection_type"`
+}
+
+// TagPolicy lists the tags every taggable resource must carry.
+type TagPolicy struct {
+	// Required maps each tag key to the value suggested when it is missing;
+	// an empty value means the owner has to choose one.
+	Required map[string]string `yaml:"required"`
+
+	// Patterns maps tag keys to regular expressions their values must match
+	// wherever the tag is set, e.g. "^(dev|stage|prod)$". "{environment}"
+	// stands for the environment's name.
+	Patterns map[string]string `yaml:"patterns,omitempty"`
+
+	// CostAllocation maps module paths, such as "module.monitoring", to the
+	// cost allocation tags their resources must carry and the value of each.
+	// "*" applies to every module, including the root module; entries for a
+	// module and its parents override it. "{environment}" in a value stands
+	// for the environment's name.
+	CostAllocation map[string]map[string]string `yaml:"cost_allocation,omitempty"`
+}
+
+// PatternRegexp compiles the pattern for the tag key in environment, or
+// returns nil when the tag has none.
+func (p TagPolicy) PatternRegexp(key, environment string) (*regexp.Regexp, error) {
+	pattern, ok := p.Patterns[key]
+	if !ok {
+		return nil, nil
+	}
+	return regexp.Compile(strings.ReplaceAll(pattern, "{environment}", regexp.QuoteMeta(environment)))
+}
+
+// CostTags returns the cost allocation tags, with their values, resources in
+// the module at modulePath must carry in environment.
+func (p TagPolicy) CostTags(modulePath, environment string) map[string]string {
+	entries := []string{"*"}
+	parts := splitAddress(modulePath)
+	for i := 2; i <= len(parts); i += 2 {
+		entries = append(entries, strings.Join(parts[:i], "."))
+	}
+
+	tags := map[string]string{}
+	for _, entry := range entries {
+		for key, value := range p.CostAllocation[entry] {
+			tags[key] = strings.ReplaceAll(value, "{environment}", environment)
+		}
+	}
+	return tags
+}
+
+// Environment holds the per-environment rule settings.
+type Environment struct {
+	// Account and Region are where the environment is deployed. An empty
+	// Account is taken to be shared with every other environment.
+	Account string `yaml:"account,omitempty"`
+	Region  string `yaml:"region,omitempty"`
+
+	// Role is the ARN of the role assumed before planning or reading the
+	// environment's account. Empty uses the caller's own credentials.
+	Role string `yaml:"role,omitempty"`
+
+	// Production marks environments that serve users, where rules allow no
+	// exceptions that only make sense while developing.
+	Production bool `yaml:"production,omitempty"`
+
+	// Sandbox marks disposable environments, the only ones that may keep
+	// their state in a local backend.
+	Sandbox bool `yaml:"sandbox,omitempty"`
+
+	// Backup enables the backup coverage rules. Environments without it,
+	// such as disposable sandboxes, are not required to be recoverable.
+	Backup *BackupPolicy `yaml:"backup,omitempty"`
+
+	// Replication lists the buckets that must be replicated to a disaster
+	// recovery region.
+	Replication *ReplicationPolicy `yaml:"replication,omitempty"`
+
+	// Lambda exempts functions from the Lambda hardening rules. Rules read
+	// it through LambdaExceptions, which ignores it in production.
+	Lambda LambdaPolicy `yaml:"lambda,omitempty"`
+
+	// Alarms maps each critical resource type to the metrics that must have
+	// a CloudWatch alarm on every resource of that type. An empty list
+	// requires an alarm on any metric. Environments without it get the
+	// top-level alarms.metrics_file, except sandboxes nobody is paged for,
+	// which need no alarms and whose alarms need not notify anyone. Rules
+	// read it through Config.RequiredAlarms.
+	Alarms map[string][]string `yaml:"alarms,omitempty"`
+
+	// InstanceTypes lists the instance classes the environment may run.
+	// Environments without it accept any.
+	InstanceTypes *InstanceTypePolicy `yaml:"instance_types,omitempty"`
+
+	// Spot sets how autoscaling groups must use spot capacity. Environments
+	// without it may mix spot and on-demand capacity as they like.
+	Spot *SpotPolicy `yaml:"spot,omitempty"`
+
+	// ScaleDown requires autoscaling groups and RDS databases to be scaled
+	// to zero or stopped on a schedule, for environments nobody uses
+	// overnight.
+	ScaleDown *ScaleDownPolicy `yaml:"scale_down,omitempty"`
+}
+
+// ScaleDownPolicy configures the scheduled scale-down rule.
+type ScaleDownPolicy struct {
+	// ScheduleTag is the tag an external scheduler, such as AWS Instance
+	// Scheduler, reads; resources carrying it count as scheduled. Empty
+	// counts only schedules in the plan.
+	ScheduleTag string `yaml:"schedule_tag,omitempty"`
+}
+
+// SpotPolicy says which autoscaling groups must, and which must not, run on
+// spot capacity. Groups are critical when they carry CriticalTag.
+type SpotPolicy struct {
+	// CriticalTag is a "key=value" tag marking critical groups; empty means
+	// "Criticality=critical".
+	CriticalTag string `yaml:"critical_tag,omitempty"`
+
+	// RequireForNonCritical makes groups that are not critical use a mixed
+	// instances policy with spot capacity and an allocation strategy.
+	RequireForNonCritical bool `yaml:"require_noncritical,omitempty"`
+
+	// ForbidForCritical keeps critical groups on on-demand capacity only.
+	ForbidForCritical bool `yaml:"forbid_critical,omitempty"`
+}
+
+// Critical returns the tag key and value that mark critical groups.
+func (p SpotPolicy) Critical() (key, value string) {
+	tag := p.CriticalTag
+	if tag == "" {
+		tag = "Criticality=critical"
+	}
+	key, value, _ = strings.Cut(tag, "=")
+	return key, value
+}
+
+// InstanceTypePolicy lists approved instance classes, as glob patterns such as
+// "t4g.*", per service.
+type InstanceTypePolicy struct {
+	// EC2 covers instances and launch templates, e.g. "t3.micro".
+	EC2 []string `yaml:"ec2,omitempty"`
+	// RDS covers database instances, e.g. "db.t4g.small".
+	RDS []string `yaml:"rds,omitempty"`
+	// ElastiCache covers cache clusters and replication groups, e.g.
+	// "cache.t4g.micro".
+	ElastiCache []string `yaml:"elasticache,omitempty"`
+}
+
+// BackupPolicy describes the recovery objectives of an environment.
+type BackupPolicy struct {
+	// RPO is the maximum acceptable data loss window. Backup plan schedules
+	// must run at least this often.
+	RPO Duration `yaml:"rpo"`
+}
+
+// ReplicationPolicy describes the cross-region replication an environment
+// requires.
+type ReplicationPolicy struct {
+	// Buckets are the names of the S3 buckets that must be replicated.
+	Buckets []string `yaml:"buckets"`
+	// Region is the disaster recovery region replicas must live in.
+	Region string `yaml:"region"`
+}
+
+// LambdaPolicy lists Lambda functions, by function_name glob pattern such as
+// "*" or "pkg-dev-*", that are exempt from a hardening rule.
+type LambdaPolicy struct {
+	CodeSigningExceptions []string `yaml:"code_signing_exceptions,omitempty"`
+	TracingExceptions     []string `yaml:"tracing_exceptions,omitempty"`
+}
+
+// LambdaExceptions returns the functions exempt from the Lambda hardening
+// rules. Production environments get none, whatever their lambda section
+// says.
+func (e Environment) LambdaExceptions() LambdaPolicy {
+	if e.Production {
+		return LambdaPolicy{}
+	}
+	return e.Lambda
+}
+
+// Duration is a time.Duration that also accepts a day suffix ("7d") in YAML.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalYAML parses values such as "15m", "24h" or "7d".
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// ParseDuration extends time.ParseDuration with a "d" (day) unit.
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return parsed, nil
+}
+
+// LoadConfig reads a compliance configuration file, layered over the
+// organization's configuration it extends.
+func LoadConfig(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err = layerConfig(filename, data); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	var config Config
+	if err := decodeYAML(data, &config); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	for ruleID, severity := range config.Severities {
+		if severity == "" || !severity.Valid() {
+			return nil, fmt.Errorf("%s: severities.%s: unknown severity %q (want error, advisory or info)", filename, ruleID, severity)
+		}
+	}
+
+	for ruleID, until := range config.Canaries {
+		if _, err := ParseCanary(until); err != nil || until == "" {
+			return nil, fmt.Errorf("%s: canaries.%s: %q is not a date such as 2026-11-30", filename, ruleID, until)
+		}
+	}
+
+	if _, err := config.Backend.KeyRegexp(""); err != nil {
+		return nil, fmt.Errorf("%s: backend.key_pattern: %w", filename, err)
+	}
+	for _, pattern := range config.Snapshot.Ignore {
+		typePattern, attribute, ok := strings.Cut(pattern, ".")
+		if !ok || attribute == "" {
+			return nil, fmt.Errorf("%s: snapshot.ignore: %q is not type.attribute", filename, pattern)
+		}
+		for _, part := range []string{typePattern, attribute} {
+			if _, err := path.Match(part, ""); err != nil {
+				return nil, fmt.Errorf("%s: snapshot.ignore: %q: %w", filename, pattern, err)
+			}
+		}
+	}
+	for key := range config.Tags.Patterns {
+		if _, err := config.Tags.PatternRegexp(key, ""); err != nil {
+			return nil, fmt.Errorf("%s: tags.patterns.%s: %w", filename, key, err)
+		}
+	}
+
+	if schema := config.DynamoDB.GSISchema; schema != "" {
+		if !filepath.IsAbs(schema) {
+			schema = filepath.Join(filepath.Dir(filename), schema)
+		}
+		data, err := os.ReadFile(schema)
+		if err != nil {
+			return nil, fmt.Errorf("%s: gsi_schema: %w", filename, err)
+		}
+		var indexes struct {
+			Tables map[string]map[string]IndexSchema `yaml:"tables"`
+		}
+		if err := decodeYAML(data, &indexes); err != nil {
+			return nil, fmt.Errorf("%s: %w", schema, err)
+		}
+		config.DynamoDB.Indexes = indexes.Tables
+	}
+
+	if metrics := config.Alarms.MetricsFile; metrics != "" {
+		if !filepath.IsAbs(metrics) {
+			metrics = filepath.Join(filepath.Dir(filename), metrics)
+		}
+		data, err := os.ReadFile(metrics)
+		if err != nil {
+			return nil, fmt.Errorf("%s: alarms.metrics_file: %w", filename, err)
+		}
+		var mapping struct {
+			Metrics map[string][]string `yaml:"metrics"`
+		}
+		if err := decodeYAML(data, &mapping); err != nil {
+			return nil, fmt.Errorf("%s: %w", metrics, err)
+		}
+		config.Alarms.Metrics = mapping.Metrics
+	}
+	return &config, nil
+}
+
+// decodeYAML decodes strictly so typos in setting names are reported.
+func decodeYAML(data []byte, v interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	return decoder.Decode(v)
+}
+
+// RequiredAlarms returns the metrics each critical resource type needs an
+// alarm on in the named environment: its own alarms, or else, unless it is a
+// sandbox, those of alarms.metrics_file. Nil requires no alarms.
+func (c *Config) RequiredAlarms(environment string) map[string][]string {
+	settings := c.Environment(environment)
+	if settings.Alarms != nil {
+		return settings.Alarms
+	}
+	if c == nil || settings.Sandbox {
+		return nil
+	}
+	return c.Alarms.Metrics
+}
+
+// Environment returns the settings for the named environment, or the zero
+// value when the environment is not configured.
+func (c *Config) Environment(name string) Environment {
+	if c == nil {
+		return Environment{}
+	}
+	return c.Environments[name]
+}
@@ -0,0 +1,81 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// TypeCoverage counts the rules that apply to one resource type in a plan.
+type TypeCoverage struct {
+	Type      string   `json:"type"`
+	Resources int      `json:"resources"`
+	Rules     []string `json:"rules"`
+}
+
+// Coverage lists every managed resource type in plan with the rules whose
+// ResourceTypes include it, least covered first, so blind spots stand out.
+func Coverage(plan *tfjson.Plan, rules ...Rule) []TypeCoverage {
+	if len(rules) == 0 {
+		rules = Rules()
+	}
+
+	counts := map[string]int{}
+	for _, resource := range PlannedResources(plan) {
+		if resource != nil && resource.Mode == tfjson.ManagedResourceMode {
+			counts[resource.Type]++
+		}
+	}
+
+	coverage := make([]TypeCoverage, 0, len(counts))
+	for resourceType, count := range counts {
+		entry := TypeCoverage{Type: resourceType, Resources: count, Rules: []string{}}
+		for _, rule := range rules {
+			for _, t := range rule.ResourceTypes {
+				if t == resourceType {
+					entry.Rules = append(entry.Rules, rule.ID)
+					break
+				}
+			}
+		}
+		coverage = append(coverage, entry)
+	}
+
+	sort.Slice(coverage, func(i, j int) bool {
+		a, b := coverage[i], coverage[j]
+		if len(a.Rules) != len(b.Rules) {
+			return len(a.Rules) < len(b.Rules)
+		}
+		return a.Type < b.Type
+	})
+	return coverage
+}
+
+// WriteCoverage writes a coverage report as indented JSON.
+func WriteCoverage(filename string, coverage []TypeCoverage) error {
+	data, err := json.MarshalIndent(coverage, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// PrintCoverage writes one line per resource type:
+// "aws_apigatewayv2_api: 0 rules (2 resources)".
+func PrintCoverage(w io.Writer, coverage []TypeCoverage) {
+	for _, entry := range coverage {
+		rules := "rules"
+		if len(entry.Rules) == 1 {
+			rules = "rule"
+		}
+		fmt.Fprintf(w, "%s: %d %s (%d resources)", entry.Type, len(entry.Rules), rules, entry.Resources)
+		if len(entry.Rules) > 0 {
+			fmt.Fprintf(w, " %v", entry.Rules)
+		}
+		fmt.Fprintln(w)
+	}
+}
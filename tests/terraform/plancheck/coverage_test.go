@@ -0,0 +1,37 @@
+package plancheck
+
+import (
+	"bytes"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverageListsUncoveredTypesFirst(t *testing.T) {
+	plan := &tfjson.Plan{PlannedValues: &tfjson.StateValues{RootModule: &tfjson.StateModule{
+		Resources: []*tfjson.StateResource{
+			{Address: "aws_iam_policy.a", Mode: tfjson.ManagedResourceMode, Type: "aws_iam_policy"},
+			{Address: "aws_iam_policy.b", Mode: tfjson.ManagedResourceMode, Type: "aws_iam_policy"},
+			{Address: "data.aws_iam_policy_document.a", Mode: tfjson.DataResourceMode, Type: "aws_iam_policy_document"},
+		},
+		ChildModules: []*tfjson.StateModule{{Resources: []*tfjson.StateResource{
+			{Address: "module.api.aws_apigatewayv2_api.this", Mode: tfjson.ManagedResourceMode, Type: "aws_apigatewayv2_api"},
+		}}},
+	}}}
+	noop := func(*Input) []Finding { return nil }
+	rules := []Rule{
+		{ID: "iam.wildcard-action", ResourceTypes: []string{"aws_iam_policy"}, Check: noop},
+		{ID: "tags.required", Check: noop},
+	}
+
+	coverage := Coverage(plan, rules...)
+	require.Equal(t, []TypeCoverage{
+		{Type: "aws_apigatewayv2_api", Resources: 1, Rules: []string{}},
+		{Type: "aws_iam_policy", Resources: 2, Rules: []string{"iam.wildcard-action"}},
+	}, coverage)
+
+	var out bytes.Buffer
+	PrintCoverage(&out, coverage)
+	require.Equal(t, "aws_apigatewayv2_api: 0 rules (1 resources)\naws_iam_policy: 1 rule (2 resources) [iam.wildcard-action]\n", out.String())
+}
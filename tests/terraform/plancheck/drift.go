@@ -0,0 +1,157 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Drift is a deployed resource that no longer matches the configuration:
+// changed outside terraform, as terraform found on refresh, or with a change
+// still to apply.
+type Drift struct {
+	Address string
+	Type    string
+	// Outside is set for a change made outside terraform, e.g. in the
+	// console; Action is then "changed outside terraform".
+	Outside bool
+	// Action is what the plan would do: create, update, delete or replace.
+	Action string
+	// Paths are the attributes that differ, in the dotted form Lookup
+	// accepts; empty for a resource created or deleted whole.
+	Paths []string
+}
+
+// driftPlan holds the parts of terraform show -json output Drifts reads.
+// The terraform-json version in use predates resource_drift.
+type driftPlan struct {
+	ResourceDrift   []*tfjson.ResourceChange `json:"resource_drift"`
+	ResourceChanges []*tfjson.ResourceChange `json:"resource_changes"`
+}
+
+// Drifts parses terraform show -json output of a plan of a deployed
+// environment and returns its drift: the resources changed outside
+// terraform, then those the plan changes, each ordered by address.
+func Drifts(planJSON []byte) ([]Drift, error) {
+	var plan driftPlan
+	if err := json.Unmarshal(planJSON, &plan); err != nil {
+		return nil, fmt.Errorf("parse plan JSON: %w", err)
+	}
+
+	var outside, planned []Drift
+	for _, change := range plan.ResourceDrift {
+		if change.Change == nil || change.Mode == tfjson.DataResourceMode {
+			continue
+		}
+		outside = append(outside, Drift{
+			Address: change.Address,
+			Type:    change.Type,
+			Outside: true,
+			Action:  "changed outside terraform",
+			Paths:   changedPaths(change),
+		})
+	}
+	for _, change := range plan.ResourceChanges {
+		if change.Change == nil || change.Mode == tfjson.DataResourceMode {
+			continue
+		}
+		action := driftAction(change.Change.Actions)
+		if action == "" {
+			continue
+		}
+		planned = append(planned, Drift{
+			Address: change.Address,
+			Type:    change.Type,
+			Action:  action,
+			Paths:   changedPaths(change),
+		})
+	}
+
+	for _, drifts := range [][]Drift{outside, planned} {
+		sort.Slice(drifts, func(i, j int) bool { return drifts[i].Address < drifts[j].Address })
+	}
+	return append(outside, planned...), nil
+}
+
+// driftAction names a planned change, or returns "" for none.
+func driftAction(actions tfjson.Actions) string {
+	switch {
+	case actions.Replace():
+		return "replace"
+	case actions.Create():
+		return "create"
+	case actions.Update():
+		return "update"
+	case actions.Delete():
+		return "delete"
+	}
+	return ""
+}
+
+// changedPaths lists the attributes whose values differ before and after a
+// change, leaving out those unknown until apply.
+func changedPaths(change *tfjson.ResourceChange) []string {
+	before, _ := change.Change.Before.(map[string]interface{})
+	after, _ := change.Change.After.(map[string]interface{})
+	if before == nil || after == nil {
+		return nil
+	}
+	unknown, _ := change.Change.AfterUnknown.(map[string]interface{})
+
+	var changes []ValueChange
+	diffValues(change.Address, "", before, after, &changes)
+	var paths []string
+	for _, c := range changes {
+		if unknownAt(unknown, c.Path) {
+			continue
+		}
+		paths = append(paths, c.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// unknownAt reports whether after_unknown marks path, or an attribute
+// holding it, as unknown.
+func unknownAt(unknown map[string]interface{}, path string) bool {
+	parts := strings.Split(path, ".")
+	for i := range parts {
+		if LookupBool(unknown, strings.Join(parts[:i+1], ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the drift as one line, e.g.
+// "aws_s3_bucket.artifacts: changed outside terraform (tags.Owner)".
+func (d Drift) String() string {
+	if len(d.Paths) == 0 {
+		return d.Address + ": " + d.Action
+	}
+	return fmt.Sprintf("%s: %s (%s)", d.Address, d.Action, strings.Join(d.Paths, ", "))
+}
+
+// WriteDriftSummary writes drifts grouped by resource type, types in
+// alphabetical order.
+func WriteDriftSummary(w io.Writer, drifts []Drift) {
+	byType := map[string][]Drift{}
+	var types []string
+	for _, drift := range drifts {
+		if _, ok := byType[drift.Type]; !ok {
+			types = append(types, drift.Type)
+		}
+		byType[drift.Type] = append(byType[drift.Type], drift)
+	}
+	sort.Strings(types)
+	for _, resourceType := range types {
+		fmt.Fprintf(w, "%s (%d):\n", resourceType, len(byType[resourceType]))
+		for _, drift := range byType[resourceType] {
+			fmt.Fprintf(w, "  %s\n", drift)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package plancheck
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const driftedPlan = `{
+  "format_version": "1.2",
+  "resource_drift": [
+    {"address": "aws_s3_bucket.artifacts", "mode": "managed", "type": "aws_s3_bucket", "name": "artifacts",
+     "change": {"actions": ["update"],
+       "before": {"bucket": "pkg-artifacts", "tags": {"Owner": "platform"}},
+       "after": {"bucket": "pkg-artifacts", "tags": {"Owner": "someone"}}}},
+    {"address": "data.aws_iam_policy_document.api", "mode": "data", "type": "aws_iam_policy_document", "name": "api",
+     "change": {"actions": ["update"], "before": {"json": "{}"}, "after": {"json": "{ }"}}}
+  ],
+  "resource_changes": [
+    {"address": "aws_s3_bucket.artifacts", "mode": "managed", "type": "aws_s3_bucket", "name": "artifacts",
+     "change": {"actions": ["update"],
+       "before": {"bucket": "pkg-artifacts", "tags": {"Owner": "someone"}, "tags_all": {"Owner": "someone"}},
+       "after": {"bucket": "pkg-artifacts", "tags": {"Owner": "platform"}},
+       "after_unknown": {"tags_all": true}}},
+    {"address": "aws_iam_role.api_task", "mode": "managed", "type": "aws_iam_role", "name": "api_task",
+     "change": {"actions": ["create"], "before": null, "after": {"name": "api-task"}}},
+    {"address": "aws_dynamodb_table.packages", "mode": "managed", "type": "aws_dynamodb_table", "name": "packages",
+     "change": {"actions": ["no-op"], "before": {"name": "packages"}, "after": {"name": "packages"}}},
+    {"address": "aws_dynamodb_table.users", "mode": "managed", "type": "aws_dynamodb_table", "name": "users",
+     "change": {"actions": ["delete", "create"], "before": {"hash_key": "id"}, "after": {"hash_key": "user_id"}}}
+  ]
+}`
+
+func TestDriftsGroupsChangesOutsideAndPlanned(t *testing.T) {
+	drifts, err := Drifts([]byte(driftedPlan))
+	require.NoError(t, err)
+	require.Equal(t, []Drift{
+		{Address: "aws_s3_bucket.artifacts", Type: "aws_s3_bucket", Outside: true, Action: "changed outside terraform", Paths: []string{"tags.Owner"}},
+		{Address: "aws_dynamodb_table.users", Type: "aws_dynamodb_table", Action: "replace", Paths: []string{"hash_key"}},
+		{Address: "aws_iam_role.api_task", Type: "aws_iam_role", Action: "create"},
+		{Address: "aws_s3_bucket.artifacts", Type: "aws_s3_bucket", Action: "update", Paths: []string{"tags.Owner"}},
+	}, drifts)
+
+	var out bytes.Buffer
+	WriteDriftSummary(&out, drifts)
+	require.Equal(t, `aws_dynamodb_table (1):
+  aws_dynamodb_table.users: replace (hash_key)
+aws_iam_role (1):
+  aws_iam_role.api_task: create
+aws_s3_bucket (2):
+  aws_s3_bucket.artifacts: changed outside terraform (tags.Owner)
+  aws_s3_bucket.artifacts: update (tags.Owner)
+`, out.String())
+
+	_, err = Drifts([]byte("not json"))
+	require.ErrorContains(t, err, "parse plan JSON")
+}
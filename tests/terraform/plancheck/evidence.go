@@ -0,0 +1,70 @@
+package plancheck
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Evidence is the fragment of plan JSON that triggered a finding, kept with
+// the finding so reviewers can check it without re-running terraform.
+type Evidence json.RawMessage
+
+// MarshalJSON returns the fragment as is.
+func (e Evidence) MarshalJSON() ([]byte, error) {
+	if len(e) == 0 {
+		return []byte("null"), nil
+	}
+	return e, nil
+}
+
+// UnmarshalJSON stores the fragment compacted, so evidence read back from an
+// indented report equals the evidence the rule produced.
+func (e *Evidence) UnmarshalJSON(data []byte) error {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return err
+	}
+	*e = compact.Bytes()
+	return nil
+}
+
+// NewEvidence encodes v as evidence.
+func NewEvidence(v interface{}) Evidence {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// EvidenceAt returns the evidence for an attribute path within a resource's
+// planned values: the innermost object within the attribute around the value
+// the path points at, such as the policy statement holding a wildcard action
+// or a nested block, or else {"attribute": value}. Paths into values the plan
+// does not contain stop at the deepest object that exists.
+func EvidenceAt(values map[string]interface{}, path string) Evidence {
+	chain, _ := walkPath(values, path)
+	for i := len(chain) - 1; i > 1; i-- {
+		if object, ok := chain[i].(map[string]interface{}); ok {
+			return NewEvidence(object)
+		}
+	}
+	attribute := rootAttribute(path)
+	if attribute == "" {
+		return nil
+	}
+	return NewEvidence(map[string]interface{}{attribute: values[attribute]})
+}
+
+// attachEvidence sets the evidence of findings that have an attribute path
+// but no evidence of their own, from the planned values of their resource.
+func attachEvidence(in *Input, findings []Finding) {
+	for i, finding := range findings {
+		if finding.Evidence != nil || finding.Path == "" {
+			continue
+		}
+		if resource := in.resource(finding.Address); resource != nil {
+			findings[i].Evidence = EvidenceAt(resource.AttributeValues, finding.Path)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Package plancheck holds the types shared by the terraform compliance tests:
+// findings produced by checks against a plan, and the reports built from them.
+package plancheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Finding is a single compliance violation reported against a planned resource.
+type Finding struct {
+	RuleID  string `json:"rule_id"`
+	Address string `json:"address"`
+	Module  string `json:"module"`
+	Region  string `json:"region,omitempty"`
+	Owner   string `json:"owner,omitempty"`
+	Message string `json:"message"`
+
+	// MessageKey and MessageArgs identify the message in a Catalog, so it can
+	// be rendered in the reader's language. Message is the English rendering.
+	MessageKey  string            `json:"message_key,omitempty"`
+	MessageArgs map[string]string `json:"message_args,omitempty"`
+
+	// Severity is empty for findings that fail the run.
+	Severity Severity `json:"severity,omitempty"`
+
+	// Canary is the day the finding's rule starts failing runs, when it is
+	// still in its canary period; the finding is then an advisory.
+	Canary string `json:"canary,omitempty"`
+
+	// Path is the attribute path of the offending value within the resource,
+	// e.g. "policy.Statement[2].Action[0]".
+	Path string `json:"path,omitempty"`
+
+	// Evidence is the plan JSON the finding was raised on, e.g. the policy
+	// statement or nested block around Path.
+	Evidence Evidence `json:"evidence,omitempty"`
+
+	// Source points at the .tf file and line that declare the value, when the
+	// configuration could be located.
+	Source *SourceLocation `json:"source,omitempty"`
+
+	// Fix describes a mechanical change to the resource's configuration that
+	// resolves the finding, when the rule knows one.
+	Fix *Fix `json:"fix,omitempty"`
+
+	// Patch is a unified diff implementing Fix against the .tf source.
+	Patch string `json:"patch,omitempty"`
+}
+
+// Severity says whether a finding fails the run.
+type Severity string
+
+const (
+	// SeverityError findings fail the run. Findings without a severity are
+	// errors.
+	SeverityError Severity = "error"
+	// SeverityAdvisory findings, such as cost smells, are reported for the
+	// owner to weigh but never fail the run.
+	SeverityAdvisory Severity = "advisory"
+	// SeverityInfo findings are notes that ask nothing of the owner, such as
+	// a rule that was demoted while a fix is rolled out. They never fail the
+	// run.
+	SeverityInfo Severity = "info"
+)
+
+// Valid reports whether s is a known severity or empty.
+func (s Severity) Valid() bool {
+	switch s {
+	case "", SeverityError, SeverityAdvisory, SeverityInfo:
+		return true
+	}
+	return false
+}
+
+// Blocking reports whether findings of this severity fail the run.
+func (s Severity) Blocking() bool {
+	return s != SeverityAdvisory && s != SeverityInfo
+}
+
+// rank orders severities from blocking to informational.
+func (s Severity) rank() int {
+	switch s {
+	case SeverityAdvisory:
+		return 1
+	case SeverityInfo:
+		return 2
+	}
+	return 0
+}
+
+// Advisory reports whether the finding is only advice: it does not fail the
+// run.
+func (f Finding) Advisory() bool {
+	return !f.Severity.Blocking()
+}
+
+// Label names the finding's rule for log output, e.g. "[logs.retention]",
+// "[cost.gp2, advisory]" or "[tags.required, canary until 2026-11-30]".
+func (f Finding) Label() string {
+	if f.Canary != "" {
+		return "[" + f.RuleID + ", canary until " + f.Canary + "]"
+	}
+	if f.Advisory() {
+		return "[" + f.RuleID + ", " + string(f.Severity) + "]"
+	}
+	return "[" + f.RuleID + "]"
+}
+
+// SplitAdvisories separates the findings that fail the run from advisory ones.
+func SplitAdvisories(findings []Finding) (blocking, advisories []Finding) {
+	for _, finding := range findings {
+		if finding.Advisory() {
+			advisories = append(advisories, finding)
+		} else {
+			blocking = append(blocking, finding)
+		}
+	}
+	return blocking, advisories
+}
+
+// Fix sets one argument of a resource block, or of a nested block within it,
+// to a value decoded from JSON (string, number, bool or map of strings).
+type Fix struct {
+	// Block names a nested block such as "metadata_options"; it is created
+	// when missing. Empty means the resource block itself.
+	Block     string      `json:"block,omitempty"`
+	Attribute string      `json:"attribute"`
+	Value     interface{} `json:"value"`
+
+	// Merge adds the keys of a map Value to the existing attribute, e.g. to
+	// add missing tags, instead of replacing it.
+	Merge bool `json:"merge,omitempty"`
+}
+
+// SourceLocation is a position in a terraform configuration file.
+type SourceLocation struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+func (l *SourceLocation) String() string {
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// Location describes where a finding points, for log and report output:
+// "address (path) at file:line".
+func (f Finding) Location() string {
+	location := f.Address
+	if f.Path != "" {
+		location += " (" + f.Path + ")"
+	}
+	if f.Source != nil {
+		location += " at " + f.Source.String()
+	}
+	return location
+}
+
+// Key identifies the resource a finding is about. Region is part of the
+// identity so the same address planned in two regions stays distinct.
+func (f Finding) Key() string {
+	if f.Region == "" {
+		return f.Address
+	}
+	return f.Region + "/" + f.Address
+}
+
+// WithPath returns a copy of the finding pointing at the given attribute path.
+func (f Finding) WithPath(path string) Finding {
+	f.Path = path
+	return f
+}
+
+// WithSource returns a copy of the finding pointing at a source location, for
+// findings about configuration that is not a planned resource.
+func (f Finding) WithSource(source *SourceLocation) Finding {
+	f.Source = source
+	return f
+}
+
+// WithMessageKey returns a copy of the finding whose message can be
+// translated: key names the message in a Catalog and args fill in its
+// placeholders.
+func (f Finding) WithMessageKey(key string, args map[string]string) Finding {
+	f.MessageKey = key
+	f.MessageArgs = args
+	return f
+}
+
+// WithEvidence returns a copy of the finding with v as its evidence, for rules
+// whose finding is not explained by the value at its path alone.
+func (f Finding) WithEvidence(v interface{}) Finding {
+	f.Evidence = NewEvidence(v)
+	return f
+}
+
+// WithFix returns a copy of the finding carrying a suggested fix.
+func (f Finding) WithFix(fix Fix) Finding {
+	f.Fix = &fix
+	return f
+}
+
+// NewFinding builds a finding for the resource at address, filling in its module path.
+func NewFinding(ruleID, address, message string) Finding {
+	return Finding{
+		RuleID:  ruleID,
+		Address: address,
+		Module:  ModulePath(address),
+		Message: message,
+	}
+}
+
+// ModulePath returns the module portion of a resource address, e.g.
+// "module.iam.module.roles" for "module.iam.module.roles.aws_iam_role.x".
+// Resources in the root module return an empty string.
+func ModulePath(address string) string {
+	parts := splitAddress(address)
+
+	var module []string
+	for i := 0; i+1 < len(parts); i += 2 {
+		if parts[i] != "module" {
+			break
+		}
+		module = append(module, parts[i], parts[i+1])
+	}
+	return strings.Join(module, ".")
+}
+
+// splitAddress splits an address on dots, keeping index keys such as
+// ["a.b"] attached to the preceding segment.
+func splitAddress(address string) []string {
+	var (
+		parts   []string
+		current strings.Builder
+		depth   int
+	)
+	for _, r := range address {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+		case r == '.' && depth == 0:
+			parts = append(parts, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	return append(parts, current.String())
+}
+
+// SortFindings orders findings by owner, address and rule so reports are stable.
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Owner != b.Owner {
+			return a.Owner < b.Owner
+		}
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		return a.RuleID < b.RuleID
+	})
+}
@@ -0,0 +1,41 @@
+package plancheck
+
+import (
+	"fmt"
+	"path"
+)
+
+// Focus restricts a run to the resources matching any of its patterns, e.g.
+// while reviewing a change to one module of a large environment. Patterns
+// match as in the ownership file: a module path such as "module.artifacts"
+// and anything inside it, or an address with '*' globs such as
+// "module.artifacts.*". An empty Focus matches every resource.
+type Focus []string
+
+// ParseFocus checks each pattern is a valid glob.
+func ParseFocus(patterns []string) (Focus, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid focus pattern %q: %w", pattern, err)
+		}
+	}
+	return Focus(patterns), nil
+}
+
+// Matches reports whether the resource at address is in focus. Findings
+// without an address, such as those about the backend, are not.
+func (f Focus) Matches(address string) bool {
+	if len(f) == 0 {
+		return true
+	}
+	if address == "" {
+		return false
+	}
+	module := ModulePath(address)
+	for _, pattern := range f {
+		if pattern == "*" || matchOwnerPattern(pattern, address, module) {
+			return true
+		}
+	}
+	return false
+}
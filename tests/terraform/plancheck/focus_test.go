@@ -0,0 +1,38 @@
+package plancheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateKeepsFindingsInFocus(t *testing.T) {
+	rule := Rule{
+		ID: "test.focus",
+		Check: func(in *Input) []Finding {
+			return []Finding{
+				NewFinding("", "module.artifacts.aws_s3_bucket.this", "in focus"),
+				NewFinding("", "module.artifacts[0].module.logs.aws_cloudwatch_log_group.this", "nested"),
+				NewFinding("", "module.registry.aws_dynamodb_table.this", "elsewhere"),
+				NewFinding("", "", "about the backend"),
+			}
+		},
+	}
+
+	require.Len(t, Evaluate(&Input{}, rule), 4, "no focus keeps every finding")
+
+	focus, err := ParseFocus([]string{"module.artifacts.*"})
+	require.NoError(t, err)
+	var messages []string
+	for _, finding := range Evaluate(&Input{Focus: focus}, rule) {
+		messages = append(messages, finding.Message)
+	}
+	require.Equal(t, []string{"in focus", "nested"}, messages)
+
+	focus, err = ParseFocus([]string{"module.registry"})
+	require.NoError(t, err)
+	require.Len(t, Evaluate(&Input{Focus: focus}, rule), 1)
+
+	_, err = ParseFocus([]string{"module.[artifacts"})
+	require.ErrorContains(t, err, `invalid focus pattern "module.[artifacts"`)
+}
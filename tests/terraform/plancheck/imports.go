@@ -0,0 +1,61 @@
+package plancheck
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ImportBlock is an import block of a root module: the address a deployed
+// object is imported to, and the ID terraform imports it by.
+type ImportBlock struct {
+	To string `json:"to"`
+	// ID is empty when the id argument is not a literal, such as one built
+	// from a variable; the object cannot be looked up before planning then.
+	ID string `json:"id,omitempty"`
+}
+
+// ResourceType returns the type of the imported resource, e.g.
+// "aws_s3_bucket".
+func (b ImportBlock) ResourceType() string {
+	parts := splitAddress(ConfigAddress(b.To))
+	for len(parts) > 2 && parts[0] == "module" {
+		parts = parts[2:]
+	}
+	return parts[0]
+}
+
+// Imports returns the import blocks of the module. Blocks using for_each,
+// whose addresses are only known once planned, are left out. A nil module
+// imports nothing.
+func (m *Module) Imports() []ImportBlock {
+	if m == nil {
+		return nil
+	}
+	var imports []ImportBlock
+	for _, file := range m.Files {
+		for _, block := range file.Body.Blocks {
+			if block.Type != "import" {
+				continue
+			}
+			if _, ok := block.Body.Attributes["for_each"]; ok {
+				continue
+			}
+			to, ok := block.Body.Attributes["to"]
+			if !ok {
+				continue
+			}
+			traversal, diags := hcl.AbsTraversalForExpr(to.Expr)
+			if diags.HasErrors() {
+				continue
+			}
+			imported := ImportBlock{To: traversalAddress(traversal)}
+			if id, ok := block.Body.Attributes["id"]; ok {
+				if value, ok := Literal(id.Expr); ok && !value.IsNull() && value.Type() == cty.String {
+					imported.ID = value.AsString()
+				}
+			}
+			imports = append(imports, imported)
+		}
+	}
+	return imports
+}
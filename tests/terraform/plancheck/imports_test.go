@@ -0,0 +1,41 @@
+package plancheck
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleImports(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "imports.tf"), `import {
+  to = module.s3.aws_s3_bucket.artifacts
+  id = "pkg-artifacts"
+}
+
+import {
+  to = aws_dynamodb_table.tables["users"]
+  id = "${var.prefix}-users"
+}
+
+import {
+  for_each = toset(["a", "b"])
+  to       = aws_iam_role.roles[each.key]
+  id       = each.key
+}
+`)
+
+	module, err := LoadModule(dir)
+	require.NoError(t, err)
+	imports := module.Imports()
+	require.Equal(t, []ImportBlock{
+		{To: "module.s3.aws_s3_bucket.artifacts", ID: "pkg-artifacts"},
+		{To: `aws_dynamodb_table.tables["users"]`},
+	}, imports)
+	require.Equal(t, "aws_s3_bucket", imports[0].ResourceType())
+	require.Equal(t, "aws_dynamodb_table", imports[1].ResourceType())
+
+	var empty *Module
+	require.Empty(t, empty.Imports())
+}
@@ -0,0 +1,110 @@
+package plancheck
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ReportSuite is the findings of one test, a testsuite in JUnit terms.
+type ReportSuite struct {
+	Name     string
+	Findings []Finding
+}
+
+// WriteJUnit writes suites as JUnit XML for CI dashboards. Each finding is a
+// test case named after its rule and resource: blocking findings fail, and
+// advisory and info findings pass with the message as output. A suite without
+// findings has one passing case, so clean tests still show up. Every suite
+// carries the policy pack version as the property policy_pack, and the AWS
+// principal each environment in identities ran as as aws_identity.<env>.
+func WriteJUnit(w io.Writer, suites []ReportSuite, identities map[string]string) error {
+	var properties []junitProperty
+	if version := PackVersion(); version != "" {
+		properties = append(properties, junitProperty{Name: "policy_pack", Value: version})
+	}
+	environments := make([]string, 0, len(identities))
+	for environment := range identities {
+		environments = append(environments, environment)
+	}
+	sort.Strings(environments)
+	for _, environment := range environments {
+		properties = append(properties, junitProperty{Name: "aws_identity." + environment, Value: identities[environment]})
+	}
+
+	report := junitTestSuites{Name: "compliance"}
+	for _, suite := range suites {
+		out := junitTestSuite{Name: suite.Name, Properties: properties}
+		for _, finding := range suite.Findings {
+			name := finding.Key()
+			if finding.Path != "" {
+				name += " " + finding.Path
+			}
+			testCase := junitTestCase{ClassName: finding.RuleID, Name: name}
+			detail := fmt.Sprintf("%s\nat %s", finding.Message, finding.Location())
+			if finding.Owner != "" {
+				detail += fmt.Sprintf(" (owner: %s)", finding.Owner)
+			}
+			if finding.Advisory() {
+				testCase.SystemOut = finding.Label() + " " + detail
+			} else {
+				testCase.Failure = &junitFailure{Message: finding.Message, Type: finding.RuleID, Text: detail}
+				out.Failures++
+			}
+			out.Cases = append(out.Cases, testCase)
+		}
+		if len(out.Cases) == 0 {
+			out.Cases = append(out.Cases, junitTestCase{ClassName: suite.Name, Name: "no findings"})
+		}
+		out.Tests = len(out.Cases)
+		report.Tests += out.Tests
+		report.Failures += out.Failures
+		report.Suites = append(report.Suites, out)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,114 @@
+package plancheck
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocaleEnv selects the language findings and rule texts are rendered in,
+// e.g. "es" or "es_ES.UTF-8". Unset, or a locale without a catalog, keeps
+// the English texts the rules are written in.
+const LocaleEnv = "LOCALE"
+
+// Catalog holds the translations of rule texts and finding messages into one
+// language. A nil Catalog translates nothing.
+type Catalog struct {
+	// Locale is the name of the catalog file, e.g. "es".
+	Locale string `yaml:"-"`
+
+	// Rules maps rule IDs to their translated description and remediation.
+	Rules map[string]RuleText `yaml:"rules"`
+
+	// Messages maps finding message keys to templates in which "{name}"
+	// stands for the finding's message argument of that name.
+	Messages map[string]string `yaml:"messages"`
+}
+
+// RuleText is the translated text of a rule.
+type RuleText struct {
+	Description string `yaml:"description,omitempty"`
+	Remediation string `yaml:"remediation,omitempty"`
+	Rationale   string `yaml:"rationale,omitempty"`
+}
+
+// LoadCatalogFromEnv loads the catalog for LOCALE from dir.
+func LoadCatalogFromEnv(dir string) (*Catalog, error) {
+	return LoadCatalog(dir, os.Getenv(LocaleEnv))
+}
+
+// LoadCatalog loads <dir>/<locale>.yaml, trying the full locale ("pt_BR")
+// before its language ("pt"). English locales, and locales without a
+// catalog file, return nil.
+func LoadCatalog(dir, locale string) (*Catalog, error) {
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	language, _, _ := strings.Cut(locale, "_")
+	switch language {
+	case "", "C", "POSIX", "en":
+		return nil, nil
+	}
+
+	for _, name := range []string{locale, language} {
+		filename := filepath.Join(dir, name+".yaml")
+		data, err := os.ReadFile(filename)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		catalog := &Catalog{Locale: name}
+		if err := decodeYAML(data, catalog); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		return catalog, nil
+	}
+	return nil, nil
+}
+
+// Rule returns a copy of rule with its description, remediation and
+// rationale translated, where the catalog has them.
+func (c *Catalog) Rule(rule Rule) Rule {
+	if c == nil {
+		return rule
+	}
+	text := c.Rules[rule.ID]
+	if text.Description != "" {
+		rule.Description = text.Description
+	}
+	if text.Remediation != "" {
+		rule.Remediation = text.Remediation
+	}
+	if text.Rationale != "" {
+		rule.Rationale = text.Rationale
+	}
+	return rule
+}
+
+// Localize rewrites the message of every finding with a message key the
+// catalog translates. Other findings keep their English message.
+func (c *Catalog) Localize(findings []Finding) {
+	if c == nil {
+		return
+	}
+	for i, finding := range findings {
+		template, ok := c.Messages[finding.MessageKey]
+		if finding.MessageKey == "" || !ok {
+			continue
+		}
+		findings[i].Message = RenderMessage(template, finding.MessageArgs)
+	}
+}
+
+// RenderMessage replaces each "{name}" in template with args[name].
+func RenderMessage(template string, args map[string]string) string {
+	pairs := make([]string, 0, 2*len(args))
+	for name, value := range args {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
@@ -0,0 +1,68 @@
+package plancheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeCatalog(t *testing.T, name, content string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(content), 0o644))
+	return dir
+}
+
+func TestLoadCatalogFallsBackToLanguage(t *testing.T) {
+	dir := writeCatalog(t, "es", "messages:\n  logs.retention.never-expires: \"{address} nunca caduca\"\n")
+
+	for _, locale := range []string{"es", "es_ES", "es_MX.UTF-8", "es_ES@euro"} {
+		catalog, err := LoadCatalog(dir, locale)
+		require.NoError(t, err, locale)
+		require.NotNil(t, catalog, locale)
+		require.Equal(t, "es", catalog.Locale)
+	}
+	for _, locale := range []string{"", "C", "en_US.UTF-8", "fr"} {
+		catalog, err := LoadCatalog(dir, locale)
+		require.NoError(t, err, locale)
+		require.Nil(t, catalog, locale)
+	}
+}
+
+func TestLoadCatalogRejectsUnknownFields(t *testing.T) {
+	dir := writeCatalog(t, "es", "mesages: {}\n")
+
+	_, err := LoadCatalog(dir, "es")
+	require.Error(t, err)
+}
+
+func TestCatalogLocalizesKeyedFindings(t *testing.T) {
+	catalog := &Catalog{
+		Rules: map[string]RuleText{"logs.retention": {Description: "descripción"}},
+		Messages: map[string]string{
+			"logs.retention.never-expires": "el grupo {address} nunca caduca",
+		},
+	}
+	findings := []Finding{
+		NewFinding("logs.retention", "aws_cloudwatch_log_group.a", "log group aws_cloudwatch_log_group.a never expires its logs").
+			WithMessageKey("logs.retention.never-expires", map[string]string{"address": "aws_cloudwatch_log_group.a"}),
+		NewFinding("logs.retention", "aws_cloudwatch_log_group.b", "english only"),
+		NewFinding("logs.retention", "aws_cloudwatch_log_group.c", "unknown key").WithMessageKey("logs.retention.other", nil),
+	}
+
+	catalog.Localize(findings)
+	require.Equal(t, "el grupo aws_cloudwatch_log_group.a nunca caduca", findings[0].Message)
+	require.Equal(t, "english only", findings[1].Message)
+	require.Equal(t, "unknown key", findings[2].Message)
+
+	rule := catalog.Rule(Rule{ID: "logs.retention", Description: "english", Remediation: "fix it"})
+	require.Equal(t, "descripción", rule.Description)
+	require.Equal(t, "fix it", rule.Remediation)
+
+	var none *Catalog
+	none.Localize(findings)
+	require.Equal(t, "english", none.Rule(Rule{Description: "english"}).Description)
+}
@@ -0,0 +1,280 @@
+package plancheck
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	// ManifestEnv names the file to write the manifest of a whole run to.
+	ManifestEnv = "COMPLIANCE_MANIFEST"
+	// ManifestKeyEnv names a PEM-encoded Ed25519 private key (PKCS #8) to
+	// sign the manifest with.
+	ManifestKeyEnv = "COMPLIANCE_MANIFEST_KEY"
+	// ManifestSignerEnv names who signs the manifest, e.g. the CI workflow.
+	ManifestSignerEnv = "COMPLIANCE_MANIFEST_SIGNER"
+)
+
+// Manifest records what a run checked and what it found, so an auditor can
+// tie "this plan passed these checks" to the commit, tools, plans and rules
+// involved.
+type Manifest struct {
+	Created time.Time `json:"created"`
+	// Commit is the git commit the run checked out; Dirty is set when the
+	// working tree had changes on top of it.
+	Commit           string           `json:"commit"`
+	Dirty            bool             `json:"dirty,omitempty"`
+	TerraformVersion string           `json:"terraform_version,omitempty"`
+	PackVersion      string           `json:"pack_version,omitempty"`
+	Plans            []ManifestPlan   `json:"plans"`
+	Rules            []ManifestRule   `json:"rules"`
+	Findings         ManifestFindings `json:"findings"`
+	Signer           string           `json:"signer,omitempty"`
+	Signature        *Signature       `json:"signature,omitempty"`
+}
+
+// ManifestPlan identifies the plan of one environment.
+type ManifestPlan struct {
+	Environment string `json:"environment"`
+	// SHA256 is the digest of the plan's JSON as the run parsed it.
+	SHA256 string `json:"sha256"`
+	// Providers maps each provider's source address to the version the
+	// environment's dependency lock file selects.
+	Providers map[string]string `json:"providers,omitempty"`
+}
+
+// ManifestRule identifies a rule the run evaluated. Its digest covers the
+// rule's ID, severity, description and remediation, so a rule changed
+// between runs is told apart even without a release of its own.
+type ManifestRule struct {
+	ID       string   `json:"id"`
+	Severity Severity `json:"severity"`
+	// Canary is the rule's own canary date, when it has one.
+	Canary string `json:"canary,omitempty"`
+	Digest string `json:"digest"`
+}
+
+// ManifestFindings digests every finding the run reported.
+type ManifestFindings struct {
+	Count  int    `json:"count"`
+	SHA256 string `json:"sha256"`
+}
+
+// Signature is an Ed25519 signature over a manifest or approval as written
+// without it.
+type Signature struct {
+	// KeyID is the hex SHA-256 digest of the public key's PKIX encoding.
+	KeyID string `json:"key_id"`
+	Value string `json:"value"`
+}
+
+// PlanDigest returns the hex SHA-256 digest of plan's JSON encoding.
+func PlanDigest(plan *tfjson.Plan) (string, error) {
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return "", err
+	}
+	return digest(data), nil
+}
+
+// RuleDigests describes rules for a manifest, in the order given.
+func RuleDigests(rules []Rule) []ManifestRule {
+	described := make([]ManifestRule, 0, len(rules))
+	for _, rule := range rules {
+		severity := rule.Severity
+		if severity == "" {
+			severity = SeverityError
+		}
+		data, _ := json.Marshal([]string{rule.ID, string(severity), rule.Description, rule.Remediation})
+		described = append(described, ManifestRule{ID: rule.ID, Severity: severity, Canary: rule.Canary, Digest: digest(data)})
+	}
+	return described
+}
+
+// FindingsDigest counts and digests findings in the order given.
+func FindingsDigest(findings []Finding) (ManifestFindings, error) {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	data, err := json.Marshal(findings)
+	if err != nil {
+		return ManifestFindings{}, err
+	}
+	return ManifestFindings{Count: len(findings), SHA256: digest(data)}, nil
+}
+
+// LockedProviders returns the provider versions the .terraform.lock.hcl in
+// dir selects, or nil when there is no lock file.
+func LockedProviders(dir string) (map[string]string, error) {
+	filename := filepath.Join(dir, ".terraform.lock.hcl")
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	file, diags := hclsyntax.ParseConfig(data, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	providers := map[string]string{}
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type != "provider" || len(block.Labels) != 1 {
+			continue
+		}
+		attr, ok := block.Body.Attributes["version"]
+		if !ok {
+			continue
+		}
+		if value, diags := attr.Expr.Value(nil); !diags.HasErrors() && value.Type() == cty.String {
+			providers[block.Labels[0]] = value.AsString()
+		}
+	}
+	return providers, nil
+}
+
+// LoadSigningKey reads a PEM-encoded PKCS #8 Ed25519 private key.
+func LoadSigningKey(filename string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", filename)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", filename)
+	}
+	return private, nil
+}
+
+// LoadVerifyingKey reads a PEM-encoded PKIX Ed25519 public key.
+func LoadVerifyingKey(filename string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", filename)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", filename)
+	}
+	return public, nil
+}
+
+// KeyID returns the ID signatures by key name it by.
+func KeyID(key ed25519.PublicKey) string {
+	der, _ := x509.MarshalPKIXPublicKey(key)
+	return digest(der)
+}
+
+// Sign replaces the manifest's signature with one by key.
+func (m *Manifest) Sign(key ed25519.PrivateKey) error {
+	m.Signature = nil
+	data, err := m.encode()
+	if err != nil {
+		return err
+	}
+	m.Signature = sign(key, data)
+	return nil
+}
+
+// Verify checks the manifest's signature against key.
+func (m Manifest) Verify(key ed25519.PublicKey) error {
+	unsigned := m
+	unsigned.Signature = nil
+	data, err := unsigned.encode()
+	if err != nil {
+		return err
+	}
+	return verify(m.Signature, key, data, "manifest")
+}
+
+func sign(key ed25519.PrivateKey, data []byte) *Signature {
+	return &Signature{
+		KeyID: KeyID(key.Public().(ed25519.PublicKey)),
+		Value: base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)),
+	}
+}
+
+// verify checks signature, over data, against key; what names the signed
+// document in errors.
+func verify(signature *Signature, key ed25519.PublicKey, data []byte, what string) error {
+	if signature == nil {
+		return fmt.Errorf("%s is not signed", what)
+	}
+	if signature.KeyID != KeyID(key) {
+		return fmt.Errorf("%s is signed by key %s, not %s", what, signature.KeyID, KeyID(key))
+	}
+	value, err := base64.StdEncoding.DecodeString(signature.Value)
+	if err != nil {
+		return fmt.Errorf("%s signature: %w", what, err)
+	}
+	if !ed25519.Verify(key, data, value) {
+		return fmt.Errorf("%s signature does not match its contents", what)
+	}
+	return nil
+}
+
+func (m Manifest) encode() ([]byte, error) {
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// WriteManifest writes m to filename as indented JSON.
+func WriteManifest(filename string, m Manifest) error {
+	data, err := m.encode()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// ReadManifest loads a manifest written by WriteManifest.
+func ReadManifest(filename string) (Manifest, error) {
+	var m Manifest
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("%s: %w", filename, err)
+	}
+	return m, nil
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
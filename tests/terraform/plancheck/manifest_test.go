@@ -0,0 +1,63 @@
+package plancheck
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestSignatureCoversItsContents(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	findings, err := FindingsDigest([]Finding{NewFinding("tags.required", "aws_sqs_queue.jobs", "missing tags")})
+	require.NoError(t, err)
+	m := Manifest{
+		Created:  time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Commit:   "16efa07",
+		Plans:    []ManifestPlan{{Environment: "dev", SHA256: "ab12", Providers: map[string]string{"registry.terraform.io/hashicorp/aws": "5.31.0"}}},
+		Rules:    RuleDigests([]Rule{{ID: "tags.required", Description: "tagged"}}),
+		Findings: findings,
+		Signer:   "ci",
+	}
+	require.Equal(t, SeverityError, m.Rules[0].Severity)
+	require.NoError(t, m.Sign(private))
+
+	filename := filepath.Join(t.TempDir(), "manifest.json")
+	require.NoError(t, WriteManifest(filename, m))
+	read, err := ReadManifest(filename)
+	require.NoError(t, err)
+	require.NoError(t, read.Verify(public), "the signature must survive writing and reading")
+
+	read.Findings.Count = 0
+	require.ErrorContains(t, read.Verify(public), "does not match")
+
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.ErrorContains(t, m.Verify(other), "signed by key")
+}
+
+func TestLockedProvidersReadsTheLockFile(t *testing.T) {
+	dir := t.TempDir()
+	providers, err := LockedProviders(dir)
+	require.NoError(t, err)
+	require.Nil(t, providers)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte(`
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.31.0"
+  constraints = "~> 5.0"
+  hashes = [
+    "h1:abc=",
+  ]
+}
+`), 0o644))
+	providers, err = LockedProviders(dir)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"registry.terraform.io/hashicorp/aws": "5.31.0"}, providers)
+}
@@ -0,0 +1,123 @@
+package plancheck
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Module is the parsed source of the planned root module, for rules that
+// judge declarations the plan does not record, such as variable types or
+// references through locals.
+type Module struct {
+	Files []*SourceFile
+}
+
+// LoadModule parses the .tf files in dir. It returns nil and no error when
+// dir has none.
+func LoadModule(dir string) (*Module, error) {
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil || len(filenames) == 0 {
+		return nil, err
+	}
+	module := &Module{}
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		file, err := ParseSource(filename, data)
+		if err != nil {
+			return nil, err
+		}
+		module.Files = append(module.Files, file)
+	}
+	return module, nil
+}
+
+// VariableReferences returns the names of the input variables referenced
+// anywhere in the module outside variable blocks, whose validation rules
+// refer to the variable itself. A nil module references none.
+func (m *Module) VariableReferences() map[string]bool {
+	referenced := map[string]bool{}
+	if m == nil {
+		return referenced
+	}
+	visit := func(node hclsyntax.Node) hcl.Diagnostics {
+		expr, ok := node.(hclsyntax.Expression)
+		if !ok {
+			return nil
+		}
+		for _, traversal := range expr.Variables() {
+			if traversal.RootName() != "var" || len(traversal) < 2 {
+				continue
+			}
+			if attr, ok := traversal[1].(hcl.TraverseAttr); ok {
+				referenced[attr.Name] = true
+			}
+		}
+		return nil
+	}
+	for _, file := range m.Files {
+		for _, block := range file.Body.Blocks {
+			if block.Type != "variable" {
+				hclsyntax.VisitAll(block, visit)
+			}
+		}
+	}
+	return referenced
+}
+
+// Requirements are the versions a module's terraform block requires.
+type Requirements struct {
+	// Terraform is required_version, e.g. ">= 1.6.0"; empty when unset.
+	Terraform string
+	// Providers maps the local name of each required provider to its source,
+	// e.g. "aws" to "hashicorp/aws".
+	Providers map[string]string
+}
+
+// Requirements returns the literal required_version and required_providers
+// of the module's terraform blocks. A nil module requires nothing.
+func (m *Module) Requirements() Requirements {
+	requirements := Requirements{Providers: map[string]string{}}
+	if m == nil {
+		return requirements
+	}
+	for _, file := range m.Files {
+		for _, block := range file.Body.Blocks {
+			if block.Type != "terraform" {
+				continue
+			}
+			if attr, ok := block.Body.Attributes["required_version"]; ok {
+				if value, ok := Literal(attr.Expr); ok && !value.IsNull() && value.Type() == cty.String {
+					requirements.Terraform = value.AsString()
+				}
+			}
+			for _, nested := range block.Body.Blocks {
+				if nested.Type != "required_providers" {
+					continue
+				}
+				for name, attr := range nested.Body.Attributes {
+					requirements.Providers[name] = providerSource(attr.Expr, name)
+				}
+			}
+		}
+	}
+	return requirements
+}
+
+// providerSource returns the source of a required_providers entry, which
+// defaults to hashicorp/<name>.
+func providerSource(expr hclsyntax.Expression, name string) string {
+	value, ok := Literal(expr)
+	if ok && !value.IsNull() && value.Type().IsObjectType() && value.Type().HasAttribute("source") {
+		if source := value.GetAttr("source"); !source.IsNull() && source.Type() == cty.String {
+			return source.AsString()
+		}
+	}
+	return "hashicorp/" + name
+}
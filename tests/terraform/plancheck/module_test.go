@@ -0,0 +1,71 @@
+package plancheck
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleVariableReferences(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "variables.tf"), `variable "region" {
+  type = string
+  validation {
+    condition     = length(var.region) > 0
+    error_message = "region must not be empty."
+  }
+}
+
+variable "account" {}
+variable "unused" {}
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `locals {
+  arn = "arn:aws:iam::${var.account}:root"
+}
+
+provider "aws" {
+  region = var.region
+}
+`)
+
+	module, err := LoadModule(dir)
+	require.NoError(t, err)
+	require.Len(t, module.Files, 2)
+	require.Equal(t, map[string]bool{"region": true, "account": true}, module.VariableReferences())
+
+	empty, err := LoadModule(t.TempDir())
+	require.NoError(t, err)
+	require.Nil(t, empty)
+	require.Empty(t, empty.VariableReferences())
+}
+
+func TestModuleRequirements(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.tf"), `terraform {
+  required_version = ">= 1.6.0"
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = ">= 5.0"
+    }
+    archive = {
+      version = "~> 2.4"
+    }
+    github = {
+      source = "integrations/github"
+    }
+  }
+}
+`)
+
+	module, err := LoadModule(dir)
+	require.NoError(t, err)
+	require.Equal(t, Requirements{
+		Terraform: ">= 1.6.0",
+		Providers: map[string]string{"aws": "hashicorp/aws", "archive": "hashicorp/archive", "github": "integrations/github"},
+	}, module.Requirements())
+
+	var empty *Module
+	require.Equal(t, Requirements{Providers: map[string]string{}}, empty.Requirements())
+}
@@ -0,0 +1,222 @@
+package plancheck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFetcher reads the configuration layer at a URL.
+type ConfigFetcher func(url string) ([]byte, error)
+
+var (
+	fetchersMu     sync.RWMutex
+	configFetchers = map[string]ConfigFetcher{"https": fetchHTTPConfig}
+
+	// orgLayers caches the layers fetched by URL, so the many tests of a
+	// run that load the configuration fetch each once.
+	orgLayersMu sync.Mutex
+	orgLayers   = map[string][]byte{}
+)
+
+// RegisterConfigFetcher makes LoadConfig read layers whose URL has the given
+// scheme, e.g. "s3", with fetch. Packages that can reach the store register
+// their fetcher when imported. Plain http is never registered: a layer read
+// over it could be rewritten in transit to unlock every setting.
+func RegisterConfigFetcher(scheme string, fetch ConfigFetcher) {
+	if scheme == "http" {
+		panic("plancheck: configuration layers may not be fetched over plain http")
+	}
+	fetchersMu.Lock()
+	defer fetchersMu.Unlock()
+	configFetchers[scheme] = fetch
+}
+
+// configHTTPClient fetches https layers and refuses redirects to plain http.
+var configHTTPClient = &http.Client{Timeout: 30 * time.Second, CheckRedirect: httpsRedirectsOnly}
+
+func httpsRedirectsOnly(req *http.Request, via []*http.Request) error {
+	if req.URL.Scheme != "https" {
+		return fmt.Errorf("redirected to %s: configuration layers must be fetched over https", req.URL)
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
+
+func fetchHTTPConfig(url string) ([]byte, error) {
+	resp, err := configHTTPClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// fetchOrgConfig reads the layer at location: a URL of a registered scheme,
+// or a file path relative to dir.
+func fetchOrgConfig(location, dir string) ([]byte, error) {
+	scheme, _, found := strings.Cut(location, "://")
+	if !found {
+		if !filepath.IsAbs(location) {
+			location = filepath.Join(dir, location)
+		}
+		return os.ReadFile(location)
+	}
+
+	orgLayersMu.Lock()
+	defer orgLayersMu.Unlock()
+	if data, ok := orgLayers[location]; ok {
+		return data, nil
+	}
+	fetchersMu.RLock()
+	fetch, ok := configFetchers[scheme]
+	fetchersMu.RUnlock()
+	if !ok && scheme == "http" {
+		return nil, fmt.Errorf("%s: configuration layers must be fetched over https, not http", location)
+	}
+	if !ok {
+		return nil, fmt.Errorf("no fetcher for %s:// URLs", scheme)
+	}
+	data, err := fetch(location)
+	if err != nil {
+		return nil, err
+	}
+	orgLayers[location] = data
+	return data, nil
+}
+
+// layerConfig reads compliance.yaml's data over the organization's layer it
+// extends, if any, and returns the merged YAML. The repository's settings
+// override the organization's, map by map, except those the organization
+// lists under locked.
+func layerConfig(filename string, data []byte) ([]byte, error) {
+	var repo yaml.Node
+	if err := yaml.Unmarshal(data, &repo); err != nil {
+		return nil, err
+	}
+	extends := mappingValue(documentRoot(&repo), "extends")
+	if extends == nil || extends.Value == "" {
+		return data, nil
+	}
+	location := extends.Value
+
+	orgData, err := fetchOrgConfig(location, filepath.Dir(filename))
+	if err != nil {
+		return nil, fmt.Errorf("extends: %w", err)
+	}
+	var org Config
+	if err := decodeYAML(orgData, &org); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("extends %s: %w", location, err)
+	}
+	if org.Extends != "" {
+		return nil, fmt.Errorf("extends %s: the organization's configuration cannot extend another", location)
+	}
+	var orgNode yaml.Node
+	if err := yaml.Unmarshal(orgData, &orgNode); err != nil {
+		return nil, fmt.Errorf("extends %s: %w", location, err)
+	}
+
+	base, override := documentRoot(&orgNode), documentRoot(&repo)
+	if base == nil {
+		return data, nil
+	}
+	if override != nil {
+		if mappingValue(override, "locked") != nil {
+			return nil, fmt.Errorf("locked can only be set by the organization's configuration")
+		}
+		locked := map[string]bool{}
+		for _, path := range org.Locked {
+			locked[path] = true
+		}
+		if err := mergeMapping(base, override, "", locked, location); err != nil {
+			return nil, err
+		}
+	}
+
+	var merged bytes.Buffer
+	encoder := yaml.NewEncoder(&merged)
+	if err := encoder.Encode(base); err != nil {
+		return nil, err
+	}
+	return merged.Bytes(), nil
+}
+
+// mergeMapping sets the keys of override in base, merging mappings both set
+// and replacing everything else, such as lists. Keys whose dotted path is
+// locked may not be set.
+func mergeMapping(base, override *yaml.Node, prefix string, locked map[string]bool, location string) error {
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		path := prefix + key.Value
+		if locked[path] {
+			return fmt.Errorf("%s: locked by the organization's configuration %s", path, location)
+		}
+		existing := mappingValue(base, key.Value)
+		switch {
+		case existing == nil:
+			if lockedBelow(locked, path) {
+				return fmt.Errorf("%s: sets settings locked by the organization's configuration %s", path, location)
+			}
+			base.Content = append(base.Content, key, value)
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			if err := mergeMapping(existing, value, path+".", locked, location); err != nil {
+				return err
+			}
+		default:
+			if lockedBelow(locked, path) {
+				return fmt.Errorf("%s: replaces settings locked by the organization's configuration %s", path, location)
+			}
+			*existing = *value
+		}
+	}
+	return nil
+}
+
+// lockedBelow reports whether a setting nested under path is locked.
+func lockedBelow(locked map[string]bool, path string) bool {
+	for lockedPath := range locked {
+		if strings.HasPrefix(lockedPath, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// documentRoot returns the top-level mapping of a parsed document, or nil
+// when it is empty or not a mapping.
+func documentRoot(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	return node
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package plancheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const orgConfig = `
+locked: [severities, environments.prod.production]
+iam:
+  allowed_action_patterns: ["kms:GenerateDataKey*"]
+  trusted_accounts: ["111111111111"]
+severities:
+  iam.wildcard-action: error
+environments:
+  prod:
+    production: true
+    region: us-east-1
+`
+
+func TestLoadConfigLayersOverOrganization(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "org.yaml"), []byte(orgConfig), 0o644))
+	filename := filepath.Join(dir, "compliance.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte(`
+extends: org.yaml
+iam:
+  allowed_action_patterns: ["logs:Create*"]
+environments:
+  prod:
+    region: eu-west-1
+  dev:
+    sandbox: true
+`), 0o644))
+
+	config, err := LoadConfig(filename)
+	require.NoError(t, err)
+	require.Equal(t, []string{"logs:Create*"}, config.IAM.AllowedActionPatterns, "lists are replaced")
+	require.Equal(t, []string{"111111111111"}, config.IAM.TrustedAccounts, "unset settings come from the organization")
+	require.Equal(t, Environment{Production: true, Region: "eu-west-1"}, config.Environment("prod"))
+	require.True(t, config.Environment("dev").Sandbox)
+	severity, ok := config.Severity("iam.wildcard-action")
+	require.True(t, ok)
+	require.Equal(t, SeverityError, severity)
+
+	for name, body := range map[string]string{
+		"severities":                   "severities:\n  iam.wildcard-action: advisory\n",
+		"environments.prod.production": "environments:\n  prod:\n    production: false\n",
+		"environments.prod":            "environments:\n  prod: null\n",
+		"locked":                       "locked: []\n",
+	} {
+		require.NoError(t, os.WriteFile(filename, []byte("extends: org.yaml\n"+body), 0o644))
+		_, err := LoadConfig(filename)
+		require.ErrorContainsf(t, err, "locked", "overriding %s", name)
+	}
+}
+
+func TestLoadConfigFetchesOrganizationURL(t *testing.T) {
+	var requests int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/downgrade.yaml" {
+			http.Redirect(w, r, "http://"+r.Host+"/compliance.yaml", http.StatusFound)
+			return
+		}
+		if r.URL.Path != "/compliance.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(orgConfig))
+	}))
+	t.Cleanup(server.Close)
+	client := configHTTPClient
+	configHTTPClient = server.Client()
+	configHTTPClient.CheckRedirect = httpsRedirectsOnly
+	t.Cleanup(func() { configHTTPClient = client })
+
+	filename := filepath.Join(t.TempDir(), "compliance.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte("extends: "+server.URL+"/compliance.yaml\n"), 0o644))
+	for i := 0; i < 2; i++ {
+		config, err := LoadConfig(filename)
+		require.NoError(t, err)
+		require.True(t, config.Environment("prod").Production)
+	}
+	require.Equal(t, 1, requests, "a layer is fetched once per process")
+
+	require.NoError(t, os.WriteFile(filename, []byte("extends: "+server.URL+"/missing.yaml\n"), 0o644))
+	_, err := LoadConfig(filename)
+	require.ErrorContains(t, err, "404 Not Found")
+
+	require.NoError(t, os.WriteFile(filename, []byte("extends: gs://bucket/compliance.yaml\n"), 0o644))
+	_, err = LoadConfig(filename)
+	require.ErrorContains(t, err, "no fetcher for gs:// URLs")
+
+	plain := strings.Replace(server.URL, "https://", "http://", 1)
+	require.NoError(t, os.WriteFile(filename, []byte("extends: "+plain+"/other.yaml\n"), 0o644))
+	_, err = LoadConfig(filename)
+	require.ErrorContains(t, err, "must be fetched over https, not http")
+	require.Panics(t, func() { RegisterConfigFetcher("http", nil) })
+
+	require.NoError(t, os.WriteFile(filename, []byte("extends: "+server.URL+"/downgrade.yaml\n"), 0o644))
+	_, err = LoadConfig(filename)
+	require.ErrorContains(t, err, "configuration layers must be fetched over https")
+}
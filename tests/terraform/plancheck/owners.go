@@ -0,0 +1,110 @@
+package plancheck
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// UnownedTeam is assigned to findings that no ownership rule matches.
+const UnownedTeam = "unowned"
+
+type ownerRule struct {
+	pattern string
+	owner   string
+}
+
+// Owners maps resource addresses to the team responsible for them. The file
+// format follows CODEOWNERS: one "<pattern> <owner>" pair per line, '#'
+// comments, and the last matching pattern wins.
+//
+// A pattern matches a module path ("module.iam"), any resource inside it, or a
+// full resource address using '*' globs ("module.*.aws_iam_role.*"). Patterns
+// without instance keys also match every instance of a count or for_each
+// module or resource.
+type Owners struct {
+	rules []ownerRule
+}
+
+// LoadOwners reads an ownership file from disk.
+func LoadOwners(filename string) (*Owners, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	owners := &Owners{}
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<pattern> <owner>\", got %q", filename, lineNo, line)
+		}
+		if _, err := path.Match(fields[0], ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid pattern %q: %w", filename, lineNo, fields[0], err)
+		}
+		owners.rules = append(owners.rules, ownerRule{pattern: fields[0], owner: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return owners, nil
+}
+
+// OwnerOf returns the owner of the resource at address, or UnownedTeam.
+func (o *Owners) OwnerOf(address string) string {
+	if o == nil {
+		return UnownedTeam
+	}
+
+	module := ModulePath(address)
+	for i := len(o.rules) - 1; i >= 0; i-- {
+		rule := o.rules[i]
+		if rule.pattern == "*" || matchOwnerPattern(rule.pattern, address, module) {
+			return rule.owner
+		}
+	}
+	return UnownedTeam
+}
+
+// Assign sets the Owner field of every finding.
+func (o *Owners) Assign(findings []Finding) {
+	for i := range findings {
+		findings[i].Owner = o.OwnerOf(findings[i].Address)
+	}
+}
+
+func matchOwnerPattern(pattern, address, module string) bool {
+	// Instance keys are tried both ways, so "module.s3" owns "module.s3[0]"
+	// and a pattern naming one instance still matches only that instance.
+	for _, candidate := range []string{address, ConfigAddress(address)} {
+		if ok, _ := path.Match(pattern, candidate); ok {
+			return true
+		}
+	}
+	if module == "" {
+		return false
+	}
+	for _, candidate := range []string{module, ConfigAddress(module)} {
+		// Instance keys such as ["users"] read as character classes to
+		// path.Match, so a module pattern may also match literally.
+		if ok, _ := path.Match(pattern, candidate); ok || candidate == pattern {
+			return true
+		}
+		// "module.iam" also owns nested modules such as "module.iam.module.roles".
+		if strings.HasPrefix(candidate, pattern+".") {
+			return true
+		}
+	}
+	return false
+}
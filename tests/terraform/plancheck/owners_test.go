@@ -0,0 +1,96 @@
+package plancheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeOwners(t *testing.T, content string) *Owners {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "OWNERS")
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+
+	owners, err := LoadOwners(filename)
+	require.NoError(t, err)
+	return owners
+}
+
+func TestModulePath(t *testing.T) {
+	cases := map[string]string{
+		"aws_iam_policy.api":                                "",
+		"module.iam.aws_iam_policy.group106_policy":         "module.iam",
+		"module.net.module.vpc.aws_subnet.a":                "module.net.module.vpc",
+		`module.buckets["logs.archive"].aws_s3_bucket.this`: `module.buckets["logs.archive"]`,
+	}
+	for address, want := range cases {
+		require.Equal(t, want, ModulePath(address), address)
+	}
+}
+
+func TestOwnersLastMatchWins(t *testing.T) {
+	owners := writeOwners(t, `
+# default owner
+*                @platform
+module.ecs       @compute
+*aws_security_group*  @networking
+`)
+
+	require.Equal(t, "@platform", owners.OwnerOf("aws_iam_policy.api"))
+	require.Equal(t, "@compute", owners.OwnerOf("module.ecs.aws_ecs_service.validator_service"))
+	require.Equal(t, "@compute", owners.OwnerOf("module.ecs.module.inner.aws_ecs_service.x"))
+	require.Equal(t, "@networking", owners.OwnerOf("module.ecs.aws_security_group.validator_sg"))
+}
+
+func TestOwnersMatchModuleInstances(t *testing.T) {
+	owners := writeOwners(t, `
+module.s3                      @storage
+module.ddb["users"]            @identity
+module.iam.aws_iam_role.ci     @platform
+`)
+
+	require.Equal(t, "@storage", owners.OwnerOf("module.s3[0].aws_s3_bucket.artifacts"))
+	require.Equal(t, "@storage", owners.OwnerOf(`module.s3["replica"].module.kms.aws_kms_key.this`))
+	require.Equal(t, "@identity", owners.OwnerOf(`module.ddb["users"].aws_dynamodb_table.this`))
+	require.Equal(t, UnownedTeam, owners.OwnerOf(`module.ddb["tokens"].aws_dynamodb_table.this`))
+	require.Equal(t, "@platform", owners.OwnerOf(`module.iam.aws_iam_role.ci[1]`))
+}
+
+func TestOwnersUnmatched(t *testing.T) {
+	owners := writeOwners(t, "module.s3 @storage\n")
+
+	require.Equal(t, UnownedTeam, owners.OwnerOf("module.s33.aws_s3_bucket.x"))
+	require.Equal(t, UnownedTeam, (*Owners)(nil).OwnerOf("aws_s3_bucket.x"))
+}
+
+func TestOwnersRejectsMalformedLines(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "OWNERS")
+	require.NoError(t, os.WriteFile(filename, []byte("module.s3\n"), 0o644))
+
+	_, err := LoadOwners(filename)
+	require.ErrorContains(t, err, "OWNERS:1")
+}
+
+func TestWriteReportsSplitsByOwner(t *testing.T) {
+	dir := t.TempDir()
+	findings := []Finding{
+		{RuleID: "r1", Address: "aws_vpc.a", Owner: "@cs450/networking"},
+		{RuleID: "r1", Address: "aws_iam_role.b", Owner: "@cs450/security"},
+		{RuleID: "r2", Address: "aws_iam_role.c", Owner: "@cs450/security"},
+	}
+
+	written, err := WriteReports(dir, findings)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "findings.json"),
+		filepath.Join(dir, "findings.cs450-networking.json"),
+		filepath.Join(dir, "findings.cs450-security.json"),
+	}, written)
+
+	security, err := ReadFindings(filepath.Join(dir, "findings.cs450-security.json"))
+	require.NoError(t, err)
+	require.Len(t, security, 2)
+}
@@ -0,0 +1,205 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	packMu      sync.RWMutex
+	packVersion string
+)
+
+// SetPackVersion records the semantic version of the registered rule set, the
+// policy pack, for reports to name. The package registering the rules sets
+// it; it panics on a version that is not MAJOR.MINOR.PATCH.
+func SetPackVersion(version string) {
+	if _, err := ParsePackVersion(version); err != nil {
+		panic(err)
+	}
+	packMu.Lock()
+	defer packMu.Unlock()
+	packVersion = version
+}
+
+// PackVersion returns the policy pack version, or "" when none was set.
+func PackVersion() string {
+	packMu.RLock()
+	defer packMu.RUnlock()
+	return packVersion
+}
+
+// ParsePackVersion splits a MAJOR.MINOR.PATCH version, with or without a
+// leading v, into its numbers.
+func ParsePackVersion(version string) ([3]int, error) {
+	var numbers [3]int
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(parts) != 3 {
+		return numbers, fmt.Errorf("plancheck: pack version %q is not MAJOR.MINOR.PATCH", version)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || part != strconv.Itoa(n) {
+			return numbers, fmt.Errorf("plancheck: pack version %q is not MAJOR.MINOR.PATCH", version)
+		}
+		numbers[i] = n
+	}
+	return numbers, nil
+}
+
+// Pack describes a version of the policy pack: its rules, with the severity
+// and a digest of the text each was released with.
+type Pack struct {
+	Version string         `json:"version"`
+	Rules   []ManifestRule `json:"rules"`
+}
+
+// CurrentPack describes rules as the pack of PackVersion, ordered by ID.
+func CurrentPack(rules []Rule) Pack {
+	sorted := append([]Rule(nil), rules...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return Pack{Version: PackVersion(), Rules: RuleDigests(sorted)}
+}
+
+// PackChange is a difference between two versions of the pack.
+type PackChange struct {
+	RuleID string `json:"rule_id"`
+	// Kind is "added", "removed", "severity", "canary" or "text", the last
+	// for a changed description or remediation.
+	Kind   string   `json:"kind"`
+	Before Severity `json:"before,omitempty"`
+	After  Severity `json:"after,omitempty"`
+	// CanaryBefore and CanaryAfter are the canary dates of a "canary"
+	// change; an empty one means the rule fails runs.
+	CanaryBefore string `json:"canary_before,omitempty"`
+	CanaryAfter  string `json:"canary_after,omitempty"`
+}
+
+func (c PackChange) String() string {
+	switch c.Kind {
+	case "added":
+		return fmt.Sprintf("+ %s (%s)", c.RuleID, c.After)
+	case "removed":
+		return fmt.Sprintf("- %s (%s)", c.RuleID, c.Before)
+	case "severity":
+		return fmt.Sprintf("~ %s severity: %s -> %s", c.RuleID, c.Before, c.After)
+	case "canary":
+		return fmt.Sprintf("~ %s canary: %s -> %s", c.RuleID, canaryText(c.CanaryBefore), canaryText(c.CanaryAfter))
+	default:
+		return fmt.Sprintf("~ %s description or remediation", c.RuleID)
+	}
+}
+
+func canaryText(until string) string {
+	if until == "" {
+		return "(none)"
+	}
+	return "until " + until
+}
+
+// DiffPacks lists the rules added, removed or changed from base to head,
+// ordered by rule ID.
+func DiffPacks(base, head Pack) []PackChange {
+	index := func(p Pack) map[string]ManifestRule {
+		rules := map[string]ManifestRule{}
+		for _, rule := range p.Rules {
+			rules[rule.ID] = rule
+		}
+		return rules
+	}
+	before, after := index(base), index(head)
+
+	var changes []PackChange
+	for id, rule := range before {
+		other, ok := after[id]
+		switch {
+		case !ok:
+			changes = append(changes, PackChange{RuleID: id, Kind: "removed", Before: rule.Severity})
+		case other.Severity != rule.Severity:
+			changes = append(changes, PackChange{RuleID: id, Kind: "severity", Before: rule.Severity, After: other.Severity})
+		case other.Canary != rule.Canary:
+			changes = append(changes, PackChange{RuleID: id, Kind: "canary", CanaryBefore: rule.Canary, CanaryAfter: other.Canary})
+		case other.Digest != rule.Digest:
+			changes = append(changes, PackChange{RuleID: id, Kind: "text"})
+		}
+	}
+	for id, rule := range after {
+		if _, ok := before[id]; !ok {
+			changes = append(changes, PackChange{RuleID: id, Kind: "added", After: rule.Severity})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].RuleID < changes[j].RuleID })
+	return changes
+}
+
+// RequiredBump returns the part of the version, 0 for major, 1 for minor and
+// 2 for patch, that changes must bump, or -1 when there are none. Removing a
+// rule breaks the baselines and suppressions that name it, so it is major;
+// a new rule, a new severity or a new canary date can fail a plan that
+// passed, so it is minor; new wording is a patch.
+func RequiredBump(changes []PackChange) int {
+	bump := -1
+	for _, change := range changes {
+		part := 2
+		switch change.Kind {
+		case "removed":
+			part = 0
+		case "added", "severity", "canary":
+			part = 1
+		}
+		if bump == -1 || part < bump {
+			bump = part
+		}
+	}
+	return bump
+}
+
+// Bumped reports whether head is a version after base that bumps at least
+// the given part: its first differing number, at or before part, is higher.
+func Bumped(base, head string, part int) (bool, error) {
+	b, err := ParsePackVersion(base)
+	if err != nil {
+		return false, err
+	}
+	h, err := ParsePackVersion(head)
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i <= part; i++ {
+		if h[i] != b[i] {
+			return h[i] > b[i], nil
+		}
+	}
+	return false, nil
+}
+
+// WritePack writes p to filename as indented JSON.
+func WritePack(filename string, p Pack) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// ReadPack loads a pack description written by WritePack.
+func ReadPack(filename string) (Pack, error) {
+	var p Pack
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("%s: %w", filename, err)
+	}
+	return p, nil
+}
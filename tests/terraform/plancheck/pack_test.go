@@ -0,0 +1,73 @@
+package plancheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePackVersion(t *testing.T) {
+	version, err := ParsePackVersion("v1.12.0")
+	require.NoError(t, err)
+	require.Equal(t, [3]int{1, 12, 0}, version)
+
+	for _, invalid := range []string{"", "1.0", "1.0.0-rc1", "1.01.0", "1.-1.0"} {
+		_, err := ParsePackVersion(invalid)
+		require.Errorf(t, err, "%q must not parse", invalid)
+	}
+}
+
+func TestDiffPacksAndRequiredBump(t *testing.T) {
+	base := Pack{Version: "1.0.0", Rules: []ManifestRule{
+		{ID: "iam.wildcard-action", Severity: SeverityError, Digest: "a"},
+		{ID: "logs.retention", Severity: SeverityAdvisory, Digest: "b"},
+		{ID: "s3.versioning", Severity: SeverityError, Digest: "c"},
+	}}
+	reworded := Pack{Version: "1.0.1", Rules: []ManifestRule{
+		{ID: "iam.wildcard-action", Severity: SeverityError, Digest: "a2"},
+		{ID: "logs.retention", Severity: SeverityAdvisory, Digest: "b"},
+		{ID: "s3.versioning", Severity: SeverityError, Digest: "c"},
+	}}
+	changes := DiffPacks(base, reworded)
+	require.Equal(t, []PackChange{{RuleID: "iam.wildcard-action", Kind: "text"}}, changes)
+	require.Equal(t, 2, RequiredBump(changes))
+
+	stricter := Pack{Version: "1.1.0", Rules: []ManifestRule{
+		{ID: "iam.wildcard-action", Severity: SeverityError, Digest: "a"},
+		{ID: "logs.retention", Severity: SeverityError, Digest: "b"},
+		{ID: "s3.versioning", Severity: SeverityError, Digest: "c"},
+		{ID: "tags.required", Severity: SeverityError, Digest: "d"},
+	}}
+	changes = DiffPacks(base, stricter)
+	require.Equal(t, []PackChange{
+		{RuleID: "logs.retention", Kind: "severity", Before: SeverityAdvisory, After: SeverityError},
+		{RuleID: "tags.required", Kind: "added", After: SeverityError},
+	}, changes)
+	require.Equal(t, 1, RequiredBump(changes))
+	require.Equal(t, "~ logs.retention severity: advisory -> error", changes[0].String())
+
+	changes = DiffPacks(base, Pack{Version: "2.0.0", Rules: base.Rules[:2]})
+	require.Equal(t, []PackChange{{RuleID: "s3.versioning", Kind: "removed", Before: SeverityError}}, changes)
+	require.Equal(t, 0, RequiredBump(changes))
+
+	require.Equal(t, -1, RequiredBump(DiffPacks(base, base)))
+}
+
+func TestBumped(t *testing.T) {
+	for _, tc := range []struct {
+		base, head string
+		part       int
+		want       bool
+	}{
+		{"1.0.0", "1.0.1", 2, true},
+		{"1.0.0", "1.0.1", 1, false},
+		{"1.0.3", "1.1.0", 1, true},
+		{"1.4.0", "2.0.0", 0, true},
+		{"1.4.0", "1.5.0", 0, false},
+		{"1.4.0", "1.3.9", 2, false},
+	} {
+		got, err := Bumped(tc.base, tc.head, tc.part)
+		require.NoError(t, err)
+		require.Equalf(t, tc.want, got, "%s -> %s bumping part %d", tc.base, tc.head, tc.part)
+	}
+}
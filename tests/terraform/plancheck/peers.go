@@ -0,0 +1,70 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// PeersEnv lists the plans or states of other environments as env=file pairs
+// separated like PATH, e.g. "stage=stage.json:prod=prod.json".
+const PeersEnv = "COMPLIANCE_PEERS"
+
+// LoadPlanOrState reads the output of "terraform show -json" for either a
+// saved plan or the current state. States are returned as a plan whose
+// planned values are the state's values.
+func LoadPlanOrState(filename string) (*tfjson.Plan, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var probe struct {
+		PlannedValues json.RawMessage `json:"planned_values"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if probe.PlannedValues != nil {
+		var plan tfjson.Plan
+		if err := json.Unmarshal(data, &plan); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		return &plan, nil
+	}
+
+	var state tfjson.State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &tfjson.Plan{FormatVersion: state.FormatVersion, PlannedValues: state.Values}, nil
+}
+
+// LoadPeers loads env=file pairs into plans keyed by environment.
+func LoadPeers(pairs []string) (map[string]*tfjson.Plan, error) {
+	peers := map[string]*tfjson.Plan{}
+	for _, pair := range pairs {
+		if pair == "" {
+			continue
+		}
+		name, filename, ok := strings.Cut(pair, "=")
+		if !ok || name == "" || filename == "" {
+			return nil, fmt.Errorf("peer %q: want env=file", pair)
+		}
+		plan, err := LoadPlanOrState(filename)
+		if err != nil {
+			return nil, err
+		}
+		peers[name] = plan
+	}
+	return peers, nil
+}
+
+// LoadPeersFromEnv loads the peers listed in COMPLIANCE_PEERS.
+func LoadPeersFromEnv() (map[string]*tfjson.Plan, error) {
+	return LoadPeers(filepath.SplitList(os.Getenv(PeersEnv)))
+}
@@ -0,0 +1,24 @@
+package plancheck
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPeersReadsPlansAndStates(t *testing.T) {
+	dir := t.TempDir()
+	planFile := filepath.Join(dir, "stage.json")
+	stateFile := filepath.Join(dir, "prod.json")
+	writeFile(t, planFile, `{"format_version":"1.0","planned_values":{"root_module":{"resources":[{"address":"aws_s3_bucket.a","mode":"managed","type":"aws_s3_bucket","name":"a","values":{"bucket":"stage"}}]}}}`)
+	writeFile(t, stateFile, `{"format_version":"1.0","values":{"root_module":{"resources":[{"address":"aws_s3_bucket.a","mode":"managed","type":"aws_s3_bucket","name":"a","values":{"bucket":"prod"}}]}}}`)
+
+	peers, err := LoadPeers([]string{"stage=" + planFile, "prod=" + stateFile})
+	require.NoError(t, err)
+	require.Equal(t, "stage", LookupString(PlannedResources(peers["stage"])[0].AttributeValues, "bucket"))
+	require.Equal(t, "prod", LookupString(PlannedResources(peers["prod"])[0].AttributeValues, "bucket"))
+
+	_, err = LoadPeers([]string{"prod"})
+	require.ErrorContains(t, err, "want env=file")
+}
@@ -0,0 +1,127 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// ValueChange is a planned value that differs between two plans of the same
+// configuration, e.g. before and after a provider upgrade. Path uses the
+// dotted form Lookup accepts and is empty when the whole resource appears
+// or disappears; the missing side is then nil.
+type ValueChange struct {
+	Address string      `json:"address"`
+	Path    string      `json:"path,omitempty"`
+	Before  interface{} `json:"before"`
+	After   interface{} `json:"after"`
+}
+
+// DiffPlannedValues reports every planned value of before that differs in
+// after, sorted by address and path. Values unknown until apply are absent
+// from planned values and so show up as nil.
+func DiffPlannedValues(before, after *tfjson.Plan) []ValueChange {
+	index := func(plan *tfjson.Plan) map[string]map[string]interface{} {
+		values := map[string]map[string]interface{}{}
+		for _, resource := range PlannedResources(plan) {
+			values[resource.Address] = resource.AttributeValues
+		}
+		return values
+	}
+	return diffResources(index(before), index(after))
+}
+
+// diffResources compares attribute values by resource address.
+func diffResources(beforeValues, afterValues map[string]map[string]interface{}) []ValueChange {
+	var changes []ValueChange
+	for address, values := range beforeValues {
+		other, ok := afterValues[address]
+		if !ok {
+			changes = append(changes, ValueChange{Address: address, Before: values})
+			continue
+		}
+		diffValues(address, "", values, other, &changes)
+	}
+	for address, values := range afterValues {
+		if _, ok := beforeValues[address]; !ok {
+			changes = append(changes, ValueChange{Address: address, After: values})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Address != changes[j].Address {
+			return changes[i].Address < changes[j].Address
+		}
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// String renders the change as one line: "+ address" for an added
+// resource, "- address" for a removed one, and otherwise
+// "~ address path: before -> after", with values as compact JSON and nil as
+// "(unset)".
+func (c ValueChange) String() string {
+	switch {
+	case c.Before == nil && c.Path == "":
+		return "+ " + c.Address
+	case c.After == nil && c.Path == "":
+		return "- " + c.Address
+	default:
+		return fmt.Sprintf("~ %s %s: %s -> %s", c.Address, c.Path, renderValue(c.Before), renderValue(c.After))
+	}
+}
+
+// renderValue prints a planned value compactly, with nil as "(unset)".
+func renderValue(value interface{}) string {
+	if value == nil {
+		return "(unset)"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func diffValues(address, path string, before, after interface{}, changes *[]ValueChange) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch b := before.(type) {
+	case map[string]interface{}:
+		a, ok := after.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for key, value := range b {
+			diffValues(address, join(key), value, a[key], changes)
+		}
+		for key, value := range a {
+			if _, ok := b[key]; !ok {
+				diffValues(address, join(key), nil, value, changes)
+			}
+		}
+		return
+	case []interface{}:
+		a, ok := after.([]interface{})
+		if !ok || len(a) != len(b) {
+			break
+		}
+		for i := range b {
+			diffValues(address, join(strconv.Itoa(i)), b[i], a[i], changes)
+		}
+		return
+	}
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, ValueChange{Address: address, Path: path, Before: before, After: after})
+	}
+}
@@ -0,0 +1,41 @@
+package plancheck
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+func plannedPlan(resources ...*tfjson.StateResource) *tfjson.Plan {
+	return &tfjson.Plan{PlannedValues: &tfjson.StateValues{RootModule: &tfjson.StateModule{Resources: resources}}}
+}
+
+func TestDiffPlannedValues(t *testing.T) {
+	before := plannedPlan(
+		&tfjson.StateResource{Address: "aws_s3_bucket.a", AttributeValues: map[string]interface{}{
+			"bucket":     "a",
+			"versioning": []interface{}{map[string]interface{}{"enabled": false}},
+			"tags":       map[string]interface{}{"Team": "core"},
+		}},
+		&tfjson.StateResource{Address: "aws_s3_bucket.gone", AttributeValues: map[string]interface{}{"bucket": "gone"}},
+	)
+	after := plannedPlan(
+		&tfjson.StateResource{Address: "aws_s3_bucket.a", AttributeValues: map[string]interface{}{
+			"bucket":     "a",
+			"versioning": []interface{}{map[string]interface{}{"enabled": true}},
+			"tags":       map[string]interface{}{"Team": "core"},
+			"region":     "us-east-1",
+		}},
+		&tfjson.StateResource{Address: "aws_s3_bucket.new", AttributeValues: map[string]interface{}{"bucket": "new"}},
+	)
+
+	require.Equal(t, []ValueChange{
+		{Address: "aws_s3_bucket.a", Path: "region", After: "us-east-1"},
+		{Address: "aws_s3_bucket.a", Path: "versioning.0.enabled", Before: false, After: true},
+		{Address: "aws_s3_bucket.gone", Before: map[string]interface{}{"bucket": "gone"}},
+		{Address: "aws_s3_bucket.new", After: map[string]interface{}{"bucket": "new"}},
+	}, DiffPlannedValues(before, after))
+
+	require.Empty(t, DiffPlannedValues(before, before))
+}
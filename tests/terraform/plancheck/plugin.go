@@ -0,0 +1,63 @@
+package plancheck
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+)
+
+// PluginEnv lists rule plugins to load, separated like PATH.
+const PluginEnv = "COMPLIANCE_PLUGINS"
+
+// PluginSymbol is the function a rule plugin must export:
+//
+//	func Rules() []plancheck.Rule
+//
+// Plugins are built from a separate module with
+// "go build -buildmode=plugin" against the same version of this package.
+// Modules that build their own binaries can instead call Register from an
+// init function.
+const PluginSymbol = "Rules"
+
+// LoadPlugin opens a Go plugin and registers the rules it exports, returning
+// their IDs.
+func LoadPlugin(path string) ([]string, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("load rule plugin %s: %w", path, err)
+	}
+	symbol, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("load rule plugin %s: %w", path, err)
+	}
+	rulesFunc, ok := symbol.(func() []Rule)
+	if !ok {
+		return nil, fmt.Errorf("load rule plugin %s: %s has type %T, want func() []plancheck.Rule", path, PluginSymbol, symbol)
+	}
+
+	var ids []string
+	for _, rule := range rulesFunc() {
+		if err := register(rule); err != nil {
+			return ids, fmt.Errorf("load rule plugin %s: %w", path, err)
+		}
+		ids = append(ids, rule.ID)
+	}
+	return ids, nil
+}
+
+// LoadPluginsFromEnv loads every plugin listed in COMPLIANCE_PLUGINS.
+func LoadPluginsFromEnv() ([]string, error) {
+	var ids []string
+	for _, path := range filepath.SplitList(os.Getenv(PluginEnv)) {
+		if path == "" {
+			continue
+		}
+		loaded, err := LoadPlugin(path)
+		ids = append(ids, loaded...)
+		if err != nil {
+			return ids, err
+		}
+	}
+	return ids, nil
+}
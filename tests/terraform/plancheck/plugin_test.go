@@ -0,0 +1,36 @@
+package plancheck
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPluginsFromEnvIgnoresEmptyEntries(t *testing.T) {
+	t.Setenv(PluginEnv, string(filepath.ListSeparator))
+
+	ids, err := LoadPluginsFromEnv()
+	require.NoError(t, err)
+	require.Empty(t, ids)
+}
+
+func TestLoadPluginReportsPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.so")
+
+	_, err := LoadPlugin(path)
+	require.ErrorContains(t, err, path)
+}
+
+func TestRegisterRejectsDuplicates(t *testing.T) {
+	rule := Rule{ID: "test.duplicate", Check: func(*Input) []Finding { return nil }}
+	require.NoError(t, register(rule))
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, rule.ID)
+		registryMu.Unlock()
+	})
+
+	require.ErrorContains(t, register(rule), "registered twice")
+	require.Panics(t, func() { Register(rule) })
+}
@@ -0,0 +1,345 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// PolicyDocument is a JSON IAM policy, located by the resource and attribute
+// path that define it.
+type PolicyDocument struct {
+	Address string
+	// Path is the attribute holding the document, e.g. "policy" or
+	// "inline_policy[0].policy".
+	Path   string
+	Policy string
+}
+
+// ParsePolicy returns the permission policy documents a resource defines:
+// the policy of a managed, role, user or group policy, the inline_policy
+// blocks of a role and the rendered json of an aws_iam_policy_document data
+// source. Empty documents are left out.
+func ParsePolicy(resource *tfjson.StateResource) []PolicyDocument {
+	var documents []PolicyDocument
+	add := func(path, policy string) {
+		if strings.TrimSpace(policy) != "" {
+			documents = append(documents, PolicyDocument{resource.Address, path, policy})
+		}
+	}
+	switch resource.Type {
+	case "aws_iam_policy", "aws_iam_role_policy", "aws_iam_user_policy", "aws_iam_group_policy":
+		add("policy", LookupString(resource.AttributeValues, "policy"))
+	case "aws_iam_role":
+		for i, inline := range Blocks(resource.AttributeValues, "inline_policy") {
+			add(fmt.Sprintf("inline_policy[%d].policy", i), LookupString(inline, "policy"))
+		}
+	case "aws_iam_policy_document":
+		add("json", LookupString(resource.AttributeValues, "json"))
+	}
+	return documents
+}
+
+// Policies returns every permission policy document in the plan: managed
+// policies, role, user and group policies, the inline_policy blocks of roles
+// and rendered aws_iam_policy_document data sources. A data source whose JSON
+// is also the policy of a resource is left out, so the grant is reported once,
+// on the resource.
+func Policies(plan *tfjson.Plan) []PolicyDocument {
+	var documents []PolicyDocument
+	granted := map[string]bool{}
+	// Policy resources come before roles' inline policies.
+	for _, types := range [][]string{{"aws_iam_policy", "aws_iam_role_policy", "aws_iam_user_policy", "aws_iam_group_policy"}, {"aws_iam_role"}} {
+		for _, resource := range Resources(plan, types...) {
+			if resource.Mode == tfjson.DataResourceMode {
+				continue
+			}
+			for _, document := range ParsePolicy(resource) {
+				granted[document.Policy] = true
+				documents = append(documents, document)
+			}
+		}
+	}
+	for _, data := range DataSources(plan, "aws_iam_policy_document") {
+		for _, document := range ParsePolicy(data) {
+			if !granted[document.Policy] {
+				documents = append(documents, document)
+			}
+		}
+	}
+	return documents
+}
+
+// Statement is one statement of a parsed policy document.
+type Statement struct {
+	// Path locates the statement within the policy document, e.g.
+	// "Statement[2]".
+	Path   string
+	Sid    string
+	Effect string
+
+	// Action, NotAction, Resource and NotResource hold the string values of
+	// those elements, given in the document as one string or a list.
+	Action      []string
+	NotAction   []string
+	Resource    []string
+	NotResource []string
+
+	// Condition lists the statement's condition tests, ordered by operator
+	// and key.
+	Condition []Condition
+
+	// Fields is the statement as parsed, for the elements above in their
+	// original form and for the others, such as Principal.
+	Fields map[string]interface{}
+}
+
+// Condition is one test of a statement's Condition block, e.g. operator
+// StringEquals, key aws:SourceArn.
+type Condition struct {
+	Operator string
+	Key      string
+	Values   []string
+}
+
+// ConstrainingConditionKeys are the condition keys that tie a grant to
+// specific principals, sources or networks, so that even a wildcard grant
+// under them reaches little.
+var ConstrainingConditionKeys = []string{
+	"aws:PrincipalArn",
+	"aws:PrincipalAccount",
+	"aws:PrincipalOrgID",
+	"aws:SourceArn",
+	"aws:SourceAccount",
+	"aws:SourceOwner",
+	"aws:SourceVpc",
+	"aws:SourceVpce",
+	"aws:SourceIp",
+	"aws:CalledVia",
+}
+
+// constrainingOperators are the operators that only pass for the values
+// they list. Their ...IfExists forms pass when the key is absent, and
+// negated operators pass for every other value, so neither constrains.
+// ForAllValues operators pass when the key is absent too.
+var constrainingOperators = []string{"StringEquals", "StringLike", "ArnEquals", "ArnLike", "IpAddress", "ForAnyValue:StringEquals", "ForAnyValue:StringLike"}
+
+// anyAddress are the IpAddress values that match every address.
+var anyAddress = []string{"0.0.0.0/0", "::/0"}
+
+// ParseStatement builds a Statement from its JSON fields.
+func ParseStatement(path string, fields map[string]interface{}) Statement {
+	statement := Statement{
+		Path:        path,
+		Action:      statementStrings(fields["Action"]),
+		NotAction:   statementStrings(fields["NotAction"]),
+		Resource:    statementStrings(fields["Resource"]),
+		NotResource: statementStrings(fields["NotResource"]),
+		Fields:      fields,
+	}
+	statement.Sid, _ = fields["Sid"].(string)
+	statement.Effect, _ = fields["Effect"].(string)
+
+	operators, _ := fields["Condition"].(map[string]interface{})
+	for operator, tests := range operators {
+		keys, _ := tests.(map[string]interface{})
+		for key, values := range keys {
+			statement.Condition = append(statement.Condition, Condition{Operator: operator, Key: key, Values: statementStrings(values)})
+		}
+	}
+	sort.Slice(statement.Condition, func(i, j int) bool {
+		a, b := statement.Condition[i], statement.Condition[j]
+		if a.Operator != b.Operator {
+			return a.Operator < b.Operator
+		}
+		return a.Key < b.Key
+	})
+	return statement
+}
+
+// statementStrings returns the strings of a value given as one string or a
+// list; other entries are left out.
+func statementStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, entry := range v {
+			if s, ok := entry.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Allows reports whether the statement grants rather than denies.
+func (s Statement) Allows() bool {
+	return s.Effect == "Allow"
+}
+
+// ElementPath returns the path of the i-th value of element, e.g.
+// "Statement[0].Action[1]", or of the element itself when it is one string.
+func (s Statement) ElementPath(element string, i int) string {
+	if _, list := s.Fields[element].([]interface{}); list {
+		return fmt.Sprintf("%s.%s[%d]", s.Path, element, i)
+	}
+	return s.Path + "." + element
+}
+
+// Constraints returns the ConstrainingConditionKeys the statement's
+// conditions limit to specific values, in the form the statement uses.
+func (s Statement) Constraints() []string {
+	var keys []string
+	for _, condition := range s.Condition {
+		if !containsFold(constrainingOperators, condition.Operator) || !containsFold(ConstrainingConditionKeys, condition.Key) {
+			continue
+		}
+		if len(condition.Values) == 0 || !condition.limits() {
+			continue
+		}
+		keys = append(keys, condition.Key)
+	}
+	return keys
+}
+
+// limits reports whether every value of a constraining condition names
+// specific principals, sources or networks: a *Like operator's values hold
+// no '*' or '?', which could stand for any account or name, and an
+// IpAddress value is not a range of every address.
+func (c Condition) limits() bool {
+	like := strings.HasSuffix(strings.ToLower(c.Operator), "like")
+	for _, value := range c.Values {
+		switch {
+		case value == "*":
+			return false
+		case like && strings.ContainsAny(value, "*?"):
+			return false
+		case strings.EqualFold(c.Operator, "IpAddress") && containsFold(anyAddress, value):
+			return false
+		}
+	}
+	return true
+}
+
+// containsFold reports whether values holds value, ignoring case as IAM
+// does for operators and condition keys.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Statements returns the statements of a parsed policy document, whose
+// Statement may be a single object or a list.
+func Statements(policy map[string]interface{}) []Statement {
+	statements, ok := policy["Statement"]
+	if !ok {
+		return nil
+	}
+
+	switch s := statements.(type) {
+	case map[string]interface{}:
+		return []Statement{ParseStatement("Statement", s)}
+	case []interface{}:
+		var result []Statement
+		for i, entry := range s {
+			stmt, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			result = append(result, ParseStatement(fmt.Sprintf("Statement[%d]", i), stmt))
+		}
+		return result
+	}
+	return nil
+}
+
+// PolicyWildcard is a statement element of a policy document that grants
+// every action or resource, or every one but those it lists.
+type PolicyWildcard struct {
+	// Path is the element's path, e.g. "Statement[2].Action[0]".
+	Path string
+	// Negated is set for an Allow statement's NotAction or NotResource,
+	// which grants everything the element does not list.
+	Negated bool
+	// Constraints are the condition keys limiting an Allow statement's
+	// grant; see Statement.Constraints.
+	Constraints []string
+}
+
+// FindWildcards returns the elements of a JSON policy document that grant a
+// wildcard field, Action or Resource: the field itself when it holds "*",
+// and the NotAction or NotResource of Allow statements.
+func FindWildcards(policy, field string) ([]PolicyWildcard, error) {
+	var policyDoc map[string]interface{}
+	if err := json.Unmarshal([]byte(policy), &policyDoc); err != nil {
+		return nil, err
+	}
+
+	var wildcards []PolicyWildcard
+	for _, statement := range Statements(policyDoc) {
+		var constraints []string
+		if statement.Allows() {
+			constraints = statement.Constraints()
+		}
+		if value, exists := statement.Fields[field]; exists {
+			if suffix, found := wildcardPath(value); found {
+				wildcards = append(wildcards, PolicyWildcard{Path: statement.Path + "." + field + suffix, Constraints: constraints})
+			}
+		}
+		if _, exists := statement.Fields["Not"+field]; exists && statement.Allows() {
+			wildcards = append(wildcards, PolicyWildcard{Path: statement.Path + ".Not" + field, Negated: true, Constraints: constraints})
+		}
+	}
+	return wildcards, nil
+}
+
+// PolicyWildcards returns the paths of the elements FindWildcards returns.
+func PolicyWildcards(policy, field string) ([]string, error) {
+	wildcards, err := FindWildcards(policy, field)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, wildcard := range wildcards {
+		paths = append(paths, wildcard.Path)
+	}
+	return paths, nil
+}
+
+// wildcardPath returns the path of the first wildcard within value relative
+// to value itself ("" for a bare "*", "[1]" for the second list element).
+func wildcardPath(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return "", strings.TrimSpace(v) == "*"
+	case []interface{}:
+		for i, item := range v {
+			if suffix, ok := wildcardPath(item); ok {
+				return fmt.Sprintf("[%d]%s", i, suffix), true
+			}
+		}
+	case map[string]interface{}:
+		// Handle structured values such as {"Fn::Join": [...] } by checking nested elements.
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if suffix, ok := wildcardPath(v[key]); ok {
+				return "." + key + suffix, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,90 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+const policyPlan = `{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"resources": [
+    {"address": "aws_iam_role.app", "mode": "managed", "type": "aws_iam_role", "name": "app", "values": {"inline_policy": [
+      {"name": "logs", "policy": "{\"Statement\":{\"Effect\":\"Allow\",\"Action\":\"logs:*\",\"Resource\":\"*\"}}"}
+    ]}},
+    {"address": "aws_iam_policy.deploy", "mode": "managed", "type": "aws_iam_policy", "name": "deploy", "values": {"policy": "{\"Statement\":[{\"Effect\":\"Allow\",\"Action\":[\"s3:GetObject\",\"*\"],\"Resource\":\"arn:aws:s3:::bucket/*\"}]}"}},
+    {"address": "aws_iam_policy.empty", "mode": "managed", "type": "aws_iam_policy", "name": "empty", "values": {"policy": " "}}
+  ]}},
+  "prior_state": {"format_version": "1.0", "values": {"root_module": {"resources": [
+    {"address": "data.aws_iam_policy_document.deploy", "mode": "data", "type": "aws_iam_policy_document", "name": "deploy", "values": {"json": "{\"Statement\":[{\"Effect\":\"Allow\",\"Action\":[\"s3:GetObject\",\"*\"],\"Resource\":\"arn:aws:s3:::bucket/*\"}]}"}},
+    {"address": "data.aws_iam_policy_document.unused", "mode": "data", "type": "aws_iam_policy_document", "name": "unused", "values": {"json": "{\"Statement\":[]}"}}
+  ]}}}
+}`
+
+func TestPoliciesReportsEachGrantOnce(t *testing.T) {
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(policyPlan), &plan))
+
+	var located []string
+	for _, document := range Policies(&plan) {
+		located = append(located, document.Address+" "+document.Path)
+	}
+	require.Equal(t, []string{
+		"aws_iam_policy.deploy policy",
+		"aws_iam_role.app inline_policy[0].policy",
+		"data.aws_iam_policy_document.unused json",
+	}, located)
+}
+
+func TestStatementsAndWildcards(t *testing.T) {
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(policyPlan), &plan))
+	role := Resources(&plan, "aws_iam_role")[0]
+
+	documents := ParsePolicy(role)
+	require.Len(t, documents, 1)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(documents[0].Policy), &doc))
+	statements := Statements(doc)
+	require.Len(t, statements, 1)
+	require.Equal(t, "Statement", statements[0].Path, "a single statement object has no index")
+	require.Equal(t, "logs:*", statements[0].Fields["Action"])
+
+	paths, err := PolicyWildcards(documents[0].Policy, "Resource")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Statement.Resource"}, paths)
+
+	paths, err = PolicyWildcards(ParsePolicy(Resources(&plan, "aws_iam_policy")[0])[0].Policy, "Action")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Statement[0].Action[1]"}, paths)
+
+	_, err = PolicyWildcards("{", "Action")
+	require.Error(t, err)
+}
+
+func TestFindWildcardsNegatedAndConstrained(t *testing.T) {
+	policy := `{"Statement":[
+	  {"Effect":"Allow","NotAction":"iam:*","Resource":"*","Condition":{"StringEquals":{"aws:PrincipalOrgID":"o-abc"}}},
+	  {"Effect":"Deny","NotAction":["sts:*"],"Resource":"*"},
+	  {"Effect":"Allow","Action":"*","Resource":"*","Condition":{"StringNotEquals":{"aws:SourceVpc":"vpc-1"},"StringLikeIfExists":{"aws:SourceArn":"arn:aws:sns:*"}}}
+	]}`
+
+	wildcards, err := FindWildcards(policy, "Action")
+	require.NoError(t, err)
+	require.Equal(t, []PolicyWildcard{
+		{Path: "Statement[0].NotAction", Negated: true, Constraints: []string{"aws:PrincipalOrgID"}},
+		{Path: "Statement[2].Action"},
+	}, wildcards, "Deny statements grant nothing, and negated or IfExists conditions do not constrain")
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(policy), &doc))
+	statement := Statements(doc)[2]
+	require.Equal(t, []string{"*"}, statement.Action)
+	require.Equal(t, []Condition{
+		{Operator: "StringLikeIfExists", Key: "aws:SourceArn", Values: []string{"arn:aws:sns:*"}},
+		{Operator: "StringNotEquals", Key: "aws:SourceVpc", Values: []string{"vpc-1"}},
+	}, statement.Condition)
+	require.Equal(t, "Statement[2].Action", statement.ElementPath("Action", 0))
+}
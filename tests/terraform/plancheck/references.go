@@ -0,0 +1,132 @@
+package plancheck
+
+import (
+	"strconv"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// ConfigResource returns the configuration block of the planned resource at
+// address, or nil when the plan carries no configuration for it.
+func (in *Input) ConfigResource(address string) *tfjson.ConfigResource {
+	if in.resources == nil {
+		in.resources = map[string]*tfjson.ConfigResource{}
+		if in.Plan != nil && in.Plan.Config != nil {
+			indexConfigResources(in.Plan.Config.RootModule, "", in.resources)
+		}
+	}
+	return in.resources[ConfigAddress(address)]
+}
+
+// References returns the configuration addresses of the resources that an
+// attribute of the resource at address refers to, e.g. the tables listed in an
+// aws_backup_selection's "resources" argument. Arguments of nested blocks are
+// named by their dotted path, e.g. "rule.destination.bucket", and collect the
+// references of every block instance; an index selects one instance, e.g.
+// "rule.1.destination.bucket". References are resolved within the
+// resource's own module; values passed in through module variables and
+// outputs are not followed.
+func (in *Input) References(address, attribute string) []string {
+	resource := in.ConfigResource(address)
+	if resource == nil {
+		return nil
+	}
+	exprs := nestedExpressions(resource.Expressions, strings.Split(attribute, "."))
+	if len(exprs) == 0 {
+		return nil
+	}
+
+	prefix := ConfigAddress(ModulePath(address))
+	if prefix != "" {
+		prefix += "."
+	}
+
+	seen := map[string]bool{}
+	var refs []string
+	for _, expr := range exprs {
+		for _, ref := range expr.References {
+			target := referencedResource(ref)
+			if target == "" || seen[target] {
+				continue
+			}
+			seen[target] = true
+			refs = append(refs, prefix+target)
+		}
+	}
+	return refs
+}
+
+// Configured reports whether the configuration of the resource at address
+// sets attribute, named as for References, to a constant or an expression.
+// It tells an argument whose value is only known at apply, such as the ARN
+// of a key created in the same run, from one left unset.
+func (in *Input) Configured(address, attribute string) bool {
+	resource := in.ConfigResource(address)
+	if resource == nil {
+		return false
+	}
+	for _, expr := range nestedExpressions(resource.Expressions, strings.Split(attribute, ".")) {
+		if expr.ConstantValue != nil || len(expr.References) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// nestedExpressions follows path through nested blocks and returns the
+// expressions of the final argument.
+func nestedExpressions(expressions map[string]*tfjson.Expression, path []string) []*tfjson.Expression {
+	expr, ok := expressions[path[0]]
+	if !ok || expr == nil || expr.ExpressionData == nil {
+		return nil
+	}
+	if len(path) == 1 {
+		return []*tfjson.Expression{expr}
+	}
+
+	if index, err := strconv.Atoi(path[1]); err == nil {
+		if index < 0 || index >= len(expr.NestedBlocks) || len(path) == 2 {
+			return nil
+		}
+		return nestedExpressions(expr.NestedBlocks[index], path[2:])
+	}
+
+	var exprs []*tfjson.Expression
+	for _, block := range expr.NestedBlocks {
+		exprs = append(exprs, nestedExpressions(block, path[1:])...)
+	}
+	return exprs
+}
+
+func indexConfigResources(module *tfjson.ConfigModule, prefix string, acc map[string]*tfjson.ConfigResource) {
+	if module == nil {
+		return
+	}
+	for _, resource := range module.Resources {
+		acc[prefix+resource.Address] = resource
+	}
+	for name, call := range module.ModuleCalls {
+		indexConfigResources(call.Module, prefix+"module."+name+".", acc)
+	}
+}
+
+// referencedResource reduces a reference such as "aws_dynamodb_table.this.arn"
+// or "data.aws_iam_role.x" to the resource it names. References to variables,
+// locals, modules and other non-resource objects return "".
+func referencedResource(ref string) string {
+	parts := strings.Split(ConfigAddress(ref), ".")
+	switch parts[0] {
+	case "var", "local", "module", "each", "count", "path", "terraform", "self":
+		return ""
+	case "data":
+		if len(parts) < 3 {
+			return ""
+		}
+		return strings.Join(parts[:3], ".")
+	}
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0] + "." + parts[1]
+}
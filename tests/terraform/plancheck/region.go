@@ -0,0 +1,154 @@
+package plancheck
+
+import (
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// RegionIndex resolves the AWS region each planned resource is created in,
+// from its own "region" attribute or from the provider configuration (alias
+// included) that the resource uses.
+type RegionIndex struct {
+	defaultRegion string
+	providers     map[string]string
+	resources     map[string]string
+	byAddress     map[string]string
+}
+
+// NewRegionIndex indexes the regions of every resource in plan. defaultRegion
+// is used for resources whose provider region cannot be determined, e.g. when
+// it comes from an environment variable.
+func NewRegionIndex(plan *tfjson.Plan, defaultRegion string) *RegionIndex {
+	index := &RegionIndex{
+		defaultRegion: defaultRegion,
+		providers:     map[string]string{},
+		resources:     map[string]string{},
+		byAddress:     map[string]string{},
+	}
+	if plan == nil {
+		return index
+	}
+
+	if plan.Config != nil {
+		for key, provider := range plan.Config.ProviderConfigs {
+			if region := expressionString(plan, provider.Expressions["region"]); region != "" {
+				index.providers[key] = region
+			}
+		}
+		collectProviderKeys(plan.Config.RootModule, "", index.resources)
+	}
+
+	for _, resource := range PlannedResources(plan) {
+		if resource == nil {
+			continue
+		}
+		index.byAddress[resource.Address] = index.resolve(resource)
+	}
+	return index
+}
+
+// RegionOf returns the region of the resource at address.
+func (r *RegionIndex) RegionOf(address string) string {
+	if region, ok := r.byAddress[address]; ok {
+		return region
+	}
+	return r.defaultRegion
+}
+
+// Regions returns the distinct regions resources are planned in.
+func (r *RegionIndex) Regions() []string {
+	seen := map[string]bool{}
+	var regions []string
+	for _, region := range r.byAddress {
+		if region != "" && !seen[region] {
+			seen[region] = true
+			regions = append(regions, region)
+		}
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+func (r *RegionIndex) resolve(resource *tfjson.StateResource) string {
+	if region, ok := resource.AttributeValues["region"].(string); ok && region != "" {
+		return region
+	}
+
+	for _, key := range r.providerKeys(resource.Address) {
+		if region, ok := r.providers[key]; ok {
+			return region
+		}
+	}
+	return r.defaultRegion
+}
+
+// providerKeys returns the keys of the provider configurations the resource
+// at address may use, most specific first. Modules that inherit their
+// provider report keys such as "module.s3:aws", followed by the root
+// provider configuration with the same name.
+func (r *RegionIndex) providerKeys(address string) []string {
+	key, ok := r.resources[ConfigAddress(address)]
+	if !ok {
+		return nil
+	}
+	keys := []string{key}
+	if idx := strings.LastIndexByte(key, ':'); idx >= 0 {
+		keys = append(keys, key[idx+1:])
+	}
+	return keys
+}
+
+func collectProviderKeys(module *tfjson.ConfigModule, prefix string, acc map[string]string) {
+	if module == nil {
+		return
+	}
+	for _, resource := range module.Resources {
+		acc[prefix+resource.Address] = resource.ProviderConfigKey
+	}
+	for name, call := range module.ModuleCalls {
+		collectProviderKeys(call.Module, prefix+"module."+name+".", acc)
+	}
+}
+
+// expressionString evaluates a provider argument that is either a constant
+// or a direct reference to a root variable.
+func expressionString(plan *tfjson.Plan, expr *tfjson.Expression) string {
+	if expr == nil || expr.ExpressionData == nil {
+		return ""
+	}
+	if value, ok := expr.ConstantValue.(string); ok {
+		return value
+	}
+	for _, ref := range expr.References {
+		name := strings.TrimPrefix(ref, "var.")
+		if name == ref {
+			continue
+		}
+		if variable, ok := plan.Variables[name]; ok && variable != nil {
+			if value, ok := variable.Value.(string); ok {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// ConfigAddress drops instance keys so a planned address matches its
+// configuration address: module.a["x"].aws_s3_bucket.b[0] -> module.a.aws_s3_bucket.b.
+func ConfigAddress(address string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range address {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
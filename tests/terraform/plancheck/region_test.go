@@ -0,0 +1,111 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+const multiRegionPlan = `{
+  "format_version": "1.0",
+  "variables": {"aws_region": {"value": "us-east-1"}},
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_s3_bucket.primary", "type": "aws_s3_bucket", "name": "primary", "values": {}},
+        {"address": "aws_s3_bucket.replica", "type": "aws_s3_bucket", "name": "replica", "values": {}},
+        {"address": "aws_s3_bucket.pinned", "type": "aws_s3_bucket", "name": "pinned", "values": {"region": "eu-west-1"}}
+      ],
+      "child_modules": [
+        {
+          "address": "module.dr[0]",
+          "resources": [
+            {"address": "module.dr[0].aws_dynamodb_table.t", "type": "aws_dynamodb_table", "name": "t", "values": {}}
+          ]
+        }
+      ]
+    }
+  },
+  "configuration": {
+    "provider_config": {
+      "aws": {"name": "aws", "expressions": {"region": {"references": ["var.aws_region"]}}},
+      "aws.dr": {"name": "aws", "alias": "dr", "expressions": {"region": {"constant_value": "us-west-2"}}}
+    },
+    "root_module": {
+      "resources": [
+        {"address": "aws_s3_bucket.primary", "type": "aws_s3_bucket", "name": "primary", "provider_config_key": "aws"},
+        {"address": "aws_s3_bucket.replica", "type": "aws_s3_bucket", "name": "replica", "provider_config_key": "aws.dr"},
+        {"address": "aws_s3_bucket.pinned", "type": "aws_s3_bucket", "name": "pinned", "provider_config_key": "aws"}
+      ],
+      "module_calls": {
+        "dr": {
+          "source": "./dr",
+          "module": {
+            "resources": [
+              {"address": "aws_dynamodb_table.t", "type": "aws_dynamodb_table", "name": "t", "provider_config_key": "module.dr:aws.dr"}
+            ]
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestRegionIndexResolvesProviderAliases(t *testing.T) {
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(multiRegionPlan), &plan))
+
+	index := NewRegionIndex(&plan, "ap-south-1")
+	require.Equal(t, "us-east-1", index.RegionOf("aws_s3_bucket.primary"))
+	require.Equal(t, "us-west-2", index.RegionOf("aws_s3_bucket.replica"))
+	require.Equal(t, "eu-west-1", index.RegionOf("aws_s3_bucket.pinned"))
+	require.Equal(t, "us-west-2", index.RegionOf("module.dr[0].aws_dynamodb_table.t"))
+	require.Equal(t, "ap-south-1", index.RegionOf("aws_s3_bucket.unknown"))
+	require.Equal(t, []string{"eu-west-1", "us-east-1", "us-west-2"}, index.Regions())
+}
+
+func TestEvaluateTagsFindingsWithRegion(t *testing.T) {
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(multiRegionPlan), &plan))
+
+	rule := Rule{
+		ID: "test.every-bucket",
+		Check: func(in *Input) []Finding {
+			var findings []Finding
+			for _, resource := range PlannedResources(in.Plan) {
+				findings = append(findings, NewFinding("", resource.Address, "found"))
+			}
+			return findings
+		},
+	}
+
+	findings := Evaluate(&Input{Plan: &plan}, rule)
+	require.Len(t, findings, 4)
+	require.Equal(t, "us-west-2/aws_s3_bucket.replica", findings[1].Key())
+	require.Equal(t, "test.every-bucket", findings[1].RuleID)
+}
+
+func TestEvaluateRegionsPassesEnvironmentConfig(t *testing.T) {
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(multiRegionPlan), &plan))
+
+	rule := Rule{
+		ID: "test.production-only",
+		Check: func(in *Input) []Finding {
+			if !in.Settings().Production {
+				return nil
+			}
+			return []Finding{NewFinding("", "aws_s3_bucket.primary", in.Environment+" in "+in.DefaultRegion)}
+		},
+	}
+	config := &Config{Environments: map[string]Environment{"prod": {Production: true}}}
+	plans := map[string]*tfjson.Plan{"us-east-1": &plan, "us-west-2": &plan}
+
+	require.Empty(t, EvaluateRegions("dev", config, plans, rule))
+	findings := EvaluateRegions("prod", config, plans, rule)
+	require.Len(t, findings, 2)
+	require.Equal(t, "prod in us-east-1", findings[0].Message)
+	require.Equal(t, "prod in us-west-2", findings[1].Message)
+}
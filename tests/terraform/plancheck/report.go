@@ -0,0 +1,203 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// ReportDirEnv names the environment variable that enables writing reports.
+	ReportDirEnv = "COMPLIANCE_REPORT_DIR"
+	// SARIFEnv names the file to write the findings of a whole run to as
+	// SARIF.
+	SARIFEnv = "COMPLIANCE_SARIF"
+	// JUnitEnv names the file to write the findings of a whole run to as
+	// JUnit XML.
+	JUnitEnv = "COMPLIANCE_JUNIT"
+)
+
+// WriteFindings writes findings to filename as indented JSON.
+func WriteFindings(filename string, findings []Finding) error {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	data, err := json.MarshalIndent(findings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// ReadFindings loads a findings file written by WriteFindings.
+func ReadFindings(filename string) ([]Finding, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	if err := json.Unmarshal(data, &findings); err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
+
+// SplitByOwner groups findings by their Owner field.
+func SplitByOwner(findings []Finding) map[string][]Finding {
+	byOwner := make(map[string][]Finding)
+	for _, finding := range findings {
+		owner := finding.Owner
+		if owner == "" {
+			owner = UnownedTeam
+		}
+		byOwner[owner] = append(byOwner[owner], finding)
+	}
+	return byOwner
+}
+
+// WriteReports writes findings.json with every finding plus one
+// findings.<owner>.json per owner into dir, and returns the files written.
+func WriteReports(dir string, findings []Finding) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	SortFindings(findings)
+	all := filepath.Join(dir, "findings.json")
+	if err := WriteFindings(all, findings); err != nil {
+		return nil, err
+	}
+	written := []string{all}
+
+	byOwner := SplitByOwner(findings)
+	owners := make([]string, 0, len(byOwner))
+	for owner := range byOwner {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+
+	for _, owner := range owners {
+		filename := filepath.Join(dir, "findings."+ownerFileName(owner)+".json")
+		if err := WriteFindings(filename, byOwner[owner]); err != nil {
+			return written, err
+		}
+		written = append(written, filename)
+	}
+	return written, nil
+}
+
+// ownerFileName turns "@org/networking" into "org-networking".
+func ownerFileName(owner string) string {
+	name := strings.TrimPrefix(owner, "@")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '-'
+		}
+	}, name)
+}
+
+// Collector gathers the findings of every test in a run, for the SARIF and
+// JUnit reports that cover the whole run. It is safe for concurrent use.
+type Collector struct {
+	mu         sync.Mutex
+	suites     []ReportSuite
+	identities map[string]string
+}
+
+// Identify records the AWS principal environment ran as, for the reports'
+// headers.
+func (c *Collector) Identify(environment, identity string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.identities == nil {
+		c.identities = map[string]string{}
+	}
+	c.identities[environment] = identity
+}
+
+// Identities returns the principals recorded by Identify, by environment.
+func (c *Collector) Identities() map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	identities := make(map[string]string, len(c.identities))
+	for environment, identity := range c.identities {
+		identities[environment] = identity
+	}
+	return identities
+}
+
+// Add records the findings of one test. A test added twice gets them all.
+func (c *Collector) Add(test string, findings []Finding) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.suites {
+		if c.suites[i].Name == test {
+			c.suites[i].Findings = append(c.suites[i].Findings, findings...)
+			return
+		}
+	}
+	c.suites = append(c.suites, ReportSuite{Name: test, Findings: append([]Finding(nil), findings...)})
+}
+
+// Suites returns the recorded tests, ordered by name.
+func (c *Collector) Suites() []ReportSuite {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	suites := append([]ReportSuite(nil), c.suites...)
+	sort.Slice(suites, func(i, j int) bool { return suites[i].Name < suites[j].Name })
+	return suites
+}
+
+// Findings returns the findings of every recorded test, in the order of
+// Suites.
+func (c *Collector) Findings() []Finding {
+	var findings []Finding
+	for _, suite := range c.Suites() {
+		findings = append(findings, suite.Findings...)
+	}
+	return findings
+}
+
+// WriteFiles writes the SARIF log to sarifFile and the JUnit report to
+// junitFile, skipping either when its name is empty, and returns the files
+// written. SARIF source paths are made relative to root. Both carry the
+// recorded identities.
+func (c *Collector) WriteFiles(sarifFile, junitFile, root string) ([]string, error) {
+	suites, identities := c.Suites(), c.Identities()
+	var written []string
+	if sarifFile != "" {
+		findings := c.Findings()
+		if err := writeReportFile(sarifFile, func(f *os.File) error { return WriteSARIF(f, findings, root, identities) }); err != nil {
+			return written, err
+		}
+		written = append(written, sarifFile)
+	}
+	if junitFile != "" {
+		if err := writeReportFile(junitFile, func(f *os.File) error { return WriteJUnit(f, suites, identities) }); err != nil {
+			return written, err
+		}
+		written = append(written, junitFile)
+	}
+	return written, nil
+}
+
+func writeReportFile(filename string, write func(*os.File) error) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,120 @@
+package plancheck
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Failures are the blocking findings of a run, kept so the next run can
+// re-check only them.
+type Failures struct {
+	// byRule maps rule IDs to the Finding.Key of each resource they failed
+	// on.
+	byRule map[string]map[string]bool
+}
+
+// NewFailures records the blocking findings among findings.
+func NewFailures(findings []Finding) Failures {
+	f := Failures{byRule: map[string]map[string]bool{}}
+	for _, finding := range findings {
+		if !finding.Severity.Blocking() {
+			continue
+		}
+		if f.byRule[finding.RuleID] == nil {
+			f.byRule[finding.RuleID] = map[string]bool{}
+		}
+		f.byRule[finding.RuleID][finding.Key()] = true
+	}
+	return f
+}
+
+// LoadFailures reads the failures SaveFailures wrote. A missing file records
+// none.
+func LoadFailures(filename string) (Failures, error) {
+	findings, err := ReadFindings(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewFailures(nil), nil
+	}
+	if err != nil {
+		return Failures{}, err
+	}
+	return NewFailures(findings), nil
+}
+
+// SaveFailures writes the blocking findings among findings to filename,
+// creating its directory.
+func SaveFailures(filename string, findings []Finding) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	blocking, _ := SplitAdvisories(findings)
+	return WriteFindings(filename, blocking)
+}
+
+// UpdateFailures records the blocking findings of a run that checked only
+// ruleIDs (every rule when empty) on the resources focus matches. The
+// failures that run re-checked are replaced by findings and the others in
+// filename are kept, so a run of one rule does not forget another's. A full
+// run replaces them all, as SaveFailures does.
+func UpdateFailures(filename string, ruleIDs []string, focus Focus, findings []Finding) error {
+	if len(ruleIDs) == 0 && len(focus) == 0 {
+		return SaveFailures(filename, findings)
+	}
+	previous, err := ReadFindings(filename)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	checked := map[string]bool{}
+	for _, id := range ruleIDs {
+		checked[id] = true
+	}
+	var kept []Finding
+	for _, finding := range previous {
+		if (len(checked) == 0 || checked[finding.RuleID]) && focus.Matches(finding.Address) {
+			continue
+		}
+		kept = append(kept, finding)
+	}
+	return SaveFailures(filename, append(kept, findings...))
+}
+
+// Empty reports whether no finding failed.
+func (f Failures) Empty() bool {
+	return len(f.byRule) == 0
+}
+
+// RuleIDs returns the rules that failed, sorted.
+func (f Failures) RuleIDs() []string {
+	ids := make([]string, 0, len(f.byRule))
+	for id := range f.byRule {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Resources returns the number of distinct resources that failed.
+func (f Failures) Resources() int {
+	keys := map[string]bool{}
+	for _, byKey := range f.byRule {
+		for key := range byKey {
+			keys[key] = true
+		}
+	}
+	return len(keys)
+}
+
+// Filter keeps the findings of a failed rule on a resource it failed on.
+// Findings of that rule on other resources are left to a full run.
+func (f Failures) Filter(findings []Finding) []Finding {
+	var kept []Finding
+	for _, finding := range findings {
+		if f.byRule[finding.RuleID][finding.Key()] {
+			kept = append(kept, finding)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,59 @@
+package plancheck
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailuresKeepBlockingFindingsByRuleAndResource(t *testing.T) {
+	logs := NewFinding("logs.retention", "aws_cloudwatch_log_group.api", "never expires")
+	wildcard := NewFinding("iam.wildcard-action", "aws_iam_policy.api", "allows s3:*")
+	wildcard.Region = "us-west-2"
+	advisory := NewFinding("tags.required", "aws_s3_bucket.artifacts", "no Owner tag")
+	advisory.Severity = SeverityAdvisory
+
+	filename := filepath.Join(t.TempDir(), "failures", "dev.json")
+	missing, err := LoadFailures(filename)
+	require.NoError(t, err)
+	require.True(t, missing.Empty())
+
+	require.NoError(t, SaveFailures(filename, []Finding{logs, wildcard, advisory}))
+	failures, err := LoadFailures(filename)
+	require.NoError(t, err)
+	require.Equal(t, []string{"iam.wildcard-action", "logs.retention"}, failures.RuleIDs())
+	require.Equal(t, 2, failures.Resources())
+
+	otherRegion := wildcard
+	otherRegion.Region = "us-east-1"
+	otherResource := NewFinding("logs.retention", "aws_cloudwatch_log_group.worker", "never expires")
+	require.Equal(t, []Finding{logs, wildcard}, failures.Filter([]Finding{logs, otherResource, wildcard, otherRegion, advisory}))
+}
+
+func TestUpdateFailuresKeepsWhatARunDidNotRecheck(t *testing.T) {
+	api := NewFinding("logs.retention", "aws_cloudwatch_log_group.api", "never expires")
+	artifacts := NewFinding("logs.retention", "module.artifacts.aws_cloudwatch_log_group.access", "never expires")
+	wildcard := NewFinding("iam.wildcard-action", "aws_iam_policy.api", "allows s3:*")
+
+	filename := filepath.Join(t.TempDir(), "dev.json")
+	require.NoError(t, UpdateFailures(filename, nil, nil, []Finding{api, artifacts, wildcard}))
+
+	// A passing run of one rule clears only that rule's failures.
+	require.NoError(t, UpdateFailures(filename, []string{"iam.wildcard-action"}, nil, nil))
+	failures, err := LoadFailures(filename)
+	require.NoError(t, err)
+	require.Equal(t, []string{"logs.retention"}, failures.RuleIDs())
+	require.Equal(t, 2, failures.Resources())
+
+	// A focused run clears only the failures on the resources in focus.
+	require.NoError(t, UpdateFailures(filename, nil, Focus{"module.artifacts"}, nil))
+	failures, err = LoadFailures(filename)
+	require.NoError(t, err)
+	require.Equal(t, []Finding{api}, failures.Filter([]Finding{api, artifacts, wildcard}))
+
+	require.NoError(t, UpdateFailures(filename, nil, nil, nil))
+	failures, err = LoadFailures(filename)
+	require.NoError(t, err)
+	require.True(t, failures.Empty())
+}
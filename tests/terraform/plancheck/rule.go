@@ -0,0 +1,322 @@
+package plancheck
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Input is everything a rule may inspect when it is evaluated.
+type Input struct {
+	Plan *tfjson.Plan
+
+	// DefaultRegion is the region of resources whose provider region cannot be
+	// resolved from the plan.
+	DefaultRegion string
+
+	// Environment names the environment the plan belongs to, e.g. "dev".
+	Environment string
+	Config      *Config
+
+	// Peers holds the plans (or states) of the other environments, keyed by
+	// environment name, for rules that compare environments.
+	Peers map[string]*tfjson.Plan
+
+	// Backend is the state backend the planned root module declares, or nil
+	// when it keeps local state or the caller did not load it.
+	Backend *Backend
+
+	// Module is the parsed source of the planned root module, or nil when
+	// the caller did not load it.
+	Module *Module
+
+	// Runtime holds what was read from the deployed stack, or nil when the
+	// caller did not read it.
+	Runtime *Runtime
+
+	// AllowDestroy lists the addresses, or glob patterns of them, whose
+	// destruction this run allows, e.g. from COMPLIANCE_ALLOW_DESTROY.
+	AllowDestroy []string
+
+	// Focus, when set, keeps only the findings on resources it matches.
+	// Rules still read the whole plan, so references to resources outside
+	// the focus resolve as in a full run.
+	Focus Focus
+
+	// Baseline holds the findings accepted in the baseline file, for rules
+	// that review the waivers, or nil when the caller did not load it.
+	Baseline *Baseline
+
+	// Now is when the plan is judged, for canary periods and waiver expiry;
+	// zero means the current time.
+	Now time.Time
+
+	regions   *RegionIndex
+	resources map[string]*tfjson.ConfigResource
+	planned   map[string]*tfjson.StateResource
+}
+
+// Time returns when the plan is judged: Now, or the current time.
+func (in *Input) Time() time.Time {
+	if in.Now.IsZero() {
+		return time.Now()
+	}
+	return in.Now
+}
+
+// Settings returns the configuration of the input's environment.
+func (in *Input) Settings() Environment {
+	return in.Config.Environment(in.Environment)
+}
+
+// Regions returns the region index for the input's plan.
+func (in *Input) Regions() *RegionIndex {
+	if in.regions == nil {
+		in.regions = NewRegionIndex(in.Plan, in.DefaultRegion)
+	}
+	return in.regions
+}
+
+// resource returns the planned resource at address, or the data source read
+// while planning, or nil.
+func (in *Input) resource(address string) *tfjson.StateResource {
+	if in.planned == nil {
+		in.planned = map[string]*tfjson.StateResource{}
+		for _, resource := range PlannedResources(in.Plan) {
+			if resource != nil {
+				in.planned[resource.Address] = resource
+			}
+		}
+		if in.Plan != nil && in.Plan.PriorState != nil && in.Plan.PriorState.Values != nil {
+			var read []*tfjson.StateResource
+			collectModuleResources(in.Plan.PriorState.Values.RootModule, &read)
+			for _, resource := range read {
+				if resource != nil && resource.Mode == tfjson.DataResourceMode && in.planned[resource.Address] == nil {
+					in.planned[resource.Address] = resource
+				}
+			}
+		}
+	}
+	return in.planned[address]
+}
+
+// Rule is a single named compliance check over a plan.
+type Rule struct {
+	// ID is the stable identifier used in findings, reports and testdata
+	// directories, e.g. "iam.wildcard-action".
+	ID          string
+	Description string
+	// Remediation tells the resource owner how to fix a finding.
+	Remediation string
+
+	// Rationale and Example, when set, are printed by tfcompliance explain:
+	// why the rule exists, and a compliant terraform snippet.
+	Rationale string
+	Example   string
+
+	// Severity is given to the rule's findings; empty means they are errors.
+	Severity Severity
+
+	// Canary, a date such as "2026-11-30", rolls a new rule out in
+	// report-only mode: until that day its blocking findings are reported as
+	// advisories. canaries in compliance.yaml overrides it.
+	Canary string
+
+	// ResourceTypes lists the resource types the rule inspects, for the
+	// coverage report. Rules that apply to any resource, such as tagging,
+	// leave it empty and are not counted.
+	ResourceTypes []string
+
+	Check func(in *Input) []Finding
+
+	// CheckSource, when set, checks a single .tf file without a plan so the
+	// rule can run in the pre-commit hook. It only sees literal values.
+	CheckSource func(file *SourceFile) []Finding
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Rule{}
+)
+
+// Register adds a rule to the global registry. It panics on an empty or
+// duplicate ID so mistakes surface when the registering package loads.
+func Register(rule Rule) {
+	if err := register(rule); err != nil {
+		panic(err)
+	}
+}
+
+func register(rule Rule) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if rule.ID == "" || rule.Check == nil && rule.CheckSource == nil {
+		return fmt.Errorf("plancheck: rule must have an ID and a Check or CheckSource function")
+	}
+	if _, err := ParseCanary(rule.Canary); err != nil {
+		return fmt.Errorf("plancheck: rule %q: %w", rule.ID, err)
+	}
+	if _, exists := registry[rule.ID]; exists {
+		return fmt.Errorf("plancheck: rule %q registered twice", rule.ID)
+	}
+	registry[rule.ID] = rule
+	return nil
+}
+
+// Rules returns every registered rule ordered by ID.
+func Rules() []Rule {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	rules := make([]Rule, 0, len(registry))
+	for _, rule := range registry {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// LookupRule returns the registered rule with the given ID.
+func LookupRule(id string) (Rule, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	rule, ok := registry[id]
+	return rule, ok
+}
+
+// Evaluate runs the given rules, or every registered rule when none are
+// passed, and returns their combined findings on the resources in focus.
+// Findings with an attribute path get the plan fragment around it as
+// evidence.
+func Evaluate(in *Input, rules ...Rule) []Finding {
+	if len(rules) == 0 {
+		rules = Rules()
+	}
+
+	var findings []Finding
+	for _, rule := range rules {
+		if rule.Check == nil {
+			continue
+		}
+		for _, finding := range rule.Check(in) {
+			if !in.Focus.Matches(finding.Address) {
+				continue
+			}
+			if finding.RuleID == "" {
+				finding.RuleID = rule.ID
+			}
+			if finding.Severity == "" {
+				finding.Severity = rule.Severity
+			}
+			if severity, ok := in.Config.Severity(rule.ID); ok {
+				finding.Severity = severity
+			}
+			if until, ok := in.canary(rule); ok && finding.Severity.Blocking() {
+				finding.Severity, finding.Canary = SeverityAdvisory, until
+			}
+			if finding.Region == "" {
+				finding.Region = in.Regions().RegionOf(finding.Address)
+			}
+			findings = append(findings, finding)
+		}
+	}
+	attachEvidence(in, findings)
+	return findings
+}
+
+// EvaluateRegions evaluates one plan per region, for environments that plan
+// each region separately rather than in a single multi-provider plan. Every
+// region is judged as part of environment under config.
+func EvaluateRegions(environment string, config *Config, plans map[string]*tfjson.Plan, rules ...Rule) []Finding {
+	regions := make([]string, 0, len(plans))
+	for region := range plans {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	var findings []Finding
+	for _, region := range regions {
+		findings = append(findings, Evaluate(&Input{
+			Plan:          plans[region],
+			DefaultRegion: region,
+			Environment:   environment,
+			Config:        config,
+		}, rules...)...)
+	}
+	return findings
+}
+
+// PlannedResources returns every resource in the plan's planned values,
+// including those in nested modules.
+func PlannedResources(plan *tfjson.Plan) []*tfjson.StateResource {
+	if plan == nil || plan.PlannedValues == nil {
+		return nil
+	}
+
+	var resources []*tfjson.StateResource
+	collectModuleResources(plan.PlannedValues.RootModule, &resources)
+	return resources
+}
+
+// Resources returns the planned resources of the given types.
+func Resources(plan *tfjson.Plan, types ...string) []*tfjson.StateResource {
+	wanted := make(map[string]bool, len(types))
+	for _, t := range types {
+		wanted[t] = true
+	}
+
+	var resources []*tfjson.StateResource
+	for _, resource := range PlannedResources(plan) {
+		if resource != nil && wanted[resource.Type] {
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// DataSources returns the data sources of the given types. Terraform records
+// data sources read while planning in the plan's prior state, and those
+// deferred to apply, whose values are unknown, in its planned values.
+func DataSources(plan *tfjson.Plan, types ...string) []*tfjson.StateResource {
+	if plan == nil {
+		return nil
+	}
+	wanted := make(map[string]bool, len(types))
+	for _, t := range types {
+		wanted[t] = true
+	}
+
+	var candidates []*tfjson.StateResource
+	if plan.PriorState != nil && plan.PriorState.Values != nil {
+		collectModuleResources(plan.PriorState.Values.RootModule, &candidates)
+	}
+	candidates = append(candidates, PlannedResources(plan)...)
+
+	seen := map[string]bool{}
+	var resources []*tfjson.StateResource
+	for _, resource := range candidates {
+		if resource == nil || resource.Mode != tfjson.DataResourceMode || !wanted[resource.Type] || seen[resource.Address] {
+			continue
+		}
+		seen[resource.Address] = true
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+func collectModuleResources(module *tfjson.StateModule, acc *[]*tfjson.StateResource) {
+	if module == nil {
+		return
+	}
+
+	*acc = append(*acc, module.Resources...)
+
+	for _, child := range module.ChildModules {
+		collectModuleResources(child, acc)
+	}
+}
@@ -0,0 +1,210 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Runtime is what the live checks read from AWS about the deployed stack,
+// for rules that compare the plan with what happens at runtime.
+type Runtime struct {
+	// AccessFindings are the active IAM Access Analyzer findings of the
+	// account and region.
+	AccessFindings []AccessFinding `json:"access_findings,omitempty"`
+
+	// PolicyValidations are the findings of IAM Access Analyzer's
+	// ValidatePolicy on the plan's permission policy documents.
+	PolicyValidations []PolicyValidation `json:"policy_validations,omitempty"`
+
+	// ServiceAccess is the IAM Access Advisor report of each role, by role
+	// ARN: when the role last used each service its policies grant.
+	ServiceAccess map[string][]ServiceAccess `json:"service_access,omitempty"`
+
+	// Secrets are the stack's Secrets Manager secrets, by secret ARN.
+	Secrets map[string]SecretActivity `json:"secrets,omitempty"`
+
+	// Certificates are the ACM and IAM server certificates the stack manages
+	// or references, by ARN.
+	Certificates map[string]Certificate `json:"certificates,omitempty"`
+
+	// Imports are the objects the root module's import blocks name, by the
+	// address they are imported to. Resource types the live checks cannot
+	// look up are left out.
+	Imports map[string]ImportTarget `json:"imports,omitempty"`
+
+	// ReadAt is when the runtime data was read; ages such as "unused for
+	// 90 days" are measured from it.
+	ReadAt time.Time `json:"read_at,omitempty"`
+}
+
+// ServiceAccess is when a role last used one AWS service.
+type ServiceAccess struct {
+	// Namespace is the service's action prefix, e.g. "s3".
+	Namespace string `json:"namespace"`
+	Name      string `json:"name,omitempty"`
+
+	// LastAccessed is nil when the role has never used the service within
+	// Access Advisor's tracking period.
+	LastAccessed *time.Time `json:"last_accessed,omitempty"`
+}
+
+// AccessFinding is an IAM Access Analyzer finding: a principal outside the
+// zone of trust can act on a resource.
+type AccessFinding struct {
+	ID           string            `json:"id"`
+	Resource     string            `json:"resource"`
+	ResourceType string            `json:"resource_type"`
+	Status       string            `json:"status"`
+	IsPublic     bool              `json:"is_public,omitempty"`
+	Principal    map[string]string `json:"principal,omitempty"`
+	Action       []string          `json:"action,omitempty"`
+	Condition    map[string]string `json:"condition,omitempty"`
+}
+
+// PolicyValidation is a finding of IAM Access Analyzer's policy validation
+// on one planned policy document.
+type PolicyValidation struct {
+	// Address and Path locate the document, as in PolicyDocument.
+	Address string `json:"address"`
+	Path    string `json:"path"`
+	// Type is ERROR, SECURITY_WARNING, WARNING or SUGGESTION.
+	Type      string `json:"type"`
+	IssueCode string `json:"issue_code"`
+	Details   string `json:"details"`
+	// Location is the path of the offending element within the document,
+	// e.g. "Statement[0].Condition", when Access Analyzer gives one.
+	Location      string `json:"location,omitempty"`
+	LearnMoreLink string `json:"learn_more_link,omitempty"`
+}
+
+// SecretActivity is when a secret was last rotated and who has read it.
+type SecretActivity struct {
+	Name      string     `json:"name"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	// LastRotated is nil when the secret has never been rotated.
+	LastRotated *time.Time `json:"last_rotated,omitempty"`
+	// Readers are the principals CloudTrail recorded reading the secret's
+	// value, over the event history it keeps (90 days).
+	Readers []SecretReader `json:"readers,omitempty"`
+}
+
+// SecretReader is a principal that read a secret's value.
+type SecretReader struct {
+	// Principal is the caller's ARN as CloudTrail records it, e.g.
+	// arn:aws:sts::123456789012:assumed-role/pkg-api/session.
+	Principal string    `json:"principal"`
+	Reads     int       `json:"reads"`
+	LastRead  time.Time `json:"last_read"`
+}
+
+// Certificate is a TLS certificate's validity.
+type Certificate struct {
+	Domain string `json:"domain,omitempty"`
+	// Type is the ACM certificate type, AMAZON_ISSUED, IMPORTED or PRIVATE,
+	// or IAM for an IAM server certificate.
+	Type     string    `json:"type"`
+	Status   string    `json:"status,omitempty"`
+	NotAfter time.Time `json:"not_after"`
+}
+
+// ImportTarget is whether the object an import block names exists.
+type ImportTarget struct {
+	ID     string `json:"id"`
+	Exists bool   `json:"exists"`
+}
+
+// LoadRuntime reads runtime data saved as JSON.
+func LoadRuntime(filename string) (*Runtime, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var runtime Runtime
+	if err := json.Unmarshal(data, &runtime); err != nil {
+		return nil, err
+	}
+	return &runtime, nil
+}
+
+// ResourceARNs maps the ARNs of the plan's resources, as far as the plan
+// knows them, to their addresses. S3 bucket ARNs are derived from the bucket
+// name, which is known before the bucket exists.
+func ResourceARNs(in *Input) map[string]string {
+	arns := map[string]string{}
+	for _, resource := range PlannedResources(in.Plan) {
+		if resource == nil {
+			continue
+		}
+		if arn := LookupString(resource.AttributeValues, "arn"); arn != "" {
+			arns[arn] = resource.Address
+		}
+		if resource.Type == "aws_s3_bucket" {
+			if bucket := LookupString(resource.AttributeValues, "bucket"); bucket != "" {
+				arns["arn:aws:s3:::"+bucket] = resource.Address
+			}
+		}
+	}
+	return arns
+}
+
+// CertificateReference is a certificate ARN held by a planned resource.
+type CertificateReference struct {
+	Address string
+	// Path is the attribute holding the ARN, e.g.
+	// "viewer_certificate[0].acm_certificate_arn".
+	Path string
+	ARN  string
+}
+
+// CertificateReferences returns the certificate ARNs the plan knows: the arn
+// of every aws_acm_certificate and aws_iam_server_certificate, and every
+// attribute named like certificate_arn, such as a load balancer listener's
+// certificate_arn or a CloudFront distribution's acm_certificate_arn.
+func CertificateReferences(plan *tfjson.Plan) []CertificateReference {
+	var refs []CertificateReference
+	for _, resource := range PlannedResources(plan) {
+		if resource == nil || resource.Mode == tfjson.DataResourceMode {
+			continue
+		}
+		if resource.Type == "aws_acm_certificate" || resource.Type == "aws_iam_server_certificate" {
+			if arn := LookupString(resource.AttributeValues, "arn"); arn != "" {
+				refs = append(refs, CertificateReference{resource.Address, "arn", arn})
+			}
+			continue
+		}
+		collectCertificateARNs(resource.Address, "", resource.AttributeValues, &refs)
+	}
+	return refs
+}
+
+func collectCertificateARNs(address, path string, value interface{}, refs *[]CertificateReference) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			if arn, ok := v[key].(string); ok && arn != "" && strings.HasSuffix(key, "certificate_arn") {
+				*refs = append(*refs, CertificateReference{address, child, arn})
+				continue
+			}
+			collectCertificateARNs(address, child, v[key], refs)
+		}
+	case []interface{}:
+		for i, item := range v {
+			collectCertificateARNs(address, fmt.Sprintf("%s[%d]", path, i), item, refs)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+)
+
+// SARIF 2.1.0, as much of it as GitHub code scanning reads.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool           `json:"tool"`
+	Results    []sarifResult       `json:"results"`
+	Properties *sarifRunProperties `json:"properties,omitempty"`
+}
+
+// sarifRunProperties is the run's property bag.
+type sarifRunProperties struct {
+	// Identities maps each environment to the AWS principal it ran as.
+	Identities map[string]string `json:"awsIdentities"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name string `json:"name"`
+	// SemanticVersion is the policy pack version, when one is set.
+	SemanticVersion string      `json:"semanticVersion,omitempty"`
+	Rules           []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	Help                 *sarifMessage      `json:"help,omitempty"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifLevel maps a severity to a SARIF result level.
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case SeverityAdvisory:
+		return "warning"
+	case SeverityInfo:
+		return "note"
+	}
+	return "error"
+}
+
+// WriteSARIF writes findings as a SARIF 2.1.0 log for GitHub code scanning.
+// Source files are given relative to root, the repository checkout, so the
+// results annotate the .tf lines that declare the offending values. Rules
+// are described from the registry; findings of unregistered rules still
+// carry their ID. identities, mapping each environment to the AWS principal
+// it ran as, go in the run's awsIdentities property.
+func WriteSARIF(w io.Writer, findings []Finding, root string, identities map[string]string) error {
+	ruleIDs := map[string]Severity{}
+	results := make([]sarifResult, 0, len(findings))
+	for _, finding := range findings {
+		ruleIDs[finding.RuleID] = finding.Severity
+		location := sarifLocation{LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: finding.Key(), Kind: "resource"}}}
+		if finding.Source != nil && finding.Source.File != "" {
+			location.PhysicalLocation = &sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: sarifURI(finding.Source.File, root), URIBaseID: "%SRCROOT%"},
+			}
+			if finding.Source.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: finding.Source.Line}
+			}
+		}
+		results = append(results, sarifResult{
+			RuleID:    finding.RuleID,
+			Level:     sarifLevel(finding.Severity),
+			Message:   sarifMessage{Text: finding.Message},
+			Locations: []sarifLocation{location},
+			// Code scanning tracks a result across commits by its
+			// fingerprint, which must not change when lines move.
+			PartialFingerprints: map[string]string{"findingKey/v1": finding.RuleID + "|" + finding.Key() + "|" + finding.Path},
+		})
+	}
+
+	ids := make([]string, 0, len(ruleIDs))
+	for id := range ruleIDs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	rules := make([]sarifRule, 0, len(ids))
+	for _, id := range ids {
+		rule := sarifRule{ID: id, ShortDescription: sarifMessage{Text: id}, DefaultConfiguration: sarifConfiguration{Level: sarifLevel(ruleIDs[id])}}
+		if registered, ok := LookupRule(id); ok {
+			if registered.Description != "" {
+				rule.ShortDescription.Text = registered.Description
+			}
+			if registered.Remediation != "" {
+				rule.Help = &sarifMessage{Text: registered.Remediation}
+			}
+		}
+		rules = append(rules, rule)
+	}
+
+	run := sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: "tfcompliance", SemanticVersion: PackVersion(), Rules: rules}}, Results: results}
+	if len(identities) > 0 {
+		run.Properties = &sarifRunProperties{Identities: identities}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifURI returns file relative to root with forward slashes, or file
+// unchanged when it cannot be made relative.
+func sarifURI(file, root string) string {
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	rel, err := filepath.Rel(absRoot, absFile)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,134 @@
+package plancheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var reportFindings = []Finding{
+	{
+		RuleID:  "test.report-blocking",
+		Address: "module.iam.aws_iam_policy.ci",
+		Region:  "us-east-1",
+		Path:    "policy.Statement[0].Action",
+		Message: "IAM policy grants every action",
+		Owner:   "@security",
+		Source:  &SourceLocation{File: "../../infra/modules/iam/main.tf", Line: 12},
+	},
+	{RuleID: "test.report-advisory", Address: "aws_ebs_volume.data", Message: "gp2 volume", Severity: SeverityAdvisory},
+}
+
+func init() {
+	Register(Rule{ID: "test.report-blocking", Description: "Policies must not grant every action.", Remediation: "List the actions.", Check: func(*Input) []Finding { return nil }})
+}
+
+func TestWriteSARIF(t *testing.T) {
+	root := t.TempDir()
+	work := filepath.Join(root, "tests", "terraform")
+	findings := append([]Finding(nil), reportFindings...)
+	findings[0].Source = &SourceLocation{File: filepath.Join(work, "../../infra/modules/iam/main.tf"), Line: 12}
+
+	var out bytes.Buffer
+	require.NoError(t, WriteSARIF(&out, findings, root, map[string]string{"dev": "arn:aws:sts::111111111111:assumed-role/tfcompliance-plan/tfcompliance-dev (account 111111111111)"}))
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID                   string            `json:"id"`
+						ShortDescription     map[string]string `json:"shortDescription"`
+						Help                 map[string]string `json:"help"`
+						DefaultConfiguration map[string]string `json:"defaultConfiguration"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results    []map[string]interface{} `json:"results"`
+			Properties map[string]interface{}   `json:"properties"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &log))
+	require.Equal(t, "2.1.0", log.Version)
+	run := log.Runs[0]
+
+	rules := run.Tool.Driver.Rules
+	require.Len(t, rules, 2)
+	require.Equal(t, "test.report-advisory", rules[0].ID)
+	require.Equal(t, "test.report-advisory", rules[0].ShortDescription["text"], "unregistered rules are described by ID")
+	require.Equal(t, "warning", rules[0].DefaultConfiguration["level"])
+	require.Equal(t, "Policies must not grant every action.", rules[1].ShortDescription["text"])
+	require.Equal(t, "List the actions.", rules[1].Help["text"])
+
+	require.Len(t, run.Results, 2)
+	first, _ := json.Marshal(run.Results[0])
+	require.JSONEq(t, `{
+	  "ruleId": "test.report-blocking",
+	  "level": "error",
+	  "message": {"text": "IAM policy grants every action"},
+	  "locations": [{
+	    "physicalLocation": {"artifactLocation": {"uri": "infra/modules/iam/main.tf", "uriBaseId": "%SRCROOT%"}, "region": {"startLine": 12}},
+	    "logicalLocations": [{"fullyQualifiedName": "us-east-1/module.iam.aws_iam_policy.ci", "kind": "resource"}]
+	  }],
+	  "partialFingerprints": {"findingKey/v1": "test.report-blocking|us-east-1/module.iam.aws_iam_policy.ci|policy.Statement[0].Action"}
+	}`, string(first))
+	require.NotContains(t, run.Results[1]["locations"].([]interface{})[0], "physicalLocation")
+	require.Equal(t, map[string]interface{}{
+		"dev": "arn:aws:sts::111111111111:assumed-role/tfcompliance-plan/tfcompliance-dev (account 111111111111)",
+	}, run.Properties["awsIdentities"])
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, WriteJUnit(&out, []ReportSuite{
+		{Name: "TestIAMPoliciesDoNotUseWildcards/dev", Findings: reportFindings},
+		{Name: "TestLogsAreRetained/dev"},
+	}, map[string]string{"prod": "arn:aws:sts::222222222222:assumed-role/tfcompliance/ci (account 222222222222)", "dev": "arn:aws:sts::111111111111:assumed-role/tfcompliance/ci (account 111111111111)"}))
+
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(out.Bytes(), &report))
+	require.Equal(t, 3, report.Tests)
+	require.Equal(t, 1, report.Failures)
+
+	iam := report.Suites[0]
+	require.Equal(t, 2, iam.Tests)
+	require.Equal(t, "us-east-1/module.iam.aws_iam_policy.ci policy.Statement[0].Action", iam.Cases[0].Name)
+	require.Equal(t, "test.report-blocking", iam.Cases[0].Failure.Type)
+	require.Contains(t, iam.Cases[0].Failure.Text, "(owner: @security)")
+	require.Nil(t, iam.Cases[1].Failure)
+	require.Equal(t, "[test.report-advisory, advisory] gp2 volume\nat aws_ebs_volume.data", iam.Cases[1].SystemOut)
+
+	require.Equal(t, []junitTestCase{{ClassName: "TestLogsAreRetained/dev", Name: "no findings"}}, report.Suites[1].Cases)
+	require.Equal(t, []junitProperty{
+		{Name: "aws_identity.dev", Value: "arn:aws:sts::111111111111:assumed-role/tfcompliance/ci (account 111111111111)"},
+		{Name: "aws_identity.prod", Value: "arn:aws:sts::222222222222:assumed-role/tfcompliance/ci (account 222222222222)"},
+	}, iam.Properties[len(iam.Properties)-2:])
+}
+
+func TestCollectorWritesRequestedFiles(t *testing.T) {
+	var collector Collector
+	collector.Add("TestB", reportFindings[1:])
+	collector.Add("TestA", nil)
+	collector.Add("TestB", reportFindings[:1])
+	collector.Identify("dev", "arn:aws:iam::111111111111:user/ci (account 111111111111)")
+	suites := collector.Suites()
+	require.Equal(t, "TestA", suites[0].Name)
+	require.Len(t, suites[1].Findings, 2)
+
+	dir := t.TempDir()
+	junit := filepath.Join(dir, "reports", "junit.xml")
+	written, err := collector.WriteFiles("", junit, dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{junit}, written)
+	data, err := os.ReadFile(junit)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `<property name="aws_identity.dev" value="arn:aws:iam::111111111111:user/ci (account 111111111111)"></property>`)
+	_, err = os.Stat(filepath.Join(dir, "compliance.sarif"))
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,205 @@
+package plancheck
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// MovedBlock is a moved block of the configuration. From and To are
+// addresses relative to the module declaring the block.
+type MovedBlock struct {
+	From string
+	To   string
+}
+
+// LoadMovedBlocks reads the moved blocks of every .tf file under dir, such as
+// infra, so those of the root modules and of the modules they call are found.
+func LoadMovedBlocks(dir string) ([]MovedBlock, error) {
+	var blocks []MovedBlock
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return err
+		}
+		if entry.Name() == ".terraform" {
+			return filepath.SkipDir
+		}
+		module, err := LoadModule(path)
+		if err != nil || module == nil {
+			return err
+		}
+		for _, file := range module.Files {
+			for _, block := range file.Body.Blocks {
+				if block.Type != "moved" {
+					continue
+				}
+				moved, err := movedBlock(block)
+				if err != nil {
+					return fmt.Errorf("%s:%d: %w", file.Name, block.DefRange().Start.Line, err)
+				}
+				blocks = append(blocks, moved)
+			}
+		}
+		return nil
+	})
+	return blocks, err
+}
+
+func movedBlock(block *hclsyntax.Block) (MovedBlock, error) {
+	var addresses [2]string
+	for i, name := range []string{"from", "to"} {
+		attr, ok := block.Body.Attributes[name]
+		if !ok {
+			return MovedBlock{}, fmt.Errorf("moved block has no %s", name)
+		}
+		traversal, diags := hcl.AbsTraversalForExpr(attr.Expr)
+		if diags.HasErrors() {
+			return MovedBlock{}, diags
+		}
+		addresses[i] = traversalAddress(traversal)
+	}
+	return MovedBlock{From: addresses[0], To: addresses[1]}, nil
+}
+
+// traversalAddress renders a traversal as terraform writes addresses, e.g.
+// module.s3.aws_s3_bucket.this["logs"].
+func traversalAddress(traversal hcl.Traversal) string {
+	var b strings.Builder
+	for _, step := range traversal {
+		switch step := step.(type) {
+		case hcl.TraverseRoot:
+			b.WriteString(step.Name)
+		case hcl.TraverseAttr:
+			b.WriteString("." + step.Name)
+		case hcl.TraverseIndex:
+			if step.Key.Type() == cty.String {
+				fmt.Fprintf(&b, "[%q]", step.Key.AsString())
+			} else {
+				fmt.Fprintf(&b, "[%s]", step.Key.AsBigFloat().String())
+			}
+		}
+	}
+	return b.String()
+}
+
+// declares reports whether the block moves the resource at from to to,
+// itself or as part of a module or of all instances of a resource, when
+// declared in the module at the same prefix of both addresses.
+func (m MovedBlock) declares(from, to string) bool {
+	for start := 0; start < len(from); {
+		i := strings.Index(from[start:], m.From)
+		if i < 0 {
+			return false
+		}
+		i += start
+		prefix, rest := from[:i], from[i+len(m.From):]
+		aligned := (prefix == "" || strings.HasSuffix(prefix, ".")) &&
+			(rest == "" || strings.HasPrefix(rest, ".") || strings.HasPrefix(rest, "["))
+		if aligned && to == prefix+m.To+rest {
+			return true
+		}
+		start = i + 1
+	}
+	return false
+}
+
+// Move is a resource that a refactor gave a new address without changing its
+// planned values. Declared is set when a moved block covers it, so terraform
+// moves the deployed resource rather than replacing it.
+type Move struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Declared bool   `json:"declared"`
+}
+
+// String renders the move as one line, e.g.
+// "aws_s3_bucket.logs -> module.logs.aws_s3_bucket.this (moved block)".
+func (m Move) String() string {
+	if m.Declared {
+		return fmt.Sprintf("%s -> %s (moved block)", m.From, m.To)
+	}
+	return fmt.Sprintf("%s -> %s (no moved block: terraform would replace it)", m.From, m.To)
+}
+
+// ShadowDiff is how the plan of a refactored configuration differs from the
+// plan of the configuration it refactors.
+type ShadowDiff struct {
+	Changes []ValueChange `json:"changes"`
+	Moves   []Move        `json:"moves"`
+}
+
+// Identical reports whether the refactor changes nothing: no planned value
+// differs and every resource that moved is covered by a moved block.
+func (d ShadowDiff) Identical() bool {
+	if len(d.Changes) > 0 {
+		return false
+	}
+	for _, move := range d.Moves {
+		if !move.Declared {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffShadow compares snapshots of a base and a refactored configuration. A
+// resource that disappears from base and appears in current with the same
+// type and values is reported as a move rather than as a removal and an
+// addition.
+func DiffShadow(base, current Snapshot, moved []MovedBlock) ShadowDiff {
+	changes := DiffSnapshots(base, current)
+	resources := func(s Snapshot) map[string]SnapshotResource {
+		byAddress := map[string]SnapshotResource{}
+		for _, resource := range s.Resources {
+			byAddress[resource.Address] = resource
+		}
+		return byAddress
+	}
+	before, after := resources(base), resources(current)
+
+	var removed, added []string
+	for _, change := range changes {
+		switch {
+		case change.Path != "":
+		case change.After == nil:
+			removed = append(removed, change.Address)
+		case change.Before == nil:
+			added = append(added, change.Address)
+		}
+	}
+
+	diff := ShadowDiff{Changes: []ValueChange{}, Moves: []Move{}}
+	matched := map[string]bool{}
+	for _, from := range removed {
+		for _, to := range added {
+			if matched[to] || before[from].Type != after[to].Type || !reflect.DeepEqual(before[from].Values, after[to].Values) {
+				continue
+			}
+			matched[from], matched[to] = true, true
+			move := Move{From: from, To: to}
+			for _, block := range moved {
+				if block.declares(from, to) {
+					move.Declared = true
+					break
+				}
+			}
+			diff.Moves = append(diff.Moves, move)
+			break
+		}
+	}
+	for _, change := range changes {
+		if change.Path == "" && matched[change.Address] {
+			continue
+		}
+		diff.Changes = append(diff.Changes, change)
+	}
+	sort.Slice(diff.Moves, func(i, j int) bool { return diff.Moves[i].From < diff.Moves[j].From })
+	return diff
+}
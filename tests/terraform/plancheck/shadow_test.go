@@ -0,0 +1,91 @@
+package plancheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMovedBlocksReadsEveryModule(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"envs/dev/main.tf": `moved {
+  from = aws_s3_bucket.logs
+  to   = module.logs.aws_s3_bucket.this
+}
+`,
+		"modules/ddb/main.tf": `moved {
+  from = aws_dynamodb_table.table["users"]
+  to   = aws_dynamodb_table.users
+}
+`,
+		"envs/dev/.terraform/modules/x/main.tf": `moved {
+  from = aws_s3_bucket.a
+  to   = aws_s3_bucket.b
+}
+`,
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	blocks, err := LoadMovedBlocks(dir)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []MovedBlock{
+		{From: "aws_s3_bucket.logs", To: "module.logs.aws_s3_bucket.this"},
+		{From: `aws_dynamodb_table.table["users"]`, To: "aws_dynamodb_table.users"},
+	}, blocks)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "envs", "dev", "bad.tf"), []byte("moved {\n  from = aws_s3_bucket.a\n}\n"), 0o644))
+	_, err = LoadMovedBlocks(dir)
+	require.ErrorContains(t, err, "moved block has no to")
+}
+
+func TestDiffShadowReportsMovesAndChanges(t *testing.T) {
+	bucket := map[string]interface{}{"bucket": "pkg-logs"}
+	base := Snapshot{Resources: []SnapshotResource{
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Values: bucket},
+		{Address: "module.ddb.aws_dynamodb_table.users", Type: "aws_dynamodb_table", Values: map[string]interface{}{"name": "users"}},
+		{Address: "module.ddb.aws_dynamodb_table.table[\"tokens\"]", Type: "aws_dynamodb_table", Values: map[string]interface{}{"name": "tokens"}},
+	}}
+	current := Snapshot{Resources: []SnapshotResource{
+		{Address: "module.logs.aws_s3_bucket.this", Type: "aws_s3_bucket", Values: bucket},
+		{Address: "module.ddb.aws_dynamodb_table.users", Type: "aws_dynamodb_table", Values: map[string]interface{}{"name": "users"}},
+		{Address: "module.ddb.aws_dynamodb_table.tokens", Type: "aws_dynamodb_table", Values: map[string]interface{}{"name": "tokens"}},
+	}}
+
+	diff := DiffShadow(base, current, []MovedBlock{{From: "aws_s3_bucket.logs", To: "module.logs.aws_s3_bucket.this"}})
+	require.Empty(t, diff.Changes)
+	require.Equal(t, []Move{
+		{From: "aws_s3_bucket.logs", To: "module.logs.aws_s3_bucket.this", Declared: true},
+		{From: "module.ddb.aws_dynamodb_table.table[\"tokens\"]", To: "module.ddb.aws_dynamodb_table.tokens"},
+	}, diff.Moves)
+	require.False(t, diff.Identical(), "a move without a moved block replaces the resource")
+	require.Equal(t, `module.ddb.aws_dynamodb_table.table["tokens"] -> module.ddb.aws_dynamodb_table.tokens (no moved block: terraform would replace it)`, diff.Moves[1].String())
+
+	// A moved block in the ddb module, relative to it, covers the move.
+	diff = DiffShadow(base, current, []MovedBlock{
+		{From: "aws_s3_bucket.logs", To: "module.logs.aws_s3_bucket.this"},
+		{From: "aws_dynamodb_table.table[\"tokens\"]", To: "aws_dynamodb_table.tokens"},
+	})
+	require.True(t, diff.Identical())
+
+	// A module moved whole covers the resources in it.
+	require.True(t, MovedBlock{From: "module.ddb", To: "module.tables"}.declares("module.ddb.aws_dynamodb_table.users", "module.tables.aws_dynamodb_table.users"))
+	require.False(t, MovedBlock{From: "module.ddb", To: "module.tables"}.declares("module.ddb2.aws_dynamodb_table.users", "module.tables2.aws_dynamodb_table.users"))
+
+	current.Resources[1].Values = map[string]interface{}{"name": "users-v2"}
+	diff = DiffShadow(base, current, nil)
+	require.False(t, diff.Identical())
+	require.Equal(t, []string{`~ module.ddb.aws_dynamodb_table.users name: "users" -> "users-v2"`}, changeStrings(diff.Changes))
+}
+
+func changeStrings(changes []ValueChange) []string {
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	return lines
+}
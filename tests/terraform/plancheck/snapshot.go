@@ -0,0 +1,157 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Snapshot is a normalized view of the resources a plan creates or keeps, to
+// commit as a golden file: any later plan that differs from it changes the
+// infrastructure, whether or not a rule cares about the change.
+type Snapshot struct {
+	Environment string             `json:"environment"`
+	Resources   []SnapshotResource `json:"resources"`
+}
+
+// SnapshotResource is one managed resource of a snapshot. Values holds its
+// planned attribute values without nulls, empty strings, lists and maps, or
+// the attributes snapshot.ignore names.
+type SnapshotResource struct {
+	Address string                 `json:"address"`
+	Type    string                 `json:"type"`
+	Name    string                 `json:"name"`
+	Values  map[string]interface{} `json:"values"`
+}
+
+// NewSnapshot takes the snapshot of plan, sorted by address. Values unknown
+// until apply are absent from planned values and so from the snapshot.
+func NewSnapshot(environment string, plan *tfjson.Plan, policy SnapshotPolicy) Snapshot {
+	s := Snapshot{Environment: environment, Resources: []SnapshotResource{}}
+	for _, resource := range PlannedResources(plan) {
+		if resource.Mode != tfjson.ManagedResourceMode {
+			continue
+		}
+		values, _ := policy.normalize(resource.Type, "", resource.AttributeValues).(map[string]interface{})
+		if values == nil {
+			values = map[string]interface{}{}
+		}
+		s.Resources = append(s.Resources, SnapshotResource{
+			Address: resource.Address,
+			Type:    resource.Type,
+			Name:    resource.Name,
+			Values:  values,
+		})
+	}
+	sort.Slice(s.Resources, func(i, j int) bool { return s.Resources[i].Address < s.Resources[j].Address })
+	return s
+}
+
+// normalize returns value without the parts a snapshot leaves out, or nil
+// when nothing is left.
+func (p SnapshotPolicy) normalize(resourceType, at string, value interface{}) interface{} {
+	join := func(key string) string {
+		if at == "" {
+			return key
+		}
+		return at + "." + key
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for key, item := range v {
+			if p.ignored(resourceType, join(key)) {
+				continue
+			}
+			if item = p.normalize(resourceType, join(key), item); item != nil {
+				out[key] = item
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case []interface{}:
+		// Elements keep their positions, so an emptied element stays as
+		// an empty map.
+		if len(v) == 0 {
+			return nil
+		}
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = p.normalize(resourceType, join(strconv.Itoa(i)), item)
+			if out[i] == nil {
+				if _, ok := item.(map[string]interface{}); ok {
+					out[i] = map[string]interface{}{}
+				}
+			}
+		}
+		return out
+	case string:
+		if v == "" {
+			return nil
+		}
+	}
+	return value
+}
+
+// ignored reports whether snapshot.ignore names the attribute at path of a
+// resource of resourceType.
+func (p SnapshotPolicy) ignored(resourceType, at string) bool {
+	for _, pattern := range p.Ignore {
+		typePattern, attribute, _ := strings.Cut(pattern, ".")
+		if matched, _ := path.Match(typePattern, resourceType); !matched {
+			continue
+		}
+		if matched, _ := path.Match(attribute, at); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffSnapshots reports every value of golden that differs in current, in the
+// form DiffPlannedValues uses.
+func DiffSnapshots(golden, current Snapshot) []ValueChange {
+	index := func(s Snapshot) map[string]map[string]interface{} {
+		values := map[string]map[string]interface{}{}
+		for _, resource := range s.Resources {
+			values[resource.Address] = resource.Values
+		}
+		return values
+	}
+	return diffResources(index(golden), index(current))
+}
+
+// WriteSnapshot writes s to filename as indented JSON.
+func WriteSnapshot(filename string, s Snapshot) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// ReadSnapshot loads a snapshot written by WriteSnapshot.
+func ReadSnapshot(filename string) (Snapshot, error) {
+	var s Snapshot
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", filename, err)
+	}
+	return s, nil
+}
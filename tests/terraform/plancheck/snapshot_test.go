@@ -0,0 +1,81 @@
+package plancheck
+
+import (
+	"path/filepath"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSnapshotNormalizesValues(t *testing.T) {
+	plan := plannedPlan(
+		&tfjson.StateResource{Address: "aws_s3_bucket.logs", Mode: tfjson.ManagedResourceMode, Type: "aws_s3_bucket", Name: "logs", AttributeValues: map[string]interface{}{
+			"bucket":        "pkg-logs",
+			"bucket_prefix": "",
+			"force_destroy": false,
+			"policy":        nil,
+			"grant":         []interface{}{},
+			"tags":          map[string]interface{}{"Team": "core"},
+			"tags_all":      map[string]interface{}{"Team": "core", "Project": "cs450"},
+		}},
+		&tfjson.StateResource{Address: "aws_lambda_function.api", Mode: tfjson.ManagedResourceMode, Type: "aws_lambda_function", Name: "api", AttributeValues: map[string]interface{}{
+			"function_name":    "api",
+			"source_code_hash": "abc=",
+			"environment":      []interface{}{map[string]interface{}{"variables": map[string]interface{}{}}},
+		}},
+		&tfjson.StateResource{Address: "data.aws_caller_identity.current", Mode: tfjson.DataResourceMode, Type: "aws_caller_identity", Name: "current"},
+	)
+
+	snapshot := NewSnapshot("dev", plan, SnapshotPolicy{Ignore: []string{"*.tags_all", "aws_lambda_function.source_code_hash"}})
+	require.Equal(t, Snapshot{Environment: "dev", Resources: []SnapshotResource{
+		{Address: "aws_lambda_function.api", Type: "aws_lambda_function", Name: "api", Values: map[string]interface{}{
+			"function_name": "api",
+			"environment":   []interface{}{map[string]interface{}{}},
+		}},
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Name: "logs", Values: map[string]interface{}{
+			"bucket":        "pkg-logs",
+			"force_destroy": false,
+			"tags":          map[string]interface{}{"Team": "core"},
+		}},
+	}}, snapshot)
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	golden := Snapshot{Environment: "dev", Resources: []SnapshotResource{
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Name: "logs", Values: map[string]interface{}{"bucket": "pkg-logs", "force_destroy": false}},
+		{Address: "aws_sqs_queue.jobs", Type: "aws_sqs_queue", Name: "jobs", Values: map[string]interface{}{"name": "jobs"}},
+	}}
+	current := Snapshot{Environment: "dev", Resources: []SnapshotResource{
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Name: "logs", Values: map[string]interface{}{"bucket": "pkg-logs", "force_destroy": true}},
+		{Address: "aws_sns_topic.events", Type: "aws_sns_topic", Name: "events", Values: map[string]interface{}{"name": "events"}},
+	}}
+
+	changes := DiffSnapshots(golden, current)
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	require.Equal(t, []string{
+		"~ aws_s3_bucket.logs force_destroy: false -> true",
+		"+ aws_sns_topic.events",
+		"- aws_sqs_queue.jobs",
+	}, lines)
+
+	require.Empty(t, DiffSnapshots(golden, golden))
+}
+
+func TestSnapshotRoundTrips(t *testing.T) {
+	plan := plannedPlan(&tfjson.StateResource{Address: "aws_sqs_queue.jobs", Mode: tfjson.ManagedResourceMode, Type: "aws_sqs_queue", Name: "jobs", AttributeValues: map[string]interface{}{
+		"name":                       "jobs",
+		"visibility_timeout_seconds": float64(30),
+	}})
+	snapshot := NewSnapshot("dev", plan, SnapshotPolicy{})
+
+	filename := filepath.Join(t.TempDir(), "snapshots", "dev.json")
+	require.NoError(t, WriteSnapshot(filename, snapshot))
+	read, err := ReadSnapshot(filename)
+	require.NoError(t, err)
+	require.Equal(t, snapshot, read)
+	require.Empty(t, DiffSnapshots(read, snapshot))
+}
@@ -0,0 +1,193 @@
+package plancheck
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// SourceIndex resolves resource addresses and attribute paths to positions in
+// the .tf files of a configuration. Module directories come from the plan's
+// configuration when it is present and from the module blocks otherwise.
+type SourceIndex struct {
+	rootDir string
+	config  *tfjson.Config
+	parser  *hclparse.Parser
+	modules map[string]*moduleSource
+}
+
+type moduleSource struct {
+	dir    string
+	config *tfjson.ConfigModule
+	blocks map[string]*hclsyntax.Block
+	calls  map[string]string
+}
+
+// NewSourceIndex indexes the configuration rooted at rootDir. plan may be nil.
+func NewSourceIndex(plan *tfjson.Plan, rootDir string) *SourceIndex {
+	index := &SourceIndex{
+		rootDir: filepath.Clean(rootDir),
+		parser:  hclparse.NewParser(),
+		modules: map[string]*moduleSource{},
+	}
+	if plan != nil {
+		index.config = plan.Config
+	}
+	return index
+}
+
+// Annotate fills in the Source field of findings that can be located.
+func (s *SourceIndex) Annotate(findings []Finding) {
+	for i := range findings {
+		if findings[i].Source == nil {
+			findings[i].Source = s.Locate(findings[i].Address, findings[i].Path)
+		}
+	}
+}
+
+// Locate returns the position of the attribute at path within the resource
+// at address, falling back to the resource block itself. It returns nil when
+// the resource's configuration cannot be found on disk.
+func (s *SourceIndex) Locate(address, path string) *SourceLocation {
+	modulePath := ModulePath(address)
+	module := s.module(modulePath)
+	if module == nil {
+		return nil
+	}
+
+	resource := strings.TrimPrefix(strings.TrimPrefix(address, modulePath), ".")
+	block, ok := module.blocks[blockKey(resource)]
+	if !ok {
+		return nil
+	}
+
+	rng := block.DefRange()
+	if name := rootAttribute(path); name != "" {
+		if attr, ok := block.Body.Attributes[name]; ok {
+			rng = attr.SrcRange
+		} else {
+			for _, nested := range block.Body.Blocks {
+				if nested.Type == name {
+					rng = nested.DefRange()
+					break
+				}
+			}
+		}
+	}
+	return &SourceLocation{File: rng.Filename, Line: rng.Start.Line}
+}
+
+// ResourceFile returns the .tf file that declares the resource at address.
+func (s *SourceIndex) ResourceFile(address string) (string, bool) {
+	modulePath := ModulePath(address)
+	module := s.module(modulePath)
+	if module == nil {
+		return "", false
+	}
+	block, ok := module.blocks[blockKey(strings.TrimPrefix(strings.TrimPrefix(address, modulePath), "."))]
+	if !ok {
+		return "", false
+	}
+	return block.DefRange().Filename, true
+}
+
+func (s *SourceIndex) module(path string) *moduleSource {
+	if module, ok := s.modules[path]; ok {
+		return module
+	}
+
+	var module *moduleSource
+	if path == "" {
+		var config *tfjson.ConfigModule
+		if s.config != nil {
+			config = s.config.RootModule
+		}
+		module = s.load(s.rootDir, config)
+	} else {
+		parentPath, name := splitModulePath(path)
+		if parent := s.module(parentPath); parent != nil {
+			source := parent.calls[name]
+			var config *tfjson.ConfigModule
+			if parent.config != nil {
+				if call, ok := parent.config.ModuleCalls[name]; ok {
+					source = call.Source
+					config = call.Module
+				}
+			}
+			if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+				module = s.load(filepath.Join(parent.dir, source), config)
+			}
+		}
+	}
+
+	s.modules[path] = module
+	return module
+}
+
+func (s *SourceIndex) load(dir string, config *tfjson.ConfigModule) *moduleSource {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil || len(files) == 0 {
+		return nil
+	}
+
+	module := &moduleSource{
+		dir:    dir,
+		config: config,
+		blocks: map[string]*hclsyntax.Block{},
+		calls:  map[string]string{},
+	}
+	for _, filename := range files {
+		file, diags := s.parser.ParseHCLFile(filename)
+		if diags.HasErrors() || file == nil {
+			continue
+		}
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, block := range body.Blocks {
+			switch {
+			case block.Type == "resource" && len(block.Labels) == 2:
+				module.blocks[block.Labels[0]+"."+block.Labels[1]] = block
+			case block.Type == "data" && len(block.Labels) == 2:
+				module.blocks["data."+block.Labels[0]+"."+block.Labels[1]] = block
+			case block.Type == "module" && len(block.Labels) == 1:
+				if attr, ok := block.Body.Attributes["source"]; ok {
+					if value, diags := attr.Expr.Value(nil); !diags.HasErrors() && value.Type() == cty.String && value.IsKnown() && !value.IsNull() {
+						module.calls[block.Labels[0]] = value.AsString()
+					}
+				}
+			}
+		}
+	}
+	return module
+}
+
+// blockKey strips instance keys: "aws_s3_bucket.this[0]" -> "aws_s3_bucket.this".
+func blockKey(resource string) string {
+	if idx := strings.IndexByte(resource, '['); idx >= 0 {
+		return resource[:idx]
+	}
+	return resource
+}
+
+// splitModulePath splits "module.a.module.b[0]" into "module.a" and "b".
+func splitModulePath(path string) (string, string) {
+	parts := splitAddress(path)
+	name := blockKey(parts[len(parts)-1])
+	return strings.Join(parts[:len(parts)-2], "."), name
+}
+
+// rootAttribute returns the top-level attribute of an attribute path:
+// "policy.Statement[0]" -> "policy".
+func rootAttribute(path string) string {
+	if idx := strings.IndexAny(path, ".["); idx >= 0 {
+		return path[:idx]
+	}
+	return path
+}
@@ -0,0 +1,56 @@
+package plancheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, filename, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0o755))
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+}
+
+func TestSourceIndexLocatesAttributesAcrossModules(t *testing.T) {
+	root := t.TempDir()
+	envDir := filepath.Join(root, "envs", "dev")
+	writeFile(t, filepath.Join(envDir, "main.tf"), `module "iam" {
+  source = "../../modules/iam"
+}
+
+resource "aws_iam_policy" "api" {
+  name   = "api"
+  policy = "{}"
+}
+`)
+	writeFile(t, filepath.Join(root, "modules", "iam", "main.tf"), `resource "aws_security_group" "sg" {
+  name = "sg"
+
+  ingress {
+    from_port = 22
+  }
+}
+`)
+
+	index := NewSourceIndex(nil, envDir)
+
+	loc := index.Locate("aws_iam_policy.api", "policy.Statement[0].Action")
+	require.NotNil(t, loc)
+	require.Equal(t, filepath.Join(envDir, "main.tf"), loc.File)
+	require.Equal(t, 7, loc.Line)
+
+	loc = index.Locate("module.iam.aws_security_group.sg[0]", "ingress[0].cidr_blocks")
+	require.NotNil(t, loc)
+	require.Equal(t, filepath.Join(root, "modules", "iam", "main.tf"), loc.File)
+	require.Equal(t, 4, loc.Line)
+
+	loc = index.Locate("module.iam.aws_security_group.sg", "")
+	require.NotNil(t, loc)
+	require.Equal(t, 1, loc.Line)
+
+	require.Nil(t, index.Locate("module.missing.aws_s3_bucket.b", "bucket"))
+	require.Nil(t, index.Locate("aws_s3_bucket.unknown", "bucket"))
+}
@@ -0,0 +1,162 @@
+package plancheck
+
+import (
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// SourceFile is a single parsed .tf file, for rules that can check the
+// configuration statically without a plan (e.g. in a pre-commit hook).
+// Static checks only see literal values; anything built from variables,
+// references or unsupported functions is left to the plan-based check.
+type SourceFile struct {
+	Name string
+	Body *hclsyntax.Body
+}
+
+// ParseSource parses the contents of a .tf file.
+func ParseSource(name string, src []byte) (*SourceFile, error) {
+	file, diags := hclsyntax.ParseConfig(src, name, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return &SourceFile{Name: name, Body: file.Body.(*hclsyntax.Body)}, nil
+}
+
+// Resources returns the managed resource blocks of the given types.
+func (f *SourceFile) Resources(types ...string) []*hclsyntax.Block {
+	return f.blocks("resource", types)
+}
+
+// Variables returns the variable blocks.
+func (f *SourceFile) Variables() []*hclsyntax.Block {
+	var blocks []*hclsyntax.Block
+	for _, block := range f.Body.Blocks {
+		if block.Type == "variable" && len(block.Labels) == 1 {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// DataSources returns the data blocks of the given types.
+func (f *SourceFile) DataSources(types ...string) []*hclsyntax.Block {
+	return f.blocks("data", types)
+}
+
+func (f *SourceFile) blocks(kind string, types []string) []*hclsyntax.Block {
+	var blocks []*hclsyntax.Block
+	for _, block := range f.Body.Blocks {
+		if block.Type != kind || len(block.Labels) != 2 {
+			continue
+		}
+		for _, t := range types {
+			if block.Labels[0] == t {
+				blocks = append(blocks, block)
+				break
+			}
+		}
+	}
+	return blocks
+}
+
+// BlockAddress returns the address of a resource, data or variable block:
+// "aws_s3_bucket.a", "data.aws_iam_policy_document.b" or "var.c".
+func BlockAddress(block *hclsyntax.Block) string {
+	if block.Type == "variable" {
+		return "var." + block.Labels[0]
+	}
+	address := block.Labels[0] + "." + block.Labels[1]
+	if block.Type == "data" {
+		address = "data." + address
+	}
+	return address
+}
+
+// StaticFinding builds a finding for a block, located at rng in the file.
+func (f *SourceFile) StaticFinding(ruleID string, block *hclsyntax.Block, rng hcl.Range, message string) Finding {
+	finding := NewFinding(ruleID, BlockAddress(block), message)
+	finding.Source = &SourceLocation{File: f.Name, Line: rng.Start.Line}
+	return finding
+}
+
+// staticFunctions are the terraform functions literal values may use.
+var staticFunctions = map[string]function.Function{
+	"jsonencode": stdlib.JSONEncodeFunc,
+	"lower":      stdlib.LowerFunc,
+	"upper":      stdlib.UpperFunc,
+	"concat":     stdlib.ConcatFunc,
+	"merge":      stdlib.MergeFunc,
+	"tolist":     stdlib.MakeToFunc(cty.List(cty.DynamicPseudoType)),
+	"toset":      stdlib.MakeToFunc(cty.Set(cty.DynamicPseudoType)),
+}
+
+// Literal evaluates an expression that does not depend on variables or other
+// resources. ok is false for anything else.
+func Literal(expr hclsyntax.Expression) (value cty.Value, ok bool) {
+	if len(expr.Variables()) > 0 {
+		return cty.NilVal, false
+	}
+	value, diags := expr.Value(&hcl.EvalContext{Functions: staticFunctions})
+	if diags.HasErrors() || !value.IsWhollyKnown() {
+		return cty.NilVal, false
+	}
+	return value, true
+}
+
+// LiteralStrings returns the elements of a literal list or set of strings.
+func LiteralStrings(expr hclsyntax.Expression) ([]string, bool) {
+	value, ok := Literal(expr)
+	if !ok || value.IsNull() || !(value.Type().IsListType() || value.Type().IsSetType() || value.Type().IsTupleType()) {
+		return nil, false
+	}
+	var values []string
+	for it := value.ElementIterator(); it.Next(); {
+		_, element := it.Element()
+		if element.IsNull() || element.Type() != cty.String {
+			return nil, false
+		}
+		values = append(values, element.AsString())
+	}
+	return values, true
+}
+
+// EvaluateSource runs the static checks of the given rules, or of every
+// registered rule when none are passed, against a parsed file.
+func EvaluateSource(file *SourceFile, rules ...Rule) []Finding {
+	if len(rules) == 0 {
+		rules = Rules()
+	}
+
+	var findings []Finding
+	for _, rule := range rules {
+		if rule.CheckSource == nil {
+			continue
+		}
+		for _, finding := range rule.CheckSource(file) {
+			if finding.RuleID == "" {
+				finding.RuleID = rule.ID
+			}
+			if finding.Severity == "" {
+				finding.Severity = rule.Severity
+			}
+			findings = append(findings, finding)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return sourceLine(findings[i]) < sourceLine(findings[j])
+	})
+	return findings
+}
+
+func sourceLine(f Finding) int {
+	if f.Source == nil {
+		return 0
+	}
+	return f.Source.Line
+}
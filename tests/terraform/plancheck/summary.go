@@ -0,0 +1,85 @@
+package plancheck
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SummaryGroup counts the findings of one rule.
+type SummaryGroup struct {
+	RuleID   string
+	Severity Severity
+	Count    int
+	// Owners lists the teams the findings are routed to, when assigned.
+	Owners []string
+}
+
+func (g SummaryGroup) String() string {
+	kind := "finding"
+	if !g.Severity.Blocking() {
+		kind = string(g.Severity) + " finding"
+	}
+	if g.Count != 1 {
+		kind += "s"
+	}
+	line := fmt.Sprintf("%d %s from %s", g.Count, kind, g.RuleID)
+	if len(g.Owners) > 0 {
+		line += " (" + strings.Join(g.Owners, ", ") + ")"
+	}
+	return line + " — run tfcompliance explain " + g.RuleID
+}
+
+// Summarize groups findings by rule: blocking rules first, then by count.
+func Summarize(findings []Finding) []SummaryGroup {
+	index := map[string]int{}
+	var groups []SummaryGroup
+	owners := map[string]map[string]bool{}
+	for _, finding := range findings {
+		i, ok := index[finding.RuleID]
+		if !ok {
+			i = len(groups)
+			index[finding.RuleID] = i
+			groups = append(groups, SummaryGroup{RuleID: finding.RuleID, Severity: finding.Severity})
+			owners[finding.RuleID] = map[string]bool{}
+		}
+		groups[i].Count++
+		if finding.Owner != "" && !owners[finding.RuleID][finding.Owner] {
+			owners[finding.RuleID][finding.Owner] = true
+			groups[i].Owners = append(groups[i].Owners, finding.Owner)
+		}
+	}
+	for i := range groups {
+		sort.Strings(groups[i].Owners)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.Severity.rank() != b.Severity.rank() {
+			return a.Severity.rank() < b.Severity.rank()
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.RuleID < b.RuleID
+	})
+	return groups
+}
+
+// WriteSummary writes one line per rule with findings, pointing at
+// tfcompliance explain for the details, under a header naming the policy
+// pack version.
+func WriteSummary(w io.Writer, findings []Finding) {
+	groups := Summarize(findings)
+	if len(groups) == 0 {
+		return
+	}
+	if version := PackVersion(); version != "" {
+		fmt.Fprintf(w, "summary (policy pack %s):\n", version)
+	} else {
+		fmt.Fprintln(w, "summary:")
+	}
+	for _, group := range groups {
+		fmt.Fprintf(w, "  %s\n", group)
+	}
+}
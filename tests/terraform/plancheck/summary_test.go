@@ -0,0 +1,34 @@
+package plancheck
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeGroupsByRule(t *testing.T) {
+	findings := []Finding{
+		{RuleID: "tags.cost-allocation", Severity: SeverityInfo},
+		{RuleID: "cost.gp2", Severity: SeverityAdvisory},
+		{RuleID: "logs.retention", Owner: "@platform"},
+		{RuleID: "iam.wildcard-action", Owner: "@security"},
+		{RuleID: "iam.wildcard-action", Owner: "@platform"},
+		{RuleID: "iam.wildcard-action", Owner: "@security"},
+		{RuleID: "cost.gp2", Severity: SeverityAdvisory},
+		{RuleID: "cost.gp2", Severity: SeverityAdvisory},
+	}
+
+	var out bytes.Buffer
+	WriteSummary(&out, findings)
+	require.Equal(t, `summary:
+  3 findings from iam.wildcard-action (@platform, @security) — run tfcompliance explain iam.wildcard-action
+  1 finding from logs.retention (@platform) — run tfcompliance explain logs.retention
+  3 advisory findings from cost.gp2 — run tfcompliance explain cost.gp2
+  1 info finding from tags.cost-allocation — run tfcompliance explain tags.cost-allocation
+`, out.String())
+
+	out.Reset()
+	WriteSummary(&out, nil)
+	require.Empty(t, out.String())
+}
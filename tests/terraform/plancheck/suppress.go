@@ -0,0 +1,100 @@
+package plancheck
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+const (
+	// SuppressTag lists the IDs of rules, separated by spaces, whose findings
+	// on the tagged resource do not fail the run, e.g.
+	// compliance:suppress = "iam.wildcard-resource s3.versioning".
+	SuppressTag = "compliance:suppress"
+	// JustificationTag says why the rules in SuppressTag do not apply. A
+	// SuppressTag without one suppresses nothing, as a baseline entry
+	// without a justification is rejected.
+	JustificationTag = "compliance:justification"
+	// ExpiresTag is the first day, e.g. 2026-11-30, the SuppressTag no
+	// longer suppresses anything, so that it is reviewed again. Without one
+	// the suppression never expires.
+	ExpiresTag = "compliance:expires"
+)
+
+// Suppression is the SuppressTag of one planned resource.
+type Suppression struct {
+	Address       string
+	RuleIDs       []string
+	Justification string
+	Expires       string
+}
+
+// Suppressions are the suppression tags of a plan, by resource address.
+type Suppressions map[string]Suppression
+
+// TagSuppressions collects the SuppressTag of every planned resource.
+func TagSuppressions(plan *tfjson.Plan) Suppressions {
+	suppressions := Suppressions{}
+	for _, resource := range PlannedResources(plan) {
+		tags := Tags(resource.AttributeValues)
+		ruleIDs := strings.Fields(tags[SuppressTag])
+		if len(ruleIDs) == 0 {
+			continue
+		}
+		suppressions[resource.Address] = Suppression{
+			Address:       resource.Address,
+			RuleIDs:       ruleIDs,
+			Justification: strings.TrimSpace(tags[JustificationTag]),
+			Expires:       strings.TrimSpace(tags[ExpiresTag]),
+		}
+	}
+	return suppressions
+}
+
+// Suppresses reports whether finding's resource is tagged to suppress its
+// rule, with a justification and without an expiry date that has passed.
+func (s Suppressions) Suppresses(finding Finding) bool {
+	suppression, ok := s[finding.Address]
+	if !ok || suppression.Justification == "" || suppression.Expired(time.Now()) {
+		return false
+	}
+	for _, ruleID := range suppression.RuleIDs {
+		if ruleID == finding.RuleID {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the suppression's expiry date has been reached by
+// now.
+func (s Suppression) Expired(now time.Time) bool {
+	return expired(s.Expires, now)
+}
+
+// Filter splits findings into those still open and those suppressed by tags.
+func (s Suppressions) Filter(findings []Finding) (open, suppressed []Finding) {
+	for _, finding := range findings {
+		if s.Suppresses(finding) {
+			suppressed = append(suppressed, finding)
+		} else {
+			open = append(open, finding)
+		}
+	}
+	return open, suppressed
+}
+
+// Unjustified returns the addresses of resources whose SuppressTag is
+// ignored for want of a JustificationTag, sorted.
+func (s Suppressions) Unjustified() []string {
+	var addresses []string
+	for address, suppression := range s {
+		if suppression.Justification == "" {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+	return addresses
+}
@@ -0,0 +1,94 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagSuppressionsNeedJustification(t *testing.T) {
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(`{
+	  "format_version": "1.2",
+	  "planned_values": {"root_module": {"resources": [
+	    {"address": "aws_iam_policy.ci", "type": "aws_iam_policy", "name": "ci", "values": {"tags_all": {
+	      "compliance:suppress": "iam.wildcard-resource  iam.service-wildcard-action",
+	      "compliance:justification": "CI deploys every stack"
+	    }}},
+	    {"address": "aws_s3_bucket.scratch", "type": "aws_s3_bucket", "name": "scratch", "values": {"tags": {"compliance:suppress": "s3.versioning"}}},
+	    {"address": "aws_s3_bucket.logs", "type": "aws_s3_bucket", "name": "logs", "values": {"tags": {"Owner": "platform"}}}
+	  ]}}
+	}`), &plan))
+
+	suppressions := TagSuppressions(&plan)
+	require.Equal(t, Suppressions{
+		"aws_iam_policy.ci": {
+			Address:       "aws_iam_policy.ci",
+			RuleIDs:       []string{"iam.wildcard-resource", "iam.service-wildcard-action"},
+			Justification: "CI deploys every stack",
+		},
+		"aws_s3_bucket.scratch": {Address: "aws_s3_bucket.scratch", RuleIDs: []string{"s3.versioning"}},
+	}, suppressions)
+	require.Equal(t, []string{"aws_s3_bucket.scratch"}, suppressions.Unjustified())
+
+	open, suppressed := suppressions.Filter([]Finding{
+		{RuleID: "iam.wildcard-resource", Address: "aws_iam_policy.ci"},
+		{RuleID: "iam.wildcard-action", Address: "aws_iam_policy.ci"},
+		{RuleID: "s3.versioning", Address: "aws_s3_bucket.scratch"},
+		{RuleID: "s3.versioning", Address: "aws_s3_bucket.logs"},
+	})
+	require.Equal(t, []Finding{{RuleID: "iam.wildcard-resource", Address: "aws_iam_policy.ci"}}, suppressed)
+	require.Len(t, open, 3)
+
+	require.Empty(t, TagSuppressions(nil))
+}
+
+func TestTagSuppressionsExpire(t *testing.T) {
+	suppressions := Suppressions{
+		"aws_iam_policy.ci":     {Address: "aws_iam_policy.ci", RuleIDs: []string{"iam.wildcard-resource"}, Justification: "CI deploys every stack", Expires: "2000-01-01"},
+		"aws_iam_policy.deploy": {Address: "aws_iam_policy.deploy", RuleIDs: []string{"iam.wildcard-resource"}, Justification: "deploys every stack", Expires: "2999-01-01"},
+		"aws_iam_policy.typo":   {Address: "aws_iam_policy.typo", RuleIDs: []string{"iam.wildcard-resource"}, Justification: "deploys every stack", Expires: "31/12/2999"},
+	}
+	open, suppressed := suppressions.Filter([]Finding{
+		{RuleID: "iam.wildcard-resource", Address: "aws_iam_policy.ci"},
+		{RuleID: "iam.wildcard-resource", Address: "aws_iam_policy.deploy"},
+		{RuleID: "iam.wildcard-resource", Address: "aws_iam_policy.typo"},
+	})
+	require.Equal(t, []Finding{{RuleID: "iam.wildcard-resource", Address: "aws_iam_policy.deploy"}}, suppressed)
+	require.Len(t, open, 2, "an expiry that is not a date counts as expired")
+
+	var keys []string
+	for _, waiver := range ExpiredWaivers(nil, suppressions, time.Now()) {
+		require.True(t, waiver.Tag)
+		keys = append(keys, waiver.Key)
+	}
+	require.Equal(t, []string{"aws_iam_policy.ci", "aws_iam_policy.typo"}, keys)
+}
+
+func TestSeveritiesOverrideRules(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "compliance.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte("severities:\n  test.always: info\n"), 0o644))
+	config, err := LoadConfig(filename)
+	require.NoError(t, err)
+
+	rule := Rule{ID: "test.always", Check: func(*Input) []Finding {
+		return []Finding{NewFinding("", "aws_s3_bucket.a", "always fails")}
+	}}
+	findings := Evaluate(&Input{Config: config}, rule)
+	require.Equal(t, SeverityInfo, findings[0].Severity)
+	require.True(t, findings[0].Advisory())
+	require.Equal(t, "[test.always, info]", findings[0].Label())
+
+	findings = Evaluate(&Input{}, rule)
+	require.False(t, findings[0].Advisory())
+	require.Equal(t, "[test.always]", findings[0].Label())
+
+	require.NoError(t, os.WriteFile(filename, []byte("severities:\n  test.always: warn\n"), 0o644))
+	_, err = LoadConfig(filename)
+	require.EqualError(t, err, filename+`: severities.test.always: unknown severity "warn" (want error, advisory or info)`)
+}
@@ -0,0 +1,58 @@
+package plancheck
+
+import (
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// DefaultTags returns the default_tags of the provider configuration the
+// resource at address uses, as far as the plan shows them: a map of
+// constants, or a root variable holding the whole map. A map mixing
+// constants and references only has its values at apply, and is left out.
+func (in *Input) DefaultTags(address string) map[string]string {
+	if in.Plan == nil || in.Plan.Config == nil {
+		return nil
+	}
+	for _, key := range in.Regions().providerKeys(address) {
+		if provider, ok := in.Plan.Config.ProviderConfigs[key]; ok && provider != nil {
+			return defaultTags(in.Plan, provider.Expressions["default_tags"])
+		}
+	}
+	return nil
+}
+
+func defaultTags(plan *tfjson.Plan, expr *tfjson.Expression) map[string]string {
+	if expr == nil || expr.ExpressionData == nil || len(expr.NestedBlocks) == 0 {
+		return nil
+	}
+	tags := expr.NestedBlocks[0]["tags"]
+	if tags == nil || tags.ExpressionData == nil {
+		return nil
+	}
+	if value, ok := tags.ConstantValue.(map[string]interface{}); ok {
+		return Tags(map[string]interface{}{"tags": value})
+	}
+	if len(tags.References) == 0 || !strings.HasPrefix(tags.References[0], "var.") {
+		return nil
+	}
+	variable, ok := plan.Variables[strings.TrimPrefix(tags.References[0], "var.")]
+	if !ok || variable == nil {
+		return nil
+	}
+	value, _ := variable.Value.(map[string]interface{})
+	return Tags(map[string]interface{}{"tags": value})
+}
+
+// ResourceTags returns the tags a resource will carry: its tags_all, or its
+// own tags when tags_all is only known at apply, with the default tags of
+// its provider configuration under them.
+func (in *Input) ResourceTags(resource *tfjson.StateResource) map[string]string {
+	tags := Tags(resource.AttributeValues)
+	for key, value := range in.DefaultTags(resource.Address) {
+		if _, ok := tags[key]; !ok {
+			tags[key] = value
+		}
+	}
+	return tags
+}
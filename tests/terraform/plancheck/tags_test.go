@@ -0,0 +1,47 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+const defaultTagsPlan = `{
+  "format_version": "1.0",
+  "variables": {"environment": {"value": "dev"}},
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_sqs_queue.jobs", "type": "aws_sqs_queue", "name": "jobs", "values": {"tags": {"Owner": "api", "Project": "own"}}},
+        {"address": "aws_sqs_queue.mixed", "type": "aws_sqs_queue", "name": "mixed", "values": {"tags": null}}
+      ]
+    }
+  },
+  "configuration": {
+    "provider_config": {
+      "aws": {"name": "aws", "expressions": {"default_tags": [{"tags": {"constant_value": {"Project": "cs450", "CostCenter": "group106"}}}]}},
+      "aws.mixed": {"name": "aws", "alias": "mixed", "expressions": {"default_tags": [{"tags": {"references": ["var.environment"]}}]}}
+    },
+    "root_module": {
+      "resources": [
+        {"address": "aws_sqs_queue.jobs", "type": "aws_sqs_queue", "name": "jobs", "provider_config_key": "aws"},
+        {"address": "aws_sqs_queue.mixed", "type": "aws_sqs_queue", "name": "mixed", "provider_config_key": "aws.mixed"}
+      ]
+    }
+  }
+}`
+
+func TestResourceTagsIncludeProviderDefaultTags(t *testing.T) {
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(defaultTagsPlan), &plan))
+	in := &Input{Plan: &plan}
+	resources := PlannedResources(&plan)
+
+	require.Equal(t, map[string]string{"Owner": "api", "Project": "own", "CostCenter": "group106"},
+		in.ResourceTags(resources[0]), "the resource's own tags override the defaults")
+
+	// A reference that is not a whole map of tags is only known at apply.
+	require.Empty(t, in.ResourceTags(resources[1]))
+}
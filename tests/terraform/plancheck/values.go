@@ -0,0 +1,140 @@
+package plancheck
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Lookup walks planned attribute values along a dotted path such as
+// "point_in_time_recovery.0.enabled" or "tags.Environment". Nested blocks are
+// lists in plan JSON, so numeric segments index into them.
+func Lookup(values map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = values
+	for _, segment := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			idx, err := strconv.Atoi(segment)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			current = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return current, current != nil
+}
+
+// ValueAt returns the value a finding's attribute path points at, such as
+// "policy.Statement[2].Action[0]". String attributes holding JSON documents
+// (policies) are decoded so paths can continue into them.
+func ValueAt(values map[string]interface{}, path string) (interface{}, bool) {
+	chain, ok := walkPath(values, path)
+	if !ok {
+		return nil, false
+	}
+	return chain[len(chain)-1], true
+}
+
+// walkPath follows path from values and returns every value it passed
+// through, starting with values itself and ending at the deepest value it
+// could reach. JSON documents on the way are returned decoded.
+func walkPath(values map[string]interface{}, path string) ([]interface{}, bool) {
+	chain := []interface{}{values}
+	for _, segment := range splitAddress(path) {
+		name, indexes := segment, []string(nil)
+		if i := strings.IndexByte(segment, '['); i >= 0 {
+			name = segment[:i]
+			indexes = strings.Split(strings.TrimSuffix(segment[i+1:], "]"), "][")
+		}
+
+		keys := indexes
+		if name != "" {
+			keys = append([]string{name}, indexes...)
+		}
+		for _, key := range keys {
+			current := chain[len(chain)-1]
+			if s, ok := current.(string); ok {
+				var decoded interface{}
+				if json.Unmarshal([]byte(s), &decoded) == nil {
+					current = decoded
+					chain[len(chain)-1] = decoded
+				}
+			}
+			switch node := current.(type) {
+			case map[string]interface{}:
+				next, ok := node[key]
+				if !ok {
+					return chain, false
+				}
+				chain = append(chain, next)
+			case []interface{}:
+				idx, err := strconv.Atoi(key)
+				if err != nil || idx < 0 || idx >= len(node) {
+					return chain, false
+				}
+				chain = append(chain, node[idx])
+			default:
+				return chain, false
+			}
+		}
+	}
+	return chain, true
+}
+
+// LookupString returns the string at path, or "" when it is absent.
+func LookupString(values map[string]interface{}, path string) string {
+	value, _ := Lookup(values, path)
+	s, _ := value.(string)
+	return s
+}
+
+// LookupBool returns the boolean at path, or false when it is absent.
+func LookupBool(values map[string]interface{}, path string) bool {
+	value, _ := Lookup(values, path)
+	b, _ := value.(bool)
+	return b
+}
+
+// LookupNumber returns the number at path and whether one was present.
+func LookupNumber(values map[string]interface{}, path string) (float64, bool) {
+	value, _ := Lookup(values, path)
+	n, ok := value.(float64)
+	return n, ok
+}
+
+// Blocks returns the nested blocks stored under key, e.g. every "ingress"
+// block of a security group.
+func Blocks(values map[string]interface{}, key string) []map[string]interface{} {
+	list, _ := values[key].([]interface{})
+	blocks := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if block, ok := item.(map[string]interface{}); ok {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// Tags returns the effective tags of a resource: tags_all when the provider
+// has computed it (it includes provider default_tags), otherwise tags.
+func Tags(values map[string]interface{}) map[string]string {
+	raw, ok := values["tags_all"].(map[string]interface{})
+	if !ok || len(raw) == 0 {
+		raw, _ = values["tags"].(map[string]interface{})
+	}
+	tags := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if s, ok := value.(string); ok {
+			tags[key] = s
+		}
+	}
+	return tags
+}
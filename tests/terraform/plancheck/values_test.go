@@ -0,0 +1,57 @@
+package plancheck
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueAtFollowsFindingPaths(t *testing.T) {
+	values := map[string]interface{}{
+		"policy": `{"Statement":[{"Action":["s3:GetObject","*"]}]}`,
+		"rule":   []interface{}{map[string]interface{}{"schedule": "cron(0 5 * * ? *)"}},
+	}
+
+	value, ok := ValueAt(values, "policy.Statement[0].Action[1]")
+	require.True(t, ok)
+	require.Equal(t, "*", value)
+
+	value, ok = ValueAt(values, "rule[0].schedule")
+	require.True(t, ok)
+	require.Equal(t, "cron(0 5 * * ? *)", value)
+
+	_, ok = ValueAt(values, "policy.Statement[3]")
+	require.False(t, ok)
+}
+
+func TestEvidenceAtKeepsTheEnclosingObject(t *testing.T) {
+	values := map[string]interface{}{
+		"policy":            `{"Statement":[{"Effect":"Allow","Action":["s3:GetObject","*"]}]}`,
+		"rule":              []interface{}{map[string]interface{}{"schedule": "cron(0 5 * * ? *)"}},
+		"tags":              map[string]interface{}{"Owner": "storage"},
+		"retention_in_days": nil,
+	}
+
+	cases := map[string]string{
+		"policy.Statement[0].Action[1]": `{"Action":["s3:GetObject","*"],"Effect":"Allow"}`,
+		"rule[0].schedule":              `{"schedule":"cron(0 5 * * ? *)"}`,
+		"tags":                          `{"tags":{"Owner":"storage"}}`,
+		"retention_in_days":             `{"retention_in_days":null}`,
+		"policy.Statement[3]":           `{"policy":"{\"Statement\":[{\"Effect\":\"Allow\",\"Action\":[\"s3:GetObject\",\"*\"]}]}"}`,
+	}
+	for path, want := range cases {
+		require.JSONEq(t, want, string(EvidenceAt(values, path)), path)
+	}
+}
+
+func TestEvidenceSurvivesIndentedReports(t *testing.T) {
+	finding := NewFinding("tags.required", "aws_sqs_queue.jobs", "missing tags").
+		WithEvidence(map[string]interface{}{"tags": map[string]interface{}{"Owner": "storage"}})
+
+	filename := filepath.Join(t.TempDir(), "findings.json")
+	require.NoError(t, WriteFindings(filename, []Finding{finding}))
+	findings, err := ReadFindings(filename)
+	require.NoError(t, err)
+	require.Equal(t, []Finding{finding}, findings)
+}
@@ -0,0 +1,86 @@
+package plancheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ParseExpiry parses the expiry date of a waiver, a baseline entry or a
+// SuppressTag, as midnight UTC: from that day on the waiver waives nothing.
+// An empty date is the zero time: the waiver never expires.
+func ParseExpiry(expires string) (time.Time, error) {
+	if expires == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(CanaryLayout, expires)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expiry %q is not a date such as 2026-11-30", expires)
+	}
+	return t, nil
+}
+
+// expired reports whether a waiver expiring on the given date has expired by
+// now. A date that does not parse counts as expired, so that a typo cannot
+// make a waiver permanent.
+func expired(expires string, now time.Time) bool {
+	day, err := ParseExpiry(expires)
+	if err != nil {
+		return true
+	}
+	return !day.IsZero() && !now.Before(day)
+}
+
+// ExpiredWaiver is a baseline entry or SuppressTag whose expiry date has
+// passed, or is not a date.
+type ExpiredWaiver struct {
+	// Key is the resource waived, as Finding.Key gives it.
+	Key     string
+	RuleIDs []string
+	// Path is the attribute path a baseline entry waives; empty for tags.
+	Path    string
+	Expires string
+	// Tag is set for a SuppressTag, and clear for a baseline entry.
+	Tag bool
+}
+
+// ExpiredWaivers returns the waivers of baseline and suppressions that have
+// expired by now, ordered by resource, baseline entries first. Either may be
+// nil.
+func ExpiredWaivers(baseline *Baseline, suppressions Suppressions, now time.Time) []ExpiredWaiver {
+	var waivers []ExpiredWaiver
+	if baseline != nil {
+		for _, entry := range baseline.Accepted {
+			if entry.Expired(now) {
+				waivers = append(waivers, ExpiredWaiver{Key: entry.Resource, RuleIDs: []string{entry.RuleID}, Path: entry.Path, Expires: entry.Expires})
+			}
+		}
+	}
+	for _, suppression := range suppressions {
+		if suppression.Justification != "" && suppression.Expired(now) {
+			waivers = append(waivers, ExpiredWaiver{Key: suppression.Address, RuleIDs: suppression.RuleIDs, Expires: suppression.Expires, Tag: true})
+		}
+	}
+	sort.SliceStable(waivers, func(i, j int) bool {
+		x, y := waivers[i], waivers[j]
+		if x.Tag != y.Tag {
+			return !x.Tag
+		}
+		if x.Key != y.Key {
+			return x.Key < y.Key
+		}
+		return strings.Join(x.RuleIDs, " ")+x.Path < strings.Join(y.RuleIDs, " ")+y.Path
+	})
+	return waivers
+}
+
+// SplitKey splits a Finding.Key into its region, empty when the key has
+// none, and address.
+func SplitKey(key string) (region, address string) {
+	prefix, rest, found := strings.Cut(key, "/")
+	if !found || prefix == "" || strings.ContainsAny(prefix, ".[") {
+		return "", key
+	}
+	return prefix, rest
+}
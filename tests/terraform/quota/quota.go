@@ -0,0 +1,274 @@
+// Package quota checks, before an apply, that the resources a plan creates
+// fit in the account's service quotas, so an apply against a sandbox fails
+// up front with the quota to raise rather than halfway through.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas/types"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/plancheck"
+)
+
+// ServiceQuotasAPI reads applied and default quota values.
+type ServiceQuotasAPI interface {
+	GetServiceQuota(context.Context, *servicequotas.GetServiceQuotaInput, ...func(*servicequotas.Options)) (*servicequotas.GetServiceQuotaOutput, error)
+	GetAWSDefaultServiceQuota(context.Context, *servicequotas.GetAWSDefaultServiceQuotaInput, ...func(*servicequotas.Options)) (*servicequotas.GetAWSDefaultServiceQuotaOutput, error)
+}
+
+// EC2API counts VPCs and Elastic IPs.
+type EC2API interface {
+	DescribeVpcs(context.Context, *ec2.DescribeVpcsInput, ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error)
+	DescribeAddresses(context.Context, *ec2.DescribeAddressesInput, ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error)
+}
+
+// S3API counts buckets.
+type S3API interface {
+	ListBuckets(context.Context, *s3.ListBucketsInput, ...func(*s3.Options)) (*s3.ListBucketsOutput, error)
+}
+
+// LambdaAPI reads the account's concurrency.
+type LambdaAPI interface {
+	GetAccountSettings(context.Context, *lambda.GetAccountSettingsInput, ...func(*lambda.Options)) (*lambda.GetAccountSettingsOutput, error)
+}
+
+// Clients are the AWS APIs quotas and current usage are read from.
+type Clients struct {
+	ServiceQuotas ServiceQuotasAPI
+	EC2           EC2API
+	S3            S3API
+	Lambda        LambdaAPI
+}
+
+// NewClients returns the clients quotas and current usage are read through
+// in region, signed with creds when set.
+func NewClients(region string, creds *credentials.Credentials) (*Clients, error) {
+	cfg, err := awsapi.Default().WithCredentials(creds).Config(context.Background(), region)
+	if err != nil {
+		return nil, fmt.Errorf("quota: %w", err)
+	}
+	return &Clients{
+		ServiceQuotas: servicequotas.NewFromConfig(cfg),
+		EC2:           ec2.NewFromConfig(cfg),
+		S3:            s3.NewFromConfig(cfg, awsapi.S3PathStyle(cfg)),
+		Lambda:        lambda.NewFromConfig(cfg),
+	}, nil
+}
+
+// Quota is one service quota a plan can run into.
+type Quota struct {
+	// Name describes the quota as the Service Quotas console does.
+	Name        string
+	ServiceCode string
+	QuotaCode   string
+
+	// ResourceType is the terraform resource type that consumes the quota.
+	ResourceType string
+	// demand is how much of the quota one planned resource consumes. Nil
+	// means one.
+	demand func(values map[string]interface{}) float64
+	// limit, when set, reads the quota's value where Service Quotas does
+	// not have it.
+	limit func(ctx context.Context, c *Clients) (float64, error)
+	usage func(ctx context.Context, c *Clients) (float64, error)
+}
+
+// Quotas are the quotas Check knows how to measure.
+var Quotas = []Quota{
+	{
+		Name:         "VPCs per Region",
+		ServiceCode:  "vpc",
+		QuotaCode:    "L-F678F1CE",
+		ResourceType: "aws_vpc",
+		usage: func(ctx context.Context, c *Clients) (float64, error) {
+			var n int
+			pages := ec2.NewDescribeVpcsPaginator(c.EC2, &ec2.DescribeVpcsInput{})
+			for pages.HasMorePages() {
+				page, err := pages.NextPage(ctx)
+				if err != nil {
+					return 0, err
+				}
+				n += len(page.Vpcs)
+			}
+			return float64(n), nil
+		},
+	},
+	{
+		Name:         "EC2-VPC Elastic IPs",
+		ServiceCode:  "ec2",
+		QuotaCode:    "L-0263D0A3",
+		ResourceType: "aws_eip",
+		usage: func(ctx context.Context, c *Clients) (float64, error) {
+			out, err := c.EC2.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{})
+			if err != nil {
+				return 0, err
+			}
+			return float64(len(out.Addresses)), nil
+		},
+	},
+	{
+		Name:         "General purpose buckets",
+		ServiceCode:  "s3",
+		QuotaCode:    "L-DC2B2D3D",
+		ResourceType: "aws_s3_bucket",
+		usage: func(ctx context.Context, c *Clients) (float64, error) {
+			out, err := c.S3.ListBuckets(ctx, &s3.ListBucketsInput{})
+			if err != nil {
+				return 0, err
+			}
+			return float64(len(out.Buckets)), nil
+		},
+	},
+	{
+		// Functions themselves are not limited, but the concurrency they
+		// reserve is: Lambda keeps UnreservedConcurrencyFloor of the
+		// account's concurrency unreserved.
+		Name:         "Lambda reserved concurrency",
+		ServiceCode:  "lambda",
+		QuotaCode:    "L-B99A9384",
+		ResourceType: "aws_lambda_function",
+		demand: func(values map[string]interface{}) float64 {
+			reserved, ok := plancheck.LookupNumber(values, "reserved_concurrent_executions")
+			if !ok || reserved < 0 {
+				return 0
+			}
+			return reserved
+		},
+		limit: func(ctx context.Context, c *Clients) (float64, error) {
+			out, err := c.Lambda.GetAccountSettings(ctx, &lambda.GetAccountSettingsInput{})
+			if err != nil {
+				return 0, err
+			}
+			return float64(out.AccountLimit.ConcurrentExecutions - UnreservedConcurrencyFloor), nil
+		},
+		usage: func(ctx context.Context, c *Clients) (float64, error) {
+			out, err := c.Lambda.GetAccountSettings(ctx, &lambda.GetAccountSettingsInput{})
+			if err != nil {
+				return 0, err
+			}
+			limit := out.AccountLimit
+			return float64(limit.ConcurrentExecutions - aws.ToInt32(limit.UnreservedConcurrentExecutions)), nil
+		},
+	},
+}
+
+// UnreservedConcurrencyFloor is the concurrency Lambda refuses to let
+// functions reserve.
+const UnreservedConcurrencyFloor = 10
+
+// Result is one quota checked against a plan.
+type Result struct {
+	Quota Quota
+	// Limit is the quota's applied value, Usage what the account already
+	// consumes and Demand what the plan adds.
+	Limit, Usage, Demand float64
+	// Addresses are the planned resources making up Demand.
+	Addresses []string
+}
+
+// Exceeded reports whether applying the plan would exceed the quota.
+func (r Result) Exceeded() bool {
+	return r.Usage+r.Demand > r.Limit
+}
+
+func (r Result) String() string {
+	s := fmt.Sprintf("%s (%s %s): %g in use + %g planned of %g", r.Quota.Name, r.Quota.ServiceCode, r.Quota.QuotaCode, r.Usage, r.Demand, r.Limit)
+	if r.Exceeded() {
+		s += fmt.Sprintf("; the plan needs %g more, by %s. Free some up or request an increase in Service Quotas", r.Usage+r.Demand-r.Limit, strings.Join(r.Addresses, ", "))
+	}
+	return s
+}
+
+// Check returns a result for each quota the plan consumes. Resources the
+// plan creates count, including the new half of a create-before-destroy
+// replacement, which exists alongside the old one mid-apply.
+func (c *Clients) Check(ctx context.Context, plan *tfjson.Plan) ([]Result, error) {
+	var results []Result
+	for _, quota := range Quotas {
+		result := Result{Quota: quota}
+		for _, change := range plan.ResourceChanges {
+			if change == nil || change.Type != quota.ResourceType || change.Mode == tfjson.DataResourceMode || change.Change == nil {
+				continue
+			}
+			if !change.Change.Actions.Create() && !change.Change.Actions.CreateBeforeDestroy() {
+				continue
+			}
+			demand := 1.0
+			if quota.demand != nil {
+				after, _ := change.Change.After.(map[string]interface{})
+				demand = quota.demand(after)
+			}
+			if demand > 0 {
+				result.Demand += demand
+				result.Addresses = append(result.Addresses, change.Address)
+			}
+		}
+		if result.Demand == 0 {
+			continue
+		}
+		sort.Strings(result.Addresses)
+
+		var err error
+		if quota.limit != nil {
+			result.Limit, err = quota.limit(ctx, c)
+		} else {
+			result.Limit, err = c.serviceQuota(ctx, quota)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("quota: reading %s: %w", quota.Name, err)
+		}
+		if result.Usage, err = quota.usage(ctx, c); err != nil {
+			return nil, fmt.Errorf("quota: measuring %s: %w", quota.Name, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// serviceQuota returns a quota's applied value, or its AWS default when the
+// account has never had it changed.
+func (c *Clients) serviceQuota(ctx context.Context, quota Quota) (float64, error) {
+	out, err := c.ServiceQuotas.GetServiceQuota(ctx, &servicequotas.GetServiceQuotaInput{
+		ServiceCode: aws.String(quota.ServiceCode),
+		QuotaCode:   aws.String(quota.QuotaCode),
+	})
+	var notApplied *types.NoSuchResourceException
+	if errors.As(err, &notApplied) {
+		def, err := c.ServiceQuotas.GetAWSDefaultServiceQuota(ctx, &servicequotas.GetAWSDefaultServiceQuotaInput{
+			ServiceCode: aws.String(quota.ServiceCode),
+			QuotaCode:   aws.String(quota.QuotaCode),
+		})
+		if err != nil {
+			return 0, err
+		}
+		return aws.ToFloat64(def.Quota.Value), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return aws.ToFloat64(out.Quota.Value), nil
+}
+
+// Exceeded returns the results whose quota the plan would exceed.
+func Exceeded(results []Result) []Result {
+	var exceeded []Result
+	for _, result := range results {
+		if result.Exceeded() {
+			exceeded = append(exceeded, result)
+		}
+	}
+	return exceeded
+}
@@ -0,0 +1,116 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas/types"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+const sandboxPlan = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main", "change": {"actions": ["create"], "after": {}}},
+    {"address": "aws_eip.nat[0]", "mode": "managed", "type": "aws_eip", "name": "nat", "index": 0, "change": {"actions": ["create"], "after": {}}},
+    {"address": "aws_eip.nat[1]", "mode": "managed", "type": "aws_eip", "name": "nat", "index": 1, "change": {"actions": ["create", "delete"], "after": {}}},
+    {"address": "aws_eip.bastion", "mode": "managed", "type": "aws_eip", "name": "bastion", "change": {"actions": ["delete", "create"], "after": {}}},
+    {"address": "aws_s3_bucket.artifacts", "mode": "managed", "type": "aws_s3_bucket", "name": "artifacts", "change": {"actions": ["no-op"], "after": {}}},
+    {"address": "aws_lambda_function.api", "mode": "managed", "type": "aws_lambda_function", "name": "api", "change": {"actions": ["create"], "after": {"reserved_concurrent_executions": 50}}},
+    {"address": "aws_lambda_function.worker", "mode": "managed", "type": "aws_lambda_function", "name": "worker", "change": {"actions": ["create"], "after": {"reserved_concurrent_executions": -1}}}
+  ]
+}`
+
+type fakeServiceQuotas struct {
+	ServiceQuotasAPI
+	applied, defaults map[string]float64
+}
+
+func (f *fakeServiceQuotas) GetServiceQuota(_ context.Context, in *servicequotas.GetServiceQuotaInput, _ ...func(*servicequotas.Options)) (*servicequotas.GetServiceQuotaOutput, error) {
+	value, ok := f.applied[aws.ToString(in.QuotaCode)]
+	if !ok {
+		return nil, &types.NoSuchResourceException{Message: aws.String("not applied")}
+	}
+	return &servicequotas.GetServiceQuotaOutput{Quota: &types.ServiceQuota{Value: aws.Float64(value)}}, nil
+}
+
+func (f *fakeServiceQuotas) GetAWSDefaultServiceQuota(_ context.Context, in *servicequotas.GetAWSDefaultServiceQuotaInput, _ ...func(*servicequotas.Options)) (*servicequotas.GetAWSDefaultServiceQuotaOutput, error) {
+	return &servicequotas.GetAWSDefaultServiceQuotaOutput{Quota: &types.ServiceQuota{Value: aws.Float64(f.defaults[aws.ToString(in.QuotaCode)])}}, nil
+}
+
+type fakeEC2 struct {
+	EC2API
+	vpcs, addresses int
+}
+
+func (f *fakeEC2) DescribeVpcs(_ context.Context, _ *ec2.DescribeVpcsInput, _ ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+	return &ec2.DescribeVpcsOutput{Vpcs: make([]ec2types.Vpc, f.vpcs)}, nil
+}
+
+func (f *fakeEC2) DescribeAddresses(_ context.Context, _ *ec2.DescribeAddressesInput, _ ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error) {
+	return &ec2.DescribeAddressesOutput{Addresses: make([]ec2types.Address, f.addresses)}, nil
+}
+
+type fakeLambda struct {
+	LambdaAPI
+	limit, unreserved int32
+}
+
+func (f *fakeLambda) GetAccountSettings(_ context.Context, _ *lambda.GetAccountSettingsInput, _ ...func(*lambda.Options)) (*lambda.GetAccountSettingsOutput, error) {
+	return &lambda.GetAccountSettingsOutput{AccountLimit: &lambdatypes.AccountLimit{
+		ConcurrentExecutions:           f.limit,
+		UnreservedConcurrentExecutions: aws.Int32(f.unreserved),
+	}}, nil
+}
+
+// panicS3 panics if the bucket quota, which the plan does not consume, is
+// measured.
+type panicS3 struct {
+	S3API
+}
+
+func TestCheckCountsCreatedResourcesAgainstQuotas(t *testing.T) {
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(sandboxPlan), &plan))
+
+	clients := &Clients{
+		ServiceQuotas: &fakeServiceQuotas{
+			applied:  map[string]float64{"L-0263D0A3": 5},
+			defaults: map[string]float64{"L-F678F1CE": 5},
+		},
+		EC2:    &fakeEC2{vpcs: 2, addresses: 4},
+		S3:     panicS3{},
+		Lambda: &fakeLambda{limit: 100, unreserved: 60},
+	}
+	results, err := clients.Check(context.Background(), &plan)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	vpcs, eips, lambdas := results[0], results[1], results[2]
+	require.Equal(t, "VPCs per Region", vpcs.Quota.Name)
+	require.Equal(t, []string{"aws_vpc.main"}, vpcs.Addresses)
+	require.Equal(t, []float64{5, 2, 1}, []float64{vpcs.Limit, vpcs.Usage, vpcs.Demand})
+	require.False(t, vpcs.Exceeded())
+
+	// A destroy-before-create replacement frees its address first.
+	require.Equal(t, []string{"aws_eip.nat[0]", "aws_eip.nat[1]"}, eips.Addresses)
+	require.True(t, eips.Exceeded())
+	require.Contains(t, eips.String(), "4 in use + 2 planned of 5; the plan needs 1 more, by aws_eip.nat[0], aws_eip.nat[1]")
+
+	require.Equal(t, []string{"aws_lambda_function.api"}, lambdas.Addresses)
+	require.Equal(t, float64(40), lambdas.Usage)
+	require.Equal(t, float64(90), lambdas.Limit)
+	require.False(t, lambdas.Exceeded())
+
+	exceeded := Exceeded(results)
+	require.Len(t, exceeded, 1)
+	require.Equal(t, eips.Quota.Name, exceeded[0].Quota.Name)
+}
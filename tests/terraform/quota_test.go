@@ -0,0 +1,46 @@
+package terraformtests
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/quota"
+)
+
+const quotaEnv = "COMPLIANCE_QUOTAS"
+
+// Before an apply, the VPCs, Elastic IPs, buckets and reserved Lambda
+// concurrency each environment's plan creates must fit in what the account's
+// service quotas leave free, so the apply does not stop halfway with some
+// resources created. It only reads quotas and usage, but needs credentials
+// for the environment's account, so it runs only with COMPLIANCE_QUOTAS=1.
+func TestPlansFitServiceQuotas(t *testing.T) {
+	if os.Getenv(quotaEnv) == "" {
+		t.Skipf("set %s=1 to check the plans against the accounts' service quotas", quotaEnv)
+	}
+	skipWithoutPlanning(t)
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		_, plan := environmentPlan(t, env.Name)
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		clients, err := quota.NewClients(env.Region, creds.AWS())
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		results, err := clients.Check(ctx, plan)
+		require.NoError(t, err)
+		for _, result := range results {
+			if result.Exceeded() {
+				t.Errorf("applying %s would exceed a quota: %s", env.Name, result)
+			} else {
+				t.Log(result)
+			}
+		}
+	})
+}
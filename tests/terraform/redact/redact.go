@@ -0,0 +1,257 @@
+// Package redact produces sanitized copies of terraform plan JSON that can be
+// attached to issues or shared without leaking credentials or account details.
+package redact
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Placeholder replaces values that are sensitive or look like secrets.
+const Placeholder = "REDACTED"
+
+var (
+	secretKeyPattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|private_key|access_key|api_key|credential)`)
+	accountIDPattern = regexp.MustCompile(`\b\d{12}\b`)
+	ipv4Pattern      = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	// ipv6Candidate finds tokens that may be IPv6 addresses, including the
+	// "::" compressed forms; ip validates each one before masking it.
+	ipv6Candidate = regexp.MustCompile(`(?i)[0-9a-f.]*:[0-9a-f:.]*(?:/\d{1,3})?`)
+
+	// nonSecretKeys match secretKeyPattern but hold settings, not secrets.
+	nonSecretKeys = map[string]bool{
+		"http_tokens": true,
+	}
+)
+
+// Redactor masks secrets, account IDs and IP addresses. Replacements are
+// deterministic within one Redactor, so two references to the same account
+// or address still compare equal in the redacted output.
+type Redactor struct {
+	accounts map[string]string
+	ips      map[string]string
+}
+
+// New returns a Redactor with empty replacement tables.
+func New() *Redactor {
+	return &Redactor{
+		accounts: map[string]string{},
+		ips:      map[string]string{},
+	}
+}
+
+// Plan redacts the output of "terraform show -json".
+func Plan(data []byte) ([]byte, error) {
+	return New().Plan(data)
+}
+
+// Plan redacts a plan JSON document and returns the indented result.
+func (r *Redactor) Plan(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("plan is not valid JSON: %w", err)
+	}
+
+	if root, ok := doc.(map[string]interface{}); ok {
+		maskSensitiveVariables(root)
+		maskSensitiveMarkers(root)
+	}
+	doc = r.walk("", doc)
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// maskSensitiveMarkers applies terraform's own sensitivity markers: resource
+// sensitive_values, change before/after_sensitive, sensitive outputs and
+// variables declared sensitive in the configuration.
+func maskSensitiveMarkers(node interface{}) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		pairs := [][2]string{
+			{"values", "sensitive_values"},
+			{"before", "before_sensitive"},
+			{"after", "after_sensitive"},
+		}
+		for _, pair := range pairs {
+			if markers, ok := v[pair[1]]; ok {
+				v[pair[0]] = maskMarked(v[pair[0]], markers)
+			}
+		}
+		if sensitive, _ := v["sensitive"].(bool); sensitive {
+			if _, ok := v["value"]; ok {
+				v["value"] = Placeholder
+			}
+		}
+		for _, child := range v {
+			maskSensitiveMarkers(child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			maskSensitiveMarkers(child)
+		}
+	}
+}
+
+func maskMarked(value, markers interface{}) interface{} {
+	switch m := markers.(type) {
+	case bool:
+		if m && value != nil {
+			return Placeholder
+		}
+	case map[string]interface{}:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		for key, marker := range m {
+			if child, exists := obj[key]; exists {
+				obj[key] = maskMarked(child, marker)
+			}
+		}
+	case []interface{}:
+		list, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+		for i, marker := range m {
+			if i < len(list) {
+				list[i] = maskMarked(list[i], marker)
+			}
+		}
+	}
+	return value
+}
+
+// maskSensitiveVariables masks top-level plan variables whose declaration in
+// configuration.root_module.variables is marked sensitive.
+func maskSensitiveVariables(root map[string]interface{}) {
+	variables, ok := root["variables"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	config, _ := root["configuration"].(map[string]interface{})
+	rootModule, _ := config["root_module"].(map[string]interface{})
+	declared, _ := rootModule["variables"].(map[string]interface{})
+
+	for name, decl := range declared {
+		d, _ := decl.(map[string]interface{})
+		if sensitive, _ := d["sensitive"].(bool); !sensitive {
+			continue
+		}
+		if variable, ok := variables[name].(map[string]interface{}); ok {
+			variable["value"] = Placeholder
+		}
+	}
+}
+
+func (r *Redactor) walk(key string, node interface{}) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		// Visit keys in order so replacement numbering is stable across runs.
+		keys := make([]string, 0, len(v))
+		for childKey := range v {
+			keys = append(keys, childKey)
+		}
+		sort.Strings(keys)
+		for _, childKey := range keys {
+			v[childKey] = r.walk(childKey, v[childKey])
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = r.walk(key, child)
+		}
+		return v
+	case string:
+		if v != "" && v != Placeholder && secretKeyPattern.MatchString(key) && !nonSecretKeys[key] && !isReference(v) {
+			return Placeholder
+		}
+		return r.String(v)
+	}
+	return node
+}
+
+// isReference reports whether a value names another object rather than
+// holding a secret, e.g. a secret ARN in "secret_arn" or a KMS alias.
+func isReference(value string) bool {
+	return strings.HasPrefix(value, "arn:") || strings.HasPrefix(value, "alias/")
+}
+
+// String masks account IDs and IP addresses embedded in a string value,
+// including inside JSON-encoded policy documents.
+func (r *Redactor) String(value string) string {
+	value = accountIDPattern.ReplaceAllStringFunc(value, r.account)
+	value = ipv4Pattern.ReplaceAllStringFunc(value, r.ip)
+	value = ipv6Candidate.ReplaceAllStringFunc(value, r.ipv6)
+	return value
+}
+
+// ipv6 masks a candidate token, leaving any trailing separators such as the
+// colon in "fe80::1: unreachable" outside the address.
+func (r *Redactor) ipv6(token string) string {
+	if strings.IndexByte(token, '/') >= 0 {
+		return r.ip(token)
+	}
+	trimmed := strings.TrimRight(token, ":.")
+	return r.ip(trimmed) + token[len(trimmed):]
+}
+
+func (r *Redactor) account(id string) string {
+	if masked, ok := r.accounts[id]; ok {
+		return masked
+	}
+	masked := fmt.Sprintf("%012d", 100000000000+len(r.accounts)+1)
+	r.accounts[id] = masked
+	return masked
+}
+
+func (r *Redactor) ip(match string) string {
+	addr, suffix := match, ""
+	if idx := strings.IndexByte(match, '/'); idx >= 0 {
+		addr, suffix = match[:idx], match[idx:]
+	}
+
+	parsed, err := netip.ParseAddr(addr)
+	if err != nil || parsed.IsUnspecified() || parsed.IsLoopback() {
+		return match
+	}
+
+	key := parsed.String()
+	if masked, ok := r.ips[key]; ok {
+		return masked + suffix
+	}
+	masked := replacement(parsed.Is4(), len(r.ips)+1).String()
+	r.ips[key] = masked
+	return masked + suffix
+}
+
+// replacement returns the nth masked address. IPv4 addresses come from the
+// documentation range 192.0.2.0/24 (RFC 5737) and, once it is used up, from
+// the reserved 240.0.0.0/4; IPv6 addresses come from 2001:db8::/32 (RFC
+// 3849). Distinct n always give distinct addresses.
+func replacement(v4 bool, n int) netip.Addr {
+	if v4 && n < 255 {
+		return netip.AddrFrom4([4]byte{192, 0, 2, byte(n)})
+	}
+	if v4 && n < 1<<28 {
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], 0xf0000000|uint32(n))
+		return netip.AddrFrom4(b)
+	}
+	b := [16]byte{0x20, 0x01, 0x0d, 0xb8}
+	binary.BigEndian.PutUint64(b[8:], uint64(n))
+	return netip.AddrFrom16(b)
+}
@@ -0,0 +1,111 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const samplePlan = `{
+  "format_version": "1.0",
+  "variables": {
+    "github_token": {"value": "ghp_live_abc"},
+    "aws_region": {"value": "us-east-1"}
+  },
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "aws_secretsmanager_secret_version.jwt",
+          "values": {"secret_string": "hunter2", "secret_id": "arn:aws:secretsmanager:us-east-1:838693051036:secret:jwt"},
+          "sensitive_values": {}
+        },
+        {
+          "address": "aws_db_instance.db",
+          "values": {"master_user": "admin", "endpoint_cfg": {"host": "db.internal"}},
+          "sensitive_values": {"endpoint_cfg": {"host": true}}
+        },
+        {
+          "address": "aws_security_group_rule.ssh",
+          "values": {"cidr_blocks": ["203.0.113.7/32", "0.0.0.0/0"], "http_tokens": "optional"}
+        },
+        {
+          "address": "aws_iam_policy.p",
+          "values": {"policy": "{\"Resource\":\"arn:aws:iam::838693051036:role/x\",\"Principal\":\"arn:aws:iam::210987654321:root\"}"}
+        }
+      ]
+    }
+  },
+  "configuration": {
+    "root_module": {
+      "variables": {
+        "github_token": {"sensitive": true},
+        "aws_region": {}
+      }
+    }
+  }
+}`
+
+func TestPlanRedactsSecretsAccountsAndIPs(t *testing.T) {
+	out, err := Plan([]byte(samplePlan))
+	require.NoError(t, err)
+	text := string(out)
+
+	require.NotContains(t, text, "ghp_live_abc")
+	require.NotContains(t, text, "hunter2")
+	require.NotContains(t, text, "db.internal")
+	require.NotContains(t, text, "838693051036")
+	require.NotContains(t, text, "210987654321")
+	require.NotContains(t, text, "203.0.113.7")
+
+	require.Contains(t, text, "us-east-1")
+	require.Contains(t, text, "0.0.0.0/0")
+	require.Contains(t, text, `"http_tokens": "optional"`)
+	require.Contains(t, text, "192.0.2.1/32")
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(out, &doc), "redacted output must stay valid JSON")
+}
+
+func TestAccountReplacementsAreConsistent(t *testing.T) {
+	r := New()
+	first := r.String("arn:aws:iam::838693051036:role/a")
+	second := r.String("arn:aws:kms:us-east-1:838693051036:key/b")
+	other := r.String("arn:aws:iam::210987654321:root")
+
+	require.True(t, strings.HasPrefix(first, "arn:aws:iam::100000000001:"))
+	require.Contains(t, second, ":100000000001:")
+	require.Contains(t, other, "100000000002")
+}
+
+func TestCompressedIPv6IsMasked(t *testing.T) {
+	r := New()
+	out := r.String(`{"cidr": "2600:1f18:abcd::/56", "peer": "fe80::1", "full": "2600:1f18:abcd:0:0:0:0:0"}`)
+
+	require.NotContains(t, out, "2600:1f18")
+	require.NotContains(t, out, "fe80")
+	require.Contains(t, out, `"2001:db8::1/56"`)
+	require.Contains(t, out, `"2001:db8::1"`, "the expanded form is the same address")
+	require.Contains(t, out, `"2001:db8::2"`)
+
+	require.Equal(t, "::1 and ::", r.String("::1 and ::"))
+	require.Equal(t, "12:30:45", r.String("12:30:45"))
+	require.Equal(t, "arn:aws:s3:::bucket", r.String("arn:aws:s3:::bucket"))
+}
+
+func TestIPReplacementsDoNotWrap(t *testing.T) {
+	r := New()
+	seen := map[string]string{}
+	for i := 0; i < 600; i++ {
+		addr := fmt.Sprintf("10.0.%d.%d", i/250, i%250+1)
+		masked := r.String(addr)
+		require.NotEqual(t, addr, masked)
+		if previous, ok := seen[masked]; ok {
+			t.Fatalf("%s and %s both masked as %s", previous, addr, masked)
+		}
+		seen[masked] = addr
+	}
+}
@@ -0,0 +1,265 @@
+// Package restore proves a deployed table's backups can be restored: it
+// writes a sentinel item, takes an on-demand backup, restores the backup to
+// a temporary table, reads the sentinel back and removes everything it
+// created. It writes to real tables: run it only against sandboxes.
+package restore
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/awsapi"
+	"cs450/terraformtests/plancheck"
+)
+
+// SentinelAttribute is the attribute holding the sentinel's nonce.
+const SentinelAttribute = "restore_sentinel"
+
+// DynamoDBAPI writes the sentinel, takes and restores the backup, and
+// removes both.
+type DynamoDBAPI interface {
+	DescribeTable(context.Context, *dynamodb.DescribeTableInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+	PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	CreateBackup(context.Context, *dynamodb.CreateBackupInput, ...func(*dynamodb.Options)) (*dynamodb.CreateBackupOutput, error)
+	DescribeBackup(context.Context, *dynamodb.DescribeBackupInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeBackupOutput, error)
+	DeleteBackup(context.Context, *dynamodb.DeleteBackupInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteBackupOutput, error)
+	RestoreTableFromBackup(context.Context, *dynamodb.RestoreTableFromBackupInput, ...func(*dynamodb.Options)) (*dynamodb.RestoreTableFromBackupOutput, error)
+	DeleteTable(context.Context, *dynamodb.DeleteTableInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error)
+}
+
+// Clients are the AWS APIs backups are taken and restored through.
+type Clients struct {
+	DynamoDB DynamoDBAPI
+
+	// PollInterval is how long to wait between checks on a backup or a
+	// restoring table. Zero means ten seconds.
+	PollInterval time.Duration
+
+	// Now returns the time used to name backups and restored tables. Nil
+	// means time.Now.
+	Now func() time.Time
+}
+
+// NewClients returns a DynamoDB client for region that backs up and restores
+// tables with creds, or with the default awsapi factory's credentials.
+func NewClients(region string, creds *credentials.Credentials) (*Clients, error) {
+	cfg, err := awsapi.Default().WithCredentials(creds).Config(context.Background(), region)
+	if err != nil {
+		return nil, fmt.Errorf("restore: %w", err)
+	}
+	return &Clients{DynamoDB: dynamodb.NewFromConfig(cfg)}, nil
+}
+
+func (c *Clients) pollInterval() time.Duration {
+	if c.PollInterval == 0 {
+		return 10 * time.Second
+	}
+	return c.PollInterval
+}
+
+func (c *Clients) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+// Table is a planned DynamoDB table.
+type Table struct {
+	Address string
+	Name    string
+}
+
+// Tables returns the DynamoDB tables of a plan whose names match one of
+// patterns, or every table when patterns is empty.
+func Tables(plan *tfjson.Plan, patterns []string) []Table {
+	var tables []Table
+	for _, resource := range plancheck.Resources(plan, "aws_dynamodb_table") {
+		name := plancheck.LookupString(resource.AttributeValues, "name")
+		if name == "" {
+			continue
+		}
+		if len(patterns) > 0 && !matchAny(patterns, name) {
+			continue
+		}
+		tables = append(tables, Table{Address: resource.Address, Name: name})
+	}
+	return tables
+}
+
+// Result describes one verified restore.
+type Result struct {
+	Table         string
+	BackupARN     string
+	RestoredTable string
+	// Duration is how long the backup and restore took, sentinel to
+	// sentinel.
+	Duration time.Duration
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%s restored from %s as %s in %s", r.Table, r.BackupARN, r.RestoredTable, r.Duration.Round(time.Second))
+}
+
+// Verify backs up table, restores the backup to a temporary table and
+// checks a sentinel item written just before the backup came back. The
+// sentinel, the backup and the restored table are removed whether or not
+// the check passes; errors removing them are returned with the check's.
+func (c *Clients) Verify(ctx context.Context, table string) (result Result, err error) {
+	result.Table = table
+	started := c.now()
+	stamp := started.UTC().Format("20060102150405")
+	nonce := strconv.FormatInt(started.UnixNano(), 10)
+
+	described, err := c.DynamoDB.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err != nil {
+		return result, fmt.Errorf("restore: describing %s: %w", table, err)
+	}
+	key, err := sentinelKey(described.Table, nonce)
+	if err != nil {
+		return result, fmt.Errorf("restore: %s: %w", table, err)
+	}
+	item := map[string]types.AttributeValue{SentinelAttribute: &types.AttributeValueMemberS{Value: nonce}}
+	for name, value := range key {
+		item[name] = value
+	}
+
+	// Cleanup runs on a fresh context, so a timed-out check still removes
+	// what it created.
+	var cleanups []func(context.Context) error
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			if cleanupErr := cleanups[i](cleanupCtx); cleanupErr != nil && err == nil {
+				err = fmt.Errorf("restore: cleaning up after %s: %w", table, cleanupErr)
+			}
+		}
+	}()
+
+	if _, err := c.DynamoDB.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String(table), Item: item}); err != nil {
+		return result, fmt.Errorf("restore: writing the sentinel to %s: %w", table, err)
+	}
+	cleanups = append(cleanups, func(ctx context.Context) error {
+		_, err := c.DynamoDB.DeleteItem(ctx, &dynamodb.DeleteItemInput{TableName: aws.String(table), Key: key})
+		return err
+	})
+
+	backup, err := c.DynamoDB.CreateBackup(ctx, &dynamodb.CreateBackupInput{
+		TableName:  aws.String(table),
+		BackupName: aws.String(truncate(table+"-restore-check-"+stamp, 255)),
+	})
+	if err != nil {
+		return result, fmt.Errorf("restore: backing up %s: %w", table, err)
+	}
+	result.BackupARN = aws.ToString(backup.BackupDetails.BackupArn)
+	cleanups = append(cleanups, func(ctx context.Context) error {
+		_, err := c.DynamoDB.DeleteBackup(ctx, &dynamodb.DeleteBackupInput{BackupArn: aws.String(result.BackupARN)})
+		return err
+	})
+	if err := awsapi.Poll(ctx, c.pollInterval(), "backup "+result.BackupARN, func() (bool, error) {
+		out, err := c.DynamoDB.DescribeBackup(ctx, &dynamodb.DescribeBackupInput{BackupArn: aws.String(result.BackupARN)})
+		if err != nil {
+			return false, err
+		}
+		status := out.BackupDescription.BackupDetails.BackupStatus
+		if status == types.BackupStatusDeleted {
+			return false, fmt.Errorf("backup was deleted before it was restored")
+		}
+		return status == types.BackupStatusAvailable, nil
+	}); err != nil {
+		return result, fmt.Errorf("restore: %s: %w", table, err)
+	}
+
+	result.RestoredTable = truncate(table, 255-len("-restore-"+stamp)) + "-restore-" + stamp
+	if _, err := c.DynamoDB.RestoreTableFromBackup(ctx, &dynamodb.RestoreTableFromBackupInput{
+		BackupArn:       aws.String(result.BackupARN),
+		TargetTableName: aws.String(result.RestoredTable),
+		// The copy only has to hold the sentinel until it is read back.
+		BillingModeOverride: types.BillingModePayPerRequest,
+	}); err != nil {
+		return result, fmt.Errorf("restore: restoring %s: %w", result.BackupARN, err)
+	}
+	cleanups = append(cleanups, func(ctx context.Context) error {
+		_, err := c.DynamoDB.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(result.RestoredTable)})
+		return err
+	})
+	if err := awsapi.Poll(ctx, c.pollInterval(), "table "+result.RestoredTable, func() (bool, error) {
+		out, err := c.DynamoDB.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(result.RestoredTable)})
+		if err != nil {
+			return false, err
+		}
+		return out.Table.TableStatus == types.TableStatusActive, nil
+	}); err != nil {
+		return result, fmt.Errorf("restore: %s: %w", table, err)
+	}
+
+	got, err := c.DynamoDB.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(result.RestoredTable),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return result, fmt.Errorf("restore: reading the sentinel from %s: %w", result.RestoredTable, err)
+	}
+	if value, ok := got.Item[SentinelAttribute].(*types.AttributeValueMemberS); !ok || value.Value != nonce {
+		return result, fmt.Errorf("restore: %s does not hold the sentinel written to %s before the backup", result.RestoredTable, table)
+	}
+	result.Duration = c.now().Sub(started)
+	return result, nil
+}
+
+// sentinelKey returns a primary key for the sentinel item, built from the
+// table's key schema so that it cannot collide with application data.
+func sentinelKey(table *types.TableDescription, nonce string) (map[string]types.AttributeValue, error) {
+	attributeTypes := map[string]types.ScalarAttributeType{}
+	for _, definition := range table.AttributeDefinitions {
+		attributeTypes[aws.ToString(definition.AttributeName)] = definition.AttributeType
+	}
+	key := map[string]types.AttributeValue{}
+	for _, element := range table.KeySchema {
+		name := aws.ToString(element.AttributeName)
+		switch attributeTypes[name] {
+		case types.ScalarAttributeTypeS:
+			key[name] = &types.AttributeValueMemberS{Value: SentinelAttribute + "-" + nonce}
+		case types.ScalarAttributeTypeN:
+			// Negative, so it sorts away from counters and timestamps.
+			key[name] = &types.AttributeValueMemberN{Value: "-" + nonce}
+		case types.ScalarAttributeTypeB:
+			key[name] = &types.AttributeValueMemberB{Value: []byte(SentinelAttribute + "-" + nonce)}
+		default:
+			return nil, fmt.Errorf("key attribute %s has unknown type %q", name, attributeTypes[name])
+		}
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("table has no key schema")
+	}
+	return key, nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,192 @@
+package restore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDynamoDB keeps items per table. A backup copies its table's items when
+// it is taken and becomes available, and a restored table active, on the
+// second describe.
+type fakeDynamoDB struct {
+	DynamoDBAPI
+	tables   map[string]map[string]map[string]types.AttributeValue
+	backups  map[string]map[string]map[string]types.AttributeValue
+	polls    map[string]int
+	loseData bool
+	deleted  []string
+}
+
+func newFake() *fakeDynamoDB {
+	return &fakeDynamoDB{
+		tables:  map[string]map[string]map[string]types.AttributeValue{"orders": {}},
+		backups: map[string]map[string]map[string]types.AttributeValue{},
+		polls:   map[string]int{},
+	}
+}
+
+func itemKey(key map[string]types.AttributeValue) string {
+	return key["order_id"].(*types.AttributeValueMemberS).Value + "/" + key["created_at"].(*types.AttributeValueMemberN).Value
+}
+
+func (f *fakeDynamoDB) DescribeTable(_ context.Context, in *dynamodb.DescribeTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	name := aws.ToString(in.TableName)
+	if _, ok := f.tables[name]; !ok {
+		return nil, errors.New("ResourceNotFoundException: " + name)
+	}
+	f.polls[name]++
+	status := types.TableStatusActive
+	if name != "orders" && f.polls[name] < 2 {
+		status = types.TableStatusCreating
+	}
+	return &dynamodb.DescribeTableOutput{Table: &types.TableDescription{
+		TableName:   in.TableName,
+		TableStatus: status,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("order_id"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("created_at"), AttributeType: types.ScalarAttributeTypeN},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("order_id"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("created_at"), KeyType: types.KeyTypeRange},
+		},
+	}}, nil
+}
+
+func (f *fakeDynamoDB) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.tables[aws.ToString(in.TableName)][itemKey(in.Item)] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) DeleteItem(_ context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	delete(f.tables[aws.ToString(in.TableName)], itemKey(in.Key))
+	f.deleted = append(f.deleted, "item "+aws.ToString(in.TableName))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) GetItem(_ context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: f.tables[aws.ToString(in.TableName)][itemKey(in.Key)]}, nil
+}
+
+func (f *fakeDynamoDB) CreateBackup(_ context.Context, in *dynamodb.CreateBackupInput, _ ...func(*dynamodb.Options)) (*dynamodb.CreateBackupOutput, error) {
+	arn := "arn:aws:dynamodb:us-east-1:123456789012:table/orders/backup/" + aws.ToString(in.BackupName)
+	items := map[string]map[string]types.AttributeValue{}
+	if !f.loseData {
+		for key, item := range f.tables[aws.ToString(in.TableName)] {
+			items[key] = item
+		}
+	}
+	f.backups[arn] = items
+	return &dynamodb.CreateBackupOutput{BackupDetails: &types.BackupDetails{BackupArn: aws.String(arn), BackupStatus: types.BackupStatusCreating}}, nil
+}
+
+func (f *fakeDynamoDB) DescribeBackup(_ context.Context, in *dynamodb.DescribeBackupInput, _ ...func(*dynamodb.Options)) (*dynamodb.DescribeBackupOutput, error) {
+	arn := aws.ToString(in.BackupArn)
+	f.polls[arn]++
+	status := types.BackupStatusCreating
+	if f.polls[arn] >= 2 {
+		status = types.BackupStatusAvailable
+	}
+	return &dynamodb.DescribeBackupOutput{BackupDescription: &types.BackupDescription{
+		BackupDetails: &types.BackupDetails{BackupArn: in.BackupArn, BackupStatus: status},
+	}}, nil
+}
+
+func (f *fakeDynamoDB) RestoreTableFromBackup(_ context.Context, in *dynamodb.RestoreTableFromBackupInput, _ ...func(*dynamodb.Options)) (*dynamodb.RestoreTableFromBackupOutput, error) {
+	f.tables[aws.ToString(in.TargetTableName)] = f.backups[aws.ToString(in.BackupArn)]
+	return &dynamodb.RestoreTableFromBackupOutput{}, nil
+}
+
+func (f *fakeDynamoDB) DeleteTable(_ context.Context, in *dynamodb.DeleteTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error) {
+	delete(f.tables, aws.ToString(in.TableName))
+	f.deleted = append(f.deleted, "table "+aws.ToString(in.TableName))
+	return &dynamodb.DeleteTableOutput{}, nil
+}
+
+func (f *fakeDynamoDB) DeleteBackup(_ context.Context, in *dynamodb.DeleteBackupInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteBackupOutput, error) {
+	delete(f.backups, aws.ToString(in.BackupArn))
+	f.deleted = append(f.deleted, "backup")
+	return &dynamodb.DeleteBackupOutput{}, nil
+}
+
+func clientsFor(fake *fakeDynamoDB) *Clients {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return &Clients{
+		DynamoDB:     fake,
+		PollInterval: time.Millisecond,
+		Now: func() time.Time {
+			now = now.Add(time.Minute)
+			return now
+		},
+	}
+}
+
+func TestVerifyRestoresSentinelAndCleansUp(t *testing.T) {
+	fake := newFake()
+	fake.tables["orders"]["existing/1"] = map[string]types.AttributeValue{"order_id": &types.AttributeValueMemberS{Value: "existing"}, "created_at": &types.AttributeValueMemberN{Value: "1"}}
+
+	result, err := clientsFor(fake).Verify(context.Background(), "orders")
+	require.NoError(t, err)
+	require.Equal(t, "orders", result.Table)
+	require.Equal(t, "orders-restore-20240501120100", result.RestoredTable)
+	require.Equal(t, "arn:aws:dynamodb:us-east-1:123456789012:table/orders/backup/orders-restore-check-20240501120100", result.BackupARN)
+	require.Equal(t, time.Minute, result.Duration)
+
+	require.Equal(t, []string{"table orders-restore-20240501120100", "backup", "item orders"}, fake.deleted, "cleanup runs in reverse")
+	require.Len(t, fake.tables, 1)
+	require.Len(t, fake.tables["orders"], 1, "only the application's item is left")
+	require.Empty(t, fake.backups)
+}
+
+func TestVerifyFailsWhenBackupMissesSentinel(t *testing.T) {
+	fake := newFake()
+	fake.loseData = true
+
+	_, err := clientsFor(fake).Verify(context.Background(), "orders")
+	require.EqualError(t, err, "restore: orders-restore-20240501120100 does not hold the sentinel written to orders before the backup")
+	require.Len(t, fake.deleted, 3, "a failed check still cleans up")
+	require.Empty(t, fake.tables["orders"])
+}
+
+func TestVerifyGivesUpAndCleansUpOnTimeout(t *testing.T) {
+	fake := newFake()
+	clients := clientsFor(fake)
+	clients.PollInterval = 50 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := clients.Verify(ctx, "orders")
+	require.ErrorContains(t, err, "restore: orders: gave up waiting for backup arn:aws:dynamodb:us-east-1:123456789012:table/orders/backup/orders-restore-check-20240501120100")
+	require.Equal(t, []string{"backup", "item orders"}, fake.deleted)
+}
+
+func TestVerifyReportsMissingTable(t *testing.T) {
+	_, err := clientsFor(newFake()).Verify(context.Background(), "missing")
+	require.EqualError(t, err, "restore: describing missing: ResourceNotFoundException: missing")
+}
+
+func TestTablesFiltersByPattern(t *testing.T) {
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(`{
+	  "format_version": "1.2",
+	  "planned_values": {"root_module": {"child_modules": [{"address": "module.dynamodb", "resources": [
+	    {"address": "module.dynamodb.aws_dynamodb_table.this[\"orders\"]", "type": "aws_dynamodb_table", "name": "this", "values": {"name": "orders"}},
+	    {"address": "module.dynamodb.aws_dynamodb_table.this[\"sessions\"]", "type": "aws_dynamodb_table", "name": "this", "values": {"name": "sessions"}}
+	  ]}]}}
+	}`), &plan))
+
+	require.Equal(t, []Table{
+		{Address: `module.dynamodb.aws_dynamodb_table.this["orders"]`, Name: "orders"},
+		{Address: `module.dynamodb.aws_dynamodb_table.this["sessions"]`, Name: "sessions"},
+	}, Tables(&plan, nil))
+	require.Equal(t, []Table{{Address: `module.dynamodb.aws_dynamodb_table.this["orders"]`, Name: "orders"}}, Tables(&plan, []string{"ord*"}))
+}
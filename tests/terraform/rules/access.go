@@ -0,0 +1,107 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:          "access.external",
+		Description: "Resources the stack manages must have no active IAM Access Analyzer findings granting access from outside the account.",
+		Remediation: "Remove the external or public grant from the resource policy, or, if the access is intended, archive the finding with an Access Analyzer archive rule.",
+		Check:       checkExternalAccess,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "iam.policy-validation",
+		Description:   "Planned IAM policy documents must have no ERROR or SECURITY_WARNING findings from IAM Access Analyzer's policy validation.",
+		Remediation:   "Fix the statement the finding points at; its learn-more link describes the issue code. Access Analyzer's WARNING and SUGGESTION findings are not reported.",
+		Rationale:     "The wildcard rules only look for \"*\". Access Analyzer also knows every service's actions, resource ARN formats and condition keys, so it catches misspelled actions, malformed ARNs, conditions that never match and grants such as iam:PassRole on any role.",
+		ResourceTypes: iamPolicyTypes,
+		Check:         checkPolicyValidation,
+	})
+}
+
+// blockingValidations are the ValidatePolicy finding types that fail a run.
+var blockingValidations = []string{"ERROR", "SECURITY_WARNING"}
+
+// checkPolicyValidation reports the validations the caller ran on the plan's
+// policy documents, and nothing when it did not run them.
+func checkPolicyValidation(in *plancheck.Input) []plancheck.Finding {
+	if in.Runtime == nil || len(in.Runtime.PolicyValidations) == 0 {
+		return nil
+	}
+	planned := map[string]bool{}
+	for _, document := range plancheck.Policies(in.Plan) {
+		planned[document.Address+" "+document.Path] = true
+	}
+
+	var findings []plancheck.Finding
+	for _, validation := range in.Runtime.PolicyValidations {
+		if !contains(blockingValidations, validation.Type) || !planned[validation.Address+" "+validation.Path] {
+			continue
+		}
+		path := validation.Path
+		if validation.Location != "" {
+			path += "." + validation.Location
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"iam.policy-validation",
+			validation.Address,
+			fmt.Sprintf("IAM policy %s: Access Analyzer %s %s: %s", validation.Address, validation.Type, validation.IssueCode, validation.Details),
+		).WithPath(path).WithEvidence(validation))
+	}
+	return findings
+}
+
+func checkExternalAccess(in *plancheck.Input) []plancheck.Finding {
+	if in.Runtime == nil || len(in.Runtime.AccessFindings) == 0 {
+		return nil
+	}
+
+	arns := plancheck.ResourceARNs(in)
+	var findings []plancheck.Finding
+	for _, access := range in.Runtime.AccessFindings {
+		if access.Status != "ACTIVE" {
+			continue
+		}
+		address, ok := arns[access.Resource]
+		if !ok {
+			continue
+		}
+
+		who := "anyone"
+		if !access.IsPublic {
+			who = formatPrincipal(access.Principal)
+		}
+		actions := "act on it"
+		if len(access.Action) > 0 {
+			actions = strings.Join(access.Action, ", ")
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"access.external",
+			address,
+			fmt.Sprintf("%s is reachable from outside the account: %s may %s (Access Analyzer finding %s)", address, who, actions, access.ID),
+		).WithEvidence(access))
+	}
+	return findings
+}
+
+func formatPrincipal(principal map[string]string) string {
+	if len(principal) == 0 {
+		return "an external principal"
+	}
+	keys := make([]string, 0, len(principal))
+	for key := range principal {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+" "+principal[key])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,100 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "account.baseline",
+		Description:   "Account-wide settings must block public S3 access, encrypt new EBS volumes, enforce a strong IAM password policy and leave default security groups without rules.",
+		Remediation:   "Enable all four S3 account public access block settings and EBS encryption by default, set an IAM password policy meeting the account settings in compliance.yaml, and remove every rule from the default security groups.",
+		ResourceTypes: []string{"aws_s3_account_public_access_block", "aws_ebs_encryption_by_default", "aws_iam_account_password_policy", "aws_default_security_group"},
+		Check:         checkAccountBaseline,
+	})
+}
+
+var publicAccessBlockSettings = []string{"block_public_acls", "block_public_policy", "ignore_public_acls", "restrict_public_buckets"}
+
+var passwordCharacterClasses = []string{"require_lowercase_characters", "require_uppercase_characters", "require_numbers", "require_symbols"}
+
+func checkAccountBaseline(in *plancheck.Input) []plancheck.Finding {
+	var policy plancheck.AccountPolicy
+	if in.Config != nil {
+		policy = in.Config.Account
+	}
+
+	var findings []plancheck.Finding
+	for _, block := range plancheck.Resources(in.Plan, "aws_s3_account_public_access_block") {
+		if off := unblocked(block); len(off) > 0 {
+			findings = append(findings, plancheck.NewFinding(
+				"account.baseline",
+				block.Address,
+				fmt.Sprintf("the S3 account public access block does not set %s", strings.Join(off, ", ")),
+			).WithPath(off[0]))
+		}
+	}
+
+	for _, ebs := range plancheck.Resources(in.Plan, "aws_ebs_encryption_by_default") {
+		if !plancheck.LookupBool(ebs.AttributeValues, "enabled") {
+			findings = append(findings, plancheck.NewFinding(
+				"account.baseline",
+				ebs.Address,
+				"EBS encryption by default is disabled, so new volumes are created unencrypted",
+			).WithPath("enabled").WithFix(plancheck.Fix{Attribute: "enabled", Value: true}))
+		}
+	}
+
+	for _, passwords := range plancheck.Resources(in.Plan, "aws_iam_account_password_policy") {
+		length, ok := plancheck.LookupNumber(passwords.AttributeValues, "minimum_password_length")
+		if !ok {
+			findings = append(findings, plancheck.NewFinding(
+				"account.baseline",
+				passwords.Address,
+				"the account has no IAM password policy",
+			))
+			continue
+		}
+		var problems []string
+		if int(length) < policy.MinLength() {
+			problems = append(problems, fmt.Sprintf("allows passwords of %d characters (minimum %d)", int(length), policy.MinLength()))
+		}
+		if reuse, _ := plancheck.LookupNumber(passwords.AttributeValues, "password_reuse_prevention"); int(reuse) < policy.ReusePrevention() {
+			problems = append(problems, fmt.Sprintf("remembers %d previous passwords (minimum %d)", int(reuse), policy.ReusePrevention()))
+		}
+		for _, class := range passwordCharacterClasses {
+			if !plancheck.LookupBool(passwords.AttributeValues, class) {
+				problems = append(problems, "does not set "+class)
+			}
+		}
+		if len(problems) > 0 {
+			findings = append(findings, plancheck.NewFinding(
+				"account.baseline",
+				passwords.Address,
+				"the IAM password policy "+strings.Join(problems, ", "),
+			).WithPath("minimum_password_length"))
+		}
+	}
+
+	for _, group := range plancheck.Resources(in.Plan, "aws_default_security_group") {
+		ingress := len(plancheck.Blocks(group.AttributeValues, "ingress"))
+		egress := len(plancheck.Blocks(group.AttributeValues, "egress"))
+		if ingress+egress == 0 {
+			continue
+		}
+		path := "ingress"
+		if ingress == 0 {
+			path = "egress"
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"account.baseline",
+			group.Address,
+			fmt.Sprintf("the default security group of %s has %d ingress and %d egress rules; it must have none",
+				plancheck.LookupString(group.AttributeValues, "vpc_id"), ingress, egress),
+		).WithPath(path))
+	}
+	return findings
+}
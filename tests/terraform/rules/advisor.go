@@ -0,0 +1,186 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "iam.unused-services",
+		Description:   "Roles should not keep permissions for services IAM Access Advisor reports as unused.",
+		Remediation:   "Remove the listed actions from the role's policies, or keep the grant with a waiver if the service is used only rarely, e.g. for disaster recovery.",
+		Rationale:     "Permissions a role never exercises add nothing but blast radius: a compromised function can still use them, and they are easiest to remove while the role's owner remembers why they were added.",
+		Severity:      plancheck.SeverityAdvisory,
+		ResourceTypes: []string{"aws_iam_role"},
+		Check:         checkUnusedServices,
+	})
+}
+
+// unusedServices is the evidence of an iam.unused-services finding: the
+// unused services and, for each policy of the role in the plan, the actions
+// to remove.
+type unusedServices struct {
+	Days     int                       `json:"days"`
+	Services []plancheck.ServiceAccess `json:"services"`
+	Remove   map[string][]string       `json:"remove,omitempty"`
+}
+
+func checkUnusedServices(in *plancheck.Input) []plancheck.Finding {
+	if in.Runtime == nil || len(in.Runtime.ServiceAccess) == 0 {
+		return nil
+	}
+	days := plancheck.DefaultUnusedServiceDays
+	if in.Config != nil {
+		days = in.Config.IAM.UnusedDays()
+	}
+	cutoff := in.Runtime.ReadAt.AddDate(0, 0, -days)
+
+	var findings []plancheck.Finding
+	for _, role := range plancheck.PlannedResources(in.Plan) {
+		if role == nil || role.Type != "aws_iam_role" {
+			continue
+		}
+		access, ok := roleServiceAccess(in.Runtime.ServiceAccess, role)
+		if !ok {
+			continue
+		}
+
+		var unused []plancheck.ServiceAccess
+		namespaces := map[string]bool{}
+		for _, service := range access {
+			if service.LastAccessed != nil && service.LastAccessed.After(cutoff) {
+				continue
+			}
+			unused = append(unused, service)
+			namespaces[strings.ToLower(service.Namespace)] = true
+		}
+		if len(unused) == 0 {
+			continue
+		}
+
+		evidence := unusedServices{Days: days, Services: unused, Remove: map[string][]string{}}
+		for _, document := range rolePolicies(in, plancheck.ConfigAddress(role.Address)) {
+			for _, action := range unusedActions(document.Policy, namespaces) {
+				if !contains(evidence.Remove[document.Address], action) {
+					evidence.Remove[document.Address] = append(evidence.Remove[document.Address], action)
+				}
+			}
+		}
+		for _, actions := range evidence.Remove {
+			sort.Strings(actions)
+		}
+
+		findings = append(findings, plancheck.NewFinding(
+			"iam.unused-services",
+			role.Address,
+			fmt.Sprintf("role %s has not used %s in the last %d days%s", role.Address, describeUnused(unused), days, describeRemovals(evidence.Remove)),
+		).WithEvidence(evidence))
+	}
+	return findings
+}
+
+// roleServiceAccess finds the Access Advisor report of a planned role, by
+// its ARN or, while the plan does not know the ARN, by its name.
+func roleServiceAccess(reports map[string][]plancheck.ServiceAccess, role *tfjson.StateResource) ([]plancheck.ServiceAccess, bool) {
+	if arn := plancheck.LookupString(role.AttributeValues, "arn"); arn != "" {
+		access, ok := reports[arn]
+		return access, ok
+	}
+	name := plancheck.LookupString(role.AttributeValues, "name")
+	if name == "" {
+		return nil, false
+	}
+	for arn, access := range reports {
+		if strings.Contains(arn, ":role/") && strings.HasSuffix(arn, "/"+name) {
+			return access, true
+		}
+	}
+	return nil, false
+}
+
+// unusedActions returns the actions a policy allows in the given service
+// namespaces, plus "*", which grants them all.
+func unusedActions(policy string, namespaces map[string]bool) []string {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	var actions []string
+	for _, statement := range plancheck.Statements(doc) {
+		if effect, _ := statement.Fields["Effect"].(string); effect != "Allow" {
+			continue
+		}
+		for _, action := range policyStrings(statement.Fields["Action"]) {
+			namespace := strings.ToLower(strings.SplitN(action, ":", 2)[0])
+			if action != "*" && !namespaces[namespace] {
+				continue
+			}
+			if !seen[action] {
+				seen[action] = true
+				actions = append(actions, action)
+			}
+		}
+	}
+	return actions
+}
+
+// policyStrings returns a policy field that holds a string or a list of
+// strings as a list.
+func policyStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, entry := range v {
+			if s, ok := entry.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func describeUnused(unused []plancheck.ServiceAccess) string {
+	parts := make([]string, 0, len(unused))
+	for _, service := range unused {
+		if service.LastAccessed == nil {
+			parts = append(parts, service.Namespace+" (never used)")
+		} else {
+			parts = append(parts, fmt.Sprintf("%s (last used %s)", service.Namespace, service.LastAccessed.Format(time.DateOnly)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describeRemovals(remove map[string][]string) string {
+	if len(remove) == 0 {
+		return ""
+	}
+	addresses := make([]string, 0, len(remove))
+	for address := range remove {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	parts := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		actions := remove[address]
+		if contains(actions, "*") {
+			parts = append(parts, fmt.Sprintf("replace \"*\" in %s with the actions of the services it uses", address))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("remove %s from %s", strings.Join(actions, ", "), address))
+	}
+	return "; " + strings.Join(parts, "; ")
+}
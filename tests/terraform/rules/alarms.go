@@ -0,0 +1,278 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// metricTarget says how CloudWatch metrics name a resource of some type: by
+// the dimension holding the resource's name attribute.
+type metricTarget struct {
+	dimension string
+	attribute string
+}
+
+// alarmTargets are the critical resource types alarms.coverage understands.
+var alarmTargets = map[string]metricTarget{
+	"aws_lambda_function":   {"FunctionName", "function_name"},
+	"aws_api_gateway_stage": {"Stage", "stage_name"},
+	"aws_dynamodb_table":    {"TableName", "name"},
+	"aws_db_instance":       {"DBInstanceIdentifier", "identifier"},
+	"aws_sqs_queue":         {"QueueName", "name"},
+}
+
+func init() {
+	types := make([]string, 0, len(alarmTargets)+1)
+	for resourceType := range alarmTargets {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+
+	plancheck.Register(plancheck.Rule{
+		ID:            "alarms.coverage",
+		Description:   "Lambda functions, API stages, DynamoDB tables, RDS instances and SQS dead-letter queues must have CloudWatch alarms on the metrics the environment or alarms.metrics_file requires.",
+		Remediation:   "Add an aws_cloudwatch_metric_alarm on each missing metric whose dimensions reference the resource.",
+		ResourceTypes: append(types, "aws_cloudwatch_metric_alarm"),
+		Check:         checkAlarmCoverage,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "alarms.actions",
+		Description:   "CloudWatch alarms in monitored environments must notify an SNS topic in the plan that has a subscription, and production alarms must keep their actions enabled.",
+		Remediation:   "Point alarm_actions at an aws_sns_topic with an aws_sns_topic_subscription, and leave actions_enabled at true in production.",
+		ResourceTypes: []string{"aws_cloudwatch_metric_alarm", "aws_sns_topic", "aws_sns_topic_subscription"},
+		Check:         checkAlarmActions,
+	})
+}
+
+// alarmMetric is one metric an alarm watches, directly or in a metric query.
+type alarmMetric struct {
+	address    string
+	name       string
+	dimensions map[string]interface{}
+	refs       []string
+}
+
+func checkAlarmCoverage(in *plancheck.Input) []plancheck.Finding {
+	required := in.Config.RequiredAlarms(in.Environment)
+	if len(required) == 0 {
+		return nil
+	}
+	metrics := alarmMetrics(in)
+	deadLetterQueues := deadLetterQueues(in)
+
+	types := make([]string, 0, len(required))
+	for resourceType := range required {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+
+	var findings []plancheck.Finding
+	for _, resourceType := range types {
+		target, ok := alarmTargets[resourceType]
+		if !ok {
+			continue
+		}
+		for _, resource := range plancheck.Resources(in.Plan, resourceType) {
+			if resource.Mode == tfjson.DataResourceMode {
+				continue
+			}
+			name := plancheck.LookupString(resource.AttributeValues, target.attribute)
+			if resourceType == "aws_sqs_queue" && !deadLetterQueues[name] {
+				continue
+			}
+
+			expected := alarmDimensions(in, resource, target)
+			watched := map[string]bool{}
+			for _, metric := range metrics {
+				if metric.watches(expected) {
+					watched[metric.name] = true
+				}
+			}
+
+			if len(watched) == 0 {
+				findings = append(findings, plancheck.NewFinding(
+					"alarms.coverage",
+					resource.Address,
+					fmt.Sprintf("%s has no CloudWatch alarm", resource.Address),
+				))
+				continue
+			}
+			var missing []string
+			for _, metric := range required[resourceType] {
+				if !watched[metric] {
+					missing = append(missing, metric)
+				}
+			}
+			if len(missing) > 0 {
+				findings = append(findings, plancheck.NewFinding(
+					"alarms.coverage",
+					resource.Address,
+					fmt.Sprintf("%s has no CloudWatch alarm on %s", resource.Address, strings.Join(missing, ", ")),
+				))
+			}
+		}
+	}
+	return findings
+}
+
+// dimensionValue is the value a metric dimension must have to name a
+// resource, and the configuration address of the resource providing it, for
+// alarms that reference the resource instead of repeating its name.
+type dimensionValue struct {
+	value  string
+	source string
+}
+
+// alarmDimensions returns the dimensions naming resource. API Gateway stage
+// metrics are named by the API and the stage together, since stage names such
+// as "prod" repeat across APIs.
+func alarmDimensions(in *plancheck.Input, resource *tfjson.StateResource, target metricTarget) map[string]dimensionValue {
+	dimensions := map[string]dimensionValue{
+		target.dimension: {plancheck.LookupString(resource.AttributeValues, target.attribute), plancheck.ConfigAddress(resource.Address)},
+	}
+	if resource.Type != "aws_api_gateway_stage" {
+		return dimensions
+	}
+	for _, ref := range in.References(resource.Address, "rest_api_id") {
+		for _, api := range plancheck.Resources(in.Plan, "aws_api_gateway_rest_api") {
+			if plancheck.ConfigAddress(api.Address) == ref {
+				dimensions["ApiName"] = dimensionValue{plancheck.LookupString(api.AttributeValues, "name"), ref}
+			}
+		}
+	}
+	return dimensions
+}
+
+// watches reports whether every expected dimension is set to the resource's
+// value, or refers to the resource providing it.
+func (m alarmMetric) watches(expected map[string]dimensionValue) bool {
+	for dimension, want := range expected {
+		if !(want.value != "" && m.dimensions[dimension] == want.value) && !contains(m.refs, want.source) {
+			return false
+		}
+	}
+	return true
+}
+
+// alarmMetrics returns the metrics watched by every alarm in the plan.
+func alarmMetrics(in *plancheck.Input) []alarmMetric {
+	var metrics []alarmMetric
+	for _, alarm := range plancheck.Resources(in.Plan, "aws_cloudwatch_metric_alarm") {
+		if name := plancheck.LookupString(alarm.AttributeValues, "metric_name"); name != "" {
+			dimensions, _ := alarm.AttributeValues["dimensions"].(map[string]interface{})
+			metrics = append(metrics, alarmMetric{alarm.Address, name, dimensions, in.References(alarm.Address, "dimensions")})
+		}
+		refs := in.References(alarm.Address, "metric_query.metric.dimensions")
+		for _, query := range plancheck.Blocks(alarm.AttributeValues, "metric_query") {
+			for _, metric := range plancheck.Blocks(query, "metric") {
+				dimensions, _ := metric["dimensions"].(map[string]interface{})
+				metrics = append(metrics, alarmMetric{alarm.Address, plancheck.LookupString(metric, "metric_name"), dimensions, refs})
+			}
+		}
+	}
+	return metrics
+}
+
+// deadLetterQueues returns the names of the queues other queues redrive to.
+func deadLetterQueues(in *plancheck.Input) map[string]bool {
+	queues := map[string]bool{}
+	for _, resource := range plancheck.Resources(in.Plan, "aws_sqs_queue", "aws_sqs_queue_redrive_policy") {
+		for _, ref := range in.References(resource.Address, "redrive_policy") {
+			for _, queue := range plancheck.Resources(in.Plan, "aws_sqs_queue") {
+				if plancheck.ConfigAddress(queue.Address) == ref {
+					queues[plancheck.LookupString(queue.AttributeValues, "name")] = true
+				}
+			}
+		}
+
+		var policy struct {
+			DeadLetterTargetArn string `json:"deadLetterTargetArn"`
+		}
+		if json.Unmarshal([]byte(plancheck.LookupString(resource.AttributeValues, "redrive_policy")), &policy) == nil {
+			if i := strings.LastIndex(policy.DeadLetterTargetArn, ":"); i >= 0 {
+				queues[policy.DeadLetterTargetArn[i+1:]] = true
+			}
+		}
+	}
+	return queues
+}
+
+func checkAlarmActions(in *plancheck.Input) []plancheck.Finding {
+	settings := in.Settings()
+	if len(in.Config.RequiredAlarms(in.Environment)) == 0 {
+		return nil
+	}
+	topics := plancheck.Resources(in.Plan, "aws_sns_topic")
+	subscriptions := plancheck.Resources(in.Plan, "aws_sns_topic_subscription")
+
+	var findings []plancheck.Finding
+	for _, alarm := range plancheck.Resources(in.Plan, "aws_cloudwatch_metric_alarm") {
+		if settings.Production {
+			if enabled, ok := plancheck.Lookup(alarm.AttributeValues, "actions_enabled"); ok && enabled == false {
+				findings = append(findings, plancheck.NewFinding(
+					"alarms.actions",
+					alarm.Address,
+					fmt.Sprintf("%s has its actions disabled in %s", alarm.Address, in.Environment),
+				).WithPath("actions_enabled"))
+			}
+		}
+
+		var notified []*tfjson.StateResource
+		for _, topic := range topics {
+			if contains(in.References(alarm.Address, "alarm_actions"), plancheck.ConfigAddress(topic.Address)) ||
+				containsTopicARN(alarm.AttributeValues["alarm_actions"], topic) {
+				notified = append(notified, topic)
+			}
+		}
+		if len(notified) == 0 {
+			findings = append(findings, plancheck.NewFinding(
+				"alarms.actions",
+				alarm.Address,
+				fmt.Sprintf("%s does not notify an SNS topic in the plan", alarm.Address),
+			).WithPath("alarm_actions"))
+			continue
+		}
+
+		for _, topic := range notified {
+			var subscribed bool
+			for _, subscription := range subscriptions {
+				subscribed = subscribed ||
+					contains(in.References(subscription.Address, "topic_arn"), plancheck.ConfigAddress(topic.Address)) ||
+					containsTopicARN(subscription.AttributeValues["topic_arn"], topic)
+			}
+			if !subscribed {
+				findings = append(findings, plancheck.NewFinding(
+					"alarms.actions",
+					alarm.Address,
+					fmt.Sprintf("%s notifies %s, which has no subscriptions", alarm.Address, topic.Address),
+				).WithPath("alarm_actions"))
+			}
+		}
+	}
+	return findings
+}
+
+// containsTopicARN reports whether value, an ARN or list of ARNs, names the
+// topic. Planned topic ARNs are usually unknown, so names are compared.
+func containsTopicARN(value interface{}, topic *tfjson.StateResource) bool {
+	name := plancheck.LookupString(topic.AttributeValues, "name")
+	if name == "" {
+		return false
+	}
+	arns, ok := value.([]interface{})
+	if !ok {
+		arns = []interface{}{value}
+	}
+	for _, arn := range arns {
+		if arn, ok := arn.(string); ok && strings.HasPrefix(arn, "arn:") && strings.HasSuffix(arn, ":"+name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+const apiThrottlingExample = `
+resource "aws_api_gateway_method_settings" "all" {
+  rest_api_id = aws_api_gateway_rest_api.main_api.id
+  stage_name  = aws_api_gateway_stage.main_stage.stage_name
+  method_path = "*/*"
+
+  settings {
+    throttling_rate_limit  = 50
+    throttling_burst_limit = 100
+  }
+}`
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "apigateway.authorization",
+		Description:   "API Gateway methods and routes must require an authorizer, unless api.public_routes in compliance.yaml lists them.",
+		Remediation:   `Set authorization (REST APIs) or authorization_type (HTTP and WebSocket APIs) to CUSTOM, AWS_IAM, COGNITO_USER_POOLS or JWT with an authorizer, or add the route to api.public_routes if it must stay open, such as a health check.`,
+		Rationale:     "The registry API is reachable from the internet. A method without an authorizer leaves every check to the handler behind it, and one forgotten check exposes the data it serves.",
+		ResourceTypes: []string{"aws_api_gateway_method", "aws_api_gateway_resource", "aws_apigatewayv2_route"},
+		Check:         checkAPIAuthorization,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "apigateway.throttling",
+		Description:   "API Gateway stages must set default throttling rate and burst limits.",
+		Remediation:   `Add an aws_api_gateway_method_settings with method_path = "*/*" whose settings set throttling_rate_limit and throttling_burst_limit, or set them in the default_route_settings of an aws_apigatewayv2_stage.`,
+		Rationale:     "Without stage limits, one client can use the whole account-level request quota, and every request it sends also runs the Lambda functions and database reads behind the API.",
+		Example:       apiThrottlingExample,
+		ResourceTypes: []string{"aws_api_gateway_stage", "aws_api_gateway_method_settings", "aws_apigatewayv2_stage"},
+		Check:         checkAPIThrottling,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "apigateway.access-logs",
+		Description:   "API Gateway stages must write access logs.",
+		Remediation:   "Add an access_log_settings block with destination_arn set to a CloudWatch log group and a format that records at least the request ID, caller IP, method, path and status.",
+		Rationale:     "Access logs are the only record of who called the API and what it returned; without them, abuse of an internet-facing endpoint cannot be traced.",
+		ResourceTypes: []string{"aws_api_gateway_stage", "aws_apigatewayv2_stage"},
+		Check:         checkAPIAccessLogs,
+	})
+}
+
+func checkAPIAuthorization(in *plancheck.Input) []plancheck.Finding {
+	var policy plancheck.APIPolicy
+	if in.Config != nil {
+		policy = in.Config.API
+	}
+
+	var findings []plancheck.Finding
+	for _, method := range plancheck.Resources(in.Plan, "aws_api_gateway_method") {
+		authorization := plancheck.LookupString(method.AttributeValues, "authorization")
+		httpMethod := plancheck.LookupString(method.AttributeValues, "http_method")
+		// Browsers send CORS preflights without credentials, so they cannot
+		// pass an authorizer.
+		if authorization != "NONE" || httpMethod == "OPTIONS" {
+			continue
+		}
+		apiPath := methodPath(in, method)
+		route := httpMethod + " " + apiPath
+		if policy.Public(route) {
+			continue
+		}
+		if apiPath == "" {
+			route = httpMethod + " on a resource whose path is unknown"
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"apigateway.authorization",
+			method.Address,
+			fmt.Sprintf("method %s has no authorizer (authorization is NONE)", route),
+		).WithPath("authorization"))
+	}
+
+	for _, route := range plancheck.Resources(in.Plan, "aws_apigatewayv2_route") {
+		authorization := plancheck.LookupString(route.AttributeValues, "authorization_type")
+		key := plancheck.LookupString(route.AttributeValues, "route_key")
+		if authorization != "" && authorization != "NONE" || strings.HasPrefix(key, "OPTIONS ") || policy.Public(key) {
+			continue
+		}
+		if authorization == "" {
+			authorization = "unset"
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"apigateway.authorization",
+			route.Address,
+			fmt.Sprintf("route %s has no authorizer (authorization_type is %s)", key, authorization),
+		).WithPath("authorization_type"))
+	}
+	return findings
+}
+
+// methodPath returns the path of the API resource a REST API method belongs
+// to, e.g. "/artifact/{id}". A path not known until apply is rebuilt from the
+// path_part of each resource up to the API's root resource. It returns ""
+// when the configuration does not show the way to the root.
+func methodPath(in *plancheck.Input, method *tfjson.StateResource) string {
+	for _, ref := range in.References(method.Address, "resource_id") {
+		switch resourceType(ref) {
+		case "aws_api_gateway_rest_api":
+			return "/"
+		case "aws_api_gateway_resource":
+			return resourcePath(in, ref, 0)
+		}
+	}
+	return ""
+}
+
+func resourcePath(in *plancheck.Input, ref string, depth int) string {
+	var resource *tfjson.StateResource
+	for _, candidate := range plancheck.Resources(in.Plan, "aws_api_gateway_resource") {
+		if plancheck.ConfigAddress(candidate.Address) == ref {
+			resource = candidate
+			break
+		}
+	}
+	if resource == nil || depth > 32 {
+		return ""
+	}
+	if known := plancheck.LookupString(resource.AttributeValues, "path"); known != "" {
+		return known
+	}
+	part := plancheck.LookupString(resource.AttributeValues, "path_part")
+	for _, parent := range in.References(resource.Address, "parent_id") {
+		switch resourceType(parent) {
+		case "aws_api_gateway_rest_api":
+			return "/" + part
+		case "aws_api_gateway_resource":
+			if parentPath := resourcePath(in, parent, depth+1); parentPath != "" {
+				return parentPath + "/" + part
+			}
+		}
+	}
+	return ""
+}
+
+func checkAPIThrottling(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, stage := range plancheck.Resources(in.Plan, "aws_api_gateway_stage") {
+		throttled := false
+		for _, settings := range stageMethodSettings(in, stage) {
+			if plancheck.LookupString(settings.AttributeValues, "method_path") == "*/*" &&
+				throttlingLimits(settings.AttributeValues, "settings.0") {
+				throttled = true
+				break
+			}
+		}
+		if throttled {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"apigateway.throttling",
+			stage.Address,
+			fmt.Sprintf(`stage %s has no method settings for "*/*" that set throttling limits`, plancheck.LookupString(stage.AttributeValues, "stage_name")),
+		))
+	}
+
+	for _, stage := range plancheck.Resources(in.Plan, "aws_apigatewayv2_stage") {
+		if throttlingLimits(stage.AttributeValues, "default_route_settings.0") {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"apigateway.throttling",
+			stage.Address,
+			fmt.Sprintf("stage %s sets no default route throttling limits", plancheck.LookupString(stage.AttributeValues, "name")),
+		).WithPath("default_route_settings"))
+	}
+	return findings
+}
+
+// throttlingLimits reports whether the settings block at prefix sets a
+// positive throttling rate and burst limit. The provider reads an unset
+// REST API limit as -1.
+func throttlingLimits(values map[string]interface{}, prefix string) bool {
+	rate, _ := plancheck.LookupNumber(values, prefix+".throttling_rate_limit")
+	burst, _ := plancheck.LookupNumber(values, prefix+".throttling_burst_limit")
+	return rate > 0 && burst > 0
+}
+
+// stageMethodSettings returns the method settings of a REST API stage: those
+// referring to the stage, or naming the same stage of the same API.
+func stageMethodSettings(in *plancheck.Input, stage *tfjson.StateResource) []*tfjson.StateResource {
+	stageName := plancheck.LookupString(stage.AttributeValues, "stage_name")
+	apiID := plancheck.LookupString(stage.AttributeValues, "rest_api_id")
+
+	var matched []*tfjson.StateResource
+	for _, settings := range plancheck.Resources(in.Plan, "aws_api_gateway_method_settings") {
+		if contains(in.References(settings.Address, "stage_name"), plancheck.ConfigAddress(stage.Address)) ||
+			stageName != "" && apiID != "" &&
+				plancheck.LookupString(settings.AttributeValues, "stage_name") == stageName &&
+				plancheck.LookupString(settings.AttributeValues, "rest_api_id") == apiID {
+			matched = append(matched, settings)
+		}
+	}
+	return matched
+}
+
+func checkAPIAccessLogs(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, stageType := range []string{"aws_api_gateway_stage", "aws_apigatewayv2_stage"} {
+		nameAttribute := "stage_name"
+		if stageType == "aws_apigatewayv2_stage" {
+			nameAttribute = "name"
+		}
+		for _, stage := range plancheck.Resources(in.Plan, stageType) {
+			// A log group created in the same apply has no ARN yet.
+			if plancheck.LookupString(stage.AttributeValues, "access_log_settings.0.destination_arn") != "" ||
+				in.Configured(stage.Address, "access_log_settings.destination_arn") {
+				continue
+			}
+			findings = append(findings, plancheck.NewFinding(
+				"apigateway.access-logs",
+				stage.Address,
+				fmt.Sprintf("stage %s does not write access logs", plancheck.LookupString(stage.AttributeValues, nameAttribute)),
+			).WithPath("access_log_settings"))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,182 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "backend.hardening",
+		Description:   "The S3 state bucket and DynamoDB lock table named by the backend must be versioned, encrypted, point-in-time recoverable and, for the bucket, restricted to the CI role.",
+		Remediation:   "Enable versioning and default encryption on the state bucket with a policy denying principals other than the CI role, and enable server_side_encryption and point_in_time_recovery on the lock table.",
+		ResourceTypes: []string{"aws_s3_bucket", "aws_s3_bucket_versioning", "aws_s3_bucket_server_side_encryption_configuration", "aws_s3_bucket_policy", "aws_dynamodb_table"},
+		Check:         checkBackendHardening,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:          "backend.config",
+		Description: "Every environment must keep its state in an approved, encrypted S3 backend under the expected key; only sandbox environments may keep local state.",
+		Remediation: "Declare backend \"s3\" with encrypt = true, an approved bucket and a key matching backend.key_pattern, or mark the environment as a sandbox in compliance.yaml.",
+		Check:       checkBackendConfig,
+	})
+}
+
+// checkBackendConfig judges the backend block of the root module rather than
+// a planned resource, so its findings are reported against "terraform".
+// Arguments passed with -backend-config are not visible and are judged as
+// missing.
+func checkBackendConfig(in *plancheck.Input) []plancheck.Finding {
+	var policy plancheck.BackendPolicy
+	if in.Config != nil {
+		policy = in.Config.Backend
+	}
+	finding := func(path, message string) plancheck.Finding {
+		f := plancheck.NewFinding("backend.config", "terraform", message).WithPath(path)
+		if in.Backend != nil {
+			f = f.WithSource(in.Backend.Source)
+		}
+		return f
+	}
+
+	if in.Backend == nil || in.Backend.Type == "local" {
+		if in.Settings().Sandbox {
+			return nil
+		}
+		return []plancheck.Finding{finding("backend", fmt.Sprintf("environment %s keeps its state locally; only sandbox environments may", in.Environment))}
+	}
+	if in.Backend.Type != "s3" {
+		return []plancheck.Finding{finding("backend", fmt.Sprintf("backend %q is not the approved s3 backend", in.Backend.Type))}
+	}
+
+	var findings []plancheck.Finding
+	if in.Backend.Config["encrypt"] != "true" {
+		findings = append(findings, finding("backend.encrypt", "state is not encrypted: set encrypt = true"))
+	}
+	if bucket := in.Backend.Config["bucket"]; len(policy.Buckets) > 0 && !exempt(policy.Buckets, bucket) {
+		findings = append(findings, finding("backend.bucket", fmt.Sprintf("state bucket %q is not one of %s", bucket, strings.Join(policy.Buckets, ", "))))
+	}
+	if pattern, err := policy.KeyRegexp(in.Environment); err == nil && pattern != nil {
+		if key := in.Backend.Config["key"]; !pattern.MatchString(key) {
+			findings = append(findings, finding("backend.key", fmt.Sprintf("state key %q does not match %s", key, pattern)))
+		}
+	}
+	return findings
+}
+
+// checkBackendHardening judges the state bucket and lock table when they are
+// part of the plan: a bootstrap configuration, or the live resources read by
+// the post-apply test. Plans that only use the backend find neither.
+func checkBackendHardening(in *plancheck.Input) []plancheck.Finding {
+	if in.Backend == nil || in.Backend.Type != "s3" {
+		return nil
+	}
+	var ciRole string
+	if in.Config != nil {
+		ciRole = in.Config.Backend.CIRole
+	}
+
+	var findings []plancheck.Finding
+	stateBucket, lockTable := in.Backend.Config["bucket"], in.Backend.Config["dynamodb_table"]
+	for _, bucket := range plancheck.Resources(in.Plan, "aws_s3_bucket") {
+		if bucket.Mode == tfjson.DataResourceMode || stateBucket == "" ||
+			plancheck.LookupString(bucket.AttributeValues, "bucket") != stateBucket {
+			continue
+		}
+		finding := func(message string) plancheck.Finding {
+			return plancheck.NewFinding("backend.hardening", bucket.Address, fmt.Sprintf("state bucket %s %s", stateBucket, message))
+		}
+
+		if !bucketVersioned(in, bucket) {
+			findings = append(findings, finding("is not versioned"))
+		}
+		if !bucketEncrypted(in, bucket) {
+			findings = append(findings, finding("has no default encryption"))
+		}
+
+		if ciRole == "" {
+			continue
+		}
+		var restricted bool
+		for _, policy := range bucketResources(in, "aws_s3_bucket_policy", bucket) {
+			restricted = restricted || deniesAllBut(plancheck.LookupString(policy.AttributeValues, "policy"), ciRole)
+		}
+		if !restricted {
+			findings = append(findings, finding(fmt.Sprintf("has no bucket policy denying principals other than %s", ciRole)))
+		}
+	}
+
+	for _, table := range dynamoDBTables(in) {
+		if lockTable == "" || plancheck.LookupString(table.AttributeValues, "name") != lockTable {
+			continue
+		}
+		if !plancheck.LookupBool(table.AttributeValues, "server_side_encryption.0.enabled") {
+			findings = append(findings, plancheck.NewFinding(
+				"backend.hardening",
+				table.Address,
+				fmt.Sprintf("lock table %s is not encrypted with a KMS key", lockTable),
+			).WithPath("server_side_encryption"))
+		}
+		if !plancheck.LookupBool(table.AttributeValues, "point_in_time_recovery.0.enabled") {
+			findings = append(findings, plancheck.NewFinding(
+				"backend.hardening",
+				table.Address,
+				fmt.Sprintf("lock table %s has no point-in-time recovery", lockTable),
+			).WithPath("point_in_time_recovery"))
+		}
+	}
+	return findings
+}
+
+// bucketResources returns the resources of resourceType configuring bucket,
+// matched by bucket name or by reference.
+func bucketResources(in *plancheck.Input, resourceType string, bucket *tfjson.StateResource) []*tfjson.StateResource {
+	name := plancheck.LookupString(bucket.AttributeValues, "bucket")
+	var resources []*tfjson.StateResource
+	for _, resource := range plancheck.Resources(in.Plan, resourceType) {
+		if plancheck.LookupString(resource.AttributeValues, "bucket") == name ||
+			contains(in.References(resource.Address, "bucket"), plancheck.ConfigAddress(bucket.Address)) {
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// deniesAllBut reports whether a JSON bucket policy has a Deny statement
+// exempting only principals whose aws:PrincipalArn matches role.
+func deniesAllBut(policy, role string) bool {
+	var document map[string]interface{}
+	if json.Unmarshal([]byte(policy), &document) != nil {
+		return false
+	}
+	for _, statement := range plancheck.Statements(document) {
+		if statement.Fields["Effect"] != "Deny" {
+			continue
+		}
+		conditions, _ := statement.Fields["Condition"].(map[string]interface{})
+		for operator, condition := range conditions {
+			switch operator {
+			case "ArnNotEquals", "ArnNotLike", "StringNotEquals", "StringNotLike":
+			default:
+				continue
+			}
+			keys, _ := condition.(map[string]interface{})
+			for key, values := range keys {
+				if !strings.EqualFold(key, "aws:PrincipalArn") {
+					continue
+				}
+				if values == role {
+					return true
+				}
+				if list, ok := values.([]interface{}); ok && len(list) == 1 && list[0] == role {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
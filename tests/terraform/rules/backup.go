@@ -0,0 +1,284 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "backup.coverage",
+		Description:   "Stateful resources must be selected by an AWS Backup plan or use native replication/point-in-time recovery.",
+		Remediation:   "Add the resource to an aws_backup_selection (by ARN or a backup tag), or enable its native point-in-time recovery or replication.",
+		ResourceTypes: []string{"aws_db_instance", "aws_rds_cluster", "aws_dynamodb_table", "aws_efs_file_system", "aws_s3_bucket", "aws_backup_selection"},
+		Check:         checkBackupCoverage,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "backup.rpo",
+		Description:   "AWS Backup plans must run at least as often as the environment's RPO.",
+		Remediation:   "Schedule a backup plan rule at least as often as the environment RPO in compliance.yaml.",
+		ResourceTypes: []string{"aws_backup_plan"},
+		Check:         checkBackupRPO,
+	})
+}
+
+// nativeProtection reports whether a stateful resource protects its own data
+// without an AWS Backup selection.
+var nativeProtection = map[string]func(in *plancheck.Input, resource *tfjson.StateResource) bool{
+	"aws_db_instance": hasBackupRetention,
+	"aws_rds_cluster": hasBackupRetention,
+	"aws_dynamodb_table": func(_ *plancheck.Input, resource *tfjson.StateResource) bool {
+		return plancheck.LookupBool(resource.AttributeValues, "point_in_time_recovery.0.enabled") ||
+			len(plancheck.Blocks(resource.AttributeValues, "replica")) > 0
+	},
+	"aws_efs_file_system": func(in *plancheck.Input, resource *tfjson.StateResource) bool {
+		return referencedBy(in, "aws_efs_replication_configuration", "source_file_system_id", nil)[plancheck.ConfigAddress(resource.Address)] ||
+			referencedBy(in, "aws_efs_backup_policy", "file_system_id", func(policy *tfjson.StateResource) bool {
+				return plancheck.LookupString(policy.AttributeValues, "backup_policy.0.status") == "ENABLED"
+			})[plancheck.ConfigAddress(resource.Address)]
+	},
+	"aws_s3_bucket": func(in *plancheck.Input, resource *tfjson.StateResource) bool {
+		return referencedBy(in, "aws_s3_bucket_replication_configuration", "bucket", nil)[plancheck.ConfigAddress(resource.Address)]
+	},
+}
+
+func hasBackupRetention(_ *plancheck.Input, resource *tfjson.StateResource) bool {
+	days, ok := plancheck.LookupNumber(resource.AttributeValues, "backup_retention_period")
+	return ok && days > 0
+}
+
+func checkBackupCoverage(in *plancheck.Input) []plancheck.Finding {
+	if in.Settings().Backup == nil {
+		return nil
+	}
+
+	selected := referencedBy(in, "aws_backup_selection", "resources", nil)
+	var tagSelectors []map[string]interface{}
+	for _, selection := range plancheck.Resources(in.Plan, "aws_backup_selection") {
+		tagSelectors = append(tagSelectors, plancheck.Blocks(selection.AttributeValues, "selection_tag")...)
+	}
+
+	var findings []plancheck.Finding
+	for _, resource := range plancheck.PlannedResources(in.Plan) {
+		native, stateful := nativeProtection[resource.Type]
+		if !stateful || resource.Mode == tfjson.DataResourceMode {
+			continue
+		}
+		if selected[plancheck.ConfigAddress(resource.Address)] || matchesTagSelector(resource, tagSelectors) || native(in, resource) {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"backup.coverage",
+			resource.Address,
+			fmt.Sprintf("%s is not selected by any aws_backup_selection and has no native replication or point-in-time recovery", resource.Address),
+		))
+	}
+	return findings
+}
+
+func checkBackupRPO(in *plancheck.Input) []plancheck.Finding {
+	policy := in.Settings().Backup
+	if policy == nil || policy.RPO.Duration <= 0 {
+		return nil
+	}
+
+	var findings []plancheck.Finding
+	for _, plan := range plancheck.Resources(in.Plan, "aws_backup_plan") {
+		var best time.Duration
+		bestRule := -1
+		for i, rule := range plancheck.Blocks(plan.AttributeValues, "rule") {
+			interval, ok := scheduleInterval(plancheck.LookupString(rule, "schedule"))
+			if ok && (bestRule < 0 || interval < best) {
+				best, bestRule = interval, i
+			}
+		}
+
+		switch {
+		case bestRule < 0:
+			findings = append(findings, plancheck.NewFinding(
+				"backup.rpo",
+				plan.Address,
+				fmt.Sprintf("backup plan %s has no scheduled rule; %s requires an RPO of %s", plan.Address, in.Environment, policy.RPO),
+			).WithPath("rule"))
+		case best > policy.RPO.Duration:
+			findings = append(findings, plancheck.NewFinding(
+				"backup.rpo",
+				plan.Address,
+				fmt.Sprintf("backup plan %s runs every %s, longer than the %s RPO of %s", plan.Address, best, in.Environment, policy.RPO),
+			).WithPath(fmt.Sprintf("rule[%d].schedule", bestRule)))
+		}
+	}
+	return findings
+}
+
+// referencedBy returns the configuration addresses referenced by attribute on
+// every resource of resourceType that passes filter (nil accepts all).
+func referencedBy(in *plancheck.Input, resourceType, attribute string, filter func(*tfjson.StateResource) bool) map[string]bool {
+	refs := map[string]bool{}
+	for _, resource := range plancheck.Resources(in.Plan, resourceType) {
+		if filter != nil && !filter(resource) {
+			continue
+		}
+		for _, ref := range in.References(resource.Address, attribute) {
+			refs[ref] = true
+		}
+	}
+	return refs
+}
+
+func matchesTagSelector(resource *tfjson.StateResource, selectors []map[string]interface{}) bool {
+	tags := plancheck.Tags(resource.AttributeValues)
+	for _, selector := range selectors {
+		if !strings.EqualFold(plancheck.LookupString(selector, "type"), "STRINGEQUALS") {
+			continue
+		}
+		if value, ok := tags[plancheck.LookupString(selector, "key")]; ok && value == plancheck.LookupString(selector, "value") {
+			return true
+		}
+	}
+	return false
+}
+
+var rateExpression = regexp.MustCompile(`^rate\((\d+)\s+(minute|minutes|hour|hours|day|days)\)$`)
+
+// scheduleInterval returns the longest gap between runs of an AWS Backup
+// schedule expression, a rate() or a six-field cron(). Day-of-month schedules
+// are judged against a 31-day month and "L", "W" and "#" days as monthly, so
+// the result errs on the long side.
+func scheduleInterval(schedule string) (time.Duration, bool) {
+	schedule = strings.TrimSpace(schedule)
+	if m := rateExpression.FindStringSubmatch(schedule); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := map[string]time.Duration{
+			"minute": time.Minute, "minutes": time.Minute,
+			"hour": time.Hour, "hours": time.Hour,
+			"day": 24 * time.Hour, "days": 24 * time.Hour,
+		}[m[2]]
+		return time.Duration(n) * unit, n > 0
+	}
+
+	inner, ok := strings.CutPrefix(schedule, "cron(")
+	if !ok {
+		return 0, false
+	}
+	fields := strings.Fields(strings.TrimSuffix(inner, ")"))
+	if len(fields) != 6 {
+		return 0, false
+	}
+	minutes, ok := cronValues(fields[0], 0, 59, nil)
+	if !ok {
+		return 0, false
+	}
+	hours, ok := cronValues(fields[1], 0, 23, nil)
+	if !ok {
+		return 0, false
+	}
+
+	// days are the run days within a cycle of period days; only the gaps
+	// between them matter, so they may count from 0 or 1.
+	days, period := []int{0}, 1
+	switch dayOfMonth, dayOfWeek := fields[2], fields[4]; {
+	case !isWildcardField(dayOfMonth) && strings.ContainsAny(dayOfMonth, "LW"):
+		period = 31
+	case !isWildcardField(dayOfMonth):
+		if days, ok = cronValues(dayOfMonth, 1, 31, nil); !ok {
+			return 0, false
+		}
+		period = 31
+	case !isWildcardField(dayOfWeek) && strings.ContainsAny(dayOfWeek, "L#"):
+		period = 35
+	case !isWildcardField(dayOfWeek):
+		if days, ok = cronValues(dayOfWeek, 1, 7, weekdays); !ok {
+			return 0, false
+		}
+		period = 7
+	}
+
+	var runs []time.Duration
+	for _, day := range days {
+		for _, hour := range hours {
+			for _, minute := range minutes {
+				runs = append(runs, time.Duration(day)*24*time.Hour+time.Duration(hour)*time.Hour+time.Duration(minute)*time.Minute)
+			}
+		}
+	}
+	cycle := time.Duration(period) * 24 * time.Hour
+	longest := runs[0] + cycle - runs[len(runs)-1]
+	for i := 1; i < len(runs); i++ {
+		if gap := runs[i] - runs[i-1]; gap > longest {
+			longest = gap
+		}
+	}
+	return longest, true
+}
+
+func isWildcardField(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// weekdays are the day-of-week names AWS cron accepts, numbered from SUN=1.
+var weekdays = map[string]int{"SUN": 1, "MON": 2, "TUE": 3, "WED": 4, "THU": 5, "FRI": 6, "SAT": 7}
+
+// cronValues expands a cron field of values, ranges, lists and steps, such
+// as "8-17", "MON-FRI", "1,5" or "*/2", into its sorted values in [lo, hi].
+func cronValues(field string, lo, hi int, names map[string]int) ([]int, bool) {
+	value := func(s string) (int, bool) {
+		if n, ok := names[strings.ToUpper(s)]; ok {
+			return n, true
+		}
+		n, err := strconv.Atoi(s)
+		return n, err == nil && n >= lo && n <= hi
+	}
+
+	set := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return nil, false
+			}
+			step = n
+		}
+
+		start, end := lo, hi
+		switch from, to, isRange := strings.Cut(rangePart, "-"); {
+		case rangePart == "*" || rangePart == "?":
+		case isRange:
+			var ok bool
+			if start, ok = value(from); !ok {
+				return nil, false
+			}
+			if end, ok = value(to); !ok || end < start {
+				return nil, false
+			}
+		default:
+			var ok bool
+			if start, ok = value(rangePart); !ok {
+				return nil, false
+			}
+			if !stepped {
+				end = start
+			}
+		}
+		for n := start; n <= end; n += step {
+			set[n] = true
+		}
+	}
+
+	values := make([]int, 0, len(set))
+	for n := lo; n <= hi; n++ {
+		if set[n] {
+			values = append(values, n)
+		}
+	}
+	return values, len(values) > 0
+}
@@ -0,0 +1,69 @@
+package rules
+
+import (
+	"fmt"
+	"time"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "acm.expiry",
+		Description:   "Certificates the stack manages or references must stay valid for more than certificates.min_days_left days (default 30).",
+		Remediation:   "For an ACM-issued certificate, fix what blocks managed renewal, usually a missing DNS validation record. Re-import a renewed certificate over an imported one, or upload a new IAM server certificate and point the resources at it.",
+		Rationale:     "An expired certificate takes the endpoint down for every client at once, and renewing one during the outage is slow; a month's notice leaves time to fix renewal properly.",
+		ResourceTypes: []string{"aws_acm_certificate", "aws_iam_server_certificate", "aws_lb_listener", "aws_api_gateway_domain_name", "aws_apigatewayv2_domain_name", "aws_cloudfront_distribution"},
+		Check:         checkCertificateExpiry,
+	})
+}
+
+func checkCertificateExpiry(in *plancheck.Input) []plancheck.Finding {
+	if in.Runtime == nil || len(in.Runtime.Certificates) == 0 {
+		return nil
+	}
+	minDays := plancheck.DefaultCertificateDaysLeft
+	if in.Config != nil {
+		minDays = in.Config.Certificates.DaysLeft()
+	}
+
+	var findings []plancheck.Finding
+	for _, ref := range plancheck.CertificateReferences(in.Plan) {
+		cert, ok := in.Runtime.Certificates[ref.ARN]
+		if !ok {
+			continue
+		}
+		left := int(cert.NotAfter.Sub(in.Runtime.ReadAt).Hours() / 24)
+		if left > minDays {
+			continue
+		}
+
+		state := fmt.Sprintf("expires on %s, in %d days", cert.NotAfter.Format(time.DateOnly), left)
+		if !cert.NotAfter.After(in.Runtime.ReadAt) {
+			state = fmt.Sprintf("expired on %s", cert.NotAfter.Format(time.DateOnly))
+		}
+		what, note := "certificate", ""
+		switch cert.Type {
+		case "IMPORTED":
+			what = "imported certificate"
+		case "AMAZON_ISSUED":
+			what, note = "ACM certificate", ", although ACM should have renewed it"
+		case "IAM":
+			what = "IAM server certificate"
+		}
+		subject := ref.ARN
+		if cert.Domain != "" {
+			subject = fmt.Sprintf("%s (%s)", cert.Domain, ref.ARN)
+		}
+		use := "used by"
+		if ref.Path == "arn" {
+			use = "managed as"
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"acm.expiry",
+			ref.Address,
+			fmt.Sprintf("%s %s %s %s %s%s; at least %d days of validity are required", what, subject, use, ref.Address, state, note, minDays),
+		).WithPath(ref.Path).WithEvidence(cert))
+	}
+	return findings
+}
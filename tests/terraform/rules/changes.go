@@ -0,0 +1,75 @@
+package rules
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:          "changes.destroy-protected",
+		Description: "Plans must not destroy or replace stateful resources (changes.protected_types in compliance.yaml) unless the run allows it for the address.",
+		Remediation: "If the destroy comes from a refactor, add a moved block so terraform keeps the resource under its new address. If it is intended, allow it for this run only: " + plancheck.AllowDestroyEnv + "=<address> or -allow-destroy <address>.",
+		Rationale:   "A renamed module, a changed for_each key or an argument that forces replacement destroys the bucket, table, database or key behind it, and the data with it; the plan shows it only as one line among many.",
+		Check:       checkDestroyProtected,
+	})
+}
+
+// allowedDestroy reports whether address is listed, exactly or by a glob
+// pattern, in allowed. In a pattern, [*] stands for any instance key rather
+// than a character class, so aws_dynamodb_table.this[*] allows every table
+// for_each makes.
+func allowedDestroy(allowed []string, address string) bool {
+	for _, pattern := range allowed {
+		if pattern == address {
+			return true
+		}
+		pattern = strings.ReplaceAll(pattern, "[*]", `\[*\]`)
+		if matched, _ := path.Match(pattern, address); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDestroyProtected reads the plan's resource changes, as destroyed
+// resources are absent from its planned values.
+func checkDestroyProtected(in *plancheck.Input) []plancheck.Finding {
+	if in.Plan == nil {
+		return nil
+	}
+	protected := plancheck.DefaultProtectedTypes
+	if in.Config != nil {
+		protected = in.Config.Changes.Protected()
+	}
+
+	var findings []plancheck.Finding
+	for _, change := range in.Plan.ResourceChanges {
+		if change == nil || change.Change == nil || change.Mode == tfjson.DataResourceMode || !contains(protected, change.Type) {
+			continue
+		}
+		var what string
+		switch actions := change.Change.Actions; {
+		case actions.Replace():
+			what = "replaces (destroys and re-creates)"
+		case actions.Delete():
+			what = "destroys"
+		default:
+			continue
+		}
+		if allowedDestroy(in.AllowDestroy, change.Address) {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"changes.destroy-protected",
+			change.Address,
+			fmt.Sprintf("the plan %s %s, a protected %s", what, change.Address, change.Type),
+		))
+	}
+	return findings
+}
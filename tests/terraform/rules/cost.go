@@ -0,0 +1,424 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// The cost rules flag configurations that work but cost more than they need
+// to. Their findings are advisories: they are reported but do not fail runs.
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "cost.nat-gateway",
+		Description:   "Environments other than production should share one NAT gateway instead of running one per availability zone.",
+		Remediation:   "Route every private subnet through a single NAT gateway outside production.",
+		Severity:      plancheck.SeverityAdvisory,
+		ResourceTypes: []string{"aws_nat_gateway"},
+		Check:         checkNATGateways,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "cost.cross-az",
+		Description:   "Network and gateway load balancers with cross-zone load balancing pay for data sent between availability zones.",
+		Remediation:   "Disable enable_cross_zone_load_balancing unless targets are unevenly spread across zones.",
+		Severity:      plancheck.SeverityAdvisory,
+		ResourceTypes: []string{"aws_lb"},
+		Check:         checkCrossZoneLoadBalancing,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "cost.gp2",
+		Description:   "EBS volumes and RDS storage should use gp3, which is cheaper than gp2 for the same baseline performance.",
+		Remediation:   `Set the volume type or storage_type to "gp3".`,
+		Severity:      plancheck.SeverityAdvisory,
+		ResourceTypes: []string{"aws_ebs_volume", "aws_instance", "aws_launch_template", "aws_db_instance"},
+		Check:         checkGP2Volumes,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "cost.s3-storage-class",
+		Description:   "Rarely read prefixes listed under storage.infrequent_prefixes in compliance.yaml should move to a cheaper storage class.",
+		Remediation:   "Add an enabled aws_s3_bucket_lifecycle_configuration rule with a transition for the prefix, or an aws_s3_bucket_intelligent_tiering_configuration covering it.",
+		Severity:      plancheck.SeverityAdvisory,
+		ResourceTypes: []string{"aws_s3_bucket"},
+		Check:         checkStorageClasses,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "cost.log-archive",
+		Description:   "Log groups keeping logs longer than storage.log_archive_days should archive them to S3 instead.",
+		Remediation:   "Lower retention_in_days and stream the logs to S3 with an aws_cloudwatch_log_subscription_filter, e.g. through Kinesis Data Firehose.",
+		Severity:      plancheck.SeverityAdvisory,
+		ResourceTypes: []string{"aws_cloudwatch_log_group"},
+		Check:         checkLogArchive,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "cost.provisioned-iops",
+		Description:   "Databases outside production should not pay for provisioned IOPS storage.",
+		Remediation:   `Use storage_type = "gp3" outside production.`,
+		Severity:      plancheck.SeverityAdvisory,
+		ResourceTypes: []string{"aws_db_instance"},
+		Check:         checkProvisionedIOPS,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "cost.graviton",
+		Description:   "x86 instance classes and Lambda functions with an ARM (Graviton) equivalent could run cheaper on it.",
+		Remediation:   `Move to the suggested Graviton class, or set architectures = ["arm64"] after checking the code and its dependencies build for ARM.`,
+		Severity:      plancheck.SeverityAdvisory,
+		ResourceTypes: append([]string{"aws_lambda_function"}, instanceClassTypes...),
+		Check:         checkGraviton,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "cost.unattached-eip",
+		Description:   "Elastic IPs are billed while they are not attached to anything.",
+		Remediation:   "Attach the Elastic IP to an instance, network interface or NAT gateway, or remove it.",
+		Severity:      plancheck.SeverityAdvisory,
+		ResourceTypes: []string{"aws_eip"},
+		Check:         checkUnattachedEIPs,
+	})
+}
+
+func checkNATGateways(in *plancheck.Input) []plancheck.Finding {
+	if in.Settings().Production {
+		return nil
+	}
+	gateways := plancheck.Resources(in.Plan, "aws_nat_gateway")
+	if len(gateways) < 2 {
+		return nil
+	}
+	sort.Slice(gateways, func(i, j int) bool { return gateways[i].Address < gateways[j].Address })
+
+	// The first gateway is the one worth keeping; report the rest.
+	var findings []plancheck.Finding
+	for _, gateway := range gateways[1:] {
+		findings = append(findings, plancheck.NewFinding(
+			"cost.nat-gateway",
+			gateway.Address,
+			fmt.Sprintf("%s is one of %d NAT gateways planned in %s; outside production one shared NAT gateway avoids paying for one per availability zone", gateway.Address, len(gateways), in.Environment),
+		))
+	}
+	return findings
+}
+
+func checkCrossZoneLoadBalancing(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, lb := range plancheck.Resources(in.Plan, "aws_lb") {
+		lbType := plancheck.LookupString(lb.AttributeValues, "load_balancer_type")
+		if lbType != "network" && lbType != "gateway" {
+			continue
+		}
+		if !plancheck.LookupBool(lb.AttributeValues, "enable_cross_zone_load_balancing") {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"cost.cross-az",
+			lb.Address,
+			fmt.Sprintf("%s is a %s load balancer with cross-zone load balancing, which bills data sent to targets in other availability zones", lb.Address, lbType),
+		).WithPath("enable_cross_zone_load_balancing").WithFix(plancheck.Fix{
+			Attribute: "enable_cross_zone_load_balancing",
+			Value:     false,
+		}))
+	}
+	return findings
+}
+
+// gp2Volumes lists where each resource type keeps its volume types, as
+// lookup paths into the nested block lists.
+var gp2Volumes = map[string][]string{
+	"aws_instance":        {"root_block_device", "ebs_block_device"},
+	"aws_launch_template": {"block_device_mappings"},
+}
+
+func checkGP2Volumes(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, resource := range plancheck.Resources(in.Plan, "aws_ebs_volume", "aws_db_instance") {
+		attribute := "type"
+		if resource.Type == "aws_db_instance" {
+			attribute = "storage_type"
+		}
+		message := fmt.Sprintf("%s uses gp2 storage", resource.Address)
+		if plancheck.LookupString(resource.AttributeValues, attribute) != "gp2" {
+			// EC2 creates gp2 volumes when no type is given.
+			config := in.ConfigResource(resource.Address)
+			if resource.Type != "aws_ebs_volume" || config == nil || config.Expressions[attribute] != nil {
+				continue
+			}
+			message = fmt.Sprintf("%s sets no type, so EC2 creates a gp2 volume", resource.Address)
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"cost.gp2",
+			resource.Address,
+			message+"; gp3 costs about 20% less for the same baseline performance",
+		).WithPath(attribute).WithFix(plancheck.Fix{Attribute: attribute, Value: "gp3"}))
+	}
+
+	for _, resource := range plancheck.Resources(in.Plan, "aws_instance", "aws_launch_template") {
+		for _, key := range gp2Volumes[resource.Type] {
+			for i, device := range plancheck.Blocks(resource.AttributeValues, key) {
+				path := fmt.Sprintf("%s.%d.volume_type", key, i)
+				volumeType := plancheck.LookupString(device, "volume_type")
+				if resource.Type == "aws_launch_template" {
+					path = fmt.Sprintf("%s.%d.ebs.0.volume_type", key, i)
+					volumeType = plancheck.LookupString(device, "ebs.0.volume_type")
+				}
+				if volumeType != "gp2" {
+					continue
+				}
+				findings = append(findings, plancheck.NewFinding(
+					"cost.gp2",
+					resource.Address,
+					fmt.Sprintf("%s has a gp2 volume in %s; gp3 costs about 20%% less for the same baseline performance", resource.Address, key),
+				).WithPath(path))
+			}
+		}
+	}
+	return findings
+}
+
+func checkStorageClasses(in *plancheck.Input) []plancheck.Finding {
+	if in.Config == nil || len(in.Config.Storage.InfrequentPrefixes) == 0 {
+		return nil
+	}
+
+	var findings []plancheck.Finding
+	for _, bucket := range plancheck.Resources(in.Plan, "aws_s3_bucket") {
+		name := plancheck.LookupString(bucket.AttributeValues, "bucket")
+		var prefixes []string
+		for pattern, listed := range in.Config.Storage.InfrequentPrefixes {
+			if exempt([]string{pattern}, name) {
+				prefixes = append(prefixes, listed...)
+			}
+		}
+		sort.Strings(prefixes)
+
+		lifecycles := bucketResources(in, "aws_s3_bucket_lifecycle_configuration", bucket)
+		tierings := bucketResources(in, "aws_s3_bucket_intelligent_tiering_configuration", bucket)
+		for _, prefix := range prefixes {
+			if transitioned(lifecycles, prefix) || tiered(tierings, prefix) {
+				continue
+			}
+			findings = append(findings, plancheck.NewFinding(
+				"cost.s3-storage-class",
+				bucket.Address,
+				fmt.Sprintf("%s keeps rarely read objects under %q in S3 Standard; no lifecycle transition or intelligent tiering covers the prefix", bucket.Address, prefix),
+			))
+		}
+	}
+	return findings
+}
+
+// transitioned reports whether an enabled lifecycle rule with a transition
+// applies to every object under prefix.
+func transitioned(lifecycles []*tfjson.StateResource, prefix string) bool {
+	for _, lifecycle := range lifecycles {
+		for _, rule := range plancheck.Blocks(lifecycle.AttributeValues, "rule") {
+			if plancheck.LookupString(rule, "status") != "Enabled" || len(plancheck.Blocks(rule, "transition")) == 0 {
+				continue
+			}
+			rulePrefix := plancheck.LookupString(rule, "prefix")
+			for _, path := range []string{"filter.0.prefix", "filter.0.and.0.prefix"} {
+				if value := plancheck.LookupString(rule, path); value != "" {
+					rulePrefix = value
+				}
+			}
+			if strings.HasPrefix(prefix, rulePrefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tiered reports whether an enabled intelligent tiering configuration applies
+// to every object under prefix.
+func tiered(tierings []*tfjson.StateResource, prefix string) bool {
+	for _, tiering := range tierings {
+		if status := plancheck.LookupString(tiering.AttributeValues, "status"); status != "" && status != "Enabled" {
+			continue
+		}
+		if strings.HasPrefix(prefix, plancheck.LookupString(tiering.AttributeValues, "filter.0.prefix")) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkLogArchive(in *plancheck.Input) []plancheck.Finding {
+	var policy plancheck.StoragePolicy
+	if in.Config != nil {
+		policy = in.Config.Storage
+	}
+	limit := policy.ArchiveDays()
+
+	var findings []plancheck.Finding
+	for _, group := range plancheck.Resources(in.Plan, "aws_cloudwatch_log_group") {
+		// Groups that never expire are logs.retention's to report.
+		days, ok := plancheck.LookupNumber(group.AttributeValues, "retention_in_days")
+		if !ok || days <= float64(limit) || archived(in, group) {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"cost.log-archive",
+			group.Address,
+			fmt.Sprintf("%s keeps logs in CloudWatch for %.0f days; beyond %d days, archiving them to S3 is far cheaper", group.Address, days, limit),
+		).WithPath("retention_in_days"))
+	}
+	return findings
+}
+
+// archived reports whether a subscription filter streams the log group
+// somewhere, by name or by reference.
+func archived(in *plancheck.Input, group *tfjson.StateResource) bool {
+	name := plancheck.LookupString(group.AttributeValues, "name")
+	for _, filter := range plancheck.Resources(in.Plan, "aws_cloudwatch_log_subscription_filter") {
+		if name != "" && plancheck.LookupString(filter.AttributeValues, "log_group_name") == name ||
+			contains(in.References(filter.Address, "log_group_name"), plancheck.ConfigAddress(group.Address)) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkProvisionedIOPS(in *plancheck.Input) []plancheck.Finding {
+	if in.Settings().Production {
+		return nil
+	}
+	var findings []plancheck.Finding
+	for _, db := range plancheck.Resources(in.Plan, "aws_db_instance") {
+		storageType := plancheck.LookupString(db.AttributeValues, "storage_type")
+		if storageType != "io1" && storageType != "io2" {
+			continue
+		}
+		message := fmt.Sprintf("%s uses %s provisioned IOPS storage in %s", db.Address, storageType, in.Environment)
+		if iops, ok := plancheck.LookupNumber(db.AttributeValues, "iops"); ok {
+			message = fmt.Sprintf("%s uses %s storage with %.0f provisioned IOPS in %s", db.Address, storageType, iops, in.Environment)
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"cost.provisioned-iops",
+			db.Address,
+			message+"; gp3 includes 3000 IOPS, enough outside production",
+		).WithPath("storage_type"))
+	}
+	return findings
+}
+
+// gravitonFamilies maps x86 instance families to their Graviton equivalent
+// and the approximate saving of moving, in percent of the on-demand price.
+// RDS and ElastiCache classes use the same families behind their "db." and
+// "cache." prefixes.
+var gravitonFamilies = map[string]struct {
+	arm     string
+	savings int
+}{
+	"t2":  {"t4g", 30},
+	"t3":  {"t4g", 20},
+	"t3a": {"t4g", 10},
+	"m5":  {"m7g", 15},
+	"m5a": {"m7g", 10},
+	"m6i": {"m7g", 15},
+	"m6a": {"m7g", 10},
+	"c5":  {"c7g", 15},
+	"c6i": {"c7g", 15},
+	"r5":  {"r7g", 15},
+	"r6i": {"r7g", 15},
+}
+
+// lambdaARMSavings is the saving, in percent of the GB-second price, of
+// running a function on arm64.
+const lambdaARMSavings = 20
+
+func checkGraviton(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, resource := range plancheck.Resources(in.Plan, instanceClassTypes...) {
+		class := instanceClasses[resource.Type]
+		value := plancheck.LookupString(resource.AttributeValues, class.attribute)
+		replacement, savings, ok := gravitonClass(value)
+		if !ok {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"cost.graviton",
+			resource.Address,
+			fmt.Sprintf("%s uses x86 class %s; %s runs on Graviton for about %d%% less", resource.Address, value, replacement, savings),
+		).WithPath(class.attribute))
+	}
+
+	for _, function := range plancheck.Resources(in.Plan, "aws_lambda_function") {
+		architectures, _ := function.AttributeValues["architectures"].([]interface{})
+		// An unset architecture is x86_64.
+		if len(architectures) > 0 && architectures[0] != "x86_64" {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"cost.graviton",
+			function.Address,
+			fmt.Sprintf("%s runs on x86_64; arm64 costs about %d%% less per GB-second", function.Address, lambdaARMSavings),
+		).WithPath("architectures"))
+	}
+	return findings
+}
+
+// gravitonClass returns the Graviton equivalent of an x86 instance class such
+// as "db.m5.large", and the saving of moving to it.
+func gravitonClass(class string) (string, int, bool) {
+	prefix := ""
+	for _, service := range []string{"db.", "cache."} {
+		if strings.HasPrefix(class, service) {
+			prefix = service
+		}
+	}
+	family, size, ok := strings.Cut(strings.TrimPrefix(class, prefix), ".")
+	graviton, known := gravitonFamilies[family]
+	if !ok || !known {
+		return "", 0, false
+	}
+	return prefix + graviton.arm + "." + size, graviton.savings, true
+}
+
+// eipAttachments lists the arguments through which other resources take an
+// Elastic IP's allocation.
+var eipAttachments = map[string]string{
+	"aws_nat_gateway":     "allocation_id",
+	"aws_eip_association": "allocation_id",
+	"aws_lb":              "subnet_mapping.allocation_id",
+}
+
+func checkUnattachedEIPs(in *plancheck.Input) []plancheck.Finding {
+	attached := map[string]bool{}
+	for resourceType, attribute := range eipAttachments {
+		for _, resource := range plancheck.Resources(in.Plan, resourceType) {
+			for _, ref := range in.References(resource.Address, attribute) {
+				attached[ref] = true
+			}
+		}
+	}
+
+	var findings []plancheck.Finding
+	for _, eip := range plancheck.Resources(in.Plan, "aws_eip") {
+		if attached[plancheck.ConfigAddress(eip.Address)] || eipAttached(in, eip) {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"cost.unattached-eip",
+			eip.Address,
+			fmt.Sprintf("%s is not attached to an instance, network interface, NAT gateway or load balancer and is billed while idle", eip.Address),
+		))
+	}
+	return findings
+}
+
+// eipAttached reports whether the Elastic IP names its own instance or
+// network interface, as a known value or an expression computed at apply.
+func eipAttached(in *plancheck.Input, eip *tfjson.StateResource) bool {
+	config := in.ConfigResource(eip.Address)
+	for _, attribute := range []string{"instance", "network_interface"} {
+		if plancheck.LookupString(eip.AttributeValues, attribute) != "" {
+			return true
+		}
+		if config != nil && config.Expressions[attribute] != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// dashboardTargets are the API, Lambda and database resources every
+// environment's dashboards must show.
+var dashboardTargets = map[string]metricTarget{
+	"aws_api_gateway_rest_api": {"ApiName", "name"},
+	"aws_lambda_function":      {"FunctionName", "function_name"},
+	"aws_db_instance":          {"DBInstanceIdentifier", "identifier"},
+	"aws_rds_cluster":          {"DBClusterIdentifier", "cluster_identifier"},
+	"aws_dynamodb_table":       {"TableName", "name"},
+}
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "dashboards.coverage",
+		Description:   "Every API, Lambda function and database must appear on a CloudWatch dashboard planned with the environment.",
+		Remediation:   "Add a metric widget for the resource to the environment's aws_cloudwatch_dashboard, using its ApiName, FunctionName, DBInstanceIdentifier, DBClusterIdentifier or TableName dimension.",
+		ResourceTypes: []string{"aws_cloudwatch_dashboard", "aws_api_gateway_rest_api", "aws_lambda_function", "aws_db_instance", "aws_rds_cluster", "aws_dynamodb_table"},
+		Check:         checkDashboardCoverage,
+	})
+}
+
+// dashboardContent is what the planned dashboards show: metric dimension
+// values by dimension name, and the text of log widget queries.
+type dashboardContent struct {
+	dimensions map[string]map[string]bool
+	queries    []string
+}
+
+func checkDashboardCoverage(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+
+	content := dashboardContent{dimensions: map[string]map[string]bool{}}
+	dashboards := plancheck.Resources(in.Plan, "aws_cloudwatch_dashboard")
+	for _, dashboard := range dashboards {
+		body := plancheck.LookupString(dashboard.AttributeValues, "dashboard_body")
+		if body == "" {
+			// Known only after apply: the missing dashboard may show any
+			// resource, so none can be reported as absent.
+			return nil
+		}
+		if err := content.add(body); err != nil {
+			findings = append(findings, plancheck.NewFinding(
+				"dashboards.coverage",
+				dashboard.Address,
+				fmt.Sprintf("%s has an invalid dashboard_body: %v", dashboard.Address, err),
+			).WithPath("dashboard_body"))
+		}
+	}
+
+	for _, resource := range plancheck.PlannedResources(in.Plan) {
+		target, ok := dashboardTargets[resource.Type]
+		if !ok || resource.Mode == tfjson.DataResourceMode {
+			continue
+		}
+		name := plancheck.LookupString(resource.AttributeValues, target.attribute)
+		if name != "" && content.shows(target.dimension, name, resource.Type) {
+			continue
+		}
+		message := fmt.Sprintf("%s is not on any CloudWatch dashboard", resource.Address)
+		if len(dashboards) == 0 {
+			message = fmt.Sprintf("%s is not on a dashboard; the plan has no aws_cloudwatch_dashboard", resource.Address)
+		}
+		findings = append(findings, plancheck.NewFinding("dashboards.coverage", resource.Address, message))
+	}
+	return findings
+}
+
+// add records what a dashboard body shows. Metric rows may use "." to repeat
+// the value at the same position in the previous row, and "..." to repeat
+// the previous row's values up to the ones that follow, as the console does:
+// ["...", "i-2"] after ["AWS/EC2", "CPUUtilization", "InstanceId", "i-1"]
+// is the same metric for instance i-2.
+func (c *dashboardContent) add(body string) error {
+	var dashboard struct {
+		Widgets []struct {
+			Type       string `json:"type"`
+			Properties struct {
+				Metrics [][]interface{} `json:"metrics"`
+				Query   string          `json:"query"`
+			} `json:"properties"`
+		} `json:"widgets"`
+	}
+	if err := json.Unmarshal([]byte(body), &dashboard); err != nil {
+		return err
+	}
+	if len(dashboard.Widgets) == 0 {
+		return fmt.Errorf("no widgets")
+	}
+
+	for i, widget := range dashboard.Widgets {
+		switch widget.Type {
+		case "log":
+			c.queries = append(c.queries, widget.Properties.Query)
+		case "metric":
+			var previous []string
+			for j, row := range widget.Properties.Metrics {
+				fields := metricFields(row, previous)
+				if len(fields) > 0 && len(fields) < 2 {
+					return fmt.Errorf("widgets[%d].properties.metrics[%d] names no metric", i, j)
+				}
+				for k := 2; k+1 < len(fields); k += 2 {
+					if c.dimensions[fields[k]] == nil {
+						c.dimensions[fields[k]] = map[string]bool{}
+					}
+					c.dimensions[fields[k]][fields[k+1]] = true
+				}
+				if len(fields) > 0 {
+					previous = fields
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// metricFields returns the namespace, metric name and dimension pairs of a
+// metric row, expanding "." and "..." against the previous row.
+func metricFields(row []interface{}, previous []string) []string {
+	var values []string
+	for _, value := range row {
+		field, ok := value.(string)
+		if !ok {
+			break // trailing rendering options, or a math expression
+		}
+		values = append(values, field)
+	}
+
+	var fields []string
+	for k, field := range values {
+		switch {
+		case field == "...":
+			rest := values[k+1:]
+			if start, end := len(fields), len(previous)-len(rest); start < end {
+				fields = append(fields, previous[start:end]...)
+			}
+			return append(fields, rest...)
+		case field == "." && k < len(previous):
+			field = previous[k]
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// shows reports whether a metric widget uses the resource's dimension, or,
+// for Lambda functions, a log widget queries the function's log group.
+func (c *dashboardContent) shows(dimension, name, resourceType string) bool {
+	if c.dimensions[dimension][name] {
+		return true
+	}
+	if resourceType == "aws_lambda_function" {
+		for _, query := range c.queries {
+			if strings.Contains(query, "/aws/lambda/"+name) {
+				return true
+			}
+		}
+	}
+	return false
+}
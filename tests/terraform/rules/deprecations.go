@@ -0,0 +1,121 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// deprecation is a resource type, or one of its arguments, that the AWS
+// provider has deprecated in favour of replacement.
+type deprecation struct {
+	resourceType string
+	// argument is the deprecated argument or nested block; empty deprecates
+	// the whole resource type.
+	argument    string
+	replacement string
+}
+
+// deprecations are the provider deprecations the rule knows about. Add an
+// entry when a provider release deprecates something this repository might
+// use, so the finding appears before the upgrade that removes it.
+var deprecations = []deprecation{
+	{"aws_launch_configuration", "", "aws_launch_template"},
+	{"aws_iam_policy_attachment", "", "aws_iam_role_policy_attachment, aws_iam_user_policy_attachment or aws_iam_group_policy_attachment"},
+	{"aws_s3_bucket", "acl", "aws_s3_bucket_acl"},
+	{"aws_s3_bucket", "grant", "aws_s3_bucket_acl"},
+	{"aws_s3_bucket", "versioning", "aws_s3_bucket_versioning"},
+	{"aws_s3_bucket", "server_side_encryption_configuration", "aws_s3_bucket_server_side_encryption_configuration"},
+	{"aws_s3_bucket", "logging", "aws_s3_bucket_logging"},
+	{"aws_s3_bucket", "lifecycle_rule", "aws_s3_bucket_lifecycle_configuration"},
+	{"aws_s3_bucket", "website", "aws_s3_bucket_website_configuration"},
+	{"aws_s3_bucket", "cors_rule", "aws_s3_bucket_cors_configuration"},
+	{"aws_s3_bucket", "replication_configuration", "aws_s3_bucket_replication_configuration"},
+	{"aws_s3_bucket", "policy", "aws_s3_bucket_policy"},
+	{"aws_s3_bucket", "acceleration_status", "aws_s3_bucket_accelerate_configuration"},
+	{"aws_s3_bucket", "request_payer", "aws_s3_bucket_request_payment_configuration"},
+	{"aws_s3_bucket", "object_lock_configuration", "object_lock_enabled and aws_s3_bucket_object_lock_configuration"},
+}
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "provider.deprecations",
+		Description:   "Resources and arguments the AWS provider has deprecated must not be used, so a provider upgrade does not break the configuration.",
+		Remediation:   "Move the configuration to the replacement named in the finding.",
+		ResourceTypes: deprecatedTypes(),
+		Check:         checkDeprecations,
+		CheckSource:   checkDeprecationsSource,
+	})
+}
+
+func deprecatedTypes() []string {
+	seen := map[string]bool{}
+	var types []string
+	for _, d := range deprecations {
+		if !seen[d.resourceType] {
+			seen[d.resourceType] = true
+			types = append(types, d.resourceType)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+func (d deprecation) message(address string) string {
+	if d.argument == "" {
+		return fmt.Sprintf("%s uses the deprecated %s; use %s", address, d.resourceType, d.replacement)
+	}
+	return fmt.Sprintf("%s sets the deprecated %s argument %s; use %s", address, d.resourceType, d.argument, d.replacement)
+}
+
+// checkDeprecations reads the configuration rather than the planned values,
+// since the provider fills in deprecated computed arguments such as
+// aws_s3_bucket's versioning whether or not they are set.
+func checkDeprecations(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, d := range deprecations {
+		for _, resource := range plancheck.Resources(in.Plan, d.resourceType) {
+			if resource.Mode == tfjson.DataResourceMode {
+				continue
+			}
+			finding := plancheck.NewFinding("provider.deprecations", resource.Address, d.message(resource.Address))
+			if d.argument == "" {
+				findings = append(findings, finding)
+				continue
+			}
+			config := in.ConfigResource(resource.Address)
+			if config == nil || config.Expressions[d.argument] == nil {
+				continue
+			}
+			findings = append(findings, finding.WithPath(d.argument))
+		}
+	}
+	return findings
+}
+
+func checkDeprecationsSource(file *plancheck.SourceFile) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, d := range deprecations {
+		for _, block := range file.Resources(d.resourceType) {
+			address := plancheck.BlockAddress(block)
+			if d.argument == "" {
+				findings = append(findings, file.StaticFinding("provider.deprecations", block, block.DefRange(), d.message(address)))
+				continue
+			}
+			if attr, ok := block.Body.Attributes[d.argument]; ok {
+				findings = append(findings, file.StaticFinding("provider.deprecations", block, attr.SrcRange, d.message(address)).WithPath(d.argument))
+				continue
+			}
+			for _, nested := range block.Body.Blocks {
+				if nested.Type == d.argument {
+					findings = append(findings, file.StaticFinding("provider.deprecations", block, nested.DefRange(), d.message(address)).WithPath(d.argument))
+					break
+				}
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,207 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// defaultTTLAttributes are the attribute names treated as expiry times when
+// the configuration does not list its own.
+var defaultTTLAttributes = []string{"ttl", "expires_at", "exp_ts"}
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "dynamodb.autoscaling",
+		Description:   "DynamoDB tables with provisioned capacity must define read and write autoscaling targets.",
+		Remediation:   "Set billing_mode = \"PAY_PER_REQUEST\", or add aws_appautoscaling_target resources for dynamodb:table:ReadCapacityUnits and dynamodb:table:WriteCapacityUnits on the table.",
+		ResourceTypes: []string{"aws_dynamodb_table", "aws_appautoscaling_target"},
+		Check:         checkDynamoDBAutoscaling,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "dynamodb.ttl",
+		Description:   "DynamoDB tables with an expiry attribute must enable TTL on it, and TTL must use one of the configured expiry attributes.",
+		Remediation:   "Add a ttl block with enabled = true and attribute_name set to the table's expiry attribute, named as listed under dynamodb.ttl_attributes.",
+		ResourceTypes: []string{"aws_dynamodb_table"},
+		Check:         checkDynamoDBTTL,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "dynamodb.gsi-schema",
+		Description:   "DynamoDB global secondary indexes must match the approved schema file.",
+		Remediation:   "Add the index to the gsi_schema file referenced from compliance.yaml, or change it to match the approved definition.",
+		ResourceTypes: []string{"aws_dynamodb_table"},
+		Check:         checkDynamoDBGSISchema,
+	})
+}
+
+// dynamoDBPolicy returns the DynamoDB settings, which are shared by every
+// environment.
+func dynamoDBPolicy(in *plancheck.Input) plancheck.DynamoDBPolicy {
+	if in.Config == nil {
+		return plancheck.DynamoDBPolicy{}
+	}
+	return in.Config.DynamoDB
+}
+
+// dynamoDBTables returns the managed tables in the plan.
+func dynamoDBTables(in *plancheck.Input) []*tfjson.StateResource {
+	var tables []*tfjson.StateResource
+	for _, table := range plancheck.Resources(in.Plan, "aws_dynamodb_table") {
+		if table.Mode != tfjson.DataResourceMode {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+func checkDynamoDBAutoscaling(in *plancheck.Input) []plancheck.Finding {
+	targets := plancheck.Resources(in.Plan, "aws_appautoscaling_target")
+
+	var findings []plancheck.Finding
+	for _, table := range dynamoDBTables(in) {
+		// PROVISIONED is the provider default when billing_mode is unset.
+		if plancheck.LookupString(table.AttributeValues, "billing_mode") == "PAY_PER_REQUEST" {
+			continue
+		}
+		name := plancheck.LookupString(table.AttributeValues, "name")
+
+		scaled := map[string]bool{}
+		for _, target := range targets {
+			if plancheck.LookupString(target.AttributeValues, "service_namespace") != "dynamodb" {
+				continue
+			}
+			if plancheck.LookupString(target.AttributeValues, "resource_id") != "table/"+name &&
+				!contains(in.References(target.Address, "resource_id"), plancheck.ConfigAddress(table.Address)) {
+				continue
+			}
+			scaled[plancheck.LookupString(target.AttributeValues, "scalable_dimension")] = true
+		}
+
+		var missing []string
+		for _, dimension := range []string{"dynamodb:table:ReadCapacityUnits", "dynamodb:table:WriteCapacityUnits"} {
+			if !scaled[dimension] {
+				missing = append(missing, dimension)
+			}
+		}
+		if len(missing) > 0 {
+			findings = append(findings, plancheck.NewFinding(
+				"dynamodb.autoscaling",
+				table.Address,
+				fmt.Sprintf("table %s uses provisioned capacity without autoscaling targets for %s", name, strings.Join(missing, ", ")),
+			).WithPath("billing_mode"))
+		}
+	}
+	return findings
+}
+
+// checkDynamoDBTTL checks the ttl block against the configured expiry
+// attribute names. A plan only declares a table's key attributes, so a table
+// without a ttl block is caught only when an expiry attribute is part of a key;
+// holding every table to a ttl block would flag tables that never expire
+// items. TTL enabled on a name outside the list is reported, which keeps the
+// naming consistent enough for that check to work.
+func checkDynamoDBTTL(in *plancheck.Input) []plancheck.Finding {
+	expiry := dynamoDBPolicy(in).TTLAttributes
+	if len(expiry) == 0 {
+		expiry = defaultTTLAttributes
+	}
+
+	var findings []plancheck.Finding
+	for _, table := range dynamoDBTables(in) {
+		name := plancheck.LookupString(table.AttributeValues, "name")
+
+		var ttl map[string]interface{}
+		if blocks := plancheck.Blocks(table.AttributeValues, "ttl"); len(blocks) > 0 {
+			ttl = blocks[0]
+		}
+		attribute := plancheck.LookupString(ttl, "attribute_name")
+		if attribute != "" {
+			switch {
+			case !plancheck.LookupBool(ttl, "enabled"):
+				findings = append(findings, plancheck.NewFinding(
+					"dynamodb.ttl",
+					table.Address,
+					fmt.Sprintf("table %s names TTL attribute %s but does not enable TTL", name, attribute),
+				).WithPath("ttl[0].enabled"))
+			case !contains(expiry, attribute):
+				findings = append(findings, plancheck.NewFinding(
+					"dynamodb.ttl",
+					table.Address,
+					fmt.Sprintf("table %s expires items by %s, which is not an expiry attribute (%s)", name, attribute, strings.Join(expiry, ", ")),
+				).WithPath("ttl[0].attribute_name"))
+			}
+			continue
+		}
+
+		for i, definition := range plancheck.Blocks(table.AttributeValues, "attribute") {
+			if candidate := plancheck.LookupString(definition, "name"); contains(expiry, candidate) {
+				findings = append(findings, plancheck.NewFinding(
+					"dynamodb.ttl",
+					table.Address,
+					fmt.Sprintf("table %s defines expiry attribute %s but has no TTL configured", name, candidate),
+				).WithPath(fmt.Sprintf("attribute[%d]", i)))
+			}
+		}
+	}
+	return findings
+}
+
+func checkDynamoDBGSISchema(in *plancheck.Input) []plancheck.Finding {
+	policy := dynamoDBPolicy(in)
+	if policy.GSISchema == "" {
+		return nil
+	}
+
+	var findings []plancheck.Finding
+	for _, table := range dynamoDBTables(in) {
+		name := plancheck.LookupString(table.AttributeValues, "name")
+		approved := policy.Indexes[name]
+
+		for i, index := range plancheck.Blocks(table.AttributeValues, "global_secondary_index") {
+			indexName := plancheck.LookupString(index, "name")
+			path := fmt.Sprintf("global_secondary_index[%d]", i)
+
+			schema, ok := approved[indexName]
+			if !ok {
+				findings = append(findings, plancheck.NewFinding(
+					"dynamodb.gsi-schema",
+					table.Address,
+					fmt.Sprintf("index %s on table %s is not in the approved schema %s", indexName, name, policy.GSISchema),
+				).WithPath(path))
+				continue
+			}
+
+			if differences := indexDifferences(schema, index); len(differences) > 0 {
+				findings = append(findings, plancheck.NewFinding(
+					"dynamodb.gsi-schema",
+					table.Address,
+					fmt.Sprintf("index %s on table %s differs from the approved schema: %s", indexName, name, strings.Join(differences, ", ")),
+				).WithPath(path))
+			}
+		}
+	}
+	return findings
+}
+
+// indexDifferences describes how a planned index differs from its approved
+// schema, one entry per differing field.
+func indexDifferences(schema plancheck.IndexSchema, index map[string]interface{}) []string {
+	fields := map[string]string{
+		"hash_key":        schema.HashKey,
+		"range_key":       schema.RangeKey,
+		"projection_type": schema.ProjectionType,
+	}
+
+	var differences []string
+	for field, want := range fields {
+		if got := plancheck.LookupString(index, field); got != want {
+			differences = append(differences, fmt.Sprintf("%s: %q (approved %q)", field, got, want))
+		}
+	}
+	sort.Strings(differences)
+	return differences
+}
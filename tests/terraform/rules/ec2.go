@@ -0,0 +1,84 @@
+package rules
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+
+	"cs450/terraformtests/plancheck"
+)
+
+const imdsv2Example = `
+resource "aws_launch_template" "api" {
+  metadata_options {
+    http_endpoint = "enabled"
+    http_tokens   = "required"
+  }
+}`
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "ec2.imdsv2",
+		Description:   "EC2 instances and launch templates must require IMDSv2 session tokens.",
+		Remediation:   `Set metadata_options { http_tokens = "required" }.`,
+		Rationale:     "IMDSv1 answers any HTTP GET from the instance, so a server-side request forgery in the application can read the instance role's credentials. IMDSv2 requires a session token obtained with a PUT, which such requests cannot make.",
+		Example:       imdsv2Example,
+		ResourceTypes: []string{"aws_instance", "aws_launch_template"},
+		Check:         checkIMDSv2,
+		CheckSource:   checkIMDSv2Source,
+	})
+}
+
+func checkIMDSv2(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, resource := range plancheck.Resources(in.Plan, "aws_instance", "aws_launch_template") {
+		tokens := plancheck.LookupString(resource.AttributeValues, "metadata_options.0.http_tokens")
+		if tokens == "required" {
+			continue
+		}
+		if tokens == "" {
+			tokens = "unset"
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"ec2.imdsv2",
+			resource.Address,
+			fmt.Sprintf("%s allows IMDSv1 (http_tokens is %s)", resource.Address, tokens),
+		).WithMessageKey("ec2.imdsv2.allows-v1", map[string]string{
+			"address":     resource.Address,
+			"http_tokens": tokens,
+		}).WithPath("metadata_options").WithFix(plancheck.Fix{
+			Block:     "metadata_options",
+			Attribute: "http_tokens",
+			Value:     "required",
+		}))
+	}
+	return findings
+}
+
+func checkIMDSv2Source(file *plancheck.SourceFile) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, block := range file.Resources("aws_instance", "aws_launch_template") {
+		tokens, rng := "unset", block.DefRange()
+		for _, nested := range block.Body.Blocks {
+			if nested.Type != "metadata_options" {
+				continue
+			}
+			rng = nested.DefRange()
+			if attr, ok := nested.Body.Attributes["http_tokens"]; ok {
+				value, known := plancheck.Literal(attr.Expr)
+				if !known || value.IsNull() || value.Type() != cty.String {
+					tokens = "required" // not a literal; left to the plan-based check
+					break
+				}
+				tokens, rng = value.AsString(), attr.SrcRange
+			}
+		}
+		if tokens == "required" {
+			continue
+		}
+		findings = append(findings, file.StaticFinding("ec2.imdsv2", block, rng,
+			fmt.Sprintf("%s allows IMDSv1 (http_tokens is %s)", plancheck.BlockAddress(block), tokens),
+		).WithPath("metadata_options"))
+	}
+	return findings
+}
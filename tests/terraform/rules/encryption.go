@@ -0,0 +1,178 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// dataStore describes how a resource type is encrypted at rest.
+type dataStore struct {
+	// encrypted reports whether the resource is encrypted, with any key.
+	encrypted func(in *plancheck.Input, resource *tfjson.StateResource) bool
+	// setting names the argument that turns encryption on, for messages,
+	// and path is its attribute path.
+	setting string
+	path    string
+	// key is the attribute naming the KMS key.
+	key string
+	// awsManaged is the alias of the service's AWS managed key.
+	awsManaged string
+}
+
+var dataStores = map[string]dataStore{
+	"aws_dynamodb_table": {
+		// Tables without server_side_encryption use an AWS owned key, which
+		// the account can neither audit nor revoke.
+		encrypted: func(_ *plancheck.Input, table *tfjson.StateResource) bool {
+			return plancheck.LookupBool(table.AttributeValues, "server_side_encryption.0.enabled")
+		},
+		setting:    "server_side_encryption { enabled = true }",
+		path:       "server_side_encryption[0].enabled",
+		key:        "server_side_encryption.0.kms_key_arn",
+		awsManaged: "alias/aws/dynamodb",
+	},
+	"aws_db_instance": {
+		encrypted: func(_ *plancheck.Input, db *tfjson.StateResource) bool {
+			return plancheck.LookupBool(db.AttributeValues, "storage_encrypted")
+		},
+		setting:    "storage_encrypted = true",
+		path:       "storage_encrypted",
+		key:        "kms_key_id",
+		awsManaged: "alias/aws/rds",
+	},
+	"aws_ebs_volume": {
+		// Volumes are also encrypted when the account encrypts new volumes by
+		// default.
+		encrypted: func(in *plancheck.Input, volume *tfjson.StateResource) bool {
+			if plancheck.LookupBool(volume.AttributeValues, "encrypted") {
+				return true
+			}
+			for _, setting := range plancheck.Resources(in.Plan, "aws_ebs_encryption_by_default") {
+				if _, set := setting.AttributeValues["enabled"]; !set || plancheck.LookupBool(setting.AttributeValues, "enabled") {
+					return true
+				}
+			}
+			return false
+		},
+		setting:    "encrypted = true",
+		path:       "encrypted",
+		key:        "kms_key_id",
+		awsManaged: "alias/aws/ebs",
+	},
+	"aws_sqs_queue": {
+		encrypted: func(in *plancheck.Input, queue *tfjson.StateResource) bool {
+			return plancheck.LookupBool(queue.AttributeValues, "sqs_managed_sse_enabled") ||
+				kmsKey(in, queue, "kms_master_key_id") != ""
+		},
+		setting:    "kms_master_key_id, or sqs_managed_sse_enabled = true",
+		path:       "kms_master_key_id",
+		key:        "kms_master_key_id",
+		awsManaged: "alias/aws/sqs",
+	},
+	"aws_sns_topic": {
+		encrypted: func(in *plancheck.Input, topic *tfjson.StateResource) bool {
+			return kmsKey(in, topic, "kms_master_key_id") != ""
+		},
+		setting:    "kms_master_key_id",
+		path:       "kms_master_key_id",
+		key:        "kms_master_key_id",
+		awsManaged: "alias/aws/sns",
+	},
+}
+
+// dataStoreTypes are the keys of dataStores, in a fixed order.
+var dataStoreTypes = []string{"aws_dynamodb_table", "aws_db_instance", "aws_ebs_volume", "aws_sqs_queue", "aws_sns_topic"}
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "encryption.at-rest",
+		Description:   "DynamoDB tables, RDS instances, EBS volumes, SQS queues and SNS topics must be encrypted at rest.",
+		Remediation:   "Turn encryption on: server_side_encryption { enabled = true } on tables, storage_encrypted on database instances, encrypted on volumes, and kms_master_key_id on queues and topics (sqs_managed_sse_enabled also suits queues).",
+		ResourceTypes: dataStoreTypes,
+		Check:         checkEncryptionAtRest,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "encryption.customer-managed-key",
+		Description:   "Resources of the types listed under encryption.customer_managed_keys in compliance.yaml must be encrypted with a customer managed KMS key.",
+		Remediation:   "Set the resource's KMS key to an aws_kms_key the account manages, instead of leaving it unset or naming an alias/aws/ key.",
+		ResourceTypes: dataStoreTypes,
+		Check:         checkCustomerManagedKeys,
+	})
+}
+
+// kmsKey returns the KMS key a resource's attribute names, "(known after
+// apply)" when the configuration sets it to a value the plan does not know,
+// or "".
+func kmsKey(in *plancheck.Input, resource *tfjson.StateResource, attribute string) string {
+	if key := plancheck.LookupString(resource.AttributeValues, attribute); key != "" {
+		return key
+	}
+	if in.Configured(resource.Address, strings.ReplaceAll(attribute, ".0.", ".")) {
+		return "(known after apply)"
+	}
+	return ""
+}
+
+// awsManagedKey reports whether key names the service's AWS managed key, by
+// alias or alias ARN.
+func awsManagedKey(key, alias string) bool {
+	return key == alias || strings.HasSuffix(key, ":"+alias)
+}
+
+func checkEncryptionAtRest(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, resourceType := range dataStoreTypes {
+		store := dataStores[resourceType]
+		for _, resource := range plancheck.Resources(in.Plan, resourceType) {
+			if resource.Mode == tfjson.DataResourceMode || store.encrypted(in, resource) {
+				continue
+			}
+			findings = append(findings, plancheck.NewFinding(
+				"encryption.at-rest",
+				resource.Address,
+				fmt.Sprintf("%s is not encrypted at rest; set %s", resource.Address, store.setting),
+			).WithPath(store.path))
+		}
+	}
+	return findings
+}
+
+// checkCustomerManagedKeys takes a key the plan only knows at apply to be
+// customer managed: AWS managed keys are named by constant aliases.
+func checkCustomerManagedKeys(in *plancheck.Input) []plancheck.Finding {
+	if in.Config == nil {
+		return nil
+	}
+
+	var findings []plancheck.Finding
+	for _, resourceType := range in.Config.Encryption.CustomerManagedKeys {
+		store, ok := dataStores[resourceType]
+		if !ok {
+			continue
+		}
+		for _, resource := range plancheck.Resources(in.Plan, resourceType) {
+			if resource.Mode == tfjson.DataResourceMode {
+				continue
+			}
+			var problem string
+			switch key := kmsKey(in, resource, store.key); {
+			case key == "":
+				problem = "names no KMS key, so it uses an AWS managed or AWS owned one"
+			case awsManagedKey(key, store.awsManaged):
+				problem = fmt.Sprintf("uses the AWS managed key %s", key)
+			default:
+				continue
+			}
+			findings = append(findings, plancheck.NewFinding(
+				"encryption.customer-managed-key",
+				resource.Address,
+				fmt.Sprintf("%s %s; %s must use a customer managed key", resource.Address, problem, resourceType),
+			).WithPath(strings.ReplaceAll(store.key, ".0.", "[0].")))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,135 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "iam.privilege-escalation",
+		Description:   "IAM policies must not grant a combination of actions known to allow privilege escalation, such as iam:PassRole with lambda:CreateFunction and lambda:InvokeFunction.",
+		Remediation:   "Split the actions between principals that need them, or scope iam:PassRole and the iam:* write actions to the specific roles, users and policies they are meant for.",
+		Rationale:     "These actions let a principal grant itself, or a resource it controls, more permissions than its policy shows, so a reviewer reading the policy underestimates what a compromised principal can do.",
+		ResourceTypes: iamPolicyTypes,
+		Check:         checkPrivilegeEscalation,
+	})
+}
+
+// escalationCombos are the built-in privilege escalation combinations, after
+// Rhino Security Labs' survey of AWS IAM escalation methods. The
+// iam.escalation_combos setting adds to them.
+var escalationCombos = []plancheck.EscalationCombo{
+	{Name: "create-policy-version", Actions: []string{"iam:CreatePolicyVersion"}},
+	{Name: "set-default-policy-version", Actions: []string{"iam:SetDefaultPolicyVersion"}},
+	{Name: "attach-user-policy", Actions: []string{"iam:AttachUserPolicy"}},
+	{Name: "attach-group-policy", Actions: []string{"iam:AttachGroupPolicy"}},
+	{Name: "attach-role-policy", Actions: []string{"iam:AttachRolePolicy"}},
+	{Name: "put-user-policy", Actions: []string{"iam:PutUserPolicy"}},
+	{Name: "put-group-policy", Actions: []string{"iam:PutGroupPolicy"}},
+	{Name: "put-role-policy", Actions: []string{"iam:PutRolePolicy"}},
+	{Name: "add-user-to-group", Actions: []string{"iam:AddUserToGroup"}},
+	{Name: "create-access-key", Actions: []string{"iam:CreateAccessKey"}},
+	{Name: "create-login-profile", Actions: []string{"iam:CreateLoginProfile"}},
+	{Name: "update-login-profile", Actions: []string{"iam:UpdateLoginProfile"}},
+	{Name: "update-assume-role-policy", Actions: []string{"iam:UpdateAssumeRolePolicy"}},
+	{Name: "pass-role-to-new-lambda", Actions: []string{"iam:PassRole", "lambda:CreateFunction", "lambda:InvokeFunction"}},
+	{Name: "pass-role-to-lambda-event-source", Actions: []string{"iam:PassRole", "lambda:CreateFunction", "lambda:CreateEventSourceMapping"}},
+	{Name: "pass-role-to-ec2", Actions: []string{"iam:PassRole", "ec2:RunInstances"}},
+	{Name: "pass-role-to-cloudformation", Actions: []string{"iam:PassRole", "cloudformation:CreateStack"}},
+	{Name: "pass-role-to-glue", Actions: []string{"iam:PassRole", "glue:CreateDevEndpoint"}},
+	{Name: "update-lambda-code", Actions: []string{"lambda:UpdateFunctionCode"}},
+	{Name: "assume-any-role", Actions: []string{"sts:AssumeRole"}, AnyResource: true},
+}
+
+// escalation is the evidence of an iam.privilege-escalation finding: the
+// combination and the statement that grants each of its actions.
+type escalation struct {
+	Combo     string            `json:"combo"`
+	GrantedBy map[string]string `json:"granted_by"`
+}
+
+func checkPrivilegeEscalation(in *plancheck.Input) []plancheck.Finding {
+	combos := escalationCombos
+	if in.Config != nil && len(in.Config.IAM.EscalationCombos) > 0 {
+		combos = append(append([]plancheck.EscalationCombo(nil), escalationCombos...), in.Config.IAM.EscalationCombos...)
+	}
+
+	var findings []plancheck.Finding
+	for _, document := range plancheck.Policies(in.Plan) {
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(document.Policy), &doc); err != nil {
+			// iam.wildcard-action reports the invalid document.
+			continue
+		}
+		statements := grantingStatements(doc)
+
+		for _, combo := range combos {
+			granted := map[string]string{}
+			var first string
+			for _, action := range combo.Actions {
+				path := grantedBy(statements, action, combo.AnyResource)
+				if path == "" {
+					break
+				}
+				granted[action] = path
+				if first == "" {
+					first = path
+				}
+			}
+			if len(granted) < len(combo.Actions) {
+				continue
+			}
+
+			parts := make([]string, 0, len(combo.Actions))
+			for _, action := range combo.Actions {
+				parts = append(parts, fmt.Sprintf("%s by %s", action, granted[action]))
+			}
+			on := ""
+			if combo.AnyResource {
+				on = ` on "*"`
+			}
+			findings = append(findings, plancheck.NewFinding(
+				"iam.privilege-escalation",
+				document.Address,
+				fmt.Sprintf("IAM policy %s allows privilege escalation through %s: it grants %s%s", document.Address, combo.Name, strings.Join(parts, ", "), on),
+			).WithPath(document.Path+"."+first).WithEvidence(escalation{Combo: combo.Name, GrantedBy: granted}))
+		}
+	}
+	return findings
+}
+
+// grantingStatements returns the Allow statements of a policy, leaving out
+// those granting the bare "*" action: iam.wildcard-action fails them, and
+// they would match every combination.
+func grantingStatements(doc map[string]interface{}) []plancheck.Statement {
+	var statements []plancheck.Statement
+	for _, statement := range plancheck.Statements(doc) {
+		if effect, _ := statement.Fields["Effect"].(string); effect != "Allow" {
+			continue
+		}
+		if contains(policyStrings(statement.Fields["Action"]), "*") {
+			continue
+		}
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+// grantedBy returns the path of the first statement granting action, on the
+// "*" resource when anyResource is set, or "".
+func grantedBy(statements []plancheck.Statement, action string, anyResource bool) string {
+	for _, statement := range statements {
+		if !policyMatches(statement.Fields["Action"], strings.ToLower(action), true) {
+			continue
+		}
+		if anyResource && !contains(policyStrings(statement.Fields["Resource"]), "*") {
+			continue
+		}
+		return statement.Path
+	}
+	return ""
+}
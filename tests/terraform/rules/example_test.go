@@ -0,0 +1,24 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// The examples tfcompliance explain prints must be valid HCL that the rule's
+// static check accepts.
+func TestRuleExamplesComply(t *testing.T) {
+	for _, rule := range plancheck.Rules() {
+		if rule.Example == "" {
+			continue
+		}
+		file, err := plancheck.ParseSource(rule.ID+".example.tf", []byte(rule.Example))
+		require.NoErrorf(t, err, "example of %s must be valid HCL", rule.ID)
+		if rule.CheckSource != nil {
+			require.Emptyf(t, plancheck.EvaluateSource(file, rule), "example of %s must comply", rule.ID)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package rules
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// Each rule ships testdata/<ruleID>/pass/*.json and testdata/<ruleID>/fail/*.json
+// plan fragments. Pass fragments must produce no findings for the rule; fail
+// fragments must produce exactly the findings recorded in the sibling
+// <name>.golden.json file. Run with -update to rewrite golden files.
+//
+// Rules that read compliance settings get them from an optional
+// testdata/<ruleID>/compliance.yaml, evaluated as environment "test", or as
+// <env> for fragments in pass/<env>/ and fail/<env>/ subdirectories. Rules
+// that compare environments read the other environments' plans from
+// testdata/<ruleID>/peers/<env>.json (plan or state JSON). Rules that read
+// the state backend or the module source get them from testdata/<ruleID>/*.tf,
+// or from .tf files next to the fragment. Rules that read runtime data from
+// the deployed stack get it from testdata/<ruleID>/runtime.json. Rules that
+// review the baseline read testdata/<ruleID>/baseline.yaml, or the
+// baseline.yaml next to the fragment. The
+// addresses a run allows to be destroyed are read from
+// testdata/<ruleID>/allow_destroy, one per line.
+//
+// Rules with a static CheckSource also ship testdata/<ruleID>/static/pass/*.tf
+// and testdata/<ruleID>/static/fail/*.tf files, checked the same way.
+var update = flag.Bool("update", false, "rewrite golden findings files")
+
+const goldenSuffix = ".golden.json"
+
+func TestRulesMatchGoldenFiles(t *testing.T) {
+	for _, rule := range plancheck.Rules() {
+		rule := rule
+		if rule.Check == nil {
+			continue
+		}
+		// Golden files record the findings as they will be once the rule
+		// leaves its canary period, so they do not change on that day.
+		rule.Canary = ""
+		t.Run(rule.ID, func(t *testing.T) {
+			dir := filepath.Join("testdata", rule.ID)
+
+			in := ruleInput(t, dir)
+
+			passCases := fragmentFiles(t, filepath.Join(dir, "pass"))
+			failCases := fragmentFiles(t, filepath.Join(dir, "fail"))
+			require.NotEmptyf(t, passCases, "rule %s must ship at least one passing fragment in %s/pass", rule.ID, dir)
+			require.NotEmptyf(t, failCases, "rule %s must ship at least one failing fragment in %s/fail", rule.ID, dir)
+
+			for _, fragment := range passCases {
+				findings := plancheck.Evaluate(in(fragment), rule)
+				require.Emptyf(t, findings, "%s must not produce findings", fragment)
+			}
+
+			for _, fragment := range failCases {
+				findings := plancheck.Evaluate(in(fragment), rule)
+				require.NotEmptyf(t, findings, "%s must produce findings", fragment)
+				plancheck.SortFindings(findings)
+
+				golden := strings.TrimSuffix(fragment, ".json") + goldenSuffix
+				if *update {
+					require.NoError(t, plancheck.WriteFindings(golden, findings))
+					continue
+				}
+
+				expected, err := plancheck.ReadFindings(golden)
+				require.NoErrorf(t, err, "golden file %s must exist (run with -update to create it)", golden)
+				require.Equalf(t, expected, findings, "findings for %s differ from %s", fragment, golden)
+			}
+		})
+	}
+}
+
+func TestStaticRulesMatchGoldenFiles(t *testing.T) {
+	for _, rule := range plancheck.Rules() {
+		rule := rule
+		if rule.CheckSource == nil {
+			continue
+		}
+		t.Run(rule.ID, func(t *testing.T) {
+			dir := filepath.Join("testdata", rule.ID, "static")
+
+			passCases := sourceFiles(t, filepath.Join(dir, "pass"))
+			failCases := sourceFiles(t, filepath.Join(dir, "fail"))
+			require.NotEmptyf(t, passCases, "rule %s must ship at least one passing file in %s/pass", rule.ID, dir)
+			require.NotEmptyf(t, failCases, "rule %s must ship at least one failing file in %s/fail", rule.ID, dir)
+
+			for _, filename := range passCases {
+				findings := plancheck.EvaluateSource(loadSource(t, filename), rule)
+				require.Emptyf(t, findings, "%s must not produce findings", filename)
+			}
+
+			for _, filename := range failCases {
+				findings := plancheck.EvaluateSource(loadSource(t, filename), rule)
+				require.NotEmptyf(t, findings, "%s must produce findings", filename)
+
+				golden := strings.TrimSuffix(filename, ".tf") + goldenSuffix
+				if *update {
+					require.NoError(t, plancheck.WriteFindings(golden, findings))
+					continue
+				}
+
+				expected, err := plancheck.ReadFindings(golden)
+				require.NoErrorf(t, err, "golden file %s must exist (run with -update to create it)", golden)
+				require.Equalf(t, expected, findings, "findings for %s differ from %s", filename, golden)
+			}
+		})
+	}
+}
+
+func TestEveryTestdataDirectoryHasARule(t *testing.T) {
+	entries, err := os.ReadDir("testdata")
+	require.NoError(t, err)
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		_, ok := plancheck.LookupRule(entry.Name())
+		require.Truef(t, ok, "testdata/%s does not belong to a registered rule", entry.Name())
+	}
+}
+
+// ruleInput returns a constructor for the inputs of a rule's fragments.
+func ruleInput(t *testing.T, dir string) func(fragment string) *plancheck.Input {
+	t.Helper()
+
+	var config *plancheck.Config
+	filename := filepath.Join(dir, "compliance.yaml")
+	if _, err := os.Stat(filename); err == nil {
+		config, err = plancheck.LoadConfig(filename)
+		require.NoError(t, err)
+	}
+
+	peerFiles, err := filepath.Glob(filepath.Join(dir, "peers", "*.json"))
+	require.NoError(t, err)
+	var peers map[string]*tfjson.Plan
+	for _, filename := range peerFiles {
+		if peers == nil {
+			peers = map[string]*tfjson.Plan{}
+		}
+		peer, err := plancheck.LoadPlanOrState(filename)
+		require.NoErrorf(t, err, "%s must be a plan or state", filename)
+		peers[strings.TrimSuffix(filepath.Base(filename), ".json")] = peer
+	}
+
+	var runtime *plancheck.Runtime
+	filename = filepath.Join(dir, "runtime.json")
+	if _, err := os.Stat(filename); err == nil {
+		runtime, err = plancheck.LoadRuntime(filename)
+		require.NoError(t, err)
+	}
+
+	baseline, err := plancheck.LoadBaseline(filepath.Join(dir, "baseline.yaml"))
+	require.NoError(t, err)
+	baselines := map[string]*plancheck.Baseline{}
+
+	var allowDestroy []string
+	if data, err := os.ReadFile(filepath.Join(dir, "allow_destroy")); err == nil {
+		allowDestroy = strings.Fields(string(data))
+	}
+
+	backend, err := plancheck.LoadBackend(dir)
+	require.NoError(t, err)
+	module, err := plancheck.LoadModule(dir)
+	require.NoError(t, err)
+	backends := map[string]*plancheck.Backend{}
+	modules := map[string]*plancheck.Module{}
+
+	return func(fragment string) *plancheck.Input {
+		// .tf files next to the fragment replace the rule's.
+		fragmentDir := filepath.Dir(fragment)
+		fragmentModule, ok := modules[fragmentDir]
+		if !ok {
+			fragmentModule, err = plancheck.LoadModule(fragmentDir)
+			require.NoError(t, err)
+			if fragmentModule == nil {
+				fragmentModule = module
+			}
+			modules[fragmentDir] = fragmentModule
+
+			fragmentBackend, err := plancheck.LoadBackend(fragmentDir)
+			require.NoError(t, err)
+			if fragmentBackend == nil {
+				fragmentBackend = backend
+			}
+			backends[fragmentDir] = fragmentBackend
+
+			fragmentBaseline := baseline
+			if _, err := os.Stat(filepath.Join(fragmentDir, "baseline.yaml")); err == nil {
+				fragmentBaseline, err = plancheck.LoadBaseline(filepath.Join(fragmentDir, "baseline.yaml"))
+				require.NoError(t, err)
+			}
+			baselines[fragmentDir] = fragmentBaseline
+		}
+		environment := "test"
+		if parent := filepath.Dir(fragment); filepath.Dir(filepath.Dir(parent)) == dir {
+			environment = filepath.Base(parent)
+		}
+		return &plancheck.Input{
+			Plan:         loadFragment(t, fragment),
+			Environment:  environment,
+			Config:       config,
+			Peers:        peers,
+			Backend:      backends[fragmentDir],
+			Module:       fragmentModule,
+			Runtime:      runtime,
+			Baseline:     baselines[fragmentDir],
+			AllowDestroy: allowDestroy,
+		}
+	}
+}
+
+func fragmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	nested, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	require.NoError(t, err)
+	matches = append(matches, nested...)
+
+	var fragments []string
+	for _, match := range matches {
+		if !strings.HasSuffix(match, goldenSuffix) {
+			fragments = append(fragments, match)
+		}
+	}
+	return fragments
+}
+
+func sourceFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	require.NoError(t, err)
+	return matches
+}
+
+func loadSource(t *testing.T, filename string) *plancheck.SourceFile {
+	t.Helper()
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	file, err := plancheck.ParseSource(filename, data)
+	require.NoErrorf(t, err, "%s must be valid HCL", filename)
+	return file
+}
+
+// loadFragment parses a plan fragment, defaulting format_version so fixtures
+// only need to spell out the parts of the plan a rule looks at.
+func loadFragment(t *testing.T, filename string) *tfjson.Plan {
+	t.Helper()
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	var raw map[string]interface{}
+	require.NoErrorf(t, json.Unmarshal(data, &raw), "%s must be valid JSON", filename)
+	if _, ok := raw["format_version"]; !ok {
+		raw["format_version"] = "1.0"
+	}
+	data, err = json.Marshal(raw)
+	require.NoError(t, err)
+
+	var plan tfjson.Plan
+	require.NoErrorf(t, json.Unmarshal(data, &plan), "%s must be a valid plan fragment", filename)
+	return &plan
+}
@@ -0,0 +1,365 @@
+// Package rules contains the built-in compliance rules. Importing it registers
+// every rule with the plancheck registry.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"cs450/terraformtests/plancheck"
+)
+
+const iamActionExample = `
+data "aws_iam_policy_document" "read_users" {
+  statement {
+    actions   = ["dynamodb:GetItem", "dynamodb:Query"]
+    resources = [aws_dynamodb_table.users.arn]
+  }
+}`
+
+const iamResourceExample = `
+data "aws_iam_policy_document" "artifacts" {
+  statement {
+    actions   = ["s3:GetObject"]
+    resources = ["${aws_s3_bucket.artifacts.arn}/*"]
+  }
+}`
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "iam.wildcard-action",
+		Description:   `IAM policy statements must not grant the "*" action, or allow with NotAction. Grants limited by a condition on the principal, source or network are advisories.`,
+		Remediation:   "List the specific actions the principal needs instead of \"*\" or NotAction.",
+		Rationale:     "A wildcard action grants every current and future action of every service, including iam:* and kms:*, so a leaked credential or a bug in the code holding it can take over the account.",
+		Example:       iamActionExample,
+		ResourceTypes: iamPolicyTypes,
+		Check: func(in *plancheck.Input) []plancheck.Finding {
+			return iamWildcardFindings(in, "iam.wildcard-action", "Action")
+		},
+		CheckSource: func(file *plancheck.SourceFile) []plancheck.Finding {
+			return iamWildcardSourceFindings(file, "iam.wildcard-action", "Action")
+		},
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "iam.service-wildcard-action",
+		Description:   `IAM policy statements must not grant service-wide or prefix wildcard actions, such as "s3:*" or "dynamodb:Get*", outside iam.allowed_action_patterns.`,
+		Remediation:   "List the specific actions the principal needs, or add the pattern to iam.allowed_action_patterns in compliance.yaml if every action it matches is intended.",
+		Rationale:     "A service wildcard grants every current and future action of the service: \"s3:*\" includes s3:DeleteBucket and s3:PutBucketPolicy, and \"iam:*\" is as good as administrator access.",
+		Example:       iamActionExample,
+		ResourceTypes: iamPolicyTypes,
+		Check:         checkServiceWildcardActions,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "iam.wildcard-resource",
+		Description:   `IAM policy statements must not apply to the "*" resource, or allow with NotResource. Grants limited by a condition on the principal, source or network are advisories.`,
+		Remediation:   "Scope the statement to the ARNs it applies to instead of \"*\" or NotResource.",
+		Rationale:     "A statement on every resource reaches data the principal has no business with, such as other groups' tables and buckets, and keeps doing so as new resources are created.",
+		Example:       iamResourceExample,
+		ResourceTypes: iamPolicyTypes,
+		Check: func(in *plancheck.Input) []plancheck.Finding {
+			return iamWildcardFindings(in, "iam.wildcard-resource", "Resource")
+		},
+		CheckSource: func(file *plancheck.SourceFile) []plancheck.Finding {
+			return iamWildcardSourceFindings(file, "iam.wildcard-resource", "Resource")
+		},
+	})
+}
+
+// iamPolicyTypes are the resource and data source types holding the policy
+// documents plancheck.Policies returns.
+var iamPolicyTypes = []string{
+	"aws_iam_policy",
+	"aws_iam_role_policy",
+	"aws_iam_user_policy",
+	"aws_iam_group_policy",
+	"aws_iam_role",
+	"aws_iam_policy_document",
+}
+
+func iamWildcardFindings(in *plancheck.Input, ruleID, field string) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, document := range plancheck.Policies(in.Plan) {
+		wildcards, err := plancheck.FindWildcards(document.Policy, field)
+		if err != nil {
+			findings = append(findings, plancheck.NewFinding(
+				ruleID,
+				document.Address,
+				fmt.Sprintf("IAM policy %s must contain valid JSON: %v", document.Address, err),
+			).WithPath(document.Path))
+			continue
+		}
+
+		for _, wildcard := range wildcards {
+			finding := plancheck.NewFinding(
+				ruleID,
+				document.Address,
+				wildcardMessage(document.Address, field, wildcard),
+			).WithPath(document.Path + "." + wildcard.Path)
+			finding.Severity = wildcardSeverity(wildcard)
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// wildcardMessage describes a wildcard grant of field, naming the condition
+// keys that limit it.
+func wildcardMessage(address, field string, wildcard plancheck.PolicyWildcard) string {
+	message := fmt.Sprintf("IAM policy %s contains wildcard %s", address, field)
+	if wildcard.Negated {
+		message = fmt.Sprintf("IAM policy %s allows every %s but those in Not%s", address, strings.ToLower(field), field)
+	}
+	if len(wildcard.Constraints) > 0 {
+		message += fmt.Sprintf(", limited by condition on %s", strings.Join(wildcard.Constraints, ", "))
+	}
+	return message
+}
+
+// wildcardSeverity demotes a wildcard grant to an advisory when its
+// statement's conditions tie it to specific principals, sources or networks;
+// the grant is still broader than it needs to be, but reaches little.
+func wildcardSeverity(wildcard plancheck.PolicyWildcard) plancheck.Severity {
+	if len(wildcard.Constraints) > 0 {
+		return plancheck.SeverityAdvisory
+	}
+	return ""
+}
+
+func checkServiceWildcardActions(in *plancheck.Input) []plancheck.Finding {
+	var allowed []string
+	if in.Config != nil {
+		allowed = in.Config.IAM.AllowedActionPatterns
+	}
+
+	var findings []plancheck.Finding
+	for _, document := range plancheck.Policies(in.Plan) {
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(document.Policy), &doc); err != nil {
+			// iam.wildcard-action reports the invalid document.
+			continue
+		}
+
+		for _, statement := range plancheck.Statements(doc) {
+			if !statement.Allows() {
+				continue
+			}
+			constraints := statement.Constraints()
+			for i, name := range statement.Action {
+				if !serviceWildcard(name) || allowedAction(allowed, name) {
+					continue
+				}
+				finding := plancheck.NewFinding(
+					"iam.service-wildcard-action",
+					document.Address,
+					fmt.Sprintf("IAM policy %s grants wildcard action %q", document.Address, name),
+				).WithPath(document.Path + "." + statement.ElementPath("Action", i))
+				finding.Severity = wildcardSeverity(plancheck.PolicyWildcard{Constraints: constraints})
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
+
+// serviceWildcard reports whether action is a wildcard within one service,
+// such as "s3:*" or "dynamodb:Get*". The bare "*" is iam.wildcard-action's.
+func serviceWildcard(action string) bool {
+	action = strings.TrimSpace(action)
+	return strings.Contains(action, ":") && strings.ContainsAny(action, "*?")
+}
+
+// allowedAction reports whether a wildcard action matches one of the allowed
+// patterns. IAM action names are case-insensitive.
+func allowedAction(patterns []string, action string) bool {
+	action = strings.ToLower(strings.TrimSpace(action))
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(strings.ToLower(pattern), action); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// policyDocumentArguments maps policy JSON fields to the arguments of an
+// aws_iam_policy_document statement block.
+var policyDocumentArguments = map[string]string{
+	"Action":      "actions",
+	"NotAction":   "not_actions",
+	"Resource":    "resources",
+	"NotResource": "not_resources",
+}
+
+// iamWildcardSourceFindings checks the literal policy documents (including
+// jsonencode) of the resources iamPolicyTypes lists, the inline_policy blocks
+// of roles, and aws_iam_policy_document data sources in a single file, as
+// iamWildcardFindings checks them in a plan.
+func iamWildcardSourceFindings(file *plancheck.SourceFile, ruleID, field string) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, block := range file.Resources("aws_iam_policy", "aws_iam_role_policy", "aws_iam_user_policy", "aws_iam_group_policy") {
+		findings = append(findings, literalPolicyFindings(file, ruleID, field, block, block.Body, "policy")...)
+	}
+	for _, block := range file.Resources("aws_iam_role") {
+		var index int
+		for _, inline := range block.Body.Blocks {
+			if inline.Type != "inline_policy" {
+				continue
+			}
+			findings = append(findings, literalPolicyFindings(file, ruleID, field, block, inline.Body, fmt.Sprintf("inline_policy[%d].policy", index))...)
+			index++
+		}
+	}
+	for _, block := range file.DataSources("aws_iam_policy_document") {
+		findings = append(findings, policyDocumentFindings(file, ruleID, field, block)...)
+	}
+	return findings
+}
+
+// literalPolicyFindings checks the policy attribute of body, a resource of
+// block or one of its nested blocks, when it is a literal. path is the
+// attribute's path within the resource.
+func literalPolicyFindings(file *plancheck.SourceFile, ruleID, field string, block *hclsyntax.Block, body *hclsyntax.Body, path string) []plancheck.Finding {
+	attr, ok := body.Attributes["policy"]
+	if !ok {
+		return nil
+	}
+	value, ok := plancheck.Literal(attr.Expr)
+	if !ok || value.IsNull() || value.Type() != cty.String {
+		return nil
+	}
+	wildcards, err := plancheck.FindWildcards(value.AsString(), field)
+	if err != nil {
+		return nil
+	}
+	var findings []plancheck.Finding
+	for _, wildcard := range wildcards {
+		finding := file.StaticFinding(ruleID, block, attr.SrcRange,
+			wildcardMessage(plancheck.BlockAddress(block), field, wildcard),
+		).WithPath(path + "." + wildcard.Path)
+		finding.Severity = wildcardSeverity(wildcard)
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// policyDocumentFindings checks the statement blocks of an
+// aws_iam_policy_document data source. Each is turned into the JSON
+// statement terraform renders, so effect, not_actions, not_resources and
+// condition blocks count as they do in the plan. Actions and resources that
+// are not literals, and the not_actions and not_resources of a statement
+// whose effect is not, are left to the plan-based check.
+func policyDocumentFindings(file *plancheck.SourceFile, ruleID, field string, block *hclsyntax.Block) []plancheck.Finding {
+	var findings []plancheck.Finding
+	var index int
+	for _, statement := range block.Body.Blocks {
+		if statement.Type != "statement" {
+			continue
+		}
+		path := fmt.Sprintf("statement[%d]", index)
+		index++
+
+		policy, err := json.Marshal(map[string]interface{}{"Statement": statementFields(statement.Body)})
+		if err != nil {
+			continue
+		}
+		wildcards, err := plancheck.FindWildcards(string(policy), field)
+		if err != nil {
+			continue
+		}
+		for _, wildcard := range wildcards {
+			// "Statement.NotAction" or "Statement.Action[1]".
+			element := strings.TrimPrefix(wildcard.Path, "Statement.")
+			name, suffix := element, ""
+			if i := strings.Index(element, "["); i >= 0 {
+				name, suffix = element[:i], element[i:]
+			}
+			argument := policyDocumentArguments[name]
+			finding := file.StaticFinding(ruleID, block, statement.Body.Attributes[argument].SrcRange,
+				wildcardMessage(plancheck.BlockAddress(block), field, wildcard),
+			).WithPath(path + "." + argument + suffix)
+			finding.Severity = wildcardSeverity(wildcard)
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// statementFields returns the JSON fields of an aws_iam_policy_document
+// statement block. An effect that is not a literal is left empty, so the
+// statement neither allows nor denies. not_actions and not_resources are kept
+// even when not literal, since they grant everything else whatever they
+// list; condition values that are not literal, such as a resource's ARN, are
+// taken to name specific values.
+func statementFields(body *hclsyntax.Body) map[string]interface{} {
+	fields := map[string]interface{}{"Effect": "Allow"}
+	if attr, ok := body.Attributes["effect"]; ok {
+		fields["Effect"], _ = literalString(attr)
+	}
+	for name, argument := range policyDocumentArguments {
+		attr, ok := body.Attributes[argument]
+		if !ok {
+			continue
+		}
+		values, literal := plancheck.LiteralStrings(attr.Expr)
+		if !literal && !strings.HasPrefix(name, "Not") {
+			continue
+		}
+		fields[name] = jsonStrings(values)
+	}
+
+	conditions := map[string]interface{}{}
+	for _, condition := range body.Blocks {
+		if condition.Type != "condition" {
+			continue
+		}
+		test, ok := literalString(condition.Body.Attributes["test"])
+		if !ok {
+			continue
+		}
+		variable, ok := literalString(condition.Body.Attributes["variable"])
+		if !ok {
+			continue
+		}
+		values := []string{"${unknown}"}
+		if attr, ok := condition.Body.Attributes["values"]; ok {
+			if literal, ok := plancheck.LiteralStrings(attr.Expr); ok {
+				values = literal
+			}
+		}
+		keys, _ := conditions[test].(map[string]interface{})
+		if keys == nil {
+			keys = map[string]interface{}{}
+			conditions[test] = keys
+		}
+		keys[variable] = jsonStrings(values)
+	}
+	if len(conditions) > 0 {
+		fields["Condition"] = conditions
+	}
+	return fields
+}
+
+// literalString returns the value of attr when it is a literal string.
+func literalString(attr *hclsyntax.Attribute) (string, bool) {
+	if attr == nil {
+		return "", false
+	}
+	value, ok := plancheck.Literal(attr.Expr)
+	if !ok || value.IsNull() || value.Type() != cty.String {
+		return "", false
+	}
+	return value.AsString(), true
+}
+
+// jsonStrings converts values to the list json.Unmarshal would produce.
+func jsonStrings(values []string) []interface{} {
+	list := make([]interface{}, len(values))
+	for i, value := range values {
+		list[i] = value
+	}
+	return list
+}
@@ -0,0 +1,37 @@
+package rules
+
+import (
+	"fmt"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:          "import.target-exists",
+		Description: "Every import block must name an object that exists in the account and region it is imported from.",
+		Remediation: "Correct the id of the import block, or the provider it imports with, so it names the deployed object; remove the block if the object was deleted.",
+		Rationale:   "An import of a missing object fails the apply halfway through, after other changes of the same run have been made.",
+		Check:       checkImportTargets,
+	})
+}
+
+func checkImportTargets(in *plancheck.Input) []plancheck.Finding {
+	if in.Runtime == nil || len(in.Runtime.Imports) == 0 {
+		return nil
+	}
+
+	var findings []plancheck.Finding
+	for _, imported := range in.Module.Imports() {
+		target, ok := in.Runtime.Imports[imported.To]
+		if !ok || target.Exists {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"import.target-exists",
+			imported.To,
+			fmt.Sprintf("%s is imported from %q, but no such %s exists", imported.To, target.ID, imported.ResourceType()),
+		))
+	}
+	return findings
+}
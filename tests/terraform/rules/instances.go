@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// instanceClasses maps each resource type to the argument holding its
+// instance class and the service list in InstanceTypePolicy it is checked
+// against.
+var instanceClasses = map[string]struct {
+	attribute string
+	service   string
+}{
+	"aws_instance":                      {"instance_type", "ec2"},
+	"aws_launch_template":               {"instance_type", "ec2"},
+	"aws_db_instance":                   {"instance_class", "rds"},
+	"aws_rds_cluster_instance":          {"instance_class", "rds"},
+	"aws_elasticache_cluster":           {"node_type", "elasticache"},
+	"aws_elasticache_replication_group": {"node_type", "elasticache"},
+}
+
+// instanceClassTypes are the keys of instanceClasses, sorted. It is set
+// before any init function runs, so rules in other files may use it.
+var instanceClassTypes = func() []string {
+	types := make([]string, 0, len(instanceClasses))
+	for resourceType := range instanceClasses {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+	return types
+}()
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "instances.approved-types",
+		Description:   "EC2, RDS and ElastiCache instance classes must be on the environment's instance_types list in compliance.yaml.",
+		Remediation:   "Pick an approved instance class, or get the class added to the environment's instance_types list.",
+		ResourceTypes: instanceClassTypes,
+		Check:         checkInstanceTypes,
+	})
+}
+
+func checkInstanceTypes(in *plancheck.Input) []plancheck.Finding {
+	policy := in.Settings().InstanceTypes
+	if policy == nil {
+		return nil
+	}
+	approved := map[string][]string{"ec2": policy.EC2, "rds": policy.RDS, "elasticache": policy.ElastiCache}
+
+	var findings []plancheck.Finding
+	for _, resource := range plancheck.Resources(in.Plan, instanceClassTypes...) {
+		class := instanceClasses[resource.Type]
+		value := plancheck.LookupString(resource.AttributeValues, class.attribute)
+		// Launch templates may leave the type to the group using them.
+		if value == "" || exempt(approved[class.service], value) {
+			continue
+		}
+		allowed := "none"
+		if list := approved[class.service]; len(list) > 0 {
+			allowed = strings.Join(list, ", ")
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"instances.approved-types",
+			resource.Address,
+			fmt.Sprintf("%s uses %s, which is not approved for %s in %s (approved: %s)", resource.Address, value, class.service, in.Environment, allowed),
+		).WithPath(class.attribute))
+	}
+	return findings
+}
@@ -0,0 +1,345 @@
+package rules
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cs450/terraformtests/plancheck"
+)
+
+const lambdaConcurrencyExample = `
+resource "aws_lambda_function" "download" {
+  function_name                  = "model-download-handler"
+  role                           = aws_iam_role.download.arn
+  runtime                        = "python3.12"
+  handler                        = "download_handler.lambda_handler"
+  filename                       = "download.zip"
+  reserved_concurrent_executions = 50
+}`
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "lambda.code-signing",
+		Description:   "Lambda functions must reference a code signing configuration unless the environment exempts them.",
+		Remediation:   "Set code_signing_config_arn to an aws_lambda_code_signing_config, or list the function under lambda.code_signing_exceptions for a non-production environment.",
+		ResourceTypes: []string{"aws_lambda_function"},
+		Check:         checkLambdaCodeSigning,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "lambda.tracing",
+		Description:   "Lambda functions must enable active X-Ray tracing unless the environment exempts them.",
+		Remediation:   `Set tracing_config { mode = "Active" }, or list the function under lambda.tracing_exceptions for a non-production environment.`,
+		ResourceTypes: []string{"aws_lambda_function"},
+		Check:         checkLambdaTracing,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "lambda.runtime",
+		Description:   "Lambda functions must use a supported runtime: one on lambda.runtimes in compliance.yaml, or one AWS has not deprecated.",
+		Remediation:   "Move the function to a supported runtime, e.g. the newest python3.x, and test it there.",
+		Rationale:     "AWS stops applying security patches to a deprecated runtime and later blocks creating and updating functions that use it, so a fix cannot be deployed when it is needed.",
+		ResourceTypes: []string{"aws_lambda_function"},
+		Check:         checkLambdaRuntime,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "lambda.limits",
+		Description:   "Lambda function timeouts and memory must stay within lambda.max_timeout and lambda.max_memory in compliance.yaml.",
+		Remediation:   "Lower timeout or memory_size, or raise the limit in compliance.yaml after checking the cost.",
+		Rationale:     "A function billed for 15 minutes and 10 GB per call turns a retry storm or a hung dependency into a large bill before anyone is paged.",
+		ResourceTypes: []string{"aws_lambda_function"},
+		Check:         checkLambdaLimits,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "lambda.environment-secrets",
+		Description:   "Lambda environment variables must not hold secrets such as passwords, tokens or access keys.",
+		Remediation:   "Store the secret in Secrets Manager or SSM Parameter Store, pass the function its ARN or name, and read it at runtime.",
+		Rationale:     "Environment variables are shown in the console and returned by GetFunctionConfiguration to anyone who may read the function, and end up in the state file and plan output.",
+		ResourceTypes: []string{"aws_lambda_function"},
+		Check:         checkLambdaEnvironmentSecrets,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "lambda.concurrency",
+		Description:   "Lambda functions listed under lambda.concurrency in compliance.yaml must set reserved or provisioned concurrency.",
+		Remediation:   "Set reserved_concurrent_executions on the function, or add an aws_lambda_provisioned_concurrency_config for it.",
+		Rationale:     "Without reserved concurrency, a burst on one function can take the account's whole concurrency pool and throttle the registry API handlers.",
+		Example:       lambdaConcurrencyExample,
+		ResourceTypes: []string{"aws_lambda_function", "aws_lambda_provisioned_concurrency_config"},
+		Check:         checkLambdaConcurrency,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "lambda.execution-role",
+		Description:   "Lambda functions must run as an execution role defined in the same plan.",
+		Remediation:   "Define the function's role, with only the permissions it needs, next to the function and reference its arn.",
+		Rationale:     "A role defined elsewhere can gain permissions nobody reviewing this configuration sees, and is often shared with other functions that need far more.",
+		ResourceTypes: []string{"aws_lambda_function", "aws_iam_role"},
+		Check:         checkLambdaExecutionRole,
+	})
+}
+
+func checkLambdaCodeSigning(in *plancheck.Input) []plancheck.Finding {
+	exceptions := in.Settings().LambdaExceptions().CodeSigningExceptions
+
+	var findings []plancheck.Finding
+	for _, function := range plancheck.Resources(in.Plan, "aws_lambda_function") {
+		name := plancheck.LookupString(function.AttributeValues, "function_name")
+		if exempt(exceptions, name) {
+			continue
+		}
+		// The ARN of a config created in the same apply is not known yet, so
+		// a reference to one counts too.
+		if plancheck.LookupString(function.AttributeValues, "code_signing_config_arn") != "" ||
+			len(in.References(function.Address, "code_signing_config_arn")) > 0 {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"lambda.code-signing",
+			function.Address,
+			fmt.Sprintf("function %s has no code signing configuration", name),
+		).WithPath("code_signing_config_arn"))
+	}
+	return findings
+}
+
+func checkLambdaTracing(in *plancheck.Input) []plancheck.Finding {
+	exceptions := in.Settings().LambdaExceptions().TracingExceptions
+
+	var findings []plancheck.Finding
+	for _, function := range plancheck.Resources(in.Plan, "aws_lambda_function") {
+		name := plancheck.LookupString(function.AttributeValues, "function_name")
+		if exempt(exceptions, name) {
+			continue
+		}
+		mode := plancheck.LookupString(function.AttributeValues, "tracing_config.0.mode")
+		if mode == "Active" {
+			continue
+		}
+		if mode == "" {
+			mode = "unset"
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"lambda.tracing",
+			function.Address,
+			fmt.Sprintf("function %s does not enable active tracing (mode is %s)", name, mode),
+		).WithPath("tracing_config").WithFix(plancheck.Fix{
+			Block:     "tracing_config",
+			Attribute: "mode",
+			Value:     "Active",
+		}))
+	}
+	return findings
+}
+
+func lambdaPolicy(in *plancheck.Input) plancheck.FunctionPolicy {
+	if in.Config == nil {
+		return plancheck.FunctionPolicy{}
+	}
+	return in.Config.Lambda
+}
+
+func checkLambdaRuntime(in *plancheck.Input) []plancheck.Finding {
+	supported := lambdaPolicy(in).SupportedRuntimes()
+
+	var findings []plancheck.Finding
+	for _, function := range plancheck.Resources(in.Plan, "aws_lambda_function") {
+		runtime := plancheck.LookupString(function.AttributeValues, "runtime")
+		// Container image functions bring their own runtime.
+		if runtime == "" || exempt(supported, runtime) {
+			continue
+		}
+		name := plancheck.LookupString(function.AttributeValues, "function_name")
+		findings = append(findings, plancheck.NewFinding(
+			"lambda.runtime",
+			function.Address,
+			fmt.Sprintf("function %s uses %s, which is not a supported runtime (supported: %s)", name, runtime, strings.Join(supported, ", ")),
+		).WithPath("runtime"))
+	}
+	return findings
+}
+
+func checkLambdaLimits(in *plancheck.Input) []plancheck.Finding {
+	policy := lambdaPolicy(in)
+	limits := []struct {
+		attribute, unit string
+		limit           int
+	}{
+		{"timeout", "s", policy.TimeoutLimit()},
+		{"memory_size", " MB", policy.MemoryLimit()},
+	}
+
+	var findings []plancheck.Finding
+	for _, function := range plancheck.Resources(in.Plan, "aws_lambda_function") {
+		name := plancheck.LookupString(function.AttributeValues, "function_name")
+		for _, l := range limits {
+			value, ok := plancheck.LookupNumber(function.AttributeValues, l.attribute)
+			if !ok || value <= float64(l.limit) {
+				continue
+			}
+			findings = append(findings, plancheck.NewFinding(
+				"lambda.limits",
+				function.Address,
+				fmt.Sprintf("function %s sets %s to %g%s, over the limit of %d%s", name, l.attribute, value, l.unit, l.limit, l.unit),
+			).WithPath(l.attribute))
+		}
+	}
+	return findings
+}
+
+var (
+	// secretValuePatterns match values that are credentials whatever the
+	// variable is called.
+	secretValuePatterns = []struct {
+		kind    string
+		pattern *regexp.Regexp
+	}{
+		{"an AWS access key ID", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+		{"a private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+		{"a GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+		{"a Slack token", regexp.MustCompile(`\bxox[abpors]-[A-Za-z0-9-]{10,}`)},
+		{"a JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	}
+
+	// secretNameWords are the words of a variable name, split on '_', that
+	// mark its value as a secret, e.g. DB_PASSWORD or JWT_SECRET.
+	secretNameWords = map[string]bool{
+		"PASSWORD": true, "PASSWD": true, "SECRET": true, "TOKEN": true,
+		"APIKEY": true, "CREDENTIAL": true, "CREDENTIALS": true, "PRIVATE": true,
+	}
+
+	// secretReferenceWords mark variables that say where a secret or other
+	// resource is kept rather than hold a secret, e.g. JWT_SECRET_ARN or
+	// DDB_TABLE_TOKENS.
+	secretReferenceWords = map[string]bool{
+		"ARN": true, "NAME": true, "ID": true, "PARAM": true, "PARAMETER": true, "PATH": true, "TABLE": true,
+	}
+)
+
+// secretKind says why value, of the environment variable name, looks like a
+// secret, or returns "".
+func secretKind(name, value string) string {
+	for _, secret := range secretValuePatterns {
+		if secret.pattern.MatchString(value) {
+			return "looks like " + secret.kind
+		}
+	}
+
+	// Names are only a hint: a reference to a secret store is fine.
+	if value == "" || strings.HasPrefix(value, "arn:aws:secretsmanager:") || strings.HasPrefix(value, "arn:aws:ssm:") {
+		return ""
+	}
+	named := false
+	for _, word := range strings.Split(strings.ToUpper(strings.ReplaceAll(name, "API_KEY", "APIKEY")), "_") {
+		if secretReferenceWords[word] {
+			return ""
+		}
+		named = named || secretNameWords[word]
+	}
+	if named {
+		return "is named like a secret"
+	}
+	return ""
+}
+
+func checkLambdaEnvironmentSecrets(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, function := range plancheck.Resources(in.Plan, "aws_lambda_function") {
+		value, _ := plancheck.Lookup(function.AttributeValues, "environment.0.variables")
+		variables, _ := value.(map[string]interface{})
+		names := make([]string, 0, len(variables))
+		for name := range variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		functionName := plancheck.LookupString(function.AttributeValues, "function_name")
+		for _, name := range names {
+			value, _ := variables[name].(string)
+			kind := secretKind(name, value)
+			if kind == "" {
+				continue
+			}
+			// No path: the evidence would copy the secret into every report.
+			findings = append(findings, plancheck.NewFinding(
+				"lambda.environment-secrets",
+				function.Address,
+				fmt.Sprintf("environment variable %s of function %s %s; pass a Secrets Manager or SSM reference instead", name, functionName, kind),
+			))
+		}
+	}
+	return findings
+}
+
+func checkLambdaConcurrency(in *plancheck.Input) []plancheck.Finding {
+	required := lambdaPolicy(in).Concurrency
+	if len(required) == 0 {
+		return nil
+	}
+
+	// Provisioned concurrency configs name their function by reference, or
+	// by the function's name.
+	provisioned := map[string]bool{}
+	for _, config := range plancheck.Resources(in.Plan, "aws_lambda_provisioned_concurrency_config") {
+		provisioned[plancheck.LookupString(config.AttributeValues, "function_name")] = true
+		for _, ref := range in.References(config.Address, "function_name") {
+			provisioned[ref] = true
+		}
+	}
+
+	var findings []plancheck.Finding
+	for _, function := range plancheck.Resources(in.Plan, "aws_lambda_function") {
+		name := plancheck.LookupString(function.AttributeValues, "function_name")
+		if !exempt(required, name) {
+			continue
+		}
+		if reserved, ok := plancheck.LookupNumber(function.AttributeValues, "reserved_concurrent_executions"); ok && reserved > 0 {
+			continue
+		}
+		if provisioned[name] || provisioned[plancheck.ConfigAddress(function.Address)] {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"lambda.concurrency",
+			function.Address,
+			fmt.Sprintf("function %s sets neither reserved nor provisioned concurrency", name),
+		).WithPath("reserved_concurrent_executions"))
+	}
+	return findings
+}
+
+// checkLambdaExecutionRole passes functions whose role ARN is unknown until
+// apply, as only a role created by the plan has no ARN yet, or is the ARN of
+// a role in the plan.
+func checkLambdaExecutionRole(in *plancheck.Input) []plancheck.Finding {
+	roles := map[string]bool{}
+	for _, role := range plancheck.Resources(in.Plan, "aws_iam_role") {
+		if arn := plancheck.LookupString(role.AttributeValues, "arn"); arn != "" {
+			roles[arn] = true
+		}
+	}
+
+	var findings []plancheck.Finding
+	for _, function := range plancheck.Resources(in.Plan, "aws_lambda_function") {
+		role := plancheck.LookupString(function.AttributeValues, "role")
+		if role == "" || roles[role] {
+			continue
+		}
+		name := plancheck.LookupString(function.AttributeValues, "function_name")
+		findings = append(findings, plancheck.NewFinding(
+			"lambda.execution-role",
+			function.Address,
+			fmt.Sprintf("function %s runs as %s, which is not a role this plan defines", name, role),
+		).WithPath("role"))
+	}
+	return findings
+}
+
+// exempt reports whether name matches one of the glob patterns.
+func exempt(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
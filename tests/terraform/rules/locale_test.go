@@ -0,0 +1,81 @@
+package rules
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+const localesDir = "../locales"
+
+var placeholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// Every catalog must translate registered rules, and message keys some golden
+// finding uses, with only the placeholders those findings supply.
+func TestCatalogsMatchRules(t *testing.T) {
+	args := goldenMessageArgs(t)
+	catalogs, err := filepath.Glob(filepath.Join(localesDir, "*.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, catalogs, "no message catalogs in %s", localesDir)
+
+	for _, filename := range catalogs {
+		locale := strings.TrimSuffix(filepath.Base(filename), ".yaml")
+		catalog, err := plancheck.LoadCatalog(localesDir, locale)
+		require.NoError(t, err)
+		require.NotNil(t, catalog, filename)
+
+		for id := range catalog.Rules {
+			_, ok := plancheck.LookupRule(id)
+			require.Truef(t, ok, "%s translates unknown rule %s", filename, id)
+		}
+		for key, template := range catalog.Messages {
+			known, ok := args[key]
+			require.Truef(t, ok, "%s translates message %s, which no golden finding uses", filename, key)
+			for _, match := range placeholder.FindAllStringSubmatch(template, -1) {
+				require.Truef(t, known[match[1]], "%s: message %s uses unknown placeholder {%s}", filename, key, match[1])
+			}
+		}
+	}
+}
+
+// goldenMessageArgs returns the argument names of every message key in the
+// golden findings.
+func goldenMessageArgs(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+
+	args := map[string]map[string]bool{}
+	err := filepath.Walk("testdata", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, goldenSuffix) {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var findings []plancheck.Finding
+		if err := json.Unmarshal(data, &findings); err != nil {
+			return err
+		}
+		for _, finding := range findings {
+			if finding.MessageKey == "" {
+				continue
+			}
+			if args[finding.MessageKey] == nil {
+				args[finding.MessageKey] = map[string]bool{}
+			}
+			for name := range finding.MessageArgs {
+				args[finding.MessageKey][name] = true
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return args
+}
@@ -0,0 +1,70 @@
+package rules
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// defaultLogRetentionDays is the retention suggested for log groups that keep
+// logs forever.
+const defaultLogRetentionDays = 30
+
+const logRetentionExample = `
+resource "aws_cloudwatch_log_group" "api" {
+  name              = "/ecs/api"
+  retention_in_days = %d
+}`
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "logs.retention",
+		Description:   "CloudWatch log groups must set retention_in_days instead of keeping logs forever.",
+		Remediation:   fmt.Sprintf("Set retention_in_days (e.g. %d) on the log group.", defaultLogRetentionDays),
+		Rationale:     "Log groups without retention keep every event forever and their storage cost only grows; logs that must be kept longer belong in an S3 archive.",
+		Example:       fmt.Sprintf(logRetentionExample, defaultLogRetentionDays),
+		ResourceTypes: []string{"aws_cloudwatch_log_group"},
+		Check:         checkLogRetention,
+		CheckSource:   checkLogRetentionSource,
+	})
+}
+
+func checkLogRetention(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, group := range plancheck.Resources(in.Plan, "aws_cloudwatch_log_group") {
+		if days, ok := plancheck.LookupNumber(group.AttributeValues, "retention_in_days"); ok && days > 0 {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"logs.retention",
+			group.Address,
+			fmt.Sprintf("log group %s never expires its logs", group.Address),
+		).WithMessageKey("logs.retention.never-expires", map[string]string{
+			"address": group.Address,
+		}).WithPath("retention_in_days").WithFix(plancheck.Fix{
+			Attribute: "retention_in_days",
+			Value:     float64(defaultLogRetentionDays),
+		}))
+	}
+	return findings
+}
+
+func checkLogRetentionSource(file *plancheck.SourceFile) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, block := range file.Resources("aws_cloudwatch_log_group") {
+		rng := block.DefRange()
+		if attr, ok := block.Body.Attributes["retention_in_days"]; ok {
+			value, known := plancheck.Literal(attr.Expr)
+			if !known || value.IsNull() || value.Type() != cty.Number || !value.Equals(cty.Zero).True() {
+				continue
+			}
+			rng = attr.SrcRange
+		}
+		findings = append(findings, file.StaticFinding("logs.retention", block, rng,
+			fmt.Sprintf("log group %s never expires its logs", plancheck.BlockAddress(block)),
+		).WithPath("retention_in_days"))
+	}
+	return findings
+}
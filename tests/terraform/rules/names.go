@@ -0,0 +1,128 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// nameScope is how widely a resource name must be unique.
+type nameScope int
+
+const (
+	scopeGlobal  nameScope = iota // every AWS account, e.g. S3 buckets
+	scopeAccount                  // one account, all regions, e.g. IAM
+	scopeRegion                   // one account and region
+)
+
+// uniqueNames maps resource types to the argument holding their unique name.
+var uniqueNames = map[string]struct {
+	attribute string
+	scope     nameScope
+}{
+	"aws_s3_bucket":               {"bucket", scopeGlobal},
+	"aws_iam_role":                {"name", scopeAccount},
+	"aws_iam_policy":              {"name", scopeAccount},
+	"aws_iam_user":                {"name", scopeAccount},
+	"aws_cloudwatch_log_group":    {"name", scopeRegion},
+	"aws_dynamodb_table":          {"name", scopeRegion},
+	"aws_lambda_function":         {"function_name", scopeRegion},
+	"aws_sqs_queue":               {"name", scopeRegion},
+	"aws_ecr_repository":          {"name", scopeRegion},
+	"aws_secretsmanager_secret":   {"name", scopeRegion},
+	"aws_ecs_cluster":             {"name", scopeRegion},
+	"aws_api_gateway_rest_api":    {"name", scopeRegion},
+	"aws_cloudwatch_metric_alarm": {"alarm_name", scopeRegion},
+}
+
+func init() {
+	types := make([]string, 0, len(uniqueNames))
+	for t := range uniqueNames {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	plancheck.Register(plancheck.Rule{
+		ID:            "names.collision",
+		Description:   "Names that must be unique per account, region or globally must not be reused by another environment.",
+		Remediation:   "Include the environment in the name, e.g. \"pkg-artifacts-${var.environment}\".",
+		ResourceTypes: types,
+		Check:         checkNameCollisions,
+	})
+}
+
+// namedResource is a resource's unique name and where it lives.
+type namedResource struct {
+	address, name, region string
+}
+
+func checkNameCollisions(in *plancheck.Input) []plancheck.Finding {
+	if len(in.Peers) == 0 {
+		return nil
+	}
+	account := in.Settings().Account
+
+	peers := make([]string, 0, len(in.Peers))
+	for peer := range in.Peers {
+		if peer != in.Environment {
+			peers = append(peers, peer)
+		}
+	}
+	sort.Strings(peers)
+
+	var findings []plancheck.Finding
+	own := uniqueResourceNames(in.Plan, in.Regions())
+	for _, peer := range peers {
+		settings := in.Config.Environment(peer)
+		sameAccount := account == "" || settings.Account == "" || account == settings.Account
+		region := settings.Region
+		if region == "" {
+			region = in.DefaultRegion
+		}
+		theirs := uniqueResourceNames(in.Peers[peer], plancheck.NewRegionIndex(in.Peers[peer], region))
+
+		for resourceType, names := range own {
+			scope := uniqueNames[resourceType].scope
+			if scope != scopeGlobal && !sameAccount {
+				continue
+			}
+			for _, mine := range names {
+				for _, other := range theirs[resourceType] {
+					if mine.name != other.name || scope == scopeRegion && mine.region != other.region {
+						continue
+					}
+					findings = append(findings, plancheck.NewFinding(
+						"names.collision",
+						mine.address,
+						fmt.Sprintf("%s name %q is also used by %s in environment %s", resourceType, mine.name, other.address, peer),
+					).WithPath(uniqueNames[resourceType].attribute))
+				}
+			}
+		}
+	}
+	plancheck.SortFindings(findings)
+	return findings
+}
+
+func uniqueResourceNames(plan *tfjson.Plan, regions *plancheck.RegionIndex) map[string][]namedResource {
+	names := map[string][]namedResource{}
+	for _, resource := range plancheck.PlannedResources(plan) {
+		unique, ok := uniqueNames[resource.Type]
+		if !ok || resource.Mode == tfjson.DataResourceMode {
+			continue
+		}
+		name := plancheck.LookupString(resource.AttributeValues, unique.attribute)
+		if name == "" {
+			continue
+		}
+		names[resource.Type] = append(names[resource.Type], namedResource{
+			address: resource.Address,
+			name:    name,
+			region:  regions.RegionOf(resource.Address),
+		})
+	}
+	return names
+}
@@ -0,0 +1,262 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// openCIDRs are the CIDR blocks meaning the whole internet.
+var openCIDRs = []string{"0.0.0.0/0", "::/0"}
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "sg.open-ingress",
+		Description:   "Security groups must not allow ingress from 0.0.0.0/0 or ::/0, except to the ports listed under network.public_ingress.",
+		Remediation:   "Narrow the rule's CIDR blocks, or reference the security group traffic comes from; a public load balancer's port belongs under network.public_ingress in compliance.yaml.",
+		ResourceTypes: []string{"aws_security_group", "aws_security_group_rule", "aws_vpc_security_group_ingress_rule"},
+		Check:         checkOpenIngress,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "sg.all-traffic",
+		Description:   "Security group rules should name a protocol and ports rather than allow all traffic (protocol -1).",
+		Remediation:   "Set the rule's protocol to tcp, udp or icmp and its ports to those the traffic uses.",
+		ResourceTypes: []string{"aws_security_group", "aws_security_group_rule", "aws_vpc_security_group_ingress_rule", "aws_vpc_security_group_egress_rule"},
+		Severity:      plancheck.SeverityAdvisory,
+		Check:         checkAllTraffic,
+	})
+}
+
+// sgRule is one ingress or egress rule, inline in an aws_security_group or
+// a resource of its own.
+type sgRule struct {
+	// Address is the resource declaring the rule, and Path the rule's block
+	// within it, e.g. "ingress[1]", or "" for a rule resource.
+	Address string
+	Path    string
+	// Groups are the configuration addresses of the security group the rule
+	// belongs to, for matching network.public_ingress.
+	Groups    []string
+	Ingress   bool
+	Protocol  string
+	FromPort  int
+	ToPort    int
+	CIDRs     []string
+	cidrsPath string
+	protoPath string
+}
+
+// attribute returns the path of one of the rule's attributes.
+func (r sgRule) attribute(name string) string {
+	if r.Path == "" {
+		return name
+	}
+	return r.Path + "." + name
+}
+
+// describe names the rule in messages.
+func (r sgRule) describe() string {
+	if r.Path == "" {
+		return r.Address
+	}
+	return r.Path + " of " + r.Address
+}
+
+// allTraffic reports whether the rule covers every protocol.
+func (r sgRule) allTraffic() bool {
+	return r.Protocol == "-1" || strings.EqualFold(r.Protocol, "all")
+}
+
+// hasPorts reports whether the rule's protocol has ports: TCP or UDP. ICMP
+// rules use from_port and to_port for the type and code.
+func (r sgRule) hasPorts() bool {
+	switch strings.ToLower(r.Protocol) {
+	case "tcp", "udp", "6", "17":
+		return true
+	}
+	return false
+}
+
+// ports describes the protocol and ports the rule opens.
+func (r sgRule) ports() string {
+	switch {
+	case r.allTraffic():
+		return "all traffic"
+	case !r.hasPorts():
+		return fmt.Sprintf("protocol %s", r.Protocol)
+	case r.FromPort == r.ToPort:
+		return fmt.Sprintf("%s port %d", r.Protocol, r.FromPort)
+	}
+	return fmt.Sprintf("%s ports %d-%d", r.Protocol, r.FromPort, r.ToPort)
+}
+
+// securityGroupRules returns the rules of every managed security group and
+// rule resource in the plan.
+func securityGroupRules(in *plancheck.Input) []sgRule {
+	number := func(values map[string]interface{}, key string) int {
+		n, _ := plancheck.LookupNumber(values, key)
+		return int(n)
+	}
+
+	var rules []sgRule
+	for _, group := range plancheck.Resources(in.Plan, "aws_security_group") {
+		if group.Mode == tfjson.DataResourceMode {
+			continue
+		}
+		for _, direction := range []string{"ingress", "egress"} {
+			for i, block := range plancheck.Blocks(group.AttributeValues, direction) {
+				rule := sgRule{
+					Address:  group.Address,
+					Path:     fmt.Sprintf("%s[%d]", direction, i),
+					Groups:   []string{plancheck.ConfigAddress(group.Address)},
+					Ingress:  direction == "ingress",
+					Protocol: plancheck.LookupString(block, "protocol"),
+					FromPort: number(block, "from_port"),
+					ToPort:   number(block, "to_port"),
+				}
+				rule.CIDRs = append(policyStrings(block["cidr_blocks"]), policyStrings(block["ipv6_cidr_blocks"])...)
+				rule.cidrsPath = rule.attribute(openCIDRKey(block, "cidr_blocks", "ipv6_cidr_blocks"))
+				rule.protoPath = rule.attribute("protocol")
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	for _, resource := range plancheck.Resources(in.Plan, "aws_security_group_rule") {
+		if resource.Mode == tfjson.DataResourceMode {
+			continue
+		}
+		values := resource.AttributeValues
+		rules = append(rules, sgRule{
+			Address:   resource.Address,
+			Groups:    in.References(resource.Address, "security_group_id"),
+			Ingress:   plancheck.LookupString(values, "type") == "ingress",
+			Protocol:  plancheck.LookupString(values, "protocol"),
+			FromPort:  number(values, "from_port"),
+			ToPort:    number(values, "to_port"),
+			CIDRs:     append(policyStrings(values["cidr_blocks"]), policyStrings(values["ipv6_cidr_blocks"])...),
+			cidrsPath: openCIDRKey(values, "cidr_blocks", "ipv6_cidr_blocks"),
+			protoPath: "protocol",
+		})
+	}
+
+	for _, resource := range plancheck.Resources(in.Plan, "aws_vpc_security_group_ingress_rule", "aws_vpc_security_group_egress_rule") {
+		if resource.Mode == tfjson.DataResourceMode {
+			continue
+		}
+		values := resource.AttributeValues
+		rule := sgRule{
+			Address:   resource.Address,
+			Groups:    in.References(resource.Address, "security_group_id"),
+			Ingress:   resource.Type == "aws_vpc_security_group_ingress_rule",
+			Protocol:  plancheck.LookupString(values, "ip_protocol"),
+			FromPort:  number(values, "from_port"),
+			ToPort:    number(values, "to_port"),
+			cidrsPath: openCIDRKey(values, "cidr_ipv4", "cidr_ipv6"),
+			protoPath: "ip_protocol",
+		}
+		for _, key := range []string{"cidr_ipv4", "cidr_ipv6"} {
+			if cidr := plancheck.LookupString(values, key); cidr != "" {
+				rule.CIDRs = append(rule.CIDRs, cidr)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// openCIDRKey returns the first of keys whose CIDR block, or list of them,
+// is open to the internet, or the first key.
+func openCIDRKey(values map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		for _, cidr := range policyStrings(values[key]) {
+			if contains(openCIDRs, cidr) {
+				return key
+			}
+		}
+	}
+	return keys[0]
+}
+
+// publicPorts returns the ports network.public_ingress opens on any of
+// groups.
+func publicPorts(in *plancheck.Input, groups []string) map[int]bool {
+	ports := map[int]bool{}
+	if in.Config == nil {
+		return ports
+	}
+	for _, allowed := range in.Config.Network.PublicIngress {
+		for _, group := range groups {
+			if exempt(allowed.SecurityGroups, group) {
+				for _, port := range allowed.Ports {
+					ports[port] = true
+				}
+			}
+		}
+	}
+	return ports
+}
+
+// allowedPublicly reports whether every port a rule opens is listed for its
+// security group. Only TCP and UDP ports can be listed.
+func allowedPublicly(in *plancheck.Input, rule sgRule) bool {
+	if !rule.hasPorts() {
+		return false
+	}
+	ports := publicPorts(in, rule.Groups)
+	if rule.ToPort-rule.FromPort >= len(ports) {
+		return false
+	}
+	for port := rule.FromPort; port <= rule.ToPort; port++ {
+		if !ports[port] {
+			return false
+		}
+	}
+	return true
+}
+
+func checkOpenIngress(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, rule := range securityGroupRules(in) {
+		if !rule.Ingress {
+			continue
+		}
+		var open []string
+		for _, cidr := range rule.CIDRs {
+			if contains(openCIDRs, cidr) {
+				open = append(open, cidr)
+			}
+		}
+		if len(open) == 0 || allowedPublicly(in, rule) {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"sg.open-ingress",
+			rule.Address,
+			fmt.Sprintf("%s allows %s from %s", rule.describe(), rule.ports(), strings.Join(open, " and ")),
+		).WithPath(rule.cidrsPath))
+	}
+	return findings
+}
+
+func checkAllTraffic(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, rule := range securityGroupRules(in) {
+		if !rule.allTraffic() {
+			continue
+		}
+		direction := "egress"
+		if rule.Ingress {
+			direction = "ingress"
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"sg.all-traffic",
+			rule.Address,
+			fmt.Sprintf("%s allows all %s traffic, whatever the protocol and port", rule.describe(), direction),
+		).WithPath(rule.protoPath))
+	}
+	return findings
+}
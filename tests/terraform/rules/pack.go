@@ -0,0 +1,13 @@
+package rules
+
+import "cs450/terraformtests/plancheck"
+
+// Version is the policy pack version of the rules in this package, which
+// every report names. Changing the rules requires a bump of the part
+// plancheck.RequiredBump gives, an entry in CHANGELOG.md, and pack.json
+// recorded again with go test ./rules -run TestPackVersion -update.
+const Version = "1.10.0"
+
+func init() {
+	plancheck.SetPackVersion(Version)
+}
@@ -0,0 +1,56 @@
+package rules
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+const packFile = "pack.json"
+
+// pack.json records the rules of the released Version. A change to the rules
+// must come with a Version bump at least as large as plancheck.RequiredBump
+// asks for and a CHANGELOG.md entry; -update then records the new pack.
+func TestPackVersion(t *testing.T) {
+	current := plancheck.CurrentPack(plancheck.Rules())
+	require.Equal(t, Version, current.Version)
+
+	changelog, err := os.ReadFile("CHANGELOG.md")
+	require.NoError(t, err)
+	require.Contains(t, string(changelog), "\n## "+Version+"\n", "CHANGELOG.md must describe pack %s", Version)
+
+	// Before the first release, every rule is new.
+	recorded, err := plancheck.ReadPack(packFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		recorded, err = plancheck.Pack{Version: "0.0.0"}, nil
+	}
+	require.NoError(t, err)
+	changes := plancheck.DiffPacks(recorded, current)
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+
+	if recorded.Version == Version {
+		require.Empty(t, changes, "the rules changed since pack %s; bump Version in pack.go, add it to CHANGELOG.md and rerun with -update:\n%s",
+			Version, strings.Join(lines, "\n"))
+		return
+	}
+	if bump := plancheck.RequiredBump(changes); bump >= 0 {
+		bumped, err := plancheck.Bumped(recorded.Version, Version, bump)
+		require.NoError(t, err)
+		require.True(t, bumped, "%s -> %s is too small a bump for these changes, which need a %s bump:\n%s",
+			recorded.Version, Version, []string{"major", "minor", "patch"}[bump], strings.Join(lines, "\n"))
+	}
+
+	if !*update {
+		require.Failf(t, "pack.json is out of date", "%s records pack %s, not %s; rerun with -update", packFile, recorded.Version, Version)
+	}
+	require.NoError(t, plancheck.WritePack(packFile, current))
+}
@@ -0,0 +1,114 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cs450/terraformtests/plancheck"
+)
+
+const passRoleExample = `
+data "aws_iam_policy_document" "deploy" {
+  statement {
+    actions   = ["iam:PassRole"]
+    resources = [aws_iam_role.api_task.arn]
+
+    condition {
+      test     = "StringEquals"
+      variable = "iam:PassedToService"
+      values   = ["ecs-tasks.amazonaws.com"]
+    }
+  }
+}`
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "iam.passrole-scope",
+		Description:   `IAM policy statements granting iam:PassRole must name the roles they pass, not "*" or every role of an account. Statements without an iam:PassedToService condition are advisories.`,
+		Remediation:   "List the ARNs of the roles the principal passes, and add an iam:PassedToService condition naming the services it passes them to.",
+		Rationale:     "iam:PassRole on every role lets a principal hand any role, administrator ones included, to a Lambda function, ECS task or instance it controls and act with that role's permissions.",
+		Example:       passRoleExample,
+		ResourceTypes: iamPolicyTypes,
+		Check:         checkPassRoleScope,
+	})
+}
+
+func checkPassRoleScope(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, document := range plancheck.Policies(in.Plan) {
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(document.Policy), &doc); err != nil {
+			// iam.wildcard-action reports the invalid document.
+			continue
+		}
+
+		for _, statement := range grantingStatements(doc) {
+			if !policyMatches(statement.Fields["Action"], "iam:passrole", true) {
+				continue
+			}
+			if _, negated := statement.Fields["NotResource"]; negated {
+				findings = append(findings, plancheck.NewFinding(
+					"iam.passrole-scope",
+					document.Address,
+					fmt.Sprintf("IAM policy %s grants iam:PassRole on every role but those in NotResource", document.Address),
+				).WithPath(document.Path+"."+statement.Path+".NotResource"))
+			}
+			for i, resource := range statement.Resource {
+				if !unscopedRole(resource) {
+					continue
+				}
+				findings = append(findings, plancheck.NewFinding(
+					"iam.passrole-scope",
+					document.Address,
+					fmt.Sprintf("IAM policy %s grants iam:PassRole on %q rather than on specific roles", document.Address, resource),
+				).WithPath(document.Path+"."+statement.ElementPath("Resource", i)))
+			}
+			if !passedToService(statement) {
+				finding := plancheck.NewFinding(
+					"iam.passrole-scope",
+					document.Address,
+					fmt.Sprintf("IAM policy %s grants iam:PassRole without an iam:PassedToService condition", document.Address),
+				).WithPath(document.Path + "." + statement.Path)
+				finding.Severity = plancheck.SeverityAdvisory
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
+
+// unscopedRole reports whether a PassRole resource covers every role, of one
+// account or of any: "*", arn:aws:iam::*:role/... or
+// arn:aws:iam::123456789012:role/*. Wildcards in a role's path or name, such
+// as role/pkg-*, still scope it.
+func unscopedRole(resource string) bool {
+	resource = strings.TrimSpace(resource)
+	if resource == "*" {
+		return true
+	}
+	parts := strings.SplitN(resource, ":", 6)
+	if len(parts) < 6 {
+		return false
+	}
+	account, name := parts[4], parts[5]
+	if strings.ContainsAny(account, "*?") {
+		return true
+	}
+	return strings.Trim(strings.TrimPrefix(name, "role/"), "*") == ""
+}
+
+// passedToService reports whether the statement limits the services a role
+// may be passed to.
+func passedToService(statement plancheck.Statement) bool {
+	for _, condition := range statement.Condition {
+		operator := strings.ToLower(condition.Operator)
+		if !strings.EqualFold(condition.Key, "iam:PassedToService") || strings.Contains(operator, "not") || strings.HasSuffix(operator, "ifexists") {
+			continue
+		}
+		if len(condition.Values) > 0 && !contains(condition.Values, "*") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "s3.replication",
+		Description:   "Buckets the environment marks for disaster recovery must replicate to a bucket in the DR region through a tightly scoped IAM role.",
+		Remediation:   "Add an aws_s3_bucket_replication_configuration with an enabled rule to a bucket in the DR region, using a role whose policy names the source and destination buckets.",
+		ResourceTypes: []string{"aws_s3_bucket", "aws_s3_bucket_replication_configuration"},
+		Check:         checkS3Replication,
+	})
+}
+
+func checkS3Replication(in *plancheck.Input) []plancheck.Finding {
+	policy := in.Settings().Replication
+	if policy == nil || len(policy.Buckets) == 0 {
+		return nil
+	}
+	wanted := map[string]bool{}
+	for _, name := range policy.Buckets {
+		wanted[name] = true
+	}
+
+	replications := plancheck.Resources(in.Plan, "aws_s3_bucket_replication_configuration")
+
+	var findings []plancheck.Finding
+	for _, bucket := range plancheck.Resources(in.Plan, "aws_s3_bucket") {
+		name := plancheck.LookupString(bucket.AttributeValues, "bucket")
+		if bucket.Mode == tfjson.DataResourceMode || !wanted[name] {
+			continue
+		}
+
+		var configured bool
+		for _, replication := range replications {
+			if !contains(in.References(replication.Address, "bucket"), plancheck.ConfigAddress(bucket.Address)) {
+				continue
+			}
+			configured = true
+			findings = append(findings, checkReplication(in, replication, policy)...)
+		}
+		if !configured {
+			findings = append(findings, plancheck.NewFinding(
+				"s3.replication",
+				bucket.Address,
+				fmt.Sprintf("bucket %s has no aws_s3_bucket_replication_configuration; %s requires replication to %s", name, in.Environment, policy.Region),
+			))
+		}
+	}
+	return findings
+}
+
+func checkReplication(in *plancheck.Input, replication *tfjson.StateResource, policy *plancheck.ReplicationPolicy) []plancheck.Finding {
+	var findings []plancheck.Finding
+
+	// One rule must be both enabled and aimed at the DR region; an enabled
+	// rule to another region next to a disabled one to the DR region is not
+	// disaster recovery.
+	var replicated bool
+	for i, rule := range plancheck.Blocks(replication.AttributeValues, "rule") {
+		if plancheck.LookupString(rule, "status") != "Enabled" {
+			continue
+		}
+		for _, destination := range in.References(replication.Address, fmt.Sprintf("rule.%d.destination.bucket", i)) {
+			for _, address := range plannedAddresses(in, destination) {
+				replicated = replicated || in.Regions().RegionOf(address) == policy.Region
+			}
+		}
+	}
+	if !replicated {
+		findings = append(findings, plancheck.NewFinding(
+			"s3.replication",
+			replication.Address,
+			fmt.Sprintf("%s has no enabled rule replicating to a bucket in %s", replication.Address, policy.Region),
+		).WithPath("rule"))
+	}
+
+	var roles []string
+	for _, ref := range in.References(replication.Address, "role") {
+		if resourceType(ref) == "aws_iam_role" {
+			roles = append(roles, ref)
+		}
+	}
+	if len(roles) == 0 {
+		return append(findings, plancheck.NewFinding(
+			"s3.replication",
+			replication.Address,
+			fmt.Sprintf("%s must use a replication role defined in this configuration", replication.Address),
+		).WithPath("role"))
+	}
+
+	for _, role := range roles {
+		documents := rolePolicies(in, role)
+		if len(documents) == 0 {
+			findings = append(findings, plancheck.NewFinding(
+				"s3.replication",
+				replication.Address,
+				fmt.Sprintf("replication role %s has no policies", role),
+			).WithPath("role"))
+		}
+		for _, document := range documents {
+			for _, field := range []string{"Action", "Resource"} {
+				paths, err := plancheck.PolicyWildcards(document.Policy, field)
+				if err != nil {
+					continue
+				}
+				for _, path := range paths {
+					findings = append(findings, plancheck.NewFinding(
+						"s3.replication",
+						document.Address,
+						fmt.Sprintf("replication role %s is granted wildcard %s by %s", role, field, document.Address),
+					).WithPath(document.Path+"."+path))
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// rolePolicies returns the inline, role policy and attached managed policy
+// documents of the aws_iam_role at configuration address role.
+func rolePolicies(in *plancheck.Input, role string) []plancheck.PolicyDocument {
+	var documents []plancheck.PolicyDocument
+	for _, address := range plannedAddresses(in, role) {
+		for _, resource := range plancheck.Resources(in.Plan, "aws_iam_role") {
+			if resource.Address != address {
+				continue
+			}
+			documents = append(documents, plancheck.ParsePolicy(resource)...)
+		}
+	}
+
+	for _, resource := range plancheck.Resources(in.Plan, "aws_iam_role_policy") {
+		if contains(in.References(resource.Address, "role"), role) {
+			documents = append(documents, plancheck.ParsePolicy(resource)...)
+		}
+	}
+
+	for _, attachment := range plancheck.Resources(in.Plan, "aws_iam_role_policy_attachment") {
+		if !contains(in.References(attachment.Address, "role"), role) {
+			continue
+		}
+		for _, ref := range in.References(attachment.Address, "policy_arn") {
+			for _, resource := range plancheck.Resources(in.Plan, "aws_iam_policy") {
+				if plancheck.ConfigAddress(resource.Address) == ref {
+					documents = append(documents, plancheck.ParsePolicy(resource)...)
+				}
+			}
+		}
+	}
+	return documents
+}
+
+// plannedAddresses returns the planned instances of a configuration address.
+func plannedAddresses(in *plancheck.Input, configAddress string) []string {
+	var addresses []string
+	for _, resource := range plancheck.PlannedResources(in.Plan) {
+		if resource != nil && plancheck.ConfigAddress(resource.Address) == configAddress {
+			addresses = append(addresses, resource.Address)
+		}
+	}
+	return addresses
+}
+
+// resourceType returns the type of a configuration address such as
+// "module.s3.aws_iam_role.replication".
+func resourceType(address string) string {
+	parts := strings.Split(address, ".")
+	for len(parts) > 2 && parts[0] == "module" {
+		parts = parts[2:]
+	}
+	if parts[0] == "data" && len(parts) > 1 {
+		return parts[1]
+	}
+	return parts[0]
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
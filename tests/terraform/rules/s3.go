@@ -0,0 +1,212 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// s3WriteActions are the S3 actions that change a bucket or its objects.
+var s3WriteActions = []string{
+	"s3:PutObject", "s3:PutObjectAcl", "s3:DeleteObject", "s3:DeleteObjectVersion", "s3:RestoreObject",
+	"s3:AbortMultipartUpload", "s3:ReplicateObject", "s3:PutBucketPolicy", "s3:DeleteBucketPolicy",
+	"s3:PutBucketAcl", "s3:DeleteBucket",
+}
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "s3.encryption",
+		Description:   "S3 buckets must have default server-side encryption.",
+		Remediation:   "Add an aws_s3_bucket_server_side_encryption_configuration for the bucket with an apply_server_side_encryption_by_default rule.",
+		ResourceTypes: []string{"aws_s3_bucket", "aws_s3_bucket_server_side_encryption_configuration"},
+		Check:         checkS3Encryption,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "s3.public-access-block",
+		Description:   "S3 buckets must block public ACLs and policies, on the bucket or the account.",
+		Remediation:   "Add an aws_s3_bucket_public_access_block for the bucket with block_public_acls, block_public_policy, ignore_public_acls and restrict_public_buckets set to true.",
+		ResourceTypes: []string{"aws_s3_bucket", "aws_s3_bucket_public_access_block", "aws_s3_account_public_access_block"},
+		Check:         checkS3PublicAccessBlock,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "s3.versioning",
+		Description:   "S3 buckets must be versioned.",
+		Remediation:   "Add an aws_s3_bucket_versioning for the bucket with versioning_configuration { status = \"Enabled\" }.",
+		ResourceTypes: []string{"aws_s3_bucket", "aws_s3_bucket_versioning"},
+		Check:         checkS3Versioning,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "s3.public-write",
+		Description:   "S3 bucket policies must not allow any principal (\"*\") to write to the bucket or its objects.",
+		Remediation:   "Name the principals that may write in the statement's Principal, or grant them access through their own IAM policies.",
+		ResourceTypes: []string{"aws_s3_bucket", "aws_s3_bucket_policy"},
+		Check:         checkS3PublicWrite,
+	})
+}
+
+// s3Buckets returns the managed buckets in the plan.
+func s3Buckets(in *plancheck.Input) []*tfjson.StateResource {
+	var buckets []*tfjson.StateResource
+	for _, bucket := range plancheck.Resources(in.Plan, "aws_s3_bucket") {
+		if bucket.Mode != tfjson.DataResourceMode {
+			buckets = append(buckets, bucket)
+		}
+	}
+	return buckets
+}
+
+// bucketName names a bucket in messages by its name, or its address when the
+// name is not known until apply.
+func bucketName(bucket *tfjson.StateResource) string {
+	if name := plancheck.LookupString(bucket.AttributeValues, "bucket"); name != "" {
+		return name
+	}
+	return bucket.Address
+}
+
+// bucketEncrypted reports whether a bucket has default encryption, set on the
+// bucket itself by provider versions before 4 or by its own resource.
+func bucketEncrypted(in *plancheck.Input, bucket *tfjson.StateResource) bool {
+	if plancheck.LookupString(bucket.AttributeValues,
+		"server_side_encryption_configuration.0.rule.0.apply_server_side_encryption_by_default.0.sse_algorithm") != "" {
+		return true
+	}
+	for _, encryption := range bucketResources(in, "aws_s3_bucket_server_side_encryption_configuration", bucket) {
+		if plancheck.LookupString(encryption.AttributeValues, "rule.0.apply_server_side_encryption_by_default.0.sse_algorithm") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// bucketVersioned reports whether a bucket has versioning enabled, on the
+// bucket itself by provider versions before 4 or by its own resource.
+func bucketVersioned(in *plancheck.Input, bucket *tfjson.StateResource) bool {
+	if plancheck.LookupBool(bucket.AttributeValues, "versioning.0.enabled") {
+		return true
+	}
+	for _, versioning := range bucketResources(in, "aws_s3_bucket_versioning", bucket) {
+		if plancheck.LookupString(versioning.AttributeValues, "versioning_configuration.0.status") == "Enabled" {
+			return true
+		}
+	}
+	return false
+}
+
+func checkS3Encryption(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, bucket := range s3Buckets(in) {
+		if !bucketEncrypted(in, bucket) {
+			findings = append(findings, plancheck.NewFinding(
+				"s3.encryption",
+				bucket.Address,
+				fmt.Sprintf("bucket %s has no default server-side encryption", bucketName(bucket)),
+			))
+		}
+	}
+	return findings
+}
+
+// checkS3PublicAccessBlock accepts an account-wide block in place of the
+// bucket's own, as it applies to every bucket in the account.
+func checkS3PublicAccessBlock(in *plancheck.Input) []plancheck.Finding {
+	for _, block := range plancheck.Resources(in.Plan, "aws_s3_account_public_access_block") {
+		if len(unblocked(block)) == 0 {
+			return nil
+		}
+	}
+
+	var findings []plancheck.Finding
+	for _, bucket := range s3Buckets(in) {
+		blocks := bucketResources(in, "aws_s3_bucket_public_access_block", bucket)
+		if len(blocks) == 0 {
+			findings = append(findings, plancheck.NewFinding(
+				"s3.public-access-block",
+				bucket.Address,
+				fmt.Sprintf("bucket %s has no aws_s3_bucket_public_access_block", bucketName(bucket)),
+			))
+			continue
+		}
+		for _, block := range blocks {
+			if missing := unblocked(block); len(missing) > 0 {
+				findings = append(findings, plancheck.NewFinding(
+					"s3.public-access-block",
+					block.Address,
+					fmt.Sprintf("public access block of bucket %s does not set %s to true", bucketName(bucket), strings.Join(missing, ", ")),
+				).WithPath(missing[0]))
+			}
+		}
+	}
+	return findings
+}
+
+// unblocked returns the settings of a public access block that are not true.
+func unblocked(block *tfjson.StateResource) []string {
+	var missing []string
+	for _, setting := range publicAccessBlockSettings {
+		if !plancheck.LookupBool(block.AttributeValues, setting) {
+			missing = append(missing, setting)
+		}
+	}
+	return missing
+}
+
+func checkS3Versioning(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, bucket := range s3Buckets(in) {
+		if !bucketVersioned(in, bucket) {
+			findings = append(findings, plancheck.NewFinding(
+				"s3.versioning",
+				bucket.Address,
+				fmt.Sprintf("bucket %s is not versioned", bucketName(bucket)),
+			))
+		}
+	}
+	return findings
+}
+
+// checkS3PublicWrite reads the policies of aws_s3_bucket_policy resources and
+// the policy argument of buckets, which provider versions before 4 accept.
+// Conditions are not weighed: a public principal narrowed by a condition is
+// still reported, and can be suppressed with a justification.
+func checkS3PublicWrite(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, resource := range plancheck.Resources(in.Plan, "aws_s3_bucket", "aws_s3_bucket_policy") {
+		if resource.Mode == tfjson.DataResourceMode {
+			continue
+		}
+		var document map[string]interface{}
+		if json.Unmarshal([]byte(plancheck.LookupString(resource.AttributeValues, "policy")), &document) != nil {
+			continue
+		}
+		for _, statement := range plancheck.Statements(document) {
+			if effect, _ := statement.Fields["Effect"].(string); effect != "Allow" || !publicPrincipal(statement.Fields["Principal"]) {
+				continue
+			}
+			for _, action := range s3WriteActions {
+				if policyMatches(statement.Fields["Action"], strings.ToLower(action), true) {
+					findings = append(findings, plancheck.NewFinding(
+						"s3.public-write",
+						resource.Address,
+						fmt.Sprintf("%s lets any principal (\"*\") write with %s", statement.Path, action),
+					).WithPath("policy"))
+					break
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// publicPrincipal reports whether a statement's Principal is "*", as a
+// string or as an AWS principal.
+func publicPrincipal(principal interface{}) bool {
+	if principals, ok := principal.(map[string]interface{}); ok {
+		principal = principals["AWS"]
+	}
+	return contains(policyStrings(principal), "*")
+}
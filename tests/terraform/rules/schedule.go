@@ -0,0 +1,111 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "schedule.scale-down",
+		Description:   "In environments with scale_down set, autoscaling groups must scale to zero and RDS databases must stop on a schedule.",
+		Remediation:   "Add an aws_autoscaling_schedule with desired_capacity = 0 for the group, or an aws_scheduler_schedule calling rds:stopDBInstance or rds:stopDBCluster for the database, or tag the resource for the environment's external scheduler.",
+		ResourceTypes: []string{"aws_autoscaling_group", "aws_db_instance", "aws_rds_cluster"},
+		Check:         checkScaleDown,
+	})
+}
+
+// stopActions maps database resource types to the EventBridge Scheduler
+// universal target that stops them and the input field naming the database.
+var stopActions = map[string]struct {
+	target     string
+	identifier string
+}{
+	"aws_db_instance": {"arn:aws:scheduler:::aws-sdk:rds:stopDBInstance", "DbInstanceIdentifier"},
+	"aws_rds_cluster": {"arn:aws:scheduler:::aws-sdk:rds:stopDBCluster", "DbClusterIdentifier"},
+}
+
+func checkScaleDown(in *plancheck.Input) []plancheck.Finding {
+	policy := in.Settings().ScaleDown
+	if policy == nil {
+		return nil
+	}
+
+	var findings []plancheck.Finding
+	for _, group := range plancheck.Resources(in.Plan, "aws_autoscaling_group") {
+		if policy.ScheduleTag != "" && groupTags(group.AttributeValues)[policy.ScheduleTag] != "" {
+			continue
+		}
+		if scaledToZero(in, group) {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"schedule.scale-down",
+			group.Address,
+			fmt.Sprintf("%s runs around the clock in %s: no aws_autoscaling_schedule scales it to zero", group.Address, in.Environment),
+		))
+	}
+
+	for _, db := range plancheck.Resources(in.Plan, "aws_db_instance", "aws_rds_cluster") {
+		if policy.ScheduleTag != "" && plancheck.Tags(db.AttributeValues)[policy.ScheduleTag] != "" {
+			continue
+		}
+		if stopped(in, db) {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"schedule.scale-down",
+			db.Address,
+			fmt.Sprintf("%s runs around the clock in %s: no aws_scheduler_schedule calls %s for it", db.Address, in.Environment, strings.TrimPrefix(stopActions[db.Type].target, "arn:aws:scheduler:::aws-sdk:")),
+		))
+	}
+	return findings
+}
+
+// scaledToZero reports whether an aws_autoscaling_schedule sets the group's
+// desired capacity to zero. Schedules name their group by reference or by a
+// known name.
+func scaledToZero(in *plancheck.Input, group *tfjson.StateResource) bool {
+	name := plancheck.LookupString(group.AttributeValues, "name")
+	for _, schedule := range plancheck.Resources(in.Plan, "aws_autoscaling_schedule") {
+		desired, ok := plancheck.LookupNumber(schedule.AttributeValues, "desired_capacity")
+		if !ok || desired != 0 {
+			continue
+		}
+		if name != "" && plancheck.LookupString(schedule.AttributeValues, "autoscaling_group_name") == name ||
+			contains(in.References(schedule.Address, "autoscaling_group_name"), plancheck.ConfigAddress(group.Address)) {
+			return true
+		}
+	}
+	return false
+}
+
+// stopped reports whether an aws_scheduler_schedule stops the database. The
+// schedule's target input names it by reference, or by a known identifier
+// under DbInstanceIdentifier or DbClusterIdentifier.
+func stopped(in *plancheck.Input, db *tfjson.StateResource) bool {
+	action := stopActions[db.Type]
+	attribute := "identifier"
+	if db.Type == "aws_rds_cluster" {
+		attribute = "cluster_identifier"
+	}
+	identifier := plancheck.LookupString(db.AttributeValues, attribute)
+
+	for _, schedule := range plancheck.Resources(in.Plan, "aws_scheduler_schedule") {
+		if plancheck.LookupString(schedule.AttributeValues, "target.0.arn") != action.target {
+			continue
+		}
+		var input map[string]interface{}
+		_ = json.Unmarshal([]byte(plancheck.LookupString(schedule.AttributeValues, "target.0.input")), &input)
+		if identifier != "" && input[action.identifier] == identifier ||
+			contains(in.References(schedule.Address, "target.input"), plancheck.ConfigAddress(db.Address)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "secrets.rotation-age",
+		Description:   "Secrets must have been rotated within their rotation window: the plan's rotation schedule, or secrets.rotation_days.",
+		Remediation:   "Rotate the secret (aws secretsmanager rotate-secret --secret-id <arn>) and check why its scheduled rotation did not run, or add an aws_secretsmanager_secret_rotation for it.",
+		Rationale:     "A credential that is never rotated stays valid for as long as anyone who ever saw it, including former team members and old log files, keeps a copy.",
+		Severity:      plancheck.SeverityAdvisory,
+		ResourceTypes: []string{"aws_secretsmanager_secret"},
+		Check:         checkSecretRotationAge,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:            "secrets.unexpected-access",
+		Description:   "Secrets should only be read by roles the stack's policies allow to, or by principals listed in secrets.readers.",
+		Remediation:   "Find out why the principal reads the secret. Revoke its access if it should not, or add it to secrets.readers in compliance.yaml if it should.",
+		Rationale:     "A reader nobody granted access on purpose usually means an over-broad policy elsewhere in the account, or a credential being used by someone it was not issued to.",
+		Severity:      plancheck.SeverityAdvisory,
+		ResourceTypes: []string{"aws_secretsmanager_secret"},
+		Check:         checkSecretAccess,
+	})
+}
+
+func checkSecretRotationAge(in *plancheck.Input) []plancheck.Finding {
+	if in.Runtime == nil || len(in.Runtime.Secrets) == 0 {
+		return nil
+	}
+	defaultDays := plancheck.DefaultSecretRotationDays
+	if in.Config != nil {
+		defaultDays = in.Config.Secrets.MaxRotationDays()
+	}
+
+	var findings []plancheck.Finding
+	for _, secret := range plancheck.Resources(in.Plan, "aws_secretsmanager_secret") {
+		activity, ok := secretActivity(in.Runtime.Secrets, secret)
+		if !ok {
+			continue
+		}
+		days, scheduled := secretRotationDays(in, secret)
+		if !scheduled {
+			days = defaultDays
+		}
+
+		since, what := activity.LastRotated, "last rotated"
+		if since == nil {
+			since, what = activity.CreatedAt, "never rotated since its creation"
+		}
+		if since == nil {
+			continue
+		}
+		age := int(in.Runtime.ReadAt.Sub(*since).Hours() / 24)
+		if age <= days {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"secrets.rotation-age",
+			secret.Address,
+			fmt.Sprintf("secret %s was %s on %s, %d days ago, beyond its %d-day rotation window", secret.Address, what, since.Format(time.DateOnly), age, days),
+		).WithEvidence(activity))
+	}
+	return findings
+}
+
+func checkSecretAccess(in *plancheck.Input) []plancheck.Finding {
+	if in.Runtime == nil || len(in.Runtime.Secrets) == 0 {
+		return nil
+	}
+	var patterns []string
+	if in.Config != nil {
+		patterns = in.Config.Secrets.Readers
+	}
+
+	var findings []plancheck.Finding
+	for _, secret := range plancheck.Resources(in.Plan, "aws_secretsmanager_secret") {
+		activity, ok := secretActivity(in.Runtime.Secrets, secret)
+		if !ok || len(activity.Readers) == 0 {
+			continue
+		}
+		readers := secretReaderRoles(in, plancheck.LookupString(secret.AttributeValues, "arn"))
+		for _, reader := range activity.Readers {
+			role := assumedRole(reader.Principal)
+			if role != "" && readers[role] {
+				continue
+			}
+			if allowedReader(patterns, reader.Principal) {
+				continue
+			}
+			findings = append(findings, plancheck.NewFinding(
+				"secrets.unexpected-access",
+				secret.Address,
+				fmt.Sprintf("secret %s was read by %s, most recently on %s, which no policy in the stack allows and secrets.readers does not list", secret.Address, reader.Principal, reader.LastRead.Format(time.DateOnly)),
+			).WithEvidence(reader))
+		}
+	}
+	return findings
+}
+
+// secretActivity finds the runtime activity of a planned secret, by its ARN
+// or, while the plan does not know the ARN, by its name.
+func secretActivity(secrets map[string]plancheck.SecretActivity, secret *tfjson.StateResource) (plancheck.SecretActivity, bool) {
+	if arn := plancheck.LookupString(secret.AttributeValues, "arn"); arn != "" {
+		activity, ok := secrets[arn]
+		return activity, ok
+	}
+	name := plancheck.LookupString(secret.AttributeValues, "name")
+	for _, activity := range secrets {
+		if name != "" && activity.Name == name {
+			return activity, true
+		}
+	}
+	return plancheck.SecretActivity{}, false
+}
+
+var rateDays = regexp.MustCompile(`^rate\((\d+) days?\)$`)
+
+// secretRotationDays returns the rotation interval an
+// aws_secretsmanager_secret_rotation in the plan gives secret, in days.
+func secretRotationDays(in *plancheck.Input, secret *tfjson.StateResource) (int, bool) {
+	ids := []string{
+		plancheck.LookupString(secret.AttributeValues, "arn"),
+		plancheck.LookupString(secret.AttributeValues, "id"),
+		plancheck.LookupString(secret.AttributeValues, "name"),
+	}
+	for _, rotation := range plancheck.Resources(in.Plan, "aws_secretsmanager_secret_rotation") {
+		id := plancheck.LookupString(rotation.AttributeValues, "secret_id")
+		if !(id != "" && contains(ids, id)) && !contains(in.References(rotation.Address, "secret_id"), plancheck.ConfigAddress(secret.Address)) {
+			continue
+		}
+		for _, rules := range plancheck.Blocks(rotation.AttributeValues, "rotation_rules") {
+			if days, ok := plancheck.LookupNumber(rules, "automatically_after_days"); ok && days > 0 {
+				return int(days), true
+			}
+			if match := rateDays.FindStringSubmatch(plancheck.LookupString(rules, "schedule_expression")); match != nil {
+				days, _ := strconv.Atoi(match[1])
+				return days, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// secretReaderRoles returns the names of the roles in the plan whose
+// policies allow secretsmanager:GetSecretValue on the secret at arn.
+func secretReaderRoles(in *plancheck.Input, arn string) map[string]bool {
+	readers := map[string]bool{}
+	if arn == "" {
+		return readers
+	}
+	for _, role := range plancheck.Resources(in.Plan, "aws_iam_role") {
+		name := plancheck.LookupString(role.AttributeValues, "name")
+		if name == "" {
+			continue
+		}
+		for _, document := range rolePolicies(in, plancheck.ConfigAddress(role.Address)) {
+			if policyAllows(document.Policy, "secretsmanager:GetSecretValue", arn) {
+				readers[name] = true
+				break
+			}
+		}
+	}
+	return readers
+}
+
+// policyAllows reports whether an Allow statement of a JSON policy grants
+// action on resource, matching the statement's wildcards.
+func policyAllows(policy, action, resource string) bool {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		return false
+	}
+	for _, statement := range plancheck.Statements(doc) {
+		if effect, _ := statement.Fields["Effect"].(string); effect != "Allow" {
+			continue
+		}
+		if policyMatches(statement.Fields["Action"], strings.ToLower(action), true) &&
+			policyMatches(statement.Fields["Resource"], resource, false) {
+			return true
+		}
+	}
+	return false
+}
+
+func policyMatches(patterns interface{}, value string, fold bool) bool {
+	for _, pattern := range policyStrings(patterns) {
+		if fold {
+			pattern = strings.ToLower(pattern)
+		}
+		// IAM wildcards match "/" too, which path.Match's do not.
+		pattern = strings.ReplaceAll(pattern, "/", "\x00")
+		if matched, _ := path.Match(pattern, strings.ReplaceAll(value, "/", "\x00")); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// assumedRole returns the role name in a principal ARN such as
+// arn:aws:sts::123456789012:assumed-role/pkg-api/session or
+// arn:aws:iam::123456789012:role/service/pkg-api, or "".
+func assumedRole(principal string) string {
+	if _, rest, ok := strings.Cut(principal, ":assumed-role/"); ok {
+		name, _, _ := strings.Cut(rest, "/")
+		return name
+	}
+	if _, rest, ok := strings.Cut(principal, ":role/"); ok {
+		return rest[strings.LastIndex(rest, "/")+1:]
+	}
+	return ""
+}
+
+// allowedReader reports whether a principal matches one of the configured
+// reader patterns, either as recorded or, for an assumed role session, as
+// the role's ARN.
+func allowedReader(patterns []string, principal string) bool {
+	candidates := []string{principal}
+	if account, rest, ok := strings.Cut(principal, ":assumed-role/"); ok {
+		role, _, _ := strings.Cut(rest, "/")
+		candidates = append(candidates, strings.Replace(account, ":sts:", ":iam:", 1)+":role/"+role)
+	}
+	for _, pattern := range patterns {
+		for _, candidate := range candidates {
+			if matched, _ := path.Match(pattern, candidate); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "autoscaling.spot-mix",
+		Description:   "Autoscaling groups must use spot capacity, or stay on on-demand capacity, as the environment's spot settings in compliance.yaml require for their criticality.",
+		Remediation:   "Give non-critical groups a mixed_instances_policy with on_demand_percentage_above_base_capacity below 100 and a spot_allocation_strategy; keep critical groups at 100% on-demand with launch templates that do not request spot instances.",
+		ResourceTypes: []string{"aws_autoscaling_group"},
+		Check:         checkSpotMix,
+	})
+}
+
+const instancesDistribution = "mixed_instances_policy.0.instances_distribution.0"
+
+func checkSpotMix(in *plancheck.Input) []plancheck.Finding {
+	policy := in.Settings().Spot
+	if policy == nil {
+		return nil
+	}
+	key, value := policy.Critical()
+	templates := spotLaunchTemplates(in.Plan)
+
+	var findings []plancheck.Finding
+	for _, group := range plancheck.Resources(in.Plan, "aws_autoscaling_group") {
+		critical := groupTags(group.AttributeValues)[key] == value
+		switch {
+		case critical && policy.ForbidForCritical:
+			if reason, path := spotUse(in, group, templates); reason != "" {
+				findings = append(findings, plancheck.NewFinding(
+					"autoscaling.spot-mix",
+					group.Address,
+					fmt.Sprintf("%s is critical (%s=%s) but %s; critical groups must stay on on-demand capacity in %s", group.Address, key, value, reason, in.Environment),
+				).WithPath(path))
+			}
+		case !critical && policy.RequireForNonCritical:
+			if reason := missingSpot(group); reason != "" {
+				findings = append(findings, plancheck.NewFinding(
+					"autoscaling.spot-mix",
+					group.Address,
+					fmt.Sprintf("%s is not critical but %s; non-critical groups must use spot capacity in %s", group.Address, reason, in.Environment),
+				).WithPath("mixed_instances_policy"))
+			}
+		}
+	}
+	return findings
+}
+
+// missingSpot explains why a group does not run on spot capacity, or returns
+// "" when it does.
+func missingSpot(group *tfjson.StateResource) string {
+	if len(plancheck.Blocks(group.AttributeValues, "mixed_instances_policy")) == 0 {
+		return "has no mixed_instances_policy"
+	}
+	// The provider defaults on_demand_percentage_above_base_capacity to 100.
+	percentage, ok := plancheck.LookupNumber(group.AttributeValues, instancesDistribution+".on_demand_percentage_above_base_capacity")
+	if !ok || percentage >= 100 {
+		return "runs 100% on-demand above its base capacity"
+	}
+	if plancheck.LookupString(group.AttributeValues, instancesDistribution+".spot_allocation_strategy") == "" {
+		return "sets no spot_allocation_strategy"
+	}
+	return ""
+}
+
+// spotUse explains how a group runs on spot capacity, and which argument
+// makes it, or returns "" when it does not: through its instances
+// distribution, or through a launch template requesting spot instances.
+func spotUse(in *plancheck.Input, group *tfjson.StateResource, templates map[string]bool) (reason, path string) {
+	if len(plancheck.Blocks(group.AttributeValues, "mixed_instances_policy")) > 0 {
+		percentage, ok := plancheck.LookupNumber(group.AttributeValues, instancesDistribution+".on_demand_percentage_above_base_capacity")
+		if ok && percentage < 100 {
+			return fmt.Sprintf("runs %.0f%% on-demand above its base capacity", percentage), "mixed_instances_policy"
+		}
+	}
+	for _, attribute := range []string{"launch_template.id", "mixed_instances_policy.launch_template.launch_template_specification.launch_template_id"} {
+		for _, ref := range in.References(group.Address, attribute) {
+			if templates[ref] {
+				return fmt.Sprintf("launches %s, which requests spot instances", ref), attribute[:strings.Index(attribute, ".")]
+			}
+		}
+	}
+	return "", ""
+}
+
+// spotLaunchTemplates returns the configuration addresses of the launch
+// templates whose instance_market_options request spot instances.
+func spotLaunchTemplates(plan *tfjson.Plan) map[string]bool {
+	templates := map[string]bool{}
+	for _, template := range plancheck.Resources(plan, "aws_launch_template") {
+		if plancheck.LookupString(template.AttributeValues, "instance_market_options.0.market_type") == "spot" {
+			templates[plancheck.ConfigAddress(template.Address)] = true
+		}
+	}
+	return templates
+}
+
+// groupTags returns an autoscaling group's tags, which it keeps as a list of
+// tag blocks rather than a map.
+func groupTags(values map[string]interface{}) map[string]string {
+	tags := map[string]string{}
+	for _, tag := range plancheck.Blocks(values, "tag") {
+		tags[plancheck.LookupString(tag, "key")] = plancheck.LookupString(tag, "value")
+	}
+	return tags
+}
@@ -0,0 +1,179 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+const requiredTagsExample = `
+provider "aws" {
+  default_tags {
+    tags = {
+      Project     = "cs450"
+      Environment = var.environment
+    }
+  }
+}`
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:          "tags.required",
+		Description: "Taggable resources must carry every tag listed under tags.required in compliance.yaml, with values matching tags.patterns.",
+		Remediation: "Add the missing tags to the resource, or to the provider's default_tags, and correct values that do not match their pattern.",
+		Rationale:   "Billing, ownership reports and the scale-down schedules find resources by their tags; an untagged resource is invisible to them.",
+		Example:     requiredTagsExample,
+		Check:       checkRequiredTags,
+	})
+	plancheck.Register(plancheck.Rule{
+		ID:          "tags.cost-allocation",
+		Description: "Cost allocation tags must have the values tags.cost_allocation in compliance.yaml gives the resource's module and environment.",
+		Remediation: "Set the tags to the listed values, or add the module to tags.cost_allocation if it belongs to another cost center or project.",
+		Check:       checkCostAllocationTags,
+	})
+}
+
+// checkRequiredTags counts the provider's default_tags as carried when the
+// plan does not know a resource's tags_all until apply.
+func checkRequiredTags(in *plancheck.Input) []plancheck.Finding {
+	if in.Config == nil || len(in.Config.Tags.Required)+len(in.Config.Tags.Patterns) == 0 {
+		return nil
+	}
+
+	var findings []plancheck.Finding
+	for _, resource := range plancheck.PlannedResources(in.Plan) {
+		if resource == nil || resource.Mode == tfjson.DataResourceMode {
+			continue
+		}
+		// Taggable resources always carry a tags attribute in plan JSON, even
+		// when it is null.
+		if _, taggable := resource.AttributeValues["tags"]; !taggable {
+			continue
+		}
+
+		tags := in.ResourceTags(resource)
+		var missing []string
+		for key := range in.Config.Tags.Required {
+			if _, ok := tags[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			finding := plancheck.NewFinding(
+				"tags.required",
+				resource.Address,
+				fmt.Sprintf("%s is missing required tags: %s", resource.Address, strings.Join(missing, ", ")),
+			).WithMessageKey("tags.required.missing", map[string]string{
+				"address": resource.Address,
+				"tags":    strings.Join(missing, ", "),
+			}).WithPath("tags")
+			if fix, ok := tagsFix(missing, in.Config.Tags.Required); ok {
+				finding = finding.WithFix(fix)
+			}
+			findings = append(findings, finding)
+		}
+
+		var mismatched []string
+		for _, key := range tagKeys(tags) {
+			pattern, err := in.Config.Tags.PatternRegexp(key, in.Environment)
+			if err != nil || pattern == nil || pattern.MatchString(tags[key]) {
+				continue
+			}
+			mismatched = append(mismatched, fmt.Sprintf("%s is %q, not matching %s", key, tags[key], pattern))
+		}
+		if len(mismatched) > 0 {
+			findings = append(findings, plancheck.NewFinding(
+				"tags.required",
+				resource.Address,
+				fmt.Sprintf("%s has malformed tag values: %s", resource.Address, strings.Join(mismatched, "; ")),
+			).WithMessageKey("tags.required.malformed", map[string]string{
+				"address": resource.Address,
+				"tags":    strings.Join(mismatched, "; "),
+			}).WithPath("tags"))
+		}
+	}
+	return findings
+}
+
+// tagsFix adds the missing tags with their suggested values, when every
+// missing tag has one.
+func tagsFix(missing []string, suggested map[string]string) (plancheck.Fix, bool) {
+	values := map[string]interface{}{}
+	for _, key := range missing {
+		if suggested[key] == "" {
+			return plancheck.Fix{}, false
+		}
+		values[key] = suggested[key]
+	}
+	return plancheck.Fix{Attribute: "tags", Value: values, Merge: true}, true
+}
+
+func checkCostAllocationTags(in *plancheck.Input) []plancheck.Finding {
+	if in.Config == nil || len(in.Config.Tags.CostAllocation) == 0 {
+		return nil
+	}
+
+	var findings []plancheck.Finding
+	for _, resource := range plancheck.PlannedResources(in.Plan) {
+		if resource == nil || resource.Mode == tfjson.DataResourceMode {
+			continue
+		}
+		if _, taggable := resource.AttributeValues["tags"]; !taggable {
+			continue
+		}
+
+		module := plancheck.ModulePath(resource.Address)
+		want := in.Config.Tags.CostTags(module, in.Environment)
+		tags := plancheck.Tags(resource.AttributeValues)
+		var problems []string
+		fix := map[string]interface{}{}
+		for _, key := range tagKeys(want) {
+			value, ok := tags[key]
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("%s is missing", key))
+			case value != want[key]:
+				problems = append(problems, fmt.Sprintf("%s is %q", key, value))
+			default:
+				continue
+			}
+			fix[key] = want[key]
+		}
+		if len(problems) == 0 {
+			continue
+		}
+
+		scope := "the root module"
+		if module != "" {
+			scope = module
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"tags.cost-allocation",
+			resource.Address,
+			fmt.Sprintf("%s: %s; %s in %s must be tagged %s", resource.Address, strings.Join(problems, ", "), scope, in.Environment, formatTags(want)),
+		).WithPath("tags").WithFix(plancheck.Fix{Attribute: "tags", Value: fix, Merge: true}))
+	}
+	return findings
+}
+
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for _, key := range tagKeys(tags) {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", key, tags[key]))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func tagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
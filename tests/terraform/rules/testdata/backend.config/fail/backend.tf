@@ -0,0 +1,8 @@
+terraform {
+  backend "s3" {
+    bucket  = "someones-scratch-bucket"
+    key     = "prod/terraform.tfstate"
+    region  = "us-east-1"
+    encrypt = false
+  }
+}
@@ -0,0 +1,9 @@
+terraform {
+  backend "s3" {
+    bucket         = "acme-terraform-state-test"
+    key            = "test/terraform.tfstate"
+    region         = "us-east-1"
+    dynamodb_table = "terraform-state-lock"
+    encrypt        = true
+  }
+}
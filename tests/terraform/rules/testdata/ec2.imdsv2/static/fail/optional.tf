@@ -0,0 +1,10 @@
+resource "aws_instance" "bastion" {
+  ami           = "ami-123"
+  instance_type = "t3.micro"
+}
+
+resource "aws_launch_template" "workers" {
+  metadata_options {
+    http_tokens = "optional"
+  }
+}
@@ -0,0 +1,15 @@
+resource "aws_instance" "bastion" {
+  ami           = "ami-123"
+  instance_type = "t3.micro"
+
+  metadata_options {
+    http_endpoint = "enabled"
+    http_tokens   = "required"
+  }
+}
+
+resource "aws_launch_template" "workers" {
+  metadata_options {
+    http_tokens = var.http_tokens
+  }
+}
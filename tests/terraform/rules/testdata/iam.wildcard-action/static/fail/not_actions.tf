@@ -0,0 +1,28 @@
+data "aws_iam_policy_document" "all_but_iam" {
+  statement {
+    not_actions = ["iam:*"]
+    resources   = ["arn:aws:s3:::pkg-artifacts/*"]
+  }
+}
+
+data "aws_iam_policy_document" "limited" {
+  statement {
+    effect      = "Deny"
+    not_actions = ["s3:GetObject"]
+    resources   = ["arn:aws:s3:::pkg-artifacts/*"]
+  }
+  statement {
+    actions   = ["*"]
+    resources = ["arn:aws:s3:::pkg-artifacts/*"]
+
+    condition {
+      test     = "StringEquals"
+      variable = "aws:SourceAccount"
+      values   = ["100000000001"]
+    }
+  }
+  statement {
+    not_actions = var.denied_actions
+    resources   = ["arn:aws:s3:::pkg-artifacts/*"]
+  }
+}
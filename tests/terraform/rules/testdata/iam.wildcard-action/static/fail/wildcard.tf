@@ -0,0 +1,17 @@
+resource "aws_iam_policy" "admin" {
+  name = "admin"
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      { Effect = "Allow", Action = "s3:GetObject", Resource = "arn:aws:s3:::pkg-artifacts/*" },
+      { Effect = "Allow", Action = ["logs:PutLogEvents", "*"], Resource = "arn:aws:logs:*:*:*" },
+    ]
+  })
+}
+
+data "aws_iam_policy_document" "everything" {
+  statement {
+    actions   = ["*"]
+    resources = ["arn:aws:s3:::pkg-artifacts"]
+  }
+}
@@ -0,0 +1,49 @@
+resource "aws_iam_role_policy" "deployer" {
+  role = aws_iam_role.deployer.id
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      { Effect = "Allow", Action = "s3:PutObject", Resource = "arn:aws:s3:::pkg-artifacts/*" },
+      { Effect = "Deny", NotAction = "s3:GetObject", Resource = "arn:aws:s3:::pkg-artifacts/locked/*" },
+    ]
+  })
+}
+
+resource "aws_iam_user_policy" "operator" {
+  user = "operator"
+  policy = jsonencode({
+    Version   = "2012-10-17"
+    Statement = [{ Effect = "Deny", NotAction = ["s3:GetObject"], Resource = "arn:aws:s3:::pkg-artifacts/*" }]
+  })
+}
+
+resource "aws_iam_role" "worker" {
+  name               = "worker"
+  assume_role_policy = data.aws_iam_policy_document.assume.json
+
+  inline_policy {
+    name = "logs"
+    policy = jsonencode({
+      Version   = "2012-10-17"
+      Statement = [{ Effect = "Allow", Action = "logs:PutLogEvents", Resource = "arn:aws:logs:*:*:*" }]
+    })
+  }
+}
+
+data "aws_iam_policy_document" "guard" {
+  statement {
+    effect      = "Deny"
+    not_actions = ["s3:GetObject"]
+    resources   = ["arn:aws:s3:::pkg-artifacts/*"]
+  }
+  # Decided by variables: left to the plan-based check.
+  statement {
+    effect      = var.effect
+    not_actions = ["s3:GetObject"]
+    resources   = ["arn:aws:s3:::pkg-artifacts/*"]
+  }
+  statement {
+    actions   = var.actions
+    resources = ["arn:aws:s3:::pkg-artifacts/*"]
+  }
+}
@@ -0,0 +1,23 @@
+resource "aws_iam_policy" "reader" {
+  name = "reader"
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect   = "Allow"
+      Action   = ["s3:GetObject"]
+      Resource = "arn:aws:s3:::pkg-artifacts/*"
+    }]
+  })
+}
+
+data "aws_iam_policy_document" "writer" {
+  statement {
+    actions   = ["s3:PutObject"]
+    resources = ["arn:aws:s3:::pkg-artifacts/packages/*"]
+  }
+}
+
+# Built from a variable: left to the plan-based check.
+resource "aws_iam_policy" "dynamic" {
+  policy = var.policy_json
+}
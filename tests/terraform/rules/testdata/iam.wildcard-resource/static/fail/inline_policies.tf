@@ -0,0 +1,44 @@
+resource "aws_iam_role_policy" "deployer" {
+  role = aws_iam_role.deployer.id
+  policy = jsonencode({
+    Version   = "2012-10-17"
+    Statement = [{ Effect = "Allow", Action = "s3:PutObject", Resource = "*" }]
+  })
+}
+
+resource "aws_iam_user_policy" "operator" {
+  user = "operator"
+  policy = jsonencode({
+    Version   = "2012-10-17"
+    Statement = [{ Effect = "Allow", Action = "s3:GetObject", NotResource = ["arn:aws:s3:::pkg-secrets/*"] }]
+  })
+}
+
+resource "aws_iam_group_policy" "developers" {
+  group = "developers"
+  policy = jsonencode({
+    Version   = "2012-10-17"
+    Statement = [{ Effect = "Allow", Action = "s3:GetObject", Resource = ["arn:aws:s3:::pkg-artifacts/*", "*"] }]
+  })
+}
+
+resource "aws_iam_role" "worker" {
+  name               = "worker"
+  assume_role_policy = data.aws_iam_policy_document.assume.json
+
+  inline_policy {
+    name = "artifacts"
+    policy = jsonencode({
+      Version   = "2012-10-17"
+      Statement = [{ Effect = "Allow", Action = "s3:GetObject", Resource = "arn:aws:s3:::pkg-artifacts/*" }]
+    })
+  }
+
+  inline_policy {
+    name = "logs"
+    policy = jsonencode({
+      Version   = "2012-10-17"
+      Statement = [{ Effect = "Allow", Action = "logs:PutLogEvents", Resource = "*" }]
+    })
+  }
+}
@@ -0,0 +1,28 @@
+data "aws_iam_policy_document" "all_but_secrets" {
+  statement {
+    actions       = ["s3:GetObject"]
+    not_resources = ["arn:aws:s3:::pkg-secrets/*"]
+  }
+}
+
+data "aws_iam_policy_document" "limited" {
+  statement {
+    effect        = "Deny"
+    actions       = ["s3:DeleteObject"]
+    not_resources = ["arn:aws:s3:::pkg-artifacts/scratch/*"]
+  }
+  statement {
+    actions   = ["sns:Publish"]
+    resources = ["*"]
+
+    condition {
+      test     = "ArnEquals"
+      variable = "aws:SourceArn"
+      values   = [aws_cloudwatch_event_rule.nightly.arn]
+    }
+  }
+  statement {
+    actions       = ["s3:GetObject"]
+    not_resources = var.secret_arns
+  }
+}
@@ -0,0 +1,22 @@
+resource "aws_iam_policy" "logs" {
+  name = "logs"
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect   = "Allow"
+      Action   = ["logs:CreateLogStream", "logs:PutLogEvents"]
+      Resource = "*"
+    }]
+  })
+}
+
+data "aws_iam_policy_document" "ecr" {
+  statement {
+    actions   = ["ecr:GetAuthorizationToken"]
+    resources = ["*"]
+  }
+  statement {
+    actions   = ["ecr:BatchGetImage"]
+    resources = ["arn:aws:ecr:us-east-1:100000000001:repository/validator", "*"]
+  }
+}
@@ -0,0 +1,44 @@
+resource "aws_iam_role_policy" "deployer" {
+  role = aws_iam_role.deployer.id
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      { Effect = "Allow", Action = "s3:PutObject", Resource = "arn:aws:s3:::pkg-artifacts/*" },
+      { Effect = "Deny", Action = "s3:DeleteObject", NotResource = "arn:aws:s3:::pkg-artifacts/scratch/*" },
+    ]
+  })
+}
+
+resource "aws_iam_user_policy" "operator" {
+  user = "operator"
+  policy = jsonencode({
+    Version   = "2012-10-17"
+    Statement = [{ Effect = "Deny", Action = "s3:*", NotResource = ["arn:aws:s3:::pkg-artifacts/*"] }]
+  })
+}
+
+resource "aws_iam_role" "worker" {
+  name               = "worker"
+  assume_role_policy = data.aws_iam_policy_document.assume.json
+
+  inline_policy {
+    name = "artifacts"
+    policy = jsonencode({
+      Version   = "2012-10-17"
+      Statement = [{ Effect = "Allow", Action = "s3:GetObject", Resource = "arn:aws:s3:::pkg-artifacts/*" }]
+    })
+  }
+}
+
+data "aws_iam_policy_document" "guard" {
+  statement {
+    effect        = "Deny"
+    actions       = ["s3:*"]
+    not_resources = ["arn:aws:s3:::pkg-artifacts/*"]
+  }
+  # Built from a variable: left to the plan-based check.
+  statement {
+    actions   = ["s3:GetObject"]
+    resources = var.bucket_arns
+  }
+}
@@ -0,0 +1,9 @@
+import {
+  to = aws_s3_bucket.artifacts
+  id = "pkg-artifacts"
+}
+
+import {
+  to = aws_dynamodb_table.users
+  id = "pkg-users-old"
+}
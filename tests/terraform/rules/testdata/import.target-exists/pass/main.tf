@@ -0,0 +1,15 @@
+import {
+  to = aws_s3_bucket.artifacts
+  id = "pkg-artifacts"
+}
+
+import {
+  to = aws_iam_role.api
+  id = "pkg-api"
+}
+
+# Looked up only when the plan knows the ID.
+import {
+  to = aws_cloudfront_distribution.cdn
+  id = var.distribution_id
+}
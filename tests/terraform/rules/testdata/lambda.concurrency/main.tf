@@ -0,0 +1,15 @@
+resource "aws_lambda_function" "search" {
+  function_name = "search-api-handler"
+}
+
+resource "aws_lambda_alias" "search_live" {
+  name             = "live"
+  function_name    = aws_lambda_function.search.function_name
+  function_version = aws_lambda_function.search.version
+}
+
+resource "aws_lambda_provisioned_concurrency_config" "search" {
+  function_name                     = aws_lambda_function.search.function_name
+  qualifier                         = aws_lambda_alias.search_live.name
+  provisioned_concurrent_executions = 5
+}
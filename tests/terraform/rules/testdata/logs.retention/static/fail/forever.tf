@@ -0,0 +1,8 @@
+resource "aws_cloudwatch_log_group" "api" {
+  name = "/ecs/api"
+}
+
+resource "aws_cloudwatch_log_group" "validator" {
+  name              = "/ecs/validator"
+  retention_in_days = 0
+}
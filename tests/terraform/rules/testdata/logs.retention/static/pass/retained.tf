@@ -0,0 +1,9 @@
+resource "aws_cloudwatch_log_group" "api" {
+  name              = "/ecs/api"
+  retention_in_days = 7
+}
+
+resource "aws_cloudwatch_log_group" "configurable" {
+  name              = "/ecs/validator"
+  retention_in_days = var.log_retention_days
+}
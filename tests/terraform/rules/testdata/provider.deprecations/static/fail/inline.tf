@@ -0,0 +1,22 @@
+resource "aws_s3_bucket" "logs" {
+  bucket = "acme-logs"
+  acl    = "private"
+
+  versioning {
+    enabled = true
+  }
+
+  lifecycle_rule {
+    enabled = true
+  }
+}
+
+resource "aws_launch_configuration" "web" {
+  name = "web"
+}
+
+resource "aws_iam_policy_attachment" "ci" {
+  name       = "ci"
+  policy_arn = "arn:aws:iam::aws:policy/ReadOnlyAccess"
+  roles      = ["ci"]
+}
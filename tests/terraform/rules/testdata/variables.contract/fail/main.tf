@@ -0,0 +1,35 @@
+variable "aws_region" {
+  type        = string
+  description = "Region the environment is deployed to"
+}
+
+variable "artifacts_bucket" {
+  type = string
+}
+
+variable "retries" {
+  description = "How often to retry"
+  default     = 3
+}
+
+# Only its own validation refers to it.
+variable "kms_key_arn" {
+  type        = string
+  description = "KMS key for encryption"
+  default     = ""
+
+  validation {
+    condition     = var.kms_key_arn == "" || can(regex("^arn:", var.kms_key_arn))
+    error_message = "kms_key_arn must be empty or an ARN."
+  }
+}
+
+provider "aws" {
+  region = var.aws_region
+}
+
+module "service" {
+  source  = "./service"
+  bucket  = var.artifacts_bucket
+  retries = var.retries
+}
@@ -0,0 +1,40 @@
+variable "aws_region" {
+  type        = string
+  description = "Region the environment is deployed to"
+
+  validation {
+    condition     = can(regex("^[a-z]{2}-[a-z]+-\\d$", var.aws_region))
+    error_message = "aws_region must be a region such as us-east-1."
+  }
+}
+
+variable "aws_account_id" {
+  type        = string
+  description = "Account the environment is deployed to"
+
+  validation {
+    condition     = can(regex("^\\d{12}$", var.aws_account_id))
+    error_message = "aws_account_id must be 12 digits."
+  }
+}
+
+variable "image_tag" {
+  type        = string
+  description = "Docker image tag"
+  default     = "latest"
+}
+
+provider "aws" {
+  region = var.aws_region
+}
+
+# Referenced only through a local, which the plan does not record.
+locals {
+  table_arn = "arn:aws:dynamodb:${var.aws_region}:${var.aws_account_id}:table/users"
+}
+
+module "service" {
+  source    = "./service"
+  image_tag = var.image_tag
+  table_arn = local.table_arn
+}
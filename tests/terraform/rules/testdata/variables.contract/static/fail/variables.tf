@@ -0,0 +1,13 @@
+variable "aws_region" {
+  type = string
+}
+
+variable "subnet_cidr" {
+  type        = string
+  description = "   "
+}
+
+variable "tags" {
+  description = "Tags for every resource"
+  default     = {}
+}
@@ -0,0 +1,92 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "tracing.propagation",
+		Description:   "API Gateway stages and the Lambda functions they or SQS queues invoke must trace with X-Ray, so no hop drops the trace.",
+		Remediation:   `Set xray_tracing_enabled = true on the stage, and tracing_config { mode = "Active" } on functions behind API Gateway integrations or SQS event source mappings.`,
+		ResourceTypes: []string{"aws_api_gateway_stage", "aws_api_gateway_integration", "aws_lambda_event_source_mapping", "aws_lambda_function"},
+		Check:         checkTracingPropagation,
+	})
+}
+
+func checkTracingPropagation(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, stage := range plancheck.Resources(in.Plan, "aws_api_gateway_stage") {
+		if plancheck.LookupBool(stage.AttributeValues, "xray_tracing_enabled") {
+			continue
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"tracing.propagation",
+			stage.Address,
+			fmt.Sprintf("stage %s does not enable X-Ray tracing, so traces start at its backends", plancheck.LookupString(stage.AttributeValues, "stage_name")),
+		).WithPath("xray_tracing_enabled").WithFix(plancheck.Fix{
+			Attribute: "xray_tracing_enabled",
+			Value:     true,
+		}))
+	}
+
+	callers := tracedCallers(in)
+	exceptions := in.Settings().LambdaExceptions().TracingExceptions
+	for _, function := range plancheck.Resources(in.Plan, "aws_lambda_function") {
+		caller, ok := callers[plancheck.ConfigAddress(function.Address)]
+		name := plancheck.LookupString(function.AttributeValues, "function_name")
+		if !ok || exempt(exceptions, name) {
+			continue
+		}
+		mode := plancheck.LookupString(function.AttributeValues, "tracing_config.0.mode")
+		if mode == "Active" {
+			continue
+		}
+		if mode == "" {
+			mode = "unset"
+		}
+		findings = append(findings, plancheck.NewFinding(
+			"tracing.propagation",
+			function.Address,
+			fmt.Sprintf("function %s is invoked by %s but its tracing mode is %s, breaking the trace", name, caller, mode),
+		).WithPath("tracing_config").WithFix(plancheck.Fix{
+			Block:     "tracing_config",
+			Attribute: "mode",
+			Value:     "Active",
+		}))
+	}
+	return findings
+}
+
+// tracedCallers maps the configuration address of each Lambda function that
+// continues a trace to the resource invoking it: an API Gateway integration
+// or an SQS event source mapping.
+func tracedCallers(in *plancheck.Input) map[string]string {
+	callers := map[string]string{}
+	for _, integration := range plancheck.Resources(in.Plan, "aws_api_gateway_integration") {
+		for _, ref := range in.References(integration.Address, "uri") {
+			if resourceType(ref) == "aws_lambda_function" {
+				callers[ref] = integration.Address
+			}
+		}
+	}
+
+	for _, mapping := range plancheck.Resources(in.Plan, "aws_lambda_event_source_mapping") {
+		fromSQS := strings.HasPrefix(plancheck.LookupString(mapping.AttributeValues, "event_source_arn"), "arn:aws:sqs:")
+		for _, ref := range in.References(mapping.Address, "event_source_arn") {
+			fromSQS = fromSQS || resourceType(ref) == "aws_sqs_queue"
+		}
+		if !fromSQS {
+			continue
+		}
+		for _, ref := range in.References(mapping.Address, "function_name") {
+			if resourceType(ref) == "aws_lambda_function" {
+				callers[ref] = mapping.Address
+			}
+		}
+	}
+	return callers
+}
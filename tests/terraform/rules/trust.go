@@ -0,0 +1,152 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cs450/terraformtests/plancheck"
+)
+
+const trustExample = `
+data "aws_iam_policy_document" "events_assume" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["events.amazonaws.com"]
+    }
+    condition {
+      test     = "StringEquals"
+      variable = "aws:SourceAccount"
+      values   = [data.aws_caller_identity.current.account_id]
+    }
+  }
+}`
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:            "iam.trust-policy",
+		Description:   `Role trust policies must not trust "*", must scope service principals open to the confused deputy problem with aws:SourceArn or aws:SourceAccount, and may only trust accounts in iam.trusted_accounts besides the environment's own.`,
+		Remediation:   "Name the principals that assume the role, add an aws:SourceArn or aws:SourceAccount condition for the service, or add the partner account to iam.trusted_accounts in compliance.yaml.",
+		Rationale:     "Whoever the trust policy admits gets every permission of the role. \"*\" admits any AWS account, an unscoped service principal lets another account's resources make the service act with this role, and an unreviewed account is access nobody approved.",
+		Example:       trustExample,
+		ResourceTypes: []string{"aws_iam_role"},
+		Check:         checkTrustPolicies,
+	})
+}
+
+var accountID = regexp.MustCompile(`^(?:arn:aws[a-z-]*:iam::)?(\d{12})(?::|$)`)
+
+func checkTrustPolicies(in *plancheck.Input) []plancheck.Finding {
+	var policy plancheck.IAMPolicy
+	if in.Config != nil {
+		policy = in.Config.IAM
+	}
+	trusted := append([]string{in.Config.Environment(in.Environment).Account}, policy.TrustedAccounts...)
+	scoped := policy.ServicePrincipals()
+
+	var findings []plancheck.Finding
+	for _, role := range plancheck.Resources(in.Plan, "aws_iam_role") {
+		document := plancheck.LookupString(role.AttributeValues, "assume_role_policy")
+		if strings.TrimSpace(document) == "" {
+			continue
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(document), &doc); err != nil {
+			findings = append(findings, plancheck.NewFinding(
+				"iam.trust-policy",
+				role.Address,
+				fmt.Sprintf("trust policy of %s must contain valid JSON: %v", role.Address, err),
+			).WithPath("assume_role_policy"))
+			continue
+		}
+
+		// The role's own account, when the plan knows its ARN.
+		own := trusted
+		if match := accountID.FindStringSubmatch(plancheck.LookupString(role.AttributeValues, "arn")); match != nil {
+			own = append([]string{match[1]}, trusted...)
+		}
+
+		for _, statement := range plancheck.Statements(doc) {
+			if effect, _ := statement.Fields["Effect"].(string); effect != "Allow" {
+				continue
+			}
+			path := "assume_role_policy." + statement.Path + ".Principal"
+			for _, principal := range trustPrincipals(statement.Fields["Principal"]) {
+				var problem string
+				switch {
+				case principal.value == "*":
+					problem = fmt.Sprintf("trusts every AWS principal (%s \"*\")", principal.kind)
+				case principal.kind == "Service" && contains(scoped, principal.value) && !sourceScoped(statement.Fields["Condition"]):
+					problem = fmt.Sprintf("trusts %s without an aws:SourceArn or aws:SourceAccount condition", principal.value)
+				case principal.kind == "AWS":
+					match := accountID.FindStringSubmatch(principal.value)
+					if match == nil || contains(own, match[1]) {
+						continue
+					}
+					problem = fmt.Sprintf("trusts account %s (%s), which iam.trusted_accounts does not list", match[1], principal.value)
+				default:
+					continue
+				}
+				findings = append(findings, plancheck.NewFinding(
+					"iam.trust-policy",
+					role.Address,
+					fmt.Sprintf("trust policy of %s %s", role.Address, problem),
+				).WithPath(path))
+			}
+		}
+	}
+	return findings
+}
+
+type trustPrincipal struct {
+	// kind is the principal type, e.g. "AWS" or "Service", or "Principal"
+	// for a bare "Principal": "*".
+	kind  string
+	value string
+}
+
+// trustPrincipals flattens a statement's Principal: "*" or a map from
+// principal type to one or more identifiers.
+func trustPrincipals(value interface{}) []trustPrincipal {
+	if s, ok := value.(string); ok {
+		return []trustPrincipal{{kind: "Principal", value: strings.TrimSpace(s)}}
+	}
+	principals, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	kinds := make([]string, 0, len(principals))
+	for kind := range principals {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var result []trustPrincipal
+	for _, kind := range kinds {
+		for _, id := range policyStrings(principals[kind]) {
+			result = append(result, trustPrincipal{kind: kind, value: strings.TrimSpace(id)})
+		}
+	}
+	return result
+}
+
+// sourceScoped reports whether a statement's Condition tests aws:SourceArn
+// or aws:SourceAccount, under any operator. Condition keys are
+// case-insensitive.
+func sourceScoped(condition interface{}) bool {
+	operators, _ := condition.(map[string]interface{})
+	for _, keys := range operators {
+		tests, _ := keys.(map[string]interface{})
+		for key := range tests {
+			switch strings.ToLower(key) {
+			case "aws:sourcearn", "aws:sourceaccount":
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:          "variables.contract",
+		Description: "Input variables must declare a type and a description, validate values with a known format, and be used.",
+		Remediation: "Add type and description to the variable, a validation block for regions, ARNs, account IDs, bucket names and CIDR blocks, or remove the variable if nothing references it.",
+		Check:       checkVariables,
+		CheckSource: checkVariablesSource,
+	})
+}
+
+// constrainedVariables maps name suffixes to the formats string variables
+// with those names hold, which a validation block can check before apply.
+var constrainedVariables = []struct {
+	suffix, format string
+}{
+	{"region", "region"},
+	{"_arn", "ARN"},
+	{"account_id", "account ID"},
+	{"bucket", "bucket name"},
+	{"cidr", "CIDR block"},
+}
+
+// checkVariables judges the declarations of the root module's variables and
+// reports those nothing references, including through locals, which the
+// plan does not record.
+func checkVariables(in *plancheck.Input) []plancheck.Finding {
+	if in.Module == nil {
+		return nil
+	}
+	referenced := in.Module.VariableReferences()
+
+	var findings []plancheck.Finding
+	for _, file := range in.Module.Files {
+		findings = append(findings, checkVariablesSource(file)...)
+		for _, block := range file.Variables() {
+			if referenced[block.Labels[0]] {
+				continue
+			}
+			findings = append(findings, file.StaticFinding("variables.contract", block, block.DefRange(),
+				fmt.Sprintf("variable %s is declared but never used", block.Labels[0])))
+		}
+	}
+	return findings
+}
+
+func checkVariablesSource(file *plancheck.SourceFile) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, block := range file.Variables() {
+		name := block.Labels[0]
+		finding := func(path, message string) plancheck.Finding {
+			return file.StaticFinding("variables.contract", block, block.DefRange(), message).WithPath(path)
+		}
+
+		typeAttr, typed := block.Body.Attributes["type"]
+		if !typed {
+			findings = append(findings, finding("type", fmt.Sprintf("variable %s has no type", name)))
+		}
+		if !hasDescription(block) {
+			findings = append(findings, finding("description", fmt.Sprintf("variable %s has no description", name)))
+		}
+
+		if !typed || hcl.ExprAsKeyword(typeAttr.Expr) != "string" || hasBlock(block, "validation") {
+			continue
+		}
+		for _, constrained := range constrainedVariables {
+			if strings.HasSuffix(name, constrained.suffix) {
+				findings = append(findings, finding("validation",
+					fmt.Sprintf("variable %s holds a %s but has no validation block", name, constrained.format)))
+				break
+			}
+		}
+	}
+	return findings
+}
+
+func hasDescription(block *hclsyntax.Block) bool {
+	attr, ok := block.Body.Attributes["description"]
+	if !ok {
+		return false
+	}
+	value, known := plancheck.Literal(attr.Expr)
+	return !known || value.Type() == cty.String && !value.IsNull() && strings.TrimSpace(value.AsString()) != ""
+}
+
+func hasBlock(block *hclsyntax.Block, blockType string) bool {
+	for _, nested := range block.Body.Blocks {
+		if nested.Type == blockType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"cs450/terraformtests/plancheck"
+)
+
+func init() {
+	plancheck.Register(plancheck.Rule{
+		ID:          "waivers.expired",
+		Description: "Baseline entries and " + plancheck.SuppressTag + " tags must not be past their expiry date.",
+		Remediation: "Review the accepted finding: fix it and remove the waiver, or renew it with a new justification and a new expiry date (" + plancheck.ExpiresTag + " tag, or expires in the baseline).",
+		Rationale:   "An expired waiver no longer hides its finding, but it still reads as accepted risk; failing on it makes someone look at the risk again rather than letting exceptions become permanent.",
+		Check:       checkExpiredWaivers,
+	})
+}
+
+func checkExpiredWaivers(in *plancheck.Input) []plancheck.Finding {
+	var findings []plancheck.Finding
+	for _, waiver := range plancheck.ExpiredWaivers(in.Baseline, plancheck.TagSuppressions(in.Plan), in.Time()) {
+		region, address := plancheck.SplitKey(waiver.Key)
+		rules := strings.Join(waiver.RuleIDs, " ")
+
+		var finding plancheck.Finding
+		_, invalid := plancheck.ParseExpiry(waiver.Expires)
+		switch {
+		case waiver.Tag && invalid != nil:
+			finding = plancheck.NewFinding("waivers.expired", address,
+				fmt.Sprintf("the %s tag of %s waiving %s no longer applies: %s %q is not a date such as 2026-11-30", plancheck.SuppressTag, address, rules, plancheck.ExpiresTag, waiver.Expires),
+			).WithPath("tags")
+		case waiver.Tag:
+			finding = plancheck.NewFinding("waivers.expired", address,
+				fmt.Sprintf("the %s tag of %s waiving %s expired on %s", plancheck.SuppressTag, address, rules, waiver.Expires),
+			).WithPath("tags")
+		case waiver.Path != "":
+			finding = plancheck.NewFinding("waivers.expired", address,
+				fmt.Sprintf("the baseline entry accepting %s at %s of %s expired on %s", rules, waiver.Path, address, waiver.Expires))
+		default:
+			finding = plancheck.NewFinding("waivers.expired", address,
+				fmt.Sprintf("the baseline entry accepting %s on %s expired on %s", rules, address, waiver.Expires))
+		}
+		finding.Region = region
+		findings = append(findings, finding)
+	}
+	return findings
+}
@@ -0,0 +1,70 @@
+package terraformtests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"cs450/terraformtests/awsauth"
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/runlock"
+)
+
+var (
+	// heldLocks are the environments this test binary has locked, which
+	// TestMain releases once every test has run.
+	heldLocksMu sync.Mutex
+	heldLocks   []*runlock.Lock
+)
+
+// lockEnvironment takes the run lock on environment, with the credentials
+// terraform plans it with, and holds it until TestMain releases it. The
+// heartbeat signs with creds.AWS(), which assumes the role again before it
+// expires, so a run longer than awsauth.DefaultDuration keeps its lock. It
+// does nothing when compliance.yaml names no lock table.
+func lockEnvironment(environment string, creds *awsauth.Credentials) error {
+	config, err := plancheck.LoadConfig(complianceFile)
+	if err != nil {
+		return err
+	}
+	if config.Lock.Table == "" {
+		return nil
+	}
+	client, err := runlock.NewClient(config.Lock.TableRegion(), config.Lock.Table, creds.AWS())
+	if err != nil {
+		return err
+	}
+	client.StaleAfter = config.Lock.StaleAfter()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	lock, err := client.Acquire(ctx, environment, runlock.DefaultOwner())
+	if err != nil {
+		return err
+	}
+	heldLocksMu.Lock()
+	heldLocks = append(heldLocks, lock)
+	heldLocksMu.Unlock()
+	return nil
+}
+
+// releaseLocks releases every lock lockEnvironment took, reporting failures
+// on stderr. It returns false if any lock was lost while held, in which
+// case another run may have planned the environment concurrently.
+func releaseLocks() bool {
+	heldLocksMu.Lock()
+	defer heldLocksMu.Unlock()
+	ok := true
+	for _, lock := range heldLocks {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		if err := lock.Release(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "releasing the run lock on %s: %v\n", lock.Environment, err)
+			ok = false
+		}
+		cancel()
+	}
+	heldLocks = nil
+	return ok
+}
@@ -0,0 +1,318 @@
+// Package runlock keeps two runs of the suite from planning or applying the
+// same environment at once. A run holds an environment by writing an item
+// to a DynamoDB table with a conditional put, and refreshes the item's
+// heartbeat while it runs; a lock whose heartbeat has gone stale, because
+// its run was killed, is taken over.
+package runlock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+
+	"cs450/terraformtests/awsapi"
+)
+
+// KeyPrefix starts the LockID of every lock, so the locks can share
+// terraform's state lock table, whose LockIDs start with the state bucket.
+const KeyPrefix = "tfcompliance/"
+
+// DefaultStaleAfter is how long a lock's heartbeat may go unrefreshed before
+// another run takes the lock over.
+const DefaultStaleAfter = 15 * time.Minute
+
+// DynamoDBAPI reads and conditionally writes lock items.
+type DynamoDBAPI interface {
+	PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	UpdateItem(context.Context, *dynamodb.UpdateItemInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// Client reads and writes the locks in one table, whose string partition
+// key is LockID.
+type Client struct {
+	DynamoDB DynamoDBAPI
+	Table    string
+
+	// StaleAfter is how old a heartbeat must be for its lock to be taken
+	// over. Zero means DefaultStaleAfter. Held locks refresh their heartbeat
+	// three times as often.
+	StaleAfter time.Duration
+
+	// Now returns the time heartbeats are stamped and judged with. Nil means
+	// time.Now.
+	Now func() time.Time
+}
+
+// NewClient returns a client for table in region from the default awsapi
+// factory, signing with creds, or the factory's credentials when creds is
+// nil.
+func NewClient(region, table string, creds *credentials.Credentials) (*Client, error) {
+	cfg, err := awsapi.Default().WithCredentials(creds).Config(context.Background(), region)
+	if err != nil {
+		return nil, fmt.Errorf("runlock: %w", err)
+	}
+	return &Client{DynamoDB: dynamodb.NewFromConfig(cfg), Table: table}, nil
+}
+
+func (c *Client) staleAfter() time.Duration {
+	if c.StaleAfter == 0 {
+		return DefaultStaleAfter
+	}
+	return c.StaleAfter
+}
+
+func (c *Client) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+// Info describes who holds a lock.
+type Info struct {
+	Environment string
+	// ID identifies this holding of the lock; force-unlocking needs it, so a
+	// lock cannot be removed by mistake after another run has taken it.
+	ID        string
+	Owner     string
+	Acquired  time.Time
+	Heartbeat time.Time
+}
+
+func (i Info) String() string {
+	return fmt.Sprintf("%s (lock %s, acquired %s, last heartbeat %s)",
+		i.Owner, i.ID, i.Acquired.UTC().Format(time.RFC3339), i.Heartbeat.UTC().Format(time.RFC3339))
+}
+
+// HeldError is returned when another run holds the lock.
+type HeldError struct {
+	Holder Info
+}
+
+func (e *HeldError) Error() string {
+	return fmt.Sprintf("runlock: %s is locked by %s; if that run is gone, wait for the lock to go stale or run tfcompliance unlock -env %s -id %s",
+		e.Holder.Environment, e.Holder, e.Holder.Environment, e.Holder.ID)
+}
+
+// ErrNotHeld is returned when a lock to release or remove is not there, or
+// is held under another ID.
+var ErrNotHeld = errors.New("runlock: lock is not held under that ID")
+
+// DefaultOwner describes the current run: the GitHub Actions run when there
+// is one, and the host and process.
+func DefaultOwner() string {
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s pid %d", host, os.Getpid())
+	if run := os.Getenv("GITHUB_RUN_ID"); run != "" {
+		owner = fmt.Sprintf("%s run %s (%s)", os.Getenv("GITHUB_REPOSITORY"), run, owner)
+	}
+	return owner
+}
+
+func key(environment string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"LockID": &types.AttributeValueMemberS{Value: KeyPrefix + environment}}
+}
+
+// idName spares condition expressions from DynamoDB's reserved words.
+var idName = map[string]string{"#id": "ID"}
+
+func unix(t time.Time) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(t.Unix(), 10)}
+}
+
+// conditionFailed reports whether err is a failed write condition.
+func conditionFailed(err error) bool {
+	var failed *types.ConditionalCheckFailedException
+	return errors.As(err, &failed)
+}
+
+// Lock is a held lock, refreshed until it is released.
+type Lock struct {
+	Info
+	client *Client
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+	// lost is set when the lock was taken over or removed while held.
+	lost bool
+	// err is why the last heartbeat failed, or why the lock was lost.
+	err error
+}
+
+// Acquire takes the lock on environment for owner, taking over a lock whose
+// heartbeat is older than the client's StaleAfter. It returns a *HeldError
+// when another run holds the lock.
+func (c *Client) Acquire(ctx context.Context, environment, owner string) (*Lock, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("runlock: %w", err)
+	}
+	now := c.now()
+	info := Info{Environment: environment, ID: hex.EncodeToString(id), Owner: owner, Acquired: now, Heartbeat: now}
+
+	item := key(environment)
+	item["ID"] = &types.AttributeValueMemberS{Value: info.ID}
+	item["Owner"] = &types.AttributeValueMemberS{Value: owner}
+	item["Acquired"] = unix(now)
+	item["Heartbeat"] = unix(now)
+	_, err := c.DynamoDB.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String(c.Table),
+		Item:                      item,
+		ConditionExpression:       aws.String("attribute_not_exists(LockID) OR Heartbeat < :stale"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":stale": unix(now.Add(-c.staleAfter()))},
+	})
+	if conditionFailed(err) {
+		holder, found, err := c.Holder(ctx, environment)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			// Released between the put and the read: try once more.
+			return c.Acquire(ctx, environment, owner)
+		}
+		return nil, &HeldError{Holder: holder}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("runlock: locking %s in %s: %w", environment, c.Table, err)
+	}
+
+	lock := &Lock{Info: info, client: c, stop: make(chan struct{}), done: make(chan struct{})}
+	go lock.heartbeat()
+	return lock, nil
+}
+
+// heartbeat refreshes the lock until it is released, and stops early if the
+// lock was taken from it.
+func (l *Lock) heartbeat() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.client.staleAfter() / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		_, err := l.client.DynamoDB.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                aws.String(l.client.Table),
+			Key:                      key(l.Environment),
+			UpdateExpression:         aws.String("SET Heartbeat = :now"),
+			ConditionExpression:      aws.String("#id = :id"),
+			ExpressionAttributeNames: idName,
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":now": unix(l.client.now()),
+				":id":  &types.AttributeValueMemberS{Value: l.ID},
+			},
+		})
+		cancel()
+		if conditionFailed(err) {
+			l.lost = true
+			if l.err != nil {
+				l.err = fmt.Errorf("runlock: lock on %s was taken over or removed while held, after its heartbeat failed: %w", l.Environment, l.err)
+			} else {
+				l.err = fmt.Errorf("runlock: lock on %s was taken over or removed while held", l.Environment)
+			}
+			return
+		}
+		// Other errors are retried on the next tick; the lock only goes
+		// stale after several of them. The last one is kept to explain a
+		// lock lost meanwhile.
+		l.err = nil
+		if err != nil {
+			l.err = fmt.Errorf("refreshing the lock on %s: %w", l.Environment, err)
+		}
+	}
+}
+
+// Release stops refreshing the lock and removes it. It returns an error if
+// the lock was taken over or removed while held, with the error of the last
+// failed heartbeat, if any.
+func (l *Lock) Release(ctx context.Context) error {
+	l.once.Do(func() { close(l.stop) })
+	<-l.done
+	if l.lost {
+		return l.err
+	}
+	err := l.client.remove(ctx, l.Environment, l.ID)
+	if err != nil && l.err != nil {
+		return fmt.Errorf("%w (last heartbeat: %v)", err, l.err)
+	}
+	return err
+}
+
+// Holder returns who holds the lock on environment, and whether anyone does.
+func (c *Client) Holder(ctx context.Context, environment string) (Info, bool, error) {
+	out, err := c.DynamoDB.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(c.Table),
+		Key:            key(environment),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return Info{}, false, fmt.Errorf("runlock: reading the lock on %s: %w", environment, err)
+	}
+	if out.Item == nil {
+		return Info{}, false, nil
+	}
+	info := Info{
+		Environment: environment,
+		ID:          stringValue(out.Item["ID"]),
+		Owner:       stringValue(out.Item["Owner"]),
+		Acquired:    unixValue(out.Item["Acquired"]),
+		Heartbeat:   unixValue(out.Item["Heartbeat"]),
+	}
+	return info, true, nil
+}
+
+func stringValue(value types.AttributeValue) string {
+	if s, ok := value.(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
+
+func unixValue(value types.AttributeValue) time.Time {
+	n, ok := value.(*types.AttributeValueMemberN)
+	if !ok {
+		return time.Time{}
+	}
+	seconds, _ := strconv.ParseInt(n.Value, 10, 64)
+	return time.Unix(seconds, 0)
+}
+
+// ForceUnlock removes the lock on environment if it is still held under id,
+// for a run that died without releasing it. The run, if it is in fact still
+// going, finds out at its next heartbeat.
+func (c *Client) ForceUnlock(ctx context.Context, environment, id string) error {
+	return c.remove(ctx, environment, id)
+}
+
+func (c *Client) remove(ctx context.Context, environment, id string) error {
+	_, err := c.DynamoDB.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(c.Table),
+		Key:                       key(environment),
+		ConditionExpression:       aws.String("#id = :id"),
+		ExpressionAttributeNames:  idName,
+		ExpressionAttributeValues: map[string]types.AttributeValue{":id": &types.AttributeValueMemberS{Value: id}},
+	})
+	if conditionFailed(err) {
+		return fmt.Errorf("%w: %s, %s", ErrNotHeld, environment, id)
+	}
+	if err != nil {
+		return fmt.Errorf("runlock: unlocking %s: %w", environment, err)
+	}
+	return nil
+}
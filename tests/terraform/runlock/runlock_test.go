@@ -0,0 +1,181 @@
+package runlock
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTable holds items by LockID and evaluates the conditions the client
+// writes with.
+type fakeTable struct {
+	DynamoDBAPI
+	items map[string]map[string]types.AttributeValue
+	// updateErr, when set, fails every heartbeat.
+	updateErr error
+}
+
+var errConditionFailed = &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+
+func lockID(key map[string]types.AttributeValue) string {
+	return stringValue(key["LockID"])
+}
+
+func number(value types.AttributeValue) int64 {
+	n, _ := strconv.ParseInt(value.(*types.AttributeValueMemberN).Value, 10, 64)
+	return n
+}
+
+// heldUnder reports whether the item at key is held under the ID in values.
+func (f *fakeTable) heldUnder(key map[string]types.AttributeValue, values map[string]types.AttributeValue) bool {
+	item, ok := f.items[lockID(key)]
+	return ok && stringValue(item["ID"]) == stringValue(values[":id"])
+}
+
+func (f *fakeTable) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if existing, ok := f.items[lockID(in.Item)]; ok && number(existing["Heartbeat"]) >= number(in.ExpressionAttributeValues[":stale"]) {
+		return nil, errConditionFailed
+	}
+	f.items[lockID(in.Item)] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeTable) GetItem(_ context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: f.items[lockID(in.Key)]}, nil
+}
+
+func (f *fakeTable) UpdateItem(_ context.Context, in *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	if f.updateErr != nil {
+		return nil, f.updateErr
+	}
+	if !f.heldUnder(in.Key, in.ExpressionAttributeValues) {
+		return nil, errConditionFailed
+	}
+	f.items[lockID(in.Key)]["Heartbeat"] = in.ExpressionAttributeValues[":now"]
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (f *fakeTable) DeleteItem(_ context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	if !f.heldUnder(in.Key, in.ExpressionAttributeValues) {
+		return nil, errConditionFailed
+	}
+	delete(f.items, lockID(in.Key))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func newClient() (*Client, *time.Time) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	client := &Client{
+		DynamoDB:   &fakeTable{items: map[string]map[string]types.AttributeValue{}},
+		Table:      "terraform-state-lock",
+		StaleAfter: time.Hour,
+		Now:        func() time.Time { return now },
+	}
+	return client, &now
+}
+
+func TestAcquireRefusesAHeldLock(t *testing.T) {
+	client, _ := newClient()
+	ctx := context.Background()
+
+	lock, err := client.Acquire(ctx, "dev", "run 1")
+	require.NoError(t, err)
+
+	_, err = client.Acquire(ctx, "dev", "run 2")
+	var held *HeldError
+	require.ErrorAs(t, err, &held)
+	require.Equal(t, "run 1", held.Holder.Owner)
+	require.Equal(t, lock.ID, held.Holder.ID)
+	require.Contains(t, err.Error(), "tfcompliance unlock -env dev -id "+lock.ID)
+
+	other, err := client.Acquire(ctx, "stage", "run 2")
+	require.NoError(t, err, "environments are locked separately")
+	require.NoError(t, other.Release(ctx))
+
+	require.NoError(t, lock.Release(ctx))
+	_, found, err := client.Holder(ctx, "dev")
+	require.NoError(t, err)
+	require.False(t, found)
+
+	again, err := client.Acquire(ctx, "dev", "run 2")
+	require.NoError(t, err)
+	require.NoError(t, again.Release(ctx))
+}
+
+func TestAcquireTakesOverAStaleLock(t *testing.T) {
+	client, now := newClient()
+	ctx := context.Background()
+
+	stale, err := client.Acquire(ctx, "dev", "killed run")
+	require.NoError(t, err)
+
+	*now = now.Add(59 * time.Minute)
+	_, err = client.Acquire(ctx, "dev", "run 2")
+	require.Error(t, err, "a heartbeat within StaleAfter holds the lock")
+
+	*now = now.Add(2 * time.Minute)
+	lock, err := client.Acquire(ctx, "dev", "run 2")
+	require.NoError(t, err)
+
+	holder, found, err := client.Holder(ctx, "dev")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "run 2", holder.Owner)
+	require.Equal(t, *now, holder.Acquired.UTC())
+
+	require.ErrorIs(t, stale.Release(ctx), ErrNotHeld, "the old holder must not remove the new lock")
+	require.NoError(t, lock.Release(ctx))
+}
+
+func TestForceUnlockNeedsTheHoldersID(t *testing.T) {
+	client, _ := newClient()
+	ctx := context.Background()
+
+	lock, err := client.Acquire(ctx, "dev", "run 1")
+	require.NoError(t, err)
+
+	err = client.ForceUnlock(ctx, "dev", "0123456789abcdef")
+	require.True(t, errors.Is(err, ErrNotHeld))
+
+	require.NoError(t, client.ForceUnlock(ctx, "dev", lock.ID))
+	_, found, err := client.Holder(ctx, "dev")
+	require.NoError(t, err)
+	require.False(t, found)
+}
+
+func TestReleaseReportsWhyHeartbeatsFailed(t *testing.T) {
+	table := &fakeTable{
+		items:     map[string]map[string]types.AttributeValue{},
+		updateErr: &smithy.GenericAPIError{Code: "ExpiredTokenException", Message: "The security token included in the request is expired"},
+	}
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	client := &Client{DynamoDB: table, Table: "terraform-state-lock", StaleAfter: 30 * time.Millisecond, Now: func() time.Time { return start }}
+	ctx := context.Background()
+
+	lock, err := client.Acquire(ctx, "dev", "run 1")
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	later := *client
+	later.Now = func() time.Time { return start.Add(time.Hour) }
+	other, err := later.Acquire(ctx, "dev", "run 2")
+	require.NoError(t, err, "a lock whose heartbeats fail goes stale")
+
+	err = lock.Release(ctx)
+	require.ErrorIs(t, err, ErrNotHeld)
+	require.ErrorContains(t, err, "ExpiredTokenException")
+
+	holder, found, err := client.Holder(ctx, "dev")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, other.ID, holder.ID, "the old holder must not remove the new lock")
+	require.NoError(t, other.Release(ctx))
+}
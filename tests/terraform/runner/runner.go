@@ -0,0 +1,171 @@
+// Package runner plans a terraform configuration outside of go test, for the
+// tfcompliance commands that need a fresh plan, and applies checked plans.
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// BinaryEnv overrides the terraform binary, e.g. TFCOMPLIANCE_TERRAFORM=tofu.
+const BinaryEnv = "TFCOMPLIANCE_TERRAFORM"
+
+// Options describes how to plan a configuration.
+type Options struct {
+	// Dir is the root module to plan.
+	Dir string
+	// Vars are passed to plan as -var name=value.
+	Vars map[string]string
+	// Binary is the terraform executable; it defaults to $TFCOMPLIANCE_TERRAFORM
+	// and then "terraform".
+	Binary string
+	// SkipInit reuses the existing .terraform directory instead of running init.
+	SkipInit bool
+	// Env adds to or overrides the environment terraform runs with, e.g.
+	// assumed-role credentials.
+	Env map[string]string
+	// PlanFile, when set, is where the binary plan is written and kept, e.g.
+	// for a later terraform apply. By default it is removed after show.
+	PlanFile string
+}
+
+// Plan runs init (unless skipped), plan and show -json and returns the plan.
+// Unless opts.PlanFile names one, the plan file is written to a directory of
+// its own, so concurrent plans of the same or different configurations do not
+// overwrite each other.
+func Plan(ctx context.Context, opts Options) (*tfjson.Plan, error) {
+	var planFile string
+	if opts.PlanFile != "" {
+		// terraform runs in opts.Dir; a relative name is the caller's.
+		abs, err := filepath.Abs(opts.PlanFile)
+		if err != nil {
+			return nil, err
+		}
+		planFile = abs
+	} else {
+		planDir, err := os.MkdirTemp("", "tfcompliance-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(planDir)
+		planFile = filepath.Join(planDir, "tfcompliance.tfplan")
+	}
+
+	if !opts.SkipInit {
+		if _, err := opts.run(ctx, "init", "-input=false", "-no-color"); err != nil {
+			return nil, err
+		}
+	}
+
+	args := []string{"plan", "-input=false", "-no-color", "-out=" + planFile}
+	names := make([]string, 0, len(opts.Vars))
+	for name := range opts.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "-var", name+"="+opts.Vars[name])
+	}
+	if _, err := opts.run(ctx, args...); err != nil {
+		return nil, err
+	}
+
+	return show(ctx, opts, planFile)
+}
+
+// Show returns the plan in an existing plan file, as terraform show -json
+// reads it in opts.Dir.
+func Show(ctx context.Context, opts Options, planFile string) (*tfjson.Plan, error) {
+	abs, err := filepath.Abs(planFile)
+	if err != nil {
+		return nil, err
+	}
+	return show(ctx, opts, abs)
+}
+
+func show(ctx context.Context, opts Options, planFile string) (*tfjson.Plan, error) {
+	out, err := opts.run(ctx, "show", "-json", planFile)
+	if err != nil {
+		return nil, err
+	}
+	var plan tfjson.Plan
+	if err := json.Unmarshal(out, &plan); err != nil {
+		return nil, fmt.Errorf("parse plan JSON: %w", err)
+	}
+	return &plan, nil
+}
+
+// Apply applies the saved plan in planFile, and nothing else: terraform
+// refuses a saved plan whose state has moved on since it was made. Its
+// output is written to w as it runs.
+func Apply(ctx context.Context, opts Options, planFile string, w io.Writer) error {
+	abs, err := filepath.Abs(planFile)
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := opts.command(ctx, "apply", "-input=false", "-no-color", abs)
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s apply: %w\n%s", opts.binary(), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Version runs terraform version -json in opts.Dir. The provider selections
+// it returns are those of the providers init has installed there.
+func Version(ctx context.Context, opts Options) (*tfjson.VersionOutput, error) {
+	out, err := opts.run(ctx, "version", "-json")
+	if err != nil {
+		return nil, err
+	}
+	var version tfjson.VersionOutput
+	if err := json.Unmarshal(out, &version); err != nil {
+		return nil, fmt.Errorf("parse version JSON: %w", err)
+	}
+	return &version, nil
+}
+
+func (opts Options) binary() string {
+	switch {
+	case opts.Binary != "":
+		return opts.Binary
+	case os.Getenv(BinaryEnv) != "":
+		return os.Getenv(BinaryEnv)
+	}
+	return "terraform"
+}
+
+func (opts Options) command(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, opts.binary(), args...)
+	cmd.Dir = opts.Dir
+	if len(opts.Env) > 0 {
+		cmd.Env = os.Environ()
+		for name, value := range opts.Env {
+			cmd.Env = append(cmd.Env, name+"="+value)
+		}
+	}
+	return cmd
+}
+
+func (opts Options) run(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := opts.command(ctx, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s %s: %w\n%s", opts.binary(), args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTerraform writes a script that logs its arguments and prints a minimal
+// plan for "show".
+func fakeTerraform(t *testing.T) (binary, log string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	binary = filepath.Join(dir, "terraform")
+	log = filepath.Join(dir, "calls.log")
+	script := `#!/bin/sh
+echo "$@" >> ` + log + `
+if [ -n "$TFCOMPLIANCE_TEST_ENV" ]; then echo "env $TFCOMPLIANCE_TEST_ENV" >> ` + log + `; fi
+if [ "$1" = "show" ]; then
+  echo '{"format_version":"1.0","planned_values":{"root_module":{}}}'
+fi
+if [ "$1" = "version" ]; then
+  echo '{"terraform_version":"1.6.2","provider_selections":{"registry.terraform.io/hashicorp/aws":"5.31.0"},"terraform_outdated":false}'
+fi
+`
+	require.NoError(t, os.WriteFile(binary, []byte(script), 0o755))
+	return binary, log
+}
+
+func TestPlanRunsInitPlanAndShow(t *testing.T) {
+	binary, log := fakeTerraform(t)
+
+	dir := t.TempDir()
+	plan, err := Plan(context.Background(), Options{
+		Dir:    dir,
+		Binary: binary,
+		Vars:   map[string]string{"b": "2", "a": "1"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, plan.PlannedValues)
+
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	planFile := planFiles(t, string(calls))[0]
+	require.NotEqual(t, dir, filepath.Dir(planFile), "the plan file must not be written into the module")
+	require.Equal(t, []string{
+		"init -input=false -no-color",
+		"plan -input=false -no-color -out=" + planFile + " -var a=1 -var b=2",
+		"show -json " + planFile,
+	}, strings.Split(strings.TrimSpace(string(calls)), "\n"))
+	require.NoDirExists(t, filepath.Dir(planFile), "the plan file must be removed")
+}
+
+func TestConcurrentPlansUseSeparateFiles(t *testing.T) {
+	binary, log := fakeTerraform(t)
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = Plan(context.Background(), Options{Dir: dir, Binary: binary, SkipInit: true})
+		}(i)
+	}
+	wg.Wait()
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	files := planFiles(t, string(calls))
+	require.Len(t, files, 2)
+	require.NotEqual(t, files[0], files[1])
+}
+
+// planFiles returns the -out paths of the plan calls in a fake terraform log.
+func planFiles(t *testing.T, calls string) []string {
+	t.Helper()
+
+	var files []string
+	for _, field := range strings.Fields(calls) {
+		if file, ok := strings.CutPrefix(field, "-out="); ok {
+			files = append(files, file)
+		}
+	}
+	require.NotEmpty(t, files, "terraform plan must have been called with -out")
+	return files
+}
+
+func TestPlanSkipsInit(t *testing.T) {
+	binary, log := fakeTerraform(t)
+
+	_, err := Plan(context.Background(), Options{Dir: t.TempDir(), Binary: binary, SkipInit: true})
+	require.NoError(t, err)
+
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	require.NotContains(t, string(calls), "init")
+}
+
+func TestPlanPassesEnv(t *testing.T) {
+	binary, log := fakeTerraform(t)
+
+	_, err := Plan(context.Background(), Options{
+		Dir:      t.TempDir(),
+		Binary:   binary,
+		SkipInit: true,
+		Env:      map[string]string{"TFCOMPLIANCE_TEST_ENV": "assumed"},
+	})
+	require.NoError(t, err)
+
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	require.Contains(t, string(calls), "env assumed")
+}
+
+func TestVersionReadsProviderSelections(t *testing.T) {
+	binary, _ := fakeTerraform(t)
+
+	version, err := Version(context.Background(), Options{Dir: t.TempDir(), Binary: binary})
+	require.NoError(t, err)
+	require.Equal(t, "1.6.2", version.Version)
+	require.Equal(t, map[string]string{"registry.terraform.io/hashicorp/aws": "5.31.0"}, version.ProviderSelections)
+}
+
+func TestPlanWritesANamedPlanFile(t *testing.T) {
+	binary, log := fakeTerraform(t)
+	planFile := filepath.Join(t.TempDir(), "dev.tfplan")
+	require.NoError(t, os.WriteFile(planFile, []byte("plan"), 0o644))
+
+	_, err := Plan(context.Background(), Options{Dir: t.TempDir(), Binary: binary, SkipInit: true, PlanFile: planFile})
+	require.NoError(t, err)
+
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	require.Equal(t, []string{planFile}, planFiles(t, string(calls)))
+	require.FileExists(t, planFile, "a named plan file must be kept for apply")
+}
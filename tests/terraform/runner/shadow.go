@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// PlanRef plans opts.Dir as it is at ref, a commit, branch or tag of the git
+// repository holding it. ref is checked out in a temporary worktree, removed
+// afterwards, so the working tree, index and .terraform directory of the
+// repository are untouched.
+func PlanRef(ctx context.Context, opts Options, ref string) (*tfjson.Plan, error) {
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return nil, err
+	}
+	out, err := git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	top := strings.TrimSpace(string(out))
+	rel, err := filepath.Rel(top, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := os.MkdirTemp("", "tfcompliance-shadow-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(worktree)
+	if _, err := git(ctx, top, "worktree", "add", "--detach", worktree, ref); err != nil {
+		return nil, err
+	}
+	// A fresh context, so the worktree is removed even after a cancellation.
+	defer git(context.Background(), top, "worktree", "remove", "--force", worktree)
+
+	opts.Dir = filepath.Join(worktree, rel)
+	if _, err := os.Stat(opts.Dir); err != nil {
+		return nil, fmt.Errorf("runner: %s does not exist at %s", rel, ref)
+	}
+	opts.SkipInit, opts.PlanFile = false, ""
+	return Plan(ctx, opts)
+}
+
+func git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w\n%s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
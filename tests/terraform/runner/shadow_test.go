@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return string(out)
+}
+
+func TestPlanRefPlansAWorktreeOfTheRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	dir := filepath.Join(repo, "infra", "dev")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte("# base\n"), 0o644))
+	runGit(t, repo, "init", "-q")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "-q", "-m", "base")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte("# refactored\n"), 0o644))
+
+	logs := t.TempDir()
+	binary := filepath.Join(logs, "terraform")
+	require.NoError(t, os.WriteFile(binary, []byte(`#!/bin/sh
+if [ "$1" = "init" ]; then
+  pwd > `+filepath.Join(logs, "dir")+`
+  cat main.tf > `+filepath.Join(logs, "main.tf")+`
+fi
+if [ "$1" = "show" ]; then
+  echo '{"format_version":"1.0","planned_values":{"root_module":{}}}'
+fi
+`), 0o755))
+
+	_, err := PlanRef(context.Background(), Options{Dir: dir, Binary: binary, SkipInit: true}, "HEAD")
+	require.NoError(t, err)
+
+	planned, err := os.ReadFile(filepath.Join(logs, "main.tf"))
+	require.NoError(t, err)
+	require.Equal(t, "# base\n", string(planned), "the ref's configuration must be planned")
+	worktree, err := os.ReadFile(filepath.Join(logs, "dir"))
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(strings.TrimSpace(string(worktree)), filepath.Join("infra", "dev")))
+	require.NoDirExists(t, strings.TrimSpace(string(worktree)), "the worktree must be removed")
+	require.NotContains(t, runGit(t, repo, "worktree", "list"), "tfcompliance-shadow-", "the worktree must be pruned")
+	current, err := os.ReadFile(filepath.Join(dir, "main.tf"))
+	require.NoError(t, err)
+	require.Equal(t, "# refactored\n", string(current), "the working tree must be untouched")
+
+	_, err = PlanRef(context.Background(), Options{Dir: dir, Binary: binary}, "no-such-ref")
+	require.ErrorContains(t, err, "git worktree")
+}
@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Upgrade is a candidate provider version to plan with.
+type Upgrade struct {
+	// Provider is the provider's local name, e.g. "aws".
+	Provider string
+	// Source defaults to hashicorp/<Provider>.
+	Source string
+	// Version is the exact version to plan with, e.g. "5.31.0".
+	Version string
+	// Root is copied so that relative module sources keep resolving. It must
+	// contain the planned directory and defaults to it.
+	Root string
+}
+
+// overrideFile pins the candidate version. Terraform merges *_override.tf
+// files over the configuration, replacing the provider's requirement.
+const overrideFile = "tfcompliance_override.tf"
+
+// PlanUpgrade plans opts.Dir with the provider pinned to upgrade.Version. It
+// works on a temporary copy of upgrade.Root without the lock file, so the
+// original configuration, lock file and .terraform directory are untouched.
+func PlanUpgrade(ctx context.Context, opts Options, upgrade Upgrade) (*tfjson.Plan, error) {
+	if upgrade.Provider == "" || upgrade.Version == "" {
+		return nil, fmt.Errorf("runner: an upgrade needs a provider and a version")
+	}
+	source := upgrade.Source
+	if source == "" {
+		source = "hashicorp/" + upgrade.Provider
+	}
+	root := upgrade.Root
+	if root == "" {
+		root = opts.Dir
+	}
+	rel, err := filepath.Rel(root, opts.Dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("runner: %s is not inside %s", opts.Dir, root)
+	}
+
+	copyRoot, err := os.MkdirTemp("", "tfcompliance-upgrade-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(copyRoot)
+	if err := copyTree(root, copyRoot); err != nil {
+		return nil, fmt.Errorf("runner: copying %s: %w", root, err)
+	}
+
+	dir := filepath.Join(copyRoot, rel)
+	if err := os.Remove(filepath.Join(dir, ".terraform.lock.hcl")); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	override := fmt.Sprintf(`terraform {
+  required_providers {
+    %s = {
+      source  = %q
+      version = %q
+    }
+  }
+}
+`, upgrade.Provider, source, upgrade.Version)
+	if err := os.WriteFile(filepath.Join(dir, overrideFile), []byte(override), 0o644); err != nil {
+		return nil, err
+	}
+
+	opts.Dir, opts.SkipInit = dir, false
+	return Plan(ctx, opts)
+}
+
+// copyTree copies the regular files under src to dst, skipping .terraform
+// directories, which hold downloaded providers and modules.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case entry.IsDir() && (entry.Name() == ".terraform" || entry.Name() == ".git"):
+			return filepath.SkipDir
+		case entry.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case !entry.Type().IsRegular():
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
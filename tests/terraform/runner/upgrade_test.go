@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanUpgradeUsesAPinnedCopy(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "terraform")
+	log := filepath.Join(dir, "calls.log")
+	script := `#!/bin/sh
+if [ "$1" = "init" ]; then
+  echo "dir $(pwd)" >> ` + log + `
+  [ -f ../../modules/s3/main.tf ] && echo "module copied" >> ` + log + `
+  [ -f .terraform.lock.hcl ] && echo "lock file kept" >> ` + log + `
+  cat tfcompliance_override.tf >> ` + log + `
+fi
+if [ "$1" = "show" ]; then
+  echo '{"format_version":"1.0","planned_values":{"root_module":{}}}'
+fi
+`
+	require.NoError(t, os.WriteFile(binary, []byte(script), 0o755))
+
+	root := filepath.Join(t.TempDir(), "infra")
+	env := filepath.Join(root, "envs", "dev")
+	for name, content := range map[string]string{
+		filepath.Join(env, "main.tf"):                     `module "s3" { source = "../../modules/s3" }`,
+		filepath.Join(env, ".terraform.lock.hcl"):         `provider "registry.terraform.io/hashicorp/aws" {}`,
+		filepath.Join(env, ".terraform", "providers.txt"): "cached",
+		filepath.Join(root, "modules", "s3", "main.tf"):   `resource "aws_s3_bucket" "this" {}`,
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0o755))
+		require.NoError(t, os.WriteFile(name, []byte(content), 0o644))
+	}
+
+	_, err := PlanUpgrade(context.Background(), Options{Dir: env, Binary: binary, SkipInit: true},
+		Upgrade{Provider: "aws", Version: "5.31.0", Root: root})
+	require.NoError(t, err)
+
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	require.Contains(t, string(calls), "module copied")
+	require.NotContains(t, string(calls), "lock file kept")
+	require.Contains(t, string(calls), `source  = "hashicorp/aws"`)
+	require.Contains(t, string(calls), `version = "5.31.0"`)
+
+	copied := strings.TrimPrefix(strings.SplitN(string(calls), "\n", 2)[0], "dir ")
+	require.NotEqual(t, env, copied, "the candidate must be planned in a copy")
+	require.NoDirExists(t, copied, "the copy must be removed")
+	require.FileExists(t, filepath.Join(env, ".terraform.lock.hcl"), "the original lock file must be kept")
+	require.NoFileExists(t, filepath.Join(env, overrideFile))
+
+	_, err = PlanUpgrade(context.Background(), Options{Dir: t.TempDir(), Binary: binary},
+		Upgrade{Provider: "aws", Version: "5.31.0", Root: root})
+	require.ErrorContains(t, err, "is not inside")
+}
@@ -0,0 +1,12 @@
+package terraformtests
+
+import (
+	"testing"
+)
+
+// Every bucket, pkg-artifacts and its package registry content included, must
+// be encrypted, versioned, shut off from public access and not writable by
+// any principal.
+func TestS3BucketsAreHardened(t *testing.T) {
+	requireCompliance(t, "s3.encryption", "s3.public-access-block", "s3.versioning", "s3.public-write")
+}
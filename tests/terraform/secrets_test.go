@@ -0,0 +1,55 @@
+package terraformtests
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/livestate"
+	"cs450/terraformtests/plancheck"
+)
+
+// The stack's secrets should be rotated on schedule and read only by the
+// roles granted access to them. Both are judged from what Secrets Manager and
+// CloudTrail recorded, and reported as advisories.
+func TestSecretsAreRotatedAndReadAsGranted(t *testing.T) {
+	if os.Getenv(postApplyEnv) == "" {
+		t.Skipf("set %s=1 to check rotation and readers of the deployed secrets", postApplyEnv)
+	}
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		options, plan := environmentPlan(t, env.Name)
+		config, err := plancheck.LoadConfig(complianceFile)
+		require.NoError(t, err)
+
+		var arns []string
+		for _, secret := range plancheck.Resources(plan, "aws_secretsmanager_secret") {
+			if arn := plancheck.LookupString(secret.AttributeValues, "arn"); arn != "" {
+				arns = append(arns, arn)
+			}
+		}
+		if len(arns) == 0 {
+			t.Skipf("the %s plan has no deployed secrets", env.Name)
+		}
+
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		clients, err := livestate.NewClients(env.Region, creds.AWS())
+		require.NoError(t, err)
+		readAt := time.Now()
+		secrets, err := clients.Secrets(arns)
+		require.NoError(t, err)
+
+		findings := plancheck.Evaluate(&plancheck.Input{
+			Plan:          plan,
+			DefaultRegion: env.Region,
+			Environment:   env.Name,
+			Config:        config,
+			Runtime:       &plancheck.Runtime{Secrets: secrets, ReadAt: readAt},
+		}, requireRules(t, "secrets.rotation-age", "secrets.unexpected-access")...)
+		plancheck.NewSourceIndex(plan, options.TerraformDir).Annotate(findings)
+		requireNoFindings(t, plan, findings)
+	})
+}
@@ -0,0 +1,12 @@
+package terraformtests
+
+import (
+	"testing"
+)
+
+// Only the ports compliance.yaml lists under network.public_ingress may be
+// open to 0.0.0.0/0 or ::/0, whether by inline ingress blocks or rule
+// resources. Rules allowing all traffic are reported as advisories.
+func TestSecurityGroupsHaveNoOpenIngress(t *testing.T) {
+	requireCompliance(t, "sg.open-ingress", "sg.all-traffic")
+}
@@ -0,0 +1,243 @@
+// Package smoke checks that a deployed stack answers where its plan says it
+// should: DNS records resolve to their planned targets, health check
+// endpoints respond in time, and private endpoints stay off the internet.
+package smoke
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultSLA is the time a health check endpoint has to answer when a
+// Checker sets none: Route 53 itself gives up after four seconds, and an
+// endpoint close to that flaps.
+const DefaultSLA = 2 * time.Second
+
+// Resolver looks up DNS names. *net.Resolver implements it.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Checker runs the smoke checks. Its zero value uses the system resolver,
+// DefaultSLA and a five-second connection timeout.
+type Checker struct {
+	Resolver Resolver
+
+	// SLA is the longest a health check endpoint may take to answer.
+	SLA time.Duration
+
+	// Timeout bounds each attempt to connect to a private endpoint.
+	Timeout time.Duration
+
+	// Dial connects to private endpoints; nil uses a net.Dialer.
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// Client requests health check endpoints; nil uses a client that does
+	// not follow redirects, as Route 53 does not.
+	Client *http.Client
+}
+
+func (c *Checker) resolver() Resolver {
+	if c.Resolver == nil {
+		return net.DefaultResolver
+	}
+	return c.Resolver
+}
+
+func (c *Checker) sla() time.Duration {
+	if c.SLA == 0 {
+		return DefaultSLA
+	}
+	return c.SLA
+}
+
+func (c *Checker) timeout() time.Duration {
+	if c.Timeout == 0 {
+		return 5 * time.Second
+	}
+	return c.Timeout
+}
+
+// Resolve checks that a record resolves to its planned target. A record with
+// values must resolve to those values and nothing else. An alias record must
+// share at least one address with its targets, which CDNs and load balancers
+// answer from a rotating pool. Types other than A, AAAA, CNAME and TXT are
+// not checked.
+func (c *Checker) Resolve(ctx context.Context, record Record) error {
+	switch record.Type {
+	case "A", "AAAA":
+		return c.resolveAddresses(ctx, record)
+	case "CNAME":
+		target, err := c.resolver().LookupCNAME(ctx, record.Name)
+		if err != nil {
+			return fmt.Errorf("%s CNAME does not resolve: %w", record.Name, err)
+		}
+		for _, value := range record.Values {
+			if canonicalName(value) == canonicalName(target) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s CNAME resolves to %s, not the planned %s", record.Name, canonicalName(target), strings.Join(record.Values, ", "))
+	case "TXT":
+		texts, err := c.resolver().LookupTXT(ctx, record.Name)
+		if err != nil {
+			return fmt.Errorf("%s TXT does not resolve: %w", record.Name, err)
+		}
+		if missing := difference(unquote(record.Values), texts); len(missing) > 0 {
+			return fmt.Errorf("%s TXT is missing the planned %q", record.Name, missing)
+		}
+	}
+	return nil
+}
+
+func (c *Checker) resolveAddresses(ctx context.Context, record Record) error {
+	resolved, err := c.resolver().LookupHost(ctx, record.Name)
+	if err != nil {
+		return fmt.Errorf("%s %s does not resolve: %w", record.Name, record.Type, err)
+	}
+	resolved = family(resolved, record.Type)
+	if len(resolved) == 0 {
+		return fmt.Errorf("%s has no %s addresses", record.Name, record.Type)
+	}
+
+	if len(record.Aliases) > 0 {
+		var targets []string
+		for _, alias := range record.Aliases {
+			addresses, err := c.resolver().LookupHost(ctx, alias)
+			if err != nil {
+				return fmt.Errorf("%s: alias target %s does not resolve: %w", record.Name, alias, err)
+			}
+			targets = append(targets, family(addresses, record.Type)...)
+		}
+		if len(difference(resolved, targets)) == len(resolved) {
+			return fmt.Errorf("%s resolves to %s, none of which belong to its alias target %s", record.Name, strings.Join(resolved, ", "), strings.Join(record.Aliases, ", "))
+		}
+		return nil
+	}
+
+	if unexpected := difference(resolved, record.Values); len(unexpected) > 0 {
+		return fmt.Errorf("%s resolves to %s, which the plan does not list (planned %s)", record.Name, strings.Join(unexpected, ", "), strings.Join(record.Values, ", "))
+	}
+	return nil
+}
+
+// Healthy checks that a health check endpoint answers 200 within the SLA
+// and, for string-matching checks, with the search string in the first 5120
+// bytes of its body.
+func (c *Checker) Healthy(ctx context.Context, check HealthCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, c.sla())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, check.URL, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", check.URL, err)
+	}
+
+	start := time.Now()
+	resp, err := c.client().Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s did not answer within %s", check.URL, c.sla())
+		}
+		return fmt.Errorf("%s: %w", check.URL, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 5120))
+	elapsed := time.Since(start)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s did not answer within %s", check.URL, c.sla())
+		}
+		return fmt.Errorf("%s: %w", check.URL, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %d, not 200, in %s", check.URL, resp.StatusCode, elapsed.Round(time.Millisecond))
+	}
+	if check.SearchString != "" && !strings.Contains(string(body), check.SearchString) {
+		return fmt.Errorf("%s answered without %q in the first 5120 bytes of its body", check.URL, check.SearchString)
+	}
+	return nil
+}
+
+func (c *Checker) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Unreachable checks that nothing accepts a TCP connection on a private
+// endpoint. Run from outside the VPC, a connection that is refused, times
+// out, or whose host does not resolve publicly all pass.
+func (c *Checker) Unreachable(ctx context.Context, endpoint Endpoint) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+	dial := c.Dial
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	conn, err := dial(ctx, "tcp", endpoint.String())
+	if err != nil {
+		return nil
+	}
+	remote := conn.RemoteAddr()
+	conn.Close()
+	return fmt.Errorf("%s (%s) accepts connections from the internet at %s", endpoint, endpoint.Address, remote)
+}
+
+// family keeps the IPv4 addresses for an A record and the IPv6 addresses
+// for an AAAA record, sorted.
+func family(addresses []string, recordType string) []string {
+	var kept []string
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			continue
+		}
+		if (ip.To4() != nil) == (recordType == "A") {
+			kept = append(kept, ip.String())
+		}
+	}
+	sort.Strings(kept)
+	return kept
+}
+
+// difference returns the values of a that are not in b.
+func difference(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, value := range b {
+		if ip := net.ParseIP(value); ip != nil {
+			value = ip.String()
+		}
+		in[value] = true
+	}
+	var out []string
+	for _, value := range a {
+		if !in[value] {
+			out = append(out, value)
+		}
+	}
+	return out
+}
+
+// unquote strips the quotes terraform configurations often keep around
+// TXT values.
+func unquote(values []string) []string {
+	out := make([]string, len(values))
+	for i, value := range values {
+		out[i] = strings.Trim(value, `"`)
+	}
+	return out
+}
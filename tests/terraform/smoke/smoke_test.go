@@ -0,0 +1,136 @@
+package smoke
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResolver struct {
+	hosts  map[string][]string
+	cnames map[string]string
+	txts   map[string][]string
+}
+
+var errNoSuchHost = errors.New("no such host")
+
+func (r fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if addresses, ok := r.hosts[host]; ok {
+		return addresses, nil
+	}
+	return nil, errNoSuchHost
+}
+
+func (r fakeResolver) LookupCNAME(_ context.Context, host string) (string, error) {
+	if target, ok := r.cnames[host]; ok {
+		return target, nil
+	}
+	return "", errNoSuchHost
+}
+
+func (r fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if texts, ok := r.txts[name]; ok {
+		return texts, nil
+	}
+	return nil, errNoSuchHost
+}
+
+func TestResolveChecksPlannedValues(t *testing.T) {
+	checker := &Checker{Resolver: fakeResolver{
+		hosts: map[string][]string{
+			"api.example.com": {"192.0.2.10", "2001:db8::1"},
+			"old.example.com": {"192.0.2.10", "198.51.100.7"},
+		},
+		cnames: map[string]string{"www.example.com": "api.example.com."},
+		txts:   map[string][]string{"example.com": {"v=spf1 -all"}},
+	}}
+	ctx := context.Background()
+
+	require.NoError(t, checker.Resolve(ctx, Record{Name: "api.example.com", Type: "A", Values: []string{"192.0.2.10"}}))
+	require.NoError(t, checker.Resolve(ctx, Record{Name: "www.example.com", Type: "CNAME", Values: []string{"API.example.com"}}))
+	require.NoError(t, checker.Resolve(ctx, Record{Name: "example.com", Type: "TXT", Values: []string{`"v=spf1 -all"`}}))
+	require.NoError(t, checker.Resolve(ctx, Record{Name: "example.com", Type: "MX", Values: []string{"10 mail.example.com"}}))
+
+	err := checker.Resolve(ctx, Record{Name: "old.example.com", Type: "A", Values: []string{"192.0.2.10"}})
+	require.EqualError(t, err, "old.example.com resolves to 198.51.100.7, which the plan does not list (planned 192.0.2.10)")
+
+	err = checker.Resolve(ctx, Record{Name: "api.example.com", Type: "AAAA", Values: []string{"2001:db8::2"}})
+	require.EqualError(t, err, "api.example.com resolves to 2001:db8::1, which the plan does not list (planned 2001:db8::2)")
+
+	err = checker.Resolve(ctx, Record{Name: "missing.example.com", Type: "A", Values: []string{"192.0.2.10"}})
+	require.ErrorIs(t, err, errNoSuchHost)
+
+	err = checker.Resolve(ctx, Record{Name: "www.example.com", Type: "CNAME", Values: []string{"cdn.example.com"}})
+	require.EqualError(t, err, "www.example.com CNAME resolves to api.example.com, not the planned cdn.example.com")
+
+	err = checker.Resolve(ctx, Record{Name: "example.com", Type: "TXT", Values: []string{"google-site-verification=abc"}})
+	require.EqualError(t, err, `example.com TXT is missing the planned ["google-site-verification=abc"]`)
+}
+
+func TestResolveMatchesAliasTargets(t *testing.T) {
+	checker := &Checker{Resolver: fakeResolver{hosts: map[string][]string{
+		"cdn.example.com":               {"203.0.113.1", "203.0.113.2"},
+		"d111111abcdef8.cloudfront.net": {"203.0.113.2", "203.0.113.3"},
+		"stale.example.com":             {"198.51.100.7"},
+	}}}
+	ctx := context.Background()
+
+	require.NoError(t, checker.Resolve(ctx, Record{Name: "cdn.example.com", Type: "A", Aliases: []string{"d111111abcdef8.cloudfront.net"}}))
+
+	err := checker.Resolve(ctx, Record{Name: "stale.example.com", Type: "A", Aliases: []string{"d111111abcdef8.cloudfront.net"}})
+	require.EqualError(t, err, "stale.example.com resolves to 198.51.100.7, none of which belong to its alias target d111111abcdef8.cloudfront.net")
+}
+
+func TestHealthyRequiresOKWithinSLA(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) { w.Write([]byte(`{"status":"ok"}`)) })
+	mux.HandleFunc("/down", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusServiceUnavailable) })
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/health", http.StatusFound) })
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	checker := &Checker{SLA: 200 * time.Millisecond}
+	ctx := context.Background()
+
+	require.NoError(t, checker.Healthy(ctx, HealthCheck{URL: server.URL + "/health", SearchString: `"ok"`}))
+
+	err := checker.Healthy(ctx, HealthCheck{URL: server.URL + "/health", SearchString: "healthy"})
+	require.ErrorContains(t, err, `without "healthy" in the first 5120 bytes`)
+
+	err = checker.Healthy(ctx, HealthCheck{URL: server.URL + "/down"})
+	require.ErrorContains(t, err, "answered 503, not 200")
+
+	err = checker.Healthy(ctx, HealthCheck{URL: server.URL + "/moved"})
+	require.ErrorContains(t, err, "answered 302, not 200")
+
+	err = checker.Healthy(ctx, HealthCheck{URL: server.URL + "/slow"})
+	require.EqualError(t, err, server.URL+"/slow did not answer within 200ms")
+}
+
+func TestUnreachableFailsOnOpenPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	checker := &Checker{Timeout: time.Second}
+	ctx := context.Background()
+
+	err = checker.Unreachable(ctx, Endpoint{Address: "aws_db_instance.main", Host: "127.0.0.1", Port: port})
+	require.ErrorContains(t, err, "(aws_db_instance.main) accepts connections from the internet")
+
+	listener.Close()
+	require.NoError(t, checker.Unreachable(ctx, Endpoint{Address: "aws_db_instance.main", Host: "127.0.0.1", Port: port}))
+}
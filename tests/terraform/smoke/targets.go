@@ -0,0 +1,209 @@
+package smoke
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"cs450/terraformtests/plancheck"
+)
+
+// Record is the planned answer for one DNS name and type. Records sharing a
+// name and type, such as the members of a weighted or failover set, are
+// merged.
+type Record struct {
+	Addresses []string
+	Name      string
+	Type      string
+	// Values are the planned record values, e.g. IP addresses or a CNAME
+	// target.
+	Values []string
+	// Aliases are the DNS names of the planned alias targets.
+	Aliases []string
+}
+
+// Records returns the aws_route53_record resources of a plan. A record's
+// fully qualified name is only known once it has been applied, so plans of
+// stacks that have not been applied return records named as written.
+func Records(plan *tfjson.Plan) []Record {
+	merged := map[string]*Record{}
+	var keys []string
+	for _, resource := range plancheck.Resources(plan, "aws_route53_record") {
+		name := plancheck.LookupString(resource.AttributeValues, "fqdn")
+		if name == "" {
+			name = plancheck.LookupString(resource.AttributeValues, "name")
+		}
+		name = canonicalName(name)
+		recordType := strings.ToUpper(plancheck.LookupString(resource.AttributeValues, "type"))
+		if name == "" || recordType == "" {
+			continue
+		}
+
+		key := name + " " + recordType
+		record, ok := merged[key]
+		if !ok {
+			record = &Record{Name: name, Type: recordType}
+			merged[key] = record
+			keys = append(keys, key)
+		}
+		record.Addresses = append(record.Addresses, resource.Address)
+		values, _ := resource.AttributeValues["records"].([]interface{})
+		for _, value := range values {
+			if s, ok := value.(string); ok {
+				record.Values = append(record.Values, s)
+			}
+		}
+		for _, alias := range plancheck.Blocks(resource.AttributeValues, "alias") {
+			if target := canonicalName(plancheck.LookupString(alias, "name")); target != "" {
+				record.Aliases = append(record.Aliases, target)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	records := make([]Record, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, *merged[key])
+	}
+	return records
+}
+
+// HealthCheck is an HTTP(S) endpoint a Route 53 health check polls.
+type HealthCheck struct {
+	Address string
+	URL     string
+	// SearchString is the text an *_STR_MATCH check expects in the first
+	// 5120 bytes of the response body, or "".
+	SearchString string
+}
+
+// HealthChecks returns the HTTP and HTTPS aws_route53_health_check
+// resources of a plan. TCP and calculated checks have no URL to request and
+// are left out.
+func HealthChecks(plan *tfjson.Plan) []HealthCheck {
+	var checks []HealthCheck
+	for _, resource := range plancheck.Resources(plan, "aws_route53_health_check") {
+		values := resource.AttributeValues
+		var scheme string
+		switch strings.ToUpper(plancheck.LookupString(values, "type")) {
+		case "HTTP", "HTTP_STR_MATCH":
+			scheme = "http"
+		case "HTTPS", "HTTPS_STR_MATCH":
+			scheme = "https"
+		default:
+			continue
+		}
+		host := plancheck.LookupString(values, "fqdn")
+		if host == "" {
+			host = plancheck.LookupString(values, "ip_address")
+		}
+		if host == "" {
+			continue
+		}
+		if port, ok := plancheck.LookupNumber(values, "port"); ok && port > 0 {
+			host = joinHostPort(host, int(port))
+		}
+		path := plancheck.LookupString(values, "resource_path")
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		checks = append(checks, HealthCheck{
+			Address:      resource.Address,
+			URL:          scheme + "://" + host + path,
+			SearchString: plancheck.LookupString(values, "search_string"),
+		})
+	}
+	return checks
+}
+
+// Endpoint is a host and port the plan keeps off the public internet.
+type Endpoint struct {
+	Address string
+	Host    string
+	Port    int
+}
+
+// String returns the endpoint as host:port.
+func (e Endpoint) String() string {
+	return joinHostPort(e.Host, e.Port)
+}
+
+// PrivateEndpoints returns the endpoints of a plan that must not be
+// reachable from the internet: internal load balancers, on the ports of their
+// listeners, databases that are not publicly accessible, and ElastiCache
+// replication groups, which never are.
+func PrivateEndpoints(plan *tfjson.Plan) []Endpoint {
+	var endpoints []Endpoint
+	listeners := plancheck.Resources(plan, "aws_lb_listener", "aws_alb_listener")
+	for _, lb := range plancheck.Resources(plan, "aws_lb", "aws_alb") {
+		if !plancheck.LookupBool(lb.AttributeValues, "internal") {
+			continue
+		}
+		host := plancheck.LookupString(lb.AttributeValues, "dns_name")
+		if host == "" {
+			continue
+		}
+		arn := plancheck.LookupString(lb.AttributeValues, "arn")
+		ports := map[int]bool{}
+		for _, listener := range listeners {
+			if arn == "" || plancheck.LookupString(listener.AttributeValues, "load_balancer_arn") != arn {
+				continue
+			}
+			if port, ok := plancheck.LookupNumber(listener.AttributeValues, "port"); ok {
+				ports[int(port)] = true
+			}
+		}
+		if len(ports) == 0 {
+			ports = map[int]bool{80: true, 443: true}
+		}
+		for _, port := range sortedPorts(ports) {
+			endpoints = append(endpoints, Endpoint{Address: lb.Address, Host: host, Port: port})
+		}
+	}
+
+	for _, db := range plancheck.Resources(plan, "aws_db_instance") {
+		if plancheck.LookupBool(db.AttributeValues, "publicly_accessible") {
+			continue
+		}
+		endpoints = appendEndpoint(endpoints, db, "address", "port")
+	}
+	for _, cluster := range plancheck.Resources(plan, "aws_rds_cluster") {
+		endpoints = appendEndpoint(endpoints, cluster, "endpoint", "port")
+	}
+	for _, group := range plancheck.Resources(plan, "aws_elasticache_replication_group") {
+		endpoints = appendEndpoint(endpoints, group, "primary_endpoint_address", "port")
+	}
+	return endpoints
+}
+
+func appendEndpoint(endpoints []Endpoint, resource *tfjson.StateResource, hostKey, portKey string) []Endpoint {
+	host := plancheck.LookupString(resource.AttributeValues, hostKey)
+	port, ok := plancheck.LookupNumber(resource.AttributeValues, portKey)
+	if host == "" || !ok || port <= 0 {
+		return endpoints
+	}
+	return append(endpoints, Endpoint{Address: resource.Address, Host: host, Port: int(port)})
+}
+
+func sortedPorts(ports map[int]bool) []int {
+	sorted := make([]int, 0, len(ports))
+	for port := range ports {
+		sorted = append(sorted, port)
+	}
+	sort.Ints(sorted)
+	return sorted
+}
+
+func joinHostPort(host string, port int) string {
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return host + ":" + strconv.Itoa(port)
+}
+
+// canonicalName lower-cases a DNS name and drops its trailing dot.
+func canonicalName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+}
@@ -0,0 +1,68 @@
+package smoke
+
+import (
+	"encoding/json"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+const appliedPlan = `{
+  "format_version": "1.0",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_route53_record.api_blue", "type": "aws_route53_record", "name": "api_blue", "values": {"name": "api", "fqdn": "api.example.com", "type": "A", "set_identifier": "blue", "records": ["192.0.2.10"]}},
+        {"address": "aws_route53_record.api_green", "type": "aws_route53_record", "name": "api_green", "values": {"name": "api", "fqdn": "api.example.com", "type": "A", "set_identifier": "green", "records": ["192.0.2.11"]}},
+        {"address": "aws_route53_record.cdn", "type": "aws_route53_record", "name": "cdn", "values": {"name": "cdn.example.com.", "type": "A", "alias": [{"name": "d111111abcdef8.cloudfront.net.", "zone_id": "Z2FDTNDATAQYW2"}]}},
+        {"address": "aws_route53_health_check.api", "type": "aws_route53_health_check", "name": "api", "values": {"type": "HTTPS_STR_MATCH", "fqdn": "api.example.com", "port": 443, "resource_path": "health", "search_string": "ok"}},
+        {"address": "aws_route53_health_check.db", "type": "aws_route53_health_check", "name": "db", "values": {"type": "TCP", "ip_address": "192.0.2.20", "port": 5432}},
+        {"address": "aws_lb.internal", "type": "aws_lb", "name": "internal", "values": {"internal": true, "arn": "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/internal/1", "dns_name": "internal-1.us-east-1.elb.amazonaws.com"}},
+        {"address": "aws_lb.public", "type": "aws_lb", "name": "public", "values": {"internal": false, "arn": "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/public/2", "dns_name": "public-2.us-east-1.elb.amazonaws.com"}},
+        {"address": "aws_lb_listener.internal", "type": "aws_lb_listener", "name": "internal", "values": {"load_balancer_arn": "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/internal/1", "port": 8080}},
+        {"address": "aws_db_instance.main", "type": "aws_db_instance", "name": "main", "values": {"publicly_accessible": false, "address": "main.abc.us-east-1.rds.amazonaws.com", "port": 5432}},
+        {"address": "aws_db_instance.reporting", "type": "aws_db_instance", "name": "reporting", "values": {"publicly_accessible": true, "address": "reporting.abc.us-east-1.rds.amazonaws.com", "port": 5432}}
+      ]
+    }
+  }
+}`
+
+func loadPlan(t *testing.T) *tfjson.Plan {
+	t.Helper()
+	var plan tfjson.Plan
+	require.NoError(t, json.Unmarshal([]byte(appliedPlan), &plan))
+	return &plan
+}
+
+func TestRecordsMergesRoutingSets(t *testing.T) {
+	records := Records(loadPlan(t))
+
+	require.Equal(t, []Record{
+		{
+			Addresses: []string{"aws_route53_record.api_blue", "aws_route53_record.api_green"},
+			Name:      "api.example.com",
+			Type:      "A",
+			Values:    []string{"192.0.2.10", "192.0.2.11"},
+		},
+		{
+			Addresses: []string{"aws_route53_record.cdn"},
+			Name:      "cdn.example.com",
+			Type:      "A",
+			Aliases:   []string{"d111111abcdef8.cloudfront.net"},
+		},
+	}, records)
+}
+
+func TestHealthChecksBuildsURLs(t *testing.T) {
+	require.Equal(t, []HealthCheck{
+		{Address: "aws_route53_health_check.api", URL: "https://api.example.com:443/health", SearchString: "ok"},
+	}, HealthChecks(loadPlan(t)))
+}
+
+func TestPrivateEndpointsSkipsPublicResources(t *testing.T) {
+	require.Equal(t, []Endpoint{
+		{Address: "aws_lb.internal", Host: "internal-1.us-east-1.elb.amazonaws.com", Port: 8080},
+		{Address: "aws_db_instance.main", Host: "main.abc.us-east-1.rds.amazonaws.com", Port: 5432},
+	}, PrivateEndpoints(loadPlan(t)))
+}
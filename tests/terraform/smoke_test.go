@@ -0,0 +1,54 @@
+package terraformtests
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+	"cs450/terraformtests/smoke"
+)
+
+// After an apply, Route 53 records must resolve to what the plan gives them,
+// health check endpoints must answer 200 within the SLA, and private
+// endpoints must not accept connections from wherever the suite runs, which
+// in CI is outside every VPC.
+func TestDeployedEndpointsAnswerAsPlanned(t *testing.T) {
+	if os.Getenv(postApplyEnv) == "" {
+		t.Skipf("set %s=1 to resolve and connect to the deployed endpoints", postApplyEnv)
+	}
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		_, plan := environmentPlan(t, env.Name)
+		config, err := plancheck.LoadConfig(complianceFile)
+		require.NoError(t, err)
+
+		records := smoke.Records(plan)
+		checks := smoke.HealthChecks(plan)
+		private := smoke.PrivateEndpoints(plan)
+		if len(records)+len(checks)+len(private) == 0 {
+			t.Skipf("the %s plan has no DNS records, health checks or private endpoints", env.Name)
+		}
+
+		checker := &smoke.Checker{SLA: config.Smoke.SLA()}
+		ctx := context.Background()
+		for _, record := range records {
+			if err := checker.Resolve(ctx, record); err != nil {
+				t.Errorf("%s: %v", strings.Join(record.Addresses, ", "), err)
+			}
+		}
+		for _, check := range checks {
+			if err := checker.Healthy(ctx, check); err != nil {
+				t.Errorf("%s: %v", check.Address, err)
+			}
+		}
+		for _, endpoint := range private {
+			if err := checker.Unreachable(ctx, endpoint); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+}
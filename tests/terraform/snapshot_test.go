@@ -0,0 +1,57 @@
+package terraformtests
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/plancheck"
+)
+
+var updateSnapshots = flag.Bool("update", false, "rewrite the plan snapshots under testdata/snapshots")
+
+// snapshotFile is the golden snapshot of an environment's plan.
+func snapshotFile(environment string) string {
+	return filepath.Join("testdata", "snapshots", environment+".json")
+}
+
+// Each environment's plan must match its snapshot under testdata/snapshots,
+// so an infrastructure change that no rule covers still shows up in review.
+// A mismatch lists added (+) and removed (-) resources and changed values
+// (~); when the change is intended, record it with
+// go test -run TestPlanMatchesSnapshot -update and commit the snapshot.
+func TestPlanMatchesSnapshot(t *testing.T) {
+	config, err := plancheck.LoadConfig(complianceFile)
+	require.NoError(t, err, "compliance configuration must load")
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		_, plan := environmentPlan(t, env.Name)
+		current := plancheck.NewSnapshot(env.Name, plan, config.Snapshot)
+		filename := snapshotFile(env.Name)
+
+		if *updateSnapshots {
+			require.NoError(t, plancheck.WriteSnapshot(filename, current))
+			t.Logf("snapshot written: %s", filename)
+			return
+		}
+		golden, err := plancheck.ReadSnapshot(filename)
+		if errors.Is(err, fs.ErrNotExist) {
+			t.Skipf("%s has no snapshot yet; record one with -update", env.Name)
+		}
+		require.NoError(t, err)
+
+		if changes := plancheck.DiffSnapshots(golden, current); len(changes) > 0 {
+			lines := make([]string, len(changes))
+			for i, change := range changes {
+				lines[i] = change.String()
+			}
+			t.Errorf("the %s plan differs from %s in %d value(s); if intended, rerun with -update:\n%s",
+				env.Name, filename, len(changes), strings.Join(lines, "\n"))
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package terraformtests
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/livestate"
+	"cs450/terraformtests/plancheck"
+)
+
+// TestBackendConfigIsApproved judges each environment's backend block alone,
+// so it needs neither a plan nor credentials.
+func TestBackendConfigIsApproved(t *testing.T) {
+	config, err := plancheck.LoadConfig(complianceFile)
+	require.NoError(t, err)
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		backend, err := plancheck.LoadBackend(env.Dir)
+		require.NoError(t, err)
+
+		findings := plancheck.Evaluate(
+			&plancheck.Input{DefaultRegion: env.Region, Environment: env.Name, Config: config, Backend: backend},
+			requireRules(t, "backend.config")...,
+		)
+		requireNoFindings(t, nil, findings)
+	})
+}
+
+// The state bucket and lock table are created outside terraform, so they are
+// read from AWS and judged by the same rule a bootstrap plan would be.
+func TestStateBackendIsHardened(t *testing.T) {
+	if os.Getenv(postApplyEnv) == "" {
+		t.Skipf("set %s=1 to check the live state bucket and lock table", postApplyEnv)
+	}
+
+	config, err := plancheck.LoadConfig(complianceFile)
+	require.NoError(t, err)
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		backend, err := plancheck.LoadBackend(env.Dir)
+		require.NoError(t, err)
+		require.NotNil(t, backend, "%s must declare a backend", env.Dir)
+
+		region := backend.Config["region"]
+		if region == "" {
+			region = env.Region
+		}
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		clients, err := livestate.NewClients(region, creds.AWS())
+		require.NoError(t, err)
+		live, err := clients.StateBackend(backend)
+		require.NoError(t, err)
+
+		findings := plancheck.Evaluate(
+			&plancheck.Input{Plan: live, DefaultRegion: region, Environment: env.Name, Config: config, Backend: backend},
+			requireRules(t, "backend.hardening")...,
+		)
+		requireNoFindings(t, live, findings)
+	})
+}
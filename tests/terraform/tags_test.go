@@ -0,0 +1,13 @@
+package terraformtests
+
+import (
+	"testing"
+)
+
+// Every taggable resource must carry the tags under tags.required in
+// compliance.yaml, whether set on the resource or inherited from the
+// provider's default_tags, with values matching tags.patterns. Each finding
+// names the resource and the tags it lacks.
+func TestResourcesCarryRequiredTags(t *testing.T) {
+	requireCompliance(t, "tags.required")
+}
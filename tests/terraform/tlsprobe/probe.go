@@ -0,0 +1,232 @@
+// Package tlsprobe connects to deployed HTTPS endpoints and checks what they
+// negotiate: the oldest TLS version and weakest cipher suites they accept, and
+// whether they send HSTS and refuse to serve content over plain HTTP.
+package tlsprobe
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy is what an endpoint must enforce.
+type Policy struct {
+	// MinVersion is the oldest TLS version an endpoint may accept, e.g.
+	// tls.VersionTLS12. Zero means tls.VersionTLS12.
+	MinVersion uint16
+
+	// HSTSMaxAge is the shortest Strict-Transport-Security max-age an
+	// endpoint may send. Zero means DefaultHSTSMaxAge.
+	HSTSMaxAge time.Duration
+}
+
+// DefaultHSTSMaxAge is the HSTSMaxAge used when none is set: one year, the
+// minimum browsers' preload lists accept.
+const DefaultHSTSMaxAge = 365 * 24 * time.Hour
+
+func (p Policy) minVersion() uint16 {
+	if p.MinVersion == 0 {
+		return tls.VersionTLS12
+	}
+	return p.MinVersion
+}
+
+func (p Policy) hstsMaxAge() time.Duration {
+	if p.HSTSMaxAge == 0 {
+		return DefaultHSTSMaxAge
+	}
+	return p.HSTSMaxAge
+}
+
+// ParseVersion parses a TLS version such as "1.2".
+func ParseVersion(version string) (uint16, error) {
+	switch strings.TrimPrefix(strings.TrimSpace(version), "TLS ") {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("tlsprobe: unknown TLS version %q", version)
+}
+
+// Prober probes endpoints against a policy.
+type Prober struct {
+	Policy Policy
+
+	// RootCAs verify the endpoints' certificates; nil uses the system pool.
+	RootCAs *x509.CertPool
+
+	// Timeout bounds each connection. Zero means ten seconds.
+	Timeout time.Duration
+}
+
+// Result is what probing one endpoint found.
+type Result struct {
+	Endpoint string
+	// Version and Cipher are what a current client negotiates.
+	Version string
+	Cipher  string
+	HSTS    string
+	// Problems are the policy violations, empty when the endpoint complies.
+	Problems []string
+}
+
+// Probe checks the endpoint at rawURL. An http:// URL is a problem in
+// itself. For an https:// URL without a port, plain HTTP on port 80 must be
+// refused or redirect to HTTPS. An error means the endpoint could not be
+// reached over TLS at all.
+func (p *Prober) Probe(rawURL string) (Result, error) {
+	result := Result{Endpoint: rawURL}
+	endpoint, err := url.Parse(rawURL)
+	if err != nil {
+		return result, fmt.Errorf("tlsprobe: %w", err)
+	}
+	switch endpoint.Scheme {
+	case "http":
+		result.Problems = append(result.Problems, "is served over plain HTTP, without TLS")
+		return result, nil
+	case "https":
+	default:
+		return result, fmt.Errorf("tlsprobe: %s is not an http(s) URL", rawURL)
+	}
+
+	host := endpoint.Hostname()
+	address := endpoint.Host
+	if endpoint.Port() == "" {
+		address = net.JoinHostPort(host, "443")
+	}
+
+	state, err := p.handshake(address, host, &tls.Config{})
+	if err != nil {
+		return result, fmt.Errorf("tlsprobe: %s: %w", rawURL, err)
+	}
+	result.Version = tls.VersionName(state.Version)
+	result.Cipher = tls.CipherSuiteName(state.CipherSuite)
+
+	minVersion := p.Policy.minVersion()
+	for version := uint16(tls.VersionTLS10); version < minVersion; version++ {
+		if _, err := p.handshake(address, host, &tls.Config{MinVersion: version, MaxVersion: version}); err == nil {
+			result.Problems = append(result.Problems, fmt.Sprintf("accepts %s, older than the minimum %s", tls.VersionName(version), tls.VersionName(minVersion)))
+		}
+	}
+	if weak, err := p.handshake(address, host, &tls.Config{MaxVersion: tls.VersionTLS12, CipherSuites: weakCipherSuites()}); err == nil {
+		result.Problems = append(result.Problems, fmt.Sprintf("accepts weak cipher suite %s", tls.CipherSuiteName(weak.CipherSuite)))
+	}
+
+	hsts, err := p.hsts(endpoint)
+	if err != nil {
+		return result, err
+	}
+	result.HSTS = hsts
+	if problem := p.checkHSTS(hsts); problem != "" {
+		result.Problems = append(result.Problems, problem)
+	}
+
+	if endpoint.Port() == "" {
+		if problem := p.plainHTTP(endpoint); problem != "" {
+			result.Problems = append(result.Problems, problem)
+		}
+	}
+	return result, nil
+}
+
+func (p *Prober) timeout() time.Duration {
+	if p.Timeout == 0 {
+		return 10 * time.Second
+	}
+	return p.Timeout
+}
+
+func (p *Prober) handshake(address, serverName string, config *tls.Config) (tls.ConnectionState, error) {
+	config.ServerName = serverName
+	config.RootCAs = p.RootCAs
+	dialer := &net.Dialer{Timeout: p.timeout()}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, config)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+	return conn.ConnectionState(), nil
+}
+
+// weakCipherSuites are the suites Go considers insecure: RC4, 3DES, and
+// the CBC suites with SHA-256 MACs, which are open to padding oracles.
+func weakCipherSuites() []uint16 {
+	var suites []uint16
+	for _, suite := range tls.InsecureCipherSuites() {
+		suites = append(suites, suite.ID)
+	}
+	return suites
+}
+
+func (p *Prober) client() *http.Client {
+	return &http.Client{
+		Timeout: p.timeout(),
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: p.RootCAs},
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func (p *Prober) hsts(endpoint *url.URL) (string, error) {
+	resp, err := p.client().Get(endpoint.String())
+	if err != nil {
+		return "", fmt.Errorf("tlsprobe: %w", err)
+	}
+	resp.Body.Close()
+	return resp.Header.Get("Strict-Transport-Security"), nil
+}
+
+func (p *Prober) checkHSTS(header string) string {
+	if header == "" {
+		return "sends no Strict-Transport-Security header"
+	}
+	want := p.Policy.hstsMaxAge()
+	for _, directive := range strings.Split(header, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+		if err != nil {
+			return fmt.Sprintf("sends an invalid Strict-Transport-Security header %q", header)
+		}
+		if age := time.Duration(seconds) * time.Second; age < want {
+			return fmt.Sprintf("sends Strict-Transport-Security max-age=%d, shorter than %d", seconds, int64(want/time.Second))
+		}
+		return ""
+	}
+	return fmt.Sprintf("sends a Strict-Transport-Security header without max-age: %q", header)
+}
+
+// plainHTTP reports a problem when port 80 of the endpoint's host serves
+// content rather than refusing the connection or redirecting to HTTPS.
+func (p *Prober) plainHTTP(endpoint *url.URL) string {
+	plain := *endpoint
+	plain.Scheme = "http"
+	resp, err := p.client().Get(plain.String())
+	if err != nil {
+		// Refused or timed out: nothing is served in the clear.
+		return ""
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		if location, err := resp.Location(); err == nil && location.Scheme == "https" {
+			return ""
+		}
+	}
+	return fmt.Sprintf("serves %s over plain HTTP (status %d) instead of redirecting to HTTPS", plain.String(), resp.StatusCode)
+}
@@ -0,0 +1,68 @@
+package tlsprobe
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func tlsServer(t *testing.T, config *tls.Config, hsts string) (*httptest.Server, *x509.CertPool) {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if hsts != "" {
+			w.Header().Set("Strict-Transport-Security", hsts)
+		}
+	}))
+	server.TLS = config
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	return server, roots
+}
+
+func TestProbeAcceptsModernEndpoint(t *testing.T) {
+	server, roots := tlsServer(t, &tls.Config{MinVersion: tls.VersionTLS12}, "max-age=63072000; includeSubDomains; preload")
+
+	result, err := (&Prober{RootCAs: roots}).Probe(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, "TLS 1.3", result.Version)
+	require.Empty(t, result.Problems)
+}
+
+func TestProbeReportsDowngradeCapableEndpoint(t *testing.T) {
+	server, roots := tlsServer(t, &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256},
+	}, "max-age=300")
+
+	prober := &Prober{Policy: Policy{MinVersion: tls.VersionTLS13}, RootCAs: roots}
+	result, err := prober.Probe(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"accepts TLS 1.2, older than the minimum TLS 1.3",
+		"accepts weak cipher suite TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+		"sends Strict-Transport-Security max-age=300, shorter than 31536000",
+	}, result.Problems)
+}
+
+func TestProbeReportsPlainHTTP(t *testing.T) {
+	result, err := (&Prober{}).Probe("http://validator.example.com")
+	require.NoError(t, err)
+	require.Equal(t, []string{"is served over plain HTTP, without TLS"}, result.Problems)
+}
+
+func TestCheckHSTS(t *testing.T) {
+	prober := &Prober{}
+	require.Equal(t, "sends no Strict-Transport-Security header", prober.checkHSTS(""))
+	require.Equal(t, `sends a Strict-Transport-Security header without max-age: "includeSubDomains"`, prober.checkHSTS("includeSubDomains"))
+	require.Empty(t, prober.checkHSTS(`max-age="31536000"`))
+}
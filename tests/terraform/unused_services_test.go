@@ -0,0 +1,56 @@
+package terraformtests
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cs450/terraformtests/livestate"
+	"cs450/terraformtests/plancheck"
+)
+
+// Access Advisor knows which granted services each deployed role has
+// actually used. The advisories it yields, with the policy changes that
+// would drop the unused grants, are for periodic tightening of the roles and
+// never fail the suite.
+func TestRolesUseTheirGrantedServices(t *testing.T) {
+	if os.Getenv(postApplyEnv) == "" {
+		t.Skipf("set %s=1 to report services the deployed roles do not use", postApplyEnv)
+	}
+
+	forEachEnvironment(t, func(t *testing.T, env EnvConfig) {
+		options, plan := environmentPlan(t, env.Name)
+		config, err := plancheck.LoadConfig(complianceFile)
+		require.NoError(t, err)
+
+		var roles []string
+		for _, resource := range plancheck.Resources(plan, "aws_iam_role") {
+			if arn := plancheck.LookupString(resource.AttributeValues, "arn"); arn != "" {
+				roles = append(roles, arn)
+			}
+		}
+		if len(roles) == 0 {
+			t.Skipf("the %s plan has no deployed IAM roles", env.Name)
+		}
+
+		creds, err := roleCredentials(env.Name)
+		require.NoError(t, err)
+		clients, err := livestate.NewClients(env.Region, creds.AWS())
+		require.NoError(t, err)
+		readAt := time.Now()
+		access, err := clients.ServiceAccess(roles)
+		require.NoError(t, err)
+
+		findings := plancheck.Evaluate(&plancheck.Input{
+			Plan:          plan,
+			DefaultRegion: env.Region,
+			Environment:   env.Name,
+			Config:        config,
+			Runtime:       &plancheck.Runtime{ServiceAccess: access, ReadAt: readAt},
+		}, requireRules(t, "iam.unused-services")...)
+		plancheck.NewSourceIndex(plan, options.TerraformDir).Annotate(findings)
+		requireNoFindings(t, plan, findings)
+	})
+}
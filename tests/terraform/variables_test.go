@@ -0,0 +1,7 @@
+package terraformtests
+
+import "testing"
+
+func TestVariablesHonorContract(t *testing.T) {
+	requireCompliance(t, "variables.contract")
+}
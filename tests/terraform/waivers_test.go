@@ -0,0 +1,12 @@
+package terraformtests
+
+import "testing"
+
+// Baseline entries and compliance:suppress tags with an expiry date stop
+// waiving their findings on that day; this test fails until each expired
+// waiver is reviewed, then renewed or removed.
+func TestWaiversHaveNotExpired(t *testing.T) {
+	t.Parallel()
+
+	requireCompliance(t, "waivers.expired")
+}